@@ -0,0 +1,81 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/example/iboz/internal/calendar"
+	"github.com/example/iboz/internal/email"
+)
+
+type calendarResponse struct {
+	Settings        calendar.Settings `json:"settings"`
+	Now             time.Time         `json:"now"`
+	AfterHours      bool              `json:"afterHours"`
+	NextWorkingTime time.Time         `json:"nextWorkingTime"`
+}
+
+func (h handler) registerCalendarRoutes(g *echo.Group) {
+	cg := g.Group("/calendar")
+	cg.GET("", h.getCalendarHandler)
+	cg.PUT("", h.updateCalendarHandler)
+	cg.POST("/holidays", h.importHolidaysHandler)
+}
+
+func (h handler) getCalendarHandler(c echo.Context) error {
+	return h.respondWithCalendar(c, http.StatusOK)
+}
+
+func (h handler) updateCalendarHandler(c echo.Context) error {
+	var hours calendar.Hours
+	if err := c.Bind(&hours); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid calendar payload"})
+	}
+	if _, err := h.calendars.UpdateSettings(c.Request().Context(), hours); err != nil {
+		return calendarError(c, err)
+	}
+	return h.respondWithCalendar(c, http.StatusOK)
+}
+
+// importHolidaysHandler reads an ICS document from the raw request body.
+func (h handler) importHolidaysHandler(c echo.Context) error {
+	holidays, err := h.calendars.ImportHolidays(c.Request().Context(), c.Request().Body)
+	if err != nil {
+		return calendarError(c, err)
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{"holidays": holidays})
+}
+
+func (h handler) respondWithCalendar(c echo.Context, status int) error {
+	ctx := c.Request().Context()
+	settings, err := h.calendars.Settings(ctx)
+	if err != nil {
+		return calendarError(c, err)
+	}
+	cal, err := h.calendars.Calendar(ctx)
+	if err != nil {
+		return calendarError(c, err)
+	}
+	now := cal.Now()
+	return c.JSON(status, calendarResponse{
+		Settings:        settings,
+		Now:             now,
+		AfterHours:      cal.AfterHours(),
+		NextWorkingTime: cal.NextWorkingTime(now).In(cal.Location()),
+	})
+}
+
+func calendarError(c echo.Context, err error) error {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, calendar.ErrInvalidHours), errors.Is(err, calendar.ErrInvalidICS), errors.Is(err, email.ErrProviderNotAuthenticated):
+		status = http.StatusBadRequest
+	case errors.Is(err, context.Canceled):
+		status = http.StatusRequestTimeout
+	}
+	return c.JSON(status, map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,60 @@
+package api
+
+import (
+	"bytes"
+	"net/http"
+	"testing"
+)
+
+func TestCalendarHandlers(t *testing.T) {
+	h := newEmailHandler(t)
+
+	ctx, rec := newContext(http.MethodPut, "/api/calendar", bytes.NewBufferString(`{"start":"09:00","end":"17:00"}`))
+	if err := h.updateCalendarHandler(ctx); err != nil {
+		t.Fatalf("update calendar handler error: %v", err)
+	}
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected bad request before authentication, got %d", rec.Code)
+	}
+
+	syncTestMessages(t, h)
+
+	ctx, rec = newContext(http.MethodPut, "/api/calendar", bytes.NewBufferString(`{"timezone":"Europe/Berlin","start":"08:00","end":"12:00"}`))
+	if err := h.updateCalendarHandler(ctx); err != nil {
+		t.Fatalf("update calendar handler error: %v", err)
+	}
+	if rec.Code != http.StatusOK {
+		t.Fatalf("unexpected status: %d (%s)", rec.Code, rec.Body.String())
+	}
+	resp := decodeBody[calendarResponse](t, rec)
+	// Tuesday 12:00 UTC is 13:00 in Berlin, after the 12:00 close.
+	if resp.Settings.User != "ops@example.com" || !resp.AfterHours {
+		t.Fatalf("unexpected calendar response: %+v", resp)
+	}
+
+	ctx, rec = newContext(http.MethodPost, "/api/calendar/holidays", bytes.NewBufferString("BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nDTSTART;VALUE=DATE:20250319\r\nSUMMARY:Founders Day\r\nEND:VEVENT\r\nEND:VCALENDAR\r\n"))
+	if err := h.importHolidaysHandler(ctx); err != nil {
+		t.Fatalf("import holidays handler error: %v", err)
+	}
+	if rec.Code != http.StatusOK {
+		t.Fatalf("unexpected status: %d (%s)", rec.Code, rec.Body.String())
+	}
+
+	ctx, rec = newContext(http.MethodGet, "/api/calendar", nil)
+	if err := h.getCalendarHandler(ctx); err != nil {
+		t.Fatalf("get calendar handler error: %v", err)
+	}
+	resp = decodeBody[calendarResponse](t, rec)
+	// Wednesday is now a holiday, so the next opening is Thursday 08:00 Berlin.
+	if resp.NextWorkingTime.UTC().Format("2006-01-02T15:04") != "2025-03-20T07:00" {
+		t.Fatalf("unexpected next working time: %s", resp.NextWorkingTime)
+	}
+
+	ctx, rec = newContext(http.MethodPost, "/api/calendar/holidays", bytes.NewBufferString("not a calendar"))
+	if err := h.importHolidaysHandler(ctx); err != nil {
+		t.Fatalf("import holidays handler error: %v", err)
+	}
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected bad request, got %d", rec.Code)
+	}
+}
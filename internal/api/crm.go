@@ -0,0 +1,70 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/example/iboz/internal/crm"
+	"github.com/example/iboz/internal/email"
+)
+
+func (h handler) registerCRMRoutes(g *echo.Group) {
+	cg := g.Group("/crm")
+	cg.GET("/contacts", h.crmContactsHandler)
+	cg.GET("/records", h.listCRMRecordsHandler)
+	cg.POST("/records", h.createCRMRecordHandler)
+	cg.GET("/records/:id", h.getCRMRecordHandler)
+}
+
+func (h handler) crmContactsHandler(c echo.Context) error {
+	contacts, err := h.crm.Contacts(c.Request().Context(), c.QueryParam("messageId"))
+	if err != nil {
+		return crmError(c, err)
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{"contacts": contacts})
+}
+
+func (h handler) listCRMRecordsHandler(c echo.Context) error {
+	list, err := h.crm.List(c.Request().Context(), c.QueryParam("messageId"))
+	if err != nil {
+		return crmError(c, err)
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{"records": list})
+}
+
+// createCRMRecordHandler answers 202: the record is created in the CRM by the outbox relay.
+func (h handler) createCRMRecordHandler(c echo.Context) error {
+	var req crm.Request
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid crm payload"})
+	}
+	record, err := h.crm.Sync(c.Request().Context(), req)
+	if err != nil {
+		return crmError(c, err)
+	}
+	return c.JSON(http.StatusAccepted, record)
+}
+
+func (h handler) getCRMRecordHandler(c echo.Context) error {
+	record, err := h.crm.Get(c.Request().Context(), c.Param("id"))
+	if err != nil {
+		return crmError(c, err)
+	}
+	return c.JSON(http.StatusOK, record)
+}
+
+func crmError(c echo.Context, err error) error {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, crm.ErrRecordNotFound), errors.Is(err, email.ErrMessageNotFound), errors.Is(err, crm.ErrUnknownProvider):
+		status = http.StatusNotFound
+	case errors.Is(err, crm.ErrInvalidRecord):
+		status = http.StatusBadRequest
+	case errors.Is(err, context.Canceled):
+		status = http.StatusRequestTimeout
+	}
+	return c.JSON(status, map[string]string{"error": err.Error()})
+}
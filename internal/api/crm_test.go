@@ -0,0 +1,90 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/example/iboz/internal/crm"
+	"github.com/example/iboz/internal/crm/adapter/hubspot"
+	crmmemory "github.com/example/iboz/internal/crm/adapter/memory"
+	"github.com/example/iboz/internal/email"
+	"github.com/example/iboz/internal/email/adapter/memory"
+	"github.com/example/iboz/internal/email/adapter/synthetic"
+)
+
+func TestCRMHandlersAndMessageDetail(t *testing.T) {
+	h := newEmailHandler(t)
+	repo := memory.NewRepository()
+	clock := testClock{}
+	if err := repo.SaveMessages(context.Background(), []email.EmailMessage{{ID: "msg-1", Subject: "Pricing", Sender: "buyer@customer.example"}}, clock.now); err != nil {
+		t.Fatalf("save messages: %v", err)
+	}
+	h.emailService = email.NewService(repo, email.NewSHA256Hasher(), memory.NewVault(), synthetic.NewGenerator(), clock)
+	h.crm = crm.NewService(crmmemory.NewRepository(), repo, repo, map[string]crm.Client{
+		crm.ProviderHubSpot: hubspot.NewClient(hubspot.Config{BaseURL: "http://127.0.0.1:1", Token: "hs-token"}),
+	}, clock)
+
+	ctx, rec := newContext(http.MethodPost, "/api/crm/records", bytes.NewBufferString(`{"messageId":"msg-1","provider":"hubspot","kind":"opportunity","amount":1200}`))
+	if err := h.createCRMRecordHandler(ctx); err != nil {
+		t.Fatalf("create record handler error: %v", err)
+	}
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("unexpected status: %d (%s)", rec.Code, rec.Body.String())
+	}
+	created := decodeBody[crm.Record](t, rec)
+	if created.Status != crm.StatusPending || created.Name != "Pricing" {
+		t.Fatalf("unexpected record: %+v", created)
+	}
+
+	ctx, rec = newContext(http.MethodGet, "/api/crm/records?messageId=msg-1", nil)
+	if err := h.listCRMRecordsHandler(ctx); err != nil {
+		t.Fatalf("list records handler error: %v", err)
+	}
+	if list := decodeBody[map[string][]crm.Record](t, rec); len(list["records"]) != 1 {
+		t.Fatalf("expected one record, got %+v", list)
+	}
+
+	ctx, rec = newContext(http.MethodGet, "/api/crm/records/"+created.ID, nil)
+	withParam(ctx, created.ID)
+	if err := h.getCRMRecordHandler(ctx); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("get record: %v (%d)", err, rec.Code)
+	}
+
+	for _, tc := range []struct {
+		body string
+		want int
+	}{
+		{`{"messageId":"msg-1","provider":"pipedrive","kind":"lead"}`, http.StatusNotFound},
+		{`{"messageId":"msg-1","provider":"hubspot","kind":"account"}`, http.StatusBadRequest},
+		{`{"messageId":"missing","provider":"hubspot","kind":"lead"}`, http.StatusNotFound},
+	} {
+		ctx, rec = newContext(http.MethodPost, "/api/crm/records", bytes.NewBufferString(tc.body))
+		if err := h.createCRMRecordHandler(ctx); err != nil {
+			t.Fatalf("create record handler error: %v", err)
+		}
+		if rec.Code != tc.want {
+			t.Fatalf("%s: expected %d, got %d (%s)", tc.body, tc.want, rec.Code, rec.Body.String())
+		}
+	}
+
+	link := email.CRMLink{Provider: crm.ProviderHubSpot, Kind: string(crm.KindOpportunity), ExternalID: "901"}
+	if err := repo.LinkCRM(context.Background(), "msg-1", link); err != nil {
+		t.Fatalf("link crm: %v", err)
+	}
+	ctx, rec = newContext(http.MethodGet, "/api/email/messages/msg-1", nil)
+	withParam(ctx, "msg-1")
+	if err := h.emailMessageHandler(ctx); err != nil {
+		t.Fatalf("message handler error: %v", err)
+	}
+	if message := decodeBody[email.EmailMessage](t, rec); len(message.CRM) != 1 || message.CRM[0] != link {
+		t.Fatalf("expected the crm link on the message, got %+v", message)
+	}
+
+	ctx, rec = newContext(http.MethodGet, "/api/email/messages/missing", nil)
+	withParam(ctx, "missing")
+	if err := h.emailMessageHandler(ctx); err != nil || rec.Code != http.StatusNotFound {
+		t.Fatalf("expected not found, got %v (%d)", err, rec.Code)
+	}
+}
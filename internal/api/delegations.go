@@ -0,0 +1,108 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/example/iboz/internal/delegation"
+	"github.com/example/iboz/internal/email"
+)
+
+type delegationCreateRequest struct {
+	MessageID string     `json:"messageId"`
+	Assignee  string     `json:"assignee"`
+	Note      string     `json:"note"`
+	DueAt     *time.Time `json:"dueAt"`
+	SLA       string     `json:"sla"`
+}
+
+type delegationUpdateRequest struct {
+	Status   *delegation.Status `json:"status"`
+	Assignee *string            `json:"assignee"`
+	DueAt    *time.Time         `json:"dueAt"`
+}
+
+func (h handler) registerDelegationRoutes(g *echo.Group) {
+	dg := g.Group("/delegations")
+	dg.GET("", h.listDelegationsHandler)
+	dg.POST("", h.createDelegationHandler)
+	dg.GET("/:id", h.getDelegationHandler)
+	dg.PATCH("/:id", h.updateDelegationHandler)
+}
+
+func (h handler) listDelegationsHandler(c echo.Context) error {
+	list, err := h.delegations.List(c.Request().Context(), delegation.Status(c.QueryParam("status")))
+	if err != nil {
+		return delegationError(c, err)
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{"delegations": list})
+}
+
+func (h handler) createDelegationHandler(c echo.Context) error {
+	var req delegationCreateRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid delegation payload"})
+	}
+
+	create := delegation.CreateRequest{MessageID: req.MessageID, Assignee: req.Assignee, Note: req.Note}
+	if req.DueAt != nil {
+		create.DueAt = *req.DueAt
+	}
+	if req.SLA != "" {
+		sla, err := time.ParseDuration(req.SLA)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid sla duration"})
+		}
+		create.SLA = sla
+	}
+
+	created, err := h.delegations.Create(c.Request().Context(), create)
+	if err != nil {
+		return delegationError(c, err)
+	}
+	return c.JSON(http.StatusCreated, created)
+}
+
+func (h handler) getDelegationHandler(c echo.Context) error {
+	d, err := h.delegations.Get(c.Request().Context(), c.Param("id"))
+	if err != nil {
+		return delegationError(c, err)
+	}
+	return c.JSON(http.StatusOK, d)
+}
+
+func (h handler) updateDelegationHandler(c echo.Context) error {
+	var req delegationUpdateRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid delegation payload"})
+	}
+
+	updated, err := h.delegations.Update(c.Request().Context(), c.Param("id"), delegation.Update{
+		Status:   req.Status,
+		Assignee: req.Assignee,
+		DueAt:    req.DueAt,
+	})
+	if err != nil {
+		return delegationError(c, err)
+	}
+	return c.JSON(http.StatusOK, updated)
+}
+
+func delegationError(c echo.Context, err error) error {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, delegation.ErrDelegationNotFound), errors.Is(err, email.ErrMessageNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, delegation.ErrInvalidDelegation):
+		status = http.StatusBadRequest
+	case errors.Is(err, delegation.ErrInvalidTransition), errors.Is(err, delegation.ErrConflict):
+		status = http.StatusConflict
+	case errors.Is(err, context.Canceled):
+		status = http.StatusRequestTimeout
+	}
+	return c.JSON(status, map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,80 @@
+package api
+
+import (
+	"bytes"
+	"net/http"
+	"testing"
+
+	"github.com/example/iboz/internal/delegation"
+)
+
+func TestDelegationHandlers(t *testing.T) {
+	h := newEmailHandler(t)
+	syncTestMessages(t, h)
+
+	ctx, rec := newContext(http.MethodPost, "/api/delegations", bytes.NewBufferString(`{"messageId":"msg-escalation","assignee":"legal-ops@example.com","sla":"48h"}`))
+	if err := h.createDelegationHandler(ctx); err != nil {
+		t.Fatalf("create delegation handler error: %v", err)
+	}
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("unexpected status: %d (%s)", rec.Code, rec.Body.String())
+	}
+	created := decodeBody[delegation.Delegation](t, rec)
+
+	ctx, rec = newContext(http.MethodGet, "/api/dashboard", nil)
+	if err := h.dashboardHandler(ctx); err != nil {
+		t.Fatalf("dashboard handler error: %v", err)
+	}
+	dashboard := decodeBody[map[string]any](t, rec)
+	for _, raw := range dashboard["queues"].([]any) {
+		queue := raw.(map[string]any)
+		if queue["id"] == "delegated" && queue["count"] != float64(1) {
+			t.Fatalf("expected one delegated item, got %v", queue["count"])
+		}
+	}
+
+	ctx, rec = newContext(http.MethodPatch, "/api/delegations/"+created.ID, bytes.NewBufferString(`{"status":"archived"}`))
+	withParam(ctx, created.ID)
+	if err := h.updateDelegationHandler(ctx); err != nil {
+		t.Fatalf("update delegation handler error: %v", err)
+	}
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected bad request for an unknown status, got %d", rec.Code)
+	}
+
+	ctx, rec = newContext(http.MethodPatch, "/api/delegations/"+created.ID, bytes.NewBufferString(`{"status":"done"}`))
+	withParam(ctx, created.ID)
+	if err := h.updateDelegationHandler(ctx); err != nil {
+		t.Fatalf("update delegation handler error: %v", err)
+	}
+	if rec.Code != http.StatusOK {
+		t.Fatalf("unexpected status: %d (%s)", rec.Code, rec.Body.String())
+	}
+
+	ctx, rec = newContext(http.MethodPatch, "/api/delegations/"+created.ID, bytes.NewBufferString(`{"status":"assigned"}`))
+	withParam(ctx, created.ID)
+	if err := h.updateDelegationHandler(ctx); err != nil {
+		t.Fatalf("update delegation handler error: %v", err)
+	}
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("expected conflict for reopening, got %d", rec.Code)
+	}
+
+	ctx, rec = newContext(http.MethodGet, "/api/delegations?status=done", nil)
+	if err := h.listDelegationsHandler(ctx); err != nil {
+		t.Fatalf("list delegations handler error: %v", err)
+	}
+	list := decodeBody[map[string][]delegation.Delegation](t, rec)
+	if len(list["delegations"]) != 1 {
+		t.Fatalf("expected one done delegation, got %v", list)
+	}
+
+	ctx, rec = newContext(http.MethodGet, "/api/delegations/missing", nil)
+	withParam(ctx, "missing")
+	if err := h.getDelegationHandler(ctx); err != nil {
+		t.Fatalf("get delegation handler error: %v", err)
+	}
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected not found, got %d", rec.Code)
+	}
+}
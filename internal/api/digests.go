@@ -0,0 +1,138 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/example/iboz/internal/digest"
+)
+
+func (h handler) registerDigestRoutes(g *echo.Group) {
+	dg := g.Group("/digests")
+	dg.GET("", h.listDigestsHandler)
+	dg.POST("", h.createDigestHandler)
+	dg.GET("/:id", h.getDigestHandler)
+	dg.PUT("/:id", h.updateDigestHandler)
+	dg.DELETE("/:id", h.deleteDigestHandler)
+	dg.GET("/:id/preview", h.previewDigestHandler)
+	dg.POST("/:id/send", h.sendDigestHandler)
+	dg.GET("/:id/editions", h.listDigestEditionsHandler)
+	dg.GET("/:id/editions/:editionId", h.getDigestEditionHandler)
+}
+
+func (h handler) listDigestsHandler(c echo.Context) error {
+	list, err := h.digests.List(c.Request().Context())
+	if err != nil {
+		return digestError(c, err)
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{"digests": list})
+}
+
+func (h handler) createDigestHandler(c echo.Context) error {
+	var req digest.Request
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid digest payload"})
+	}
+	created, err := h.digests.Create(c.Request().Context(), req)
+	if err != nil {
+		return digestError(c, err)
+	}
+	return c.JSON(http.StatusCreated, created)
+}
+
+func (h handler) getDigestHandler(c echo.Context) error {
+	d, err := h.digests.Get(c.Request().Context(), c.Param("id"))
+	if err != nil {
+		return digestError(c, err)
+	}
+	return c.JSON(http.StatusOK, d)
+}
+
+func (h handler) updateDigestHandler(c echo.Context) error {
+	var req digest.Request
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid digest payload"})
+	}
+	updated, err := h.digests.Update(c.Request().Context(), c.Param("id"), req)
+	if err != nil {
+		return digestError(c, err)
+	}
+	return c.JSON(http.StatusOK, updated)
+}
+
+func (h handler) deleteDigestHandler(c echo.Context) error {
+	if err := h.digests.Delete(c.Request().Context(), c.Param("id")); err != nil {
+		return digestError(c, err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// previewDigestHandler builds the next edition without sending it; see
+// respondWithEdition for ?format=.
+func (h handler) previewDigestHandler(c echo.Context) error {
+	edition, err := h.digests.Preview(c.Request().Context(), c.Param("id"))
+	if err != nil {
+		return digestError(c, err)
+	}
+	return respondWithEdition(c, http.StatusOK, edition)
+}
+
+// sendDigestHandler queues the next edition for delivery now, or replies 204
+// when no message arrived since the previous edition.
+func (h handler) sendDigestHandler(c echo.Context) error {
+	edition, err := h.digests.Send(c.Request().Context(), c.Param("id"))
+	if err != nil {
+		return digestError(c, err)
+	}
+	if edition == nil {
+		return c.NoContent(http.StatusNoContent)
+	}
+	return c.JSON(http.StatusAccepted, edition)
+}
+
+func (h handler) listDigestEditionsHandler(c echo.Context) error {
+	list, err := h.digests.Editions(c.Request().Context(), c.Param("id"))
+	if err != nil {
+		return digestError(c, err)
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{"editions": list})
+}
+
+func (h handler) getDigestEditionHandler(c echo.Context) error {
+	edition, err := h.digests.GetEdition(c.Request().Context(), c.Param("id"), c.Param("editionId"))
+	if err != nil {
+		return digestError(c, err)
+	}
+	return respondWithEdition(c, http.StatusOK, edition)
+}
+
+// respondWithEdition returns the edition as JSON, or only its rendered body
+// with ?format=html or ?format=text.
+func respondWithEdition(c echo.Context, status int, edition *digest.Edition) error {
+	switch c.QueryParam("format") {
+	case "":
+		return c.JSON(status, edition)
+	case "html":
+		return c.HTML(status, edition.HTMLBody)
+	case "text":
+		return c.String(status, edition.TextBody)
+	default:
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "format must be html or text"})
+	}
+}
+
+func digestError(c echo.Context, err error) error {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, digest.ErrDigestNotFound), errors.Is(err, digest.ErrEditionNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, digest.ErrInvalidDigest):
+		status = http.StatusBadRequest
+	case errors.Is(err, context.Canceled):
+		status = http.StatusRequestTimeout
+	}
+	return c.JSON(status, map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,85 @@
+package api
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/example/iboz/internal/digest"
+)
+
+func TestDigestHandlers(t *testing.T) {
+	h := newEmailHandler(t)
+	syncTestMessages(t, h)
+
+	ctx, rec := newContext(http.MethodPost, "/api/digests", bytes.NewBufferString(`{"name":"Friday recap","channels":["sms"]}`))
+	if err := h.createDigestHandler(ctx); err != nil || rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an unknown channel, got %d (%v)", rec.Code, err)
+	}
+	ctx, rec = newContext(http.MethodPost, "/api/digests", bytes.NewBufferString(`{"name":"Friday recap","categories":["newsletter"]}`))
+	if err := h.createDigestHandler(ctx); err != nil || rec.Code != http.StatusCreated {
+		t.Fatalf("create digest: %v (%d %s)", err, rec.Code, rec.Body.String())
+	}
+	created := decodeBody[digest.Digest](t, rec)
+	if created.Schedule != digest.DefaultSchedule || created.NextRunAt.IsZero() {
+		t.Fatalf("unexpected digest: %+v", created)
+	}
+
+	ctx, rec = newContext(http.MethodGet, "/api/digests/"+created.ID+"/preview?format=text", nil)
+	withParam(ctx, created.ID)
+	if err := h.previewDigestHandler(ctx); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("preview digest: %v (%d)", err, rec.Code)
+	}
+	if !strings.HasPrefix(rec.Body.String(), "Friday recap\n1 message from 1 sender") {
+		t.Fatalf("unexpected text preview:\n%s", rec.Body.String())
+	}
+
+	ctx, rec = newContext(http.MethodPost, "/api/digests/"+created.ID+"/send", nil)
+	withParam(ctx, created.ID)
+	if err := h.sendDigestHandler(ctx); err != nil || rec.Code != http.StatusAccepted {
+		t.Fatalf("send digest: %v (%d %s)", err, rec.Code, rec.Body.String())
+	}
+	edition := decodeBody[digest.Edition](t, rec)
+	if edition.ID == "" || edition.Messages != 1 || edition.Recipient != "ops@example.com" {
+		t.Fatalf("unexpected edition: %+v", edition)
+	}
+
+	ctx, rec = newContext(http.MethodPost, "/api/digests/"+created.ID+"/send", nil)
+	withParam(ctx, created.ID)
+	if err := h.sendDigestHandler(ctx); err != nil || rec.Code != http.StatusNoContent {
+		t.Fatalf("expected 204 without new messages, got %d (%v)", rec.Code, err)
+	}
+
+	ctx, rec = newContext(http.MethodGet, "/api/digests/"+created.ID+"/editions", nil)
+	withParam(ctx, created.ID)
+	if err := h.listDigestEditionsHandler(ctx); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("list editions: %v (%d)", err, rec.Code)
+	}
+	if list := decodeBody[map[string][]digest.Edition](t, rec)["editions"]; len(list) != 1 || list[0].ID != edition.ID {
+		t.Fatalf("unexpected editions: %+v", list)
+	}
+
+	for format, want := range map[string]int{"html": http.StatusOK, "pdf": http.StatusBadRequest} {
+		ctx, rec = newContext(http.MethodGet, "/api/digests/"+created.ID+"/editions/"+edition.ID+"?format="+format, nil)
+		ctx.SetParamNames("id", "editionId")
+		ctx.SetParamValues(created.ID, edition.ID)
+		if err := h.getDigestEditionHandler(ctx); err != nil || rec.Code != want {
+			t.Fatalf("format %s: expected %d, got %d (%v)", format, want, rec.Code, err)
+		}
+		if format == "html" && !strings.Contains(rec.Header().Get("Content-Type"), "text/html") {
+			t.Fatalf("expected an html edition, got %q", rec.Header().Get("Content-Type"))
+		}
+	}
+
+	ctx, rec = newContext(http.MethodDelete, "/api/digests/"+created.ID, nil)
+	withParam(ctx, created.ID)
+	if err := h.deleteDigestHandler(ctx); err != nil || rec.Code != http.StatusNoContent {
+		t.Fatalf("delete digest: %v (%d)", err, rec.Code)
+	}
+	ctx, rec = newContext(http.MethodGet, "/api/digests/"+created.ID, nil)
+	withParam(ctx, created.ID)
+	if err := h.getDigestHandler(ctx); err != nil || rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 after delete, got %d (%v)", rec.Code, err)
+	}
+}
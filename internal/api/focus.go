@@ -0,0 +1,174 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/example/iboz/internal/email"
+	"github.com/example/iboz/internal/focus"
+)
+
+type focusItemRequest struct {
+	State focus.ItemState `json:"state"`
+}
+
+func (h handler) registerFocusRoutes(g *echo.Group) {
+	fg := g.Group("/focus")
+	fg.GET("/plan", h.focusPlanHandler)
+	fg.GET("/history", h.focusHistoryHandler)
+	fg.GET("/sessions", h.listFocusSessionsHandler)
+	fg.POST("/sessions", h.startFocusSessionHandler)
+	fg.GET("/sessions/current", h.currentFocusSessionHandler)
+	fg.GET("/sessions/:id", h.getFocusSessionHandler)
+	fg.POST("/sessions/:id/pause", h.pauseFocusSessionHandler)
+	fg.POST("/sessions/:id/resume", h.resumeFocusSessionHandler)
+	fg.POST("/sessions/:id/complete", h.completeFocusSessionHandler)
+	fg.POST("/sessions/:id/abandon", h.abandonFocusSessionHandler)
+	fg.POST("/sessions/:id/messages/:messageId", h.markFocusItemHandler)
+	fg.GET("/settings", h.getFocusSettingsHandler)
+	fg.PUT("/settings", h.updateFocusSettingsHandler)
+	fg.GET("/held", h.listHeldNotificationsHandler)
+}
+
+func (h handler) focusPlanHandler(c echo.Context) error {
+	plan, err := h.focus.Plan(c.Request().Context())
+	if err != nil {
+		return focusError(c, err)
+	}
+	return c.JSON(http.StatusOK, plan)
+}
+
+// focusHistoryHandler returns ?days= daily values, 30 by default.
+func (h handler) focusHistoryHandler(c echo.Context) error {
+	days := 0
+	if raw := c.QueryParam("days"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 1 {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "days must be a positive integer"})
+		}
+		days = parsed
+	}
+	history, err := h.focus.History(c.Request().Context(), days)
+	if err != nil {
+		return focusError(c, err)
+	}
+	return c.JSON(http.StatusOK, history)
+}
+
+func (h handler) listFocusSessionsHandler(c echo.Context) error {
+	list, err := h.focusSessions.List(c.Request().Context())
+	if err != nil {
+		return focusError(c, err)
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{"sessions": list})
+}
+
+func (h handler) startFocusSessionHandler(c echo.Context) error {
+	var req focus.StartRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid focus session payload"})
+	}
+	session, err := h.focusSessions.Start(c.Request().Context(), req)
+	if err != nil {
+		return focusError(c, err)
+	}
+	return c.JSON(http.StatusCreated, session)
+}
+
+// currentFocusSessionHandler answers {"session": null} when no session is open.
+func (h handler) currentFocusSessionHandler(c echo.Context) error {
+	session, err := h.focusSessions.Current(c.Request().Context())
+	if err != nil {
+		return focusError(c, err)
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{"session": session})
+}
+
+func (h handler) getFocusSessionHandler(c echo.Context) error {
+	session, err := h.focusSessions.Get(c.Request().Context(), c.Param("id"))
+	return focusSessionResponse(c, session, err)
+}
+
+func (h handler) pauseFocusSessionHandler(c echo.Context) error {
+	session, err := h.focusSessions.Pause(c.Request().Context(), c.Param("id"))
+	return focusSessionResponse(c, session, err)
+}
+
+func (h handler) resumeFocusSessionHandler(c echo.Context) error {
+	session, err := h.focusSessions.Resume(c.Request().Context(), c.Param("id"))
+	return focusSessionResponse(c, session, err)
+}
+
+// completeFocusSessionHandler returns the session with its summary.
+func (h handler) completeFocusSessionHandler(c echo.Context) error {
+	session, err := h.focusSessions.Complete(c.Request().Context(), c.Param("id"))
+	return focusSessionResponse(c, session, err)
+}
+
+func (h handler) abandonFocusSessionHandler(c echo.Context) error {
+	session, err := h.focusSessions.Abandon(c.Request().Context(), c.Param("id"))
+	return focusSessionResponse(c, session, err)
+}
+
+func (h handler) markFocusItemHandler(c echo.Context) error {
+	var req focusItemRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid focus item payload"})
+	}
+	session, err := h.focusSessions.Mark(c.Request().Context(), c.Param("id"), c.Param("messageId"), req.State)
+	return focusSessionResponse(c, session, err)
+}
+
+func (h handler) getFocusSettingsHandler(c echo.Context) error {
+	settings, err := h.focusSessions.Settings(c.Request().Context())
+	if err != nil {
+		return focusError(c, err)
+	}
+	return c.JSON(http.StatusOK, settings)
+}
+
+func (h handler) updateFocusSettingsHandler(c echo.Context) error {
+	var settings focus.Settings
+	if err := c.Bind(&settings); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid focus settings payload"})
+	}
+	updated, err := h.focusSessions.UpdateSettings(c.Request().Context(), settings)
+	if err != nil {
+		return focusError(c, err)
+	}
+	return c.JSON(http.StatusOK, updated)
+}
+
+func (h handler) listHeldNotificationsHandler(c echo.Context) error {
+	held, err := h.focusSessions.Holds(c.Request().Context())
+	if err != nil {
+		return focusError(c, err)
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{"held": held})
+}
+
+func focusSessionResponse(c echo.Context, session focus.Session, err error) error {
+	if err != nil {
+		return focusError(c, err)
+	}
+	return c.JSON(http.StatusOK, session)
+}
+
+func focusError(c echo.Context, err error) error {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, focus.ErrSessionNotFound), errors.Is(err, email.ErrMessageNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, focus.ErrInvalidSession), errors.Is(err, focus.ErrInvalidSettings), errors.Is(err, email.ErrProviderNotAuthenticated):
+		status = http.StatusBadRequest
+	case errors.Is(err, focus.ErrInvalidTransition), errors.Is(err, focus.ErrSessionInProgress), errors.Is(err, focus.ErrConflict):
+		status = http.StatusConflict
+	case errors.Is(err, context.Canceled):
+		status = http.StatusRequestTimeout
+	}
+	return c.JSON(status, map[string]string{"error": err.Error()})
+}
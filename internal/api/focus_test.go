@@ -0,0 +1,214 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/example/iboz/internal/calendar"
+	calendarmemory "github.com/example/iboz/internal/calendar/adapter/memory"
+	"github.com/example/iboz/internal/email"
+	"github.com/example/iboz/internal/email/adapter/memory"
+	"github.com/example/iboz/internal/focus"
+	focusmemory "github.com/example/iboz/internal/focus/adapter/memory"
+)
+
+func TestFocusPlanHandler(t *testing.T) {
+	h := newEmailHandler(t)
+	repo := memory.NewRepository()
+	clock := testClock{now: time.Date(2025, time.March, 18, 12, 0, 0, 0, time.UTC)}
+	received := clock.now.Add(-time.Hour)
+	if err := repo.SaveMessages(context.Background(), []email.EmailMessage{
+		{ID: "msg-1", Subject: "Contract renewal", Sender: "legal@customer.example", Category: "action", ReceivedAt: received},
+		{ID: "msg-2", Subject: "Contract signature", Sender: "legal@customer.example", Category: "action", ReceivedAt: received},
+		{ID: "msg-3", Subject: "Weekly roundup", Sender: "news@letters.example", Category: "newsletter", ReceivedAt: received},
+		{ID: "msg-4", Subject: "Unsorted", Sender: "someone@else.example", ReceivedAt: received},
+	}, clock.now); err != nil {
+		t.Fatalf("save messages: %v", err)
+	}
+	calendars := calendar.NewService(calendarmemory.NewRepository(), repo, calendar.DefaultHours(), clock)
+	h.focus = focus.NewPlanner(focusmemory.NewRepository(), repo, calendars, nil, nil, clock, focus.Config{})
+
+	ctx, rec := newContext(http.MethodGet, "/api/focus/plan", nil)
+	if err := h.focusPlanHandler(ctx); err != nil {
+		t.Fatalf("focus plan handler returned error: %v", err)
+	}
+	if rec.Code != http.StatusOK {
+		t.Fatalf("unexpected status: %d", rec.Code)
+	}
+
+	resp := decodeBody[map[string]any](t, rec)
+	if _, err := time.Parse(time.RFC3339, resp["date"].(string)); err != nil {
+		t.Fatalf("date was not RFC3339: %v", err)
+	}
+	sessions, ok := resp["sessions"].([]any)
+	if !ok || len(sessions) != 2 {
+		t.Fatalf("expected two sessions, got %v", resp["sessions"])
+	}
+	first := sessions[0].(map[string]any)
+	if first["emails"].(float64) != 2 || first["llmSupport"] != true || first["start"] != "2025-03-18T12:00:00Z" {
+		t.Fatalf("unexpected first session: %v", first)
+	}
+	for _, key := range []string{"id", "label", "estimated", "description"} {
+		if _, ok := first[key]; !ok {
+			t.Fatalf("session is missing %q: %v", key, first)
+		}
+	}
+
+	metrics, ok := resp["metrics"].(map[string]any)
+	if !ok || metrics["goal"].(float64) != focus.DefaultGoal {
+		t.Fatalf("unexpected metrics: %v", resp["metrics"])
+	}
+	controls, ok := resp["controls"].(map[string]any)
+	if !ok || len(controls) == 0 {
+		t.Fatalf("controls missing or empty: %v", resp["controls"])
+	}
+
+	ctx, rec = newContext(http.MethodGet, "/api/focus/history?days=7", nil)
+	if err := h.focusHistoryHandler(ctx); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("unexpected history response: %d (%v)", rec.Code, err)
+	}
+	history := decodeBody[map[string]any](t, rec)
+	days, ok := history["days"].([]any)
+	if !ok || len(days) != 7 || days[6].(map[string]any)["date"] != "2025-03-18" || history["timezone"] != "UTC" {
+		t.Fatalf("unexpected history: %v", history)
+	}
+	ctx, rec = newContext(http.MethodGet, "/api/focus/history", nil)
+	if err := h.focusHistoryHandler(ctx); err != nil || len(decodeBody[map[string]any](t, rec)["days"].([]any)) != focus.DefaultHistoryDays {
+		t.Fatalf("expected %d days by default (%v)", focus.DefaultHistoryDays, err)
+	}
+	ctx, rec = newContext(http.MethodGet, "/api/focus/history?days=abc", nil)
+	if err := h.focusHistoryHandler(ctx); err != nil || rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for invalid days, got %d (%v)", rec.Code, err)
+	}
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	ctx, rec = newContext(http.MethodGet, "/api/focus/plan", nil)
+	ctx.SetRequest(ctx.Request().WithContext(cancelled))
+	if err := h.focusPlanHandler(ctx); err != nil || rec.Code != http.StatusRequestTimeout {
+		t.Fatalf("expected 408 for cancelled request, got %d (%v)", rec.Code, err)
+	}
+}
+
+func TestFocusSessionHandlers(t *testing.T) {
+	h := newEmailHandler(t)
+	repo := memory.NewRepository()
+	clock := testClock{now: time.Date(2025, time.March, 18, 12, 0, 0, 0, time.UTC)}
+	if err := repo.SaveMessages(context.Background(), []email.EmailMessage{
+		{ID: "msg-1", Subject: "Contract renewal", Sender: "legal@customer.example", Category: "action"},
+		{ID: "msg-2", Subject: "Weekly roundup", Sender: "news@letters.example", Category: "newsletter"},
+	}, clock.now); err != nil {
+		t.Fatalf("save messages: %v", err)
+	}
+	calendars := calendar.NewService(calendarmemory.NewRepository(), repo, calendar.DefaultHours(), clock)
+	focusRepo := focusmemory.NewRepository()
+	h.focus = focus.NewPlanner(focusRepo, repo, calendars, nil, nil, clock, focus.Config{})
+	h.focusSessions = focus.NewService(focusRepo, repo, h.focus, clock)
+
+	ctx, rec := newContext(http.MethodPost, "/api/focus/sessions", bytes.NewBufferString(`{"messageIds":["msg-1","msg-2"]}`))
+	if err := h.startFocusSessionHandler(ctx); err != nil || rec.Code != http.StatusCreated {
+		t.Fatalf("start session: %v (%d: %s)", err, rec.Code, rec.Body.String())
+	}
+	started := decodeBody[focus.Session](t, rec)
+	if started.Status != focus.StatusActive || started.Progress.Remaining != 2 {
+		t.Fatalf("unexpected session: %+v", started)
+	}
+
+	ctx, rec = newContext(http.MethodPost, "/api/focus/sessions", bytes.NewBufferString(`{"messageIds":["msg-2"]}`))
+	if err := h.startFocusSessionHandler(ctx); err != nil || rec.Code != http.StatusConflict {
+		t.Fatalf("expected 409 for a second session, got %d (%v)", rec.Code, err)
+	}
+
+	mark := func(messageID, state string) *httptest.ResponseRecorder {
+		t.Helper()
+		ctx, rec := newContext(http.MethodPost, "/api/focus/sessions/"+started.ID+"/messages/"+messageID, bytes.NewBufferString(`{"state":"`+state+`"}`))
+		ctx.SetParamNames("id", "messageId")
+		ctx.SetParamValues(started.ID, messageID)
+		if err := h.markFocusItemHandler(ctx); err != nil {
+			t.Fatalf("mark handler error: %v", err)
+		}
+		return rec
+	}
+	if rec := mark("msg-1", "handled"); rec.Code != http.StatusOK {
+		t.Fatalf("mark handled: %d (%s)", rec.Code, rec.Body.String())
+	}
+	if rec := mark("msg-2", "archived"); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an unknown state, got %d", rec.Code)
+	}
+
+	ctx, rec = newContext(http.MethodPost, "/api/focus/sessions/"+started.ID+"/pause", nil)
+	withParam(ctx, started.ID)
+	if err := h.pauseFocusSessionHandler(ctx); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("pause session: %v (%d)", err, rec.Code)
+	}
+	if rec := mark("msg-2", "skipped"); rec.Code != http.StatusConflict {
+		t.Fatalf("expected 409 while paused, got %d", rec.Code)
+	}
+
+	ctx, rec = newContext(http.MethodGet, "/api/focus/sessions/current", nil)
+	if err := h.currentFocusSessionHandler(ctx); err != nil {
+		t.Fatalf("current session handler error: %v", err)
+	}
+	if current := decodeBody[map[string]*focus.Session](t, rec)["session"]; current == nil || current.Status != focus.StatusPaused {
+		t.Fatalf("expected the paused session, got %+v", current)
+	}
+
+	ctx, rec = newContext(http.MethodPost, "/api/focus/sessions/"+started.ID+"/complete", nil)
+	withParam(ctx, started.ID)
+	if err := h.completeFocusSessionHandler(ctx); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("complete session: %v (%d)", err, rec.Code)
+	}
+	completed := decodeBody[focus.Session](t, rec)
+	if completed.Summary == nil || completed.Summary.Progress.Handled != 1 || len(completed.Summary.FollowUps) != 2 {
+		t.Fatalf("unexpected summary: %+v", completed.Summary)
+	}
+
+	ctx, rec = newContext(http.MethodPost, "/api/focus/sessions/"+started.ID+"/resume", nil)
+	withParam(ctx, started.ID)
+	if err := h.resumeFocusSessionHandler(ctx); err != nil || rec.Code != http.StatusConflict {
+		t.Fatalf("expected 409 resuming a completed session, got %d (%v)", rec.Code, err)
+	}
+
+	ctx, rec = newContext(http.MethodGet, "/api/focus/sessions/missing", nil)
+	withParam(ctx, "missing")
+	if err := h.getFocusSessionHandler(ctx); err != nil || rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for a missing session, got %d (%v)", rec.Code, err)
+	}
+
+	ctx, rec = newContext(http.MethodGet, "/api/focus/sessions", nil)
+	if err := h.listFocusSessionsHandler(ctx); err != nil {
+		t.Fatalf("list sessions handler error: %v", err)
+	}
+	if list := decodeBody[map[string][]focus.Session](t, rec); len(list["sessions"]) != 1 {
+		t.Fatalf("expected one session, got %+v", list)
+	}
+}
+
+func TestFocusSettingsHandlers(t *testing.T) {
+	h := newEmailHandler(t)
+
+	ctx, rec := newContext(http.MethodGet, "/api/focus/settings", nil)
+	if err := h.getFocusSettingsHandler(ctx); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("get settings: %v (%d)", err, rec.Code)
+	}
+	if settings := decodeBody[focus.Settings](t, rec); !settings.Controls.NotificationsMuted || len(settings.Policy.Importance) == 0 {
+		t.Fatalf("expected default settings, got %+v", settings)
+	}
+
+	ctx, rec = newContext(http.MethodPut, "/api/focus/settings", bytes.NewBufferString(`{"controls":{"notificationsMuted":false}}`))
+	if err := h.updateFocusSettingsHandler(ctx); err != nil || rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 without an authenticated mailbox, got %d (%v)", rec.Code, err)
+	}
+
+	ctx, rec = newContext(http.MethodGet, "/api/focus/held", nil)
+	if err := h.listHeldNotificationsHandler(ctx); err != nil {
+		t.Fatalf("held handler error: %v", err)
+	}
+	if held := decodeBody[map[string][]focus.Held](t, rec); len(held["held"]) != 0 {
+		t.Fatalf("expected nothing held, got %+v", held)
+	}
+}
@@ -0,0 +1,103 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/example/iboz/internal/email"
+	"github.com/example/iboz/internal/push"
+)
+
+const maxPushNotificationBytes = 1 << 20
+
+func (h handler) registerPushRoutes(g *echo.Group) {
+	pg := g.Group("/push")
+	pg.POST("/graph", h.graphPushHandler)
+	pg.POST("/gmail", h.gmailPushHandler)
+	pg.GET("/subscriptions", h.listPushSubscriptionsHandler)
+	pg.POST("/subscriptions", h.createPushSubscriptionHandler)
+	pg.POST("/subscriptions/:id/renew", h.renewPushSubscriptionHandler)
+	pg.DELETE("/subscriptions/:id", h.deletePushSubscriptionHandler)
+}
+
+// graphPushHandler answers Graph's endpoint validation by echoing the token as
+// plain text, and acknowledges notification batches with 202.
+func (h handler) graphPushHandler(c echo.Context) error {
+	if token := c.QueryParam("validationToken"); token != "" {
+		return c.String(http.StatusOK, token)
+	}
+	body, err := io.ReadAll(io.LimitReader(c.Request().Body, maxPushNotificationBytes))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid notification payload"})
+	}
+	queued, err := h.push.HandleGraph(c.Request().Context(), body)
+	if err != nil {
+		return pushError(c, err)
+	}
+	return c.JSON(http.StatusAccepted, map[string]int{"queued": queued})
+}
+
+// gmailPushHandler acknowledges a Pub/Sub push; any 2xx stops redelivery.
+func (h handler) gmailPushHandler(c echo.Context) error {
+	body, err := io.ReadAll(io.LimitReader(c.Request().Body, maxPushNotificationBytes))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid notification payload"})
+	}
+	queued, err := h.push.HandleGmail(c.Request().Context(), c.QueryParam("token"), body)
+	if err != nil {
+		return pushError(c, err)
+	}
+	return c.JSON(http.StatusAccepted, map[string]int{"queued": queued})
+}
+
+func (h handler) listPushSubscriptionsHandler(c echo.Context) error {
+	list, err := h.push.List(c.Request().Context())
+	if err != nil {
+		return pushError(c, err)
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{"subscriptions": list})
+}
+
+func (h handler) createPushSubscriptionHandler(c echo.Context) error {
+	subscription, err := h.push.Subscribe(c.Request().Context())
+	if err != nil {
+		return pushError(c, err)
+	}
+	return c.JSON(http.StatusCreated, subscription)
+}
+
+func (h handler) renewPushSubscriptionHandler(c echo.Context) error {
+	subscription, err := h.push.Renew(c.Request().Context(), c.Param("id"))
+	if err != nil {
+		return pushError(c, err)
+	}
+	return c.JSON(http.StatusOK, subscription)
+}
+
+func (h handler) deletePushSubscriptionHandler(c echo.Context) error {
+	if err := h.push.Unsubscribe(c.Request().Context(), c.Param("id")); err != nil {
+		return pushError(c, err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+func pushError(c echo.Context, err error) error {
+	status := http.StatusBadGateway
+	switch {
+	case errors.Is(err, push.ErrSubscriptionNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, push.ErrUnauthorized):
+		status = http.StatusUnauthorized
+	case errors.Is(err, push.ErrInvalidNotification):
+		status = http.StatusBadRequest
+	case errors.Is(err, push.ErrUnsupportedProvider), errors.Is(err, email.ErrProviderNotConfigured), errors.Is(err, email.ErrProviderNotAuthenticated):
+		status = http.StatusConflict
+	case errors.Is(err, context.Canceled):
+		status = http.StatusRequestTimeout
+	}
+	return c.JSON(status, map[string]string{"error": err.Error()})
+}
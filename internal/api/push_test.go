@@ -0,0 +1,81 @@
+package api
+
+import (
+	"bytes"
+	"net/http"
+	"testing"
+
+	"github.com/example/iboz/internal/push"
+)
+
+func TestGraphPushHandlerEchoesValidationToken(t *testing.T) {
+	h := newEmailHandler(t)
+
+	ctx, rec := newContext(http.MethodPost, "/api/email/push/graph?validationToken=Validation%3A+Testing", nil)
+	if err := h.graphPushHandler(ctx); err != nil {
+		t.Fatalf("graph push handler error: %v", err)
+	}
+	if rec.Code != http.StatusOK || rec.Body.String() != "Validation: Testing" {
+		t.Fatalf("unexpected validation response: %d %q", rec.Code, rec.Body.String())
+	}
+	if contentType := rec.Header().Get("Content-Type"); contentType != "text/plain; charset=UTF-8" {
+		t.Fatalf("unexpected content type %q", contentType)
+	}
+
+	ctx, rec = newContext(http.MethodPost, "/api/email/push/graph", bytes.NewBufferString(`{"value":[]}`))
+	if err := h.graphPushHandler(ctx); err != nil {
+		t.Fatalf("graph push handler error: %v", err)
+	}
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("unexpected status: %d (%s)", rec.Code, rec.Body.String())
+	}
+
+	ctx, rec = newContext(http.MethodPost, "/api/email/push/graph", bytes.NewBufferString(`not json`))
+	if err := h.graphPushHandler(ctx); err != nil {
+		t.Fatalf("graph push handler error: %v", err)
+	}
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("unexpected status: %d (%s)", rec.Code, rec.Body.String())
+	}
+}
+
+func TestGmailPushHandlerRequiresToken(t *testing.T) {
+	h := newEmailHandler(t)
+
+	ctx, rec := newContext(http.MethodPost, "/api/email/push/gmail?token=guess", bytes.NewBufferString(`{"message":{"data":""}}`))
+	if err := h.gmailPushHandler(ctx); err != nil {
+		t.Fatalf("gmail push handler error: %v", err)
+	}
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("unexpected status: %d (%s)", rec.Code, rec.Body.String())
+	}
+}
+
+func TestPushSubscriptionHandlers(t *testing.T) {
+	h := newEmailHandler(t)
+
+	ctx, rec := newContext(http.MethodPost, "/api/email/push/subscriptions", nil)
+	if err := h.createPushSubscriptionHandler(ctx); err != nil {
+		t.Fatalf("create subscription handler error: %v", err)
+	}
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("expected conflict without a configured provider, got %d (%s)", rec.Code, rec.Body.String())
+	}
+
+	ctx, rec = newContext(http.MethodGet, "/api/email/push/subscriptions", nil)
+	if err := h.listPushSubscriptionsHandler(ctx); err != nil {
+		t.Fatalf("list subscriptions handler error: %v", err)
+	}
+	if list := decodeBody[map[string][]push.Subscription](t, rec); len(list["subscriptions"]) != 0 {
+		t.Fatalf("expected no subscriptions, got %+v", list)
+	}
+
+	ctx, rec = newContext(http.MethodDelete, "/api/email/push/subscriptions/push-missing", nil)
+	withParam(ctx, "push-missing")
+	if err := h.deletePushSubscriptionHandler(ctx); err != nil {
+		t.Fatalf("delete subscription handler error: %v", err)
+	}
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("unexpected status: %d (%s)", rec.Code, rec.Body.String())
+	}
+}
@@ -0,0 +1,56 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/example/iboz/internal/queue"
+)
+
+func (h handler) registerQueueRoutes(g *echo.Group) {
+	qg := g.Group("/queue")
+	qg.GET("/dead-letters", h.listDeadLettersHandler)
+	qg.POST("/dead-letters/:id/retry", h.retryDeadLetterHandler)
+	qg.DELETE("/dead-letters/:id", h.deleteDeadLetterHandler)
+}
+
+func (h handler) listDeadLettersHandler(c echo.Context) error {
+	list, err := h.queue.DeadLetters(c.Request().Context())
+	if err != nil {
+		return queueError(c, err)
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{"deadLetters": list})
+}
+
+func (h handler) retryDeadLetterHandler(c echo.Context) error {
+	msg, err := h.queue.RetryDeadLetter(c.Request().Context(), c.Param("id"))
+	if err != nil {
+		return queueError(c, err)
+	}
+	return c.JSON(http.StatusAccepted, msg)
+}
+
+func (h handler) deleteDeadLetterHandler(c echo.Context) error {
+	if err := h.queue.DeleteDeadLetter(c.Request().Context(), c.Param("id")); err != nil {
+		return queueError(c, err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+func queueError(c echo.Context, err error) error {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, queue.ErrDeadLetterNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, queue.ErrInvalidMessage):
+		status = http.StatusBadRequest
+	case errors.Is(err, queue.ErrClosed):
+		status = http.StatusServiceUnavailable
+	case errors.Is(err, context.Canceled):
+		status = http.StatusRequestTimeout
+	}
+	return c.JSON(status, map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,71 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/example/iboz/internal/queue"
+	"github.com/example/iboz/internal/queue/adapter/inproc"
+	queuememory "github.com/example/iboz/internal/queue/adapter/memory"
+)
+
+func TestDeadLetterHandlers(t *testing.T) {
+	h := newEmailHandler(t)
+	repo := queuememory.NewRepository()
+	broker := inproc.NewBroker(0)
+	h.queue = queue.NewService(broker, repo, testClock{now: time.Date(2025, time.March, 18, 12, 0, 0, 0, time.UTC)}, queue.Config{})
+
+	for _, id := range []string{"job-retry", "job-drop"} {
+		letter := queue.DeadLetter{
+			Message:  queue.Message{ID: id, Topic: "email.synced", Attempt: 5},
+			Error:    "provider unavailable",
+			FailedAt: time.Date(2025, time.March, 18, 11, 0, 0, 0, time.UTC),
+		}
+		if err := repo.SaveDeadLetter(context.Background(), letter); err != nil {
+			t.Fatalf("save dead letter: %v", err)
+		}
+	}
+
+	ctx, rec := newContext(http.MethodGet, "/api/queue/dead-letters", nil)
+	if err := h.listDeadLettersHandler(ctx); err != nil {
+		t.Fatalf("list dead letters handler error: %v", err)
+	}
+	listed := decodeBody[map[string][]queue.DeadLetter](t, rec)
+	if len(listed["deadLetters"]) != 2 {
+		t.Fatalf("expected two dead letters, got %+v", listed)
+	}
+
+	ctx, rec = newContext(http.MethodPost, "/api/queue/dead-letters/job-retry/retry", nil)
+	withParam(ctx, "job-retry")
+	if err := h.retryDeadLetterHandler(ctx); err != nil {
+		t.Fatalf("retry handler error: %v", err)
+	}
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("unexpected status: %d (%s)", rec.Code, rec.Body.String())
+	}
+	if retried := decodeBody[queue.Message](t, rec); retried.Attempt != 1 {
+		t.Fatalf("expected a fresh attempt budget, got %+v", retried)
+	}
+	sub, _ := broker.Subscribe("email.synced", queue.Group)
+	select {
+	case delivery := <-sub.Deliveries():
+		if msg := delivery.Message(); msg.ID != "job-retry" {
+			t.Fatalf("unexpected republished message: %+v", msg)
+		}
+	default:
+		t.Fatal("expected the dead letter to be republished")
+	}
+
+	for _, want := range []int{http.StatusNoContent, http.StatusNotFound} {
+		ctx, rec = newContext(http.MethodDelete, "/api/queue/dead-letters/job-drop", nil)
+		withParam(ctx, "job-drop")
+		if err := h.deleteDeadLetterHandler(ctx); err != nil {
+			t.Fatalf("delete handler error: %v", err)
+		}
+		if rec.Code != want {
+			t.Fatalf("expected %d, got %d", want, rec.Code)
+		}
+	}
+}
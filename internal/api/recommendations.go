@@ -0,0 +1,39 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+)
+
+// dashboardRecommendations is the number of recommendations on the dashboard.
+const dashboardRecommendations = 3
+
+// recommendationsHandler returns ?limit= recommendations, all of them by
+// default. Each carries the automation template it would create.
+func (h handler) recommendationsHandler(c echo.Context) error {
+	limit := 0
+	if raw := c.QueryParam("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 1 {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "limit must be a positive integer"})
+		}
+		limit = parsed
+	}
+	list, err := h.recommendations.Recommend(c.Request().Context(), limit)
+	if err != nil {
+		return recommendationsError(c, err)
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{"recommendations": list})
+}
+
+func recommendationsError(c echo.Context, err error) error {
+	status := http.StatusInternalServerError
+	if errors.Is(err, context.Canceled) {
+		status = http.StatusRequestTimeout
+	}
+	return c.JSON(status, map[string]string{"error": err.Error()})
+}
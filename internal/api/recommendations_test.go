@@ -0,0 +1,64 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/example/iboz/internal/email"
+	"github.com/example/iboz/internal/email/adapter/memory"
+	"github.com/example/iboz/internal/recommendations"
+)
+
+func TestRecommendationsHandler(t *testing.T) {
+	h := newEmailHandler(t)
+	repo := memory.NewRepository()
+	clock := testClock{now: time.Date(2025, time.March, 18, 12, 0, 0, 0, time.UTC)}
+	var messages []email.EmailMessage
+	for i := 0; i < 4; i++ {
+		messages = append(messages,
+			email.EmailMessage{ID: fmt.Sprintf("news-%d", i), Subject: fmt.Sprintf("Issue %d", i), Sender: "news@letters.example", Category: "newsletter", Labels: []string{"INBOX", "UNREAD"}, ReceivedAt: clock.now.Add(-time.Duration(i+1) * time.Hour)},
+			email.EmailMessage{ID: fmt.Sprintf("alert-%d", i), Subject: "Disk alert", Sender: "alerts@monitoring.example", Category: "updates", ReceivedAt: clock.now.Add(-time.Duration(i+1) * 24 * time.Hour)},
+		)
+	}
+	if err := repo.SaveMessages(context.Background(), messages, clock.now); err != nil {
+		t.Fatalf("save messages: %v", err)
+	}
+	h.recommendations = recommendations.NewEngine(repo, clock, recommendations.Config{})
+
+	ctx, rec := newContext(http.MethodGet, "/api/automations/recommendations", nil)
+	if err := h.recommendationsHandler(ctx); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("recommendations handler: %v (%d)", err, rec.Code)
+	}
+	list := decodeBody[map[string][]map[string]any](t, rec)["recommendations"]
+	if len(list) != 2 || list[0]["kind"] != "archive" || list[1]["kind"] != "digest" {
+		t.Fatalf("unexpected recommendations: %v", list)
+	}
+	automation := list[0]["automation"].(map[string]any)
+	if automation["id"] != list[0]["id"] || automation["trigger"] != "sender: alerts@monitoring.example" {
+		t.Fatalf("automation payload does not match the recommendation: %v", automation)
+	}
+
+	ctx, rec = newContext(http.MethodGet, "/api/automations/recommendations?limit=1", nil)
+	if err := h.recommendationsHandler(ctx); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("recommendations handler: %v (%d)", err, rec.Code)
+	}
+	if limited := decodeBody[map[string][]map[string]any](t, rec)["recommendations"]; len(limited) != 1 || limited[0]["id"] != list[0]["id"] {
+		t.Fatalf("expected the best recommendation only, got %v", limited)
+	}
+
+	ctx, rec = newContext(http.MethodGet, "/api/automations/recommendations?limit=0", nil)
+	if err := h.recommendationsHandler(ctx); err != nil || rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an invalid limit, got %d (%v)", rec.Code, err)
+	}
+
+	ctx, rec = newContext(http.MethodGet, "/api/dashboard", nil)
+	if err := h.dashboardHandler(ctx); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("dashboard handler: %v (%d)", err, rec.Code)
+	}
+	if dashboard := decodeBody[map[string]any](t, rec)["recommendations"].([]any); len(dashboard) != 2 {
+		t.Fatalf("expected the mined recommendations on the dashboard, got %v", dashboard)
+	}
+}
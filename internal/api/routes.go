@@ -5,35 +5,192 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/labstack/echo/v4"
 
+	"github.com/example/iboz/internal/bulk"
+	"github.com/example/iboz/internal/calendar"
+	"github.com/example/iboz/internal/crm"
+	"github.com/example/iboz/internal/dashboard"
+	"github.com/example/iboz/internal/delegation"
+	"github.com/example/iboz/internal/digest"
 	"github.com/example/iboz/internal/email"
+	"github.com/example/iboz/internal/focus"
+	"github.com/example/iboz/internal/push"
+	"github.com/example/iboz/internal/queue"
+	"github.com/example/iboz/internal/recommendations"
+	"github.com/example/iboz/internal/schedule"
+	"github.com/example/iboz/internal/sla"
+	"github.com/example/iboz/internal/snooze"
+	"github.com/example/iboz/internal/tasks"
+	"github.com/example/iboz/internal/templates"
+	"github.com/example/iboz/internal/waiting"
+	"github.com/example/iboz/internal/webhooks"
 )
 
 type handler struct {
-	emailService email.ProviderService
+	emailService    email.ProviderService
+	replies         email.ReplyService
+	templates       templates.TemplateService
+	snoozes         snooze.SnoozeService
+	delegations     delegation.DelegationService
+	waiting         waiting.TrackerService
+	slaEngine       sla.SLAService
+	calendars       calendar.CalendarService
+	schedules       schedule.ScheduleService
+	queue           queue.QueueService
+	tasks           tasks.TaskService
+	webhooks        webhooks.WebhookService
+	push            push.PushService
+	crm             crm.CRMService
+	focus           focus.PlannerService
+	focusSessions   focus.SessionService
+	dashboard       dashboard.DashboardService
+	recommendations recommendations.RecommendationService
+	digests         digest.DigestService
+	unsubscribes    bulk.UnsubscribeService
+}
+
+// Dependencies bundles the application services exposed over HTTP.
+type Dependencies struct {
+	Email           email.ProviderService
+	Replies         email.ReplyService
+	Templates       templates.TemplateService
+	Snoozes         snooze.SnoozeService
+	Delegations     delegation.DelegationService
+	Waiting         waiting.TrackerService
+	SLA             sla.SLAService
+	Calendar        calendar.CalendarService
+	Schedules       schedule.ScheduleService
+	Queue           queue.QueueService
+	Tasks           tasks.TaskService
+	Webhooks        webhooks.WebhookService
+	Push            push.PushService
+	CRM             crm.CRMService
+	Focus           focus.PlannerService
+	FocusSessions   focus.SessionService
+	Dashboard       dashboard.DashboardService
+	Recommendations recommendations.RecommendationService
+	Digests         digest.DigestService
+	Unsubscribes    bulk.UnsubscribeService
 }
 
 // Register wires the API routes to the provided echo group.
-func Register(g *echo.Group, emailService email.ProviderService) {
-	if emailService == nil {
+func Register(g *echo.Group, deps Dependencies) {
+	if deps.Email == nil {
 		panic("api: email service dependency is required")
 	}
-	h := handler{emailService: emailService}
+	if deps.Replies == nil {
+		panic("api: reply service dependency is required")
+	}
+	if deps.Templates == nil {
+		panic("api: template service dependency is required")
+	}
+	if deps.Snoozes == nil {
+		panic("api: snooze service dependency is required")
+	}
+	if deps.Delegations == nil {
+		panic("api: delegation service dependency is required")
+	}
+	if deps.Waiting == nil {
+		panic("api: waiting tracker dependency is required")
+	}
+	if deps.SLA == nil {
+		panic("api: sla service dependency is required")
+	}
+	if deps.Calendar == nil {
+		panic("api: calendar service dependency is required")
+	}
+	if deps.Schedules == nil {
+		panic("api: schedule service dependency is required")
+	}
+	if deps.Queue == nil {
+		panic("api: queue service dependency is required")
+	}
+	if deps.Tasks == nil {
+		panic("api: task service dependency is required")
+	}
+	if deps.Webhooks == nil {
+		panic("api: webhook service dependency is required")
+	}
+	if deps.Push == nil {
+		panic("api: push service dependency is required")
+	}
+	if deps.CRM == nil {
+		panic("api: crm service dependency is required")
+	}
+	if deps.Focus == nil {
+		panic("api: focus planner dependency is required")
+	}
+	if deps.FocusSessions == nil {
+		panic("api: focus session service dependency is required")
+	}
+	if deps.Dashboard == nil {
+		panic("api: dashboard service dependency is required")
+	}
+	if deps.Recommendations == nil {
+		panic("api: recommendation service dependency is required")
+	}
+	if deps.Digests == nil {
+		panic("api: digest service dependency is required")
+	}
+	if deps.Unsubscribes == nil {
+		panic("api: unsubscribe service dependency is required")
+	}
+	h := handler{
+		emailService:    deps.Email,
+		replies:         deps.Replies,
+		templates:       deps.Templates,
+		snoozes:         deps.Snoozes,
+		delegations:     deps.Delegations,
+		waiting:         deps.Waiting,
+		slaEngine:       deps.SLA,
+		calendars:       deps.Calendar,
+		schedules:       deps.Schedules,
+		queue:           deps.Queue,
+		tasks:           deps.Tasks,
+		webhooks:        deps.Webhooks,
+		push:            deps.Push,
+		crm:             deps.CRM,
+		focus:           deps.Focus,
+		focusSessions:   deps.FocusSessions,
+		dashboard:       deps.Dashboard,
+		recommendations: deps.Recommendations,
+		digests:         deps.Digests,
+		unsubscribes:    deps.Unsubscribes,
+	}
 
 	g.GET("/health", healthHandler)
-	g.GET("/dashboard", dashboardHandler)
-	g.GET("/focus/plan", focusPlanHandler)
-	g.GET("/automations", automationsHandler)
-	g.POST("/automations/test-run", automationTestRunHandler)
+	g.GET("/dashboard", h.dashboardHandler)
+	g.GET("/automations", h.automationsHandler)
+	g.GET("/automations/recommendations", h.recommendationsHandler)
+	g.POST("/automations/test-run", h.automationTestRunHandler)
 
 	emailGroup := g.Group("/email")
 	emailGroup.GET("/provider", h.emailProviderStateHandler)
 	emailGroup.POST("/provider", h.emailProviderConfigureHandler)
 	emailGroup.POST("/provider/authenticate", h.emailProviderAuthenticateHandler)
 	emailGroup.GET("/messages", h.emailFetchMessagesHandler)
+	emailGroup.GET("/messages/:id", h.emailMessageHandler)
+	emailGroup.POST("/messages/:id/reply", h.emailReplyHandler)
+	h.registerPushRoutes(emailGroup)
+
+	h.registerTemplateRoutes(g)
+	h.registerSnoozeRoutes(g)
+	h.registerDelegationRoutes(g)
+	h.registerWaitingRoutes(g)
+	h.registerSLARoutes(g)
+	h.registerCalendarRoutes(g)
+	h.registerScheduledActionRoutes(g)
+	h.registerQueueRoutes(g)
+	h.registerTaskRoutes(g)
+	h.registerCRMRoutes(g)
+	h.registerFocusRoutes(g)
+	h.registerDigestRoutes(g)
+	h.registerUnsubscribeRoutes(g)
+	h.registerWebhookRoutes(g)
 }
 
 func healthHandler(c echo.Context) error {
@@ -43,14 +200,30 @@ func healthHandler(c echo.Context) error {
 	})
 }
 
-func dashboardHandler(c echo.Context) error {
+func (h handler) dashboardHandler(c echo.Context) error {
+	summary, err := h.dashboard.Summary(c.Request().Context())
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, context.Canceled) {
+			status = http.StatusRequestTimeout
+		}
+		return c.JSON(status, map[string]string{"error": err.Error()})
+	}
+	delegated, err := h.delegations.OpenCount(c.Request().Context())
+	if err != nil {
+		return delegationError(c, err)
+	}
+	awaiting, err := h.waiting.WaitingCount(c.Request().Context())
+	if err != nil {
+		return waitingError(c, err)
+	}
+	recommended, err := h.recommendations.Recommend(c.Request().Context(), dashboardRecommendations)
+	if err != nil {
+		return recommendationsError(c, err)
+	}
+
 	payload := map[string]interface{}{
-		"summary": map[string]interface{}{
-			"inboxZeroTarget":  10,
-			"currentInbox":     7,
-			"automationRate":   0.68,
-			"timeSavedMinutes": 125,
-		},
+		"summary": summary,
 		"focusSessions": []map[string]interface{}{
 			{
 				"id":          "focus-urgent",
@@ -90,120 +263,112 @@ func dashboardHandler(c echo.Context) error {
 				"id":          "waiting",
 				"label":       "Waiting",
 				"description": "Awaiting responses from others",
-				"count":       4,
+				"count":       awaiting,
 				"llmEnabled":  false,
 			},
 			{
 				"id":          "delegated",
 				"label":       "Delegated",
 				"description": "Assigned to teammates with SLA tracking",
-				"count":       5,
+				"count":       delegated,
 				"llmEnabled":  false,
 			},
 		},
-		"recommendations": []map[string]interface{}{
-			{
-				"id":          "digest",
-				"title":       "Send weekly digest",
-				"description": "Snooze newsletters into a Friday recap.",
-				"confidence":  0.87,
-			},
-			{
-				"id":          "delegate-contract",
-				"title":       "Delegate contract review",
-				"description": "Assign to legal ops and request update in 2 days.",
-				"confidence":  0.73,
-			},
-		},
+		"recommendations": recommended,
 	}
 
 	return c.JSON(http.StatusOK, payload)
 }
 
-func focusPlanHandler(c echo.Context) error {
-	payload := map[string]interface{}{
-		"date": time.Now().UTC().Format(time.RFC3339),
-		"sessions": []map[string]interface{}{
-			{
-				"id":          "focus-urgent",
-				"label":       "Urgent Action",
-				"estimated":   45,
-				"emails":      4,
-				"llmSupport":  true,
-				"description": "Batch handle urgent client escalations with recommended replies.",
-			},
-			{
-				"id":          "focus-followup",
-				"label":       "Follow-Up",
-				"estimated":   30,
-				"emails":      6,
-				"llmSupport":  false,
-				"description": "Send nudges on pending approvals and delegated tasks.",
-			},
-		},
-		"metrics": map[string]interface{}{
-			"clearedToday": 18,
-			"streak":       4,
-			"goal":         3,
-		},
-		"controls": map[string]interface{}{
-			"notificationsMuted": true,
-			"batchingEnabled":    true,
-			"autoSummaries":      true,
-		},
+// automationsHandler lists the automation templates. Each template whose
+// trigger has time conditions reports whether they hold on the user's calendar.
+func (h handler) automationsHandler(c echo.Context) error {
+	cal, err := h.calendars.Calendar(c.Request().Context())
+	if err != nil {
+		return calendarError(c, err)
 	}
 
-	return c.JSON(http.StatusOK, payload)
-}
+	templates := automationTemplates()
+	for _, template := range templates {
+		met, ok, err := timeConditionsMet(cal, template["trigger"].(string))
+		if err != nil {
+			return calendarError(c, err)
+		}
+		if ok {
+			template["timeConditionMet"] = met
+		}
+	}
 
-func automationsHandler(c echo.Context) error {
 	payload := map[string]interface{}{
 		"overview": map[string]interface{}{
 			"active":             12,
 			"automationCoverage": 0.74,
 			"avgTimeSaved":       32,
 		},
-		"templates": []map[string]interface{}{
-			{
-				"id":          "auto-ack",
-				"name":        "Auto-acknowledge support tickets",
-				"description": "Send branded receipt, create Jira issue, and assign to support queue.",
-				"trigger":     "sender: support@customer.com",
-				"conditions": []string{
-					"subject CONTAINS 'case #'",
-					"attachment.type = 'pdf'",
-				},
-				"actions": []string{
-					"Send template response",
-					"Create Jira ticket",
-					"Label as Waiting",
-				},
-				"requiresApproval": true,
-				"owner":            "Support Operations",
-				"lastRun":          "2025-03-18T10:24:00Z",
+		"templates": templates,
+	}
+
+	return c.JSON(http.StatusOK, payload)
+}
+
+// automationTemplates returns the automation templates, built afresh on every
+// call so handlers can annotate them.
+func automationTemplates() []map[string]interface{} {
+	return []map[string]interface{}{
+		{
+			"id":          "auto-ack",
+			"name":        "Auto-acknowledge support tickets",
+			"description": "Send branded receipt, create Jira issue, and assign to support queue.",
+			"trigger":     "sender: support@customer.com",
+			"conditions": []string{
+				"subject CONTAINS 'case #'",
+				"attachment.type = 'pdf'",
 			},
-			{
-				"id":          "vip-sms",
-				"name":        "VIP Escalation to Slack",
-				"description": "If VIP contacts after hours, alert on-call channel and schedule morning follow-up.",
-				"trigger":     "tag:vip AND time:after_hours",
-				"conditions": []string{
-					"sender IN vip_list",
-					"llm.confidence >= 0.65",
-				},
-				"actions": []string{
-					"Post to #escalations channel",
-					"Create Asana task",
-					"Snooze email until 8am",
-				},
-				"requiresApproval": false,
-				"owner":            "Customer Experience",
-				"lastRun":          "2025-03-18T07:10:00Z",
+			"actions": []string{
+				"Send template response",
+				"Create Jira ticket",
+				"Label as Waiting",
 			},
+			"requiresApproval": true,
+			"owner":            "Support Operations",
+			"lastRun":          "2025-03-18T10:24:00Z",
+		},
+		{
+			"id":          "vip-sms",
+			"name":        "VIP Escalation to Slack",
+			"description": "If VIP contacts after hours, alert on-call channel and schedule morning follow-up.",
+			"trigger":     "tag:vip AND time:after_hours",
+			"conditions": []string{
+				"sender IN vip_list",
+				"llm.confidence >= 0.65",
+			},
+			"actions": []string{
+				"Post to #escalations channel",
+				"Create Asana task",
+				"Snooze email until 8am",
+			},
+			"requiresApproval": false,
+			"owner":            "Customer Experience",
+			"lastRun":          "2025-03-18T07:10:00Z",
 		},
 	}
+}
 
-	return c.JSON(http.StatusOK, payload)
+// timeConditionsMet evaluates the time conditions of an AND-joined trigger on
+// cal. It reports false for ok when the trigger has none.
+func timeConditionsMet(cal *calendar.Calendar, trigger string) (met, ok bool, err error) {
+	met = true
+	for _, term := range strings.Split(trigger, " AND ") {
+		if !calendar.IsTimeCondition(term) {
+			continue
+		}
+		holds, err := cal.Holds(term)
+		if err != nil {
+			return false, false, err
+		}
+		met, ok = met && holds, true
+	}
+	return met, ok, nil
 }
 
 type emailProviderStateResponse struct {
@@ -289,6 +454,11 @@ func (h handler) emailFetchMessagesHandler(c echo.Context) error {
 		return c.JSON(status, map[string]string{"error": err.Error()})
 	}
 
+	messages, err = h.snoozes.Visible(c.Request().Context(), messages)
+	if err != nil {
+		return snoozeError(c, err)
+	}
+
 	state, err := h.emailService.State(c.Request().Context())
 	if err != nil {
 		status := http.StatusInternalServerError
@@ -310,6 +480,56 @@ func (h handler) emailFetchMessagesHandler(c echo.Context) error {
 	})
 }
 
+// emailMessageHandler returns a synced message with the task and CRM records linked to it.
+func (h handler) emailMessageHandler(c echo.Context) error {
+	message, err := h.emailService.Message(c.Request().Context(), c.Param("id"))
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, email.ErrMessageNotFound):
+			status = http.StatusNotFound
+		case errors.Is(err, context.Canceled):
+			status = http.StatusRequestTimeout
+		}
+		return c.JSON(status, map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusOK, message)
+}
+
+type emailReplyRequest struct {
+	TextBody string `json:"textBody"`
+	HTMLBody string `json:"htmlBody"`
+}
+
+func (h handler) emailReplyHandler(c echo.Context) error {
+	var req emailReplyRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid reply payload"})
+	}
+
+	sent, err := h.replies.Reply(c.Request().Context(), c.Param("id"), email.ReplyRequest{TextBody: req.TextBody, HTMLBody: req.HTMLBody})
+	if err != nil {
+		return replyError(c, err)
+	}
+
+	return c.JSON(http.StatusAccepted, sent)
+}
+
+func replyError(c echo.Context, err error) error {
+	status := http.StatusBadGateway
+	switch {
+	case errors.Is(err, email.ErrMessageNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, email.ErrSenderNotConfigured):
+		status = http.StatusServiceUnavailable
+	case errors.Is(err, email.ErrProviderNotAuthenticated), errors.Is(err, email.ErrEmptyReply):
+		status = http.StatusBadRequest
+	case errors.Is(err, context.Canceled):
+		status = http.StatusRequestTimeout
+	}
+	return c.JSON(status, map[string]string{"error": err.Error()})
+}
+
 func (h handler) respondWithEmailState(c echo.Context, status int) error {
 	state, err := h.emailService.State(c.Request().Context())
 	if err != nil {
@@ -334,10 +554,15 @@ func (h handler) respondWithEmailState(c echo.Context, status int) error {
 	})
 }
 
-func automationTestRunHandler(c echo.Context) error {
+// automationTestRunHandler simulates a run of a template or of a recommended
+// automation. Nothing is executed, so no webhook events are published; whether
+// a real run would need approval comes from the template when it is listed.
+// A reply template bound to the run must render every variable it references.
+func (h handler) automationTestRunHandler(c echo.Context) error {
 	var input struct {
-		TemplateID string                 `json:"templateId"`
-		Parameters map[string]interface{} `json:"parameters"`
+		TemplateID      string                 `json:"templateId"`
+		ReplyTemplateID string                 `json:"replyTemplateId"`
+		Parameters      map[string]interface{} `json:"parameters"`
 	}
 
 	if err := c.Bind(&input); err != nil {
@@ -347,14 +572,27 @@ func automationTestRunHandler(c echo.Context) error {
 	if input.TemplateID == "" {
 		return c.JSON(http.StatusBadRequest, map[string]string{"error": "templateId is required"})
 	}
+	if input.ReplyTemplateID != "" {
+		if err := h.templates.Attachable(c.Request().Context(), input.ReplyTemplateID); err != nil {
+			return templateError(c, err)
+		}
+	}
+
+	requiresApproval := false
+	for _, template := range automationTemplates() {
+		if template["id"] == input.TemplateID {
+			requiresApproval = template["requiresApproval"].(bool)
+		}
+	}
 
 	response := map[string]interface{}{
-		"templateId": input.TemplateID,
-		"status":     "simulated",
-		"summary":    "Automation would execute 3 actions with estimated savings of 12 minutes.",
-		"parameters": input.Parameters,
+		"templateId":      input.TemplateID,
+		"replyTemplateId": input.ReplyTemplateID,
+		"status":          "simulated",
+		"summary":         "Automation would execute 3 actions with estimated savings of 12 minutes.",
+		"parameters":      input.Parameters,
 		"review": map[string]interface{}{
-			"requiresApproval": input.TemplateID == "auto-ack",
+			"requiresApproval": requiresApproval,
 			"confidence":       0.82,
 		},
 	}
@@ -6,14 +6,51 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/labstack/echo/v4"
 
+	"github.com/example/iboz/internal/bulk"
+	bulkmemory "github.com/example/iboz/internal/bulk/adapter/memory"
+	"github.com/example/iboz/internal/calendar"
+	calendarmemory "github.com/example/iboz/internal/calendar/adapter/memory"
+	"github.com/example/iboz/internal/crm"
+	crmmemory "github.com/example/iboz/internal/crm/adapter/memory"
+	"github.com/example/iboz/internal/dashboard"
+	"github.com/example/iboz/internal/delegation"
+	delegationmemory "github.com/example/iboz/internal/delegation/adapter/memory"
+	"github.com/example/iboz/internal/digest"
+	digestmemory "github.com/example/iboz/internal/digest/adapter/memory"
 	"github.com/example/iboz/internal/email"
 	"github.com/example/iboz/internal/email/adapter/memory"
 	"github.com/example/iboz/internal/email/adapter/synthetic"
+	"github.com/example/iboz/internal/focus"
+	focusmemory "github.com/example/iboz/internal/focus/adapter/memory"
+	"github.com/example/iboz/internal/notify"
+	"github.com/example/iboz/internal/push"
+	pushmemory "github.com/example/iboz/internal/push/adapter/memory"
+	"github.com/example/iboz/internal/queue"
+	"github.com/example/iboz/internal/queue/adapter/inproc"
+	queuememory "github.com/example/iboz/internal/queue/adapter/memory"
+	"github.com/example/iboz/internal/recommendations"
+	"github.com/example/iboz/internal/schedule"
+	schedulememory "github.com/example/iboz/internal/schedule/adapter/memory"
+	"github.com/example/iboz/internal/schedule/adapter/reply"
+	"github.com/example/iboz/internal/sla"
+	slamemory "github.com/example/iboz/internal/sla/adapter/memory"
+	"github.com/example/iboz/internal/snooze"
+	snoozememory "github.com/example/iboz/internal/snooze/adapter/memory"
+	"github.com/example/iboz/internal/tasks"
+	tasksmemory "github.com/example/iboz/internal/tasks/adapter/memory"
+	"github.com/example/iboz/internal/templates"
+	templatememory "github.com/example/iboz/internal/templates/adapter/memory"
+	"github.com/example/iboz/internal/testutil"
+	"github.com/example/iboz/internal/waiting"
+	waitingmemory "github.com/example/iboz/internal/waiting/adapter/memory"
+	"github.com/example/iboz/internal/webhooks"
+	webhookmemory "github.com/example/iboz/internal/webhooks/adapter/memory"
 )
 
 type stubEmailService struct{}
@@ -23,10 +60,19 @@ func (stubEmailService) Authenticate(context.Context, email.AuthRequest) (email.
 	return email.AuthState{}, nil
 }
 func (stubEmailService) FetchEmails(context.Context) ([]email.EmailMessage, error) { return nil, nil }
+func (stubEmailService) Message(context.Context, string) (email.EmailMessage, error) {
+	return email.EmailMessage{}, email.ErrMessageNotFound
+}
 func (stubEmailService) State(context.Context) (email.ServiceState, error) {
 	return email.ServiceState{}, nil
 }
 
+type stubReplyService struct{}
+
+func (stubReplyService) Reply(context.Context, string, email.ReplyRequest) (email.OutgoingMessage, error) {
+	return email.OutgoingMessage{}, nil
+}
+
 type testClock struct {
 	now time.Time
 }
@@ -36,27 +82,176 @@ func (t testClock) Now() time.Time {
 }
 
 func newEmailHandler(t *testing.T) handler {
+	t.Helper()
+	h, _ := newEmailHandlerWithSender(t)
+	return h
+}
+
+func newEmailHandlerWithSender(t *testing.T) (handler, *testutil.RecordingSender) {
 	t.Helper()
 	repo := memory.NewRepository()
 	clock := testClock{now: time.Date(2025, time.March, 18, 12, 0, 0, 0, time.UTC)}
-	svc := email.NewService(repo, email.NewSHA256Hasher(), synthetic.NewGenerator(), clock)
-	return handler{emailService: svc}
+	svc := email.NewService(repo, email.NewSHA256Hasher(), memory.NewVault(), synthetic.NewGenerator(), clock)
+	svc.ClassifyWith(bulk.NewDetector())
+	calendars := calendar.NewService(calendarmemory.NewRepository(), repo, calendar.DefaultHours(), clock)
+	tracker := waiting.NewTracker(waitingmemory.NewRepository(), repo, nil, calendars, clock, waiting.Config{})
+	svc.OnSync(tracker)
+	engine := sla.NewEngine(slamemory.NewRepository(), repo, calendars, nil, nil, clock)
+	svc.OnSync(engine)
+	sender := &testutil.RecordingSender{}
+	mailer := email.NewMailer(repo, sender, clock)
+	schedules := schedule.NewService(schedulememory.NewRepository(), map[string]schedule.Handler{
+		reply.Type: reply.NewHandler(mailer),
+	}, calendars, clock, schedule.Config{})
+	queues := queue.NewService(inproc.NewBroker(0), queuememory.NewRepository(), clock, queue.Config{})
+	focusRepo := focusmemory.NewRepository()
+	planner := focus.NewPlanner(focusRepo, repo, calendars, nil, nil, clock, focus.Config{})
+	return handler{
+		emailService:    svc,
+		replies:         mailer,
+		templates:       templates.NewService(templatememory.NewRepository(), repo, clock),
+		snoozes:         snooze.NewService(snoozememory.NewRepository(), repo, repo, calendars, clock),
+		delegations:     delegation.NewService(delegationmemory.NewRepository(), repo, nil, clock),
+		waiting:         tracker,
+		slaEngine:       engine,
+		calendars:       calendars,
+		schedules:       schedules,
+		queue:           queues,
+		tasks:           tasks.NewService(tasksmemory.NewRepository(), repo, repo, nil, notify.Linker{}, clock),
+		webhooks:        webhooks.NewService(webhookmemory.NewRepository(), nil, clock),
+		push:            push.NewService(pushmemory.NewRepository(), repo, nil, queues, schedules, clock, push.Config{}),
+		crm:             crm.NewService(crmmemory.NewRepository(), repo, repo, nil, clock),
+		focus:           planner,
+		focusSessions:   focus.NewService(focusRepo, repo, planner, clock),
+		dashboard:       dashboard.NewService(repo, nil, nil, clock, dashboard.Config{}),
+		recommendations: recommendations.NewEngine(repo, clock, recommendations.Config{}),
+		digests:         digest.NewService(digestmemory.NewRepository(), repo, sender, nil, nil, clock, digest.Config{}),
+		unsubscribes:    bulk.NewService(bulkmemory.NewRepository(), repo, sender, nil, clock),
+	}, sender
 }
 
 func TestRegisterRegistersExpectedRoutes(t *testing.T) {
 	e := echo.New()
-	Register(e.Group("/api"), stubEmailService{})
+	calendars := calendar.NewService(calendarmemory.NewRepository(), memory.NewRepository(), calendar.DefaultHours(), testClock{})
+	schedules := schedule.NewService(schedulememory.NewRepository(), nil, calendars, testClock{}, schedule.Config{})
+	queues := queue.NewService(inproc.NewBroker(0), queuememory.NewRepository(), testClock{}, queue.Config{})
+	planner := focus.NewPlanner(focusmemory.NewRepository(), memory.NewRepository(), calendars, nil, nil, testClock{}, focus.Config{})
+	Register(e.Group("/api"), Dependencies{
+		Email:           stubEmailService{},
+		Replies:         stubReplyService{},
+		Templates:       templates.NewService(templatememory.NewRepository(), memory.NewRepository(), testClock{}),
+		Snoozes:         snooze.NewService(snoozememory.NewRepository(), memory.NewRepository(), nil, calendars, testClock{}),
+		Delegations:     delegation.NewService(delegationmemory.NewRepository(), memory.NewRepository(), nil, testClock{}),
+		Waiting:         waiting.NewTracker(waitingmemory.NewRepository(), memory.NewRepository(), nil, calendars, testClock{}, waiting.Config{}),
+		SLA:             sla.NewEngine(slamemory.NewRepository(), memory.NewRepository(), calendars, nil, nil, testClock{}),
+		Calendar:        calendars,
+		Schedules:       schedules,
+		Queue:           queues,
+		Tasks:           tasks.NewService(tasksmemory.NewRepository(), memory.NewRepository(), memory.NewRepository(), nil, notify.Linker{}, testClock{}),
+		Webhooks:        webhooks.NewService(webhookmemory.NewRepository(), nil, testClock{}),
+		Push:            push.NewService(pushmemory.NewRepository(), memory.NewRepository(), nil, queues, schedules, testClock{}, push.Config{}),
+		CRM:             crm.NewService(crmmemory.NewRepository(), memory.NewRepository(), memory.NewRepository(), nil, testClock{}),
+		Focus:           planner,
+		FocusSessions:   focus.NewService(focusmemory.NewRepository(), memory.NewRepository(), planner, testClock{}),
+		Dashboard:       dashboard.NewService(memory.NewRepository(), nil, nil, testClock{}, dashboard.Config{}),
+		Recommendations: recommendations.NewEngine(memory.NewRepository(), testClock{}, recommendations.Config{}),
+		Digests:         digest.NewService(digestmemory.NewRepository(), memory.NewRepository(), nil, nil, nil, testClock{}, digest.Config{}),
+		Unsubscribes:    bulk.NewService(bulkmemory.NewRepository(), memory.NewRepository(), nil, nil, testClock{}),
+	})
 
 	expected := map[string]bool{
-		http.MethodGet + "/api/health":                       true,
-		http.MethodGet + "/api/dashboard":                    true,
-		http.MethodGet + "/api/focus/plan":                   true,
-		http.MethodGet + "/api/automations":                  true,
-		http.MethodPost + "/api/automations/test-run":        true,
-		http.MethodGet + "/api/email/provider":               true,
-		http.MethodPost + "/api/email/provider":              true,
-		http.MethodPost + "/api/email/provider/authenticate": true,
-		http.MethodGet + "/api/email/messages":               true,
+		http.MethodGet + "/api/health":                                  true,
+		http.MethodGet + "/api/dashboard":                               true,
+		http.MethodGet + "/api/focus/plan":                              true,
+		http.MethodGet + "/api/focus/sessions":                          true,
+		http.MethodPost + "/api/focus/sessions":                         true,
+		http.MethodGet + "/api/focus/sessions/current":                  true,
+		http.MethodGet + "/api/focus/sessions/:id":                      true,
+		http.MethodPost + "/api/focus/sessions/:id/pause":               true,
+		http.MethodPost + "/api/focus/sessions/:id/resume":              true,
+		http.MethodPost + "/api/focus/sessions/:id/complete":            true,
+		http.MethodPost + "/api/focus/sessions/:id/abandon":             true,
+		http.MethodPost + "/api/focus/sessions/:id/messages/:messageId": true,
+		http.MethodGet + "/api/focus/settings":                          true,
+		http.MethodPut + "/api/focus/settings":                          true,
+		http.MethodGet + "/api/focus/held":                              true,
+		http.MethodGet + "/api/digests":                                 true,
+		http.MethodPost + "/api/digests":                                true,
+		http.MethodGet + "/api/digests/:id":                             true,
+		http.MethodPut + "/api/digests/:id":                             true,
+		http.MethodDelete + "/api/digests/:id":                          true,
+		http.MethodGet + "/api/digests/:id/preview":                     true,
+		http.MethodPost + "/api/digests/:id/send":                       true,
+		http.MethodGet + "/api/digests/:id/editions":                    true,
+		http.MethodGet + "/api/digests/:id/editions/:editionId":         true,
+		http.MethodGet + "/api/automations":                             true,
+		http.MethodPost + "/api/automations/test-run":                   true,
+		http.MethodGet + "/api/automations/recommendations":             true,
+		http.MethodGet + "/api/email/provider":                          true,
+		http.MethodPost + "/api/email/provider":                         true,
+		http.MethodPost + "/api/email/provider/authenticate":            true,
+		http.MethodGet + "/api/email/messages":                          true,
+		http.MethodPost + "/api/email/messages/:id/reply":               true,
+		http.MethodPost + "/api/email/messages/:id/unsubscribe":         true,
+		http.MethodGet + "/api/unsubscriptions":                         true,
+		http.MethodGet + "/api/unsubscriptions/:id":                     true,
+		http.MethodGet + "/api/templates":                               true,
+		http.MethodPost + "/api/templates":                              true,
+		http.MethodGet + "/api/templates/:id":                           true,
+		http.MethodPut + "/api/templates/:id":                           true,
+		http.MethodDelete + "/api/templates/:id":                        true,
+		http.MethodGet + "/api/templates/:id/validation":                true,
+		http.MethodPost + "/api/templates/:id/preview":                  true,
+		http.MethodPost + "/api/templates/:id/send":                     true,
+		http.MethodGet + "/api/snoozes":                                 true,
+		http.MethodPost + "/api/snoozes":                                true,
+		http.MethodDelete + "/api/snoozes/:id":                          true,
+		http.MethodGet + "/api/delegations":                             true,
+		http.MethodPost + "/api/delegations":                            true,
+		http.MethodGet + "/api/delegations/:id":                         true,
+		http.MethodPatch + "/api/delegations/:id":                       true,
+		http.MethodGet + "/api/waiting":                                 true,
+		http.MethodGet + "/api/sla/policies":                            true,
+		http.MethodPost + "/api/sla/policies":                           true,
+		http.MethodGet + "/api/sla/policies/:id":                        true,
+		http.MethodPut + "/api/sla/policies/:id":                        true,
+		http.MethodDelete + "/api/sla/policies/:id":                     true,
+		http.MethodGet + "/api/sla/deadlines":                           true,
+		http.MethodGet + "/api/calendar":                                true,
+		http.MethodPut + "/api/calendar":                                true,
+		http.MethodPost + "/api/calendar/holidays":                      true,
+		http.MethodGet + "/api/scheduled-actions":                       true,
+		http.MethodPost + "/api/scheduled-actions":                      true,
+		http.MethodGet + "/api/scheduled-actions/:id":                   true,
+		http.MethodPatch + "/api/scheduled-actions/:id":                 true,
+		http.MethodDelete + "/api/scheduled-actions/:id":                true,
+		http.MethodGet + "/api/queue/dead-letters":                      true,
+		http.MethodPost + "/api/queue/dead-letters/:id/retry":           true,
+		http.MethodDelete + "/api/queue/dead-letters/:id":               true,
+		http.MethodGet + "/api/tasks":                                   true,
+		http.MethodPost + "/api/tasks":                                  true,
+		http.MethodGet + "/api/tasks/:id":                               true,
+		http.MethodGet + "/api/email/messages/:id":                      true,
+		http.MethodGet + "/api/crm/contacts":                            true,
+		http.MethodGet + "/api/crm/records":                             true,
+		http.MethodPost + "/api/crm/records":                            true,
+		http.MethodGet + "/api/crm/records/:id":                         true,
+		http.MethodPost + "/api/email/push/graph":                       true,
+		http.MethodPost + "/api/email/push/gmail":                       true,
+		http.MethodGet + "/api/email/push/subscriptions":                true,
+		http.MethodPost + "/api/email/push/subscriptions":               true,
+		http.MethodPost + "/api/email/push/subscriptions/:id/renew":     true,
+		http.MethodDelete + "/api/email/push/subscriptions/:id":         true,
+		http.MethodPost + "/api/tasks/webhooks/:provider":               true,
+		http.MethodGet + "/api/webhooks/events":                         true,
+		http.MethodGet + "/api/webhooks/subscriptions":                  true,
+		http.MethodPost + "/api/webhooks/subscriptions":                 true,
+		http.MethodGet + "/api/webhooks/subscriptions/:id":              true,
+		http.MethodPut + "/api/webhooks/subscriptions/:id":              true,
+		http.MethodDelete + "/api/webhooks/subscriptions/:id":           true,
+		http.MethodGet + "/api/webhooks/subscriptions/:id/deliveries":   true,
+		http.MethodGet + "/api/webhooks/deliveries/:id":                 true,
+		http.MethodPost + "/api/webhooks/deliveries/:id/replay":         true,
 	}
 
 	for _, route := range e.Routes() {
@@ -116,7 +311,7 @@ func TestHealthHandler(t *testing.T) {
 func TestDashboardHandler(t *testing.T) {
 	ctx, rec := newContext(http.MethodGet, "/api/dashboard", nil)
 
-	if err := dashboardHandler(ctx); err != nil {
+	if err := newEmailHandler(t).dashboardHandler(ctx); err != nil {
 		t.Fatalf("dashboard handler returned error: %v", err)
 	}
 
@@ -130,8 +325,11 @@ func TestDashboardHandler(t *testing.T) {
 	if !ok {
 		t.Fatalf("summary not found in response: %v", resp)
 	}
-	if _, ok := summary["inboxZeroTarget"]; !ok {
-		t.Fatalf("summary missing inboxZeroTarget: %v", summary)
+	if summary["inboxZeroTarget"] != float64(dashboard.DefaultInboxZeroTarget) || summary["currentInbox"] != float64(0) || summary["automationRate"] != float64(0) {
+		t.Fatalf("unexpected summary for an empty inbox: %v", summary)
+	}
+	if summary["computedAt"] != "2025-03-18T12:00:00Z" {
+		t.Fatalf("summary was not computed at the clock time: %v", summary)
 	}
 
 	queues, ok := resp["queues"].([]any)
@@ -139,9 +337,9 @@ func TestDashboardHandler(t *testing.T) {
 		t.Fatalf("expected four queues, got %v", resp["queues"])
 	}
 
-	recommendations, ok := resp["recommendations"].([]any)
-	if !ok || len(recommendations) != 2 {
-		t.Fatalf("expected two recommendations, got %v", resp["recommendations"])
+	recommended, ok := resp["recommendations"].([]any)
+	if !ok || len(recommended) != 0 {
+		t.Fatalf("expected no recommendations for an empty history, got %v", resp["recommendations"])
 	}
 
 	focusSessions, ok := resp["focusSessions"].([]any)
@@ -150,43 +348,10 @@ func TestDashboardHandler(t *testing.T) {
 	}
 }
 
-func TestFocusPlanHandler(t *testing.T) {
-	ctx, rec := newContext(http.MethodGet, "/api/focus/plan", nil)
-
-	if err := focusPlanHandler(ctx); err != nil {
-		t.Fatalf("focus plan handler returned error: %v", err)
-	}
-
-	if rec.Code != http.StatusOK {
-		t.Fatalf("unexpected status: %d", rec.Code)
-	}
-
-	resp := decodeBody[map[string]any](t, rec)
-
-	if _, err := time.Parse(time.RFC3339, resp["date"].(string)); err != nil {
-		t.Fatalf("date was not RFC3339: %v", err)
-	}
-
-	sessions, ok := resp["sessions"].([]any)
-	if !ok || len(sessions) != 2 {
-		t.Fatalf("expected two sessions, got %v", resp["sessions"])
-	}
-
-	metrics, ok := resp["metrics"].(map[string]any)
-	if !ok || len(metrics) == 0 {
-		t.Fatalf("metrics missing or empty: %v", resp["metrics"])
-	}
-
-	controls, ok := resp["controls"].(map[string]any)
-	if !ok || len(controls) == 0 {
-		t.Fatalf("controls missing or empty: %v", resp["controls"])
-	}
-}
-
 func TestAutomationsHandler(t *testing.T) {
 	ctx, rec := newContext(http.MethodGet, "/api/automations", nil)
 
-	if err := automationsHandler(ctx); err != nil {
+	if err := newEmailHandler(t).automationsHandler(ctx); err != nil {
 		t.Fatalf("automations handler returned error: %v", err)
 	}
 
@@ -204,6 +369,16 @@ func TestAutomationsHandler(t *testing.T) {
 	if _, ok := resp["overview"].(map[string]any); !ok {
 		t.Fatalf("overview missing: %v", resp)
 	}
+
+	// Tuesday 12:00 UTC falls within the default working hours.
+	met := map[string]any{}
+	for _, template := range templates {
+		template := template.(map[string]any)
+		met[template["id"].(string)] = template["timeConditionMet"]
+	}
+	if met["vip-sms"] != false || met["auto-ack"] != nil {
+		t.Fatalf("expected only the after-hours trigger to report its time condition, got %v", met)
+	}
 }
 
 func TestAutomationTestRunHandlerSuccess(t *testing.T) {
@@ -216,9 +391,14 @@ func TestAutomationTestRunHandlerSuccess(t *testing.T) {
 		t.Fatalf("failed to marshal payload: %v", err)
 	}
 
+	h := newEmailHandler(t)
+	subscription, err := h.webhooks.CreateSubscription(context.Background(), webhooks.SubscriptionRequest{URL: "https://partner.example/hooks", Events: []string{webhooks.WildcardEvent}})
+	if err != nil {
+		t.Fatalf("create subscription: %v", err)
+	}
 	ctx, rec := newContext(http.MethodPost, "/api/automations/test-run", bytes.NewBuffer(body))
 
-	if err := automationTestRunHandler(ctx); err != nil {
+	if err := h.automationTestRunHandler(ctx); err != nil {
 		t.Fatalf("automation test run handler returned error: %v", err)
 	}
 
@@ -240,12 +420,63 @@ func TestAutomationTestRunHandlerSuccess(t *testing.T) {
 	if review["requiresApproval"] != true {
 		t.Fatalf("expected requiresApproval to be true, got %v", review["requiresApproval"])
 	}
+	if deliveries, err := h.webhooks.Deliveries(context.Background(), subscription.ID); err != nil || len(deliveries) != 0 {
+		t.Fatalf("expected a simulated run to publish no events, got %+v (%v)", deliveries, err)
+	}
+}
+
+func TestAutomationTestRunHandlerUnlistedTemplate(t *testing.T) {
+	ctx, rec := newContext(http.MethodPost, "/api/automations/test-run", bytes.NewBufferString(`{"templateId":"rec-digest-news"}`))
+
+	if err := newEmailHandler(t).automationTestRunHandler(ctx); err != nil {
+		t.Fatalf("automation test run handler returned error: %v", err)
+	}
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected a recommended automation to be simulated, got %d", rec.Code)
+	}
+	if review, _ := decodeBody[map[string]any](t, rec)["review"].(map[string]any); review["requiresApproval"] != false {
+		t.Fatalf("expected no approval for an unlisted template, got %v", review)
+	}
+}
+
+func TestAutomationTestRunHandlerChecksReplyTemplate(t *testing.T) {
+	h := newEmailHandler(t)
+	ctx := context.Background()
+	valid, err := h.templates.Create(ctx, templates.Template{Name: "Receipt", TextBody: "Hi {{.SenderName}}"})
+	if err != nil {
+		t.Fatalf("create template: %v", err)
+	}
+	broken, err := h.templates.Create(ctx, templates.Template{Name: "Ticket", TextBody: "Ticket {{.TicketID}}"})
+	if err != nil {
+		t.Fatalf("create template: %v", err)
+	}
+
+	for _, tc := range []struct {
+		replyTemplateID string
+		want            int
+	}{
+		{valid.ID, http.StatusOK},
+		{broken.ID, http.StatusUnprocessableEntity},
+		{"tpl-missing", http.StatusNotFound},
+	} {
+		body := `{"templateId":"auto-ack","replyTemplateId":"` + tc.replyTemplateID + `"}`
+		c, rec := newContext(http.MethodPost, "/api/automations/test-run", bytes.NewBufferString(body))
+		if err := h.automationTestRunHandler(c); err != nil {
+			t.Fatalf("automation test run handler returned error: %v", err)
+		}
+		if rec.Code != tc.want {
+			t.Fatalf("%s: expected %d, got %d (%s)", tc.replyTemplateID, tc.want, rec.Code, rec.Body.String())
+		}
+		if tc.want == http.StatusUnprocessableEntity && !strings.Contains(rec.Body.String(), "TicketID") {
+			t.Fatalf("expected the unknown variable to be named, got %s", rec.Body.String())
+		}
+	}
 }
 
 func TestAutomationTestRunHandlerInvalidJSON(t *testing.T) {
 	ctx, rec := newContext(http.MethodPost, "/api/automations/test-run", bytes.NewBufferString("{"))
 
-	if err := automationTestRunHandler(ctx); err != nil {
+	if err := newEmailHandler(t).automationTestRunHandler(ctx); err != nil {
 		t.Fatalf("automation test run handler returned error: %v", err)
 	}
 
@@ -265,7 +496,7 @@ func TestAutomationTestRunHandlerMissingTemplateID(t *testing.T) {
 
 	ctx, rec := newContext(http.MethodPost, "/api/automations/test-run", bytes.NewBuffer(body))
 
-	if err := automationTestRunHandler(ctx); err != nil {
+	if err := newEmailHandler(t).automationTestRunHandler(ctx); err != nil {
 		t.Fatalf("automation test run handler returned error: %v", err)
 	}
 
@@ -386,3 +617,48 @@ func TestEmailProviderAuthenticateRequiresSecret(t *testing.T) {
 		t.Fatalf("expected bad request, got %d", rec.Code)
 	}
 }
+
+// syncTestMessages configures, authenticates and fetches the synthetic inbox through the handlers.
+func syncTestMessages(t *testing.T, h handler) {
+	t.Helper()
+	ctx, rec := newContext(http.MethodPost, "/api/email/provider", bytes.NewBufferString(`{"provider":"gmail","displayName":"Ops","connection":{"protocol":"api"}}`))
+	if err := h.emailProviderConfigureHandler(ctx); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("configure provider: %v (%d)", err, rec.Code)
+	}
+	ctx, rec = newContext(http.MethodPost, "/api/email/provider/authenticate", bytes.NewBufferString(`{"method":"appPassword","username":"ops@example.com","appPassword":"supersafesecret"}`))
+	if err := h.emailProviderAuthenticateHandler(ctx); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("authenticate: %v (%d)", err, rec.Code)
+	}
+	ctx, rec = newContext(http.MethodGet, "/api/email/messages", nil)
+	if err := h.emailFetchMessagesHandler(ctx); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("fetch messages: %v (%d)", err, rec.Code)
+	}
+}
+
+func TestEmailReplyHandler(t *testing.T) {
+	h, sender := newEmailHandlerWithSender(t)
+	syncTestMessages(t, h)
+
+	ctx, rec := newContext(http.MethodPost, "/api/email/messages/msg-escalation/reply", bytes.NewBufferString(`{"textBody":"Countersigned, thanks."}`))
+	ctx.SetParamNames("id")
+	ctx.SetParamValues("msg-escalation")
+	if err := h.emailReplyHandler(ctx); err != nil {
+		t.Fatalf("reply handler error: %v", err)
+	}
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("unexpected status: %d (%s)", rec.Code, rec.Body.String())
+	}
+	if len(sender.Sent) != 1 || sender.Sent[0].InReplyTo != "<msg-escalation@example.com>" {
+		t.Fatalf("unexpected sent messages: %+v", sender.Sent)
+	}
+
+	ctx, rec = newContext(http.MethodPost, "/api/email/messages/missing/reply", bytes.NewBufferString(`{"textBody":"hello"}`))
+	ctx.SetParamNames("id")
+	ctx.SetParamValues("missing")
+	if err := h.emailReplyHandler(ctx); err != nil {
+		t.Fatalf("reply handler error: %v", err)
+	}
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected not found, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,163 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/example/iboz/internal/schedule"
+)
+
+type scheduledActionRequest struct {
+	Type             string          `json:"type"`
+	Payload          json.RawMessage `json:"payload"`
+	RunAt            *time.Time      `json:"runAt"`
+	Delay            string          `json:"delay"`
+	BusinessHours    bool            `json:"businessHours"`
+	RequiresApproval bool            `json:"requiresApproval"`
+}
+
+// toRequest converts the payload, leaving Type and Payload empty for reschedules.
+func (r scheduledActionRequest) toRequest() (schedule.Request, error) {
+	req := schedule.Request{Type: r.Type, Payload: r.Payload, BusinessHours: r.BusinessHours, RequiresApproval: r.RequiresApproval}
+	if r.RunAt != nil {
+		req.RunAt = *r.RunAt
+	}
+	if r.Delay != "" {
+		delay, err := time.ParseDuration(r.Delay)
+		if err != nil {
+			return schedule.Request{}, err
+		}
+		req.Delay = delay
+	}
+	return req, nil
+}
+
+func (h handler) registerScheduledActionRoutes(g *echo.Group) {
+	sg := g.Group("/scheduled-actions")
+	sg.GET("", h.listScheduledActionsHandler)
+	sg.POST("", h.createScheduledActionHandler)
+	sg.GET("/:id", h.getScheduledActionHandler)
+	sg.PATCH("/:id", h.rescheduleActionHandler)
+	sg.DELETE("/:id", h.cancelScheduledActionHandler)
+	sg.POST("/:id/approve", h.approveScheduledActionHandler)
+}
+
+func (h handler) listScheduledActionsHandler(c echo.Context) error {
+	status, err := schedule.ParseStatus(c.QueryParam("status"))
+	if err != nil {
+		return scheduleError(c, err)
+	}
+	all, err := h.schedules.List(c.Request().Context(), status)
+	if err != nil {
+		return scheduleError(c, err)
+	}
+	list := make([]schedule.Action, 0, len(all))
+	for _, action := range all {
+		if h.schedules.UserFacing(action.Type) {
+			list = append(list, action)
+		}
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{"actions": list})
+}
+
+func (h handler) createScheduledActionHandler(c echo.Context) error {
+	var body scheduledActionRequest
+	if err := c.Bind(&body); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid scheduled action payload"})
+	}
+	req, err := body.toRequest()
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid delay duration"})
+	}
+	if !h.schedules.UserFacing(req.Type) {
+		return scheduleError(c, fmt.Errorf("%w: unsupported type %q", schedule.ErrInvalidAction, req.Type))
+	}
+	action, err := h.schedules.Schedule(c.Request().Context(), req)
+	if err != nil {
+		return scheduleError(c, err)
+	}
+	return c.JSON(http.StatusCreated, action)
+}
+
+func (h handler) getScheduledActionHandler(c echo.Context) error {
+	action, err := h.userFacingAction(c.Request().Context(), c.Param("id"))
+	if err != nil {
+		return scheduleError(c, err)
+	}
+	return c.JSON(http.StatusOK, action)
+}
+
+// userFacingAction returns an action users may manage, hiding the actions
+// other services schedule for themselves.
+func (h handler) userFacingAction(ctx context.Context, id string) (*schedule.Action, error) {
+	action, err := h.schedules.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !h.schedules.UserFacing(action.Type) {
+		return nil, schedule.ErrActionNotFound
+	}
+	return action, nil
+}
+
+func (h handler) rescheduleActionHandler(c echo.Context) error {
+	var body scheduledActionRequest
+	if err := c.Bind(&body); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid scheduled action payload"})
+	}
+	req, err := body.toRequest()
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid delay duration"})
+	}
+	if _, err := h.userFacingAction(c.Request().Context(), c.Param("id")); err != nil {
+		return scheduleError(c, err)
+	}
+	action, err := h.schedules.Reschedule(c.Request().Context(), c.Param("id"), req)
+	if err != nil {
+		return scheduleError(c, err)
+	}
+	return c.JSON(http.StatusOK, action)
+}
+
+func (h handler) cancelScheduledActionHandler(c echo.Context) error {
+	if _, err := h.userFacingAction(c.Request().Context(), c.Param("id")); err != nil {
+		return scheduleError(c, err)
+	}
+	action, err := h.schedules.Cancel(c.Request().Context(), c.Param("id"))
+	if err != nil {
+		return scheduleError(c, err)
+	}
+	return c.JSON(http.StatusOK, action)
+}
+
+func (h handler) approveScheduledActionHandler(c echo.Context) error {
+	if _, err := h.userFacingAction(c.Request().Context(), c.Param("id")); err != nil {
+		return scheduleError(c, err)
+	}
+	action, err := h.schedules.Approve(c.Request().Context(), c.Param("id"))
+	if err != nil {
+		return scheduleError(c, err)
+	}
+	return c.JSON(http.StatusOK, action)
+}
+
+func scheduleError(c echo.Context, err error) error {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, schedule.ErrActionNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, schedule.ErrInvalidAction), errors.Is(err, schedule.ErrUnknownStatus):
+		status = http.StatusBadRequest
+	case errors.Is(err, schedule.ErrNotPending):
+		status = http.StatusConflict
+	case errors.Is(err, context.Canceled):
+		status = http.StatusRequestTimeout
+	}
+	return c.JSON(status, map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,136 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/example/iboz/internal/schedule"
+)
+
+func TestScheduledActionHandlers(t *testing.T) {
+	h := newEmailHandler(t)
+	syncTestMessages(t, h)
+
+	ctx, rec := newContext(http.MethodPost, "/api/scheduled-actions", bytes.NewBufferString(`{"type":"email.reply","payload":{"messageId":"msg-escalation","textBody":"Any update?"},"delay":"48h"}`))
+	if err := h.createScheduledActionHandler(ctx); err != nil {
+		t.Fatalf("create scheduled action handler error: %v", err)
+	}
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("unexpected status: %d (%s)", rec.Code, rec.Body.String())
+	}
+	created := decodeBody[schedule.Action](t, rec)
+	if !created.RunAt.Equal(time.Date(2025, time.March, 20, 12, 0, 0, 0, time.UTC)) {
+		t.Fatalf("unexpected run time: %s", created.RunAt)
+	}
+
+	ctx, rec = newContext(http.MethodPost, "/api/scheduled-actions", bytes.NewBufferString(`{"type":"email.reply","payload":{"messageId":"msg-escalation"},"delay":"1h"}`))
+	if err := h.createScheduledActionHandler(ctx); err != nil {
+		t.Fatalf("create scheduled action handler error: %v", err)
+	}
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected bad request for an empty reply, got %d", rec.Code)
+	}
+
+	// Saturday 07:00 is deferred to Monday 09:00 under the default working hours.
+	ctx, rec = newContext(http.MethodPatch, "/api/scheduled-actions/"+created.ID, bytes.NewBufferString(`{"runAt":"2025-03-22T07:00:00Z","businessHours":true}`))
+	withParam(ctx, created.ID)
+	if err := h.rescheduleActionHandler(ctx); err != nil {
+		t.Fatalf("reschedule handler error: %v", err)
+	}
+	if rec.Code != http.StatusOK {
+		t.Fatalf("unexpected status: %d (%s)", rec.Code, rec.Body.String())
+	}
+	rescheduled := decodeBody[schedule.Action](t, rec)
+	if !rescheduled.RunAt.Equal(time.Date(2025, time.March, 24, 9, 0, 0, 0, time.UTC)) {
+		t.Fatalf("unexpected rescheduled time: %s", rescheduled.RunAt)
+	}
+
+	for _, want := range []int{http.StatusOK, http.StatusConflict} {
+		ctx, rec = newContext(http.MethodDelete, "/api/scheduled-actions/"+created.ID, nil)
+		withParam(ctx, created.ID)
+		if err := h.cancelScheduledActionHandler(ctx); err != nil {
+			t.Fatalf("cancel handler error: %v", err)
+		}
+		if rec.Code != want {
+			t.Fatalf("expected %d, got %d", want, rec.Code)
+		}
+	}
+
+	ctx, rec = newContext(http.MethodGet, "/api/scheduled-actions?status=cancelled", nil)
+	if err := h.listScheduledActionsHandler(ctx); err != nil {
+		t.Fatalf("list handler error: %v", err)
+	}
+	resp := decodeBody[map[string][]schedule.Action](t, rec)
+	if len(resp["actions"]) != 1 {
+		t.Fatalf("expected one cancelled action, got %+v", resp["actions"])
+	}
+}
+
+func TestApproveScheduledActionHandler(t *testing.T) {
+	h := newEmailHandler(t)
+	syncTestMessages(t, h)
+
+	ctx, rec := newContext(http.MethodPost, "/api/scheduled-actions", bytes.NewBufferString(`{"type":"email.reply","payload":{"messageId":"msg-escalation","textBody":"Any update?"},"delay":"1h","requiresApproval":true}`))
+	if err := h.createScheduledActionHandler(ctx); err != nil {
+		t.Fatalf("create scheduled action handler error: %v", err)
+	}
+	created := decodeBody[schedule.Action](t, rec)
+	if rec.Code != http.StatusCreated || created.Status != schedule.StatusAwaitingApproval {
+		t.Fatalf("expected an action awaiting approval, got %d %+v", rec.Code, created)
+	}
+
+	for _, want := range []int{http.StatusOK, http.StatusConflict} {
+		ctx, rec = newContext(http.MethodPost, "/api/scheduled-actions/"+created.ID+"/approve", nil)
+		withParam(ctx, created.ID)
+		if err := h.approveScheduledActionHandler(ctx); err != nil {
+			t.Fatalf("approve handler error: %v", err)
+		}
+		if rec.Code != want {
+			t.Fatalf("expected %d, got %d (%s)", want, rec.Code, rec.Body.String())
+		}
+	}
+}
+
+func TestScheduledActionHandlersHideInternalTypes(t *testing.T) {
+	h := newEmailHandler(t)
+	h.schedules.(*schedule.Service).Handle("internal.renew", noopHandler{})
+
+	ctx, rec := newContext(http.MethodPost, "/api/scheduled-actions", bytes.NewBufferString(`{"type":"internal.renew","delay":"1h"}`))
+	if err := h.createScheduledActionHandler(ctx); err != nil {
+		t.Fatalf("create scheduled action handler error: %v", err)
+	}
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected bad request for an internal type, got %d", rec.Code)
+	}
+
+	internal, err := h.schedules.Schedule(context.Background(), schedule.Request{Type: "internal.renew", RunAt: time.Date(2025, time.March, 20, 12, 0, 0, 0, time.UTC)})
+	if err != nil {
+		t.Fatalf("schedule internal action: %v", err)
+	}
+
+	ctx, rec = newContext(http.MethodGet, "/api/scheduled-actions", nil)
+	if err := h.listScheduledActionsHandler(ctx); err != nil {
+		t.Fatalf("list handler error: %v", err)
+	}
+	if resp := decodeBody[map[string][]schedule.Action](t, rec); len(resp["actions"]) != 0 {
+		t.Fatalf("expected internal actions to be hidden, got %+v", resp["actions"])
+	}
+
+	ctx, rec = newContext(http.MethodDelete, "/api/scheduled-actions/"+internal.ID, nil)
+	withParam(ctx, internal.ID)
+	if err := h.cancelScheduledActionHandler(ctx); err != nil {
+		t.Fatalf("cancel handler error: %v", err)
+	}
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected not found for an internal action, got %d", rec.Code)
+	}
+}
+
+type noopHandler struct{}
+
+func (noopHandler) Validate(json.RawMessage) error             { return nil }
+func (noopHandler) Run(context.Context, schedule.Action) error { return nil }
@@ -0,0 +1,93 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/example/iboz/internal/sla"
+)
+
+func (h handler) registerSLARoutes(g *echo.Group) {
+	sg := g.Group("/sla")
+	sg.GET("/policies", h.listSLAPoliciesHandler)
+	sg.POST("/policies", h.createSLAPolicyHandler)
+	sg.GET("/policies/:id", h.getSLAPolicyHandler)
+	sg.PUT("/policies/:id", h.updateSLAPolicyHandler)
+	sg.DELETE("/policies/:id", h.deleteSLAPolicyHandler)
+	sg.GET("/deadlines", h.listSLADeadlinesHandler)
+}
+
+func (h handler) listSLAPoliciesHandler(c echo.Context) error {
+	list, err := h.slaEngine.ListPolicies(c.Request().Context())
+	if err != nil {
+		return slaError(c, err)
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{"policies": list})
+}
+
+func (h handler) createSLAPolicyHandler(c echo.Context) error {
+	var policy sla.Policy
+	if err := c.Bind(&policy); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid sla policy payload"})
+	}
+	created, err := h.slaEngine.CreatePolicy(c.Request().Context(), policy)
+	if err != nil {
+		return slaError(c, err)
+	}
+	return c.JSON(http.StatusCreated, created)
+}
+
+func (h handler) getSLAPolicyHandler(c echo.Context) error {
+	policy, err := h.slaEngine.GetPolicy(c.Request().Context(), c.Param("id"))
+	if err != nil {
+		return slaError(c, err)
+	}
+	return c.JSON(http.StatusOK, policy)
+}
+
+func (h handler) updateSLAPolicyHandler(c echo.Context) error {
+	var policy sla.Policy
+	if err := c.Bind(&policy); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid sla policy payload"})
+	}
+	updated, err := h.slaEngine.UpdatePolicy(c.Request().Context(), c.Param("id"), policy)
+	if err != nil {
+		return slaError(c, err)
+	}
+	return c.JSON(http.StatusOK, updated)
+}
+
+func (h handler) deleteSLAPolicyHandler(c echo.Context) error {
+	if err := h.slaEngine.DeletePolicy(c.Request().Context(), c.Param("id")); err != nil {
+		return slaError(c, err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (h handler) listSLADeadlinesHandler(c echo.Context) error {
+	status, err := sla.ParseStatus(c.QueryParam("status"))
+	if err != nil {
+		return slaError(c, err)
+	}
+	list, err := h.slaEngine.Deadlines(c.Request().Context(), status)
+	if err != nil {
+		return slaError(c, err)
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{"deadlines": list})
+}
+
+func slaError(c echo.Context, err error) error {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, sla.ErrPolicyNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, sla.ErrInvalidPolicy), errors.Is(err, sla.ErrUnknownStatus):
+		status = http.StatusBadRequest
+	case errors.Is(err, context.Canceled):
+		status = http.StatusRequestTimeout
+	}
+	return c.JSON(status, map[string]string{"error": err.Error()})
+}
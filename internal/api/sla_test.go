@@ -0,0 +1,58 @@
+package api
+
+import (
+	"bytes"
+	"net/http"
+	"testing"
+
+	"github.com/example/iboz/internal/sla"
+)
+
+func TestSLAPolicyHandlers(t *testing.T) {
+	h := newEmailHandler(t)
+
+	ctx, rec := newContext(http.MethodPost, "/api/sla/policies", bytes.NewBufferString(`{"name":"Action items","match":{"category":"action"},"responseWithin":"4h","warnBefore":"1h"}`))
+	if err := h.createSLAPolicyHandler(ctx); err != nil {
+		t.Fatalf("create policy handler error: %v", err)
+	}
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("unexpected status: %d (%s)", rec.Code, rec.Body.String())
+	}
+	created := decodeBody[map[string]any](t, rec)
+	if created["responseWithin"] != "4h0m0s" {
+		t.Fatalf("expected duration string, got %v", created["responseWithin"])
+	}
+	id, _ := created["id"].(string)
+
+	ctx, rec = newContext(http.MethodPut, "/api/sla/policies/"+id, bytes.NewBufferString(`{"name":"Action items","responseWithin":"4h","escalations":[{"action":"pager","target":"ops"}]}`))
+	withParam(ctx, id)
+	if err := h.updateSLAPolicyHandler(ctx); err != nil {
+		t.Fatalf("update policy handler error: %v", err)
+	}
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected bad request for unknown escalation action, got %d", rec.Code)
+	}
+
+	syncTestMessages(t, h)
+
+	ctx, rec = newContext(http.MethodGet, "/api/sla/deadlines?status=pending", nil)
+	if err := h.listSLADeadlinesHandler(ctx); err != nil {
+		t.Fatalf("list deadlines handler error: %v", err)
+	}
+	if rec.Code != http.StatusOK {
+		t.Fatalf("unexpected status: %d", rec.Code)
+	}
+	resp := decodeBody[map[string][]sla.Deadline](t, rec)
+	if len(resp["deadlines"]) != 1 || resp["deadlines"][0].MessageID != "msg-escalation" {
+		t.Fatalf("expected a deadline for the escalation, got %+v", resp["deadlines"])
+	}
+
+	ctx, rec = newContext(http.MethodDelete, "/api/sla/policies/missing", nil)
+	withParam(ctx, "missing")
+	if err := h.deleteSLAPolicyHandler(ctx); err != nil {
+		t.Fatalf("delete policy handler error: %v", err)
+	}
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected not found, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,90 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/example/iboz/internal/email"
+	"github.com/example/iboz/internal/snooze"
+)
+
+type snoozeRequest struct {
+	MessageID string     `json:"messageId"`
+	Until     *time.Time `json:"until"`
+	Duration  string     `json:"duration"`
+	Preset    string     `json:"preset"`
+	Timezone  string     `json:"timezone"`
+	Label     string     `json:"label"`
+}
+
+func (h handler) registerSnoozeRoutes(g *echo.Group) {
+	sg := g.Group("/snoozes")
+	sg.GET("", h.listSnoozesHandler)
+	sg.POST("", h.createSnoozeHandler)
+	sg.DELETE("/:id", h.cancelSnoozeHandler)
+}
+
+func (h handler) listSnoozesHandler(c echo.Context) error {
+	list, err := h.snoozes.List(c.Request().Context())
+	if err != nil {
+		return snoozeError(c, err)
+	}
+	if list == nil {
+		list = []snooze.Snooze{}
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{"snoozes": list})
+}
+
+func (h handler) createSnoozeHandler(c echo.Context) error {
+	var req snoozeRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid snooze payload"})
+	}
+
+	request := snooze.Request{
+		MessageID: req.MessageID,
+		Preset:    req.Preset,
+		Timezone:  req.Timezone,
+		Label:     req.Label,
+	}
+	if req.Until != nil {
+		request.Until = *req.Until
+	}
+	if req.Duration != "" {
+		duration, err := time.ParseDuration(req.Duration)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid duration"})
+		}
+		request.Duration = duration
+	}
+
+	created, err := h.snoozes.Snooze(c.Request().Context(), request)
+	if err != nil {
+		return snoozeError(c, err)
+	}
+	return c.JSON(http.StatusCreated, created)
+}
+
+func (h handler) cancelSnoozeHandler(c echo.Context) error {
+	if err := h.snoozes.Cancel(c.Request().Context(), c.Param("id")); err != nil {
+		return snoozeError(c, err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+func snoozeError(c echo.Context, err error) error {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, snooze.ErrSnoozeNotFound), errors.Is(err, email.ErrMessageNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, snooze.ErrInvalidSnooze):
+		status = http.StatusBadRequest
+	case errors.Is(err, context.Canceled):
+		status = http.StatusRequestTimeout
+	}
+	return c.JSON(status, map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,63 @@
+package api
+
+import (
+	"bytes"
+	"net/http"
+	"testing"
+
+	"github.com/example/iboz/internal/snooze"
+)
+
+func TestSnoozeHandlersHideMessages(t *testing.T) {
+	h := newEmailHandler(t)
+	syncTestMessages(t, h)
+
+	ctx, rec := newContext(http.MethodPost, "/api/snoozes", bytes.NewBufferString(`{"messageId":"msg-digest","duration":"2h"}`))
+	if err := h.createSnoozeHandler(ctx); err != nil {
+		t.Fatalf("create snooze handler error: %v", err)
+	}
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("unexpected status: %d (%s)", rec.Code, rec.Body.String())
+	}
+	created := decodeBody[snooze.Snooze](t, rec)
+	if created.MessageID != "msg-digest" || created.Until.IsZero() {
+		t.Fatalf("unexpected snooze: %+v", created)
+	}
+
+	ctx, rec = newContext(http.MethodGet, "/api/email/messages", nil)
+	if err := h.emailFetchMessagesHandler(ctx); err != nil {
+		t.Fatalf("fetch messages handler error: %v", err)
+	}
+	messages := decodeBody[emailMessagesResponse](t, rec)
+	for _, message := range messages.Messages {
+		if message.ID == "msg-digest" {
+			t.Fatalf("snoozed message should be hidden")
+		}
+	}
+
+	ctx, rec = newContext(http.MethodPost, "/api/snoozes", bytes.NewBufferString(`{"messageId":"msg-digest","duration":"2h","preset":"next-business-morning"}`))
+	if err := h.createSnoozeHandler(ctx); err != nil {
+		t.Fatalf("create snooze handler error: %v", err)
+	}
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected bad request for ambiguous snooze, got %d", rec.Code)
+	}
+
+	ctx, rec = newContext(http.MethodDelete, "/api/snoozes/msg-digest", nil)
+	withParam(ctx, "msg-digest")
+	if err := h.cancelSnoozeHandler(ctx); err != nil {
+		t.Fatalf("cancel snooze handler error: %v", err)
+	}
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("unexpected status: %d", rec.Code)
+	}
+
+	ctx, rec = newContext(http.MethodDelete, "/api/snoozes/msg-digest", nil)
+	withParam(ctx, "msg-digest")
+	if err := h.cancelSnoozeHandler(ctx); err != nil {
+		t.Fatalf("cancel snooze handler error: %v", err)
+	}
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected not found for missing snooze, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,83 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/example/iboz/internal/email"
+	"github.com/example/iboz/internal/tasks"
+)
+
+const maxTaskWebhookBytes = 1 << 20
+
+func (h handler) registerTaskRoutes(g *echo.Group) {
+	tg := g.Group("/tasks")
+	tg.GET("", h.listTasksHandler)
+	tg.POST("", h.createTaskHandler)
+	tg.GET("/:id", h.getTaskHandler)
+	tg.POST("/webhooks/:provider", h.taskWebhookHandler)
+}
+
+func (h handler) listTasksHandler(c echo.Context) error {
+	list, err := h.tasks.List(c.Request().Context(), c.QueryParam("messageId"))
+	if err != nil {
+		return tasksError(c, err)
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{"tasks": list})
+}
+
+// createTaskHandler answers 202: the task is created in the tracker by the outbox relay.
+func (h handler) createTaskHandler(c echo.Context) error {
+	var req tasks.Request
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid task payload"})
+	}
+	created, err := h.tasks.Create(c.Request().Context(), req)
+	if err != nil {
+		return tasksError(c, err)
+	}
+	return c.JSON(http.StatusAccepted, created)
+}
+
+func (h handler) getTaskHandler(c echo.Context) error {
+	task, err := h.tasks.Get(c.Request().Context(), c.Param("id"))
+	if err != nil {
+		return tasksError(c, err)
+	}
+	return c.JSON(http.StatusOK, task)
+}
+
+func (h handler) taskWebhookHandler(c echo.Context) error {
+	body, err := io.ReadAll(io.LimitReader(c.Request().Body, maxTaskWebhookBytes))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid webhook payload"})
+	}
+	header := c.Request().Header
+	updated, err := h.tasks.HandleWebhook(c.Request().Context(), c.Param("provider"), header, body)
+	if err != nil {
+		return tasksError(c, err)
+	}
+	if secret := header.Get(tasks.HandshakeHeader); secret != "" {
+		c.Response().Header().Set(tasks.HandshakeHeader, secret)
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{"tasks": updated})
+}
+
+func tasksError(c echo.Context, err error) error {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, tasks.ErrTaskNotFound), errors.Is(err, email.ErrMessageNotFound), errors.Is(err, tasks.ErrUnknownProvider):
+		status = http.StatusNotFound
+	case errors.Is(err, tasks.ErrInvalidTask):
+		status = http.StatusBadRequest
+	case errors.Is(err, tasks.ErrInvalidSignature):
+		status = http.StatusUnauthorized
+	case errors.Is(err, context.Canceled):
+		status = http.StatusRequestTimeout
+	}
+	return c.JSON(status, map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,101 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/example/iboz/internal/email"
+	"github.com/example/iboz/internal/email/adapter/memory"
+	"github.com/example/iboz/internal/notify"
+	"github.com/example/iboz/internal/tasks"
+	"github.com/example/iboz/internal/tasks/adapter/asana"
+	tasksmemory "github.com/example/iboz/internal/tasks/adapter/memory"
+	"github.com/example/iboz/internal/tasks/adapter/webhook"
+)
+
+func TestTaskHandlers(t *testing.T) {
+	h := newEmailHandler(t)
+	repo := memory.NewRepository()
+	clock := testClock{}
+	if err := repo.SaveMessages(context.Background(), []email.EmailMessage{{ID: "msg-1", Subject: "Renewal contract"}}, clock.now); err != nil {
+		t.Fatalf("save messages: %v", err)
+	}
+	h.tasks = tasks.NewService(tasksmemory.NewRepository(), repo, repo, map[string]tasks.Sink{
+		tasks.ProviderWebhook: webhook.NewSink(webhook.Config{URL: "http://127.0.0.1:1", Secret: "shh"}),
+		tasks.ProviderAsana:   asana.NewSink(asana.Config{BaseURL: "http://127.0.0.1:1", Token: "pat", Workspace: "1"}),
+	}, notify.Linker{BaseURL: "https://iboz.example"}, clock)
+
+	ctx, rec := newContext(http.MethodPost, "/api/tasks", bytes.NewBufferString(`{"messageId":"msg-1","provider":"webhook","dueDate":"2025-03-21"}`))
+	if err := h.createTaskHandler(ctx); err != nil {
+		t.Fatalf("create task handler error: %v", err)
+	}
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("unexpected status: %d (%s)", rec.Code, rec.Body.String())
+	}
+	created := decodeBody[tasks.Task](t, rec)
+	if created.Status != tasks.StatusPending || created.Title != "Renewal contract" {
+		t.Fatalf("unexpected task: %+v", created)
+	}
+
+	ctx, rec = newContext(http.MethodGet, "/api/tasks?messageId=msg-1", nil)
+	if err := h.listTasksHandler(ctx); err != nil {
+		t.Fatalf("list tasks handler error: %v", err)
+	}
+	if list := decodeBody[map[string][]tasks.Task](t, rec); len(list["tasks"]) != 1 {
+		t.Fatalf("expected one task, got %+v", list)
+	}
+
+	ctx, rec = newContext(http.MethodGet, "/api/tasks/"+created.ID, nil)
+	withParam(ctx, created.ID)
+	if err := h.getTaskHandler(ctx); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("get task: %v (%d)", err, rec.Code)
+	}
+
+	for _, tc := range []struct {
+		body string
+		want int
+	}{
+		{`{"messageId":"msg-1","provider":"trello"}`, http.StatusNotFound},
+		{`{"messageId":"msg-1","provider":"webhook","dueDate":"soon"}`, http.StatusBadRequest},
+		{`{"messageId":"msg-404","provider":"webhook"}`, http.StatusNotFound},
+	} {
+		ctx, rec = newContext(http.MethodPost, "/api/tasks", bytes.NewBufferString(tc.body))
+		if err := h.createTaskHandler(ctx); err != nil {
+			t.Fatalf("create task handler error: %v", err)
+		}
+		if rec.Code != tc.want {
+			t.Fatalf("%s: expected %d, got %d (%s)", tc.body, tc.want, rec.Code, rec.Body.String())
+		}
+	}
+}
+
+func TestTaskWebhookHandler(t *testing.T) {
+	h := newEmailHandler(t)
+	h.tasks = tasks.NewService(tasksmemory.NewRepository(), memory.NewRepository(), memory.NewRepository(), map[string]tasks.Sink{
+		tasks.ProviderWebhook: webhook.NewSink(webhook.Config{URL: "http://127.0.0.1:1", Secret: "shh"}),
+		tasks.ProviderAsana:   asana.NewSink(asana.Config{Token: "pat", Workspace: "1", AcceptHandshake: true}),
+	}, notify.Linker{}, testClock{})
+
+	ctx, rec := newContext(http.MethodPost, "/api/tasks/webhooks/asana", nil)
+	ctx.Request().Header.Set(tasks.HandshakeHeader, "hook-secret")
+	ctx.SetParamNames("provider")
+	ctx.SetParamValues(tasks.ProviderAsana)
+	if err := h.taskWebhookHandler(ctx); err != nil {
+		t.Fatalf("webhook handler error: %v", err)
+	}
+	if rec.Code != http.StatusOK || rec.Header().Get(tasks.HandshakeHeader) != "hook-secret" {
+		t.Fatalf("expected handshake echo, got %d %v", rec.Code, rec.Header())
+	}
+
+	ctx, rec = newContext(http.MethodPost, "/api/tasks/webhooks/webhook", bytes.NewBufferString(`{"id":"T-1","done":true}`))
+	ctx.SetParamNames("provider")
+	ctx.SetParamValues(tasks.ProviderWebhook)
+	if err := h.taskWebhookHandler(ctx); err != nil {
+		t.Fatalf("webhook handler error: %v", err)
+	}
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected unsigned callback to be rejected, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,134 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/example/iboz/internal/email"
+	"github.com/example/iboz/internal/templates"
+)
+
+type templateMessageRequest struct {
+	MessageID string `json:"messageId"`
+}
+
+func (h handler) registerTemplateRoutes(g *echo.Group) {
+	tg := g.Group("/templates")
+	tg.GET("", h.listTemplatesHandler)
+	tg.POST("", h.createTemplateHandler)
+	tg.GET("/:id", h.getTemplateHandler)
+	tg.PUT("/:id", h.updateTemplateHandler)
+	tg.DELETE("/:id", h.deleteTemplateHandler)
+	tg.GET("/:id/validation", h.validateTemplateHandler)
+	tg.POST("/:id/preview", h.previewTemplateHandler)
+	tg.POST("/:id/send", h.sendTemplateHandler)
+}
+
+func (h handler) listTemplatesHandler(c echo.Context) error {
+	list, err := h.templates.List(c.Request().Context())
+	if err != nil {
+		return templateError(c, err)
+	}
+	if list == nil {
+		list = []templates.Template{}
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{"templates": list})
+}
+
+func (h handler) createTemplateHandler(c echo.Context) error {
+	var tpl templates.Template
+	if err := c.Bind(&tpl); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid template payload"})
+	}
+	created, err := h.templates.Create(c.Request().Context(), tpl)
+	if err != nil {
+		return templateError(c, err)
+	}
+	return c.JSON(http.StatusCreated, created)
+}
+
+func (h handler) getTemplateHandler(c echo.Context) error {
+	tpl, err := h.templates.Get(c.Request().Context(), c.Param("id"))
+	if err != nil {
+		return templateError(c, err)
+	}
+	return c.JSON(http.StatusOK, tpl)
+}
+
+func (h handler) updateTemplateHandler(c echo.Context) error {
+	var tpl templates.Template
+	if err := c.Bind(&tpl); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid template payload"})
+	}
+	updated, err := h.templates.Update(c.Request().Context(), c.Param("id"), tpl)
+	if err != nil {
+		return templateError(c, err)
+	}
+	return c.JSON(http.StatusOK, updated)
+}
+
+func (h handler) deleteTemplateHandler(c echo.Context) error {
+	if err := h.templates.Delete(c.Request().Context(), c.Param("id")); err != nil {
+		return templateError(c, err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (h handler) validateTemplateHandler(c echo.Context) error {
+	result, err := h.templates.Validate(c.Request().Context(), c.Param("id"))
+	if err != nil {
+		return templateError(c, err)
+	}
+	return c.JSON(http.StatusOK, result)
+}
+
+func (h handler) previewTemplateHandler(c echo.Context) error {
+	var req templateMessageRequest
+	if err := c.Bind(&req); err != nil || req.MessageID == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "messageId is required"})
+	}
+	rendered, err := h.templates.Preview(c.Request().Context(), c.Param("id"), req.MessageID)
+	if err != nil {
+		return templateError(c, err)
+	}
+	return c.JSON(http.StatusOK, rendered)
+}
+
+func (h handler) sendTemplateHandler(c echo.Context) error {
+	var req templateMessageRequest
+	if err := c.Bind(&req); err != nil || req.MessageID == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "messageId is required"})
+	}
+
+	ctx := c.Request().Context()
+	rendered, err := h.templates.Preview(ctx, c.Param("id"), req.MessageID)
+	if err != nil {
+		return templateError(c, err)
+	}
+
+	sent, err := h.replies.Reply(ctx, req.MessageID, email.ReplyRequest{
+		Subject:  rendered.Subject,
+		TextBody: rendered.TextBody,
+		HTMLBody: rendered.HTMLBody,
+	})
+	if err != nil {
+		return replyError(c, err)
+	}
+	return c.JSON(http.StatusAccepted, sent)
+}
+
+func templateError(c echo.Context, err error) error {
+	status := http.StatusBadRequest
+	switch {
+	case errors.Is(err, templates.ErrTemplateNotFound), errors.Is(err, email.ErrMessageNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, templates.ErrUnknownVariables):
+		status = http.StatusUnprocessableEntity
+	case errors.Is(err, context.Canceled):
+		status = http.StatusRequestTimeout
+	}
+	return c.JSON(status, map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,115 @@
+package api
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/example/iboz/internal/templates"
+)
+
+func withParam(ctx echo.Context, value string) {
+	ctx.SetParamNames("id")
+	ctx.SetParamValues(value)
+}
+
+func TestTemplateLifecycleHandlers(t *testing.T) {
+	h, sender := newEmailHandlerWithSender(t)
+	syncTestMessages(t, h)
+
+	body := `{"name":"Auto-ack","subject":"Received: case {{.CaseNumber}}","textBody":"Hi {{.SenderName}}, we received {{.Subject}}.","htmlBody":"<p>Hi {{.SenderName}}</p>"}`
+	ctx, rec := newContext(http.MethodPost, "/api/templates", bytes.NewBufferString(body))
+	if err := h.createTemplateHandler(ctx); err != nil {
+		t.Fatalf("create handler error: %v", err)
+	}
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("unexpected status: %d (%s)", rec.Code, rec.Body.String())
+	}
+	created := decodeBody[templates.Template](t, rec)
+	if created.ID == "" {
+		t.Fatalf("expected generated id")
+	}
+
+	ctx, rec = newContext(http.MethodGet, "/api/templates", nil)
+	if err := h.listTemplatesHandler(ctx); err != nil {
+		t.Fatalf("list handler error: %v", err)
+	}
+	list := decodeBody[map[string][]templates.Template](t, rec)
+	if len(list["templates"]) != 1 {
+		t.Fatalf("expected one template, got %v", list)
+	}
+
+	ctx, rec = newContext(http.MethodPost, "/api/templates/"+created.ID+"/preview", bytes.NewBufferString(`{"messageId":"msg-escalation"}`))
+	withParam(ctx, created.ID)
+	if err := h.previewTemplateHandler(ctx); err != nil {
+		t.Fatalf("preview handler error: %v", err)
+	}
+	if rec.Code != http.StatusOK {
+		t.Fatalf("unexpected status: %d (%s)", rec.Code, rec.Body.String())
+	}
+	rendered := decodeBody[templates.Rendered](t, rec)
+	if rendered.TextBody != "Hi legal-ops, we received Escalation: Contract signature pending." {
+		t.Fatalf("unexpected rendered text: %q", rendered.TextBody)
+	}
+
+	ctx, rec = newContext(http.MethodPost, "/api/templates/"+created.ID+"/send", bytes.NewBufferString(`{"messageId":"msg-escalation"}`))
+	withParam(ctx, created.ID)
+	if err := h.sendTemplateHandler(ctx); err != nil {
+		t.Fatalf("send handler error: %v", err)
+	}
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("unexpected status: %d (%s)", rec.Code, rec.Body.String())
+	}
+	if len(sender.Sent) != 1 || sender.Sent[0].HTMLBody != "<p>Hi legal-ops</p>" {
+		t.Fatalf("unexpected sent messages: %+v", sender.Sent)
+	}
+
+	ctx, rec = newContext(http.MethodPut, "/api/templates/"+created.ID, bytes.NewBufferString(`{"name":"Auto-ack","textBody":"Ticket {{.TicketID}}"}`))
+	withParam(ctx, created.ID)
+	if err := h.updateTemplateHandler(ctx); err != nil {
+		t.Fatalf("update handler error: %v", err)
+	}
+	if rec.Code != http.StatusOK {
+		t.Fatalf("unexpected status: %d (%s)", rec.Code, rec.Body.String())
+	}
+
+	ctx, rec = newContext(http.MethodGet, "/api/templates/"+created.ID+"/validation", nil)
+	withParam(ctx, created.ID)
+	if err := h.validateTemplateHandler(ctx); err != nil {
+		t.Fatalf("validate handler error: %v", err)
+	}
+	validation := decodeBody[templates.Validation](t, rec)
+	if validation.Valid || strings.Join(validation.Unknown, ",") != "TicketID" {
+		t.Fatalf("expected TicketID to be flagged, got %+v", validation)
+	}
+
+	ctx, rec = newContext(http.MethodPost, "/api/templates/"+created.ID+"/send", bytes.NewBufferString(`{"messageId":"msg-escalation"}`))
+	withParam(ctx, created.ID)
+	if err := h.sendTemplateHandler(ctx); err != nil {
+		t.Fatalf("send handler error: %v", err)
+	}
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected unprocessable entity for unknown variables, got %d", rec.Code)
+	}
+
+	ctx, rec = newContext(http.MethodDelete, "/api/templates/"+created.ID, nil)
+	withParam(ctx, created.ID)
+	if err := h.deleteTemplateHandler(ctx); err != nil {
+		t.Fatalf("delete handler error: %v", err)
+	}
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("unexpected status: %d", rec.Code)
+	}
+
+	ctx, rec = newContext(http.MethodGet, "/api/templates/"+created.ID, nil)
+	withParam(ctx, created.ID)
+	if err := h.getTemplateHandler(ctx); err != nil {
+		t.Fatalf("get handler error: %v", err)
+	}
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected not found after delete, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,57 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/example/iboz/internal/bulk"
+	"github.com/example/iboz/internal/email"
+)
+
+func (h handler) registerUnsubscribeRoutes(g *echo.Group) {
+	g.POST("/email/messages/:id/unsubscribe", h.unsubscribeHandler)
+	g.GET("/unsubscriptions", h.listUnsubscriptionsHandler)
+	g.GET("/unsubscriptions/:id", h.getUnsubscriptionHandler)
+}
+
+// unsubscribeHandler queues the unsubscribe request of a bulk message. The
+// unsubscription is performed by the outbox relay, so 202 is returned.
+func (h handler) unsubscribeHandler(c echo.Context) error {
+	unsubscription, err := h.unsubscribes.Unsubscribe(c.Request().Context(), c.Param("id"))
+	if err != nil {
+		return unsubscribeError(c, err)
+	}
+	return c.JSON(http.StatusAccepted, unsubscription)
+}
+
+func (h handler) listUnsubscriptionsHandler(c echo.Context) error {
+	list, err := h.unsubscribes.List(c.Request().Context())
+	if err != nil {
+		return unsubscribeError(c, err)
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{"unsubscriptions": list})
+}
+
+func (h handler) getUnsubscriptionHandler(c echo.Context) error {
+	unsubscription, err := h.unsubscribes.Get(c.Request().Context(), c.Param("id"))
+	if err != nil {
+		return unsubscribeError(c, err)
+	}
+	return c.JSON(http.StatusOK, unsubscription)
+}
+
+func unsubscribeError(c echo.Context, err error) error {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, bulk.ErrUnsubscriptionNotFound), errors.Is(err, email.ErrMessageNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, bulk.ErrCannotUnsubscribe):
+		status = http.StatusUnprocessableEntity
+	case errors.Is(err, context.Canceled):
+		status = http.StatusRequestTimeout
+	}
+	return c.JSON(status, map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,62 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/example/iboz/internal/bulk"
+)
+
+func TestUnsubscribeHandlers(t *testing.T) {
+	h := newEmailHandler(t)
+	syncTestMessages(t, h)
+
+	ctx, rec := newContext(http.MethodPost, "/api/email/messages/msg-digest/unsubscribe", nil)
+	withParam(ctx, "msg-digest")
+	if err := h.unsubscribeHandler(ctx); err != nil || rec.Code != http.StatusAccepted {
+		t.Fatalf("unsubscribe: %v (%d %s)", err, rec.Code, rec.Body.String())
+	}
+	created := decodeBody[bulk.Unsubscription](t, rec)
+	if created.Method != bulk.MethodOneClick || created.ListID != "digest.automation.example.com" || created.Status != bulk.StatusPending {
+		t.Fatalf("unexpected unsubscription: %+v", created)
+	}
+
+	ctx, rec = newContext(http.MethodPost, "/api/email/messages/msg-digest/unsubscribe", nil)
+	withParam(ctx, "msg-digest")
+	if err := h.unsubscribeHandler(ctx); err != nil || rec.Code != http.StatusAccepted {
+		t.Fatalf("unsubscribe again: %v (%d)", err, rec.Code)
+	}
+	if again := decodeBody[bulk.Unsubscription](t, rec); again.ID != created.ID {
+		t.Fatalf("expected the existing unsubscription, got %+v", again)
+	}
+
+	ctx, rec = newContext(http.MethodPost, "/api/email/messages/msg-escalation/unsubscribe", nil)
+	withParam(ctx, "msg-escalation")
+	if err := h.unsubscribeHandler(ctx); err != nil || rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected 422 for a message without list headers, got %d (%v)", rec.Code, err)
+	}
+	ctx, rec = newContext(http.MethodPost, "/api/email/messages/missing/unsubscribe", nil)
+	withParam(ctx, "missing")
+	if err := h.unsubscribeHandler(ctx); err != nil || rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for an unknown message, got %d (%v)", rec.Code, err)
+	}
+
+	ctx, rec = newContext(http.MethodGet, "/api/unsubscriptions", nil)
+	if err := h.listUnsubscriptionsHandler(ctx); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("list unsubscriptions: %v (%d)", err, rec.Code)
+	}
+	if list := decodeBody[map[string][]bulk.Unsubscription](t, rec)["unsubscriptions"]; len(list) != 1 || list[0].ID != created.ID {
+		t.Fatalf("unexpected unsubscriptions: %+v", list)
+	}
+
+	ctx, rec = newContext(http.MethodGet, "/api/unsubscriptions/"+created.ID, nil)
+	withParam(ctx, created.ID)
+	if err := h.getUnsubscriptionHandler(ctx); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("get unsubscription: %v (%d)", err, rec.Code)
+	}
+	ctx, rec = newContext(http.MethodGet, "/api/unsubscriptions/missing", nil)
+	withParam(ctx, "missing")
+	if err := h.getUnsubscriptionHandler(ctx); err != nil || rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d (%v)", rec.Code, err)
+	}
+}
@@ -0,0 +1,38 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/example/iboz/internal/waiting"
+)
+
+func (h handler) registerWaitingRoutes(g *echo.Group) {
+	g.GET("/waiting", h.listWaitingHandler)
+}
+
+func (h handler) listWaitingHandler(c echo.Context) error {
+	status, err := waiting.ParseStatus(c.QueryParam("status"))
+	if err != nil {
+		return waitingError(c, err)
+	}
+	list, err := h.waiting.List(c.Request().Context(), status)
+	if err != nil {
+		return waitingError(c, err)
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{"threads": list})
+}
+
+func waitingError(c echo.Context, err error) error {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, waiting.ErrUnknownStatus):
+		status = http.StatusBadRequest
+	case errors.Is(err, context.Canceled):
+		status = http.StatusRequestTimeout
+	}
+	return c.JSON(status, map[string]string{"error": err.Error()})
+}
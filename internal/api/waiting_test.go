@@ -0,0 +1,33 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/example/iboz/internal/waiting"
+)
+
+func TestListWaitingHandler(t *testing.T) {
+	h := newEmailHandler(t)
+	syncTestMessages(t, h)
+
+	ctx, rec := newContext(http.MethodGet, "/api/waiting?status=waiting", nil)
+	if err := h.listWaitingHandler(ctx); err != nil {
+		t.Fatalf("list waiting handler error: %v", err)
+	}
+	if rec.Code != http.StatusOK {
+		t.Fatalf("unexpected status: %d", rec.Code)
+	}
+	resp := decodeBody[map[string][]waiting.Thread](t, rec)
+	if len(resp["threads"]) != 1 || resp["threads"][0].ThreadID != "thread-quote" {
+		t.Fatalf("expected quote thread to be waiting, got %+v", resp["threads"])
+	}
+
+	ctx, rec = newContext(http.MethodGet, "/api/waiting?status=bogus", nil)
+	if err := h.listWaitingHandler(ctx); err != nil {
+		t.Fatalf("list waiting handler error: %v", err)
+	}
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected bad request, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,113 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/example/iboz/internal/webhooks"
+)
+
+func (h handler) registerWebhookRoutes(g *echo.Group) {
+	wg := g.Group("/webhooks")
+	wg.GET("/events", webhookEventTypesHandler)
+	wg.GET("/subscriptions", h.listWebhookSubscriptionsHandler)
+	wg.POST("/subscriptions", h.createWebhookSubscriptionHandler)
+	wg.GET("/subscriptions/:id", h.getWebhookSubscriptionHandler)
+	wg.PUT("/subscriptions/:id", h.updateWebhookSubscriptionHandler)
+	wg.DELETE("/subscriptions/:id", h.deleteWebhookSubscriptionHandler)
+	wg.GET("/subscriptions/:id/deliveries", h.listWebhookDeliveriesHandler)
+	wg.GET("/deliveries/:id", h.getWebhookDeliveryHandler)
+	wg.POST("/deliveries/:id/replay", h.replayWebhookDeliveryHandler)
+}
+
+func webhookEventTypesHandler(c echo.Context) error {
+	return c.JSON(http.StatusOK, map[string]interface{}{"events": webhooks.EventTypes})
+}
+
+func (h handler) listWebhookSubscriptionsHandler(c echo.Context) error {
+	list, err := h.webhooks.ListSubscriptions(c.Request().Context())
+	if err != nil {
+		return webhooksError(c, err)
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{"subscriptions": list})
+}
+
+func (h handler) createWebhookSubscriptionHandler(c echo.Context) error {
+	var req webhooks.SubscriptionRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid subscription payload"})
+	}
+	created, err := h.webhooks.CreateSubscription(c.Request().Context(), req)
+	if err != nil {
+		return webhooksError(c, err)
+	}
+	return c.JSON(http.StatusCreated, created)
+}
+
+func (h handler) getWebhookSubscriptionHandler(c echo.Context) error {
+	subscription, err := h.webhooks.GetSubscription(c.Request().Context(), c.Param("id"))
+	if err != nil {
+		return webhooksError(c, err)
+	}
+	return c.JSON(http.StatusOK, subscription)
+}
+
+func (h handler) updateWebhookSubscriptionHandler(c echo.Context) error {
+	var req webhooks.SubscriptionRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid subscription payload"})
+	}
+	updated, err := h.webhooks.UpdateSubscription(c.Request().Context(), c.Param("id"), req)
+	if err != nil {
+		return webhooksError(c, err)
+	}
+	return c.JSON(http.StatusOK, updated)
+}
+
+func (h handler) deleteWebhookSubscriptionHandler(c echo.Context) error {
+	if err := h.webhooks.DeleteSubscription(c.Request().Context(), c.Param("id")); err != nil {
+		return webhooksError(c, err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (h handler) listWebhookDeliveriesHandler(c echo.Context) error {
+	list, err := h.webhooks.Deliveries(c.Request().Context(), c.Param("id"))
+	if err != nil {
+		return webhooksError(c, err)
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{"deliveries": list})
+}
+
+func (h handler) getWebhookDeliveryHandler(c echo.Context) error {
+	delivery, err := h.webhooks.GetDelivery(c.Request().Context(), c.Param("id"))
+	if err != nil {
+		return webhooksError(c, err)
+	}
+	return c.JSON(http.StatusOK, delivery)
+}
+
+// replayWebhookDeliveryHandler answers 202: the replay is sent by the outbox relay.
+func (h handler) replayWebhookDeliveryHandler(c echo.Context) error {
+	delivery, err := h.webhooks.Replay(c.Request().Context(), c.Param("id"))
+	if err != nil {
+		return webhooksError(c, err)
+	}
+	return c.JSON(http.StatusAccepted, delivery)
+}
+
+func webhooksError(c echo.Context, err error) error {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, webhooks.ErrSubscriptionNotFound), errors.Is(err, webhooks.ErrDeliveryNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, webhooks.ErrInvalidSubscription), errors.Is(err, webhooks.ErrInvalidEvent):
+		status = http.StatusBadRequest
+	case errors.Is(err, context.Canceled):
+		status = http.StatusRequestTimeout
+	}
+	return c.JSON(status, map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,86 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/example/iboz/internal/webhooks"
+)
+
+func TestWebhookSubscriptionHandlers(t *testing.T) {
+	h := newEmailHandler(t)
+
+	ctx, rec := newContext(http.MethodPost, "/api/webhooks/subscriptions", bytes.NewBufferString(`{"url":"https://partner.example/hooks","events":["digest.ready","waiting.follow_up_due"]}`))
+	if err := h.createWebhookSubscriptionHandler(ctx); err != nil {
+		t.Fatalf("create subscription handler error: %v", err)
+	}
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("unexpected status: %d (%s)", rec.Code, rec.Body.String())
+	}
+	created := decodeBody[webhooks.Subscription](t, rec)
+	if created.Secret == "" {
+		t.Fatal("expected the generated secret in the create response")
+	}
+
+	ctx, rec = newContext(http.MethodGet, "/api/webhooks/subscriptions", nil)
+	if err := h.listWebhookSubscriptionsHandler(ctx); err != nil {
+		t.Fatalf("list subscriptions handler error: %v", err)
+	}
+	list := decodeBody[map[string][]webhooks.Subscription](t, rec)
+	if len(list["subscriptions"]) != 1 || list["subscriptions"][0].Secret != "" {
+		t.Fatalf("expected one redacted subscription, got %+v", list)
+	}
+
+	for _, event := range []string{webhooks.EventDigestReady, webhooks.EventFollowUpDue} {
+		if _, err := h.webhooks.Publish(context.Background(), event, "", map[string]string{"digestId": "dig-1"}); err != nil {
+			t.Fatalf("publish %s: %v", event, err)
+		}
+	}
+
+	ctx, rec = newContext(http.MethodGet, "/api/webhooks/subscriptions/"+created.ID+"/deliveries", nil)
+	withParam(ctx, created.ID)
+	if err := h.listWebhookDeliveriesHandler(ctx); err != nil {
+		t.Fatalf("list deliveries handler error: %v", err)
+	}
+	deliveries := decodeBody[map[string][]webhooks.Delivery](t, rec)["deliveries"]
+	if len(deliveries) != 2 {
+		t.Fatalf("expected digest and follow-up deliveries, got %+v", deliveries)
+	}
+
+	ctx, rec = newContext(http.MethodPost, "/api/webhooks/deliveries/"+deliveries[0].ID+"/replay", nil)
+	withParam(ctx, deliveries[0].ID)
+	if err := h.replayWebhookDeliveryHandler(ctx); err != nil {
+		t.Fatalf("replay handler error: %v", err)
+	}
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("unexpected status: %d (%s)", rec.Code, rec.Body.String())
+	}
+	if replay := decodeBody[webhooks.Delivery](t, rec); replay.ReplayOf != deliveries[0].ID {
+		t.Fatalf("unexpected replay: %+v", replay)
+	}
+
+	ctx, rec = newContext(http.MethodPost, "/api/webhooks/subscriptions", bytes.NewBufferString(`{"url":"https://partner.example/hooks","events":["message.deleted"]}`))
+	if err := h.createWebhookSubscriptionHandler(ctx); err != nil {
+		t.Fatalf("create subscription handler error: %v", err)
+	}
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected bad request for an unknown event, got %d", rec.Code)
+	}
+
+	ctx, rec = newContext(http.MethodDelete, "/api/webhooks/subscriptions/"+created.ID, nil)
+	withParam(ctx, created.ID)
+	if err := h.deleteWebhookSubscriptionHandler(ctx); err != nil || rec.Code != http.StatusNoContent {
+		t.Fatalf("delete: %v (%d)", err, rec.Code)
+	}
+
+	ctx, rec = newContext(http.MethodGet, "/api/webhooks/subscriptions/"+created.ID, nil)
+	withParam(ctx, created.ID)
+	if err := h.getWebhookSubscriptionHandler(ctx); err != nil {
+		t.Fatalf("get subscription handler error: %v", err)
+	}
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected not found after delete, got %d", rec.Code)
+	}
+}
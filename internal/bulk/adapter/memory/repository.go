@@ -0,0 +1,117 @@
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/example/iboz/internal/bulk"
+	"github.com/example/iboz/internal/outbox"
+	outboxmemory "github.com/example/iboz/internal/outbox/adapter/memory"
+)
+
+var _ bulk.Repository = (*Repository)(nil)
+
+// Repository provides an in-memory implementation of the bulk.Repository port.
+type Repository struct {
+	mu              sync.RWMutex
+	unsubscriptions map[string]bulk.Unsubscription
+	outbox          *outboxmemory.Table
+}
+
+// NewRepository builds a new in-memory unsubscription repository.
+func NewRepository() *Repository {
+	return &Repository{
+		unsubscriptions: make(map[string]bulk.Unsubscription),
+		outbox:          outboxmemory.NewTable(),
+	}
+}
+
+// Save inserts or replaces an unsubscription and appends effects to the outbox under the same lock.
+func (r *Repository) Save(ctx context.Context, unsubscription bulk.Unsubscription, effects ...outbox.Entry) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	r.unsubscriptions[unsubscription.ID] = cloneUnsubscription(unsubscription)
+	r.outbox.Append(effects...)
+	r.mu.Unlock()
+	return nil
+}
+
+// Get returns the unsubscription with the supplied identifier if present.
+func (r *Repository) Get(ctx context.Context, id string) (*bulk.Unsubscription, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	unsubscription, ok := r.unsubscriptions[id]
+	if !ok {
+		return nil, nil
+	}
+	cloned := cloneUnsubscription(unsubscription)
+	return &cloned, nil
+}
+
+// List returns every stored unsubscription.
+func (r *Repository) List(ctx context.Context) ([]bulk.Unsubscription, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	list := make([]bulk.Unsubscription, 0, len(r.unsubscriptions))
+	for _, unsubscription := range r.unsubscriptions {
+		list = append(list, cloneUnsubscription(unsubscription))
+	}
+	return list, nil
+}
+
+// PendingEntries implements the outbox.Store interface.
+func (r *Repository) PendingEntries(ctx context.Context, now time.Time, limit int) ([]outbox.Entry, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.outbox.Pending(now, limit), nil
+}
+
+// UpdateEntry implements the outbox.Store interface and marks the pending
+// unsubscription of a failed entry, keyed by its ID, as failed.
+func (r *Repository) UpdateEntry(ctx context.Context, entry outbox.Entry) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.outbox.Update(entry)
+	if entry.Destination != bulk.OutboxDestination || entry.Status != outbox.StatusFailed {
+		return nil
+	}
+	unsubscription, ok := r.unsubscriptions[entry.Key]
+	if !ok || unsubscription.Status != bulk.StatusPending {
+		return nil
+	}
+	unsubscription.Status = bulk.StatusFailed
+	unsubscription.LastError = entry.LastError
+	r.unsubscriptions[entry.Key] = unsubscription
+	return nil
+}
+
+func cloneUnsubscription(unsubscription bulk.Unsubscription) bulk.Unsubscription {
+	if unsubscription.CompletedAt != nil {
+		completed := *unsubscription.CompletedAt
+		unsubscription.CompletedAt = &completed
+	}
+	return unsubscription
+}
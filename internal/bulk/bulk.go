@@ -0,0 +1,82 @@
+// Package bulk detects newsletters and other bulk mail from their list and
+// sending-platform headers, and unsubscribes from them on request.
+package bulk
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/example/iboz/internal/outbox"
+)
+
+// Category is set on bulk messages that have no category yet.
+const Category = "newsletter"
+
+// Status enumerates the lifecycle of an unsubscription.
+type Status string
+
+const (
+	// StatusPending unsubscriptions wait for the outbox relay to perform them.
+	StatusPending      Status = "pending"
+	StatusUnsubscribed Status = "unsubscribed"
+	// StatusFailed unsubscriptions were given up on by the relay; asking again
+	// for the list queues a new one.
+	StatusFailed Status = "failed"
+)
+
+// Unsubscribe methods. MethodOneClick posts to the sender's RFC 8058 endpoint;
+// MethodMailto mails the sender's unsubscribe address from the mailbox.
+const (
+	MethodOneClick = "one-click"
+	MethodMailto   = "mailto"
+)
+
+// OutboxDestination is the outbox destination of unsubscriptions, delivered by
+// the Service's Deliverer.
+const OutboxDestination = "bulk.unsubscribe"
+
+var (
+	// ErrUnsubscriptionNotFound is returned when an unsubscription does not exist.
+	ErrUnsubscriptionNotFound = errors.New("unsubscription not found")
+	// ErrCannotUnsubscribe is returned when a message offers no unsubscribe
+	// option that can be performed on the user's behalf.
+	ErrCannotUnsubscribe = errors.New("cannot unsubscribe")
+)
+
+// Unsubscription is a request to leave the list a message was sent to. Lists
+// are identified by their List-Id, or by the sender when there is none.
+type Unsubscription struct {
+	ID          string     `json:"id"`
+	MessageID   string     `json:"messageId"`
+	Sender      string     `json:"sender"`
+	ListID      string     `json:"listId,omitempty"`
+	Method      string     `json:"method"`
+	Target      string     `json:"target"`
+	Status      Status     `json:"status"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	UpdatedAt   time.Time  `json:"updatedAt"`
+	CompletedAt *time.Time `json:"completedAt,omitempty"`
+	LastError   string     `json:"lastError,omitempty"`
+}
+
+// Repository defines the persistence contract for unsubscriptions. It owns the
+// outbox table holding pending unsubscribe requests; UpdateEntry marks the
+// unsubscription of an entry that failed as StatusFailed.
+type Repository interface {
+	outbox.Store
+	// Save stores unsubscription and appends effects to the outbox atomically.
+	Save(ctx context.Context, unsubscription Unsubscription, effects ...outbox.Entry) error
+	Get(ctx context.Context, id string) (*Unsubscription, error)
+	List(ctx context.Context) ([]Unsubscription, error)
+}
+
+// UnsubscribeService unsubscribes from the lists of bulk messages.
+type UnsubscribeService interface {
+	// Unsubscribe queues an unsubscribe request for the list of a message. A
+	// list already unsubscribed from, or being unsubscribed from, returns the
+	// existing unsubscription; one whose unsubscription failed is tried again.
+	Unsubscribe(ctx context.Context, messageID string) (*Unsubscription, error)
+	Get(ctx context.Context, id string) (*Unsubscription, error)
+	List(ctx context.Context) ([]Unsubscription, error)
+}
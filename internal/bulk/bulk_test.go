@@ -0,0 +1,353 @@
+package bulk_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/example/iboz/internal/bulk"
+	"github.com/example/iboz/internal/bulk/adapter/memory"
+	"github.com/example/iboz/internal/email"
+	"github.com/example/iboz/internal/outbox"
+	"github.com/example/iboz/internal/spam"
+	"github.com/example/iboz/internal/testutil"
+)
+
+type fixture struct {
+	service *bulk.Service
+	relay   *outbox.Relay
+	sender  *testutil.RecordingSender
+	clock   *testutil.Clock
+}
+
+// newFixture stores messages after running them through the detector, as a
+// sync does.
+func newFixture(t *testing.T, client *http.Client, messages ...email.EmailMessage) fixture {
+	t.Helper()
+	ctx := context.Background()
+	clock := testutil.NewClock(testutil.Start)
+	messages, err := bulk.NewDetector().Classify(ctx, messages)
+	if err != nil {
+		t.Fatalf("classify: %v", err)
+	}
+	store := testutil.Mailbox(t, clock, messages...)
+	repo := memory.NewRepository()
+	sender := &testutil.RecordingSender{}
+	service := bulk.NewService(repo, store, sender, client, clock)
+	relay := testutil.NewRelay(repo, map[string]outbox.Deliverer{
+		bulk.OutboxDestination: service.Deliverer(),
+	}, clock)
+	return fixture{service: service, relay: relay, sender: sender, clock: clock}
+}
+
+func newsletter(id, unsubscribe string, oneClick bool) email.EmailMessage {
+	headers := email.Header{
+		"List-Id":          {"Weekly News <weekly.news.example>"},
+		"List-Unsubscribe": {unsubscribe},
+	}
+	if oneClick {
+		headers["List-Unsubscribe-Post"] = []string{"List-Unsubscribe=One-Click"}
+		headers["Authentication-Results"] = []string{"mx.example.com; spf=pass; dkim=pass header.d=news.example; dmarc=pass"}
+	}
+	return email.EmailMessage{
+		ID:         id,
+		Subject:    "This week",
+		Sender:     "Weekly News <news@news.example>",
+		ReceivedAt: time.Date(2025, time.March, 17, 9, 0, 0, 0, time.UTC),
+		Headers:    headers,
+	}
+}
+
+func TestDetectorClassifiesBulkMail(t *testing.T) {
+	detector := bulk.NewDetector()
+	cases := []struct {
+		name     string
+		message  email.EmailMessage
+		bulk     bool
+		category string
+		check    func(t *testing.T, record *email.BulkMail)
+	}{
+		{
+			name:     "list headers",
+			message:  newsletter("msg-1", "<mailto:leave@news.example?subject=stop>, <https://news.example/u/1>", true),
+			bulk:     true,
+			category: bulk.Category,
+			check: func(t *testing.T, record *email.BulkMail) {
+				if record.ListID != "weekly.news.example" {
+					t.Fatalf("expected list id, got %q", record.ListID)
+				}
+				if record.UnsubscribeURL != "https://news.example/u/1" || record.UnsubscribeMailto != "mailto:leave@news.example?subject=stop" {
+					t.Fatalf("unexpected unsubscribe options: %+v", record)
+				}
+				if !record.OneClick {
+					t.Fatal("expected one-click unsubscribe")
+				}
+			},
+		},
+		{
+			name: "one-click needs https",
+			message: email.EmailMessage{ID: "msg-2", Headers: email.Header{
+				"List-Unsubscribe":      {"<http://news.example/u/2>"},
+				"List-Unsubscribe-Post": {"List-Unsubscribe=One-Click"},
+			}},
+			bulk:     true,
+			category: bulk.Category,
+			check: func(t *testing.T, record *email.BulkMail) {
+				if record.OneClick {
+					t.Fatal("expected no one-click unsubscribe over http")
+				}
+			},
+		},
+		{
+			name: "marketing platform",
+			message: email.EmailMessage{ID: "msg-3", Category: "promotions", Headers: email.Header{
+				"X-Mc-User": {"abc123"},
+			}},
+			bulk:     true,
+			category: "promotions",
+			check: func(t *testing.T, record *email.BulkMail) {
+				if record.ESP != "Mailchimp" || len(record.Signals) != 1 || record.Signals[0] != "esp:mailchimp" {
+					t.Fatalf("unexpected record: %+v", record)
+				}
+			},
+		},
+		{
+			name: "precedence",
+			message: email.EmailMessage{ID: "msg-4", Headers: email.Header{
+				"Precedence": {"Bulk"},
+			}},
+			bulk:     true,
+			category: bulk.Category,
+		},
+		{
+			name: "transactional platform",
+			message: email.EmailMessage{ID: "msg-5", Headers: email.Header{
+				"X-Ses-Outgoing": {"2025.03.17-54.240.1.1"},
+			}},
+		},
+		{
+			name:    "no headers",
+			message: email.EmailMessage{ID: "msg-6"},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			batch, err := detector.Classify(context.Background(), []email.EmailMessage{tc.message})
+			if err != nil {
+				t.Fatalf("classify: %v", err)
+			}
+			classified := batch[0]
+			if (classified.Bulk != nil) != tc.bulk {
+				t.Fatalf("expected bulk %v, got %+v", tc.bulk, classified.Bulk)
+			}
+			if classified.Category != tc.category {
+				t.Fatalf("expected category %q, got %q", tc.category, classified.Category)
+			}
+			if tc.check != nil {
+				tc.check(t, classified.Bulk)
+			}
+		})
+	}
+}
+
+func TestOneClickUnsubscribePostsOnceWithoutRedirects(t *testing.T) {
+	var posts []string
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		posts = append(posts, r.Method+" "+r.URL.Path+" "+r.Header.Get("Content-Type")+" "+string(body))
+		if r.URL.Path == "/moved" {
+			http.Redirect(w, r, "/u/1", http.StatusFound)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	f := newFixture(t, server.Client(),
+		newsletter("msg-1", "<"+server.URL+"/u/1>, <mailto:leave@news.example>", true),
+		newsletter("msg-2", "<"+server.URL+"/u/1>", true),
+	)
+	ctx := context.Background()
+	first, err := f.service.Unsubscribe(ctx, "msg-1")
+	if err != nil {
+		t.Fatalf("unsubscribe: %v", err)
+	}
+	if first.Method != bulk.MethodOneClick || first.Status != bulk.StatusPending || first.Sender != "news@news.example" {
+		t.Fatalf("unexpected unsubscription: %+v", first)
+	}
+	second, err := f.service.Unsubscribe(ctx, "msg-2")
+	if err != nil {
+		t.Fatalf("unsubscribe again: %v", err)
+	}
+	if second.ID != first.ID {
+		t.Fatalf("expected the list to be unsubscribed once, got %s and %s", first.ID, second.ID)
+	}
+
+	testutil.Deliver(t, f.relay)
+	if len(posts) != 1 || posts[0] != "POST /u/1 application/x-www-form-urlencoded List-Unsubscribe=One-Click" {
+		t.Fatalf("unexpected requests: %v", posts)
+	}
+	done, err := f.service.Get(ctx, first.ID)
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if done.Status != bulk.StatusUnsubscribed || done.CompletedAt == nil {
+		t.Fatalf("expected unsubscribed, got %+v", done)
+	}
+
+	moved := newFixture(t, server.Client(), newsletter("msg-3", "<"+server.URL+"/moved>", true))
+	if _, err := moved.service.Unsubscribe(ctx, "msg-3"); err != nil {
+		t.Fatalf("unsubscribe: %v", err)
+	}
+	if _, err := moved.relay.Deliver(ctx); err == nil {
+		t.Fatal("expected a redirect to fail the delivery")
+	}
+	if len(posts) != 2 {
+		t.Fatalf("expected the redirect not to be followed, got %v", posts)
+	}
+}
+
+func TestFailedUnsubscribeCanBeRetried(t *testing.T) {
+	failing := true
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if failing {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	f := newFixture(t, server.Client(), newsletter("msg-1", "<"+server.URL+"/u/1>", true))
+	ctx := context.Background()
+	first, err := f.service.Unsubscribe(ctx, "msg-1")
+	if err != nil {
+		t.Fatalf("unsubscribe: %v", err)
+	}
+	for i := 0; i < outbox.DefaultMaxAttempts; i++ {
+		if _, err := f.relay.Deliver(ctx); err == nil {
+			t.Fatal("expected the delivery to fail")
+		}
+		f.clock.Advance(24 * time.Hour)
+	}
+	failed, err := f.service.Get(ctx, first.ID)
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if failed.Status != bulk.StatusFailed || !strings.Contains(failed.LastError, "503") {
+		t.Fatalf("expected the unsubscription to fail, got %+v", failed)
+	}
+
+	failing = false
+	retry, err := f.service.Unsubscribe(ctx, "msg-1")
+	if err != nil {
+		t.Fatalf("unsubscribe again: %v", err)
+	}
+	if retry.ID == first.ID || retry.Status != bulk.StatusPending {
+		t.Fatalf("expected a new pending unsubscription, got %+v", retry)
+	}
+	testutil.Deliver(t, f.relay)
+	done, err := f.service.Get(ctx, retry.ID)
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if done.Status != bulk.StatusUnsubscribed {
+		t.Fatalf("expected the retry to unsubscribe, got %+v", done)
+	}
+}
+
+func TestMailtoUnsubscribeSendsFromMailbox(t *testing.T) {
+	f := newFixture(t, nil, newsletter("msg-1", "<mailto:leave%2Bweekly@news.example?subject=Remove%20me>", false))
+	ctx := context.Background()
+	unsubscription, err := f.service.Unsubscribe(ctx, "msg-1")
+	if err != nil {
+		t.Fatalf("unsubscribe: %v", err)
+	}
+	if unsubscription.Method != bulk.MethodMailto {
+		t.Fatalf("expected mailto, got %+v", unsubscription)
+	}
+	testutil.Deliver(t, f.relay)
+	if len(f.sender.Sent) != 1 {
+		t.Fatalf("expected one message, got %d", len(f.sender.Sent))
+	}
+	sent := f.sender.Sent[0]
+	if sent.From != "me@example.com" || sent.To[0] != "leave+weekly@news.example" || sent.Subject != "Remove me" || sent.TextBody != "unsubscribe" {
+		t.Fatalf("unexpected message: %+v", sent)
+	}
+}
+
+func TestUnsubscribeRejectsUnsupportedOptions(t *testing.T) {
+	f := newFixture(t, nil,
+		newsletter("msg-1", "<https://news.example/manage>", false),
+		email.EmailMessage{ID: "msg-2", Subject: "Hello", Sender: "friend@example.com"},
+	)
+	ctx := context.Background()
+	_, err := f.service.Unsubscribe(ctx, "msg-1")
+	if !errors.Is(err, bulk.ErrCannotUnsubscribe) || !strings.Contains(err.Error(), "https://news.example/manage") {
+		t.Fatalf("expected the page to visit, got %v", err)
+	}
+	if _, err := f.service.Unsubscribe(ctx, "msg-2"); !errors.Is(err, bulk.ErrCannotUnsubscribe) {
+		t.Fatalf("expected ErrCannotUnsubscribe, got %v", err)
+	}
+	if _, err := f.service.Unsubscribe(ctx, "missing"); !errors.Is(err, email.ErrMessageNotFound) {
+		t.Fatalf("expected ErrMessageNotFound, got %v", err)
+	}
+}
+
+func TestOneClickNeedsTrustedMessage(t *testing.T) {
+	unsigned := newsletter("msg-1", "<https://news.example/u/1>", true)
+	unsigned.Headers["Authentication-Results"] = []string{"mx.example.com; spf=pass; dkim=none; dmarc=pass"}
+	spoofed := newsletter("msg-2", "<https://news.example/u/2>", true)
+	spoofed.Headers["Authentication-Results"] = []string{"mx.example.com; spf=pass; dkim=pass; dmarc=fail"}
+	junk := newsletter("msg-3", "<https://news.example/u/3>", true)
+	junk.Category = spam.Category
+	withMailto := newsletter("msg-4", "<https://other.example/u/4>, <mailto:leave@other.example>", true)
+	withMailto.Headers["List-Id"] = []string{"Other <other.example>"}
+	withMailto.Headers["Authentication-Results"] = nil
+
+	f := newFixture(t, nil, unsigned, spoofed, junk, withMailto)
+	ctx := context.Background()
+	for i, id := range []string{"msg-1", "msg-2", "msg-3"} {
+		target := fmt.Sprintf("https://news.example/u/%d", i+1)
+		if _, err := f.service.Unsubscribe(ctx, id); !errors.Is(err, bulk.ErrCannotUnsubscribe) || !strings.Contains(err.Error(), target) {
+			t.Fatalf("expected %s to report %s, got %v", id, target, err)
+		}
+	}
+	unsubscription, err := f.service.Unsubscribe(ctx, "msg-4")
+	if err != nil {
+		t.Fatalf("unsubscribe: %v", err)
+	}
+	if unsubscription.Method != bulk.MethodMailto {
+		t.Fatalf("expected the mailto fallback, got %+v", unsubscription)
+	}
+}
+
+func TestDefaultClientRefusesPrivateEndpoints(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request to %s", r.URL)
+	}))
+	defer server.Close()
+
+	f := newFixture(t, nil, newsletter("msg-1", "<"+server.URL+"/u/1>", true))
+	ctx := context.Background()
+	unsubscription, err := f.service.Unsubscribe(ctx, "msg-1")
+	if err != nil {
+		t.Fatalf("unsubscribe: %v", err)
+	}
+	if _, err := f.relay.Deliver(ctx); err == nil || !strings.Contains(err.Error(), "not a public address") {
+		t.Fatalf("expected the loopback endpoint to be refused, got %v", err)
+	}
+	pending, err := f.service.Get(ctx, unsubscription.ID)
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if pending.Status != bulk.StatusPending {
+		t.Fatalf("expected pending, got %s", pending.Status)
+	}
+}
@@ -0,0 +1,144 @@
+package bulk
+
+import (
+	"context"
+	"net/url"
+	"strings"
+
+	"github.com/example/iboz/internal/email"
+)
+
+var _ email.Classifier = (*Detector)(nil)
+
+// esp fingerprints an email service provider by a header only it sets.
+// Marketing platforms mark a message as bulk on their own; transactional ones
+// are only recorded.
+type esp struct {
+	header    string
+	name      string
+	marketing bool
+}
+
+var esps = []esp{
+	{header: "X-Mc-User", name: "Mailchimp", marketing: true},
+	{header: "X-Sfmc-Stack", name: "Salesforce Marketing Cloud", marketing: true},
+	{header: "X-Mailin-Eid", name: "Brevo", marketing: true},
+	{header: "X-Mandrill-User", name: "Mandrill"},
+	{header: "X-Sg-Eid", name: "SendGrid"},
+	{header: "X-Mailgun-Sid", name: "Mailgun"},
+	{header: "X-Ses-Outgoing", name: "Amazon SES"},
+	{header: "X-Pm-Message-Id", name: "Postmark"},
+}
+
+// campaignHeaders are set by bulk senders regardless of their platform.
+var campaignHeaders = []string{"X-Campaign", "X-Campaign-Id", "X-Csa-Complaints"}
+
+// Detector classifies messages as bulk mail from their List-Id,
+// List-Unsubscribe and Precedence headers, campaign headers and the
+// fingerprints of marketing platforms. It records the unsubscribe options of
+// every message that offers them.
+type Detector struct{}
+
+// NewDetector constructs a Detector.
+func NewDetector() *Detector {
+	return &Detector{}
+}
+
+// Classify implements email.Classifier. Bulk messages without a category are
+// filed as Category.
+func (d *Detector) Classify(ctx context.Context, messages []email.EmailMessage) ([]email.EmailMessage, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	for i, message := range messages {
+		messages[i] = detect(message)
+	}
+	return messages, nil
+}
+
+// detect records the bulk signals and unsubscribe options of message.
+func detect(message email.EmailMessage) email.EmailMessage {
+	if len(message.Headers) == 0 {
+		return message
+	}
+	headers := message.Headers
+
+	record := email.BulkMail{Signals: []string{}}
+	if listID := headers.Get("List-Id"); listID != "" {
+		record.ListID = parseListID(listID)
+		record.Signals = append(record.Signals, "list-id")
+	}
+	if unsubscribe := headers.Get("List-Unsubscribe"); unsubscribe != "" {
+		record.UnsubscribeURL, record.UnsubscribeMailto = parseUnsubscribe(unsubscribe)
+		record.Signals = append(record.Signals, "list-unsubscribe")
+	}
+	record.OneClick = strings.HasPrefix(record.UnsubscribeURL, "https://") &&
+		strings.EqualFold(strings.TrimSpace(headers.Get("List-Unsubscribe-Post")), "List-Unsubscribe=One-Click")
+	switch precedence := strings.ToLower(strings.TrimSpace(headers.Get("Precedence"))); precedence {
+	case "bulk", "list", "junk":
+		record.Signals = append(record.Signals, "precedence:"+precedence)
+	}
+	for _, header := range campaignHeaders {
+		if headers.Get(header) != "" {
+			record.Signals = append(record.Signals, "campaign")
+			break
+		}
+	}
+	for _, fingerprint := range esps {
+		if headers.Get(fingerprint.header) == "" {
+			continue
+		}
+		record.ESP = fingerprint.name
+		if fingerprint.marketing {
+			record.Signals = append(record.Signals, "esp:"+strings.ToLower(fingerprint.name))
+		}
+		break
+	}
+
+	if len(record.Signals) == 0 {
+		message.Bulk = nil
+		return message
+	}
+	message.Bulk = &record
+	if message.Category == "" {
+		message.Category = Category
+	}
+	return message
+}
+
+// parseListID returns the identifier of a List-Id header, the part in angle
+// brackets after the optional description.
+func parseListID(value string) string {
+	if start := strings.LastIndex(value, "<"); start >= 0 {
+		if end := strings.Index(value[start:], ">"); end > 0 {
+			return strings.ToLower(strings.TrimSpace(value[start+1 : start+end]))
+		}
+	}
+	return strings.ToLower(strings.TrimSpace(value))
+}
+
+// parseUnsubscribe returns the first web and mailto URIs of a List-Unsubscribe
+// header, a comma-separated list of URIs in angle brackets.
+func parseUnsubscribe(value string) (web, mailto string) {
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if !strings.HasPrefix(part, "<") || !strings.HasSuffix(part, ">") {
+			continue
+		}
+		uri, err := url.Parse(strings.TrimSpace(part[1 : len(part)-1]))
+		if err != nil {
+			continue
+		}
+		switch strings.ToLower(uri.Scheme) {
+		case "https", "http":
+			if web == "" && uri.Host != "" {
+				web = uri.String()
+			}
+		case "mailto":
+			if mailto == "" && uri.Opaque != "" {
+				mailto = uri.String()
+			}
+		}
+	}
+	return web, mailto
+}
@@ -0,0 +1,337 @@
+package bulk
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/example/iboz/internal/email"
+	"github.com/example/iboz/internal/outbox"
+	"github.com/example/iboz/internal/spam"
+)
+
+const (
+	defaultTimeout   = 10 * time.Second
+	maxResponseBytes = 1 << 16
+)
+
+// errPrivateAddress is returned when a one-click endpoint resolves to an
+// address that is not publicly routable.
+var errPrivateAddress = errors.New("unsubscribe endpoint is not a public address")
+
+var _ UnsubscribeService = (*Service)(nil)
+
+// Service unsubscribes from mailing lists through the outbox.
+type Service struct {
+	repo     Repository
+	messages email.Repository
+	sender   email.MailSender
+	client   *http.Client
+	clock    email.Clock
+}
+
+// request is the outbox payload of an unsubscription.
+type request struct {
+	UnsubscriptionID string `json:"unsubscriptionId"`
+}
+
+// NewService constructs an unsubscribe Service. A nil sender disables mailto
+// unsubscribes. A nil client selects one that refuses endpoints on private
+// addresses; either way redirects are not followed, as RFC 8058 requires.
+func NewService(repo Repository, messages email.Repository, sender email.MailSender, client *http.Client, clock email.Clock) *Service {
+	if repo == nil {
+		panic("bulk: repository dependency is required")
+	}
+	if messages == nil {
+		panic("bulk: email repository dependency is required")
+	}
+	if clock == nil {
+		panic("bulk: clock dependency is required")
+	}
+	if client == nil {
+		dialer := &net.Dialer{Timeout: defaultTimeout, Control: publicOnly}
+		client = &http.Client{
+			Timeout:   defaultTimeout,
+			Transport: &http.Transport{DialContext: dialer.DialContext, TLSHandshakeTimeout: defaultTimeout},
+		}
+	}
+	noRedirects := *client
+	noRedirects.CheckRedirect = func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }
+	return &Service{repo: repo, messages: messages, sender: sender, client: &noRedirects, clock: clock}
+}
+
+// Unsubscribe queues the unsubscribe request of a bulk message. One-click
+// endpoints are preferred over mailto addresses; a web page that needs a
+// visit is reported in the error. As RFC 8058 section 4 asks, one-click is only
+// used for trusted messages, and otherwise its URL is reported like a page.
+func (s *Service) Unsubscribe(ctx context.Context, messageID string) (*Unsubscription, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	message, err := email.FindMessage(ctx, s.messages, messageID)
+	if err != nil {
+		return nil, err
+	}
+	if !message.Bulk.CanUnsubscribe() {
+		return nil, fmt.Errorf("%w: message %s offers no unsubscribe option", ErrCannotUnsubscribe, message.ID)
+	}
+
+	sender := strings.ToLower(message.Sender)
+	if parsed, err := mail.ParseAddress(message.Sender); err == nil {
+		sender = strings.ToLower(parsed.Address)
+	}
+	existing, err := s.find(ctx, message.Bulk.ListID, sender)
+	if err != nil || existing != nil {
+		return existing, err
+	}
+
+	var method, target string
+	switch {
+	case message.Bulk.OneClick && trusted(message):
+		method, target = MethodOneClick, message.Bulk.UnsubscribeURL
+	case message.Bulk.UnsubscribeMailto != "" && s.sender != nil:
+		if _, _, _, err := parseMailto(message.Bulk.UnsubscribeMailto); err == nil {
+			method, target = MethodMailto, message.Bulk.UnsubscribeMailto
+		}
+	}
+	if method == "" {
+		if message.Bulk.UnsubscribeURL != "" {
+			return nil, fmt.Errorf("%w: visit %s to unsubscribe", ErrCannotUnsubscribe, message.Bulk.UnsubscribeURL)
+		}
+		return nil, fmt.Errorf("%w: mail delivery is not configured", ErrCannotUnsubscribe)
+	}
+
+	id, err := newID()
+	if err != nil {
+		return nil, err
+	}
+	now := s.clock.Now().UTC()
+	unsubscription := Unsubscription{
+		ID:        id,
+		MessageID: message.ID,
+		Sender:    sender,
+		ListID:    message.Bulk.ListID,
+		Method:    method,
+		Target:    target,
+		Status:    StatusPending,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	entry, err := outbox.NewEntry(OutboxDestination, id, request{UnsubscriptionID: id}, now)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.Save(ctx, unsubscription, entry); err != nil {
+		return nil, err
+	}
+	return &unsubscription, nil
+}
+
+// Get returns an unsubscription by ID.
+func (s *Service) Get(ctx context.Context, id string) (*Unsubscription, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	unsubscription, err := s.repo.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if unsubscription == nil {
+		return nil, ErrUnsubscriptionNotFound
+	}
+	return unsubscription, nil
+}
+
+// List returns every unsubscription, newest first.
+func (s *Service) List(ctx context.Context) ([]Unsubscription, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	list, err := s.repo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if list == nil {
+		list = []Unsubscription{}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].CreatedAt.After(list[j].CreatedAt) })
+	return list, nil
+}
+
+// Deliverer returns the outbox deliverer performing unsubscriptions.
+func (s *Service) Deliverer() outbox.Deliverer {
+	return outbox.DelivererFunc(func(ctx context.Context, entry outbox.Entry) error {
+		var payload request
+		if err := json.Unmarshal(entry.Payload, &payload); err != nil {
+			return fmt.Errorf("decode unsubscription: %w", err)
+		}
+		unsubscription, err := s.repo.Get(ctx, payload.UnsubscriptionID)
+		if err != nil {
+			return err
+		}
+		if unsubscription == nil {
+			return ErrUnsubscriptionNotFound
+		}
+		if unsubscription.Status == StatusUnsubscribed {
+			return nil
+		}
+
+		switch unsubscription.Method {
+		case MethodOneClick:
+			err = s.post(ctx, unsubscription.Target)
+		case MethodMailto:
+			err = s.mail(ctx, unsubscription.Target)
+		default:
+			err = fmt.Errorf("unknown unsubscribe method %q", unsubscription.Method)
+		}
+		if err != nil {
+			return err
+		}
+		now := s.clock.Now().UTC()
+		unsubscription.Status = StatusUnsubscribed
+		unsubscription.UpdatedAt = now
+		unsubscription.CompletedAt = &now
+		return s.repo.Save(ctx, *unsubscription)
+	})
+}
+
+// trusted reports whether a one-click endpoint of message may be posted to: the
+// receiving server verified its DKIM signature and DMARC alignment, and it was
+// not filed as spam.
+func trusted(message email.EmailMessage) bool {
+	verdicts := message.Headers.Authentication()
+	return verdicts["dkim"] == "pass" && verdicts["dmarc"] == "pass" && message.Category != spam.Category
+}
+
+// find returns the pending or completed unsubscription of the list, or of the
+// sender for messages without a List-Id, if present. Failed ones are skipped so
+// the list can be unsubscribed from again.
+func (s *Service) find(ctx context.Context, listID, sender string) (*Unsubscription, error) {
+	list, err := s.repo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, unsubscription := range list {
+		if unsubscription.Status == StatusFailed {
+			continue
+		}
+		if listID != "" && unsubscription.ListID == listID ||
+			listID == "" && unsubscription.ListID == "" && unsubscription.Sender == sender {
+			return &unsubscription, nil
+		}
+	}
+	return nil, nil
+}
+
+// post performs an RFC 8058 one-click unsubscribe.
+func (s *Service) post(ctx context.Context, target string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, strings.NewReader("List-Unsubscribe=One-Click"))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxResponseBytes))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unsubscribe endpoint returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// mail sends the unsubscribe message of a mailto URI from the authenticated
+// mailbox.
+func (s *Service) mail(ctx context.Context, target string) error {
+	if s.sender == nil {
+		return email.ErrSenderNotConfigured
+	}
+	to, subject, body, err := parseMailto(target)
+	if err != nil {
+		return err
+	}
+	auth, err := s.messages.GetAuth(ctx)
+	if err != nil {
+		return err
+	}
+	if auth == nil || auth.State.Username == "" {
+		return email.ErrProviderNotAuthenticated
+	}
+	owner := auth.State.Username
+	now := s.clock.Now().UTC()
+	messageID, err := email.NewMessageID(owner, now)
+	if err != nil {
+		return err
+	}
+	return s.sender.Send(ctx, email.OutgoingMessage{
+		From:      owner,
+		To:        []string{to},
+		Subject:   subject,
+		TextBody:  body,
+		Date:      now,
+		MessageID: messageID,
+	})
+}
+
+// parseMailto returns the address, subject and body of a mailto URI. Subject
+// and body default to "unsubscribe".
+func parseMailto(target string) (to, subject, body string, err error) {
+	uri, err := url.Parse(target)
+	if err != nil || uri.Scheme != "mailto" {
+		return "", "", "", fmt.Errorf("invalid mailto URI %q", target)
+	}
+	address, err := url.PathUnescape(uri.Opaque)
+	if err != nil {
+		return "", "", "", fmt.Errorf("invalid mailto URI %q", target)
+	}
+	parsed, err := mail.ParseAddress(address)
+	if err != nil {
+		return "", "", "", fmt.Errorf("invalid mailto address %q", address)
+	}
+	query := uri.Query()
+	subject, body = query.Get("subject"), query.Get("body")
+	if subject == "" {
+		subject = "unsubscribe"
+	}
+	if body == "" {
+		body = "unsubscribe"
+	}
+	return parsed.Address, subject, body, nil
+}
+
+// publicOnly refuses connections to loopback, private and link-local
+// addresses, which unsubscribe links in received mail must not reach.
+func publicOnly(_, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || !ip.IsGlobalUnicast() || ip.IsPrivate() {
+		return fmt.Errorf("%w: %s", errPrivateAddress, host)
+	}
+	return nil
+}
+
+func newID() (string, error) {
+	buf := make([]byte, 6)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("generate unsubscription id: %w", err)
+	}
+	return "uns-" + hex.EncodeToString(buf), nil
+}
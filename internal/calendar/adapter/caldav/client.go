@@ -0,0 +1,225 @@
+// Package caldav reads busy times from a CalDAV calendar collection or a
+// published iCalendar feed, and writes events back to CalDAV collections.
+package caldav
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/example/iboz/internal/calendar"
+	"github.com/example/iboz/internal/email"
+)
+
+const (
+	defaultTimeout = 10 * time.Second
+	maxBody        = 4 << 20
+	timeRange      = "20060102T150405Z"
+)
+
+var (
+	_ calendar.Source = (*Client)(nil)
+	_ calendar.Writer = (*Client)(nil)
+	_ calendar.Source = (*Feed)(nil)
+)
+
+// Config configures a Client. URL is the calendar collection, such as
+// https://dav.example.com/calendars/me/work/. Location reads floating times
+// and all-day events and defaults to UTC.
+type Config struct {
+	URL      string
+	Username string
+	Password string
+	Location *time.Location
+	Client   *http.Client
+}
+
+// Client queries a CalDAV calendar collection (RFC 4791) with basic auth.
+type Client struct {
+	cfg   Config
+	clock email.Clock
+}
+
+// NewClient constructs a CalDAV Client. It panics without a collection URL.
+func NewClient(cfg Config, clock email.Clock) *Client {
+	if cfg.URL == "" {
+		panic("caldav: calendar url is required")
+	}
+	if clock == nil {
+		panic("caldav: clock dependency is required")
+	}
+	cfg.URL = strings.TrimRight(cfg.URL, "/") + "/"
+	if cfg.Location == nil {
+		cfg.Location = time.UTC
+	}
+	if cfg.Client == nil {
+		cfg.Client = &http.Client{Timeout: defaultTimeout}
+	}
+	return &Client{cfg: cfg, clock: clock}
+}
+
+type multistatus struct {
+	Responses []struct {
+		Href      string `xml:"DAV: href"`
+		Propstats []struct {
+			Status string `xml:"DAV: status"`
+			Prop   struct {
+				Data string `xml:"urn:ietf:params:xml:ns:caldav calendar-data"`
+			} `xml:"DAV: prop"`
+		} `xml:"DAV: propstat"`
+	} `xml:"DAV: response"`
+}
+
+// Events implements the calendar.Source interface with a calendar-query
+// REPORT. The server is asked to expand recurring events within the range.
+func (c *Client) Events(ctx context.Context, from, to time.Time) ([]calendar.Event, error) {
+	start, end := from.UTC().Format(timeRange), to.UTC().Format(timeRange)
+	query := `<?xml version="1.0" encoding="utf-8"?>
+<C:calendar-query xmlns:D="DAV:" xmlns:C="urn:ietf:params:xml:ns:caldav">
+  <D:prop>
+    <C:calendar-data><C:expand start="` + start + `" end="` + end + `"/></C:calendar-data>
+  </D:prop>
+  <C:filter>
+    <C:comp-filter name="VCALENDAR">
+      <C:comp-filter name="VEVENT"><C:time-range start="` + start + `" end="` + end + `"/></C:comp-filter>
+    </C:comp-filter>
+  </C:filter>
+</C:calendar-query>`
+	body, err := c.do(ctx, "REPORT", c.cfg.URL, "application/xml; charset=utf-8", strings.NewReader(query), map[string]string{"Depth": "1"})
+	if err != nil {
+		return nil, fmt.Errorf("caldav: query events: %w", err)
+	}
+	var status multistatus
+	if err := xml.Unmarshal(body, &status); err != nil {
+		return nil, fmt.Errorf("caldav: decode multistatus: %w", err)
+	}
+
+	var events []calendar.Event
+	for _, response := range status.Responses {
+		for _, propstat := range response.Propstats {
+			if strings.TrimSpace(propstat.Prop.Data) == "" || (propstat.Status != "" && !strings.Contains(propstat.Status, " 200 ")) {
+				continue
+			}
+			parsed, err := calendar.ParseEvents(strings.NewReader(propstat.Prop.Data), c.cfg.Location, from, to)
+			if err != nil {
+				return nil, fmt.Errorf("caldav: %s: %w", response.Href, err)
+			}
+			events = append(events, parsed...)
+		}
+	}
+	return overlapping(events, from, to), nil
+}
+
+// PutEvent implements the calendar.Writer interface. Each event is stored as
+// <uid>.ics in the collection, so writing the same UID again replaces it.
+func (c *Client) PutEvent(ctx context.Context, event calendar.Event) error {
+	if strings.TrimSpace(event.UID) == "" {
+		return fmt.Errorf("caldav: event uid is required")
+	}
+	var buf bytes.Buffer
+	if err := calendar.EncodeEvent(&buf, event, c.clock.Now()); err != nil {
+		return err
+	}
+	target := c.cfg.URL + url.PathEscape(event.UID) + ".ics"
+	if _, err := c.do(ctx, http.MethodPut, target, "text/calendar; charset=utf-8", &buf, nil); err != nil {
+		return fmt.Errorf("caldav: put event %s: %w", event.UID, err)
+	}
+	return nil
+}
+
+func (c *Client) do(ctx context.Context, method, target, contentType string, body io.Reader, headers map[string]string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, method, target, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", contentType)
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	if c.cfg.Username != "" {
+		req.SetBasicAuth(c.cfg.Username, c.cfg.Password)
+	}
+	return send(c.cfg.Client, req)
+}
+
+// Feed reads a published iCalendar feed, such as the secret address of a
+// hosted calendar. It is read-only.
+type Feed struct {
+	url    string
+	loc    *time.Location
+	client *http.Client
+}
+
+// NewFeed constructs a Feed reading rawURL; webcal:// addresses are fetched
+// over HTTPS. loc reads floating times and defaults to UTC. It panics without
+// a URL.
+func NewFeed(rawURL string, loc *time.Location, client *http.Client) *Feed {
+	if rawURL == "" {
+		panic("caldav: feed url is required")
+	}
+	if rest, ok := strings.CutPrefix(rawURL, "webcal://"); ok {
+		rawURL = "https://" + rest
+	}
+	if loc == nil {
+		loc = time.UTC
+	}
+	if client == nil {
+		client = &http.Client{Timeout: defaultTimeout}
+	}
+	return &Feed{url: rawURL, loc: loc, client: client}
+}
+
+// Events implements the calendar.Source interface by downloading the feed.
+// Feeds list recurring events once, so they are expanded within the range.
+func (f *Feed) Events(ctx context.Context, from, to time.Time) ([]calendar.Event, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, f.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "text/calendar")
+	body, err := send(f.client, req)
+	if err != nil {
+		return nil, fmt.Errorf("caldav: fetch feed: %w", err)
+	}
+	events, err := calendar.ParseEvents(bytes.NewReader(body), f.loc, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("caldav: feed: %w", err)
+	}
+	return overlapping(events, from, to), nil
+}
+
+func send(client *http.Client, req *http.Request) ([]byte, error) {
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxBody))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("unexpected status %d: %s", resp.StatusCode, bytes.TrimSpace(body))
+	}
+	return body, nil
+}
+
+// overlapping keeps the events within [from, to), ordered by start, since
+// servers may return whole objects and feeds are never filtered.
+func overlapping(events []calendar.Event, from, to time.Time) []calendar.Event {
+	kept := make([]calendar.Event, 0, len(events))
+	for _, event := range events {
+		if event.Overlaps(from, to) {
+			kept = append(kept, event)
+		}
+	}
+	sort.SliceStable(kept, func(i, j int) bool { return kept[i].Start.Before(kept[j].Start) })
+	return kept
+}
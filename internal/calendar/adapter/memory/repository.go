@@ -0,0 +1,57 @@
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/example/iboz/internal/calendar"
+)
+
+var _ calendar.Repository = (*Repository)(nil)
+
+// Repository provides an in-memory implementation of the calendar.Repository port.
+type Repository struct {
+	mu       sync.RWMutex
+	settings map[string]calendar.Settings
+}
+
+// NewRepository builds a new in-memory calendar repository.
+func NewRepository() *Repository {
+	return &Repository{settings: make(map[string]calendar.Settings)}
+}
+
+// Save inserts or replaces the settings of a user.
+func (r *Repository) Save(ctx context.Context, settings calendar.Settings) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	r.settings[settings.User] = clone(settings)
+	r.mu.Unlock()
+	return nil
+}
+
+// Get returns the settings of a user if present.
+func (r *Repository) Get(ctx context.Context, user string) (*calendar.Settings, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	settings, ok := r.settings[user]
+	if !ok {
+		return nil, nil
+	}
+	cloned := clone(settings)
+	return &cloned, nil
+}
+
+func clone(settings calendar.Settings) calendar.Settings {
+	settings.Hours.Days = append([]time.Weekday(nil), settings.Hours.Days...)
+	settings.Hours.Holidays = append([]string(nil), settings.Hours.Holidays...)
+	return settings
+}
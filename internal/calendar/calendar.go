@@ -0,0 +1,229 @@
+package calendar
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/example/iboz/internal/email"
+)
+
+// ErrInvalidHours is returned when working hours fail validation.
+var ErrInvalidHours = errors.New("invalid working hours")
+
+// Hours describes a working week. Days defaults to Monday through Friday and
+// Holidays lists non-working dates as YYYY-MM-DD in the calendar's timezone.
+type Hours struct {
+	Timezone string         `json:"timezone,omitempty"`
+	Start    string         `json:"start"`
+	End      string         `json:"end"`
+	Days     []time.Weekday `json:"days,omitempty"`
+	Holidays []string       `json:"holidays,omitempty"`
+}
+
+// DefaultHours returns a 09:00-17:00 Monday to Friday week in UTC.
+func DefaultHours() Hours {
+	return Hours{Start: "09:00", End: "17:00"}
+}
+
+// Provider resolves the calendar that applies to the current mailbox owner.
+type Provider interface {
+	Calendar(ctx context.Context) (*Calendar, error)
+}
+
+var _ Provider = (*Calendar)(nil)
+
+// Calendar answers working-time questions for one set of Hours. All "now"
+// questions are answered through the injected clock.
+type Calendar struct {
+	hours    Hours
+	loc      *time.Location
+	start    time.Duration
+	end      time.Duration
+	days     map[time.Weekday]bool
+	holidays map[string]bool
+	clock    email.Clock
+}
+
+// New validates hours and builds a Calendar reading the time from clock.
+func New(hours Hours, clock email.Clock) (*Calendar, error) {
+	if clock == nil {
+		panic("calendar: clock dependency is required")
+	}
+	loc := time.UTC
+	if name := strings.TrimSpace(hours.Timezone); name != "" {
+		var err error
+		if loc, err = time.LoadLocation(name); err != nil {
+			return nil, fmt.Errorf("%w: unknown timezone %q", ErrInvalidHours, name)
+		}
+	}
+	start, err := parseClock(hours.Start)
+	if err != nil {
+		return nil, fmt.Errorf("%w: start: %v", ErrInvalidHours, err)
+	}
+	end, err := parseClock(hours.End)
+	if err != nil {
+		return nil, fmt.Errorf("%w: end: %v", ErrInvalidHours, err)
+	}
+	if end <= start {
+		return nil, fmt.Errorf("%w: working hours must end after they start", ErrInvalidHours)
+	}
+
+	cal := &Calendar{hours: hours, loc: loc, start: start, end: end, days: map[time.Weekday]bool{}, holidays: map[string]bool{}, clock: clock}
+	days := hours.Days
+	if len(days) == 0 {
+		days = []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday}
+	}
+	for _, day := range days {
+		if day < time.Sunday || day > time.Saturday {
+			return nil, fmt.Errorf("%w: invalid weekday %d", ErrInvalidHours, day)
+		}
+		cal.days[day] = true
+	}
+	for _, holiday := range hours.Holidays {
+		if _, err := time.Parse(time.DateOnly, holiday); err != nil {
+			return nil, fmt.Errorf("%w: invalid holiday %q", ErrInvalidHours, holiday)
+		}
+		cal.holidays[holiday] = true
+	}
+	return cal, nil
+}
+
+// Calendar implements Provider so a fixed calendar can be injected directly.
+func (c *Calendar) Calendar(context.Context) (*Calendar, error) {
+	return c, nil
+}
+
+// Hours returns the working hours the calendar was built from.
+func (c *Calendar) Hours() Hours {
+	hours := c.hours
+	hours.Days = append([]time.Weekday(nil), c.hours.Days...)
+	hours.Holidays = append([]string(nil), c.hours.Holidays...)
+	return hours
+}
+
+// Location returns the calendar's timezone.
+func (c *Calendar) Location() *time.Location {
+	return c.loc
+}
+
+// In returns a copy of the calendar evaluated in loc instead of its own timezone.
+func (c *Calendar) In(loc *time.Location) *Calendar {
+	clone := *c
+	clone.loc = loc
+	clone.hours.Timezone = loc.String()
+	return &clone
+}
+
+// Now returns the clock's current time in the calendar's timezone.
+func (c *Calendar) Now() time.Time {
+	return c.clock.Now().In(c.loc)
+}
+
+// IsWorkingDay reports whether t falls on a working weekday that is not a holiday.
+func (c *Calendar) IsWorkingDay(t time.Time) bool {
+	local := t.In(c.loc)
+	return c.days[local.Weekday()] && !c.holidays[local.Format(time.DateOnly)]
+}
+
+// IsWorkingTime reports whether t falls within working hours.
+func (c *Calendar) IsWorkingTime(t time.Time) bool {
+	if !c.IsWorkingDay(t) {
+		return false
+	}
+	local := t.In(c.loc)
+	return !local.Before(at(local, c.start)) && local.Before(at(local, c.end))
+}
+
+// WorkingDay returns the working hours of the day t falls on, in UTC, and
+// false when that day is not a working day.
+func (c *Calendar) WorkingDay(t time.Time) (time.Time, time.Time, bool) {
+	day := midnight(t.In(c.loc))
+	if !c.IsWorkingDay(day) {
+		return time.Time{}, time.Time{}, false
+	}
+	return at(day, c.start).UTC(), at(day, c.end).UTC(), true
+}
+
+// AfterHours reports whether the current time is outside working hours.
+func (c *Calendar) AfterHours() bool {
+	return !c.IsWorkingTime(c.Now())
+}
+
+// NextWorkingTime returns t when it falls within working hours, otherwise the next opening.
+func (c *Calendar) NextWorkingTime(t time.Time) time.Time {
+	if c.IsWorkingTime(t) {
+		return t.UTC()
+	}
+	return c.NextOpening(t)
+}
+
+// NextOpening returns the first start of working hours strictly after t.
+func (c *Calendar) NextOpening(t time.Time) time.Time {
+	local := t.In(c.loc)
+	day := midnight(local)
+	for {
+		opens := at(day, c.start)
+		if opens.After(local) && c.IsWorkingDay(day) {
+			return opens.UTC()
+		}
+		day = time.Date(day.Year(), day.Month(), day.Day()+1, 0, 0, 0, 0, c.loc)
+	}
+}
+
+// Add advances t by d of working time, pausing outside working hours.
+func (c *Calendar) Add(t time.Time, d time.Duration) time.Time {
+	cursor := t.In(c.loc)
+	remaining := d
+	for {
+		day := midnight(cursor)
+		if c.IsWorkingDay(day) {
+			opens := at(day, c.start)
+			closes := at(day, c.end)
+			if cursor.Before(opens) {
+				cursor = opens
+			}
+			if cursor.Before(closes) {
+				available := closes.Sub(cursor)
+				if remaining <= available {
+					return cursor.Add(remaining).UTC()
+				}
+				remaining -= available
+			}
+		}
+		cursor = time.Date(day.Year(), day.Month(), day.Day()+1, 0, 0, 0, 0, c.loc)
+	}
+}
+
+// AddWorkingDays advances t by n working days, keeping the local time of day.
+func (c *Calendar) AddWorkingDays(t time.Time, n int) time.Time {
+	local := t.In(c.loc)
+	for added := 0; added < n; {
+		local = local.AddDate(0, 0, 1)
+		if c.IsWorkingDay(local) {
+			added++
+		}
+	}
+	return local.UTC()
+}
+
+func midnight(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// at returns the wall-clock time of day on the date of day, so opening and
+// closing times stay put on daylight saving transition days.
+func at(day time.Time, clock time.Duration) time.Time {
+	hour, minute := int(clock/time.Hour), int(clock%time.Hour/time.Minute)
+	return time.Date(day.Year(), day.Month(), day.Day(), hour, minute, 0, 0, day.Location())
+}
+
+func parseClock(value string) (time.Duration, error) {
+	parsed, err := time.Parse("15:04", strings.TrimSpace(value))
+	if err != nil {
+		return 0, fmt.Errorf("expected HH:MM, got %q", value)
+	}
+	return time.Duration(parsed.Hour())*time.Hour + time.Duration(parsed.Minute())*time.Minute, nil
+}
@@ -0,0 +1,458 @@
+package calendar_test
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/example/iboz/internal/calendar"
+	"github.com/example/iboz/internal/calendar/adapter/caldav"
+	"github.com/example/iboz/internal/calendar/adapter/memory"
+	"github.com/example/iboz/internal/email"
+	emailmemory "github.com/example/iboz/internal/email/adapter/memory"
+	"github.com/example/iboz/internal/testutil"
+)
+
+func newCalendar(t *testing.T, hours calendar.Hours, clock email.Clock) *calendar.Calendar {
+	t.Helper()
+	cal, err := calendar.New(hours, clock)
+	if err != nil {
+		t.Fatalf("calendar: %v", err)
+	}
+	return cal
+}
+
+func TestNextOpening(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skipf("timezone data unavailable: %v", err)
+	}
+	cal := newCalendar(t, calendar.Hours{Timezone: "Europe/Berlin", Start: "08:00", End: "17:00"}, testutil.NewClock(time.Time{}))
+
+	cases := []struct {
+		name string
+		now  time.Time
+		want time.Time
+	}{
+		{"weekday before eight", time.Date(2025, time.March, 18, 6, 0, 0, 0, berlin), time.Date(2025, time.March, 18, 8, 0, 0, 0, berlin)},
+		{"weekday after eight", time.Date(2025, time.March, 18, 9, 0, 0, 0, berlin), time.Date(2025, time.March, 19, 8, 0, 0, 0, berlin)},
+		{"friday evening", time.Date(2025, time.March, 21, 18, 0, 0, 0, berlin), time.Date(2025, time.March, 24, 8, 0, 0, 0, berlin)},
+		{"saturday morning", time.Date(2025, time.March, 22, 7, 0, 0, 0, berlin), time.Date(2025, time.March, 24, 8, 0, 0, 0, berlin)},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := cal.NextOpening(tc.now.UTC())
+			if !got.Equal(tc.want) {
+				t.Fatalf("expected %s, got %s", tc.want, got.In(berlin))
+			}
+		})
+	}
+}
+
+func TestWorkingHoursKeepWallClockOnDSTDays(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skipf("timezone data unavailable: %v", err)
+	}
+	everyDay := []time.Weekday{time.Sunday, time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday, time.Saturday}
+	cal := newCalendar(t, calendar.Hours{Timezone: "Europe/Berlin", Start: "09:00", End: "17:00", Days: everyDay}, testutil.NewClock(time.Time{}))
+
+	// Clocks go forward on Sunday 30 March and back on Sunday 26 October 2025.
+	for _, day := range []int{30, 26} {
+		month := time.March
+		if day == 26 {
+			month = time.October
+		}
+		opens := time.Date(2025, month, day, 9, 0, 0, 0, berlin)
+		closes := time.Date(2025, month, day, 17, 0, 0, 0, berlin)
+		if got := cal.NextOpening(opens.Add(-3 * time.Hour)); !got.Equal(opens) {
+			t.Fatalf("expected opening at %s, got %s", opens, got.In(berlin))
+		}
+		start, end, ok := cal.WorkingDay(opens)
+		if !ok || !start.Equal(opens) || !end.Equal(closes) {
+			t.Fatalf("expected %s-%s, got %s-%s", opens, closes, start.In(berlin), end.In(berlin))
+		}
+		if cal.IsWorkingTime(opens.Add(-time.Minute)) || !cal.IsWorkingTime(closes.Add(-time.Minute)) || cal.IsWorkingTime(closes) {
+			t.Fatalf("expected working time to follow the wall clock on %s", opens.Format(time.DateOnly))
+		}
+		if got := cal.Add(opens, 8*time.Hour); !got.Equal(closes) {
+			t.Fatalf("expected a full day to end at %s, got %s", closes, got.In(berlin))
+		}
+	}
+}
+
+func TestAddWorkingDaysSkipsWeekendsAndHolidays(t *testing.T) {
+	cal := newCalendar(t, calendar.DefaultHours(), testutil.NewClock(time.Time{}))
+	friday := time.Date(2025, time.March, 21, 10, 0, 0, 0, time.UTC)
+	if got, want := cal.AddWorkingDays(friday, 2), time.Date(2025, time.March, 25, 10, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Fatalf("expected %s, got %s", want, got)
+	}
+
+	hours := calendar.DefaultHours()
+	hours.Holidays = []string{"2025-03-24"}
+	cal = newCalendar(t, hours, testutil.NewClock(time.Time{}))
+	if got, want := cal.AddWorkingDays(friday, 2), time.Date(2025, time.March, 26, 10, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Fatalf("expected %s with holiday, got %s", want, got)
+	}
+}
+
+func TestAddCountsWorkingTimeOnly(t *testing.T) {
+	cal := newCalendar(t, calendar.Hours{Timezone: "America/New_York", Start: "09:00", End: "17:00", Holidays: []string{"2025-03-24"}}, testutil.NewClock(time.Time{}))
+	// Friday 16:00 New York: one hour on Friday, Monday is a holiday, three hours on Tuesday.
+	received := time.Date(2025, time.March, 21, 20, 0, 0, 0, time.UTC)
+	want := time.Date(2025, time.March, 25, 16, 0, 0, 0, time.UTC)
+	if got := cal.Add(received, 4*time.Hour); !got.Equal(want) {
+		t.Fatalf("expected %s, got %s", want, got)
+	}
+}
+
+func TestAfterHoursFollowsClock(t *testing.T) {
+	clock := testutil.NewClock(time.Date(2025, time.March, 18, 10, 0, 0, 0, time.UTC))
+	cal := newCalendar(t, calendar.DefaultHours(), clock)
+	if cal.AfterHours() {
+		t.Fatalf("tuesday 10:00 should be working time")
+	}
+	if got := cal.NextWorkingTime(clock.Now()); !got.Equal(clock.Now()) {
+		t.Fatalf("expected working time to be returned unchanged, got %s", got)
+	}
+
+	clock.Set(time.Date(2025, time.March, 18, 17, 0, 0, 0, time.UTC))
+	if !cal.AfterHours() {
+		t.Fatalf("17:00 should be after hours")
+	}
+	if got, want := cal.NextWorkingTime(clock.Now()), time.Date(2025, time.March, 19, 9, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Fatalf("expected %s, got %s", want, got)
+	}
+}
+
+func TestHoldsEvaluatesTimeConditions(t *testing.T) {
+	clock := testutil.NewClock(time.Date(2025, time.March, 18, 10, 0, 0, 0, time.UTC))
+	cal := newCalendar(t, calendar.DefaultHours(), clock)
+
+	for _, tc := range []struct {
+		now       time.Time
+		condition string
+		want      bool
+	}{
+		{clock.Now(), calendar.ConditionBusinessHours, true},
+		{clock.Now(), calendar.ConditionAfterHours, false},
+		{time.Date(2025, time.March, 18, 22, 0, 0, 0, time.UTC), "TIME:after_hours", true},
+		{time.Date(2025, time.March, 22, 10, 0, 0, 0, time.UTC), calendar.ConditionAfterHours, true},
+	} {
+		clock.Set(tc.now)
+		got, err := cal.Holds(tc.condition)
+		if err != nil || got != tc.want {
+			t.Fatalf("%s at %s: expected %v, got %v (%v)", tc.condition, tc.now, tc.want, got, err)
+		}
+	}
+	if _, err := cal.Holds("time:lunch"); !errors.Is(err, calendar.ErrUnknownCondition) {
+		t.Fatalf("expected unknown condition error, got %v", err)
+	}
+}
+
+func TestNewRejectsInvalidHours(t *testing.T) {
+	cases := []calendar.Hours{
+		{Start: "17:00", End: "09:00"},
+		{Start: "9am", End: "17:00"},
+		{Timezone: "Mars/Olympus", Start: "09:00", End: "17:00"},
+		{Start: "09:00", End: "17:00", Holidays: []string{"25/12/2025"}},
+		{Start: "09:00", End: "17:00", Days: []time.Weekday{9}},
+	}
+	for _, hours := range cases {
+		if _, err := calendar.New(hours, testutil.NewClock(time.Time{})); !errors.Is(err, calendar.ErrInvalidHours) {
+			t.Fatalf("expected invalid hours for %+v, got %v", hours, err)
+		}
+	}
+}
+
+const holidaysICS = "BEGIN:VCALENDAR\r\n" +
+	"VERSION:2.0\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"DTSTART;VALUE=DATE:20251225\r\n" +
+	"DTEND;VALUE=DATE:20251227\r\n" +
+	"SUMMARY:Christmas\\, Boxing\r\n" +
+	"  Day\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"DTSTART:20250101T000000Z\r\n" +
+	"SUMMARY:New Year\r\n" +
+	"END:VEVENT\r\n" +
+	"END:VCALENDAR\r\n"
+
+func TestParseICS(t *testing.T) {
+	holidays, err := calendar.ParseICS(strings.NewReader(holidaysICS))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	want := []calendar.Holiday{
+		{Date: "2025-01-01", Name: "New Year"},
+		{Date: "2025-12-25", Name: "Christmas, Boxing Day"},
+		{Date: "2025-12-26", Name: "Christmas, Boxing Day"},
+	}
+	if len(holidays) != len(want) {
+		t.Fatalf("expected %d holidays, got %+v", len(want), holidays)
+	}
+	for i := range want {
+		if holidays[i] != want[i] {
+			t.Fatalf("holiday %d: expected %+v, got %+v", i, want[i], holidays[i])
+		}
+	}
+
+	if _, err := calendar.ParseICS(strings.NewReader("BEGIN:VCALENDAR\nBEGIN:VEVENT\nSUMMARY:Broken\nEND:VEVENT\nEND:VCALENDAR\n")); !errors.Is(err, calendar.ErrInvalidICS) {
+		t.Fatalf("expected invalid ics without DTSTART, got %v", err)
+	}
+}
+
+func TestServiceStoresSettingsPerUser(t *testing.T) {
+	ctx := context.Background()
+	clock := testutil.NewClock(time.Date(2025, time.March, 18, 10, 0, 0, 0, time.UTC))
+	auth := emailmemory.NewRepository()
+	svc := calendar.NewService(memory.NewRepository(), auth, calendar.DefaultHours(), clock)
+
+	if _, err := svc.UpdateSettings(ctx, calendar.DefaultHours()); !errors.Is(err, email.ErrProviderNotAuthenticated) {
+		t.Fatalf("expected unauthenticated error, got %v", err)
+	}
+	if err := auth.SaveAuth(ctx, email.AuthRecord{State: email.AuthState{Username: "me@example.com"}}); err != nil {
+		t.Fatalf("save auth: %v", err)
+	}
+
+	settings, err := svc.UpdateSettings(ctx, calendar.Hours{Timezone: "Asia/Tokyo", Start: "10:00", End: "19:00"})
+	if err != nil {
+		t.Fatalf("update settings: %v", err)
+	}
+	if settings.User != "me@example.com" || !settings.UpdatedAt.Equal(clock.Now()) {
+		t.Fatalf("unexpected settings: %+v", settings)
+	}
+	if _, err := svc.ImportHolidays(ctx, strings.NewReader(holidaysICS)); err != nil {
+		t.Fatalf("import holidays: %v", err)
+	}
+
+	cal, err := svc.Calendar(ctx)
+	if err != nil {
+		t.Fatalf("calendar: %v", err)
+	}
+	if cal.Location().String() != "Asia/Tokyo" || len(cal.Hours().Holidays) != 3 {
+		t.Fatalf("unexpected calendar hours: %+v", cal.Hours())
+	}
+	if cal.IsWorkingDay(time.Date(2025, time.December, 25, 12, 0, 0, 0, cal.Location())) {
+		t.Fatalf("imported holiday should not be a working day")
+	}
+
+	if err := auth.SaveAuth(ctx, email.AuthRecord{State: email.AuthState{Username: "other@example.com"}}); err != nil {
+		t.Fatalf("save auth: %v", err)
+	}
+	settings, err = svc.Settings(ctx)
+	if err != nil || settings.Hours.Timezone != "" || settings.Hours.Start != "09:00" {
+		t.Fatalf("expected defaults for another user, got %+v (%v)", settings, err)
+	}
+}
+
+const eventsICS = "BEGIN:VCALENDAR\r\n" +
+	"BEGIN:VEVENT\r\nUID:standup\r\nSUMMARY:Stand-up\r\nDTSTART;TZID=Europe/Berlin:20250318T100000\r\nDURATION:PT15M\r\nEND:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\nUID:review\r\nSUMMARY:Design review\\, Q2\r\nDTSTART:20250318T130000Z\r\nDTEND:20250318T143000Z\r\nEND:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\nUID:lunch\r\nSUMMARY:Lunch\r\nDTSTART:20250318T120000\r\nDTEND:20250318T130000\r\nTRANSP:TRANSPARENT\r\nEND:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\nUID:offsite\r\nSUMMARY:Offsite\r\nDTSTART;VALUE=DATE:20250319\r\nEND:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\nUID:moved\r\nDTSTART:20250318T150000Z\r\nDTEND:20250318T160000Z\r\nSTATUS:CANCELLED\r\nEND:VEVENT\r\n" +
+	"END:VCALENDAR\r\n"
+
+func TestParseEvents(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("timezone data unavailable: %v", err)
+	}
+	events, err := calendar.ParseEvents(strings.NewReader(eventsICS), newYork, time.Date(2025, time.March, 17, 0, 0, 0, 0, time.UTC), time.Date(2025, time.March, 24, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	want := []calendar.Event{
+		{UID: "standup", Summary: "Stand-up", Start: time.Date(2025, time.March, 18, 9, 0, 0, 0, time.UTC), End: time.Date(2025, time.March, 18, 9, 15, 0, 0, time.UTC)},
+		{UID: "review", Summary: "Design review, Q2", Start: time.Date(2025, time.March, 18, 13, 0, 0, 0, time.UTC), End: time.Date(2025, time.March, 18, 14, 30, 0, 0, time.UTC)},
+		{UID: "lunch", Summary: "Lunch", Start: time.Date(2025, time.March, 18, 16, 0, 0, 0, time.UTC), End: time.Date(2025, time.March, 18, 17, 0, 0, 0, time.UTC), Transparent: true},
+		{UID: "offsite", Summary: "Offsite", Start: time.Date(2025, time.March, 19, 4, 0, 0, 0, time.UTC), End: time.Date(2025, time.March, 20, 4, 0, 0, 0, time.UTC), AllDay: true},
+	}
+	if len(events) != len(want) {
+		t.Fatalf("expected %d events, got %+v", len(want), events)
+	}
+	for i := range want {
+		got := events[i]
+		if got.UID != want[i].UID || got.Summary != want[i].Summary || !got.Start.Equal(want[i].Start) || !got.End.Equal(want[i].End) ||
+			got.AllDay != want[i].AllDay || got.Transparent != want[i].Transparent {
+			t.Fatalf("event %d: expected %+v, got %+v", i, want[i], got)
+		}
+	}
+
+	var buf strings.Builder
+	block := calendar.Event{
+		UID:         "iboz-fs-1",
+		Summary:     "Focus: " + strings.Repeat("Contract renewals; ", 5),
+		Description: "3 messages\nin a session.",
+		Start:       want[1].Start,
+		End:         want[1].End,
+	}
+	if err := calendar.EncodeEvent(&buf, block, want[0].Start); err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	for _, line := range strings.Split(buf.String(), "\r\n") {
+		if len(line) > 75 {
+			t.Fatalf("line longer than 75 octets: %q", line)
+		}
+	}
+	decoded, err := calendar.ParseEvents(strings.NewReader(buf.String()), nil, time.Time{}, time.Time{})
+	if err != nil || len(decoded) != 1 {
+		t.Fatalf("decode: %+v (%v)", decoded, err)
+	}
+	if got := decoded[0]; got.UID != block.UID || got.Summary != block.Summary || got.Description != "3 messages in a session." || !got.Start.Equal(block.Start) || !got.End.Equal(block.End) || got.Transparent {
+		t.Fatalf("round trip changed the event: %+v", got)
+	}
+
+	if _, err := calendar.ParseEvents(strings.NewReader("BEGIN:VCALENDAR\nBEGIN:VEVENT\nDTSTART:20250318T130000Z\nDURATION:soon\nEND:VEVENT\nEND:VCALENDAR\n"), nil, time.Time{}, time.Time{}); !errors.Is(err, calendar.ErrInvalidICS) {
+		t.Fatalf("expected invalid ics for a bad duration, got %v", err)
+	}
+}
+
+const recurringICS = "BEGIN:VCALENDAR\r\n" +
+	// Mondays and Wednesdays at 10:00 Berlin time, except the 19th, and moved on the 24th.
+	"BEGIN:VEVENT\r\nUID:sync\r\nSUMMARY:Team sync\r\nDTSTART;TZID=Europe/Berlin:20250303T100000\r\nDTEND;TZID=Europe/Berlin:20250303T103000\r\n" +
+	"RRULE:FREQ=WEEKLY;BYDAY=MO,WE\r\nEXDATE;TZID=Europe/Berlin:20250319T100000\r\nEND:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\nUID:sync\r\nSUMMARY:Team sync\r\nRECURRENCE-ID;TZID=Europe/Berlin:20250324T100000\r\nDTSTART;TZID=Europe/Berlin:20250324T150000\r\nDTEND;TZID=Europe/Berlin:20250324T153000\r\nEND:VEVENT\r\n" +
+	// The last Friday of the month, three times, plus an extra date.
+	"BEGIN:VEVENT\r\nUID:retro\r\nSUMMARY:Retro\r\nDTSTART:20250131T140000Z\r\nDURATION:PT1H\r\nRRULE:FREQ=MONTHLY;BYDAY=-1FR;COUNT=3\r\nRDATE:20250321T140000Z\r\nEND:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\nUID:daily\r\nSUMMARY:Check-in\r\nDTSTART:20250301T080000Z\r\nDURATION:PT15M\r\nRRULE:FREQ=DAILY;INTERVAL=2;UNTIL=20250320T000000Z\r\nEND:VEVENT\r\n" +
+	"END:VCALENDAR\r\n"
+
+func TestParseEventsExpandsRecurringEvents(t *testing.T) {
+	from, to := time.Date(2025, time.March, 17, 0, 0, 0, 0, time.UTC), time.Date(2025, time.April, 1, 0, 0, 0, 0, time.UTC)
+	events, err := calendar.ParseEvents(strings.NewReader(recurringICS), time.UTC, from, to)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	var got []string
+	for _, event := range events {
+		got = append(got, fmt.Sprintf("%s@%s-%s", event.UID, event.Start.UTC().Format("01-02T15:04"), event.End.UTC().Format("15:04")))
+	}
+	want := []string{
+		"daily@03-17T08:00-08:15", "sync@03-17T09:00-09:30", "daily@03-19T08:00-08:15", "retro@03-21T14:00-15:00",
+		"sync@03-24T14:00-14:30", "sync@03-26T09:00-09:30", "retro@03-28T14:00-15:00", "sync@03-31T08:00-08:30",
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("unexpected occurrences:\n got %v\nwant %v", got, want)
+	}
+
+	unsupported := "BEGIN:VCALENDAR\nBEGIN:VEVENT\nDTSTART:20250318T130000Z\nRRULE:FREQ=MONTHLY;BYSETPOS=-1;BYDAY=MO,TU,WE,TH,FR\nEND:VEVENT\nEND:VCALENDAR\n"
+	if _, err := calendar.ParseEvents(strings.NewReader(unsupported), nil, from, to); !errors.Is(err, calendar.ErrInvalidICS) {
+		t.Fatalf("expected invalid ics for an unsupported rule, got %v", err)
+	}
+}
+
+// davStub is a minimal CalDAV collection: REPORT returns every stored object
+// and PUT stores one under its path.
+type davStub struct {
+	mu      sync.Mutex
+	objects map[string]string
+	reports []string
+}
+
+func (d *davStub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if user, pass, ok := r.BasicAuth(); !ok || user != "me" || pass != "secret" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	body, _ := io.ReadAll(r.Body)
+	switch r.Method {
+	case "REPORT":
+		if r.URL.Path != "/cal/work/" || r.Header.Get("Depth") != "1" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		d.reports = append(d.reports, string(body))
+		w.WriteHeader(http.StatusMultiStatus)
+		fmt.Fprint(w, `<?xml version="1.0"?><d:multistatus xmlns:d="DAV:" xmlns:cal="urn:ietf:params:xml:ns:caldav">`)
+		for path, data := range d.objects {
+			fmt.Fprintf(w, `<d:response><d:href>%s</d:href><d:propstat><d:prop><cal:calendar-data>`, path)
+			_ = xml.EscapeText(w, []byte(data))
+			fmt.Fprint(w, `</cal:calendar-data></d:prop><d:status>HTTP/1.1 200 OK</d:status></d:propstat></d:response>`)
+		}
+		fmt.Fprint(w, `</d:multistatus>`)
+	case http.MethodPut:
+		if !strings.HasPrefix(r.Header.Get("Content-Type"), "text/calendar") {
+			w.WriteHeader(http.StatusUnsupportedMediaType)
+			return
+		}
+		d.objects[r.URL.Path] = string(body)
+		w.WriteHeader(http.StatusCreated)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func TestCalDAVClientReadsAndWritesEvents(t *testing.T) {
+	ctx := context.Background()
+	stub := &davStub{objects: map[string]string{"/cal/work/meetings.ics": eventsICS}}
+	server := httptest.NewServer(stub)
+	defer server.Close()
+	clock := testutil.NewClock(time.Date(2025, time.March, 18, 8, 0, 0, 0, time.UTC))
+	client := caldav.NewClient(caldav.Config{URL: server.URL + "/cal/work", Username: "me", Password: "secret"}, clock)
+
+	from, to := time.Date(2025, time.March, 18, 9, 0, 0, 0, time.UTC), time.Date(2025, time.March, 18, 17, 0, 0, 0, time.UTC)
+	events, err := client.Events(ctx, from, to)
+	if err != nil {
+		t.Fatalf("events: %v", err)
+	}
+	if len(events) != 3 || events[0].UID != "standup" || events[1].UID != "lunch" || events[2].UID != "review" {
+		t.Fatalf("unexpected events: %+v", events)
+	}
+	if report := stub.reports[0]; !strings.Contains(report, `<C:time-range start="20250318T090000Z" end="20250318T170000Z"/>`) || !strings.Contains(report, "<C:expand") {
+		t.Fatalf("unexpected calendar-query: %s", report)
+	}
+
+	block := calendar.Event{UID: "iboz-fs-1", Summary: "Focus: Contracts", Start: to.Add(-time.Hour), End: to.Add(-30 * time.Minute)}
+	if err := client.PutEvent(ctx, block); err != nil {
+		t.Fatalf("put: %v", err)
+	}
+	if stored := stub.objects["/cal/work/iboz-fs-1.ics"]; !strings.Contains(stored, "DTSTAMP:20250318T080000Z") {
+		t.Fatalf("unexpected stored object: %q", stored)
+	}
+	block.End = to
+	if err := client.PutEvent(ctx, block); err != nil {
+		t.Fatalf("replace: %v", err)
+	}
+	events, err = client.Events(ctx, to.Add(-time.Hour), to)
+	if err != nil {
+		t.Fatalf("events after put: %v", err)
+	}
+	if len(events) != 1 || events[0].UID != "iboz-fs-1" || !events[0].End.Equal(to) {
+		t.Fatalf("expected only the replaced block, got %+v", events)
+	}
+
+	denied := caldav.NewClient(caldav.Config{URL: server.URL + "/cal/work/", Username: "me", Password: "wrong"}, clock)
+	if _, err := denied.Events(ctx, from, to); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Fatalf("expected unauthorized, got %v", err)
+	}
+}
+
+func TestFeedReadsPublishedCalendar(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/calendar")
+		fmt.Fprint(w, eventsICS)
+	}))
+	defer server.Close()
+
+	feed := caldav.NewFeed(server.URL+"/basic.ics", time.UTC, nil)
+	events, err := feed.Events(context.Background(), time.Date(2025, time.March, 19, 0, 0, 0, 0, time.UTC), time.Date(2025, time.March, 20, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("events: %v", err)
+	}
+	if len(events) != 1 || events[0].UID != "offsite" || !events[0].AllDay {
+		t.Fatalf("expected only the all-day offsite, got %+v", events)
+	}
+}
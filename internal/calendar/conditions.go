@@ -0,0 +1,34 @@
+package calendar
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Time conditions of the automation rule DSL, as in "tag:vip AND time:after_hours".
+const (
+	ConditionAfterHours    = "time:after_hours"
+	ConditionBusinessHours = "time:business_hours"
+)
+
+// ErrUnknownCondition is returned for a time condition the calendar cannot evaluate.
+var ErrUnknownCondition = errors.New("unknown time condition")
+
+// IsTimeCondition reports whether a rule term is a time condition.
+func IsTimeCondition(term string) bool {
+	return strings.HasPrefix(strings.ToLower(strings.TrimSpace(term)), "time:")
+}
+
+// Holds reports whether a time condition of the rule DSL holds at the clock's
+// current time.
+func (c *Calendar) Holds(condition string) (bool, error) {
+	switch strings.ToLower(strings.TrimSpace(condition)) {
+	case ConditionAfterHours:
+		return c.AfterHours(), nil
+	case ConditionBusinessHours:
+		return !c.AfterHours(), nil
+	default:
+		return false, fmt.Errorf("%w: %q", ErrUnknownCondition, condition)
+	}
+}
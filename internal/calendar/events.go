@@ -0,0 +1,36 @@
+package calendar
+
+import (
+	"context"
+	"time"
+)
+
+// Event is an entry of the mailbox owner's calendar. Transparent events, such
+// as reminders marked free, do not make the owner busy.
+type Event struct {
+	UID         string    `json:"uid"`
+	Summary     string    `json:"summary,omitempty"`
+	Description string    `json:"description,omitempty"`
+	Start       time.Time `json:"start"`
+	End         time.Time `json:"end"`
+	AllDay      bool      `json:"allDay,omitempty"`
+	Transparent bool      `json:"transparent,omitempty"`
+}
+
+// Overlaps reports whether the event covers part of [from, to).
+func (e Event) Overlaps(from, to time.Time) bool {
+	return e.Start.Before(to) && e.End.After(from)
+}
+
+// Source reads the events of the owner's calendar, such as a CalDAV
+// collection or a published iCalendar feed.
+type Source interface {
+	// Events returns the events overlapping [from, to), ordered by start.
+	Events(ctx context.Context, from, to time.Time) ([]Event, error)
+}
+
+// Writer creates events on the owner's calendar. Writing an event whose UID
+// already exists replaces it.
+type Writer interface {
+	PutEvent(ctx context.Context, event Event) error
+}
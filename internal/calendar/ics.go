@@ -0,0 +1,377 @@
+package calendar
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// ErrInvalidICS is returned when a holiday calendar cannot be parsed.
+var ErrInvalidICS = errors.New("invalid ics calendar")
+
+// maxHolidaySpan bounds multi-day events so a malformed DTEND cannot expand without limit.
+const maxHolidaySpan = 366
+
+// Holiday is a non-working date imported from an iCalendar feed.
+type Holiday struct {
+	Date string `json:"date"`
+	Name string `json:"name,omitempty"`
+}
+
+// ParseICS reads the VEVENTs of an RFC 5545 calendar as holidays. Multi-day
+// events yield one holiday per day; DTEND is exclusive as the RFC specifies.
+func ParseICS(r io.Reader) ([]Holiday, error) {
+	lines, err := unfold(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		holidays []Holiday
+		inEvent  bool
+		start    time.Time
+		end      time.Time
+		summary  string
+		seen     bool
+	)
+	for _, line := range lines {
+		name, params, value := splitProperty(line)
+		switch {
+		case name == "BEGIN" && strings.EqualFold(value, "VCALENDAR"):
+			seen = true
+		case name == "BEGIN" && strings.EqualFold(value, "VEVENT"):
+			inEvent, start, end, summary = true, time.Time{}, time.Time{}, ""
+		case name == "END" && strings.EqualFold(value, "VEVENT"):
+			if !inEvent {
+				return nil, fmt.Errorf("%w: unexpected END:VEVENT", ErrInvalidICS)
+			}
+			if start.IsZero() {
+				return nil, fmt.Errorf("%w: event %q has no DTSTART", ErrInvalidICS, summary)
+			}
+			holidays = append(holidays, expand(start, end, summary)...)
+			inEvent = false
+		case !inEvent:
+		case name == "DTSTART":
+			if start, err = parseDate(params, value); err != nil {
+				return nil, err
+			}
+		case name == "DTEND":
+			if end, err = parseDate(params, value); err != nil {
+				return nil, err
+			}
+		case name == "SUMMARY":
+			summary = unescape(value)
+		}
+	}
+	if !seen {
+		return nil, fmt.Errorf("%w: missing BEGIN:VCALENDAR", ErrInvalidICS)
+	}
+	if inEvent {
+		return nil, fmt.Errorf("%w: unterminated VEVENT", ErrInvalidICS)
+	}
+
+	sort.SliceStable(holidays, func(i, j int) bool { return holidays[i].Date < holidays[j].Date })
+	return holidays, nil
+}
+
+func expand(start, end time.Time, summary string) []Holiday {
+	if !end.After(start) {
+		end = start.AddDate(0, 0, 1)
+	}
+	var holidays []Holiday
+	for day := start; day.Before(end) && len(holidays) < maxHolidaySpan; day = day.AddDate(0, 0, 1) {
+		holidays = append(holidays, Holiday{Date: day.Format(time.DateOnly), Name: summary})
+	}
+	return holidays
+}
+
+// unfold joins RFC 5545 continuation lines, which start with a space or tab.
+func unfold(r io.Reader) ([]string, error) {
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidICS, err)
+	}
+	return lines, nil
+}
+
+func splitProperty(line string) (string, map[string]string, string) {
+	colon := strings.IndexByte(line, ':')
+	if colon < 0 {
+		return strings.ToUpper(line), nil, ""
+	}
+	parts := strings.Split(line[:colon], ";")
+	params := make(map[string]string, len(parts)-1)
+	for _, param := range parts[1:] {
+		if key, value, ok := strings.Cut(param, "="); ok {
+			params[strings.ToUpper(key)] = strings.Trim(value, `"`)
+		}
+	}
+	return strings.ToUpper(parts[0]), params, line[colon+1:]
+}
+
+// parseDate returns the calendar date of a DATE or DATE-TIME value. Times are
+// read in their TZID, or UTC, and only the date part is kept.
+func parseDate(params map[string]string, value string) (time.Time, error) {
+	loc := time.UTC
+	if tzid := params["TZID"]; tzid != "" {
+		if parsed, err := time.LoadLocation(tzid); err == nil {
+			loc = parsed
+		}
+	}
+	for _, layout := range []string{"20060102", "20060102T150405Z", "20060102T150405"} {
+		if parsed, err := time.ParseInLocation(layout, value, loc); err == nil {
+			return time.Date(parsed.Year(), parsed.Month(), parsed.Day(), 0, 0, 0, 0, time.UTC), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("%w: unsupported date %q", ErrInvalidICS, value)
+}
+
+func unescape(value string) string {
+	return strings.NewReplacer(`\n`, " ", `\N`, " ", `\,`, ",", `\;`, ";", `\\`, `\`).Replace(value)
+}
+
+// ParseEvents reads the VEVENTs of an RFC 5545 calendar with their times.
+// Floating times and all-day dates are read in loc. An event without DTEND
+// lasts for its DURATION, or a day when it is all-day. Cancelled events are
+// dropped. Recurring events are expanded into their occurrences overlapping
+// [from, to) following RRULE, RDATE and EXDATE, less the instances the
+// calendar overrides with a RECURRENCE-ID; rules that cannot be expanded
+// faithfully are rejected with ErrInvalidICS.
+func ParseEvents(r io.Reader, loc *time.Location, from, to time.Time) ([]Event, error) {
+	if loc == nil {
+		loc = time.UTC
+	}
+	lines, err := unfold(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		events     []Event
+		masters    []recurring
+		overridden = make(map[string][]time.Time)
+		event      Event
+		recurrence recurring
+		instance   time.Time
+		inEvent    bool
+		cancelled  bool
+		duration   time.Duration
+		seen       bool
+	)
+	for _, line := range lines {
+		name, params, value := splitProperty(line)
+		switch {
+		case name == "BEGIN" && strings.EqualFold(value, "VCALENDAR"):
+			seen = true
+		case name == "BEGIN" && strings.EqualFold(value, "VEVENT"):
+			event, recurrence, instance, inEvent, cancelled, duration = Event{}, recurring{}, time.Time{}, true, false, 0
+		case name == "END" && strings.EqualFold(value, "VEVENT"):
+			if !inEvent {
+				return nil, fmt.Errorf("%w: unexpected END:VEVENT", ErrInvalidICS)
+			}
+			inEvent = false
+			if event.Start.IsZero() {
+				return nil, fmt.Errorf("%w: event %q has no DTSTART", ErrInvalidICS, event.Summary)
+			}
+			if !instance.IsZero() {
+				overridden[event.UID] = append(overridden[event.UID], instance)
+			}
+			if cancelled {
+				continue
+			}
+			if !event.End.After(event.Start) {
+				switch {
+				case duration > 0:
+					event.End = event.Start.Add(duration)
+				case event.AllDay:
+					event.End = event.Start.AddDate(0, 0, 1)
+				default:
+					event.End = event.Start
+				}
+			}
+			if recurrence.rule != "" || len(recurrence.rdates) > 0 {
+				recurrence.event = event
+				masters = append(masters, recurrence)
+				continue
+			}
+			events = append(events, event)
+		case !inEvent:
+		case name == "UID":
+			event.UID = value
+		case name == "SUMMARY":
+			event.Summary = unescape(value)
+		case name == "DESCRIPTION":
+			event.Description = unescape(value)
+		case name == "DTSTART":
+			if event.Start, event.AllDay, err = parseTime(params, value, loc); err != nil {
+				return nil, err
+			}
+		case name == "DTEND":
+			if event.End, _, err = parseTime(params, value, loc); err != nil {
+				return nil, err
+			}
+		case name == "DURATION":
+			if duration, err = parseDuration(value); err != nil {
+				return nil, err
+			}
+		case name == "RRULE":
+			recurrence.rule = value
+		case name == "RDATE":
+			dates, err := parseTimes(params, value, loc)
+			if err != nil {
+				return nil, err
+			}
+			recurrence.rdates = append(recurrence.rdates, dates...)
+		case name == "EXDATE":
+			dates, err := parseTimes(params, value, loc)
+			if err != nil {
+				return nil, err
+			}
+			recurrence.exdates = append(recurrence.exdates, dates...)
+		case name == "RECURRENCE-ID":
+			if instance, _, err = parseTime(params, value, loc); err != nil {
+				return nil, err
+			}
+		case name == "TRANSP":
+			event.Transparent = strings.EqualFold(value, "TRANSPARENT")
+		case name == "STATUS":
+			cancelled = strings.EqualFold(value, "CANCELLED")
+		}
+	}
+	if !seen {
+		return nil, fmt.Errorf("%w: missing BEGIN:VCALENDAR", ErrInvalidICS)
+	}
+	if inEvent {
+		return nil, fmt.Errorf("%w: unterminated VEVENT", ErrInvalidICS)
+	}
+	for _, master := range masters {
+		occurrences, err := expandRecurring(master, overridden[master.event.UID], from, to)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, occurrences...)
+	}
+
+	sort.SliceStable(events, func(i, j int) bool { return events[i].Start.Before(events[j].Start) })
+	return events, nil
+}
+
+// EncodeEvent writes event as a calendar object holding one VEVENT, with its
+// times in UTC and stamp as DTSTAMP.
+func EncodeEvent(w io.Writer, event Event, stamp time.Time) error {
+	const layout = "20060102T150405Z"
+	transp := "OPAQUE"
+	if event.Transparent {
+		transp = "TRANSPARENT"
+	}
+	lines := []string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:-//iboz//focus//EN",
+		"BEGIN:VEVENT",
+		"UID:" + event.UID,
+		"DTSTAMP:" + stamp.UTC().Format(layout),
+		"DTSTART:" + event.Start.UTC().Format(layout),
+		"DTEND:" + event.End.UTC().Format(layout),
+		"SUMMARY:" + escape(event.Summary),
+	}
+	if event.Description != "" {
+		lines = append(lines, "DESCRIPTION:"+escape(event.Description))
+	}
+	lines = append(lines, "TRANSP:"+transp, "END:VEVENT", "END:VCALENDAR")
+	for _, line := range lines {
+		if _, err := io.WriteString(w, fold(line)+"\r\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseTime returns the instant of a DATE or DATE-TIME value and whether it is
+// a date. Times are read in UTC when suffixed with Z, else in their TZID or loc.
+func parseTime(params map[string]string, value string, loc *time.Location) (time.Time, bool, error) {
+	if tzid := params["TZID"]; tzid != "" {
+		if parsed, err := time.LoadLocation(tzid); err == nil {
+			loc = parsed
+		}
+	}
+	if parsed, err := time.ParseInLocation("20060102", value, loc); err == nil {
+		return parsed, true, nil
+	}
+	if parsed, err := time.Parse("20060102T150405Z", value); err == nil {
+		return parsed, false, nil
+	}
+	if parsed, err := time.ParseInLocation("20060102T150405", value, loc); err == nil {
+		return parsed, false, nil
+	}
+	return time.Time{}, false, fmt.Errorf("%w: unsupported time %q", ErrInvalidICS, value)
+}
+
+// parseDuration reads a positive RFC 5545 duration such as PT1H30M or P1D.
+func parseDuration(value string) (time.Duration, error) {
+	rest, ok := strings.CutPrefix(strings.TrimPrefix(value, "+"), "P")
+	if !ok || rest == "" {
+		return 0, fmt.Errorf("%w: unsupported duration %q", ErrInvalidICS, value)
+	}
+	units := map[byte]time.Duration{'W': 7 * 24 * time.Hour, 'D': 24 * time.Hour}
+	var total time.Duration
+	number := 0
+	digits := false
+	for i := 0; i < len(rest); i++ {
+		c := rest[i]
+		switch {
+		case c >= '0' && c <= '9':
+			number, digits = number*10+int(c-'0'), true
+		case c == 'T':
+			units = map[byte]time.Duration{'H': time.Hour, 'M': time.Minute, 'S': time.Second}
+		case digits && units[c] > 0:
+			total += time.Duration(number) * units[c]
+			number, digits = 0, false
+		default:
+			return 0, fmt.Errorf("%w: unsupported duration %q", ErrInvalidICS, value)
+		}
+	}
+	if digits {
+		return 0, fmt.Errorf("%w: unsupported duration %q", ErrInvalidICS, value)
+	}
+	return total, nil
+}
+
+func escape(value string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`).Replace(value)
+}
+
+// fold splits a content line into lines of at most 75 octets, without
+// breaking UTF-8 sequences, as RFC 5545 requires.
+func fold(line string) string {
+	var b strings.Builder
+	limit := 75
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		b.WriteString(line[:cut])
+		b.WriteString("\r\n ")
+		line = line[cut:]
+		limit = 74
+	}
+	b.WriteString(line)
+	return b.String()
+}
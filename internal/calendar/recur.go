@@ -0,0 +1,304 @@
+package calendar
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var weekdays = map[string]time.Weekday{
+	"SU": time.Sunday, "MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday,
+	"TH": time.Thursday, "FR": time.Friday, "SA": time.Saturday,
+}
+
+// recurring is a VEVENT with an RRULE or RDATE, kept until the whole calendar
+// is read so instances overridden by a RECURRENCE-ID are known.
+type recurring struct {
+	event   Event
+	rule    string
+	rdates  []time.Time
+	exdates []time.Time
+}
+
+// rule is a parsed RRULE. FREQ, INTERVAL, COUNT, UNTIL, BYDAY, BYMONTHDAY,
+// BYMONTH and WKST are supported; other parts are refused rather than expanded
+// into the wrong occurrences.
+type rule struct {
+	freq       string
+	interval   int
+	count      int
+	until      time.Time
+	byDay      []weekdayNum
+	byMonthDay []int
+	byMonth    []time.Month
+	weekStart  time.Weekday
+}
+
+// weekdayNum is a BYDAY entry such as TU or -1FR; n is zero for every such
+// weekday of the period.
+type weekdayNum struct {
+	n   int
+	day time.Weekday
+}
+
+func parseRule(value string, start time.Time) (rule, error) {
+	unsupported := fmt.Errorf("%w: unsupported recurrence rule %q", ErrInvalidICS, value)
+	r := rule{interval: 1, weekStart: time.Monday}
+	for _, part := range strings.Split(value, ";") {
+		key, val, ok := strings.Cut(part, "=")
+		if !ok {
+			return rule{}, unsupported
+		}
+		var err error
+		switch strings.ToUpper(key) {
+		case "FREQ":
+			r.freq = strings.ToUpper(val)
+		case "INTERVAL":
+			if r.interval, err = strconv.Atoi(val); err != nil || r.interval < 1 {
+				return rule{}, unsupported
+			}
+		case "COUNT":
+			if r.count, err = strconv.Atoi(val); err != nil || r.count < 1 {
+				return rule{}, unsupported
+			}
+		case "UNTIL":
+			if r.until, _, err = parseTime(nil, val, start.Location()); err != nil {
+				return rule{}, err
+			}
+		case "WKST":
+			if r.weekStart, ok = weekdays[strings.ToUpper(val)]; !ok {
+				return rule{}, unsupported
+			}
+		case "BYDAY":
+			for _, item := range strings.Split(strings.ToUpper(val), ",") {
+				if len(item) < 2 {
+					return rule{}, unsupported
+				}
+				day, ok := weekdays[item[len(item)-2:]]
+				if !ok {
+					return rule{}, unsupported
+				}
+				n := 0
+				if ordinal := item[:len(item)-2]; ordinal != "" {
+					if n, err = strconv.Atoi(ordinal); err != nil || n == 0 || n < -5 || n > 5 {
+						return rule{}, unsupported
+					}
+				}
+				r.byDay = append(r.byDay, weekdayNum{n: n, day: day})
+			}
+		case "BYMONTHDAY":
+			for _, item := range strings.Split(val, ",") {
+				day, err := strconv.Atoi(item)
+				if err != nil || day == 0 || day < -31 || day > 31 {
+					return rule{}, unsupported
+				}
+				r.byMonthDay = append(r.byMonthDay, day)
+			}
+		case "BYMONTH":
+			for _, item := range strings.Split(val, ",") {
+				month, err := strconv.Atoi(item)
+				if err != nil || month < 1 || month > 12 {
+					return rule{}, unsupported
+				}
+				r.byMonth = append(r.byMonth, time.Month(month))
+			}
+		default:
+			return rule{}, unsupported
+		}
+	}
+
+	ordinals := false
+	for _, day := range r.byDay {
+		ordinals = ordinals || day.n != 0
+	}
+	switch r.freq {
+	case "DAILY":
+		if ordinals {
+			return rule{}, unsupported
+		}
+	case "WEEKLY":
+		if ordinals || len(r.byMonthDay) > 0 {
+			return rule{}, unsupported
+		}
+		if len(r.byDay) == 0 {
+			r.byDay = []weekdayNum{{day: start.Weekday()}}
+		}
+	case "MONTHLY":
+		if len(r.byDay) == 0 && len(r.byMonthDay) == 0 {
+			r.byMonthDay = []int{start.Day()}
+		}
+	case "YEARLY":
+		// Ordinals are only read within the months of BYMONTH, not the year.
+		if ordinals && len(r.byMonth) == 0 {
+			return rule{}, unsupported
+		}
+		if len(r.byMonth) == 0 && len(r.byDay) == 0 {
+			r.byMonth = []time.Month{start.Month()}
+		}
+		if len(r.byDay) == 0 && len(r.byMonthDay) == 0 {
+			r.byMonthDay = []int{start.Day()}
+		}
+	default:
+		return rule{}, unsupported
+	}
+	return r, nil
+}
+
+// matches reports whether the date d falls under the BY* parts of the rule.
+func (r rule) matches(d time.Time) bool {
+	if len(r.byMonth) > 0 && !containsMonth(r.byMonth, d.Month()) {
+		return false
+	}
+	days := daysIn(d)
+	if len(r.byMonthDay) > 0 {
+		found := false
+		for _, day := range r.byMonthDay {
+			found = found || day == d.Day() || (day < 0 && days+day+1 == d.Day())
+		}
+		if !found {
+			return false
+		}
+	}
+	if len(r.byDay) > 0 {
+		found := false
+		for _, day := range r.byDay {
+			if day.day != d.Weekday() {
+				continue
+			}
+			switch {
+			case day.n > 0:
+				found = found || (d.Day()-1)/7+1 == day.n
+			case day.n < 0:
+				found = found || (days-d.Day())/7+1 == -day.n
+			default:
+				found = true
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// period returns the dates of the k-th period of the rule counted from date,
+// the date of DTSTART at midnight UTC.
+func (r rule) period(date time.Time, k int) (time.Time, time.Time) {
+	switch r.freq {
+	case "DAILY":
+		from := date.AddDate(0, 0, k*r.interval)
+		return from, from.AddDate(0, 0, 1)
+	case "WEEKLY":
+		week := date.AddDate(0, 0, -int((date.Weekday()-r.weekStart+7)%7))
+		from := week.AddDate(0, 0, 7*k*r.interval)
+		return from, from.AddDate(0, 0, 7)
+	case "MONTHLY":
+		from := time.Date(date.Year(), date.Month(), 1, 0, 0, 0, 0, time.UTC).AddDate(0, k*r.interval, 0)
+		return from, from.AddDate(0, 1, 0)
+	default:
+		from := time.Date(date.Year()+k*r.interval, time.January, 1, 0, 0, 0, 0, time.UTC)
+		return from, from.AddDate(1, 0, 0)
+	}
+}
+
+// expandRecurring returns the occurrences of a recurring event overlapping
+// [from, to), without the dates in EXDATE and the instances in overridden,
+// which the calendar lists as events of their own.
+func expandRecurring(master recurring, overridden []time.Time, from, to time.Time) ([]Event, error) {
+	event := master.event
+	starts := []time.Time{event.Start}
+	if master.rule != "" {
+		r, err := parseRule(master.rule, event.Start)
+		if err != nil {
+			return nil, err
+		}
+		starts = r.starts(event.Start, to)
+	}
+	starts = append(starts, master.rdates...)
+	sort.Slice(starts, func(i, j int) bool { return starts[i].Before(starts[j]) })
+
+	length := event.End.Sub(event.Start)
+	days := int(length.Round(24*time.Hour) / (24 * time.Hour))
+	var occurrences []Event
+	for i, start := range starts {
+		if (i > 0 && start.Equal(starts[i-1])) || containsTime(master.exdates, start) || containsTime(overridden, start) {
+			continue
+		}
+		occurrence := event
+		occurrence.Start = start
+		occurrence.End = start.Add(length)
+		if event.AllDay {
+			occurrence.End = start.AddDate(0, 0, days)
+		}
+		if occurrence.Overlaps(from, to) {
+			occurrences = append(occurrences, occurrence)
+		}
+	}
+	return occurrences, nil
+}
+
+// starts returns the occurrence starts of the rule from DTSTART, which always
+// counts as the first, until COUNT or UNTIL is reached or they pass to.
+func (r rule) starts(start, to time.Time) []time.Time {
+	date := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, time.UTC)
+	starts := []time.Time{start}
+	for k := 0; ; k++ {
+		first, next := r.period(date, k)
+		if !first.Before(to.AddDate(0, 0, 1)) {
+			return starts
+		}
+		for day := first; day.Before(next); day = day.AddDate(0, 0, 1) {
+			if !r.matches(day) {
+				continue
+			}
+			occurrence := time.Date(day.Year(), day.Month(), day.Day(), start.Hour(), start.Minute(), start.Second(), 0, start.Location())
+			if !occurrence.After(start) {
+				continue
+			}
+			if (!r.until.IsZero() && occurrence.After(r.until)) || (r.count > 0 && len(starts) >= r.count) || !occurrence.Before(to) {
+				return starts
+			}
+			starts = append(starts, occurrence)
+		}
+	}
+}
+
+// parseTimes reads a comma-separated RDATE or EXDATE list.
+func parseTimes(params map[string]string, value string, loc *time.Location) ([]time.Time, error) {
+	if strings.EqualFold(params["VALUE"], "PERIOD") {
+		return nil, fmt.Errorf("%w: unsupported period %q", ErrInvalidICS, value)
+	}
+	var list []time.Time
+	for _, item := range strings.Split(value, ",") {
+		parsed, _, err := parseTime(params, item, loc)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, parsed)
+	}
+	return list, nil
+}
+
+func daysIn(d time.Time) int {
+	return time.Date(d.Year(), d.Month()+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
+
+func containsMonth(months []time.Month, month time.Month) bool {
+	for _, candidate := range months {
+		if candidate == month {
+			return true
+		}
+	}
+	return false
+}
+
+func containsTime(list []time.Time, t time.Time) bool {
+	for _, candidate := range list {
+		if candidate.Equal(t) {
+			return true
+		}
+	}
+	return false
+}
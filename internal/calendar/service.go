@@ -0,0 +1,155 @@
+package calendar
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/example/iboz/internal/email"
+)
+
+// Settings are the working hours stored for one mailbox owner.
+type Settings struct {
+	User      string    `json:"user"`
+	Hours     Hours     `json:"hours"`
+	UpdatedAt time.Time `json:"updatedAt,omitempty"`
+}
+
+// Repository defines the persistence contract for per-user settings.
+type Repository interface {
+	Save(ctx context.Context, settings Settings) error
+	Get(ctx context.Context, user string) (*Settings, error)
+}
+
+// CalendarService exposes the working-hours calendar of the authenticated user.
+type CalendarService interface {
+	Provider
+	Settings(ctx context.Context) (Settings, error)
+	UpdateSettings(ctx context.Context, hours Hours) (Settings, error)
+	ImportHolidays(ctx context.Context, r io.Reader) ([]Holiday, error)
+}
+
+var _ CalendarService = (*Service)(nil)
+
+// Service resolves per-user calendars, falling back to the deployment defaults.
+type Service struct {
+	repo     Repository
+	auth     email.Repository
+	defaults Hours
+	clock    email.Clock
+}
+
+// NewService constructs a calendar Service. defaults apply to users without settings.
+func NewService(repo Repository, auth email.Repository, defaults Hours, clock email.Clock) *Service {
+	if repo == nil {
+		panic("calendar: repository dependency is required")
+	}
+	if auth == nil {
+		panic("calendar: email repository dependency is required")
+	}
+	if clock == nil {
+		panic("calendar: clock dependency is required")
+	}
+	if _, err := New(defaults, clock); err != nil {
+		panic(fmt.Sprintf("calendar: default hours: %v", err))
+	}
+	return &Service{repo: repo, auth: auth, defaults: defaults, clock: clock}
+}
+
+// Calendar returns the calendar of the authenticated user.
+func (s *Service) Calendar(ctx context.Context) (*Calendar, error) {
+	settings, err := s.Settings(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return New(settings.Hours, s.clock)
+}
+
+// Settings returns the stored settings of the authenticated user, or the defaults.
+func (s *Service) Settings(ctx context.Context) (Settings, error) {
+	if err := ctx.Err(); err != nil {
+		return Settings{}, err
+	}
+	user, err := s.user(ctx)
+	if err != nil {
+		return Settings{}, err
+	}
+	if user != "" {
+		stored, err := s.repo.Get(ctx, user)
+		if err != nil {
+			return Settings{}, err
+		}
+		if stored != nil {
+			return *stored, nil
+		}
+	}
+	hours := s.defaults
+	hours.Days = append([]time.Weekday(nil), s.defaults.Days...)
+	hours.Holidays = append([]string(nil), s.defaults.Holidays...)
+	return Settings{User: user, Hours: hours}, nil
+}
+
+// UpdateSettings validates and stores the working hours of the authenticated user.
+func (s *Service) UpdateSettings(ctx context.Context, hours Hours) (Settings, error) {
+	if err := ctx.Err(); err != nil {
+		return Settings{}, err
+	}
+	if _, err := New(hours, s.clock); err != nil {
+		return Settings{}, err
+	}
+	user, err := s.user(ctx)
+	if err != nil {
+		return Settings{}, err
+	}
+	if user == "" {
+		return Settings{}, email.ErrProviderNotAuthenticated
+	}
+
+	settings := Settings{User: user, Hours: hours, UpdatedAt: s.clock.Now().UTC()}
+	if err := s.repo.Save(ctx, settings); err != nil {
+		return Settings{}, err
+	}
+	return settings, nil
+}
+
+// ImportHolidays parses an ICS feed and merges its dates into the user's holidays.
+func (s *Service) ImportHolidays(ctx context.Context, r io.Reader) ([]Holiday, error) {
+	holidays, err := ParseICS(r)
+	if err != nil {
+		return nil, err
+	}
+	settings, err := s.Settings(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	dates := make(map[string]struct{}, len(settings.Hours.Holidays)+len(holidays))
+	for _, date := range settings.Hours.Holidays {
+		dates[date] = struct{}{}
+	}
+	for _, holiday := range holidays {
+		dates[holiday.Date] = struct{}{}
+	}
+	merged := make([]string, 0, len(dates))
+	for date := range dates {
+		merged = append(merged, date)
+	}
+	sort.Strings(merged)
+
+	hours := settings.Hours
+	hours.Holidays = merged
+	if _, err := s.UpdateSettings(ctx, hours); err != nil {
+		return nil, err
+	}
+	return holidays, nil
+}
+
+func (s *Service) user(ctx context.Context) (string, error) {
+	auth, err := s.auth.GetAuth(ctx)
+	if err != nil || auth == nil {
+		return "", err
+	}
+	return auth.State.Username, nil
+}
@@ -0,0 +1,227 @@
+// Package hubspot creates HubSpot contacts, deals and logged emails through
+// the CRM v3 API.
+package hubspot
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/example/iboz/internal/crm"
+	"github.com/example/iboz/internal/email"
+	"github.com/example/iboz/internal/notify"
+)
+
+const (
+	// DefaultBaseURL is the HubSpot API root.
+	DefaultBaseURL = "https://api.hubapi.com"
+	// DefaultAppURL is the root of record links.
+	DefaultAppURL = "https://app.hubspot.com"
+	// DefaultPipeline is the deal pipeline used when none is configured.
+	DefaultPipeline = "default"
+	// DefaultStage is the stage of deals created without one.
+	DefaultStage   = "appointmentscheduled"
+	defaultTimeout = 10 * time.Second
+)
+
+// HubSpot-defined association types.
+const (
+	dealToContact  = 3
+	emailToContact = 198
+	emailToDeal    = 210
+)
+
+var _ crm.Client = (*Client)(nil)
+
+// Config configures a Client. Token is a private app access token; record
+// links are only built when PortalID is set.
+type Config struct {
+	BaseURL  string
+	AppURL   string
+	Token    string
+	PortalID string
+	Pipeline string
+	Client   *http.Client
+}
+
+// Client talks to one HubSpot account. Leads are contacts in the "lead"
+// lifecycle stage; a sender who already is a contact is used as is.
+type Client struct {
+	cfg Config
+}
+
+// NewClient constructs a HubSpot Client. It panics without a token.
+func NewClient(cfg Config) *Client {
+	if cfg.Token == "" {
+		panic("hubspot: token is required")
+	}
+	if cfg.BaseURL == "" {
+		cfg.BaseURL = DefaultBaseURL
+	}
+	cfg.BaseURL = strings.TrimRight(cfg.BaseURL, "/")
+	if cfg.AppURL == "" {
+		cfg.AppURL = DefaultAppURL
+	}
+	cfg.AppURL = strings.TrimRight(cfg.AppURL, "/")
+	if cfg.Pipeline == "" {
+		cfg.Pipeline = DefaultPipeline
+	}
+	if cfg.Client == nil {
+		cfg.Client = &http.Client{Timeout: defaultTimeout}
+	}
+	return &Client{cfg: cfg}
+}
+
+type object struct {
+	ID         string            `json:"id"`
+	Properties map[string]string `json:"properties"`
+}
+
+type association struct {
+	To    map[string]string `json:"to"`
+	Types []associationType `json:"types"`
+}
+
+type associationType struct {
+	Category string `json:"associationCategory"`
+	TypeID   int    `json:"associationTypeId"`
+}
+
+func associate(id string, typeID int) association {
+	return association{To: map[string]string{"id": id}, Types: []associationType{{Category: "HUBSPOT_DEFINED", TypeID: typeID}}}
+}
+
+// FindContact implements the crm.Client interface.
+func (c *Client) FindContact(ctx context.Context, address string) (*crm.Contact, error) {
+	search := map[string]interface{}{
+		"filterGroups": []map[string]interface{}{{
+			"filters": []map[string]string{{"propertyName": "email", "operator": "EQ", "value": address}},
+		}},
+		"properties": []string{"email", "firstname", "lastname", "company"},
+		"limit":      1,
+	}
+	var result struct {
+		Results []object `json:"results"`
+	}
+	if err := notify.DoJSON(ctx, c.cfg.Client, http.MethodPost, c.cfg.BaseURL+"/crm/v3/objects/contacts/search", c.header(), search, &result); err != nil {
+		return nil, fmt.Errorf("hubspot: search contacts: %w", err)
+	}
+	if len(result.Results) == 0 {
+		return nil, nil
+	}
+	found := result.Results[0]
+	return &crm.Contact{
+		ID:      found.ID,
+		Name:    strings.TrimSpace(found.Properties["firstname"] + " " + found.Properties["lastname"]),
+		Email:   found.Properties["email"],
+		Company: found.Properties["company"],
+		URL:     c.recordURL("0-1", found.ID),
+	}, nil
+}
+
+// CreateLead implements the crm.Client interface.
+func (c *Client) CreateLead(ctx context.Context, record crm.Record, message email.EmailMessage) (crm.External, error) {
+	if record.ContactID != "" {
+		return crm.External{ID: record.ContactID, URL: c.recordURL("0-1", record.ContactID)}, nil
+	}
+	address, _ := crm.Sender(message)
+	first, last := splitName(record.Name)
+	properties := map[string]string{
+		"email":          address,
+		"firstname":      first,
+		"lastname":       last,
+		"company":        record.Company,
+		"lifecyclestage": "lead",
+	}
+	var contact object
+	if err := notify.DoJSON(ctx, c.cfg.Client, http.MethodPost, c.cfg.BaseURL+"/crm/v3/objects/contacts", c.header(), map[string]interface{}{"properties": properties}, &contact); err != nil {
+		return crm.External{}, fmt.Errorf("hubspot: create contact: %w", err)
+	}
+	if contact.ID == "" {
+		return crm.External{}, fmt.Errorf("hubspot: create contact: response has no id")
+	}
+	return crm.External{ID: contact.ID, URL: c.recordURL("0-1", contact.ID)}, nil
+}
+
+// CreateOpportunity implements the crm.Client interface with a deal associated
+// with the sender's contact.
+func (c *Client) CreateOpportunity(ctx context.Context, record crm.Record, contact *crm.Contact) (crm.External, error) {
+	stage := record.Stage
+	if stage == "" {
+		stage = DefaultStage
+	}
+	properties := map[string]string{
+		"dealname":  record.Name,
+		"pipeline":  c.cfg.Pipeline,
+		"dealstage": stage,
+	}
+	if record.Amount > 0 {
+		properties["amount"] = fmt.Sprintf("%.2f", record.Amount)
+	}
+	if closeDate, err := time.Parse(crm.DateLayout, record.CloseDate); err == nil {
+		properties["closedate"] = closeDate.UTC().Format(time.RFC3339)
+	}
+	body := map[string]interface{}{"properties": properties}
+	if contact != nil {
+		body["associations"] = []association{associate(contact.ID, dealToContact)}
+	}
+	var deal object
+	if err := notify.DoJSON(ctx, c.cfg.Client, http.MethodPost, c.cfg.BaseURL+"/crm/v3/objects/deals", c.header(), body, &deal); err != nil {
+		return crm.External{}, fmt.Errorf("hubspot: create deal: %w", err)
+	}
+	if deal.ID == "" {
+		return crm.External{}, fmt.Errorf("hubspot: create deal: response has no id")
+	}
+	return crm.External{ID: deal.ID, URL: c.recordURL("0-3", deal.ID)}, nil
+}
+
+// LogActivity implements the crm.Client interface with an incoming email
+// engagement on the contact and, for deals, the deal.
+func (c *Client) LogActivity(ctx context.Context, record crm.Record, message email.EmailMessage) (string, error) {
+	properties := map[string]string{
+		"hs_timestamp":       message.ReceivedAt.UTC().Format(time.RFC3339),
+		"hs_email_direction": "INCOMING_EMAIL",
+		"hs_email_subject":   message.Subject,
+		"hs_email_text":      message.Summary(),
+	}
+	var associations []association
+	if record.Kind == crm.KindOpportunity {
+		associations = append(associations, associate(record.ExternalID, emailToDeal))
+		if record.ContactID != "" {
+			associations = append(associations, associate(record.ContactID, emailToContact))
+		}
+	} else {
+		associations = append(associations, associate(record.ExternalID, emailToContact))
+	}
+	var logged object
+	if err := notify.DoJSON(ctx, c.cfg.Client, http.MethodPost, c.cfg.BaseURL+"/crm/v3/objects/emails", c.header(), map[string]interface{}{
+		"properties":   properties,
+		"associations": associations,
+	}, &logged); err != nil {
+		return "", fmt.Errorf("hubspot: log email: %w", err)
+	}
+	return logged.ID, nil
+}
+
+// recordURL links to a record of objectType ("0-1" contacts, "0-3" deals).
+func (c *Client) recordURL(objectType, id string) string {
+	if c.cfg.PortalID == "" {
+		return ""
+	}
+	return c.cfg.AppURL + "/contacts/" + url.PathEscape(c.cfg.PortalID) + "/record/" + objectType + "/" + url.PathEscape(id)
+}
+
+func (c *Client) header() http.Header {
+	return http.Header{"Authorization": {"Bearer " + c.cfg.Token}}
+}
+
+func splitName(name string) (string, string) {
+	name = strings.TrimSpace(name)
+	if i := strings.LastIndex(name, " "); i > 0 {
+		return strings.TrimSpace(name[:i]), name[i+1:]
+	}
+	return "", name
+}
@@ -0,0 +1,26 @@
+package memory
+
+import (
+	"github.com/example/iboz/internal/crm"
+	outboxmemory "github.com/example/iboz/internal/outbox/adapter/memory"
+)
+
+var _ crm.Repository = (*Repository)(nil)
+
+// Repository provides an in-memory implementation of the crm.Repository port.
+type Repository struct {
+	*outboxmemory.Records[crm.Record]
+}
+
+// NewRepository builds a new in-memory CRM record repository.
+func NewRepository() *Repository {
+	return &Repository{Records: outboxmemory.NewRecords(func(record crm.Record) string { return record.ID }, cloneRecord)}
+}
+
+func cloneRecord(record crm.Record) crm.Record {
+	if record.SyncedAt != nil {
+		synced := *record.SyncedAt
+		record.SyncedAt = &synced
+	}
+	return record
+}
@@ -0,0 +1,213 @@
+// Package salesforce creates Salesforce leads, opportunities and email
+// activities through the REST API.
+package salesforce
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/example/iboz/internal/crm"
+	"github.com/example/iboz/internal/email"
+	"github.com/example/iboz/internal/notify"
+)
+
+const (
+	// DefaultAPIVersion is the REST API version used when none is configured.
+	DefaultAPIVersion = "v60.0"
+	// DefaultStage is the stage of opportunities created without one.
+	DefaultStage = "Prospecting"
+	// DefaultLeadSource is recorded on created leads.
+	DefaultLeadSource = "Email"
+	defaultTimeout    = 10 * time.Second
+)
+
+var _ crm.Client = (*Client)(nil)
+
+// Config configures a Client. BaseURL is the org's instance URL, e.g.
+// https://acme.my.salesforce.com, and AccessToken an OAuth access token.
+type Config struct {
+	BaseURL     string
+	AccessToken string
+	APIVersion  string
+	LeadSource  string
+	Client      *http.Client
+}
+
+// Client talks to one Salesforce org.
+type Client struct {
+	cfg Config
+}
+
+// NewClient constructs a Salesforce Client. It panics without an instance URL or token.
+func NewClient(cfg Config) *Client {
+	if cfg.BaseURL == "" {
+		panic("salesforce: instance url is required")
+	}
+	if cfg.AccessToken == "" {
+		panic("salesforce: access token is required")
+	}
+	cfg.BaseURL = strings.TrimRight(cfg.BaseURL, "/")
+	if cfg.APIVersion == "" {
+		cfg.APIVersion = DefaultAPIVersion
+	}
+	if cfg.LeadSource == "" {
+		cfg.LeadSource = DefaultLeadSource
+	}
+	if cfg.Client == nil {
+		cfg.Client = &http.Client{Timeout: defaultTimeout}
+	}
+	return &Client{cfg: cfg}
+}
+
+type created struct {
+	ID string `json:"id"`
+}
+
+// FindContact implements the crm.Client interface.
+func (c *Client) FindContact(ctx context.Context, address string) (*crm.Contact, error) {
+	query := "SELECT Id, Name, Email, AccountId, Account.Name FROM Contact WHERE Email = '" + escape(address) + "' LIMIT 1"
+	var result struct {
+		Records []struct {
+			ID        string `json:"Id"`
+			Name      string `json:"Name"`
+			Email     string `json:"Email"`
+			AccountID string `json:"AccountId"`
+			Account   *struct {
+				Name string `json:"Name"`
+			} `json:"Account"`
+		} `json:"records"`
+	}
+	target := c.dataURL("/query") + "?q=" + url.QueryEscape(query)
+	if err := notify.DoJSON(ctx, c.cfg.Client, http.MethodGet, target, c.header(), nil, &result); err != nil {
+		return nil, fmt.Errorf("salesforce: query contact: %w", err)
+	}
+	if len(result.Records) == 0 {
+		return nil, nil
+	}
+	record := result.Records[0]
+	contact := &crm.Contact{
+		ID:        record.ID,
+		Name:      record.Name,
+		Email:     record.Email,
+		AccountID: record.AccountID,
+		URL:       c.recordURL("Contact", record.ID),
+	}
+	if record.Account != nil {
+		contact.Company = record.Account.Name
+	}
+	return contact, nil
+}
+
+// CreateLead implements the crm.Client interface.
+func (c *Client) CreateLead(ctx context.Context, record crm.Record, message email.EmailMessage) (crm.External, error) {
+	first, last := splitName(record.Name)
+	address, _ := crm.Sender(message)
+	fields := map[string]interface{}{
+		"LastName":    last,
+		"Company":     record.Company,
+		"Email":       address,
+		"LeadSource":  c.cfg.LeadSource,
+		"Description": message.Subject,
+	}
+	if first != "" {
+		fields["FirstName"] = first
+	}
+	return c.create(ctx, "Lead", fields)
+}
+
+// CreateOpportunity implements the crm.Client interface. The opportunity is
+// attached to the contact's account when the sender is a known contact.
+func (c *Client) CreateOpportunity(ctx context.Context, record crm.Record, contact *crm.Contact) (crm.External, error) {
+	stage := record.Stage
+	if stage == "" {
+		stage = DefaultStage
+	}
+	fields := map[string]interface{}{
+		"Name":      record.Name,
+		"StageName": stage,
+		"CloseDate": record.CloseDate,
+	}
+	if record.Amount > 0 {
+		fields["Amount"] = record.Amount
+	}
+	if contact != nil && contact.AccountID != "" {
+		fields["AccountId"] = contact.AccountID
+	}
+	return c.create(ctx, "Opportunity", fields)
+}
+
+// LogActivity implements the crm.Client interface with a completed email Task
+// on the lead, or on the opportunity and the sender's contact.
+func (c *Client) LogActivity(ctx context.Context, record crm.Record, message email.EmailMessage) (string, error) {
+	fields := map[string]interface{}{
+		"Subject":      truncate("Email: "+message.Subject, 255),
+		"Description":  message.Summary(),
+		"Status":       "Completed",
+		"TaskSubtype":  "Email",
+		"ActivityDate": message.ReceivedAt.UTC().Format(crm.DateLayout),
+	}
+	switch {
+	case record.Kind == crm.KindOpportunity:
+		fields["WhatId"] = record.ExternalID
+		if record.ContactID != "" {
+			fields["WhoId"] = record.ContactID
+		}
+	default:
+		fields["WhoId"] = record.ExternalID
+	}
+	external, err := c.create(ctx, "Task", fields)
+	if err != nil {
+		return "", err
+	}
+	return external.ID, nil
+}
+
+func (c *Client) create(ctx context.Context, object string, fields map[string]interface{}) (crm.External, error) {
+	var result created
+	if err := notify.DoJSON(ctx, c.cfg.Client, http.MethodPost, c.dataURL("/sobjects/"+object), c.header(), fields, &result); err != nil {
+		return crm.External{}, fmt.Errorf("salesforce: create %s: %w", object, err)
+	}
+	if result.ID == "" {
+		return crm.External{}, fmt.Errorf("salesforce: create %s: response has no id", object)
+	}
+	return crm.External{ID: result.ID, URL: c.recordURL(object, result.ID)}, nil
+}
+
+func (c *Client) dataURL(path string) string {
+	return c.cfg.BaseURL + "/services/data/" + c.cfg.APIVersion + path
+}
+
+func (c *Client) recordURL(object, id string) string {
+	return c.cfg.BaseURL + "/lightning/r/" + object + "/" + url.PathEscape(id) + "/view"
+}
+
+func (c *Client) header() http.Header {
+	return http.Header{"Authorization": {"Bearer " + c.cfg.AccessToken}}
+}
+
+// escape quotes a value for a SOQL string literal.
+func escape(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(value)
+}
+
+// splitName splits a display name into first and last name; Salesforce
+// requires the last name.
+func splitName(name string) (string, string) {
+	name = strings.TrimSpace(name)
+	if i := strings.LastIndex(name, " "); i > 0 {
+		return strings.TrimSpace(name[:i]), name[i+1:]
+	}
+	return "", name
+}
+
+func truncate(value string, limit int) string {
+	runes := []rune(value)
+	if len(runes) <= limit {
+		return value
+	}
+	return string(runes[:limit-1]) + "…"
+}
@@ -0,0 +1,131 @@
+// Package crm turns messages into CRM leads and opportunities, logs them as
+// email activities and links the records back to the message.
+package crm
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/example/iboz/internal/email"
+	"github.com/example/iboz/internal/outbox"
+)
+
+// Status enumerates the lifecycle of a CRM record created from a message.
+type Status string
+
+const (
+	// StatusPending records wait for the outbox relay to create them in the CRM.
+	StatusPending Status = "pending"
+	StatusSynced  Status = "synced"
+)
+
+// Kind enumerates the CRM objects a message can become.
+type Kind string
+
+const (
+	KindLead        Kind = "lead"
+	KindOpportunity Kind = "opportunity"
+)
+
+// Provider names of the bundled clients.
+const (
+	ProviderSalesforce = "salesforce"
+	ProviderHubSpot    = "hubspot"
+)
+
+// DateLayout is the format of opportunity close dates.
+const DateLayout = "2006-01-02"
+
+// OutboxDestination is the outbox destination of CRM syncs, delivered by the
+// Service's Deliverer.
+const OutboxDestination = "crm.sync"
+
+var (
+	// ErrRecordNotFound is returned when a CRM record does not exist.
+	ErrRecordNotFound = errors.New("crm record not found")
+	// ErrInvalidRecord is returned when a sync request fails validation.
+	ErrInvalidRecord = errors.New("invalid crm record")
+	// ErrUnknownProvider is returned when no client is configured for a provider.
+	ErrUnknownProvider = errors.New("unknown crm provider")
+)
+
+// Contact is a person known to a CRM.
+type Contact struct {
+	Provider  string `json:"provider"`
+	ID        string `json:"id"`
+	Name      string `json:"name,omitempty"`
+	Email     string `json:"email"`
+	Company   string `json:"company,omitempty"`
+	AccountID string `json:"accountId,omitempty"`
+	URL       string `json:"url,omitempty"`
+}
+
+// Record is a lead or opportunity created in a CRM from a message. ContactID
+// is the existing contact of the sender, if any; ActivityID is the email
+// activity logged against the record.
+type Record struct {
+	ID         string     `json:"id"`
+	MessageID  string     `json:"messageId"`
+	Provider   string     `json:"provider"`
+	Kind       Kind       `json:"kind"`
+	Name       string     `json:"name"`
+	Company    string     `json:"company,omitempty"`
+	Amount     float64    `json:"amount,omitempty"`
+	Stage      string     `json:"stage,omitempty"`
+	CloseDate  string     `json:"closeDate,omitempty"`
+	Status     Status     `json:"status"`
+	ContactID  string     `json:"contactId,omitempty"`
+	ExternalID string     `json:"externalId,omitempty"`
+	URL        string     `json:"url,omitempty"`
+	ActivityID string     `json:"activityId,omitempty"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	UpdatedAt  time.Time  `json:"updatedAt"`
+	SyncedAt   *time.Time `json:"syncedAt,omitempty"`
+}
+
+// Request describes a record to create from a message. Name defaults to the
+// sender for leads and to the subject for opportunities; CloseDate defaults to
+// thirty days after the message was received.
+type Request struct {
+	MessageID string  `json:"messageId"`
+	Provider  string  `json:"provider"`
+	Kind      Kind    `json:"kind"`
+	Name      string  `json:"name"`
+	Company   string  `json:"company"`
+	Amount    float64 `json:"amount"`
+	Stage     string  `json:"stage"`
+	CloseDate string  `json:"closeDate"`
+}
+
+// External is a record as created in the CRM.
+type External struct {
+	ID  string
+	URL string
+}
+
+// Repository defines the persistence contract for CRM records. It owns the
+// outbox table holding pending syncs.
+type Repository interface {
+	outbox.Store
+	// Save stores record and appends effects to the outbox atomically.
+	Save(ctx context.Context, record Record, effects ...outbox.Entry) error
+	Get(ctx context.Context, id string) (*Record, error)
+	List(ctx context.Context) ([]Record, error)
+}
+
+// Client is the port to one CRM.
+type Client interface {
+	// FindContact returns the contact with the given address, or nil.
+	FindContact(ctx context.Context, address string) (*Contact, error)
+	CreateLead(ctx context.Context, record Record, message email.EmailMessage) (External, error)
+	CreateOpportunity(ctx context.Context, record Record, contact *Contact) (External, error)
+	// LogActivity records message as an email activity on the record and its
+	// contact and returns the activity ID.
+	LogActivity(ctx context.Context, record Record, message email.EmailMessage) (string, error)
+}
+
+// MessageLinker records CRM links on messages.
+type MessageLinker interface {
+	LinkCRM(ctx context.Context, messageID string, link email.CRMLink) error
+}
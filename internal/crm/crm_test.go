@@ -0,0 +1,276 @@
+package crm_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/example/iboz/internal/crm"
+	"github.com/example/iboz/internal/crm/adapter/hubspot"
+	"github.com/example/iboz/internal/crm/adapter/memory"
+	"github.com/example/iboz/internal/crm/adapter/salesforce"
+	"github.com/example/iboz/internal/email"
+	emailmemory "github.com/example/iboz/internal/email/adapter/memory"
+	"github.com/example/iboz/internal/outbox"
+	"github.com/example/iboz/internal/testutil"
+)
+
+type fixture struct {
+	service  *crm.Service
+	relay    *outbox.Relay
+	messages *emailmemory.Repository
+	clock    *testutil.Clock
+}
+
+func newFixture(t *testing.T, clients map[string]crm.Client) fixture {
+	t.Helper()
+	clock := testutil.NewClock(testutil.Start)
+	messages := testutil.Mailbox(t, clock, email.EmailMessage{
+		ID:         "msg-1",
+		Subject:    "Pricing for 200 seats",
+		Sender:     "Grace O'Hopper <Grace@Navy.example>",
+		ReceivedAt: clock.Now().Add(-time.Hour),
+		Snippet:    "Could you send a quote for 200 seats?",
+	})
+	repo := memory.NewRepository()
+	service := crm.NewService(repo, messages, messages, clients, clock)
+	relay := testutil.NewRelay(repo, map[string]outbox.Deliverer{
+		crm.OutboxDestination: service.Deliverer(),
+	}, clock)
+	return fixture{service: service, relay: relay, messages: messages, clock: clock}
+}
+
+// recorder is a CRM API stub answering by method and path.
+type recorder struct {
+	mu     sync.Mutex
+	token  string
+	bodies map[string]map[string]any
+	routes map[string]string
+	fail   map[string]int
+}
+
+func newRecorder(token string, routes map[string]string) *recorder {
+	return &recorder{token: token, bodies: make(map[string]map[string]any), routes: routes, fail: make(map[string]int)}
+}
+
+func (r *recorder) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Header.Get("Authorization") != "Bearer "+r.token {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	route := req.Method + " " + req.URL.Path
+	if req.URL.RawQuery != "" {
+		r.bodies[route] = map[string]any{"q": req.URL.Query().Get("q")}
+	} else {
+		var body map[string]any
+		json.NewDecoder(req.Body).Decode(&body)
+		r.bodies[route] = body
+	}
+	if r.fail[route] > 0 {
+		r.fail[route]--
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	reply, ok := r.routes[route]
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	w.Write([]byte(reply))
+}
+
+func (r *recorder) body(route string) map[string]any {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.bodies[route]
+}
+
+func TestSalesforceLeadCreatedWithActivity(t *testing.T) {
+	const base = "/services/data/v60.0"
+	api := newRecorder("sf-token", map[string]string{
+		"GET " + base + "/query":          `{"totalSize":0,"records":[]}`,
+		"POST " + base + "/sobjects/Lead": `{"id":"00Q1","success":true,"errors":[]}`,
+		"POST " + base + "/sobjects/Task": `{"id":"00T1","success":true,"errors":[]}`,
+	})
+	api.fail["POST "+base+"/sobjects/Task"] = 1
+	srv := httptest.NewServer(api)
+	defer srv.Close()
+
+	f := newFixture(t, map[string]crm.Client{
+		crm.ProviderSalesforce: salesforce.NewClient(salesforce.Config{BaseURL: srv.URL, AccessToken: "sf-token"}),
+	})
+	ctx := context.Background()
+	record, err := f.service.Sync(ctx, crm.Request{MessageID: "msg-1", Provider: "Salesforce", Kind: crm.KindLead})
+	if err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+	if record.Status != crm.StatusPending || record.Name != "Grace O'Hopper" || record.Company != "navy.example" {
+		t.Fatalf("unexpected record: %+v", record)
+	}
+
+	if _, err := f.relay.Deliver(ctx); err == nil {
+		t.Fatal("expected the failed activity to fail the delivery")
+	}
+	if query, _ := api.body("GET " + base + "/query")["q"].(string); !strings.Contains(query, `Email = 'grace@navy.example'`) {
+		t.Fatalf("unexpected contact query %q", query)
+	}
+	lead := api.body("POST " + base + "/sobjects/Lead")
+	if lead["LastName"] != "O'Hopper" || lead["FirstName"] != "Grace" || lead["Email"] != "grace@navy.example" {
+		t.Fatalf("unexpected lead: %v", lead)
+	}
+
+	f.clock.Advance(time.Minute)
+	testutil.Deliver(t, f.relay)
+	synced, err := f.service.Get(ctx, record.ID)
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if synced.Status != crm.StatusSynced || synced.ExternalID != "00Q1" || synced.ActivityID != "00T1" {
+		t.Fatalf("unexpected synced record: %+v", synced)
+	}
+	if synced.URL != srv.URL+"/lightning/r/Lead/00Q1/view" {
+		t.Fatalf("unexpected url %q", synced.URL)
+	}
+	if activity := api.body("POST " + base + "/sobjects/Task"); activity["WhoId"] != "00Q1" || activity["TaskSubtype"] != "Email" {
+		t.Fatalf("unexpected activity: %v", activity)
+	}
+	if links := testutil.Message(t, f.messages, "msg-1").CRM; len(links) != 1 || links[0].ExternalID != "00Q1" || links[0].Kind != "lead" {
+		t.Fatalf("unexpected message links: %+v", links)
+	}
+}
+
+func TestSalesforceOpportunityAttachedToKnownContact(t *testing.T) {
+	const base = "/services/data/v60.0"
+	api := newRecorder("sf-token", map[string]string{
+		"GET " + base + "/query":                 `{"records":[{"Id":"003C","Name":"Grace Hopper","Email":"grace@navy.example","AccountId":"001A","Account":{"Name":"Navy"}}]}`,
+		"POST " + base + "/sobjects/Opportunity": `{"id":"006O","success":true}`,
+		"POST " + base + "/sobjects/Task":        `{"id":"00T2","success":true}`,
+	})
+	srv := httptest.NewServer(api)
+	defer srv.Close()
+
+	f := newFixture(t, map[string]crm.Client{
+		crm.ProviderSalesforce: salesforce.NewClient(salesforce.Config{BaseURL: srv.URL, AccessToken: "sf-token"}),
+	})
+	ctx := context.Background()
+	contacts, err := f.service.Contacts(ctx, "msg-1")
+	if err != nil || len(contacts) != 1 || contacts[0].Provider != crm.ProviderSalesforce || contacts[0].Company != "Navy" {
+		t.Fatalf("unexpected contacts %+v, %v", contacts, err)
+	}
+
+	record, err := f.service.Sync(ctx, crm.Request{MessageID: "msg-1", Provider: crm.ProviderSalesforce, Kind: crm.KindOpportunity, Amount: 24000})
+	if err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+	if record.Name != "Pricing for 200 seats" || record.CloseDate != "2025-04-17" {
+		t.Fatalf("unexpected record: %+v", record)
+	}
+	testutil.Deliver(t, f.relay)
+
+	opportunity := api.body("POST " + base + "/sobjects/Opportunity")
+	if opportunity["AccountId"] != "001A" || opportunity["StageName"] != salesforce.DefaultStage || opportunity["Amount"] != 24000.0 {
+		t.Fatalf("unexpected opportunity: %v", opportunity)
+	}
+	if activity := api.body("POST " + base + "/sobjects/Task"); activity["WhatId"] != "006O" || activity["WhoId"] != "003C" {
+		t.Fatalf("unexpected activity: %v", activity)
+	}
+	if links := testutil.Message(t, f.messages, "msg-1").CRM; len(links) != 1 || links[0].ContactID != "003C" {
+		t.Fatalf("unexpected message links: %+v", links)
+	}
+}
+
+func TestHubSpotDealLoggedAgainstContact(t *testing.T) {
+	api := newRecorder("hs-token", map[string]string{
+		"POST /crm/v3/objects/contacts/search": `{"total":1,"results":[{"id":"501","properties":{"email":"grace@navy.example","firstname":"Grace","lastname":"Hopper"}}]}`,
+		"POST /crm/v3/objects/deals":           `{"id":"901","properties":{}}`,
+		"POST /crm/v3/objects/emails":          `{"id":"701","properties":{}}`,
+	})
+	srv := httptest.NewServer(api)
+	defer srv.Close()
+
+	f := newFixture(t, map[string]crm.Client{
+		crm.ProviderHubSpot: hubspot.NewClient(hubspot.Config{BaseURL: srv.URL, Token: "hs-token", PortalID: "42"}),
+	})
+	ctx := context.Background()
+	record, err := f.service.Sync(ctx, crm.Request{MessageID: "msg-1", Provider: crm.ProviderHubSpot, Kind: crm.KindOpportunity, CloseDate: "2025-05-01"})
+	if err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+	testutil.Deliver(t, f.relay)
+
+	deal := api.body("POST /crm/v3/objects/deals")
+	properties, _ := deal["properties"].(map[string]any)
+	if properties["dealstage"] != hubspot.DefaultStage || properties["closedate"] != "2025-05-01T00:00:00Z" {
+		t.Fatalf("unexpected deal: %v", deal)
+	}
+	if associations, _ := deal["associations"].([]any); len(associations) != 1 {
+		t.Fatalf("expected the deal to be associated with the contact: %v", deal)
+	}
+	logged := api.body("POST /crm/v3/objects/emails")
+	if associations, _ := logged["associations"].([]any); len(associations) != 2 {
+		t.Fatalf("expected the email on the deal and contact: %v", logged)
+	}
+
+	synced, err := f.service.Get(ctx, record.ID)
+	if err != nil || synced.Status != crm.StatusSynced || synced.URL != "https://app.hubspot.com/contacts/42/record/0-3/901" {
+		t.Fatalf("unexpected synced record: %+v, %v", synced, err)
+	}
+}
+
+func TestHubSpotLeadReusesExistingContact(t *testing.T) {
+	api := newRecorder("hs-token", map[string]string{
+		"POST /crm/v3/objects/contacts/search": `{"total":1,"results":[{"id":"501","properties":{"email":"grace@navy.example"}}]}`,
+		"POST /crm/v3/objects/emails":          `{"id":"702"}`,
+	})
+	srv := httptest.NewServer(api)
+	defer srv.Close()
+
+	f := newFixture(t, map[string]crm.Client{
+		crm.ProviderHubSpot: hubspot.NewClient(hubspot.Config{BaseURL: srv.URL, Token: "hs-token"}),
+	})
+	ctx := context.Background()
+	record, err := f.service.Sync(ctx, crm.Request{MessageID: "msg-1", Provider: crm.ProviderHubSpot, Kind: crm.KindLead})
+	if err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+	testutil.Deliver(t, f.relay)
+	if created := api.body("POST /crm/v3/objects/contacts"); created != nil {
+		t.Fatalf("existing contact should not be recreated: %v", created)
+	}
+	synced, err := f.service.Get(ctx, record.ID)
+	if err != nil || synced.ExternalID != "501" || synced.ActivityID != "702" {
+		t.Fatalf("unexpected synced record: %+v, %v", synced, err)
+	}
+}
+
+func TestSyncValidation(t *testing.T) {
+	f := newFixture(t, map[string]crm.Client{
+		crm.ProviderHubSpot: hubspot.NewClient(hubspot.Config{BaseURL: "http://127.0.0.1:1", Token: "hs-token"}),
+	})
+	ctx := context.Background()
+	for _, tc := range []struct {
+		req  crm.Request
+		want error
+	}{
+		{crm.Request{MessageID: "msg-1", Provider: "pipedrive", Kind: crm.KindLead}, crm.ErrUnknownProvider},
+		{crm.Request{MessageID: "msg-1", Provider: crm.ProviderHubSpot, Kind: "account"}, crm.ErrInvalidRecord},
+		{crm.Request{MessageID: "msg-1", Provider: crm.ProviderHubSpot, Kind: crm.KindOpportunity, CloseDate: "May 1"}, crm.ErrInvalidRecord},
+		{crm.Request{MessageID: "msg-1", Provider: crm.ProviderHubSpot, Kind: crm.KindOpportunity, Amount: -1}, crm.ErrInvalidRecord},
+		{crm.Request{MessageID: "missing", Provider: crm.ProviderHubSpot, Kind: crm.KindLead}, email.ErrMessageNotFound},
+	} {
+		if _, err := f.service.Sync(ctx, tc.req); !errors.Is(err, tc.want) {
+			t.Fatalf("sync %+v: expected %v, got %v", tc.req, tc.want, err)
+		}
+	}
+	if _, err := f.service.Get(ctx, "crm-missing"); !errors.Is(err, crm.ErrRecordNotFound) {
+		t.Fatalf("expected not found, got %v", err)
+	}
+}
@@ -0,0 +1,308 @@
+package crm
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/mail"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/example/iboz/internal/email"
+	"github.com/example/iboz/internal/outbox"
+)
+
+// defaultCloseWindow is added to the received time for opportunities without a close date.
+const defaultCloseWindow = 30 * 24 * time.Hour
+
+// CRMService exposes contact lookup and record creation from messages.
+type CRMService interface {
+	Contacts(ctx context.Context, messageID string) ([]Contact, error)
+	Sync(ctx context.Context, req Request) (*Record, error)
+	Get(ctx context.Context, id string) (*Record, error)
+	List(ctx context.Context, messageID string) ([]Record, error)
+}
+
+var _ CRMService = (*Service)(nil)
+
+// Service creates CRM records through the outbox and links them to messages.
+type Service struct {
+	repo     Repository
+	messages email.Repository
+	linker   MessageLinker
+	clients  map[string]Client
+	clock    email.Clock
+}
+
+// NewService constructs a CRM Service. clients is keyed by provider.
+func NewService(repo Repository, messages email.Repository, linker MessageLinker, clients map[string]Client, clock email.Clock) *Service {
+	if repo == nil {
+		panic("crm: repository dependency is required")
+	}
+	if messages == nil {
+		panic("crm: email repository dependency is required")
+	}
+	if linker == nil {
+		panic("crm: message linker dependency is required")
+	}
+	if clock == nil {
+		panic("crm: clock dependency is required")
+	}
+	registered := make(map[string]Client, len(clients))
+	for provider, client := range clients {
+		if client == nil {
+			panic(fmt.Sprintf("crm: client for %q is nil", provider))
+		}
+		registered[provider] = client
+	}
+	return &Service{repo: repo, messages: messages, linker: linker, clients: registered, clock: clock}
+}
+
+// creation is the outbox payload of a record creation.
+type creation struct {
+	RecordID string `json:"recordId"`
+}
+
+// Contacts looks the sender of a message up in every configured CRM, in
+// provider order.
+func (s *Service) Contacts(ctx context.Context, messageID string) ([]Contact, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	message, err := email.FindMessage(ctx, s.messages, messageID)
+	if err != nil {
+		return nil, err
+	}
+	address, _ := Sender(message)
+	providers := make([]string, 0, len(s.clients))
+	for provider := range s.clients {
+		providers = append(providers, provider)
+	}
+	sort.Strings(providers)
+
+	contacts := []Contact{}
+	for _, provider := range providers {
+		contact, err := s.clients[provider].FindContact(ctx, address)
+		if err != nil {
+			return nil, fmt.Errorf("crm: %s contact lookup: %w", provider, err)
+		}
+		if contact != nil {
+			contact.Provider = provider
+			contacts = append(contacts, *contact)
+		}
+	}
+	return contacts, nil
+}
+
+// Sync validates the request and stores a pending record together with the
+// outbox entry that creates it in the CRM and logs the message against it.
+func (s *Service) Sync(ctx context.Context, req Request) (*Record, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	req.Provider = strings.ToLower(strings.TrimSpace(req.Provider))
+	if _, ok := s.clients[req.Provider]; !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownProvider, req.Provider)
+	}
+	if req.Kind != KindLead && req.Kind != KindOpportunity {
+		return nil, fmt.Errorf("%w: kind must be %q or %q", ErrInvalidRecord, KindLead, KindOpportunity)
+	}
+	if req.Amount < 0 {
+		return nil, fmt.Errorf("%w: amount cannot be negative", ErrInvalidRecord)
+	}
+	if req.CloseDate = strings.TrimSpace(req.CloseDate); req.CloseDate != "" {
+		if _, err := time.Parse(DateLayout, req.CloseDate); err != nil {
+			return nil, fmt.Errorf("%w: closeDate must use YYYY-MM-DD", ErrInvalidRecord)
+		}
+	}
+	message, err := email.FindMessage(ctx, s.messages, req.MessageID)
+	if err != nil {
+		return nil, err
+	}
+
+	address, displayName := Sender(message)
+	name := strings.TrimSpace(req.Name)
+	company := strings.TrimSpace(req.Company)
+	if company == "" {
+		company = message.SenderDomain()
+	}
+	if req.Kind == KindLead {
+		if name == "" {
+			name = displayName
+		}
+		if name == "" {
+			name = address
+		}
+	} else {
+		if name == "" {
+			name = message.Subject
+		}
+		if req.CloseDate == "" {
+			req.CloseDate = message.ReceivedAt.Add(defaultCloseWindow).UTC().Format(DateLayout)
+		}
+	}
+	if name == "" {
+		return nil, fmt.Errorf("%w: name is required", ErrInvalidRecord)
+	}
+
+	id, err := newID()
+	if err != nil {
+		return nil, err
+	}
+	now := s.clock.Now().UTC()
+	record := Record{
+		ID:        id,
+		MessageID: message.ID,
+		Provider:  req.Provider,
+		Kind:      req.Kind,
+		Name:      name,
+		Company:   company,
+		Amount:    req.Amount,
+		Stage:     strings.TrimSpace(req.Stage),
+		CloseDate: req.CloseDate,
+		Status:    StatusPending,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	entry, err := outbox.NewEntry(OutboxDestination, "crm:sync:"+record.ID, creation{RecordID: record.ID}, now)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.Save(ctx, record, entry); err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+// Get returns a record by ID.
+func (s *Service) Get(ctx context.Context, id string) (*Record, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	record, err := s.repo.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if record == nil {
+		return nil, ErrRecordNotFound
+	}
+	return record, nil
+}
+
+// List returns records, newest first, optionally only those of one message.
+func (s *Service) List(ctx context.Context, messageID string) ([]Record, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	all, err := s.repo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	list := make([]Record, 0, len(all))
+	for _, record := range all {
+		if messageID == "" || record.MessageID == messageID {
+			list = append(list, record)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].CreatedAt.After(list[j].CreatedAt) })
+	return list, nil
+}
+
+// Deliverer returns the outbox deliverer creating pending records in their CRM.
+// Each step is saved as it completes so a retried delivery resumes after it.
+func (s *Service) Deliverer() outbox.Deliverer {
+	return outbox.DelivererFunc(func(ctx context.Context, entry outbox.Entry) error {
+		var payload creation
+		if err := json.Unmarshal(entry.Payload, &payload); err != nil {
+			return fmt.Errorf("decode crm sync: %w", err)
+		}
+		record, err := s.Get(ctx, payload.RecordID)
+		if err != nil {
+			return err
+		}
+		if record.Status != StatusPending {
+			return nil
+		}
+		client, ok := s.clients[record.Provider]
+		if !ok {
+			return fmt.Errorf("%w: %q", ErrUnknownProvider, record.Provider)
+		}
+		message, err := email.FindMessage(ctx, s.messages, record.MessageID)
+		if err != nil {
+			return err
+		}
+
+		if record.ExternalID == "" {
+			address, _ := Sender(message)
+			contact, err := client.FindContact(ctx, address)
+			if err != nil {
+				return err
+			}
+			if contact != nil {
+				record.ContactID = contact.ID
+			}
+			var external External
+			if record.Kind == KindLead {
+				external, err = client.CreateLead(ctx, *record, message)
+			} else {
+				external, err = client.CreateOpportunity(ctx, *record, contact)
+			}
+			if err != nil {
+				return err
+			}
+			record.ExternalID = external.ID
+			record.URL = external.URL
+			if err := s.save(ctx, record); err != nil {
+				return err
+			}
+		}
+
+		activityID, err := client.LogActivity(ctx, *record, message)
+		if err != nil {
+			return err
+		}
+		now := s.clock.Now().UTC()
+		record.ActivityID = activityID
+		record.Status = StatusSynced
+		record.SyncedAt = &now
+		if err := s.save(ctx, record); err != nil {
+			return err
+		}
+		link := email.CRMLink{
+			Provider:   record.Provider,
+			Kind:       string(record.Kind),
+			ExternalID: record.ExternalID,
+			URL:        record.URL,
+			ContactID:  record.ContactID,
+		}
+		if err := s.linker.LinkCRM(ctx, record.MessageID, link); err != nil && !errors.Is(err, email.ErrMessageNotFound) {
+			return err
+		}
+		return nil
+	})
+}
+
+func (s *Service) save(ctx context.Context, record *Record) error {
+	record.UpdatedAt = s.clock.Now().UTC()
+	return s.repo.Save(ctx, *record)
+}
+
+// Sender returns the lower-cased address and the display name of the message sender.
+func Sender(message email.EmailMessage) (string, string) {
+	if parsed, err := mail.ParseAddress(message.Sender); err == nil {
+		return strings.ToLower(parsed.Address), parsed.Name
+	}
+	return strings.ToLower(strings.TrimSpace(message.Sender)), ""
+}
+
+func newID() (string, error) {
+	buf := make([]byte, 6)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("generate crm id: %w", err)
+	}
+	return "crm-" + hex.EncodeToString(buf), nil
+}
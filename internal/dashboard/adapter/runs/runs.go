@@ -0,0 +1,88 @@
+// Package runs reads dashboard run records from the automations that act on
+// messages: scheduled replies, tasks and CRM records.
+package runs
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/example/iboz/internal/crm"
+	"github.com/example/iboz/internal/dashboard"
+	"github.com/example/iboz/internal/schedule"
+	"github.com/example/iboz/internal/schedule/adapter/reply"
+	"github.com/example/iboz/internal/tasks"
+)
+
+// Replies returns the scheduled replies sent since a time.
+func Replies(repo schedule.Repository) dashboard.RunSource {
+	if repo == nil {
+		panic("runs: schedule repository dependency is required")
+	}
+	return sourceFunc(func(ctx context.Context, since time.Time) ([]dashboard.Run, error) {
+		actions, err := repo.List(ctx)
+		if err != nil {
+			return nil, err
+		}
+		var runs []dashboard.Run
+		for _, action := range actions {
+			if action.Type != reply.Type || action.Status != schedule.StatusDone || action.CompletedAt == nil || action.CompletedAt.Before(since) {
+				continue
+			}
+			var payload reply.Payload
+			if err := json.Unmarshal(action.Payload, &payload); err != nil {
+				continue
+			}
+			runs = append(runs, dashboard.Run{Kind: dashboard.KindReply, MessageID: payload.MessageID, CompletedAt: *action.CompletedAt})
+		}
+		return runs, nil
+	})
+}
+
+// Tasks returns the tasks created in an external tracker since a time.
+func Tasks(repo tasks.Repository) dashboard.RunSource {
+	if repo == nil {
+		panic("runs: task repository dependency is required")
+	}
+	return sourceFunc(func(ctx context.Context, since time.Time) ([]dashboard.Run, error) {
+		list, err := repo.List(ctx)
+		if err != nil {
+			return nil, err
+		}
+		var runs []dashboard.Run
+		for _, task := range list {
+			if task.ExternalID == "" || task.CreatedAt.Before(since) {
+				continue
+			}
+			runs = append(runs, dashboard.Run{Kind: dashboard.KindTask, MessageID: task.MessageID, CompletedAt: task.CreatedAt})
+		}
+		return runs, nil
+	})
+}
+
+// CRM returns the CRM records synced since a time.
+func CRM(repo crm.Repository) dashboard.RunSource {
+	if repo == nil {
+		panic("runs: crm repository dependency is required")
+	}
+	return sourceFunc(func(ctx context.Context, since time.Time) ([]dashboard.Run, error) {
+		records, err := repo.List(ctx)
+		if err != nil {
+			return nil, err
+		}
+		var runs []dashboard.Run
+		for _, record := range records {
+			if record.Status != crm.StatusSynced || record.SyncedAt == nil || record.SyncedAt.Before(since) {
+				continue
+			}
+			runs = append(runs, dashboard.Run{Kind: dashboard.KindCRM, MessageID: record.MessageID, CompletedAt: *record.SyncedAt})
+		}
+		return runs, nil
+	})
+}
+
+type sourceFunc func(ctx context.Context, since time.Time) ([]dashboard.Run, error)
+
+func (f sourceFunc) Runs(ctx context.Context, since time.Time) ([]dashboard.Run, error) {
+	return f(ctx, since)
+}
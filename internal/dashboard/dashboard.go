@@ -0,0 +1,78 @@
+// Package dashboard computes the inbox summary shown on the dashboard from
+// the synced messages and the runs of automations that handled them.
+package dashboard
+
+import (
+	"context"
+	"time"
+
+	"github.com/example/iboz/internal/email"
+)
+
+const (
+	// DefaultInboxZeroTarget is the inbox size counted as inbox zero.
+	DefaultInboxZeroTarget = 10
+	// DefaultWindow is the period the automation rate and time saved cover.
+	DefaultWindow = 7 * 24 * time.Hour
+	// DefaultTTL bounds how long a summary is cached between syncs, since
+	// automations also run between them.
+	DefaultTTL = 5 * time.Minute
+)
+
+// Run kinds recorded by the automations of the platform.
+const (
+	KindReply = "reply"
+	KindTask  = "task"
+	KindCRM   = "crm"
+)
+
+// DefaultSavedMinutes returns the manual handling time each run kind saves.
+func DefaultSavedMinutes() map[string]int {
+	return map[string]int{KindReply: 4, KindTask: 3, KindCRM: 5}
+}
+
+// Run is one automation completed on a message, such as a scheduled reply
+// sent or a task created from it.
+type Run struct {
+	Kind        string    `json:"kind"`
+	MessageID   string    `json:"messageId"`
+	CompletedAt time.Time `json:"completedAt"`
+}
+
+// Summary is the headline of the dashboard. AutomationRate is the share of the
+// messages received in the window that an automation handled.
+type Summary struct {
+	InboxZeroTarget   int       `json:"inboxZeroTarget"`
+	CurrentInbox      int       `json:"currentInbox"`
+	AutomationRate    float64   `json:"automationRate"`
+	TimeSavedMinutes  int       `json:"timeSavedMinutes"`
+	AutomatedMessages int       `json:"automatedMessages"`
+	ReceivedMessages  int       `json:"receivedMessages"`
+	WindowDays        int       `json:"windowDays"`
+	ComputedAt        time.Time `json:"computedAt"`
+}
+
+// Config tunes the summary. Zero values select the defaults.
+type Config struct {
+	InboxZeroTarget int
+	Window          time.Duration
+	TTL             time.Duration
+	// SavedMinutes overrides the handling time saved per run kind.
+	SavedMinutes map[string]int
+}
+
+// DashboardService exposes the computed summary.
+type DashboardService interface {
+	Summary(ctx context.Context) (Summary, error)
+}
+
+// RunSource lists the automation runs completed since a time.
+type RunSource interface {
+	Runs(ctx context.Context, since time.Time) ([]Run, error)
+}
+
+// Visibility filters out messages hidden from the inbox, such as snoozed
+// ones. It is optional.
+type Visibility interface {
+	Visible(ctx context.Context, messages []email.EmailMessage) ([]email.EmailMessage, error)
+}
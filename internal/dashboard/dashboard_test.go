@@ -0,0 +1,144 @@
+package dashboard_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/example/iboz/internal/crm"
+	crmmemory "github.com/example/iboz/internal/crm/adapter/memory"
+	"github.com/example/iboz/internal/dashboard"
+	"github.com/example/iboz/internal/dashboard/adapter/runs"
+	"github.com/example/iboz/internal/email"
+	emailmemory "github.com/example/iboz/internal/email/adapter/memory"
+	"github.com/example/iboz/internal/schedule"
+	schedulememory "github.com/example/iboz/internal/schedule/adapter/memory"
+	"github.com/example/iboz/internal/schedule/adapter/reply"
+	"github.com/example/iboz/internal/tasks"
+	tasksmemory "github.com/example/iboz/internal/tasks/adapter/memory"
+	"github.com/example/iboz/internal/testutil"
+)
+
+type visibleFunc func(messages []email.EmailMessage) []email.EmailMessage
+
+func (f visibleFunc) Visible(_ context.Context, messages []email.EmailMessage) ([]email.EmailMessage, error) {
+	return f(messages), nil
+}
+
+func TestSummaryIsComputedFromMessagesAndRuns(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2025, time.March, 18, 12, 0, 0, 0, time.UTC)
+	clock := testutil.NewClock(now)
+	day := 24 * time.Hour
+
+	emails := emailmemory.NewRepository()
+	if err := emails.SaveAuth(ctx, email.AuthRecord{State: email.AuthState{Username: "me@example.com"}}); err != nil {
+		t.Fatalf("save auth: %v", err)
+	}
+	messages := []email.EmailMessage{
+		{ID: "answered", Sender: "a@customer.example", ThreadID: "t-1", ReceivedAt: now.Add(-2 * day)},
+		{ID: "reply", Sender: "me@example.com", ThreadID: "t-1", ReceivedAt: now.Add(-day)},
+		{ID: "scheduled", Sender: "b@customer.example", ReceivedAt: now.Add(-2 * day)},
+		{ID: "open", Sender: "c@customer.example", ReceivedAt: now.Add(-3 * day)},
+		{ID: "old-crm", Sender: "d@customer.example", ReceivedAt: now.Add(-20 * day)},
+		{ID: "snoozed", Sender: "e@customer.example", ReceivedAt: now.Add(-day)},
+		{ID: "tasked", Sender: "f@customer.example", ReceivedAt: now.Add(-day)},
+	}
+	if err := emails.SaveMessages(ctx, messages, now); err != nil {
+		t.Fatalf("save messages: %v", err)
+	}
+
+	completed := now.Add(-day)
+	actions := schedulememory.NewRepository()
+	for _, action := range []schedule.Action{
+		{ID: "sent", Type: reply.Type, Payload: json.RawMessage(`{"messageId":"scheduled"}`), Status: schedule.StatusDone, CompletedAt: &completed},
+		{ID: "queued", Type: reply.Type, Payload: json.RawMessage(`{"messageId":"open"}`), Status: schedule.StatusScheduled, RunAt: now.Add(day)},
+	} {
+		if err := actions.Create(ctx, action); err != nil {
+			t.Fatalf("create action: %v", err)
+		}
+	}
+	taskRepo := tasksmemory.NewRepository()
+	for _, task := range []tasks.Task{
+		{ID: "task-1", MessageID: "tasked", ExternalID: "JIRA-1", Status: tasks.StatusOpen, CreatedAt: now.Add(-time.Hour)},
+		{ID: "task-2", MessageID: "open", Status: tasks.StatusPending, CreatedAt: now.Add(-time.Hour)},
+	} {
+		if err := taskRepo.Save(ctx, task); err != nil {
+			t.Fatalf("save task: %v", err)
+		}
+	}
+	synced := now.Add(-19 * day)
+	crmRepo := crmmemory.NewRepository()
+	if err := crmRepo.Save(ctx, crm.Record{ID: "rec-1", MessageID: "old-crm", Status: crm.StatusSynced, SyncedAt: &synced}); err != nil {
+		t.Fatalf("save record: %v", err)
+	}
+
+	svc := dashboard.NewService(emails, visibleFunc(func(messages []email.EmailMessage) []email.EmailMessage {
+		var visible []email.EmailMessage
+		for _, message := range messages {
+			if message.ID != "snoozed" {
+				visible = append(visible, message)
+			}
+		}
+		return visible
+	}), []dashboard.RunSource{runs.Replies(actions), runs.Tasks(taskRepo), runs.CRM(crmRepo)}, clock, dashboard.Config{})
+
+	summary, err := svc.Summary(ctx)
+	if err != nil {
+		t.Fatalf("summary: %v", err)
+	}
+	want := dashboard.Summary{
+		InboxZeroTarget:   dashboard.DefaultInboxZeroTarget,
+		CurrentInbox:      1,
+		AutomationRate:    0.4,
+		TimeSavedMinutes:  7,
+		AutomatedMessages: 2,
+		ReceivedMessages:  5,
+		WindowDays:        7,
+		ComputedAt:        now,
+	}
+	if summary != want {
+		t.Fatalf("unexpected summary:\n got %+v\nwant %+v", summary, want)
+	}
+
+	clock.Set(now.Add(time.Minute))
+	if err := emails.SaveMessages(ctx, append(messages, email.EmailMessage{ID: "new", Sender: "g@customer.example", ReceivedAt: clock.Now()}), clock.Now()); err != nil {
+		t.Fatalf("save new message: %v", err)
+	}
+	if cached, err := svc.Summary(ctx); err != nil || cached != want {
+		t.Fatalf("expected the cached summary, got %+v (%v)", cached, err)
+	}
+	if err := svc.MessagesSynced(ctx, nil, clock.Now()); err != nil {
+		t.Fatalf("synced: %v", err)
+	}
+	summary, err = svc.Summary(ctx)
+	if err != nil || summary.CurrentInbox != 2 || summary.ReceivedMessages != 6 || summary.AutomationRate != 0.33 || !summary.ComputedAt.Equal(clock.Now()) {
+		t.Fatalf("expected a recomputed summary after sync, got %+v (%v)", summary, err)
+	}
+
+	if err := taskRepo.Save(ctx, tasks.Task{ID: "task-2", MessageID: "open", ExternalID: "JIRA-2", Status: tasks.StatusOpen, CreatedAt: clock.Now()}); err != nil {
+		t.Fatalf("update task: %v", err)
+	}
+	clock.Advance(dashboard.DefaultTTL)
+	if summary, err = svc.Summary(ctx); err != nil || summary.CurrentInbox != 1 || summary.TimeSavedMinutes != 10 {
+		t.Fatalf("expected the summary to expire after the TTL, got %+v (%v)", summary, err)
+	}
+}
+
+func TestSummaryWithoutMessages(t *testing.T) {
+	svc := dashboard.NewService(emailmemory.NewRepository(), nil, nil, testutil.NewClock(time.Date(2025, time.March, 18, 12, 0, 0, 0, time.UTC)), dashboard.Config{InboxZeroTarget: 5, Window: 36 * time.Hour})
+	summary, err := svc.Summary(context.Background())
+	if err != nil {
+		t.Fatalf("summary: %v", err)
+	}
+	if summary.InboxZeroTarget != 5 || summary.WindowDays != 2 || summary.CurrentInbox != 0 || summary.AutomationRate != 0 {
+		t.Fatalf("unexpected empty summary: %+v", summary)
+	}
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := svc.Summary(cancelled); err == nil {
+		t.Fatal("expected an error for a cancelled context")
+	}
+}
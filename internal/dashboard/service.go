@@ -0,0 +1,184 @@
+package dashboard
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/example/iboz/internal/email"
+)
+
+var (
+	_ DashboardService   = (*Service)(nil)
+	_ email.SyncListener = (*Service)(nil)
+)
+
+// Service computes the dashboard summary and caches it until the next sync or
+// for at most the configured TTL.
+type Service struct {
+	messages   email.Repository
+	visibility Visibility
+	sources    []RunSource
+	clock      email.Clock
+	cfg        Config
+
+	mu         sync.Mutex
+	cached     *Summary
+	expires    time.Time
+	generation uint64
+}
+
+// NewService constructs a dashboard Service reading runs from sources.
+func NewService(messages email.Repository, visibility Visibility, sources []RunSource, clock email.Clock, cfg Config) *Service {
+	if messages == nil {
+		panic("dashboard: email repository dependency is required")
+	}
+	if clock == nil {
+		panic("dashboard: clock dependency is required")
+	}
+	for _, source := range sources {
+		if source == nil {
+			panic("dashboard: run source is nil")
+		}
+	}
+	if cfg.InboxZeroTarget < 0 || cfg.Window < 0 || cfg.TTL < 0 {
+		panic("dashboard: config values cannot be negative")
+	}
+	if cfg.InboxZeroTarget == 0 {
+		cfg.InboxZeroTarget = DefaultInboxZeroTarget
+	}
+	if cfg.Window == 0 {
+		cfg.Window = DefaultWindow
+	}
+	if cfg.TTL == 0 {
+		cfg.TTL = DefaultTTL
+	}
+	saved := DefaultSavedMinutes()
+	for kind, minutes := range cfg.SavedMinutes {
+		saved[kind] = minutes
+	}
+	cfg.SavedMinutes = saved
+	return &Service{messages: messages, visibility: visibility, sources: sources, clock: clock, cfg: cfg}
+}
+
+// MessagesSynced implements email.SyncListener by dropping the cached summary.
+func (s *Service) MessagesSynced(context.Context, []email.EmailMessage, time.Time) error {
+	s.Invalidate()
+	return nil
+}
+
+// Invalidate drops the cached summary so the next request recomputes it.
+func (s *Service) Invalidate() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cached = nil
+	s.generation++
+}
+
+// Summary returns the cached summary, computing it when none is cached or it
+// expired. A summary computed while the cache was invalidated is returned but
+// not cached.
+func (s *Service) Summary(ctx context.Context) (Summary, error) {
+	if err := ctx.Err(); err != nil {
+		return Summary{}, err
+	}
+	now := s.clock.Now().UTC()
+	s.mu.Lock()
+	if s.cached != nil && now.Before(s.expires) {
+		summary := *s.cached
+		s.mu.Unlock()
+		return summary, nil
+	}
+	generation := s.generation
+	s.mu.Unlock()
+
+	summary, err := s.compute(ctx, now)
+	if err != nil {
+		return Summary{}, err
+	}
+	s.mu.Lock()
+	if s.generation == generation {
+		s.cached = &summary
+		s.expires = now.Add(s.cfg.TTL)
+	}
+	s.mu.Unlock()
+	return summary, nil
+}
+
+// compute counts the inbox as the visible received messages that were neither
+// answered later in their thread nor handled by an automation, and rates the
+// automations over the messages received in the window.
+func (s *Service) compute(ctx context.Context, now time.Time) (Summary, error) {
+	since := now.Add(-s.cfg.Window)
+	summary := Summary{
+		InboxZeroTarget: s.cfg.InboxZeroTarget,
+		WindowDays:      int(math.Ceil(s.cfg.Window.Hours() / 24)),
+		ComputedAt:      now,
+	}
+
+	// Every run is read: a message handled before the window is still out of
+	// the inbox, but only runs within it count as time saved.
+	handled := make(map[string]bool)
+	for _, source := range s.sources {
+		runs, err := source.Runs(ctx, time.Time{})
+		if err != nil {
+			return Summary{}, err
+		}
+		for _, run := range runs {
+			if run.CompletedAt.After(now) {
+				continue
+			}
+			if !run.CompletedAt.Before(since) {
+				summary.TimeSavedMinutes += s.cfg.SavedMinutes[run.Kind]
+			}
+			if run.MessageID != "" {
+				handled[run.MessageID] = true
+			}
+		}
+	}
+
+	messages, _, err := s.messages.GetMessages(ctx)
+	if err != nil {
+		return Summary{}, err
+	}
+	auth, err := s.messages.GetAuth(ctx)
+	if err != nil {
+		return Summary{}, err
+	}
+	user := ""
+	if auth != nil {
+		user = auth.State.Username
+	}
+	replies := email.LatestReplies(messages, user)
+	received := make([]email.EmailMessage, 0, len(messages))
+	for _, message := range messages {
+		if message.SentBy(user) {
+			continue
+		}
+		received = append(received, message)
+		if !message.ReceivedAt.Before(since) {
+			summary.ReceivedMessages++
+			if handled[message.ID] {
+				summary.AutomatedMessages++
+			}
+		}
+	}
+	if summary.ReceivedMessages > 0 {
+		rate := float64(summary.AutomatedMessages) / float64(summary.ReceivedMessages)
+		summary.AutomationRate = math.Round(rate*100) / 100
+	}
+
+	if s.visibility != nil {
+		if received, err = s.visibility.Visible(ctx, received); err != nil {
+			return Summary{}, err
+		}
+	}
+	for _, message := range received {
+		if handled[message.ID] || replies.Answered(message) {
+			continue
+		}
+		summary.CurrentInbox++
+	}
+	return summary, nil
+}
@@ -0,0 +1,67 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/example/iboz/internal/delegation"
+	"github.com/example/iboz/internal/email"
+)
+
+var _ delegation.Nudger = (*Nudger)(nil)
+
+// Nudger emails assignees from the authenticated mailbox when a delegation is overdue.
+type Nudger struct {
+	sender email.MailSender
+	repo   email.Repository
+	clock  email.Clock
+}
+
+// NewNudger constructs an email-backed Nudger.
+func NewNudger(sender email.MailSender, repo email.Repository, clock email.Clock) *Nudger {
+	if sender == nil {
+		panic("mail: sender dependency is required")
+	}
+	if repo == nil {
+		panic("mail: repository dependency is required")
+	}
+	if clock == nil {
+		panic("mail: clock dependency is required")
+	}
+	return &Nudger{sender: sender, repo: repo, clock: clock}
+}
+
+// Nudge implements the delegation.Nudger interface.
+func (n *Nudger) Nudge(ctx context.Context, d delegation.Delegation) error {
+	auth, err := n.repo.GetAuth(ctx)
+	if err != nil {
+		return err
+	}
+	if auth == nil {
+		return email.ErrProviderNotAuthenticated
+	}
+
+	now := n.clock.Now().UTC()
+	messageID, err := email.NewMessageID(auth.State.Username, now)
+	if err != nil {
+		return err
+	}
+
+	return n.sender.Send(ctx, email.OutgoingMessage{
+		From:      auth.State.Username,
+		To:        []string{d.Assignee},
+		Subject:   fmt.Sprintf("Reminder: %q is overdue", d.Subject),
+		TextBody:  body(d),
+		Date:      now,
+		MessageID: messageID,
+	})
+}
+
+func body(d delegation.Delegation) string {
+	text := fmt.Sprintf("Hi,\n\nThe item %q delegated to you was due %s and is still open.\n", d.Subject, d.DueAt.Format(time.RFC1123))
+	if d.Note != "" {
+		text += fmt.Sprintf("\nNote: %s\n", d.Note)
+	}
+	return text + "\nPlease reply with an update or mark it done.\n"
+}
@@ -0,0 +1,95 @@
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/example/iboz/internal/delegation"
+)
+
+var _ delegation.Repository = (*Repository)(nil)
+
+// Repository provides an in-memory implementation of the delegation.Repository port.
+type Repository struct {
+	mu          sync.RWMutex
+	delegations map[string]delegation.Delegation
+}
+
+// NewRepository builds a new in-memory delegation repository.
+func NewRepository() *Repository {
+	return &Repository{delegations: make(map[string]delegation.Delegation)}
+}
+
+// Save inserts a new delegation or replaces one whose version matches the stored one.
+func (r *Repository) Save(ctx context.Context, d delegation.Delegation) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.delegations[d.ID]
+	if ok != (d.Version > 0) || stored.Version != d.Version {
+		return delegation.ErrConflict
+	}
+	d.Version++
+	r.delegations[d.ID] = clone(d)
+	return nil
+}
+
+// Get returns the delegation with the supplied identifier if present.
+func (r *Repository) Get(ctx context.Context, id string) (*delegation.Delegation, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	d, ok := r.delegations[id]
+	if !ok {
+		return nil, nil
+	}
+	cloned := clone(d)
+	return &cloned, nil
+}
+
+// List returns every stored delegation.
+func (r *Repository) List(ctx context.Context) ([]delegation.Delegation, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	list := make([]delegation.Delegation, 0, len(r.delegations))
+	for _, d := range r.delegations {
+		list = append(list, clone(d))
+	}
+	return list, nil
+}
+
+func clone(d delegation.Delegation) delegation.Delegation {
+	d.AcknowledgedAt = cloneTime(d.AcknowledgedAt)
+	d.CompletedAt = cloneTime(d.CompletedAt)
+	d.LastNudgedAt = cloneTime(d.LastNudgedAt)
+	if d.NudgedChannels != nil {
+		channels := make(map[string]time.Time, len(d.NudgedChannels))
+		for channel, at := range d.NudgedChannels {
+			channels[channel] = at
+		}
+		d.NudgedChannels = channels
+	}
+	return d
+}
+
+func cloneTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	v := *t
+	return &v
+}
@@ -0,0 +1,65 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/example/iboz/internal/delegation"
+)
+
+const defaultTimeout = 10 * time.Second
+
+var _ delegation.Nudger = (*Nudger)(nil)
+
+// Nudger posts overdue delegations to an HTTP endpoint as JSON.
+type Nudger struct {
+	url    string
+	client *http.Client
+}
+
+type payload struct {
+	Event      string                `json:"event"`
+	Delegation delegation.Delegation `json:"delegation"`
+}
+
+// NewNudger constructs a webhook-backed Nudger. A nil client uses a client with a 10s timeout.
+func NewNudger(url string, client *http.Client) *Nudger {
+	if url == "" {
+		panic("webhook: url is required")
+	}
+	if client == nil {
+		client = &http.Client{Timeout: defaultTimeout}
+	}
+	return &Nudger{url: url, client: client}
+}
+
+// Nudge implements the delegation.Nudger interface.
+func (n *Nudger) Nudge(ctx context.Context, d delegation.Delegation) error {
+	body, err := json.Marshal(payload{Event: "delegation.overdue", Delegation: d})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("webhook: post nudge: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook: unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,434 @@
+package delegation
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/mail"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/example/iboz/internal/email"
+)
+
+// Status enumerates the lifecycle of a delegation.
+type Status string
+
+const (
+	StatusAssigned     Status = "assigned"
+	StatusAcknowledged Status = "acknowledged"
+	StatusDone         Status = "done"
+	StatusOverdue      Status = "overdue"
+)
+
+// DefaultNudgeInterval is the minimum time between two nudges for the same delegation.
+const DefaultNudgeInterval = 24 * time.Hour
+
+// maxMergeAttempts bounds how often nudges are merged into a delegation that
+// keeps changing concurrently.
+const maxMergeAttempts = 3
+
+var transitions = map[Status]map[Status]struct{}{
+	StatusAssigned:     {StatusAcknowledged: {}, StatusDone: {}, StatusOverdue: {}},
+	StatusAcknowledged: {StatusDone: {}, StatusOverdue: {}},
+	StatusOverdue:      {StatusAcknowledged: {}, StatusDone: {}},
+	StatusDone:         {},
+}
+
+var (
+	// ErrDelegationNotFound is returned when a delegation does not exist.
+	ErrDelegationNotFound = errors.New("delegation not found")
+	// ErrInvalidTransition is returned when a status change is not allowed.
+	ErrInvalidTransition = errors.New("invalid delegation status transition")
+	// ErrInvalidDelegation is returned when a delegation request fails validation.
+	ErrInvalidDelegation = errors.New("invalid delegation")
+	// ErrConflict is returned by repositories when an update races with another writer.
+	ErrConflict = errors.New("delegation was modified concurrently")
+)
+
+// Delegation links a message to the teammate responsible for it. Version
+// increases on every stored change and guards updates against concurrent writers.
+type Delegation struct {
+	ID             string     `json:"id"`
+	MessageID      string     `json:"messageId"`
+	Subject        string     `json:"subject"`
+	Assignee       string     `json:"assignee"`
+	Note           string     `json:"note,omitempty"`
+	Status         Status     `json:"status"`
+	Version        int        `json:"version"`
+	SLA            string     `json:"sla,omitempty"`
+	DueAt          time.Time  `json:"dueAt"`
+	CreatedAt      time.Time  `json:"createdAt"`
+	UpdatedAt      time.Time  `json:"updatedAt"`
+	AcknowledgedAt *time.Time `json:"acknowledgedAt,omitempty"`
+	CompletedAt    *time.Time `json:"completedAt,omitempty"`
+	LastNudgedAt   *time.Time `json:"lastNudgedAt,omitempty"`
+	Nudges         int        `json:"nudges"`
+	// NudgedChannels records when each nudge channel last reached the assignee.
+	NudgedChannels map[string]time.Time `json:"nudgedChannels,omitempty"`
+}
+
+// Open reports whether the delegation still awaits completion.
+func (d Delegation) Open() bool {
+	return d.Status != StatusDone
+}
+
+// CreateRequest describes a new delegation. DueAt wins over SLA when both are set.
+type CreateRequest struct {
+	MessageID string
+	Assignee  string
+	Note      string
+	DueAt     time.Time
+	SLA       time.Duration
+}
+
+// Update describes a partial change to a delegation.
+type Update struct {
+	Status   *Status
+	Assignee *string
+	DueAt    *time.Time
+}
+
+// Repository defines the persistence contract for delegations.
+type Repository interface {
+	// Save inserts d when its Version is zero. Otherwise d is stored if the
+	// stored version equals d.Version and the version is incremented; a
+	// mismatch, or inserting an existing delegation, returns ErrConflict.
+	Save(ctx context.Context, d Delegation) error
+	Get(ctx context.Context, id string) (*Delegation, error)
+	List(ctx context.Context) ([]Delegation, error)
+}
+
+// Nudger reminds an assignee about an overdue delegation.
+type Nudger interface {
+	Nudge(ctx context.Context, d Delegation) error
+}
+
+// Nudgers maps nudge channel names, such as "email" or "webhook", to their
+// Nudger. Each channel keeps its own nudge interval, so a failing channel is
+// retried without re-sending on the channels that succeeded.
+type Nudgers map[string]Nudger
+
+// DelegationService exposes delegation tracking.
+type DelegationService interface {
+	Create(ctx context.Context, req CreateRequest) (Delegation, error)
+	Get(ctx context.Context, id string) (Delegation, error)
+	List(ctx context.Context, status Status) ([]Delegation, error)
+	Update(ctx context.Context, id string, update Update) (Delegation, error)
+	OpenCount(ctx context.Context) (int, error)
+	CheckOverdue(ctx context.Context) ([]Delegation, error)
+}
+
+var _ DelegationService = (*Service)(nil)
+
+// Service tracks delegations and nudges assignees when they become overdue.
+type Service struct {
+	repo          Repository
+	messages      email.Repository
+	nudgers       Nudgers
+	clock         email.Clock
+	nudgeInterval time.Duration
+}
+
+// NewService constructs a delegation Service. nudgers may be empty to only track overdue state.
+func NewService(repo Repository, messages email.Repository, nudgers Nudgers, clock email.Clock) *Service {
+	if repo == nil {
+		panic("delegation: repository dependency is required")
+	}
+	if messages == nil {
+		panic("delegation: message repository dependency is required")
+	}
+	if clock == nil {
+		panic("delegation: clock dependency is required")
+	}
+	for channel, nudger := range nudgers {
+		if nudger == nil {
+			panic(fmt.Sprintf("delegation: nudger %q is nil", channel))
+		}
+	}
+	return &Service{repo: repo, messages: messages, nudgers: nudgers, clock: clock, nudgeInterval: DefaultNudgeInterval}
+}
+
+// Create assigns a cached message to a teammate.
+func (s *Service) Create(ctx context.Context, req CreateRequest) (Delegation, error) {
+	if err := ctx.Err(); err != nil {
+		return Delegation{}, err
+	}
+
+	assignee, err := parseAssignee(req.Assignee)
+	if err != nil {
+		return Delegation{}, err
+	}
+	if req.DueAt.IsZero() && req.SLA <= 0 {
+		return Delegation{}, fmt.Errorf("%w: due date or SLA is required", ErrInvalidDelegation)
+	}
+
+	message, err := email.FindMessage(ctx, s.messages, req.MessageID)
+	if err != nil {
+		return Delegation{}, err
+	}
+
+	now := s.clock.Now().UTC()
+	due := req.DueAt.UTC()
+	var sla string
+	if req.SLA > 0 {
+		sla = req.SLA.String()
+		if due.IsZero() {
+			due = now.Add(req.SLA)
+		}
+	}
+	if !due.After(now) {
+		return Delegation{}, fmt.Errorf("%w: due date must be in the future", ErrInvalidDelegation)
+	}
+
+	id, err := newID()
+	if err != nil {
+		return Delegation{}, err
+	}
+
+	d := Delegation{
+		ID:        id,
+		MessageID: message.ID,
+		Subject:   message.Subject,
+		Assignee:  assignee,
+		Note:      strings.TrimSpace(req.Note),
+		Status:    StatusAssigned,
+		SLA:       sla,
+		DueAt:     due,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := s.repo.Save(ctx, d); err != nil {
+		return Delegation{}, err
+	}
+	d.Version++
+	return d, nil
+}
+
+// Get returns the delegation with the supplied identifier.
+func (s *Service) Get(ctx context.Context, id string) (Delegation, error) {
+	if err := ctx.Err(); err != nil {
+		return Delegation{}, err
+	}
+	d, err := s.repo.Get(ctx, id)
+	if err != nil {
+		return Delegation{}, err
+	}
+	if d == nil {
+		return Delegation{}, ErrDelegationNotFound
+	}
+	return *d, nil
+}
+
+// List returns delegations ordered by due date, optionally filtered by status.
+func (s *Service) List(ctx context.Context, status Status) ([]Delegation, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	all, err := s.repo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	list := make([]Delegation, 0, len(all))
+	for _, d := range all {
+		if status == "" || d.Status == status {
+			list = append(list, d)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].DueAt.Before(list[j].DueAt) })
+	return list, nil
+}
+
+// Update applies a status transition, reassignment or due date change.
+func (s *Service) Update(ctx context.Context, id string, update Update) (Delegation, error) {
+	d, err := s.Get(ctx, id)
+	if err != nil {
+		return Delegation{}, err
+	}
+
+	now := s.clock.Now().UTC()
+	if update.Assignee != nil {
+		assignee, err := parseAssignee(*update.Assignee)
+		if err != nil {
+			return Delegation{}, err
+		}
+		d.Assignee = assignee
+	}
+	if update.DueAt != nil {
+		d.DueAt = update.DueAt.UTC()
+		if d.Status == StatusOverdue && d.DueAt.After(now) {
+			d.Status = StatusAssigned
+			if d.AcknowledgedAt != nil {
+				d.Status = StatusAcknowledged
+			}
+		}
+	}
+	if update.Status != nil {
+		if _, known := transitions[*update.Status]; !known {
+			return Delegation{}, fmt.Errorf("%w: unknown status %q", ErrInvalidDelegation, *update.Status)
+		}
+	}
+	if update.Status != nil && *update.Status != d.Status {
+		if _, ok := transitions[d.Status][*update.Status]; !ok {
+			return Delegation{}, fmt.Errorf("%w: %s to %s", ErrInvalidTransition, d.Status, *update.Status)
+		}
+		d.Status = *update.Status
+		switch d.Status {
+		case StatusAcknowledged:
+			d.AcknowledgedAt = &now
+		case StatusDone:
+			d.CompletedAt = &now
+		}
+	}
+	d.UpdatedAt = now
+
+	if err := s.repo.Save(ctx, d); err != nil {
+		return Delegation{}, err
+	}
+	d.Version++
+	return d, nil
+}
+
+// OpenCount returns the number of delegations not yet done.
+func (s *Service) OpenCount(ctx context.Context) (int, error) {
+	list, err := s.List(ctx, "")
+	if err != nil {
+		return 0, err
+	}
+	count := 0
+	for _, d := range list {
+		if d.Open() {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// CheckOverdue marks open delegations past their due date as overdue and
+// nudges assignees at most once per nudge interval on every channel. A failing
+// delegation or channel does not hold back the others; their errors are joined.
+// A delegation changed by another writer during its nudge keeps that change.
+// It returns the delegations that were nudged on at least one channel.
+func (s *Service) CheckOverdue(ctx context.Context) ([]Delegation, error) {
+	list, err := s.List(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+
+	channels := make([]string, 0, len(s.nudgers))
+	for channel := range s.nudgers {
+		channels = append(channels, channel)
+	}
+	sort.Strings(channels)
+
+	now := s.clock.Now().UTC()
+	var (
+		nudged []Delegation
+		errs   []error
+	)
+	for _, d := range list {
+		if !d.Open() || now.Before(d.DueAt) {
+			continue
+		}
+
+		changed := d.Status != StatusOverdue
+		d.Status = StatusOverdue
+		sent := make(map[string]time.Time, len(channels))
+		for _, channel := range channels {
+			if last, ok := d.NudgedChannels[channel]; ok && now.Sub(last) < s.nudgeInterval {
+				continue
+			}
+			if err := s.nudgers[channel].Nudge(ctx, d); err != nil {
+				errs = append(errs, fmt.Errorf("nudge delegation %s via %s: %w", d.ID, channel, err))
+				continue
+			}
+			sent[channel] = now
+		}
+		reached := len(sent) > 0
+		recordNudges(&d, sent, now)
+		if !changed && !reached {
+			continue
+		}
+
+		d.UpdatedAt = now
+		err := s.repo.Save(ctx, d)
+		if errors.Is(err, ErrConflict) {
+			if !reached {
+				// Completed, reassigned or rescheduled meanwhile; keep that change.
+				continue
+			}
+			// The nudges went out, so they are recorded on top of that change.
+			d, err = s.mergeNudges(ctx, d.ID, sent, now)
+		}
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		d.Version++
+		if reached {
+			nudged = append(nudged, d)
+		}
+	}
+	return nudged, errors.Join(errs...)
+}
+
+// recordNudges records on d the nudges sent at now through the channels of sent.
+func recordNudges(d *Delegation, sent map[string]time.Time, now time.Time) {
+	if len(sent) == 0 {
+		return
+	}
+	if d.NudgedChannels == nil {
+		d.NudgedChannels = make(map[string]time.Time, len(sent))
+	}
+	for channel, at := range sent {
+		d.NudgedChannels[channel] = at
+	}
+	at := now
+	d.LastNudgedAt = &at
+	d.Nudges++
+}
+
+// mergeNudges records sent nudges on the stored delegation after it changed
+// while they were sent, reloading it until the save no longer conflicts. The
+// stored status is kept unless the delegation is still open and due.
+func (s *Service) mergeNudges(ctx context.Context, id string, sent map[string]time.Time, now time.Time) (Delegation, error) {
+	for attempt := 0; ; attempt++ {
+		d, err := s.Get(ctx, id)
+		if err != nil {
+			return Delegation{}, err
+		}
+		if d.Open() && !now.Before(d.DueAt) {
+			d.Status = StatusOverdue
+		}
+		recordNudges(&d, sent, now)
+		d.UpdatedAt = now
+		err = s.repo.Save(ctx, d)
+		if !errors.Is(err, ErrConflict) || attempt == maxMergeAttempts-1 {
+			return d, err
+		}
+	}
+}
+
+// parseAssignee validates that an assignee is a deliverable email address.
+func parseAssignee(value string) (string, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return "", fmt.Errorf("%w: assignee is required", ErrInvalidDelegation)
+	}
+	if _, err := mail.ParseAddress(value); err != nil {
+		return "", fmt.Errorf("%w: assignee %q is not an email address", ErrInvalidDelegation, value)
+	}
+	return value, nil
+}
+
+func newID() (string, error) {
+	buf := make([]byte, 6)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("generate delegation id: %w", err)
+	}
+	return "dlg-" + hex.EncodeToString(buf), nil
+}
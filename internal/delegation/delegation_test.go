@@ -0,0 +1,263 @@
+package delegation_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/example/iboz/internal/delegation"
+	"github.com/example/iboz/internal/delegation/adapter/memory"
+	"github.com/example/iboz/internal/delegation/adapter/webhook"
+	"github.com/example/iboz/internal/email"
+	emailmemory "github.com/example/iboz/internal/email/adapter/memory"
+	"github.com/example/iboz/internal/testutil"
+)
+
+type recordingNudger struct {
+	nudged []delegation.Delegation
+}
+
+func (r *recordingNudger) Nudge(_ context.Context, d delegation.Delegation) error {
+	r.nudged = append(r.nudged, d)
+	return nil
+}
+
+func newService(t *testing.T, nudgers delegation.Nudgers) (*delegation.Service, *testutil.Clock) {
+	t.Helper()
+	clock := testutil.NewClock(time.Date(2025, time.March, 18, 12, 0, 0, 0, time.UTC))
+	messages := emailmemory.NewRepository()
+	seed := []email.EmailMessage{{ID: "msg-contract", Subject: "Contract review", Sender: "legal@example.com"}}
+	if err := messages.SaveMessages(context.Background(), seed, clock.Now()); err != nil {
+		t.Fatalf("seed messages: %v", err)
+	}
+	return delegation.NewService(memory.NewRepository(), messages, nudgers, clock), clock
+}
+
+func TestCreateValidation(t *testing.T) {
+	svc, clock := newService(t, nil)
+	ctx := context.Background()
+
+	if _, err := svc.Create(ctx, delegation.CreateRequest{MessageID: "msg-contract", SLA: time.Hour}); !errors.Is(err, delegation.ErrInvalidDelegation) {
+		t.Fatalf("expected missing assignee error, got %v", err)
+	}
+	if _, err := svc.Create(ctx, delegation.CreateRequest{MessageID: "msg-contract", Assignee: "legal-ops@example.com"}); !errors.Is(err, delegation.ErrInvalidDelegation) {
+		t.Fatalf("expected missing due date error, got %v", err)
+	}
+	if _, err := svc.Create(ctx, delegation.CreateRequest{MessageID: "msg-contract", Assignee: "Legal team", SLA: time.Hour}); !errors.Is(err, delegation.ErrInvalidDelegation) {
+		t.Fatalf("expected an undeliverable assignee to be rejected, got %v", err)
+	}
+	if _, err := svc.Create(ctx, delegation.CreateRequest{MessageID: "missing", Assignee: "a@example.com", SLA: time.Hour}); !errors.Is(err, email.ErrMessageNotFound) {
+		t.Fatalf("expected message not found, got %v", err)
+	}
+	if _, err := svc.Create(ctx, delegation.CreateRequest{MessageID: "msg-contract", Assignee: "a@example.com", DueAt: clock.Now().Add(-time.Hour)}); !errors.Is(err, delegation.ErrInvalidDelegation) {
+		t.Fatalf("expected past due date error, got %v", err)
+	}
+
+	created, err := svc.Create(ctx, delegation.CreateRequest{MessageID: "msg-contract", Assignee: "legal-ops@example.com", SLA: 48 * time.Hour})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if created.Status != delegation.StatusAssigned || !created.DueAt.Equal(clock.Now().Add(48*time.Hour)) || created.Subject != "Contract review" {
+		t.Fatalf("unexpected delegation: %+v", created)
+	}
+}
+
+func TestStatusTransitions(t *testing.T) {
+	svc, _ := newService(t, nil)
+	ctx := context.Background()
+
+	created, err := svc.Create(ctx, delegation.CreateRequest{MessageID: "msg-contract", Assignee: "legal-ops@example.com", SLA: time.Hour})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	unknown := delegation.Status("archived")
+	if _, err := svc.Update(ctx, created.ID, delegation.Update{Status: &unknown}); !errors.Is(err, delegation.ErrInvalidDelegation) {
+		t.Fatalf("expected an unknown status to be invalid, got %v", err)
+	}
+
+	acknowledged := delegation.StatusAcknowledged
+	updated, err := svc.Update(ctx, created.ID, delegation.Update{Status: &acknowledged})
+	if err != nil {
+		t.Fatalf("acknowledge: %v", err)
+	}
+	if updated.AcknowledgedAt == nil {
+		t.Fatalf("expected acknowledgement timestamp")
+	}
+
+	done := delegation.StatusDone
+	if _, err := svc.Update(ctx, created.ID, delegation.Update{Status: &done}); err != nil {
+		t.Fatalf("complete: %v", err)
+	}
+
+	assigned := delegation.StatusAssigned
+	if _, err := svc.Update(ctx, created.ID, delegation.Update{Status: &assigned}); !errors.Is(err, delegation.ErrInvalidTransition) {
+		t.Fatalf("expected invalid transition, got %v", err)
+	}
+
+	count, err := svc.OpenCount(ctx)
+	if err != nil || count != 0 {
+		t.Fatalf("expected no open delegations, got %d (%v)", count, err)
+	}
+}
+
+func TestCheckOverdueNudgesOncePerInterval(t *testing.T) {
+	nudger := &recordingNudger{}
+	svc, clock := newService(t, delegation.Nudgers{"test": nudger})
+	ctx := context.Background()
+
+	created, err := svc.Create(ctx, delegation.CreateRequest{MessageID: "msg-contract", Assignee: "legal-ops@example.com", SLA: time.Hour})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	if nudged, _ := svc.CheckOverdue(ctx); len(nudged) != 0 {
+		t.Fatalf("nothing should be overdue yet")
+	}
+
+	clock.Advance(2 * time.Hour)
+	nudged, err := svc.CheckOverdue(ctx)
+	if err != nil {
+		t.Fatalf("check overdue: %v", err)
+	}
+	if len(nudged) != 1 || len(nudger.nudged) != 1 {
+		t.Fatalf("expected one nudge, got %d", len(nudger.nudged))
+	}
+
+	stored, _ := svc.Get(ctx, created.ID)
+	if stored.Status != delegation.StatusOverdue || stored.Nudges != 1 {
+		t.Fatalf("unexpected stored delegation: %+v", stored)
+	}
+
+	clock.Advance(time.Hour)
+	if _, err := svc.CheckOverdue(ctx); err != nil || len(nudger.nudged) != 1 {
+		t.Fatalf("expected no repeat nudge within interval, got %d (%v)", len(nudger.nudged), err)
+	}
+
+	clock.Advance(delegation.DefaultNudgeInterval)
+	if _, err := svc.CheckOverdue(ctx); err != nil || len(nudger.nudged) != 2 {
+		t.Fatalf("expected second nudge after interval, got %d (%v)", len(nudger.nudged), err)
+	}
+
+	later := clock.Now().Add(24 * time.Hour)
+	rescheduled, err := svc.Update(ctx, created.ID, delegation.Update{DueAt: &later})
+	if err != nil {
+		t.Fatalf("reschedule: %v", err)
+	}
+	if rescheduled.Status != delegation.StatusAssigned {
+		t.Fatalf("expected rescheduled delegation to leave overdue, got %s", rescheduled.Status)
+	}
+}
+
+// failingNudger fails every nudge for the assignees in fail.
+type failingNudger struct {
+	recordingNudger
+	fail map[string]bool
+}
+
+func (f *failingNudger) Nudge(ctx context.Context, d delegation.Delegation) error {
+	if f.fail[d.Assignee] {
+		return errors.New("mailbox unavailable")
+	}
+	return f.recordingNudger.Nudge(ctx, d)
+}
+
+func TestCheckOverdueContinuesPastFailures(t *testing.T) {
+	hook := &recordingNudger{}
+	mail := &failingNudger{fail: map[string]bool{"first@example.com": true}}
+	svc, clock := newService(t, delegation.Nudgers{"email": mail, "webhook": hook})
+	ctx := context.Background()
+
+	first, err := svc.Create(ctx, delegation.CreateRequest{MessageID: "msg-contract", Assignee: "first@example.com", SLA: time.Hour})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	second, err := svc.Create(ctx, delegation.CreateRequest{MessageID: "msg-contract", Assignee: "second@example.com", SLA: 2 * time.Hour})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	clock.Advance(3 * time.Hour)
+	nudged, err := svc.CheckOverdue(ctx)
+	if err == nil {
+		t.Fatal("expected the failing email nudge to be reported")
+	}
+	if len(nudged) != 2 || len(hook.nudged) != 2 || len(mail.nudged) != 1 || mail.nudged[0].ID != second.ID {
+		t.Fatalf("expected both delegations nudged past the failure, got %d (webhook %d, email %d)", len(nudged), len(hook.nudged), len(mail.nudged))
+	}
+	stored, _ := svc.Get(ctx, first.ID)
+	if _, ok := stored.NudgedChannels["webhook"]; !ok || stored.Nudges != 1 {
+		t.Fatalf("expected the webhook nudge to be recorded, got %+v", stored)
+	}
+	if _, ok := stored.NudgedChannels["email"]; ok {
+		t.Fatalf("expected the failed email nudge not to be recorded, got %+v", stored)
+	}
+
+	clock.Advance(time.Minute)
+	mail.fail = nil
+	if _, err := svc.CheckOverdue(ctx); err != nil {
+		t.Fatalf("check overdue: %v", err)
+	}
+	if len(hook.nudged) != 2 || len(mail.nudged) != 2 || mail.nudged[1].ID != first.ID {
+		t.Fatalf("expected only the failed email nudge to be retried, got webhook %d, email %d", len(hook.nudged), len(mail.nudged))
+	}
+}
+
+// nudgerFunc adapts a function to the delegation.Nudger interface.
+type nudgerFunc func(ctx context.Context, d delegation.Delegation) error
+
+func (f nudgerFunc) Nudge(ctx context.Context, d delegation.Delegation) error {
+	return f(ctx, d)
+}
+
+func TestCheckOverdueKeepsCompletionRecordedDuringNudge(t *testing.T) {
+	var svc *delegation.Service
+	done := delegation.StatusDone
+	svc, clock := newService(t, delegation.Nudgers{"email": nudgerFunc(func(ctx context.Context, d delegation.Delegation) error {
+		_, err := svc.Update(ctx, d.ID, delegation.Update{Status: &done})
+		return err
+	})})
+	ctx := context.Background()
+
+	created, err := svc.Create(ctx, delegation.CreateRequest{MessageID: "msg-contract", Assignee: "legal-ops@example.com", SLA: time.Hour})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	clock.Advance(2 * time.Hour)
+	if _, err := svc.CheckOverdue(ctx); err != nil {
+		t.Fatalf("check overdue: %v", err)
+	}
+	got, err := svc.Get(ctx, created.ID)
+	if err != nil || got.Status != delegation.StatusDone {
+		t.Fatalf("expected the completion to survive the nudge, got %+v (%v)", got, err)
+	}
+	if _, ok := got.NudgedChannels["email"]; !ok || got.Nudges != 1 || got.LastNudgedAt == nil {
+		t.Fatalf("expected the nudge sent meanwhile to be recorded, got %+v", got)
+	}
+}
+
+func TestWebhookNudgerPostsDelegation(t *testing.T) {
+	var received struct {
+		Event      string                `json:"event"`
+		Delegation delegation.Delegation `json:"delegation"`
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+			t.Errorf("decode payload: %v", err)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	nudger := webhook.NewNudger(srv.URL, srv.Client())
+	if err := nudger.Nudge(context.Background(), delegation.Delegation{ID: "dlg-1", Assignee: "a@example.com"}); err != nil {
+		t.Fatalf("nudge: %v", err)
+	}
+	if received.Event != "delegation.overdue" || received.Delegation.ID != "dlg-1" {
+		t.Fatalf("unexpected payload: %+v", received)
+	}
+}
@@ -0,0 +1,130 @@
+// Package llm summarises digest groups with a chat completions API, such as
+// OpenAI's or a compatible local server.
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/example/iboz/internal/digest"
+	"github.com/example/iboz/internal/email"
+)
+
+const (
+	// DefaultBaseURL is the OpenAI API root.
+	DefaultBaseURL = "https://api.openai.com/v1"
+	// MaxMessages caps the messages sent for one summary.
+	MaxMessages      = 20
+	defaultTimeout   = 30 * time.Second
+	maxResponseBytes = 1 << 20
+)
+
+const instructions = "You write the email digest of a busy professional. Summarise the messages of one sender " +
+	"in at most two plain sentences, naming the topics worth reading. Do not add greetings or lists."
+
+var _ digest.Summarizer = (*Summarizer)(nil)
+
+// Config configures a Summarizer. APIKey is sent as a bearer token when set.
+type Config struct {
+	BaseURL string
+	APIKey  string
+	Model   string
+	Client  *http.Client
+}
+
+// Summarizer asks a chat completions endpoint for a summary of each group.
+type Summarizer struct {
+	cfg Config
+}
+
+type chatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type chatRequest struct {
+	Model       string        `json:"model"`
+	Messages    []chatMessage `json:"messages"`
+	Temperature float64       `json:"temperature"`
+}
+
+type chatResponse struct {
+	Choices []struct {
+		Message chatMessage `json:"message"`
+	} `json:"choices"`
+}
+
+// NewSummarizer constructs a Summarizer. It panics without a model.
+func NewSummarizer(cfg Config) *Summarizer {
+	if cfg.Model == "" {
+		panic("llm: model is required")
+	}
+	if cfg.BaseURL == "" {
+		cfg.BaseURL = DefaultBaseURL
+	}
+	cfg.BaseURL = strings.TrimRight(cfg.BaseURL, "/")
+	if cfg.Client == nil {
+		cfg.Client = &http.Client{Timeout: defaultTimeout}
+	}
+	return &Summarizer{cfg: cfg}
+}
+
+// Summarize implements the digest.Summarizer interface.
+func (s *Summarizer) Summarize(ctx context.Context, sender string, messages []email.EmailMessage) (string, error) {
+	var prompt strings.Builder
+	fmt.Fprintf(&prompt, "Sender: %s\n", sender)
+	for i, message := range messages {
+		if i == MaxMessages {
+			break
+		}
+		fmt.Fprintf(&prompt, "\nSubject: %s\n%s\n", message.Subject, message.Snippet)
+	}
+	body, err := json.Marshal(chatRequest{
+		Model: s.cfg.Model,
+		Messages: []chatMessage{
+			{Role: "system", Content: instructions},
+			{Role: "user", Content: prompt.String()},
+		},
+		Temperature: 0.2,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.cfg.BaseURL+"/chat/completions", bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.cfg.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+s.cfg.APIKey)
+	}
+	resp, err := s.cfg.Client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("llm: post completion: %w", err)
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseBytes))
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return "", fmt.Errorf("llm: unexpected status %d: %s", resp.StatusCode, bytes.TrimSpace(respBody))
+	}
+
+	var completion chatResponse
+	if err := json.Unmarshal(respBody, &completion); err != nil {
+		return "", fmt.Errorf("llm: decode completion: %w", err)
+	}
+	if len(completion.Choices) == 0 {
+		return "", errors.New("llm: completion has no choices")
+	}
+	return strings.TrimSpace(completion.Choices[0].Message.Content), nil
+}
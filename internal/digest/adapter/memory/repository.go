@@ -0,0 +1,205 @@
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/example/iboz/internal/digest"
+	"github.com/example/iboz/internal/outbox"
+	outboxmemory "github.com/example/iboz/internal/outbox/adapter/memory"
+)
+
+var _ digest.Repository = (*Repository)(nil)
+
+// Repository provides an in-memory implementation of the digest.Repository port.
+type Repository struct {
+	mu       sync.RWMutex
+	digests  map[string]digest.Digest
+	editions map[string]digest.Edition
+	outbox   *outboxmemory.Table
+}
+
+// NewRepository builds a new in-memory digest repository.
+func NewRepository() *Repository {
+	return &Repository{
+		digests:  make(map[string]digest.Digest),
+		editions: make(map[string]digest.Edition),
+		outbox:   outboxmemory.NewTable(),
+	}
+}
+
+// SaveDigest inserts or replaces a digest.
+func (r *Repository) SaveDigest(ctx context.Context, d digest.Digest) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	r.digests[d.ID] = cloneDigest(d)
+	r.mu.Unlock()
+	return nil
+}
+
+// GetDigest returns the digest with the supplied identifier if present.
+func (r *Repository) GetDigest(ctx context.Context, id string) (*digest.Digest, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	d, ok := r.digests[id]
+	if !ok {
+		return nil, nil
+	}
+	cloned := cloneDigest(d)
+	return &cloned, nil
+}
+
+// ListDigests returns every stored digest.
+func (r *Repository) ListDigests(ctx context.Context) ([]digest.Digest, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	list := make([]digest.Digest, 0, len(r.digests))
+	for _, d := range r.digests {
+		list = append(list, cloneDigest(d))
+	}
+	return list, nil
+}
+
+// DeleteDigest removes a digest.
+func (r *Repository) DeleteDigest(ctx context.Context, id string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	delete(r.digests, id)
+	r.mu.Unlock()
+	return nil
+}
+
+// SaveEdition stores an edition and its digest and appends effects to the
+// outbox under the same lock.
+func (r *Repository) SaveEdition(ctx context.Context, edition digest.Edition, d digest.Digest, effects ...outbox.Entry) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	r.editions[edition.ID] = cloneEdition(edition)
+	r.digests[d.ID] = cloneDigest(d)
+	r.outbox.Append(effects...)
+	r.mu.Unlock()
+	return nil
+}
+
+// UpdateEdition replaces a stored edition.
+func (r *Repository) UpdateEdition(ctx context.Context, edition digest.Edition) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	r.editions[edition.ID] = cloneEdition(edition)
+	r.mu.Unlock()
+	return nil
+}
+
+// GetEdition returns the edition with the supplied identifier if present.
+func (r *Repository) GetEdition(ctx context.Context, id string) (*digest.Edition, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	edition, ok := r.editions[id]
+	if !ok {
+		return nil, nil
+	}
+	cloned := cloneEdition(edition)
+	return &cloned, nil
+}
+
+// ListEditions returns the editions of a digest.
+func (r *Repository) ListEditions(ctx context.Context, digestID string) ([]digest.Edition, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var list []digest.Edition
+	for _, edition := range r.editions {
+		if edition.DigestID == digestID {
+			list = append(list, cloneEdition(edition))
+		}
+	}
+	return list, nil
+}
+
+// PendingEntries implements the outbox.Store interface.
+func (r *Repository) PendingEntries(ctx context.Context, now time.Time, limit int) ([]outbox.Entry, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.outbox.Pending(now, limit), nil
+}
+
+// UpdateEntry implements the outbox.Store interface.
+func (r *Repository) UpdateEntry(ctx context.Context, entry outbox.Entry) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	r.outbox.Update(entry)
+	r.mu.Unlock()
+	return nil
+}
+
+func cloneDigest(d digest.Digest) digest.Digest {
+	d.Categories = append([]string(nil), d.Categories...)
+	d.Channels = append([]string(nil), d.Channels...)
+	if d.LastRunAt != nil {
+		last := *d.LastRunAt
+		d.LastRunAt = &last
+	}
+	if d.Included != nil {
+		included := make(map[string]time.Time, len(d.Included))
+		for id, receivedAt := range d.Included {
+			included[id] = receivedAt
+		}
+		d.Included = included
+	}
+	return d
+}
+
+func cloneEdition(edition digest.Edition) digest.Edition {
+	groups := make([]digest.Group, len(edition.Groups))
+	for i, group := range edition.Groups {
+		group.Items = append([]digest.Item(nil), group.Items...)
+		groups[i] = group
+	}
+	edition.Groups = groups
+	edition.Channels = append([]string(nil), edition.Channels...)
+	edition.Delivered = append([]string{}, edition.Delivered...)
+	if edition.DeliveredAt != nil {
+		delivered := *edition.DeliveredAt
+		edition.DeliveredAt = &delivered
+	}
+	return edition
+}
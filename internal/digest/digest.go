@@ -0,0 +1,179 @@
+// Package digest collects the messages of chosen categories into periodic
+// recaps grouped by sender, and delivers them by email or webhook on a cron
+// schedule.
+package digest
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/example/iboz/internal/email"
+	"github.com/example/iboz/internal/outbox"
+	"github.com/example/iboz/internal/webhooks"
+)
+
+const (
+	// DefaultSchedule sends digests on Fridays at 16:00.
+	DefaultSchedule = "0 16 * * 5"
+	// DefaultCategory is collected when a digest selects no categories.
+	DefaultCategory = "newsletter"
+	// DefaultPeriod is covered by the first edition of a digest; later editions
+	// cover the time since the previous one, plus the messages received up to
+	// DefaultPeriod before it that were synced too late for it.
+	DefaultPeriod = 7 * 24 * time.Hour
+	// MaxItems caps the messages listed per sender; the rest are only counted.
+	MaxItems = 10
+)
+
+// Delivery channels of a digest.
+const (
+	ChannelEmail   = "email"
+	ChannelWebhook = "webhook"
+)
+
+// OutboxDestination is the outbox destination of edition deliveries, delivered
+// by the Service's Deliverer.
+const OutboxDestination = "digest.delivery"
+
+var (
+	// ErrDigestNotFound is returned when a digest does not exist.
+	ErrDigestNotFound = errors.New("digest not found")
+	// ErrEditionNotFound is returned when an edition does not exist.
+	ErrEditionNotFound = errors.New("digest edition not found")
+	// ErrInvalidDigest is returned when a digest fails validation.
+	ErrInvalidDigest = errors.New("invalid digest")
+)
+
+// Digest is a recurring recap of the messages in Categories. Schedule is a
+// five-field cron expression evaluated in Timezone. Recipient defaults to the
+// authenticated mailbox. Included maps the messages already sent in an edition
+// to their receipt time; it reaches back DefaultPeriod before LastRunAt, the
+// span in which a message synced late is still picked up by the next edition.
+type Digest struct {
+	ID         string               `json:"id"`
+	Name       string               `json:"name"`
+	Categories []string             `json:"categories"`
+	Schedule   string               `json:"schedule"`
+	Timezone   string               `json:"timezone"`
+	Channels   []string             `json:"channels"`
+	Recipient  string               `json:"recipient,omitempty"`
+	Summarize  bool                 `json:"summarize"`
+	Active     bool                 `json:"active"`
+	NextRunAt  time.Time            `json:"nextRunAt"`
+	LastRunAt  *time.Time           `json:"lastRunAt,omitempty"`
+	Included   map[string]time.Time `json:"-"`
+	CreatedAt  time.Time            `json:"createdAt"`
+	UpdatedAt  time.Time            `json:"updatedAt"`
+}
+
+// Request creates or replaces the settings of a digest. Empty fields select
+// the defaults; Active defaults to true.
+type Request struct {
+	Name       string   `json:"name"`
+	Categories []string `json:"categories"`
+	Schedule   string   `json:"schedule"`
+	Timezone   string   `json:"timezone"`
+	Channels   []string `json:"channels"`
+	Recipient  string   `json:"recipient"`
+	Summarize  bool     `json:"summarize"`
+	Active     *bool    `json:"active"`
+}
+
+// Item is one message listed in a digest.
+type Item struct {
+	ID         string    `json:"id"`
+	Subject    string    `json:"subject"`
+	Snippet    string    `json:"snippet,omitempty"`
+	ReceivedAt time.Time `json:"receivedAt"`
+}
+
+// Group is the messages of one sender, newest first. Count includes the
+// messages beyond MaxItems.
+type Group struct {
+	Sender  string `json:"sender"`
+	Name    string `json:"name,omitempty"`
+	Count   int    `json:"count"`
+	Summary string `json:"summary,omitempty"`
+	Items   []Item `json:"items"`
+}
+
+// Edition is a digest built for the messages received between From and To.
+// Delivered lists the channels it was delivered to so far.
+type Edition struct {
+	ID          string     `json:"id"`
+	DigestID    string     `json:"digestId"`
+	From        time.Time  `json:"from"`
+	To          time.Time  `json:"to"`
+	Messages    int        `json:"messages"`
+	Groups      []Group    `json:"groups"`
+	Subject     string     `json:"subject"`
+	TextBody    string     `json:"textBody"`
+	HTMLBody    string     `json:"htmlBody"`
+	Channels    []string   `json:"channels"`
+	Recipient   string     `json:"recipient,omitempty"`
+	Delivered   []string   `json:"delivered"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	DeliveredAt *time.Time `json:"deliveredAt,omitempty"`
+}
+
+// DeliveredTo reports whether the edition was delivered to channel.
+func (e Edition) DeliveredTo(channel string) bool {
+	for _, delivered := range e.Delivered {
+		if delivered == channel {
+			return true
+		}
+	}
+	return false
+}
+
+// Repository defines the persistence contract for digests and their editions.
+// It owns the outbox table holding pending deliveries.
+type Repository interface {
+	outbox.Store
+	SaveDigest(ctx context.Context, digest Digest) error
+	GetDigest(ctx context.Context, id string) (*Digest, error)
+	ListDigests(ctx context.Context) ([]Digest, error)
+	DeleteDigest(ctx context.Context, id string) error
+	// SaveEdition stores edition and digest, as advanced by the run that built
+	// the edition, and appends effects to the outbox atomically.
+	SaveEdition(ctx context.Context, edition Edition, digest Digest, effects ...outbox.Entry) error
+	UpdateEdition(ctx context.Context, edition Edition) error
+	GetEdition(ctx context.Context, id string) (*Edition, error)
+	ListEditions(ctx context.Context, digestID string) ([]Edition, error)
+}
+
+// Summarizer is the LLM port summarising the messages of one sender.
+type Summarizer interface {
+	Summarize(ctx context.Context, sender string, messages []email.EmailMessage) (string, error)
+}
+
+// Publisher publishes platform events to webhook subscriptions.
+type Publisher interface {
+	Publish(ctx context.Context, eventType, key string, data any) (*webhooks.Event, error)
+}
+
+// Config tunes digests. Zero values select the defaults.
+type Config struct {
+	// Timezone is the default timezone of schedules, UTC when empty.
+	Timezone string
+}
+
+// DigestService manages digests and builds their editions.
+type DigestService interface {
+	Create(ctx context.Context, req Request) (*Digest, error)
+	Get(ctx context.Context, id string) (*Digest, error)
+	List(ctx context.Context) ([]Digest, error)
+	Update(ctx context.Context, id string, req Request) (*Digest, error)
+	Delete(ctx context.Context, id string) error
+	// Preview builds the next edition of a digest without storing it.
+	Preview(ctx context.Context, id string) (*Edition, error)
+	// Send builds the next edition now and queues its delivery. It returns nil
+	// when no message was received since the previous edition.
+	Send(ctx context.Context, id string) (*Edition, error)
+	Editions(ctx context.Context, digestID string) ([]Edition, error)
+	GetEdition(ctx context.Context, digestID, id string) (*Edition, error)
+	// RunDue sends the digests whose schedule is due and returns the number of
+	// editions queued.
+	RunDue(ctx context.Context) (int, error)
+}
@@ -0,0 +1,339 @@
+package digest_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/example/iboz/internal/digest"
+	"github.com/example/iboz/internal/digest/adapter/llm"
+	digestmemory "github.com/example/iboz/internal/digest/adapter/memory"
+	"github.com/example/iboz/internal/email"
+	emailmemory "github.com/example/iboz/internal/email/adapter/memory"
+	"github.com/example/iboz/internal/outbox"
+	"github.com/example/iboz/internal/testutil"
+	"github.com/example/iboz/internal/webhooks"
+)
+
+type recordingPublisher struct {
+	fail   error
+	events []string
+	data   []any
+}
+
+func (r *recordingPublisher) Publish(_ context.Context, eventType, key string, data any) (*webhooks.Event, error) {
+	if r.fail != nil {
+		return nil, r.fail
+	}
+	r.events = append(r.events, eventType+" "+key)
+	r.data = append(r.data, data)
+	return &webhooks.Event{}, nil
+}
+
+type stubSummarizer map[string]string
+
+func (s stubSummarizer) Summarize(_ context.Context, sender string, _ []email.EmailMessage) (string, error) {
+	summary, ok := s[sender]
+	if !ok {
+		return "", errors.New("model unavailable")
+	}
+	return summary, nil
+}
+
+type fixture struct {
+	service   *digest.Service
+	repo      *digestmemory.Repository
+	messages  *emailmemory.Repository
+	sender    *testutil.RecordingSender
+	publisher *recordingPublisher
+	relay     *outbox.Relay
+	clock     *testutil.Clock
+}
+
+func newFixture(t *testing.T, now time.Time, summarizer digest.Summarizer) fixture {
+	t.Helper()
+	clock := testutil.NewClock(now)
+	f := fixture{
+		repo:      digestmemory.NewRepository(),
+		messages:  testutil.Mailbox(t, clock),
+		sender:    &testutil.RecordingSender{},
+		publisher: &recordingPublisher{},
+		clock:     clock,
+	}
+	f.service = digest.NewService(f.repo, f.messages, f.sender, f.publisher, summarizer, f.clock, digest.Config{})
+	f.relay = testutil.NewRelay(f.repo, map[string]outbox.Deliverer{
+		digest.OutboxDestination: f.service.Deliverer(),
+	}, f.clock)
+	return f
+}
+
+func TestScheduleNext(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("timezone data unavailable: %v", err)
+	}
+	cases := []struct {
+		expr  string
+		after time.Time
+		want  time.Time
+	}{
+		{digest.DefaultSchedule, time.Date(2025, time.March, 5, 9, 0, 0, 0, ny), time.Date(2025, time.March, 7, 16, 0, 0, 0, ny)},
+		// The clocks go forward on March 9; the digest stays at 16:00 local time.
+		{digest.DefaultSchedule, time.Date(2025, time.March, 7, 16, 0, 0, 0, ny), time.Date(2025, time.March, 14, 16, 0, 0, 0, ny)},
+		{"*/15 9-17 * * mon-fri", time.Date(2025, time.March, 7, 17, 50, 0, 0, time.UTC), time.Date(2025, time.March, 10, 9, 0, 0, 0, time.UTC)},
+		{"@monthly", time.Date(2025, time.December, 15, 0, 0, 0, 0, time.UTC), time.Date(2026, time.January, 1, 0, 0, 0, 0, time.UTC)},
+		// Either restricted day field matches: the 15th or any Monday.
+		{"0 8 15 * 1", time.Date(2025, time.March, 11, 0, 0, 0, 0, time.UTC), time.Date(2025, time.March, 15, 8, 0, 0, 0, time.UTC)},
+		{"30 6 * * 7", time.Date(2025, time.March, 11, 0, 0, 0, 0, time.UTC), time.Date(2025, time.March, 16, 6, 30, 0, 0, time.UTC)},
+		{"0 0 29 feb *", time.Date(2025, time.March, 1, 0, 0, 0, 0, time.UTC), time.Date(2028, time.February, 29, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tc := range cases {
+		schedule, err := digest.ParseSchedule(tc.expr)
+		if err != nil {
+			t.Fatalf("parse %q: %v", tc.expr, err)
+		}
+		if got := schedule.Next(tc.after); !got.Equal(tc.want) {
+			t.Fatalf("%q after %s: got %s, want %s", tc.expr, tc.after, got, tc.want)
+		}
+	}
+
+	for _, expr := range []string{"", "* * * *", "60 * * * *", "0 24 * * *", "0 0 0 * *", "0 0 * 13 *", "0 0 * * 8", "5-1 * * * *", "*/0 * * * *", "0 0 30 2 *"} {
+		if _, err := digest.ParseSchedule(expr); err == nil {
+			t.Fatalf("expected %q to be rejected", expr)
+		}
+	}
+}
+
+func TestDigestCollectsGroupsAndDeliversOnSchedule(t *testing.T) {
+	ctx := context.Background()
+	friday := time.Date(2025, time.March, 14, 15, 0, 0, 0, time.UTC)
+	f := newFixture(t, friday, stubSummarizer{"news@letters.example": "Three issues on Go generics and tooling."})
+	if err := f.messages.SaveMessages(ctx, []email.EmailMessage{
+		{ID: "news-1", Subject: "Issue 41", Sender: "Letters <news@letters.example>", Snippet: "Generics deep dive", Category: "newsletter", ReceivedAt: friday.Add(-72 * time.Hour)},
+		{ID: "news-2", Subject: "Issue 42", Sender: "Letters <news@letters.example>", Category: "Newsletter", ReceivedAt: friday.Add(-48 * time.Hour)},
+		{ID: "news-3", Subject: "Issue 43", Sender: "news@letters.example", Category: "newsletter", ReceivedAt: friday.Add(-24 * time.Hour)},
+		{ID: "promo", Subject: "Spring sale", Sender: "deals@shop.example", Category: "promotions", ReceivedAt: friday.Add(-24 * time.Hour)},
+		{ID: "update", Subject: "Build passed", Sender: "ci@example.com", Category: "updates", ReceivedAt: friday.Add(-time.Hour)},
+		{ID: "old", Subject: "Issue 39", Sender: "news@letters.example", Category: "newsletter", ReceivedAt: friday.Add(-10 * 24 * time.Hour)},
+		{ID: "mine", Subject: "Re: Issue 43", Sender: "me@example.com", Category: "newsletter", Labels: []string{email.LabelSent}, ReceivedAt: friday.Add(-time.Hour)},
+	}, friday); err != nil {
+		t.Fatalf("save messages: %v", err)
+	}
+
+	created, err := f.service.Create(ctx, digest.Request{
+		Name:       "Friday recap",
+		Categories: []string{"Newsletter", "promotions", "newsletter"},
+		Channels:   []string{"email", "webhook"},
+		Summarize:  true,
+	})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if created.Schedule != digest.DefaultSchedule || created.Timezone != "UTC" || !created.Active || len(created.Categories) != 2 ||
+		!created.NextRunAt.Equal(time.Date(2025, time.March, 14, 16, 0, 0, 0, time.UTC)) {
+		t.Fatalf("unexpected digest: %+v", created)
+	}
+
+	if sent, err := f.service.RunDue(ctx); err != nil || sent != 0 {
+		t.Fatalf("expected nothing due before 16:00, got %d (%v)", sent, err)
+	}
+	f.clock.Set(created.NextRunAt)
+	if sent, err := f.service.RunDue(ctx); err != nil || sent != 1 {
+		t.Fatalf("expected one edition, got %d (%v)", sent, err)
+	}
+
+	editions, err := f.service.Editions(ctx, created.ID)
+	if err != nil || len(editions) != 1 {
+		t.Fatalf("expected one edition, got %+v (%v)", editions, err)
+	}
+	edition := editions[0]
+	if edition.Messages != 4 || len(edition.Groups) != 2 || edition.Recipient != "me@example.com" || edition.Subject != "Friday recap: 4 messages from 2 senders" {
+		t.Fatalf("unexpected edition: %+v", edition)
+	}
+	letters := edition.Groups[0]
+	if letters.Sender != "news@letters.example" || letters.Name != "Letters" || letters.Count != 3 || letters.Items[0].ID != "news-3" ||
+		letters.Summary != "Three issues on Go generics and tooling." {
+		t.Fatalf("unexpected first group: %+v", letters)
+	}
+	if shop := edition.Groups[1]; shop.Sender != "deals@shop.example" || shop.Summary != "" {
+		t.Fatalf("a failed summary should leave the group without one: %+v", shop)
+	}
+	for _, want := range []string{"Letters <news@letters.example> (3)", "- Issue 43 (Thu Mar 13)", "Generics deep dive"} {
+		if !strings.Contains(edition.TextBody, want) {
+			t.Fatalf("text body is missing %q:\n%s", want, edition.TextBody)
+		}
+	}
+	if !strings.Contains(edition.HTMLBody, "Letters &lt;news@letters.example&gt;") {
+		t.Fatalf("html body does not escape the sender:\n%s", edition.HTMLBody)
+	}
+
+	stored, err := f.service.Get(ctx, created.ID)
+	if err != nil || !stored.NextRunAt.Equal(time.Date(2025, time.March, 21, 16, 0, 0, 0, time.UTC)) || stored.LastRunAt == nil {
+		t.Fatalf("digest was not advanced to next Friday: %+v (%v)", stored, err)
+	}
+
+	// The webhook fails after the email went out; the retry only publishes.
+	f.publisher.fail = errors.New("gate closed")
+	if _, err := f.relay.Deliver(ctx); err == nil {
+		t.Fatal("expected the failed webhook to surface")
+	}
+	f.publisher.fail = nil
+	f.clock.Advance(time.Hour)
+	testutil.Deliver(t, f.relay)
+	if len(f.sender.Sent) != 1 || f.sender.Sent[0].To[0] != "me@example.com" || f.sender.Sent[0].From != "me@example.com" || f.sender.Sent[0].HTMLBody == "" {
+		t.Fatalf("expected one email to the mailbox, got %+v", f.sender.Sent)
+	}
+	if len(f.publisher.events) != 1 || f.publisher.events[0] != webhooks.EventDigestReady+" "+webhooks.EventDigestReady+":"+edition.ID {
+		t.Fatalf("expected one digest.ready event, got %v", f.publisher.events)
+	}
+	delivered, err := f.service.GetEdition(ctx, created.ID, edition.ID)
+	if err != nil || delivered.DeliveredAt == nil || len(delivered.Delivered) != 2 {
+		t.Fatalf("edition was not marked delivered: %+v (%v)", delivered, err)
+	}
+
+	// Nothing arrived since: the run is skipped and the schedule still moves on.
+	f.clock.Set(time.Date(2025, time.March, 21, 16, 0, 0, 0, time.UTC))
+	if sent, err := f.service.RunDue(ctx); err != nil || sent != 0 {
+		t.Fatalf("expected an empty run, got %d (%v)", sent, err)
+	}
+	if stored, _ := f.service.Get(ctx, created.ID); !stored.NextRunAt.Equal(time.Date(2025, time.March, 28, 16, 0, 0, 0, time.UTC)) {
+		t.Fatalf("empty run did not advance the schedule: %+v", stored)
+	}
+	if edition, err := f.service.Send(ctx, created.ID); err != nil || edition != nil {
+		t.Fatalf("expected no edition to send, got %+v (%v)", edition, err)
+	}
+}
+
+func TestDigestIncludesMessagesSyncedAfterAnEdition(t *testing.T) {
+	ctx := context.Background()
+	friday := time.Date(2025, time.March, 14, 16, 0, 0, 0, time.UTC)
+	f := newFixture(t, friday, nil)
+	early := email.EmailMessage{ID: "news-1", Subject: "Issue 41", Sender: "news@letters.example", Category: "newsletter", ReceivedAt: friday.Add(-24 * time.Hour)}
+	if err := f.messages.SaveMessages(ctx, []email.EmailMessage{early}, friday); err != nil {
+		t.Fatalf("save messages: %v", err)
+	}
+	created, err := f.service.Create(ctx, digest.Request{Name: "Recap", Channels: []string{"email"}})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if edition, err := f.service.Send(ctx, created.ID); err != nil || edition == nil || edition.Messages != 1 {
+		t.Fatalf("expected the first edition, got %+v (%v)", edition, err)
+	}
+
+	// Received before the edition was built, but only synced after it.
+	late := email.EmailMessage{ID: "news-2", Subject: "Issue 42", Sender: "news@letters.example", Category: "newsletter", ReceivedAt: friday.Add(-time.Hour)}
+	f.clock.Advance(time.Hour)
+	if err := f.messages.SaveMessages(ctx, []email.EmailMessage{early, late}, f.clock.Now()); err != nil {
+		t.Fatalf("save messages: %v", err)
+	}
+	edition, err := f.service.Send(ctx, created.ID)
+	if err != nil || edition == nil || edition.Messages != 1 || edition.Groups[0].Items[0].ID != "news-2" || !edition.From.Equal(late.ReceivedAt) {
+		t.Fatalf("expected only the late message in the next edition, got %+v (%v)", edition, err)
+	}
+	if edition, err := f.service.Send(ctx, created.ID); err != nil || edition != nil {
+		t.Fatalf("expected no message to be sent twice, got %+v (%v)", edition, err)
+	}
+}
+
+func TestDigestValidationAndPreview(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2025, time.March, 18, 12, 0, 0, 0, time.UTC)
+	f := newFixture(t, now, nil)
+
+	for _, req := range []digest.Request{
+		{Channels: []string{"sms"}},
+		{Schedule: "every friday"},
+		{Timezone: "Mars/Olympus"},
+		{Recipient: "not an address"},
+	} {
+		if _, err := f.service.Create(ctx, req); !errors.Is(err, digest.ErrInvalidDigest) {
+			t.Fatalf("expected %+v to be invalid, got %v", req, err)
+		}
+	}
+	noSender := digest.NewService(digestmemory.NewRepository(), f.messages, nil, nil, nil, f.clock, digest.Config{})
+	if _, err := noSender.Create(ctx, digest.Request{}); !errors.Is(err, digest.ErrInvalidDigest) {
+		t.Fatalf("expected email delivery to require a sender, got %v", err)
+	}
+
+	created, err := f.service.Create(ctx, digest.Request{Schedule: "@daily", Timezone: "Europe/Berlin", Recipient: "Me <team@example.com>", Summarize: true})
+	if err != nil {
+		t.Skipf("timezone data unavailable: %v", err)
+	}
+	if created.Name != "Digest" || created.Categories[0] != digest.DefaultCategory || created.Channels[0] != digest.ChannelEmail || created.Recipient != "team@example.com" ||
+		!created.NextRunAt.Equal(time.Date(2025, time.March, 18, 23, 0, 0, 0, time.UTC)) {
+		t.Fatalf("unexpected defaults: %+v", created)
+	}
+
+	if err := f.messages.SaveMessages(ctx, []email.EmailMessage{
+		{ID: "news-1", Subject: "Issue 1", Sender: "news@letters.example", Category: "newsletter", ReceivedAt: now.Add(-time.Hour)},
+	}, now); err != nil {
+		t.Fatalf("save messages: %v", err)
+	}
+	preview, err := f.service.Preview(ctx, created.ID)
+	if err != nil || preview.ID != "" || preview.Messages != 1 || preview.Subject != "Digest: 1 message from 1 sender" {
+		t.Fatalf("unexpected preview: %+v (%v)", preview, err)
+	}
+	if editions, err := f.service.Editions(ctx, created.ID); err != nil || len(editions) != 0 {
+		t.Fatalf("preview should not store an edition: %+v (%v)", editions, err)
+	}
+
+	paused := false
+	updated, err := f.service.Update(ctx, created.ID, digest.Request{Active: &paused, Channels: []string{"webhook"}})
+	if err != nil || updated.Active || updated.Timezone != "UTC" || updated.Channels[0] != digest.ChannelWebhook {
+		t.Fatalf("unexpected update: %+v (%v)", updated, err)
+	}
+	f.clock.Set(now.Add(48 * time.Hour))
+	if sent, err := f.service.RunDue(ctx); err != nil || sent != 0 {
+		t.Fatalf("paused digests should not run, got %d (%v)", sent, err)
+	}
+
+	if err := f.service.Delete(ctx, created.ID); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if _, err := f.service.Get(ctx, created.ID); !errors.Is(err, digest.ErrDigestNotFound) {
+		t.Fatalf("expected not found after delete, got %v", err)
+	}
+	if _, err := f.service.GetEdition(ctx, created.ID, "edn-missing"); !errors.Is(err, digest.ErrEditionNotFound) {
+		t.Fatalf("expected edition not found, got %v", err)
+	}
+}
+
+func TestLLMSummarizerPostsChatCompletion(t *testing.T) {
+	var auth, model string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/chat/completions" {
+			http.NotFound(w, r)
+			return
+		}
+		auth = r.Header.Get("Authorization")
+		var body struct {
+			Model    string `json:"model"`
+			Messages []struct {
+				Content string `json:"content"`
+			} `json:"messages"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil || len(body.Messages) != 2 || !strings.Contains(body.Messages[1].Content, "Subject: Issue 42") {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		model = body.Model
+		w.Write([]byte(`{"choices": [{"message": {"role": "assistant", "content": " Generics and tooling. "}}]}`))
+	}))
+	defer server.Close()
+
+	summarizer := llm.NewSummarizer(llm.Config{BaseURL: server.URL + "/v1/", APIKey: "sk-test", Model: "small"})
+	summary, err := summarizer.Summarize(context.Background(), "news@letters.example", []email.EmailMessage{{Subject: "Issue 42", Snippet: "Generics"}})
+	if err != nil || summary != "Generics and tooling." || auth != "Bearer sk-test" || model != "small" {
+		t.Fatalf("unexpected summary %q (%v), auth %q, model %q", summary, err, auth, model)
+	}
+
+	failing := llm.NewSummarizer(llm.Config{BaseURL: server.URL, Model: "small"})
+	if _, err := failing.Summarize(context.Background(), "news@letters.example", nil); err == nil {
+		t.Fatal("expected an error for an unexpected status")
+	}
+}
@@ -0,0 +1,90 @@
+package digest
+
+import (
+	"bytes"
+	"fmt"
+	htmltemplate "html/template"
+	texttemplate "text/template"
+	"time"
+)
+
+// view is the data exposed to the digest templates.
+type view struct {
+	Name     string
+	Overview string
+	Groups   []Group
+}
+
+var funcs = map[string]any{
+	"date": func(t time.Time) string { return t.Format("Mon Jan 2") },
+	"more": func(g Group) int { return g.Count - len(g.Items) },
+	"sender": func(g Group) string {
+		if g.Name == "" {
+			return g.Sender
+		}
+		return g.Name + " <" + g.Sender + ">"
+	},
+}
+
+var textTemplate = texttemplate.Must(texttemplate.New("text").Funcs(funcs).Parse(`{{.Name}}
+{{.Overview}}
+{{range .Groups}}
+{{sender .}} ({{.Count}})
+{{if .Summary}}{{.Summary}}
+{{end}}{{range .Items}}- {{.Subject}} ({{date .ReceivedAt}})
+{{if .Snippet}}  {{.Snippet}}
+{{end}}{{end}}{{if gt (more .) 0}}...and {{more .}} more
+{{end}}{{end}}`))
+
+var htmlTemplate = htmltemplate.Must(htmltemplate.New("html").Funcs(funcs).Parse(`<!DOCTYPE html>
+<html>
+<body style="font-family: sans-serif; max-width: 640px;">
+<h1 style="font-size: 20px;">{{.Name}}</h1>
+<p style="color: #555;">{{.Overview}}</p>
+{{range .Groups}}<h2 style="font-size: 16px; margin-top: 24px;">{{sender .}} <span style="color: #888;">({{.Count}})</span></h2>
+{{if .Summary}}<p>{{.Summary}}</p>
+{{end}}<ul>
+{{range .Items}}<li><strong>{{.Subject}}</strong> <span style="color: #888;">{{date .ReceivedAt}}</span>{{if .Snippet}}<br><span style="color: #555;">{{.Snippet}}</span>{{end}}</li>
+{{end}}</ul>
+{{if gt (more .) 0}}<p style="color: #888;">...and {{more .}} more</p>
+{{end}}{{end}}</body>
+</html>
+`))
+
+// render fills in the subject and bodies of edition, with dates in loc.
+func render(name string, loc *time.Location, edition *Edition) error {
+	senders := "senders"
+	if len(edition.Groups) == 1 {
+		senders = "sender"
+	}
+	messages := "messages"
+	if edition.Messages == 1 {
+		messages = "message"
+	}
+	counts := fmt.Sprintf("%d %s from %d %s", edition.Messages, messages, len(edition.Groups), senders)
+	v := view{
+		Name:     name,
+		Overview: fmt.Sprintf("%s, %s to %s.", counts, edition.From.In(loc).Format("Mon Jan 2"), edition.To.In(loc).Format("Mon Jan 2")),
+	}
+	for _, group := range edition.Groups {
+		items := make([]Item, len(group.Items))
+		for i, item := range group.Items {
+			item.ReceivedAt = item.ReceivedAt.In(loc)
+			items[i] = item
+		}
+		group.Items = items
+		v.Groups = append(v.Groups, group)
+	}
+
+	var text, html bytes.Buffer
+	if err := textTemplate.Execute(&text, v); err != nil {
+		return fmt.Errorf("render digest text: %w", err)
+	}
+	if err := htmlTemplate.Execute(&html, v); err != nil {
+		return fmt.Errorf("render digest html: %w", err)
+	}
+	edition.Subject = name + ": " + counts
+	edition.TextBody = text.String()
+	edition.HTMLBody = html.String()
+	return nil
+}
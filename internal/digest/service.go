@@ -0,0 +1,584 @@
+package digest
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/mail"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/example/iboz/internal/email"
+	"github.com/example/iboz/internal/outbox"
+	"github.com/example/iboz/internal/webhooks"
+)
+
+// defaultName names digests created without one.
+const defaultName = "Digest"
+
+var _ DigestService = (*Service)(nil)
+
+// Service builds digest editions and delivers them through the outbox.
+type Service struct {
+	repo       Repository
+	messages   email.Repository
+	sender     email.MailSender
+	publisher  Publisher
+	summarizer Summarizer
+	clock      email.Clock
+	cfg        Config
+
+	// mu serialises runs so a due digest is not sent twice.
+	mu sync.Mutex
+}
+
+// delivery is the outbox payload of an edition delivery.
+type delivery struct {
+	EditionID string `json:"editionId"`
+}
+
+// NewService constructs a digest Service. A nil sender or publisher disables
+// the email or webhook channel, and a nil summarizer leaves groups without
+// summaries.
+func NewService(repo Repository, messages email.Repository, sender email.MailSender, publisher Publisher, summarizer Summarizer, clock email.Clock, cfg Config) *Service {
+	if repo == nil {
+		panic("digest: repository dependency is required")
+	}
+	if messages == nil {
+		panic("digest: email repository dependency is required")
+	}
+	if clock == nil {
+		panic("digest: clock dependency is required")
+	}
+	if _, err := time.LoadLocation(cfg.Timezone); err != nil {
+		panic("digest: invalid timezone: " + err.Error())
+	}
+	return &Service{repo: repo, messages: messages, sender: sender, publisher: publisher, summarizer: summarizer, clock: clock, cfg: cfg}
+}
+
+// Create validates and stores a digest, scheduling its first edition.
+func (s *Service) Create(ctx context.Context, req Request) (*Digest, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	id, err := newID("dig-")
+	if err != nil {
+		return nil, err
+	}
+	now := s.clock.Now().UTC()
+	digest := Digest{ID: id, CreatedAt: now}
+	if err := s.apply(&digest, req, now); err != nil {
+		return nil, err
+	}
+	if err := s.repo.SaveDigest(ctx, digest); err != nil {
+		return nil, err
+	}
+	return &digest, nil
+}
+
+// Get returns a digest by ID.
+func (s *Service) Get(ctx context.Context, id string) (*Digest, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	digest, err := s.repo.GetDigest(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if digest == nil {
+		return nil, ErrDigestNotFound
+	}
+	return digest, nil
+}
+
+// List returns every digest, oldest first.
+func (s *Service) List(ctx context.Context) ([]Digest, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	list, err := s.repo.ListDigests(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if list == nil {
+		list = []Digest{}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].CreatedAt.Before(list[j].CreatedAt) })
+	return list, nil
+}
+
+// Update replaces the settings of a digest and reschedules it.
+func (s *Service) Update(ctx context.Context, id string, req Request) (*Digest, error) {
+	digest, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.apply(digest, req, s.clock.Now().UTC()); err != nil {
+		return nil, err
+	}
+	if err := s.repo.SaveDigest(ctx, *digest); err != nil {
+		return nil, err
+	}
+	return digest, nil
+}
+
+// Delete removes a digest. Its editions are kept.
+func (s *Service) Delete(ctx context.Context, id string) error {
+	if _, err := s.Get(ctx, id); err != nil {
+		return err
+	}
+	return s.repo.DeleteDigest(ctx, id)
+}
+
+// Preview implements the DigestService interface. The edition has no ID.
+func (s *Service) Preview(ctx context.Context, id string) (*Edition, error) {
+	digest, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	edition, _, err := s.build(ctx, *digest, s.clock.Now().UTC())
+	return edition, err
+}
+
+// Send implements the DigestService interface. The schedule is unchanged, so
+// the next scheduled edition covers the messages received after this one.
+func (s *Service) Send(ctx context.Context, id string) (*Edition, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	digest, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return s.send(ctx, *digest, s.clock.Now().UTC(), false)
+}
+
+// RunDue implements the DigestService interface. A digest whose edition could
+// not be built is retried on the next run.
+func (s *Service) RunDue(ctx context.Context) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	digests, err := s.repo.ListDigests(ctx)
+	if err != nil {
+		return 0, err
+	}
+	now := s.clock.Now().UTC()
+	sent := 0
+	var errs []error
+	for _, digest := range digests {
+		if !digest.Active || digest.NextRunAt.After(now) {
+			continue
+		}
+		edition, err := s.send(ctx, digest, now, true)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("digest %s: %w", digest.ID, err))
+			continue
+		}
+		if edition != nil {
+			sent++
+		}
+	}
+	return sent, errors.Join(errs...)
+}
+
+// Editions returns the editions of a digest, newest first.
+func (s *Service) Editions(ctx context.Context, digestID string) ([]Edition, error) {
+	if _, err := s.Get(ctx, digestID); err != nil {
+		return nil, err
+	}
+	list, err := s.repo.ListEditions(ctx, digestID)
+	if err != nil {
+		return nil, err
+	}
+	if list == nil {
+		list = []Edition{}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].CreatedAt.After(list[j].CreatedAt) })
+	return list, nil
+}
+
+// GetEdition returns an edition of a digest.
+func (s *Service) GetEdition(ctx context.Context, digestID, id string) (*Edition, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	edition, err := s.repo.GetEdition(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if edition == nil || edition.DigestID != digestID {
+		return nil, ErrEditionNotFound
+	}
+	return edition, nil
+}
+
+// Deliverer returns the outbox deliverer sending editions to their channels.
+// Each channel is recorded as it completes so a retried delivery resumes after
+// it.
+func (s *Service) Deliverer() outbox.Deliverer {
+	return outbox.DelivererFunc(func(ctx context.Context, entry outbox.Entry) error {
+		var payload delivery
+		if err := json.Unmarshal(entry.Payload, &payload); err != nil {
+			return fmt.Errorf("decode digest delivery: %w", err)
+		}
+		edition, err := s.repo.GetEdition(ctx, payload.EditionID)
+		if err != nil {
+			return err
+		}
+		if edition == nil {
+			return ErrEditionNotFound
+		}
+		if edition.DeliveredAt != nil {
+			return nil
+		}
+
+		for _, channel := range edition.Channels {
+			if edition.DeliveredTo(channel) {
+				continue
+			}
+			switch channel {
+			case ChannelEmail:
+				err = s.mail(ctx, *edition)
+			case ChannelWebhook:
+				err = s.publish(ctx, *edition)
+			default:
+				err = fmt.Errorf("%w: unknown channel %q", ErrInvalidDigest, channel)
+			}
+			if err != nil {
+				return err
+			}
+			edition.Delivered = append(edition.Delivered, channel)
+			if err := s.repo.UpdateEdition(ctx, *edition); err != nil {
+				return err
+			}
+		}
+		now := s.clock.Now().UTC()
+		edition.DeliveredAt = &now
+		return s.repo.UpdateEdition(ctx, *edition)
+	})
+}
+
+// send builds the next edition of digest and stores it with its delivery.
+// Scheduled runs also advance the digest to its next time. Without messages no
+// edition is stored and nil is returned.
+func (s *Service) send(ctx context.Context, digest Digest, now time.Time, scheduled bool) (*Edition, error) {
+	edition, selected, err := s.build(ctx, digest, now)
+	if err != nil {
+		return nil, err
+	}
+	included := make(map[string]time.Time, len(digest.Included)+len(selected))
+	for id, receivedAt := range digest.Included {
+		if receivedAt.After(now.Add(-DefaultPeriod)) {
+			included[id] = receivedAt
+		}
+	}
+	for _, message := range selected {
+		included[message.ID] = message.ReceivedAt
+	}
+	digest.Included = included
+	if scheduled {
+		schedule, loc, err := digest.parse()
+		if err != nil {
+			return nil, err
+		}
+		digest.NextRunAt = schedule.Next(now.In(loc)).UTC()
+	}
+	digest.LastRunAt = &now
+	if edition.Messages == 0 {
+		return nil, s.repo.SaveDigest(ctx, digest)
+	}
+
+	if edition.ID, err = newID("edn-"); err != nil {
+		return nil, err
+	}
+	entry, err := outbox.NewEntry(OutboxDestination, "digest:deliver:"+edition.ID, delivery{EditionID: edition.ID}, now)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.SaveEdition(ctx, *edition, digest, entry); err != nil {
+		return nil, err
+	}
+	return edition, nil
+}
+
+// build collects the messages received in the digest's categories that no
+// previous edition included, over DefaultPeriod for the first edition and
+// since DefaultPeriod before the previous one for later editions, so messages
+// synced after an edition was built still make the next one. They are grouped
+// by sender, largest group first, and returned with the edition.
+func (s *Service) build(ctx context.Context, digest Digest, now time.Time) (*Edition, []email.EmailMessage, error) {
+	_, loc, err := digest.parse()
+	if err != nil {
+		return nil, nil, err
+	}
+	since, floor := now.Add(-DefaultPeriod), now.Add(-DefaultPeriod)
+	if digest.LastRunAt != nil {
+		since, floor = *digest.LastRunAt, digest.LastRunAt.Add(-DefaultPeriod)
+	}
+	messages, _, err := s.messages.GetMessages(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	owner, err := s.owner(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	bySender := make(map[string][]email.EmailMessage)
+	var order []string
+	var selected []email.EmailMessage
+	for _, message := range messages {
+		if message.SentBy(owner) || !message.ReceivedAt.After(floor) || message.ReceivedAt.After(now) || !digest.collects(message.Category) {
+			continue
+		}
+		if _, ok := digest.Included[message.ID]; ok {
+			continue
+		}
+		if message.ReceivedAt.Before(since) {
+			since = message.ReceivedAt
+		}
+		selected = append(selected, message)
+		address, _ := senderOf(message.Sender)
+		if _, ok := bySender[address]; !ok {
+			order = append(order, address)
+		}
+		bySender[address] = append(bySender[address], message)
+	}
+
+	groups := make([]Group, 0, len(order))
+	for _, address := range order {
+		received := bySender[address]
+		sort.Slice(received, func(i, j int) bool { return received[i].ReceivedAt.After(received[j].ReceivedAt) })
+		group := Group{Sender: address, Count: len(received), Items: []Item{}}
+		for _, message := range received {
+			if _, name := senderOf(message.Sender); name != "" {
+				group.Name = name
+				break
+			}
+		}
+		for i, message := range received {
+			if i == MaxItems {
+				break
+			}
+			group.Items = append(group.Items, Item{ID: message.ID, Subject: message.Subject, Snippet: message.Snippet, ReceivedAt: message.ReceivedAt})
+		}
+		if digest.Summarize && s.summarizer != nil {
+			summary, err := s.summarizer.Summarize(ctx, address, received)
+			if err != nil {
+				if ctx.Err() != nil {
+					return nil, nil, ctx.Err()
+				}
+				// The digest is still useful without the summary.
+				log.Printf("digest: summarising %s failed: %v", address, err)
+			}
+			group.Summary = strings.TrimSpace(summary)
+		}
+		groups = append(groups, group)
+	}
+	sort.SliceStable(groups, func(i, j int) bool {
+		if groups[i].Count != groups[j].Count {
+			return groups[i].Count > groups[j].Count
+		}
+		return groups[i].Sender < groups[j].Sender
+	})
+
+	recipient := digest.Recipient
+	if recipient == "" {
+		recipient = owner
+	}
+	edition := &Edition{
+		DigestID:  digest.ID,
+		From:      since,
+		To:        now,
+		Messages:  len(selected),
+		Groups:    groups,
+		Channels:  append([]string(nil), digest.Channels...),
+		Recipient: recipient,
+		Delivered: []string{},
+		CreatedAt: now,
+	}
+	if err := render(digest.Name, loc, edition); err != nil {
+		return nil, nil, err
+	}
+	return edition, selected, nil
+}
+
+// mail sends the edition from the authenticated mailbox.
+func (s *Service) mail(ctx context.Context, edition Edition) error {
+	if s.sender == nil {
+		return email.ErrSenderNotConfigured
+	}
+	owner, err := s.owner(ctx)
+	if err != nil {
+		return err
+	}
+	if owner == "" {
+		return email.ErrProviderNotAuthenticated
+	}
+	recipient := edition.Recipient
+	if recipient == "" {
+		recipient = owner
+	}
+	now := s.clock.Now().UTC()
+	messageID, err := email.NewMessageID(owner, now)
+	if err != nil {
+		return err
+	}
+	return s.sender.Send(ctx, email.OutgoingMessage{
+		From:      owner,
+		To:        []string{recipient},
+		Subject:   edition.Subject,
+		TextBody:  edition.TextBody,
+		HTMLBody:  edition.HTMLBody,
+		Date:      now,
+		MessageID: messageID,
+	})
+}
+
+// publish sends the edition to the webhook subscriptions of digest.ready.
+func (s *Service) publish(ctx context.Context, edition Edition) error {
+	if s.publisher == nil {
+		return fmt.Errorf("%w: webhook delivery is not configured", ErrInvalidDigest)
+	}
+	_, err := s.publisher.Publish(ctx, webhooks.EventDigestReady, webhooks.EventDigestReady+":"+edition.ID, edition)
+	return err
+}
+
+func (s *Service) owner(ctx context.Context) (string, error) {
+	auth, err := s.messages.GetAuth(ctx)
+	if err != nil || auth == nil {
+		return "", err
+	}
+	return auth.State.Username, nil
+}
+
+// apply validates req and copies it onto digest.
+func (s *Service) apply(digest *Digest, req Request, now time.Time) error {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		name = defaultName
+	}
+
+	categories := []string{}
+	seen := make(map[string]bool)
+	for _, category := range req.Categories {
+		category = strings.ToLower(strings.TrimSpace(category))
+		if category != "" && !seen[category] {
+			seen[category] = true
+			categories = append(categories, category)
+		}
+	}
+	if len(categories) == 0 {
+		categories = []string{DefaultCategory}
+	}
+
+	requested := req.Channels
+	if len(requested) == 0 {
+		requested = []string{ChannelEmail}
+	}
+	channels := []string{}
+	selected := make(map[string]bool)
+	for _, channel := range requested {
+		channel = strings.ToLower(strings.TrimSpace(channel))
+		if selected[channel] {
+			continue
+		}
+		switch channel {
+		case ChannelEmail:
+			if s.sender == nil {
+				return fmt.Errorf("%w: email delivery is not configured", ErrInvalidDigest)
+			}
+		case ChannelWebhook:
+			if s.publisher == nil {
+				return fmt.Errorf("%w: webhook delivery is not configured", ErrInvalidDigest)
+			}
+		default:
+			return fmt.Errorf("%w: unknown channel %q", ErrInvalidDigest, channel)
+		}
+		selected[channel] = true
+		channels = append(channels, channel)
+	}
+
+	recipient := strings.TrimSpace(req.Recipient)
+	if recipient != "" {
+		parsed, err := mail.ParseAddress(recipient)
+		if err != nil {
+			return fmt.Errorf("%w: invalid recipient %q", ErrInvalidDigest, recipient)
+		}
+		recipient = parsed.Address
+	}
+
+	candidate := *digest
+	candidate.Name = name
+	candidate.Categories = categories
+	candidate.Schedule = strings.TrimSpace(req.Schedule)
+	if candidate.Schedule == "" {
+		candidate.Schedule = DefaultSchedule
+	}
+	candidate.Timezone = strings.TrimSpace(req.Timezone)
+	if candidate.Timezone == "" {
+		candidate.Timezone = s.cfg.Timezone
+	}
+	if candidate.Timezone == "" {
+		candidate.Timezone = "UTC"
+	}
+	schedule, loc, err := candidate.parse()
+	if err != nil {
+		return err
+	}
+	candidate.Channels = channels
+	candidate.Recipient = recipient
+	candidate.Summarize = req.Summarize
+	candidate.Active = req.Active == nil || *req.Active
+	candidate.NextRunAt = schedule.Next(now.In(loc)).UTC()
+	candidate.UpdatedAt = now
+	*digest = candidate
+	return nil
+}
+
+// parse returns the schedule and timezone of the digest.
+func (d Digest) parse() (Schedule, *time.Location, error) {
+	schedule, err := ParseSchedule(d.Schedule)
+	if err != nil {
+		return Schedule{}, nil, fmt.Errorf("%w: %v", ErrInvalidDigest, err)
+	}
+	loc, err := time.LoadLocation(d.Timezone)
+	if err != nil {
+		return Schedule{}, nil, fmt.Errorf("%w: unknown timezone %q", ErrInvalidDigest, d.Timezone)
+	}
+	return schedule, loc, nil
+}
+
+// collects reports whether the digest collects messages of category.
+func (d Digest) collects(category string) bool {
+	for _, selected := range d.Categories {
+		if strings.EqualFold(selected, category) {
+			return true
+		}
+	}
+	return false
+}
+
+// senderOf returns the lower-cased address and the display name of a sender.
+func senderOf(sender string) (string, string) {
+	if parsed, err := mail.ParseAddress(sender); err == nil {
+		return strings.ToLower(parsed.Address), parsed.Name
+	}
+	return strings.ToLower(strings.TrimSpace(sender)), ""
+}
+
+func newID(prefix string) (string, error) {
+	buf := make([]byte, 6)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("generate digest id: %w", err)
+	}
+	return prefix + hex.EncodeToString(buf), nil
+}
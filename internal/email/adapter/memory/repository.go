@@ -117,6 +117,7 @@ func (r *Repository) ClearMessages(ctx context.Context) error {
 }
 
 // SaveMessages replaces the cached messages and updates the last sync timestamp.
+// Task and CRM links are local annotations and carry over to messages that keep their ID.
 func (r *Repository) SaveMessages(ctx context.Context, messages []email.EmailMessage, syncedAt time.Time) error {
 	if err := ctx.Err(); err != nil {
 		return err
@@ -125,6 +126,20 @@ func (r *Repository) SaveMessages(ctx context.Context, messages []email.EmailMes
 	cloned := cloneMessages(messages)
 
 	r.mu.Lock()
+	for i, msg := range cloned {
+		for _, previous := range r.messages {
+			if previous.ID != msg.ID {
+				continue
+			}
+			if len(msg.Tasks) == 0 {
+				cloned[i].Tasks = append([]email.TaskLink(nil), previous.Tasks...)
+			}
+			if len(msg.CRM) == 0 {
+				cloned[i].CRM = append([]email.CRMLink(nil), previous.CRM...)
+			}
+			break
+		}
+	}
 	r.messages = cloned
 	r.lastSync = syncedAt
 	r.mu.Unlock()
@@ -155,6 +170,100 @@ func cloneMessages(messages []email.EmailMessage) []email.EmailMessage {
 	for i, msg := range messages {
 		cloned[i] = msg
 		cloned[i].Labels = append([]string(nil), msg.Labels...)
+		cloned[i].References = append([]string(nil), msg.References...)
+		cloned[i].Recipients = append([]string(nil), msg.Recipients...)
+		cloned[i].Tasks = append([]email.TaskLink(nil), msg.Tasks...)
+		cloned[i].CRM = append([]email.CRMLink(nil), msg.CRM...)
+		cloned[i].Headers = msg.Headers.Clone()
+		cloned[i].Bulk = msg.Bulk.Clone()
+		cloned[i].Spam = msg.Spam.Clone()
 	}
 	return cloned
 }
+
+// Relabel adds and removes labels on a cached message, mirroring a provider label change.
+func (r *Repository) Relabel(ctx context.Context, messageID string, add, remove []string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, msg := range r.messages {
+		if msg.ID != messageID {
+			continue
+		}
+		labels := make([]string, 0, len(msg.Labels)+len(add))
+		for _, label := range msg.Labels {
+			if !containsLabel(remove, label) && !containsLabel(add, label) {
+				labels = append(labels, label)
+			}
+		}
+		labels = append(labels, add...)
+		r.messages[i].Labels = labels
+		return nil
+	}
+	return email.ErrMessageNotFound
+}
+
+// LinkTask records a task created from a message, replacing an earlier link to
+// the same external task.
+func (r *Repository) LinkTask(ctx context.Context, messageID string, link email.TaskLink) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, msg := range r.messages {
+		if msg.ID != messageID {
+			continue
+		}
+		for j, existing := range msg.Tasks {
+			if existing.Provider == link.Provider && existing.ExternalID == link.ExternalID {
+				r.messages[i].Tasks[j] = link
+				return nil
+			}
+		}
+		r.messages[i].Tasks = append(r.messages[i].Tasks, link)
+		return nil
+	}
+	return email.ErrMessageNotFound
+}
+
+// LinkCRM records a CRM record created from a message, replacing an earlier
+// link to the same external record.
+func (r *Repository) LinkCRM(ctx context.Context, messageID string, link email.CRMLink) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, msg := range r.messages {
+		if msg.ID != messageID {
+			continue
+		}
+		for j, existing := range msg.CRM {
+			if existing.Provider == link.Provider && existing.ExternalID == link.ExternalID {
+				r.messages[i].CRM[j] = link
+				return nil
+			}
+		}
+		r.messages[i].CRM = append(r.messages[i].CRM, link)
+		return nil
+	}
+	return email.ErrMessageNotFound
+}
+
+func containsLabel(labels []string, label string) bool {
+	for _, candidate := range labels {
+		if candidate == label {
+			return true
+		}
+	}
+	return false
+}
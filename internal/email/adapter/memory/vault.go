@@ -0,0 +1,61 @@
+package memory
+
+import (
+	"context"
+	"sync"
+
+	"github.com/example/iboz/internal/email"
+)
+
+var _ email.Vault = (*Vault)(nil)
+
+// Vault provides an in-memory implementation of the email.Vault port.
+type Vault struct {
+	mu      sync.RWMutex
+	secrets map[string]string
+}
+
+// NewVault builds a new in-memory vault instance.
+func NewVault() *Vault {
+	return &Vault{secrets: make(map[string]string)}
+}
+
+// StoreSecret records the secret under the supplied key, replacing any previous value.
+func (v *Vault) StoreSecret(ctx context.Context, key, secret string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	v.mu.Lock()
+	v.secrets[key] = secret
+	v.mu.Unlock()
+	return nil
+}
+
+// Secret returns the secret stored under key or email.ErrSecretNotFound.
+func (v *Vault) Secret(ctx context.Context, key string) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+
+	secret, ok := v.secrets[key]
+	if !ok {
+		return "", email.ErrSecretNotFound
+	}
+	return secret, nil
+}
+
+// DeleteSecret removes the secret stored under key if present.
+func (v *Vault) DeleteSecret(ctx context.Context, key string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	v.mu.Lock()
+	delete(v.secrets, key)
+	v.mu.Unlock()
+	return nil
+}
@@ -0,0 +1,45 @@
+package smtp
+
+import (
+	"errors"
+	"fmt"
+	netsmtp "net/smtp"
+	"strings"
+)
+
+// loginAuth implements the non-standard but widely deployed AUTH LOGIN mechanism.
+type loginAuth struct {
+	username string
+	password string
+	host     string
+}
+
+func (a loginAuth) Start(server *netsmtp.ServerInfo) (string, []byte, error) {
+	// Mirror net/smtp.PlainAuth: never expose credentials over an unencrypted link to a remote host.
+	if !server.TLS && !isLocalhost(server.Name) {
+		return "", nil, errors.New("unencrypted connection")
+	}
+	if server.Name != a.host {
+		return "", nil, errors.New("wrong host name")
+	}
+	return "LOGIN", nil, nil
+}
+
+func (a loginAuth) Next(fromServer []byte, more bool) ([]byte, error) {
+	if !more {
+		return nil, nil
+	}
+	prompt := strings.ToLower(strings.TrimSpace(string(fromServer)))
+	switch {
+	case strings.HasPrefix(prompt, "username"):
+		return []byte(a.username), nil
+	case strings.HasPrefix(prompt, "password"):
+		return []byte(a.password), nil
+	default:
+		return nil, fmt.Errorf("unexpected server challenge %q", fromServer)
+	}
+}
+
+func isLocalhost(name string) bool {
+	return name == "localhost" || name == "127.0.0.1" || name == "::1"
+}
@@ -0,0 +1,163 @@
+package smtp
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"time"
+
+	"github.com/example/iboz/internal/email"
+)
+
+// buildMessage renders msg as an RFC 5322 message with CRLF line endings.
+func buildMessage(msg email.OutgoingMessage) ([]byte, error) {
+	if msg.TextBody == "" && msg.HTMLBody == "" {
+		return nil, errors.New("smtp: message body is required")
+	}
+
+	var buf bytes.Buffer
+	date := msg.Date
+	if date.IsZero() {
+		date = time.Now().UTC()
+	}
+
+	from, err := addressHeader("From", []string{msg.From})
+	if err != nil {
+		return nil, err
+	}
+	to, err := addressHeader("To", msg.To)
+	if err != nil {
+		return nil, err
+	}
+	cc, err := addressHeader("Cc", msg.Cc)
+	if err != nil {
+		return nil, err
+	}
+	for key, value := range map[string]string{
+		"Message-ID":  msg.MessageID,
+		"In-Reply-To": msg.InReplyTo,
+		"References":  strings.Join(msg.References, " "),
+	} {
+		if strings.ContainsAny(value, "\r\n") {
+			return nil, fmt.Errorf("smtp: %s header contains a line break", key)
+		}
+	}
+
+	writeHeader(&buf, "From", from)
+	writeHeader(&buf, "To", to)
+	if cc != "" {
+		writeHeader(&buf, "Cc", cc)
+	}
+	writeHeader(&buf, "Subject", mime.QEncoding.Encode("utf-8", msg.Subject))
+	writeHeader(&buf, "Date", date.Format(time.RFC1123Z))
+	if msg.MessageID != "" {
+		writeHeader(&buf, "Message-ID", msg.MessageID)
+	}
+	if msg.InReplyTo != "" {
+		writeHeader(&buf, "In-Reply-To", msg.InReplyTo)
+	}
+	if len(msg.References) > 0 {
+		writeHeader(&buf, "References", strings.Join(msg.References, " "))
+	}
+	writeHeader(&buf, "MIME-Version", "1.0")
+
+	switch {
+	case msg.TextBody != "" && msg.HTMLBody != "":
+		mw := multipart.NewWriter(&buf)
+		writeHeader(&buf, "Content-Type", fmt.Sprintf("multipart/alternative; boundary=%q", mw.Boundary()))
+		buf.WriteString("\r\n")
+		if err := writePart(mw, "text/plain", msg.TextBody); err != nil {
+			return nil, err
+		}
+		if err := writePart(mw, "text/html", msg.HTMLBody); err != nil {
+			return nil, err
+		}
+		if err := mw.Close(); err != nil {
+			return nil, err
+		}
+	case msg.HTMLBody != "":
+		if err := writeSinglePart(&buf, "text/html", msg.HTMLBody); err != nil {
+			return nil, err
+		}
+	default:
+		if err := writeSinglePart(&buf, "text/plain", msg.TextBody); err != nil {
+			return nil, err
+		}
+	}
+
+	return buf.Bytes(), nil
+}
+
+// addressHeader formats values as an address list, re-encoding display names
+// with RFC 2047 so that names taken from synced mail cannot inject headers.
+func addressHeader(key string, values []string) (string, error) {
+	formatted := make([]string, 0, len(values))
+	for _, value := range values {
+		if strings.ContainsAny(value, "\r\n") {
+			return "", fmt.Errorf("smtp: %s address contains a line break", key)
+		}
+		addr, err := mail.ParseAddress(value)
+		if err != nil {
+			return "", fmt.Errorf("smtp: invalid %s address %q: %w", key, value, err)
+		}
+		formatted = append(formatted, addr.String())
+	}
+	return strings.Join(formatted, ", "), nil
+}
+
+func writeHeader(buf *bytes.Buffer, key, value string) {
+	buf.WriteString(key)
+	buf.WriteString(": ")
+	buf.WriteString(value)
+	buf.WriteString("\r\n")
+}
+
+func writeSinglePart(buf *bytes.Buffer, contentType, body string) error {
+	writeHeader(buf, "Content-Type", contentType+"; charset=utf-8")
+	writeHeader(buf, "Content-Transfer-Encoding", "quoted-printable")
+	buf.WriteString("\r\n")
+	return writeQuotedPrintable(buf, body)
+}
+
+func writePart(mw *multipart.Writer, contentType, body string) error {
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Type", contentType+"; charset=utf-8")
+	header.Set("Content-Transfer-Encoding", "quoted-printable")
+	part, err := mw.CreatePart(header)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	if err := writeQuotedPrintable(&buf, body); err != nil {
+		return err
+	}
+	_, err = part.Write(buf.Bytes())
+	return err
+}
+
+func writeQuotedPrintable(buf *bytes.Buffer, body string) error {
+	qp := quotedprintable.NewWriter(buf)
+	normalized := strings.ReplaceAll(strings.ReplaceAll(body, "\r\n", "\n"), "\n", "\r\n")
+	if _, err := qp.Write([]byte(normalized)); err != nil {
+		return err
+	}
+	if err := qp.Close(); err != nil {
+		return err
+	}
+	buf.WriteString("\r\n")
+	return nil
+}
+
+func envelopeAddress(value string) (string, error) {
+	addr, err := mail.ParseAddress(value)
+	if err != nil {
+		return "", err
+	}
+	return addr.Address, nil
+}
@@ -0,0 +1,257 @@
+package smtp
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	netsmtp "net/smtp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/example/iboz/internal/email"
+)
+
+// Security selects how the connection to the relay is protected.
+type Security string
+
+const (
+	// SecurityNone sends over a plaintext connection.
+	SecurityNone Security = "none"
+	// SecurityStartTLS upgrades a plaintext connection with STARTTLS.
+	SecurityStartTLS Security = "starttls"
+	// SecurityTLS dials the relay with implicit TLS (SMTPS).
+	SecurityTLS Security = "tls"
+)
+
+// Valid reports whether s is a supported security mode. The empty mode selects
+// STARTTLS.
+func (s Security) Valid() bool {
+	switch s {
+	case "", SecurityNone, SecurityStartTLS, SecurityTLS:
+		return true
+	}
+	return false
+}
+
+// Supported AUTH mechanisms.
+const (
+	AuthPlain = "PLAIN"
+	AuthLogin = "LOGIN"
+)
+
+const defaultTimeout = 30 * time.Second
+
+// VaultKey returns the vault key holding the relay password of username. It
+// is separate from the mailbox credentials stored on authentication, as the
+// relay account need not be the authenticated mailbox.
+func VaultKey(username string) string {
+	return "smtp:" + username
+}
+
+var _ email.MailSender = (*Sender)(nil)
+
+// Config describes how to reach and authenticate against an SMTP relay.
+type Config struct {
+	Host      string
+	Port      int
+	Security  Security
+	Username  string
+	Mechanism string
+	LocalName string
+	TLSConfig *tls.Config
+	Timeout   time.Duration
+}
+
+// Sender delivers messages through an SMTP relay using credentials from the vault.
+type Sender struct {
+	cfg   Config
+	vault email.Vault
+}
+
+// NewSender constructs a Sender. The vault is only consulted, under VaultKey,
+// when a username is configured.
+// An unknown Security value is rejected rather than falling back to plaintext.
+func NewSender(cfg Config, vault email.Vault) *Sender {
+	if strings.TrimSpace(cfg.Host) == "" {
+		panic("smtp: host is required")
+	}
+	if cfg.Username != "" && vault == nil {
+		panic("smtp: vault dependency is required when authenticating")
+	}
+	if !cfg.Security.Valid() {
+		panic(fmt.Sprintf("smtp: unsupported security %q", cfg.Security))
+	}
+	if cfg.Security == "" {
+		cfg.Security = SecurityStartTLS
+	}
+	if cfg.Port == 0 {
+		cfg.Port = defaultPort(cfg.Security)
+	}
+	if cfg.Timeout == 0 {
+		cfg.Timeout = defaultTimeout
+	}
+	return &Sender{cfg: cfg, vault: vault}
+}
+
+// Send implements the email.MailSender interface.
+func (s *Sender) Send(ctx context.Context, msg email.OutgoingMessage) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	recipients := msg.Recipients()
+	if len(recipients) == 0 {
+		return errors.New("smtp: at least one recipient is required")
+	}
+	from, err := envelopeAddress(msg.From)
+	if err != nil {
+		return fmt.Errorf("smtp: invalid sender: %w", err)
+	}
+
+	data, err := buildMessage(msg)
+	if err != nil {
+		return err
+	}
+
+	auth, err := s.auth(ctx)
+	if err != nil {
+		return err
+	}
+
+	conn, err := s.dial(ctx)
+	if err != nil {
+		return err
+	}
+
+	deadline := time.Now().Add(s.cfg.Timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	if err := conn.SetDeadline(deadline); err != nil {
+		conn.Close()
+		return err
+	}
+
+	client, err := netsmtp.NewClient(conn, s.cfg.Host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("smtp: handshake: %w", err)
+	}
+	defer client.Close()
+
+	if s.cfg.LocalName != "" {
+		if err := client.Hello(s.cfg.LocalName); err != nil {
+			return fmt.Errorf("smtp: hello: %w", err)
+		}
+	}
+
+	if s.cfg.Security == SecurityStartTLS {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			return errors.New("smtp: server does not support STARTTLS")
+		}
+		if err := client.StartTLS(s.tlsConfig()); err != nil {
+			return fmt.Errorf("smtp: starttls: %w", err)
+		}
+	}
+
+	if auth != nil {
+		if ok, _ := client.Extension("AUTH"); !ok {
+			return errors.New("smtp: server does not support AUTH")
+		}
+		if err := client.Auth(auth); err != nil {
+			return fmt.Errorf("smtp: auth: %w", err)
+		}
+	}
+
+	if err := client.Mail(from); err != nil {
+		return fmt.Errorf("smtp: mail from: %w", err)
+	}
+	for _, rcpt := range recipients {
+		addr, err := envelopeAddress(rcpt)
+		if err != nil {
+			return fmt.Errorf("smtp: invalid recipient %q: %w", rcpt, err)
+		}
+		if err := client.Rcpt(addr); err != nil {
+			return fmt.Errorf("smtp: rcpt to %s: %w", addr, err)
+		}
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("smtp: data: %w", err)
+	}
+	if _, err := w.Write(data); err != nil {
+		w.Close()
+		return fmt.Errorf("smtp: write body: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("smtp: finish data: %w", err)
+	}
+
+	return client.Quit()
+}
+
+func (s *Sender) dial(ctx context.Context) (net.Conn, error) {
+	addr := net.JoinHostPort(s.cfg.Host, strconv.Itoa(s.cfg.Port))
+	dialer := &net.Dialer{Timeout: s.cfg.Timeout}
+
+	if s.cfg.Security == SecurityTLS {
+		tlsDialer := &tls.Dialer{NetDialer: dialer, Config: s.tlsConfig()}
+		conn, err := tlsDialer.DialContext(ctx, "tcp", addr)
+		if err != nil {
+			return nil, fmt.Errorf("smtp: dial %s: %w", addr, err)
+		}
+		return conn, nil
+	}
+
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("smtp: dial %s: %w", addr, err)
+	}
+	return conn, nil
+}
+
+func (s *Sender) tlsConfig() *tls.Config {
+	if s.cfg.TLSConfig != nil {
+		cfg := s.cfg.TLSConfig.Clone()
+		if cfg.ServerName == "" {
+			cfg.ServerName = s.cfg.Host
+		}
+		return cfg
+	}
+	return &tls.Config{ServerName: s.cfg.Host, MinVersion: tls.VersionTLS12}
+}
+
+func (s *Sender) auth(ctx context.Context) (netsmtp.Auth, error) {
+	if s.cfg.Username == "" {
+		return nil, nil
+	}
+
+	secret, err := s.vault.Secret(ctx, VaultKey(s.cfg.Username))
+	if err != nil {
+		return nil, fmt.Errorf("smtp: load credentials: %w", err)
+	}
+
+	switch strings.ToUpper(s.cfg.Mechanism) {
+	case "", AuthPlain:
+		return netsmtp.PlainAuth("", s.cfg.Username, secret, s.cfg.Host), nil
+	case AuthLogin:
+		return loginAuth{username: s.cfg.Username, password: secret, host: s.cfg.Host}, nil
+	default:
+		return nil, fmt.Errorf("smtp: unsupported auth mechanism %q", s.cfg.Mechanism)
+	}
+}
+
+func defaultPort(security Security) int {
+	switch security {
+	case SecurityTLS:
+		return 465
+	case SecurityNone:
+		return 25
+	default:
+		return 587
+	}
+}
@@ -0,0 +1,222 @@
+package smtp_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/example/iboz/internal/email"
+	"github.com/example/iboz/internal/email/adapter/memory"
+	"github.com/example/iboz/internal/email/adapter/smtp"
+	"github.com/example/iboz/internal/email/adapter/smtp/smtptest"
+)
+
+func newVault(t *testing.T, username, secret string) email.Vault {
+	t.Helper()
+	vault := memory.NewVault()
+	if err := vault.StoreSecret(context.Background(), smtp.VaultKey(username), secret); err != nil {
+		t.Fatalf("store secret: %v", err)
+	}
+	return vault
+}
+
+func testMessage() email.OutgoingMessage {
+	return email.OutgoingMessage{
+		From:       "Ops Desk <ops@example.com>",
+		To:         []string{"legal-ops@example.com"},
+		Cc:         []string{"audit@example.com"},
+		Subject:    "Re: Contract signature pending",
+		TextBody:   "Countersigned.\nThanks!",
+		HTMLBody:   "<p>Countersigned.</p>",
+		Date:       time.Date(2025, time.March, 18, 12, 0, 0, 0, time.UTC),
+		MessageID:  "<reply-1@example.com>",
+		InReplyTo:  "<b@example.com>",
+		References: []string{"<a@example.com>", "<b@example.com>"},
+	}
+}
+
+func TestSenderDeliversThreadedMultipartMessage(t *testing.T) {
+	srv := smtptest.NewServer(smtptest.Options{Mode: smtptest.ModePlain})
+	defer srv.Close()
+
+	sender := smtp.NewSender(smtp.Config{Host: srv.Host, Port: srv.Port, Security: smtp.SecurityNone}, nil)
+	if err := sender.Send(context.Background(), testMessage()); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+
+	delivered := srv.Messages()
+	if len(delivered) != 1 {
+		t.Fatalf("expected one message, got %d", len(delivered))
+	}
+	got := delivered[0]
+	if got.From != "ops@example.com" {
+		t.Fatalf("unexpected envelope sender: %q", got.From)
+	}
+	if strings.Join(got.To, ",") != "legal-ops@example.com,audit@example.com" {
+		t.Fatalf("unexpected envelope recipients: %v", got.To)
+	}
+
+	parsed, err := mail.ReadMessage(bytes.NewReader(got.Data))
+	if err != nil {
+		t.Fatalf("parse message: %v", err)
+	}
+	if parsed.Header.Get("In-Reply-To") != "<b@example.com>" {
+		t.Fatalf("unexpected In-Reply-To: %q", parsed.Header.Get("In-Reply-To"))
+	}
+	if parsed.Header.Get("References") != "<a@example.com> <b@example.com>" {
+		t.Fatalf("unexpected References: %q", parsed.Header.Get("References"))
+	}
+	if parsed.Header.Get("Message-Id") != "<reply-1@example.com>" {
+		t.Fatalf("unexpected Message-ID: %q", parsed.Header.Get("Message-Id"))
+	}
+
+	mediaType, params, err := mime.ParseMediaType(parsed.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/alternative" {
+		t.Fatalf("unexpected content type %q (%v)", mediaType, err)
+	}
+	reader := multipart.NewReader(parsed.Body, params["boundary"])
+	var types []string
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("read part: %v", err)
+		}
+		body, _ := io.ReadAll(part)
+		types = append(types, part.Header.Get("Content-Type"))
+		if strings.HasPrefix(part.Header.Get("Content-Type"), "text/plain") && !strings.Contains(string(body), "Countersigned.\nThanks!") {
+			t.Fatalf("unexpected text body: %q", body)
+		}
+	}
+	if len(types) != 2 {
+		t.Fatalf("expected text and html parts, got %v", types)
+	}
+}
+
+func TestSenderStartTLSWithPlainAuth(t *testing.T) {
+	srv := smtptest.NewServer(smtptest.Options{Mode: smtptest.ModeStartTLS, Username: "ops@example.com", Password: "app-password"})
+	defer srv.Close()
+
+	sender := smtp.NewSender(smtp.Config{
+		Host:      srv.Host,
+		Port:      srv.Port,
+		Security:  smtp.SecurityStartTLS,
+		Username:  "ops@example.com",
+		Mechanism: smtp.AuthPlain,
+		TLSConfig: srv.ClientTLSConfig(),
+	}, newVault(t, "ops@example.com", "app-password"))
+
+	if err := sender.Send(context.Background(), testMessage()); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+	delivered := srv.Messages()
+	if len(delivered) != 1 || !delivered[0].TLS || delivered[0].AuthUser != "ops@example.com" {
+		t.Fatalf("expected authenticated TLS delivery, got %+v", delivered)
+	}
+}
+
+func TestSenderImplicitTLSWithLoginAuth(t *testing.T) {
+	srv := smtptest.NewServer(smtptest.Options{Mode: smtptest.ModeTLS, Username: "ops@example.com", Password: "app-password"})
+	defer srv.Close()
+
+	sender := smtp.NewSender(smtp.Config{
+		Host:      srv.Host,
+		Port:      srv.Port,
+		Security:  smtp.SecurityTLS,
+		Username:  "ops@example.com",
+		Mechanism: smtp.AuthLogin,
+		TLSConfig: srv.ClientTLSConfig(),
+	}, newVault(t, "ops@example.com", "app-password"))
+
+	if err := sender.Send(context.Background(), testMessage()); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+	delivered := srv.Messages()
+	if len(delivered) != 1 || !delivered[0].TLS || delivered[0].AuthUser != "ops@example.com" {
+		t.Fatalf("expected authenticated TLS delivery, got %+v", delivered)
+	}
+}
+
+func TestSenderRejectsBadCredentials(t *testing.T) {
+	srv := smtptest.NewServer(smtptest.Options{Mode: smtptest.ModeStartTLS, Username: "ops@example.com", Password: "app-password"})
+	defer srv.Close()
+
+	sender := smtp.NewSender(smtp.Config{
+		Host:      srv.Host,
+		Port:      srv.Port,
+		Username:  "ops@example.com",
+		TLSConfig: srv.ClientTLSConfig(),
+	}, newVault(t, "ops@example.com", "wrong-password"))
+
+	if err := sender.Send(context.Background(), testMessage()); err == nil {
+		t.Fatalf("expected authentication failure")
+	}
+	if len(srv.Messages()) != 0 {
+		t.Fatalf("no message should be delivered")
+	}
+}
+
+func TestSenderRequiresStartTLSSupport(t *testing.T) {
+	srv := smtptest.NewServer(smtptest.Options{Mode: smtptest.ModePlain})
+	defer srv.Close()
+
+	sender := smtp.NewSender(smtp.Config{Host: srv.Host, Port: srv.Port, Security: smtp.SecurityStartTLS}, nil)
+	if err := sender.Send(context.Background(), testMessage()); err == nil || !strings.Contains(err.Error(), "STARTTLS") {
+		t.Fatalf("expected STARTTLS error, got %v", err)
+	}
+}
+
+func TestNewSenderRejectsUnknownSecurity(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected an unknown security mode to be rejected")
+		}
+	}()
+	smtp.NewSender(smtp.Config{Host: "smtp.example.com", Security: "ssl"}, nil)
+}
+
+func TestSenderEncodesAddressHeaders(t *testing.T) {
+	srv := smtptest.NewServer(smtptest.Options{Mode: smtptest.ModePlain})
+	defer srv.Close()
+	sender := smtp.NewSender(smtp.Config{Host: srv.Host, Port: srv.Port, Security: smtp.SecurityNone}, nil)
+
+	injected := testMessage()
+	injected.To = []string{"Legal\r\nBcc: leak@evil.example <legal-ops@example.com>"}
+	if err := sender.Send(context.Background(), injected); err == nil {
+		t.Fatal("expected a line break in an address to be rejected")
+	}
+	injected = testMessage()
+	injected.InReplyTo = "<b@example.com>\r\nBcc: leak@evil.example"
+	if err := sender.Send(context.Background(), injected); err == nil {
+		t.Fatal("expected a line break in In-Reply-To to be rejected")
+	}
+
+	msg := testMessage()
+	msg.To = []string{"Zoë Martín <legal-ops@example.com>"}
+	if err := sender.Send(context.Background(), msg); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+	delivered := srv.Messages()
+	if len(delivered) != 1 {
+		t.Fatalf("expected only the valid message to be delivered, got %d", len(delivered))
+	}
+	parsed, err := mail.ReadMessage(bytes.NewReader(delivered[0].Data))
+	if err != nil {
+		t.Fatalf("parse message: %v", err)
+	}
+	if raw := parsed.Header.Get("To"); !strings.HasPrefix(raw, "=?utf-8?q?") {
+		t.Fatalf("expected an encoded display name, got %q", raw)
+	}
+	to, err := parsed.Header.AddressList("To")
+	if err != nil || len(to) != 1 || to[0].Name != "Zoë Martín" {
+		t.Fatalf("unexpected To: %v (%v)", to, err)
+	}
+}
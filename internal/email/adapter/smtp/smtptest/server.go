@@ -0,0 +1,410 @@
+// Package smtptest provides a local SMTP server for exercising outbound mail offline.
+package smtptest
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+	"net"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Mode selects the transport security offered by the server.
+type Mode int
+
+const (
+	// ModePlain accepts plaintext sessions without STARTTLS.
+	ModePlain Mode = iota
+	// ModeStartTLS advertises STARTTLS on a plaintext listener.
+	ModeStartTLS
+	// ModeTLS wraps the listener in implicit TLS.
+	ModeTLS
+)
+
+// Options configures a Server.
+type Options struct {
+	Mode Mode
+	// Username and Password enable AUTH PLAIN/LOGIN and require it before MAIL FROM.
+	Username string
+	Password string
+}
+
+// Message is a delivered envelope captured by the server. Data is dot-decoded
+// with CRLF line endings normalised to LF.
+type Message struct {
+	From     string
+	To       []string
+	Data     []byte
+	AuthUser string
+	TLS      bool
+}
+
+// Server is a minimal ESMTP server listening on the loopback interface.
+type Server struct {
+	Host string
+	Port int
+
+	opts      Options
+	listener  net.Listener
+	tlsConfig *tls.Config
+	cert      *x509.Certificate
+
+	mu       sync.Mutex
+	conns    map[net.Conn]struct{}
+	messages []Message
+	wg       sync.WaitGroup
+}
+
+// NewServer starts a server on a random loopback port. It panics on failure, like httptest.NewServer.
+func NewServer(opts Options) *Server {
+	cert, leaf, err := selfSignedCertificate()
+	if err != nil {
+		panic(fmt.Sprintf("smtptest: generate certificate: %v", err))
+	}
+	tlsConfig := &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12}
+
+	var listener net.Listener
+	if opts.Mode == ModeTLS {
+		listener, err = tls.Listen("tcp", "127.0.0.1:0", tlsConfig)
+	} else {
+		listener, err = net.Listen("tcp", "127.0.0.1:0")
+	}
+	if err != nil {
+		panic(fmt.Sprintf("smtptest: listen: %v", err))
+	}
+
+	host, port, _ := net.SplitHostPort(listener.Addr().String())
+	portNum, _ := strconv.Atoi(port)
+
+	s := &Server{
+		Host:      host,
+		Port:      portNum,
+		opts:      opts,
+		listener:  listener,
+		tlsConfig: tlsConfig,
+		cert:      leaf,
+		conns:     make(map[net.Conn]struct{}),
+	}
+
+	s.wg.Add(1)
+	go s.acceptLoop()
+	return s
+}
+
+// ClientTLSConfig returns a client configuration trusting the server certificate.
+func (s *Server) ClientTLSConfig() *tls.Config {
+	pool := x509.NewCertPool()
+	pool.AddCert(s.cert)
+	return &tls.Config{RootCAs: pool, ServerName: s.Host, MinVersion: tls.VersionTLS12}
+}
+
+// Messages returns a copy of every message delivered so far.
+func (s *Server) Messages() []Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Message(nil), s.messages...)
+}
+
+// Close stops the listener and terminates open sessions.
+func (s *Server) Close() {
+	s.listener.Close()
+	s.mu.Lock()
+	for conn := range s.conns {
+		conn.Close()
+	}
+	s.mu.Unlock()
+	s.wg.Wait()
+}
+
+func (s *Server) acceptLoop() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		s.conns[conn] = struct{}{}
+		s.mu.Unlock()
+
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			sess := &session{srv: s, conn: conn, tls: s.opts.Mode == ModeTLS}
+			sess.run()
+			s.mu.Lock()
+			delete(s.conns, sess.conn)
+			delete(s.conns, conn)
+			s.mu.Unlock()
+			sess.conn.Close()
+		}()
+	}
+}
+
+func (s *Server) deliver(msg Message) {
+	s.mu.Lock()
+	s.messages = append(s.messages, msg)
+	s.mu.Unlock()
+}
+
+type session struct {
+	srv      *Server
+	conn     net.Conn
+	text     *textproto.Conn
+	tls      bool
+	authUser string
+	from     string
+	to       []string
+}
+
+func (s *session) run() {
+	s.text = textproto.NewConn(s.conn)
+	s.reply(220, "%s ESMTP smtptest", s.srv.Host)
+
+	for {
+		line, err := s.text.ReadLine()
+		if err != nil {
+			return
+		}
+		verb, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(verb) {
+		case "EHLO", "HELO":
+			s.hello()
+		case "STARTTLS":
+			if !s.startTLS() {
+				return
+			}
+		case "AUTH":
+			s.auth(arg)
+		case "MAIL":
+			s.mail(arg)
+		case "RCPT":
+			s.rcpt(arg)
+		case "DATA":
+			if !s.data() {
+				return
+			}
+		case "RSET":
+			s.from, s.to = "", nil
+			s.reply(250, "2.0.0 OK")
+		case "NOOP":
+			s.reply(250, "2.0.0 OK")
+		case "QUIT":
+			s.reply(221, "2.0.0 Bye")
+			return
+		default:
+			s.reply(500, "5.5.2 unrecognized command")
+		}
+	}
+}
+
+func (s *session) hello() {
+	lines := []string{"smtptest"}
+	if s.srv.opts.Mode == ModeStartTLS && !s.tls {
+		lines = append(lines, "STARTTLS")
+	}
+	if s.srv.opts.Username != "" {
+		lines = append(lines, "AUTH PLAIN LOGIN")
+	}
+	lines = append(lines, "8BITMIME")
+	for i, line := range lines {
+		sep := "-"
+		if i == len(lines)-1 {
+			sep = " "
+		}
+		s.text.PrintfLine("250%s%s", sep, line)
+	}
+}
+
+func (s *session) startTLS() bool {
+	if s.srv.opts.Mode != ModeStartTLS || s.tls {
+		s.reply(502, "5.5.1 STARTTLS not available")
+		return true
+	}
+	s.reply(220, "2.0.0 Ready to start TLS")
+
+	tlsConn := tls.Server(s.conn, s.srv.tlsConfig)
+	if err := tlsConn.Handshake(); err != nil {
+		return false
+	}
+	s.srv.mu.Lock()
+	s.srv.conns[tlsConn] = struct{}{}
+	s.srv.mu.Unlock()
+
+	s.conn = tlsConn
+	s.text = textproto.NewConn(tlsConn)
+	s.tls = true
+	s.authUser, s.from, s.to = "", "", nil
+	return true
+}
+
+func (s *session) auth(arg string) {
+	if s.srv.opts.Username == "" {
+		s.reply(502, "5.5.1 AUTH not available")
+		return
+	}
+	mechanism, initial, _ := strings.Cut(arg, " ")
+
+	var username, password string
+	switch strings.ToUpper(mechanism) {
+	case "PLAIN":
+		if initial == "" {
+			s.reply(334, "")
+			line, err := s.text.ReadLine()
+			if err != nil {
+				return
+			}
+			initial = line
+		}
+		decoded, err := base64.StdEncoding.DecodeString(initial)
+		if err != nil {
+			s.reply(501, "5.5.2 invalid encoding")
+			return
+		}
+		parts := strings.Split(string(decoded), "\x00")
+		if len(parts) != 3 {
+			s.reply(501, "5.5.2 invalid PLAIN response")
+			return
+		}
+		username, password = parts[1], parts[2]
+	case "LOGIN":
+		var ok bool
+		if username, ok = s.challenge("Username:"); !ok {
+			return
+		}
+		if password, ok = s.challenge("Password:"); !ok {
+			return
+		}
+	default:
+		s.reply(504, "5.5.4 unrecognized authentication type")
+		return
+	}
+
+	if username != s.srv.opts.Username || password != s.srv.opts.Password {
+		s.reply(535, "5.7.8 Authentication credentials invalid")
+		return
+	}
+	s.authUser = username
+	s.reply(235, "2.7.0 Authentication successful")
+}
+
+func (s *session) challenge(prompt string) (string, bool) {
+	s.reply(334, "%s", base64.StdEncoding.EncodeToString([]byte(prompt)))
+	line, err := s.text.ReadLine()
+	if err != nil {
+		return "", false
+	}
+	decoded, err := base64.StdEncoding.DecodeString(line)
+	if err != nil {
+		s.reply(501, "5.5.2 invalid encoding")
+		return "", false
+	}
+	return string(decoded), true
+}
+
+func (s *session) mail(arg string) {
+	if s.srv.opts.Username != "" && s.authUser == "" {
+		s.reply(530, "5.7.0 Authentication required")
+		return
+	}
+	addr, ok := pathArgument(arg, "FROM:")
+	if !ok {
+		s.reply(501, "5.5.4 syntax: MAIL FROM:<address>")
+		return
+	}
+	s.from, s.to = addr, nil
+	s.reply(250, "2.1.0 OK")
+}
+
+func (s *session) rcpt(arg string) {
+	if s.from == "" {
+		s.reply(503, "5.5.1 MAIL first")
+		return
+	}
+	addr, ok := pathArgument(arg, "TO:")
+	if !ok {
+		s.reply(501, "5.5.4 syntax: RCPT TO:<address>")
+		return
+	}
+	s.to = append(s.to, addr)
+	s.reply(250, "2.1.5 OK")
+}
+
+func (s *session) data() bool {
+	if len(s.to) == 0 {
+		s.reply(503, "5.5.1 RCPT first")
+		return true
+	}
+	s.reply(354, "End data with <CR><LF>.<CR><LF>")
+	body, err := s.text.ReadDotBytes()
+	if err != nil {
+		return false
+	}
+	s.srv.deliver(Message{
+		From:     s.from,
+		To:       append([]string(nil), s.to...),
+		Data:     body,
+		AuthUser: s.authUser,
+		TLS:      s.tls,
+	})
+	s.from, s.to = "", nil
+	s.reply(250, "2.0.0 OK queued")
+	return true
+}
+
+func (s *session) reply(code int, format string, args ...any) {
+	s.text.PrintfLine("%d %s", code, fmt.Sprintf(format, args...))
+}
+
+func pathArgument(arg, prefix string) (string, bool) {
+	if len(arg) < len(prefix) || !strings.EqualFold(arg[:len(prefix)], prefix) {
+		return "", false
+	}
+	rest := strings.TrimSpace(arg[len(prefix):])
+	if !strings.HasPrefix(rest, "<") {
+		return "", false
+	}
+	end := strings.Index(rest, ">")
+	if end < 0 {
+		return "", false
+	}
+	return rest[1:end], true
+}
+
+func selfSignedCertificate() (tls.Certificate, *x509.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, nil, err
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "smtptest"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback},
+		DNSNames:              []string{"localhost"},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return tls.Certificate{}, nil, err
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		return tls.Certificate{}, nil, err
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}, leaf, nil
+}
@@ -35,6 +35,8 @@ func (g *Generator) Generate(ctx context.Context, cfg email.ProviderConfig, auth
 		Snippet:    fmt.Sprintf("Automation insights for %s. 3 urgent items need review.", cfg.DisplayName),
 		Labels:     append([]string(nil), baseLabels...),
 		Importance: "high",
+		Category:   "updates",
+		MessageID:  fmt.Sprintf("<msg-schedule@%s.iboz.local>", cfg.Provider),
 	}
 
 	escalated := email.EmailMessage{
@@ -45,6 +47,37 @@ func (g *Generator) Generate(ctx context.Context, cfg email.ProviderConfig, auth
 		Snippet:    fmt.Sprintf("Hi %s, procurement is awaiting countersignature from vendor.", auth.Username),
 		Labels:     append([]string{"Escalations"}, cfg.LabelFilters...),
 		Importance: "high",
+		Category:   "action",
+		MessageID:  "<msg-escalation@example.com>",
+		References: []string{"<contract-thread@example.com>"},
+		ThreadID:   "thread-contract",
+		Recipients: []string{auth.Username},
+	}
+
+	contractSent := email.EmailMessage{
+		ID:         "msg-contract-sent",
+		Subject:    "Contract signature pending",
+		Sender:     auth.Username,
+		ReceivedAt: now.Add(-3 * time.Hour),
+		Snippet:    "Could you confirm when the vendor countersignature is expected?",
+		Labels:     []string{email.LabelSent},
+		Importance: "normal",
+		MessageID:  "<contract-thread@example.com>",
+		ThreadID:   "thread-contract",
+		Recipients: []string{"legal-ops@example.com"},
+	}
+
+	quoteSent := email.EmailMessage{
+		ID:         "msg-quote-sent",
+		Subject:    "Quote for Q2 renewal",
+		Sender:     auth.Username,
+		ReceivedAt: now.Add(-96 * time.Hour),
+		Snippet:    "Following up on the renewal quote we discussed last week.",
+		Labels:     []string{email.LabelSent},
+		Importance: "normal",
+		MessageID:  "<quote-renewal@example.com>",
+		ThreadID:   "thread-quote",
+		Recipients: []string{"sales@vendor.example"},
 	}
 
 	digest := email.EmailMessage{
@@ -55,7 +88,16 @@ func (g *Generator) Generate(ctx context.Context, cfg email.ProviderConfig, auth
 		Snippet:    fmt.Sprintf("%d workflows executed, 12 emails triaged automatically.", 4+cfg.SyncWindowHours/24),
 		Labels:     append([]string{"Automation"}, cfg.LabelFilters...),
 		Importance: "normal",
+		Category:   "newsletter",
+		MessageID:  "<msg-digest@example.com>",
+		Headers: email.Header{
+			"Authentication-Results": {"mx.example.com; spf=pass smtp.mailfrom=example.com; dkim=pass header.d=example.com; dmarc=pass header.from=example.com"},
+			"List-Id":                {"Automation digest <digest.automation.example.com>"},
+			"List-Unsubscribe":       {"<https://automation.example.com/unsubscribe/digest>, <mailto:unsubscribe@automation.example.com?subject=unsubscribe>"},
+			"List-Unsubscribe-Post":  {"List-Unsubscribe=One-Click"},
+			"Precedence":             {"bulk"},
+		},
 	}
 
-	return []email.EmailMessage{summary, escalated, digest}, nil
+	return []email.EmailMessage{summary, escalated, digest, contractSent, quoteSent}, nil
 }
@@ -0,0 +1,206 @@
+package email
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/mail"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrSenderNotConfigured is returned when no outbound transport is available.
+	ErrSenderNotConfigured = errors.New("mail sender not configured")
+	// ErrMessageNotFound is returned when a cached message cannot be located.
+	ErrMessageNotFound = errors.New("email message not found")
+	// ErrEmptyReply is returned when a reply carries neither a text nor an HTML body.
+	ErrEmptyReply = errors.New("reply body is required")
+)
+
+// OutgoingMessage describes a message submitted through a MailSender.
+type OutgoingMessage struct {
+	From       string    `json:"from"`
+	To         []string  `json:"to"`
+	Cc         []string  `json:"cc,omitempty"`
+	Subject    string    `json:"subject"`
+	TextBody   string    `json:"textBody,omitempty"`
+	HTMLBody   string    `json:"htmlBody,omitempty"`
+	Date       time.Time `json:"date"`
+	MessageID  string    `json:"messageId"`
+	InReplyTo  string    `json:"inReplyTo,omitempty"`
+	References []string  `json:"references,omitempty"`
+}
+
+// Recipients returns every envelope recipient of the message.
+func (m OutgoingMessage) Recipients() []string {
+	recipients := make([]string, 0, len(m.To)+len(m.Cc))
+	recipients = append(recipients, m.To...)
+	recipients = append(recipients, m.Cc...)
+	return recipients
+}
+
+// MailSender delivers outgoing messages through an upstream transport.
+type MailSender interface {
+	Send(ctx context.Context, msg OutgoingMessage) error
+}
+
+// ReplyRequest carries the body variants of a reply. An empty Subject keeps "Re: <original>".
+type ReplyRequest struct {
+	Subject  string
+	TextBody string
+	HTMLBody string
+}
+
+// ReplyService sends replies to cached provider messages.
+type ReplyService interface {
+	Reply(ctx context.Context, messageID string, req ReplyRequest) (OutgoingMessage, error)
+}
+
+// ReplyComposer builds replies to cached provider messages without sending them.
+type ReplyComposer interface {
+	Compose(ctx context.Context, messageID string, req ReplyRequest) (OutgoingMessage, error)
+}
+
+var (
+	_ ReplyService  = (*Mailer)(nil)
+	_ ReplyComposer = (*Mailer)(nil)
+)
+
+// Mailer composes threaded replies and hands them to the configured MailSender.
+type Mailer struct {
+	repo   Repository
+	sender MailSender
+	clock  Clock
+}
+
+// NewMailer constructs a Mailer. A nil sender yields ErrSenderNotConfigured on every send.
+func NewMailer(repo Repository, sender MailSender, clock Clock) *Mailer {
+	if repo == nil {
+		panic("email: repository dependency is required")
+	}
+	if clock == nil {
+		panic("email: clock dependency is required")
+	}
+	return &Mailer{repo: repo, sender: sender, clock: clock}
+}
+
+// Reply sends a response to the cached message identified by messageID.
+func (m *Mailer) Reply(ctx context.Context, messageID string, req ReplyRequest) (OutgoingMessage, error) {
+	msg, err := m.Compose(ctx, messageID, req)
+	if err != nil {
+		return OutgoingMessage{}, err
+	}
+	if err := m.sender.Send(ctx, msg); err != nil {
+		return OutgoingMessage{}, err
+	}
+	return msg, nil
+}
+
+// Compose builds the response to the cached message identified by messageID
+// so it can be sent later through the configured MailSender.
+func (m *Mailer) Compose(ctx context.Context, messageID string, req ReplyRequest) (OutgoingMessage, error) {
+	if err := ctx.Err(); err != nil {
+		return OutgoingMessage{}, err
+	}
+	if m.sender == nil {
+		return OutgoingMessage{}, ErrSenderNotConfigured
+	}
+	if strings.TrimSpace(req.TextBody) == "" && strings.TrimSpace(req.HTMLBody) == "" {
+		return OutgoingMessage{}, ErrEmptyReply
+	}
+
+	auth, err := m.repo.GetAuth(ctx)
+	if err != nil {
+		return OutgoingMessage{}, err
+	}
+	if auth == nil {
+		return OutgoingMessage{}, ErrProviderNotAuthenticated
+	}
+
+	original, err := FindMessage(ctx, m.repo, messageID)
+	if err != nil {
+		return OutgoingMessage{}, err
+	}
+
+	msg, err := NewReply(original, auth.State.Username, m.clock.Now().UTC())
+	if err != nil {
+		return OutgoingMessage{}, err
+	}
+	if subject := strings.TrimSpace(req.Subject); subject != "" {
+		msg.Subject = subject
+	}
+	msg.TextBody = req.TextBody
+	msg.HTMLBody = req.HTMLBody
+	return msg, nil
+}
+
+// FindMessage returns the cached message with the supplied identifier.
+func FindMessage(ctx context.Context, repo Repository, messageID string) (EmailMessage, error) {
+	messages, _, err := repo.GetMessages(ctx)
+	if err != nil {
+		return EmailMessage{}, err
+	}
+	for _, message := range messages {
+		if message.ID == messageID {
+			return message, nil
+		}
+	}
+	return EmailMessage{}, ErrMessageNotFound
+}
+
+// NewReply builds an outgoing message addressed to the original sender with
+// In-Reply-To and References headers set per RFC 5322 section 3.6.4.
+func NewReply(original EmailMessage, from string, now time.Time) (OutgoingMessage, error) {
+	if strings.TrimSpace(from) == "" {
+		return OutgoingMessage{}, errors.New("reply sender address is required")
+	}
+	if strings.TrimSpace(original.Sender) == "" {
+		return OutgoingMessage{}, errors.New("original message has no sender")
+	}
+
+	messageID, err := NewMessageID(from, now)
+	if err != nil {
+		return OutgoingMessage{}, err
+	}
+
+	msg := OutgoingMessage{
+		From:      from,
+		To:        []string{original.Sender},
+		Subject:   replySubject(original.Subject),
+		Date:      now,
+		MessageID: messageID,
+	}
+
+	if original.MessageID != "" {
+		msg.InReplyTo = original.MessageID
+		msg.References = append(append([]string(nil), original.References...), original.MessageID)
+	}
+	return msg, nil
+}
+
+// NewMessageID generates a globally unique Message-ID using the domain of from.
+func NewMessageID(from string, now time.Time) (string, error) {
+	domain := "localhost"
+	if addr, err := mail.ParseAddress(from); err == nil {
+		if at := strings.LastIndex(addr.Address, "@"); at >= 0 && at < len(addr.Address)-1 {
+			domain = addr.Address[at+1:]
+		}
+	}
+
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("generate message id: %w", err)
+	}
+	return fmt.Sprintf("<%d.%s@%s>", now.UnixNano(), hex.EncodeToString(buf), domain), nil
+}
+
+func replySubject(subject string) string {
+	trimmed := strings.TrimSpace(subject)
+	if len(trimmed) >= 3 && strings.EqualFold(trimmed[:3], "re:") {
+		return trimmed
+	}
+	return "Re: " + trimmed
+}
@@ -0,0 +1,145 @@
+package email_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/example/iboz/internal/email"
+	"github.com/example/iboz/internal/email/adapter/memory"
+	"github.com/example/iboz/internal/email/adapter/synthetic"
+)
+
+type captureSender struct {
+	sent []email.OutgoingMessage
+}
+
+func (c *captureSender) Send(_ context.Context, msg email.OutgoingMessage) error {
+	c.sent = append(c.sent, msg)
+	return nil
+}
+
+func TestNewReplyThreadsHeaders(t *testing.T) {
+	now := time.Date(2025, time.March, 18, 15, 30, 0, 0, time.UTC)
+	original := email.EmailMessage{
+		ID:         "msg-1",
+		Subject:    "Contract signature pending",
+		Sender:     "legal-ops@example.com",
+		MessageID:  "<b@example.com>",
+		References: []string{"<a@example.com>"},
+	}
+
+	reply, err := email.NewReply(original, "ops@iboz.dev", now)
+	if err != nil {
+		t.Fatalf("new reply: %v", err)
+	}
+
+	if reply.Subject != "Re: Contract signature pending" {
+		t.Fatalf("unexpected subject: %q", reply.Subject)
+	}
+	if reply.InReplyTo != "<b@example.com>" {
+		t.Fatalf("unexpected In-Reply-To: %q", reply.InReplyTo)
+	}
+	if !reflect.DeepEqual(reply.References, []string{"<a@example.com>", "<b@example.com>"}) {
+		t.Fatalf("unexpected References: %v", reply.References)
+	}
+	if !strings.HasSuffix(reply.MessageID, "@iboz.dev>") {
+		t.Fatalf("message id should use sender domain: %q", reply.MessageID)
+	}
+
+	original.Subject = "RE: already replied"
+	reply, err = email.NewReply(original, "ops@iboz.dev", now)
+	if err != nil {
+		t.Fatalf("new reply: %v", err)
+	}
+	if reply.Subject != "RE: already replied" {
+		t.Fatalf("subject should not gain a second prefix: %q", reply.Subject)
+	}
+}
+
+func TestMailerReply(t *testing.T) {
+	ctx := context.Background()
+	repo := memory.NewRepository()
+	clock := fixedClock{now: time.Date(2025, time.March, 18, 15, 30, 0, 0, time.UTC)}
+	svc := email.NewService(repo, email.NewSHA256Hasher(), memory.NewVault(), synthetic.NewGenerator(), clock)
+
+	if _, err := email.NewMailer(repo, nil, clock).Reply(ctx, "msg-digest", email.ReplyRequest{TextBody: "hi"}); !errors.Is(err, email.ErrSenderNotConfigured) {
+		t.Fatalf("expected sender not configured, got %v", err)
+	}
+
+	sender := &captureSender{}
+	mailer := email.NewMailer(repo, sender, clock)
+
+	if _, err := mailer.Reply(ctx, "msg-digest", email.ReplyRequest{TextBody: "hi"}); !errors.Is(err, email.ErrProviderNotAuthenticated) {
+		t.Fatalf("expected auth error, got %v", err)
+	}
+
+	if err := svc.ConfigureProvider(ctx, email.ProviderConfig{
+		Provider:    email.ProviderGmail,
+		DisplayName: "Ops",
+		Connection:  email.ConnectionSettings{Protocol: email.ProtocolAPI},
+	}); err != nil {
+		t.Fatalf("configure provider: %v", err)
+	}
+	if _, err := svc.Authenticate(ctx, email.AuthRequest{Method: email.AuthMethodOAuth, Username: "ops@example.com", Secret: "abcdefghi"}); err != nil {
+		t.Fatalf("authenticate: %v", err)
+	}
+	if _, err := svc.FetchEmails(ctx); err != nil {
+		t.Fatalf("fetch emails: %v", err)
+	}
+
+	if _, err := mailer.Reply(ctx, "msg-digest", email.ReplyRequest{}); !errors.Is(err, email.ErrEmptyReply) {
+		t.Fatalf("expected empty reply error, got %v", err)
+	}
+	if _, err := mailer.Reply(ctx, "missing", email.ReplyRequest{TextBody: "hi"}); !errors.Is(err, email.ErrMessageNotFound) {
+		t.Fatalf("expected not found error, got %v", err)
+	}
+
+	sent, err := mailer.Reply(ctx, "msg-escalation", email.ReplyRequest{TextBody: "Signed."})
+	if err != nil {
+		t.Fatalf("reply: %v", err)
+	}
+	if len(sender.sent) != 1 {
+		t.Fatalf("expected one sent message, got %d", len(sender.sent))
+	}
+	if sent.From != "ops@example.com" || !reflect.DeepEqual(sent.To, []string{"legal-ops@example.com"}) {
+		t.Fatalf("unexpected addressing: %+v", sent)
+	}
+	if !sent.Date.Equal(clock.now) {
+		t.Fatalf("expected date from clock, got %s", sent.Date)
+	}
+}
+
+func TestAuthenticateStoresSecretInVault(t *testing.T) {
+	ctx := context.Background()
+	vault := memory.NewVault()
+	clock := fixedClock{now: time.Date(2025, time.March, 18, 15, 30, 0, 0, time.UTC)}
+	svc := email.NewService(memory.NewRepository(), email.NewSHA256Hasher(), vault, synthetic.NewGenerator(), clock)
+
+	cfg := email.ProviderConfig{
+		Provider:    email.ProviderGmail,
+		DisplayName: "Ops",
+		Connection:  email.ConnectionSettings{Protocol: email.ProtocolAPI},
+	}
+	if err := svc.ConfigureProvider(ctx, cfg); err != nil {
+		t.Fatalf("configure provider: %v", err)
+	}
+	if _, err := svc.Authenticate(ctx, email.AuthRequest{Method: email.AuthMethodAppPassword, Username: "ops@example.com", Secret: "supersecure"}); err != nil {
+		t.Fatalf("authenticate: %v", err)
+	}
+
+	secret, err := vault.Secret(ctx, "ops@example.com")
+	if err != nil || secret != "supersecure" {
+		t.Fatalf("expected stored secret, got %q (%v)", secret, err)
+	}
+
+	if err := svc.ConfigureProvider(ctx, cfg); err != nil {
+		t.Fatalf("reconfigure provider: %v", err)
+	}
+	if _, err := vault.Secret(ctx, "ops@example.com"); !errors.Is(err, email.ErrSecretNotFound) {
+		t.Fatalf("expected secret to be removed on reconfigure, got %v", err)
+	}
+}
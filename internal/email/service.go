@@ -4,6 +4,8 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net/mail"
+	"net/textproto"
 	"strings"
 	"time"
 )
@@ -35,6 +37,8 @@ var (
 	ErrProviderNotConfigured = errors.New("email provider not configured")
 	// ErrProviderNotAuthenticated is returned when authentication is missing.
 	ErrProviderNotAuthenticated = errors.New("email provider authentication not configured")
+	// ErrSecretNotFound is returned when the vault holds no secret for a key.
+	ErrSecretNotFound = errors.New("secret not found in vault")
 )
 
 // ConnectionSettings describes how to reach the upstream provider.
@@ -71,15 +75,223 @@ type AuthState struct {
 	UpdatedAt time.Time  `json:"updatedAt"`
 }
 
-// EmailMessage represents a fetched message from the provider.
+// EmailMessage represents a fetched message from the provider. Providers that
+// read the raw message keep its headers, keyed in canonical MIME form.
 type EmailMessage struct {
-	ID         string    `json:"id"`
-	Subject    string    `json:"subject"`
-	Sender     string    `json:"sender"`
-	ReceivedAt time.Time `json:"receivedAt"`
-	Snippet    string    `json:"snippet"`
-	Labels     []string  `json:"labels"`
-	Importance string    `json:"importance"`
+	ID         string     `json:"id"`
+	Subject    string     `json:"subject"`
+	Sender     string     `json:"sender"`
+	ReceivedAt time.Time  `json:"receivedAt"`
+	Snippet    string     `json:"snippet"`
+	Labels     []string   `json:"labels"`
+	Importance string     `json:"importance"`
+	Category   string     `json:"category,omitempty"`
+	MessageID  string     `json:"messageId,omitempty"`
+	References []string   `json:"references,omitempty"`
+	ThreadID   string     `json:"threadId,omitempty"`
+	Recipients []string   `json:"recipients,omitempty"`
+	Tasks      []TaskLink `json:"tasks,omitempty"`
+	CRM        []CRMLink  `json:"crm,omitempty"`
+	Headers    Header     `json:"headers,omitempty"`
+	Bulk       *BulkMail  `json:"bulk,omitempty"`
+	Spam       *SpamScore `json:"spam,omitempty"`
+}
+
+// Header holds message headers keyed in canonical MIME form.
+type Header map[string][]string
+
+// Get returns the first value of the header key, or "".
+func (h Header) Get(key string) string {
+	return textproto.MIMEHeader(h).Get(key)
+}
+
+// Values returns every value of the header key.
+func (h Header) Values(key string) []string {
+	return textproto.MIMEHeader(h).Values(key)
+}
+
+// Authentication returns the result of each method, such as "dkim" or "dmarc",
+// in the topmost Authentication-Results header, the one added by the receiving
+// server. The first result of a method wins.
+func (h Header) Authentication() map[string]string {
+	verdicts := make(map[string]string)
+	results := h.Get("Authentication-Results")
+	if results == "" {
+		return verdicts
+	}
+	parts := strings.Split(results, ";")
+	for _, part := range parts[1:] {
+		fields := strings.Fields(part)
+		if len(fields) == 0 {
+			continue
+		}
+		method, result, ok := strings.Cut(strings.ToLower(fields[0]), "=")
+		if ok && verdicts[method] == "" {
+			verdicts[method] = result
+		}
+	}
+	return verdicts
+}
+
+// Clone returns a deep copy of the headers.
+func (h Header) Clone() Header {
+	if h == nil {
+		return nil
+	}
+	cloned := make(Header, len(h))
+	for key, values := range h {
+		cloned[key] = append([]string(nil), values...)
+	}
+	return cloned
+}
+
+// BulkMail records why a message was detected as bulk mail and how to
+// unsubscribe from it. OneClick is set when the sender accepts RFC 8058
+// one-click unsubscribes at UnsubscribeURL.
+type BulkMail struct {
+	Signals           []string `json:"signals"`
+	ListID            string   `json:"listId,omitempty"`
+	ESP               string   `json:"esp,omitempty"`
+	UnsubscribeURL    string   `json:"unsubscribeUrl,omitempty"`
+	UnsubscribeMailto string   `json:"unsubscribeMailto,omitempty"`
+	OneClick          bool     `json:"oneClick"`
+}
+
+// Clone returns a deep copy of the record.
+func (b *BulkMail) Clone() *BulkMail {
+	if b == nil {
+		return nil
+	}
+	cloned := *b
+	cloned.Signals = append([]string(nil), b.Signals...)
+	return &cloned
+}
+
+// CanUnsubscribe reports whether the message offers an unsubscribe option.
+func (b *BulkMail) CanUnsubscribe() bool {
+	return b != nil && (b.UnsubscribeURL != "" || b.UnsubscribeMailto != "")
+}
+
+// SpamScore is the spam and phishing score of a message, from 0 to 100, with
+// the reasons that added to it.
+type SpamScore struct {
+	Score   int          `json:"score"`
+	Reasons []SpamReason `json:"reasons"`
+}
+
+// SpamReason is one signal that added Points to a spam score.
+type SpamReason struct {
+	Signal string `json:"signal"`
+	Points int    `json:"points"`
+	Detail string `json:"detail"`
+}
+
+// Clone returns a deep copy of the score.
+func (s *SpamScore) Clone() *SpamScore {
+	if s == nil {
+		return nil
+	}
+	cloned := *s
+	cloned.Reasons = append([]SpamReason{}, s.Reasons...)
+	return &cloned
+}
+
+// TaskLink references a task created from the message in an external tracker.
+type TaskLink struct {
+	Provider   string `json:"provider"`
+	ExternalID string `json:"externalId"`
+	URL        string `json:"url,omitempty"`
+	Status     string `json:"status"`
+}
+
+// CRMLink references a CRM record created from the message.
+type CRMLink struct {
+	Provider   string `json:"provider"`
+	Kind       string `json:"kind"`
+	ExternalID string `json:"externalId"`
+	URL        string `json:"url,omitempty"`
+	ContactID  string `json:"contactId,omitempty"`
+}
+
+// LabelSent marks messages sent from the authenticated mailbox.
+const LabelSent = "SENT"
+
+// ThreadKey returns the identifier grouping msg with the rest of its conversation.
+func (m EmailMessage) ThreadKey() string {
+	switch {
+	case m.ThreadID != "":
+		return m.ThreadID
+	case len(m.References) > 0:
+		return m.References[0]
+	case m.MessageID != "":
+		return m.MessageID
+	default:
+		return m.ID
+	}
+}
+
+// SentBy reports whether the message was sent from the mailbox of user.
+func (m EmailMessage) SentBy(user string) bool {
+	if m.HasLabel(LabelSent) {
+		return true
+	}
+	return user != "" && strings.EqualFold(strings.TrimSpace(m.Sender), user)
+}
+
+// Replies maps a thread key to the time of the latest message the mailbox
+// owner sent in that thread.
+type Replies map[string]time.Time
+
+// LatestReplies collects the replies user sent among messages.
+func LatestReplies(messages []EmailMessage, user string) Replies {
+	replies := make(Replies)
+	for _, message := range messages {
+		if !message.SentBy(user) {
+			continue
+		}
+		if thread := message.ThreadKey(); message.ReceivedAt.After(replies[thread]) {
+			replies[thread] = message.ReceivedAt
+		}
+	}
+	return replies
+}
+
+// Answered reports whether the owner replied in the thread of message after receiving it.
+func (r Replies) Answered(message EmailMessage) bool {
+	return r[message.ThreadKey()].After(message.ReceivedAt)
+}
+
+// SenderDomain returns the lower-cased domain of the sender address.
+func (m EmailMessage) SenderDomain() string {
+	address := m.Sender
+	if parsed, err := mail.ParseAddress(m.Sender); err == nil {
+		address = parsed.Address
+	}
+	at := strings.LastIndex(address, "@")
+	if at < 0 {
+		return ""
+	}
+	return strings.ToLower(strings.TrimSpace(address[at+1:]))
+}
+
+// Summary describes the message for records created from it in external
+// systems, such as tracker tasks and CRM activities.
+func (m EmailMessage) Summary() string {
+	summary := fmt.Sprintf("From: %s\nReceived: %s", m.Sender, m.ReceivedAt.UTC().Format(time.RFC1123))
+	if m.Snippet != "" {
+		summary += "\n\n" + m.Snippet
+	}
+	return summary
+}
+
+// HasLabel reports whether the message carries label.
+func (m EmailMessage) HasLabel(label string) bool {
+	for _, candidate := range m.Labels {
+		if strings.EqualFold(candidate, label) {
+			return true
+		}
+	}
+	return false
 }
 
 // ServiceState captures the public state exported by the service.
@@ -115,6 +327,25 @@ type Repository interface {
 	GetMessages(ctx context.Context) ([]EmailMessage, time.Time, error)
 }
 
+// Vault stores credential secrets that adapters must replay to upstream services.
+type Vault interface {
+	StoreSecret(ctx context.Context, key, secret string) error
+	Secret(ctx context.Context, key string) (string, error)
+	DeleteSecret(ctx context.Context, key string) error
+}
+
+// Classifier annotates the messages of a sync, for example with their
+// category, before they are stored. It returns the messages in the order it
+// received them.
+type Classifier interface {
+	Classify(ctx context.Context, messages []EmailMessage) ([]EmailMessage, error)
+}
+
+// SyncListener is notified after every successful message sync.
+type SyncListener interface {
+	MessagesSynced(ctx context.Context, messages []EmailMessage, syncedAt time.Time) error
+}
+
 // SecretHasher abstracts hashing of sensitive credentials.
 type SecretHasher interface {
 	Hash(secret string) (string, error)
@@ -135,6 +366,7 @@ type ProviderService interface {
 	ConfigureProvider(ctx context.Context, cfg ProviderConfig) error
 	Authenticate(ctx context.Context, req AuthRequest) (AuthState, error)
 	FetchEmails(ctx context.Context) ([]EmailMessage, error)
+	Message(ctx context.Context, id string) (EmailMessage, error)
 	State(ctx context.Context) (ServiceState, error)
 }
 
@@ -142,27 +374,52 @@ var _ ProviderService = (*Service)(nil)
 
 // Service manages provider configuration, authentication and message retrieval.
 type Service struct {
-	repo      Repository
-	hasher    SecretHasher
-	generator MessageGenerator
-	clock     Clock
+	repo        Repository
+	hasher      SecretHasher
+	vault       Vault
+	generator   MessageGenerator
+	clock       Clock
+	classifiers []Classifier
+	listeners   []SyncListener
 }
 
 // NewService constructs a Service instance with the supplied dependencies.
-func NewService(repo Repository, hasher SecretHasher, generator MessageGenerator, clock Clock) *Service {
+func NewService(repo Repository, hasher SecretHasher, vault Vault, generator MessageGenerator, clock Clock) *Service {
 	if repo == nil {
 		panic("email: repository dependency is required")
 	}
 	if hasher == nil {
 		panic("email: secret hasher dependency is required")
 	}
+	if vault == nil {
+		panic("email: vault dependency is required")
+	}
 	if generator == nil {
 		panic("email: message generator dependency is required")
 	}
 	if clock == nil {
 		panic("email: clock dependency is required")
 	}
-	return &Service{repo: repo, hasher: hasher, generator: generator, clock: clock}
+	return &Service{repo: repo, hasher: hasher, vault: vault, generator: generator, clock: clock}
+}
+
+// OnSync registers a listener invoked after each successful FetchEmails.
+// It must be called before the service handles requests.
+func (s *Service) OnSync(listener SyncListener) {
+	if listener == nil {
+		panic("email: sync listener must not be nil")
+	}
+	s.listeners = append(s.listeners, listener)
+}
+
+// ClassifyWith registers a classifier applied to every synced message before
+// it is stored, after the classifiers registered earlier. It must be called
+// before the service handles requests.
+func (s *Service) ClassifyWith(classifier Classifier) {
+	if classifier == nil {
+		panic("email: classifier must not be nil")
+	}
+	s.classifiers = append(s.classifiers, classifier)
 }
 
 // ConfigureProvider validates and stores provider configuration.
@@ -176,9 +433,19 @@ func (s *Service) ConfigureProvider(ctx context.Context, cfg ProviderConfig) err
 
 	cleaned := normalizeConfig(cfg)
 
+	previous, err := s.repo.GetAuth(ctx)
+	if err != nil {
+		return err
+	}
+
 	if err := s.repo.SaveConfig(ctx, cleaned); err != nil {
 		return err
 	}
+	if previous != nil {
+		if err := s.vault.DeleteSecret(ctx, previous.State.Username); err != nil {
+			return err
+		}
+	}
 	if err := s.repo.ClearAuth(ctx); err != nil {
 		return err
 	}
@@ -233,6 +500,9 @@ func (s *Service) Authenticate(ctx context.Context, req AuthRequest) (AuthState,
 	}
 	record := AuthRecord{State: state, SecretHash: hash}
 
+	if err := s.vault.StoreSecret(ctx, req.Username, req.Secret); err != nil {
+		return AuthState{}, err
+	}
 	if err := s.repo.SaveAuth(ctx, record); err != nil {
 		return AuthState{}, err
 	}
@@ -267,14 +537,37 @@ func (s *Service) FetchEmails(ctx context.Context) ([]EmailMessage, error) {
 	if err != nil {
 		return nil, err
 	}
+	for _, classifier := range s.classifiers {
+		if messages, err = classifier.Classify(ctx, messages); err != nil {
+			return nil, fmt.Errorf("classify messages: %w", err)
+		}
+	}
 
 	if err := s.repo.SaveMessages(ctx, messages, now); err != nil {
 		return nil, err
 	}
 
+	for _, listener := range s.listeners {
+		if err := listener.MessagesSynced(ctx, cloneMessages(messages), now); err != nil {
+			return nil, fmt.Errorf("sync listener: %w", err)
+		}
+	}
+
 	return cloneMessages(messages), nil
 }
 
+// Message returns a synced message by ID with the links recorded on it.
+func (s *Service) Message(ctx context.Context, id string) (EmailMessage, error) {
+	if err := ctx.Err(); err != nil {
+		return EmailMessage{}, err
+	}
+	message, err := FindMessage(ctx, s.repo, id)
+	if err != nil {
+		return EmailMessage{}, err
+	}
+	return cloneMessages([]EmailMessage{message})[0], nil
+}
+
 // State returns a snapshot of the current service state suitable for JSON encoding.
 func (s *Service) State(ctx context.Context) (ServiceState, error) {
 	if err := ctx.Err(); err != nil {
@@ -374,6 +667,13 @@ func cloneMessages(messages []EmailMessage) []EmailMessage {
 	for i, message := range messages {
 		cloned[i] = message
 		cloned[i].Labels = append([]string(nil), message.Labels...)
+		cloned[i].References = append([]string(nil), message.References...)
+		cloned[i].Recipients = append([]string(nil), message.Recipients...)
+		cloned[i].Tasks = append([]TaskLink(nil), message.Tasks...)
+		cloned[i].CRM = append([]CRMLink(nil), message.CRM...)
+		cloned[i].Headers = message.Headers.Clone()
+		cloned[i].Bulk = message.Bulk.Clone()
+		cloned[i].Spam = message.Spam.Clone()
 	}
 	return cloned
 }
@@ -23,7 +23,7 @@ func newTestService(t *testing.T) email.ProviderService {
 	t.Helper()
 	repo := memory.NewRepository()
 	clock := fixedClock{now: time.Date(2025, time.March, 18, 15, 30, 0, 0, time.UTC)}
-	return email.NewService(repo, email.NewSHA256Hasher(), synthetic.NewGenerator(), clock)
+	return email.NewService(repo, email.NewSHA256Hasher(), memory.NewVault(), synthetic.NewGenerator(), clock)
 }
 
 func TestConfigureProviderValidation(t *testing.T) {
@@ -156,3 +156,82 @@ func TestAuthenticateValidation(t *testing.T) {
 		t.Fatalf("expected validation error for secret length")
 	}
 }
+
+type recordingListener struct {
+	batches [][]email.EmailMessage
+}
+
+func (r *recordingListener) MessagesSynced(_ context.Context, messages []email.EmailMessage, _ time.Time) error {
+	r.batches = append(r.batches, messages)
+	return nil
+}
+
+func TestFetchEmailsNotifiesSyncListeners(t *testing.T) {
+	ctx := context.Background()
+	clock := fixedClock{now: time.Date(2025, time.March, 18, 15, 30, 0, 0, time.UTC)}
+	svc := email.NewService(memory.NewRepository(), email.NewSHA256Hasher(), memory.NewVault(), synthetic.NewGenerator(), clock)
+	listener := &recordingListener{}
+	svc.OnSync(listener)
+
+	if err := svc.ConfigureProvider(ctx, email.ProviderConfig{
+		Provider:    email.ProviderGmail,
+		DisplayName: "Ops",
+		Connection:  email.ConnectionSettings{Protocol: email.ProtocolAPI},
+	}); err != nil {
+		t.Fatalf("configure provider: %v", err)
+	}
+	if _, err := svc.Authenticate(ctx, email.AuthRequest{Method: email.AuthMethodOAuth, Username: "ops@example.com", Secret: "abcdefghi"}); err != nil {
+		t.Fatalf("authenticate: %v", err)
+	}
+
+	messages, err := svc.FetchEmails(ctx)
+	if err != nil {
+		t.Fatalf("fetch emails: %v", err)
+	}
+	if len(listener.batches) != 1 || len(listener.batches[0]) != len(messages) {
+		t.Fatalf("expected listener to receive the synced batch, got %v", listener.batches)
+	}
+}
+
+type labelClassifier struct {
+	label string
+}
+
+func (l labelClassifier) Classify(_ context.Context, messages []email.EmailMessage) ([]email.EmailMessage, error) {
+	for i := range messages {
+		messages[i].Labels = append(messages[i].Labels, l.label)
+	}
+	return messages, nil
+}
+
+func TestFetchEmailsAppliesClassifiersBeforeStoring(t *testing.T) {
+	ctx := context.Background()
+	clock := fixedClock{now: time.Date(2025, time.March, 18, 15, 30, 0, 0, time.UTC)}
+	svc := email.NewService(memory.NewRepository(), email.NewSHA256Hasher(), memory.NewVault(), synthetic.NewGenerator(), clock)
+	svc.ClassifyWith(labelClassifier{label: "first"})
+	svc.ClassifyWith(labelClassifier{label: "second"})
+
+	if err := svc.ConfigureProvider(ctx, email.ProviderConfig{
+		Provider:    email.ProviderGmail,
+		DisplayName: "Ops",
+		Connection:  email.ConnectionSettings{Protocol: email.ProtocolAPI},
+	}); err != nil {
+		t.Fatalf("configure provider: %v", err)
+	}
+	if _, err := svc.Authenticate(ctx, email.AuthRequest{Method: email.AuthMethodOAuth, Username: "ops@example.com", Secret: "abcdefghi"}); err != nil {
+		t.Fatalf("authenticate: %v", err)
+	}
+	messages, err := svc.FetchEmails(ctx)
+	if err != nil {
+		t.Fatalf("fetch emails: %v", err)
+	}
+
+	stored, err := svc.Message(ctx, messages[0].ID)
+	if err != nil {
+		t.Fatalf("message: %v", err)
+	}
+	labels := stored.Labels
+	if len(labels) < 2 || labels[len(labels)-2] != "first" || labels[len(labels)-1] != "second" {
+		t.Fatalf("expected classifiers applied in order, got %v", labels)
+	}
+}
@@ -0,0 +1,106 @@
+// Package chat holds chat notifications back during focus sessions and posts
+// them, as a digest per destination channel, once the session ends.
+package chat
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/example/iboz/internal/focus"
+	"github.com/example/iboz/internal/notify"
+)
+
+var (
+	_ notify.Notifier = (*Notifier)(nil)
+	_ focus.Releaser  = (*Notifier)(nil)
+)
+
+// Notifier wraps a chat notifier. Channel names the focus channel its held
+// notifications are released on and must be unique per wrapped notifier.
+type Notifier struct {
+	channel string
+	next    notify.Notifier
+	holder  focus.Holder
+}
+
+// NewNotifier constructs a Notifier holding notifications for next.
+func NewNotifier(channel string, next notify.Notifier, holder focus.Holder) *Notifier {
+	if channel == "" {
+		panic("chat: channel is required")
+	}
+	if next == nil {
+		panic("chat: notifier dependency is required")
+	}
+	if holder == nil {
+		panic("chat: holder dependency is required")
+	}
+	return &Notifier{channel: channel, next: next, holder: holder}
+}
+
+// Notify implements the notify.Notifier interface.
+func (n *Notifier) Notify(ctx context.Context, notification notify.Notification) error {
+	if err := notification.Validate(); err != nil {
+		return err
+	}
+	held, err := n.holder.Hold(ctx, focus.Notice{Channel: n.channel, Event: notification.Event, MessageID: notification.MessageID, Payload: notification})
+	if err != nil || held {
+		return err
+	}
+	return n.next.Notify(ctx, notification)
+}
+
+// Release implements the focus.Releaser interface. Without digest the
+// notifications are posted one by one, otherwise as one digest per destination.
+func (n *Notifier) Release(ctx context.Context, held []focus.Held, digest bool) ([]string, error) {
+	var released, destinations []string
+	byDestination := make(map[string][]notify.Notification)
+	heldIDs := make(map[string][]string)
+	for _, h := range held {
+		var notification notify.Notification
+		if err := json.Unmarshal(h.Payload, &notification); err != nil {
+			return released, fmt.Errorf("chat: decode held notification: %w", err)
+		}
+		if !digest {
+			if err := n.next.Notify(ctx, notification); err != nil {
+				return released, err
+			}
+			released = append(released, h.ID)
+			continue
+		}
+		if _, ok := byDestination[notification.Channel]; !ok {
+			destinations = append(destinations, notification.Channel)
+		}
+		byDestination[notification.Channel] = append(byDestination[notification.Channel], notification)
+		heldIDs[notification.Channel] = append(heldIDs[notification.Channel], h.ID)
+	}
+	for _, destination := range destinations {
+		if err := n.next.Notify(ctx, Digest(destination, byDestination[destination])); err != nil {
+			return released, err
+		}
+		released = append(released, heldIDs[destination]...)
+	}
+	return released, nil
+}
+
+// Digest combines notifications into one, listing each title with its link.
+func Digest(channel string, notifications []notify.Notification) notify.Notification {
+	noun := "notifications"
+	if len(notifications) == 1 {
+		noun = "notification"
+	}
+	lines := make([]string, 0, len(notifications))
+	for _, notification := range notifications {
+		line := "• " + notification.Title
+		if notification.Link != "" {
+			line += " — " + notification.Link
+		}
+		lines = append(lines, line)
+	}
+	return notify.Notification{
+		Channel: channel,
+		Title:   fmt.Sprintf("%d %s held during your focus session", len(notifications), noun),
+		Text:    strings.Join(lines, "\n"),
+	}
+}
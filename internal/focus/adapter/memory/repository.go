@@ -0,0 +1,263 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/example/iboz/internal/focus"
+	"github.com/example/iboz/internal/outbox"
+	outboxmemory "github.com/example/iboz/internal/outbox/adapter/memory"
+)
+
+var _ focus.Repository = (*Repository)(nil)
+
+// Repository provides an in-memory implementation of the focus.Repository port.
+type Repository struct {
+	mu       sync.RWMutex
+	samples  []focus.Sample
+	sessions map[string]focus.Session
+	holds    []focus.Held
+	settings map[string]focus.Settings
+	outbox   *outboxmemory.Table
+}
+
+// NewRepository builds a new in-memory focus repository.
+func NewRepository() *Repository {
+	return &Repository{
+		sessions: make(map[string]focus.Session),
+		settings: make(map[string]focus.Settings),
+		outbox:   outboxmemory.NewTable(),
+	}
+}
+
+// Samples returns the samples handled at or after since, oldest first.
+func (r *Repository) Samples(ctx context.Context, since time.Time) ([]focus.Sample, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var list []focus.Sample
+	for _, sample := range r.samples {
+		if !sample.HandledAt.Before(since) {
+			list = append(list, sample)
+		}
+	}
+	return list, nil
+}
+
+// SaveSession inserts a new session or replaces one whose version matches the
+// stored one, appending samples and effects under the same lock.
+func (r *Repository) SaveSession(ctx context.Context, session focus.Session, samples []focus.Sample, effects ...outbox.Entry) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.sessions[session.ID]
+	if ok != (session.Version > 0) || stored.Version != session.Version {
+		return focus.ErrConflict
+	}
+	if !ok && session.Open() {
+		for _, other := range r.sessions {
+			if other.Open() {
+				return fmt.Errorf("%w: %s", focus.ErrSessionInProgress, other.ID)
+			}
+		}
+	}
+	session.Version++
+	r.sessions[session.ID] = clone(session)
+	r.samples = append(r.samples, samples...)
+	r.outbox.Append(effects...)
+	return nil
+}
+
+// GetSession returns the session with the supplied identifier if present.
+func (r *Repository) GetSession(ctx context.Context, id string) (*focus.Session, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	session, ok := r.sessions[id]
+	if !ok {
+		return nil, nil
+	}
+	cloned := clone(session)
+	return &cloned, nil
+}
+
+// ListSessions returns every stored session.
+func (r *Repository) ListSessions(ctx context.Context) ([]focus.Session, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	list := make([]focus.Session, 0, len(r.sessions))
+	for _, session := range r.sessions {
+		list = append(list, clone(session))
+	}
+	return list, nil
+}
+
+// SaveHold appends a held notification while its session is open, unless one
+// with the same channel and key is held.
+func (r *Repository) SaveHold(ctx context.Context, held focus.Held) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if session, ok := r.sessions[held.SessionID]; !ok || !session.Open() {
+		return false, nil
+	}
+	if held.Key != "" {
+		for _, existing := range r.holds {
+			if existing.Channel == held.Channel && existing.Key == held.Key {
+				return true, nil
+			}
+		}
+	}
+	held.Payload = append([]byte(nil), held.Payload...)
+	r.holds = append(r.holds, held)
+	return true, nil
+}
+
+// ListHolds returns the held notifications, oldest first.
+func (r *Repository) ListHolds(ctx context.Context) ([]focus.Held, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	list := make([]focus.Held, 0, len(r.holds))
+	for _, held := range r.holds {
+		held.Payload = append([]byte(nil), held.Payload...)
+		list = append(list, held)
+	}
+	return list, nil
+}
+
+// DeleteHolds removes held notifications by ID.
+func (r *Repository) DeleteHolds(ctx context.Context, ids ...string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	remove := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		remove[id] = true
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	kept := r.holds[:0]
+	for _, held := range r.holds {
+		if !remove[held.ID] {
+			kept = append(kept, held)
+		}
+	}
+	r.holds = kept
+	return nil
+}
+
+// SaveSettings inserts or replaces the settings of a user.
+func (r *Repository) SaveSettings(ctx context.Context, settings focus.Settings) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	r.settings[settings.User] = cloneSettings(settings)
+	r.mu.Unlock()
+	return nil
+}
+
+// GetSettings returns the settings of a user if present.
+func (r *Repository) GetSettings(ctx context.Context, user string) (*focus.Settings, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	settings, ok := r.settings[user]
+	if !ok {
+		return nil, nil
+	}
+	cloned := cloneSettings(settings)
+	return &cloned, nil
+}
+
+// PendingEntries implements the outbox.Store interface.
+func (r *Repository) PendingEntries(ctx context.Context, now time.Time, limit int) ([]outbox.Entry, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.outbox.Pending(now, limit), nil
+}
+
+// UpdateEntry implements the outbox.Store interface.
+func (r *Repository) UpdateEntry(ctx context.Context, entry outbox.Entry) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	r.outbox.Update(entry)
+	r.mu.Unlock()
+	return nil
+}
+
+func clone(session focus.Session) focus.Session {
+	items := make([]focus.Item, len(session.Items))
+	for i, item := range session.Items {
+		item.MarkedAt = cloneTime(item.MarkedAt)
+		items[i] = item
+	}
+	session.Items = items
+	session.Spans = append([]focus.Interval(nil), session.Spans...)
+	session.ResumedAt = cloneTime(session.ResumedAt)
+	session.EndedAt = cloneTime(session.EndedAt)
+	if session.Summary != nil {
+		summary := *session.Summary
+		summary.FollowUps = append([]focus.FollowUp(nil), summary.FollowUps...)
+		session.Summary = &summary
+	}
+	return session
+}
+
+func cloneSettings(settings focus.Settings) focus.Settings {
+	settings.Policy.Importance = append([]string{}, settings.Policy.Importance...)
+	settings.Policy.Categories = append([]string{}, settings.Policy.Categories...)
+	settings.Policy.VIPs = append([]string{}, settings.Policy.VIPs...)
+	settings.Policy.Events = append([]string{}, settings.Policy.Events...)
+	return settings
+}
+
+func cloneTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	v := *t
+	return &v
+}
@@ -0,0 +1,81 @@
+// Package webhook holds webhook events back during focus sessions and
+// delivers them once the session ends.
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/example/iboz/internal/focus"
+	"github.com/example/iboz/internal/webhooks"
+)
+
+// Channel is the focus channel webhook events are held on.
+const Channel = "webhooks"
+
+var (
+	_ webhooks.Gate  = (*Gate)(nil)
+	_ focus.Releaser = (*Gate)(nil)
+)
+
+// Publisher queues the deliveries of released events.
+type Publisher interface {
+	Release(ctx context.Context, events []webhooks.Event) error
+}
+
+// Gate routes webhook events through a focus Holder.
+type Gate struct {
+	holder    focus.Holder
+	publisher Publisher
+}
+
+// NewGate constructs a Gate releasing held events to publisher.
+func NewGate(holder focus.Holder, publisher Publisher) *Gate {
+	if holder == nil {
+		panic("webhook: holder dependency is required")
+	}
+	if publisher == nil {
+		panic("webhook: publisher dependency is required")
+	}
+	return &Gate{holder: holder, publisher: publisher}
+}
+
+// subject picks the message an event is about out of its data.
+type subject struct {
+	MessageID string `json:"messageId"`
+	Deadline  struct {
+		MessageID string `json:"messageId"`
+	} `json:"deadline"`
+}
+
+// Hold implements the webhooks.Gate interface.
+func (g *Gate) Hold(ctx context.Context, event webhooks.Event) (bool, error) {
+	var about subject
+	// Best effort: events that are not about a message are held by event type.
+	_ = json.Unmarshal(event.Data, &about)
+	messageID := about.MessageID
+	if messageID == "" {
+		messageID = about.Deadline.MessageID
+	}
+	return g.holder.Hold(ctx, focus.Notice{Channel: Channel, Key: event.Key, Event: event.Type, MessageID: messageID, Payload: event})
+}
+
+// Release implements the focus.Releaser interface. Events are always delivered
+// one by one, in the order they were held, so subscribers see the usual
+// payloads.
+func (g *Gate) Release(ctx context.Context, held []focus.Held, _ bool) ([]string, error) {
+	var released []string
+	for _, h := range held {
+		var event webhooks.Event
+		if err := json.Unmarshal(h.Payload, &event); err != nil {
+			return released, fmt.Errorf("webhook: decode held event: %w", err)
+		}
+		event.Key = h.Key
+		if err := g.publisher.Release(ctx, []webhooks.Event{event}); err != nil {
+			return released, err
+		}
+		released = append(released, h.ID)
+	}
+	return released, nil
+}
@@ -0,0 +1,104 @@
+package focus
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/example/iboz/internal/calendar"
+	"github.com/example/iboz/internal/outbox"
+)
+
+// CalendarBusy adapts the events of a calendar source to a BusySource so plans
+// are scheduled around meetings. Transparent events leave the owner free.
+func CalendarBusy(source calendar.Source) BusySource {
+	if source == nil {
+		panic("focus: calendar source is nil")
+	}
+	return calendarBusy{source: source}
+}
+
+type calendarBusy struct {
+	source calendar.Source
+}
+
+func (c calendarBusy) Busy(ctx context.Context, from, to time.Time) ([]Interval, error) {
+	events, err := c.source.Events(ctx, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("read calendar: %w", err)
+	}
+	busy := make([]Interval, 0, len(events))
+	for _, event := range events {
+		if !event.Transparent {
+			busy = append(busy, Interval{Start: event.Start.UTC(), End: event.End.UTC()})
+		}
+	}
+	return busy, nil
+}
+
+type block struct {
+	SessionID string `json:"sessionId"`
+}
+
+// BlockWith writes sessions to the owner's calendar through writer: a block
+// for the estimated length when a session starts, shortened or extended to
+// its actual end once it ends. It must be called before sessions start.
+func (s *Service) BlockWith(writer calendar.Writer) {
+	if writer == nil {
+		panic("focus: calendar writer is nil")
+	}
+	s.blocks = writer
+}
+
+// blockEffects returns the outbox entry writing the session's block, if
+// sessions are written to a calendar.
+func (s *Service) blockEffects(session Session, now time.Time) ([]outbox.Entry, error) {
+	if s.blocks == nil {
+		return nil, nil
+	}
+	entry, err := outbox.NewEntry(BlockDestination, "focus:block:"+session.ID+":"+string(session.Status), block{SessionID: session.ID}, now)
+	if err != nil {
+		return nil, err
+	}
+	return []outbox.Entry{entry}, nil
+}
+
+// BlockDeliverer returns the outbox deliverer writing session blocks to the
+// calendar. The block is built from the session as stored when it is
+// delivered, so a retried start never overwrites the block of an ended one.
+func (s *Service) BlockDeliverer() outbox.Deliverer {
+	return outbox.DelivererFunc(func(ctx context.Context, entry outbox.Entry) error {
+		var payload block
+		if err := json.Unmarshal(entry.Payload, &payload); err != nil {
+			return fmt.Errorf("decode focus block: %w", err)
+		}
+		if s.blocks == nil {
+			return fmt.Errorf("focus: no calendar writer for session %s", payload.SessionID)
+		}
+		session, err := s.Get(ctx, payload.SessionID)
+		if err != nil {
+			return err
+		}
+		return s.blocks.PutEvent(ctx, blockEvent(session))
+	})
+}
+
+// blockEvent is the calendar event of a session. Open sessions reserve their
+// estimate; ended ones cover the time from start to end.
+func blockEvent(session Session) calendar.Event {
+	end := session.StartedAt.Add(time.Duration(session.Estimated) * time.Minute)
+	if session.Estimated <= 0 {
+		end = session.StartedAt.Add(DefaultBlock)
+	}
+	if session.EndedAt != nil {
+		end = *session.EndedAt
+	}
+	return calendar.Event{
+		UID:         "iboz-" + session.ID,
+		Summary:     "Focus: " + session.Label,
+		Description: fmt.Sprintf("%d messages in a focus session.", len(session.Items)),
+		Start:       session.StartedAt,
+		End:         end,
+	}
+}
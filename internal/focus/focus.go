@@ -0,0 +1,337 @@
+// Package focus plans and tracks focus sessions: it batches classified
+// messages by category and similarity, estimates the effort of each batch,
+// schedules the batches into the free time of the working day, records how
+// the messages of a running session are handled and holds notifications back
+// until the session ends.
+package focus
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/example/iboz/internal/email"
+	"github.com/example/iboz/internal/outbox"
+	"github.com/example/iboz/internal/sla"
+)
+
+const (
+	// DefaultGoal is the number of focus sessions to complete each day to keep
+	// the streak going.
+	DefaultGoal = 3
+	// DefaultMaxBatchSize caps the number of messages in one session.
+	DefaultMaxBatchSize = 10
+	// DefaultMaxSession caps the estimated length of one session.
+	DefaultMaxSession = 90 * time.Minute
+	// DefaultBreak is the gap left between two scheduled sessions.
+	DefaultBreak = 10 * time.Minute
+	// MinSamples is the number of handled messages of a category needed before
+	// its history replaces the default handling time.
+	MinSamples = 3
+	// DefaultBlock is the calendar block reserved for a session started from
+	// explicit messages, which has no batch estimate.
+	DefaultBlock = 30 * time.Minute
+)
+
+const (
+	// OutboxDestination is the outbox destination releasing the notifications
+	// held during a session, delivered by the Service's Deliverer.
+	OutboxDestination = "focus.release"
+	// BlockDestination is the outbox destination writing sessions to the
+	// owner's calendar, delivered by the Service's BlockDeliverer.
+	BlockDestination = "focus.block"
+)
+
+// Status enumerates the lifecycle of a focus session.
+type Status string
+
+const (
+	StatusActive    Status = "active"
+	StatusPaused    Status = "paused"
+	StatusCompleted Status = "completed"
+	StatusAbandoned Status = "abandoned"
+)
+
+var transitions = map[Status]map[Status]struct{}{
+	StatusActive:    {StatusPaused: {}, StatusCompleted: {}, StatusAbandoned: {}},
+	StatusPaused:    {StatusActive: {}, StatusCompleted: {}, StatusAbandoned: {}},
+	StatusCompleted: {},
+	StatusAbandoned: {},
+}
+
+// ItemState is the progress of one message of a session.
+type ItemState string
+
+const (
+	ItemPending ItemState = "pending"
+	ItemHandled ItemState = "handled"
+	ItemSkipped ItemState = "skipped"
+)
+
+var (
+	// ErrSessionNotFound is returned when a focus session does not exist.
+	ErrSessionNotFound = errors.New("focus session not found")
+	// ErrInvalidSession is returned when a session request fails validation.
+	ErrInvalidSession = errors.New("invalid focus session")
+	// ErrInvalidTransition is returned when a status or progress change is not allowed.
+	ErrInvalidTransition = errors.New("invalid focus session transition")
+	// ErrSessionInProgress is returned when starting a session while another is open.
+	ErrSessionInProgress = errors.New("a focus session is already in progress")
+	// ErrConflict is returned by repositories when a session update races with another writer.
+	ErrConflict = errors.New("focus session was modified concurrently")
+	// ErrInvalidSettings is returned when focus settings fail validation.
+	ErrInvalidSettings = errors.New("invalid focus settings")
+)
+
+// Sample records how long handling one message took.
+type Sample struct {
+	MessageID string        `json:"messageId"`
+	Category  string        `json:"category"`
+	Words     int           `json:"words"`
+	Duration  time.Duration `json:"duration"`
+	HandledAt time.Time     `json:"handledAt"`
+}
+
+// Batch is a group of similar messages scheduled to be handled together in
+// one focus session. Start and End are unset when the batch does not fit into
+// the working day.
+type Batch struct {
+	ID          string     `json:"id"`
+	Label       string     `json:"label"`
+	Category    string     `json:"category"`
+	Estimated   int        `json:"estimated"`
+	Emails      int        `json:"emails"`
+	LLMSupport  bool       `json:"llmSupport"`
+	Description string     `json:"description"`
+	MessageIDs  []string   `json:"messageIds"`
+	Start       *time.Time `json:"start,omitempty"`
+	End         *time.Time `json:"end,omitempty"`
+}
+
+// Metrics summarises progress towards the daily goal.
+type Metrics struct {
+	ClearedToday int `json:"clearedToday"`
+	Streak       int `json:"streak"`
+	Goal         int `json:"goal"`
+}
+
+// Controls are the focus mode preferences.
+type Controls struct {
+	NotificationsMuted bool `json:"notificationsMuted"`
+	BatchingEnabled    bool `json:"batchingEnabled"`
+	AutoSummaries      bool `json:"autoSummaries"`
+}
+
+// DefaultControls mutes notifications and batches them during focus sessions.
+func DefaultControls() Controls {
+	return Controls{NotificationsMuted: true, BatchingEnabled: true, AutoSummaries: true}
+}
+
+// Policy selects the notifications that break through a focus session:
+// those about messages of the listed importance or category or from a VIP
+// sender, given as an address or a domain, and webhook events of the listed
+// types.
+type Policy struct {
+	Importance []string `json:"importance"`
+	Categories []string `json:"categories"`
+	VIPs       []string `json:"vips"`
+	Events     []string `json:"events"`
+}
+
+// DefaultPolicy lets high-importance messages and SLA breaches through.
+func DefaultPolicy() Policy {
+	return Policy{
+		Importance: []string{"high"},
+		Categories: []string{},
+		VIPs:       []string{},
+		Events:     []string{string(sla.EventBreached)},
+	}
+}
+
+// Settings are the focus preferences stored for one mailbox owner. Goal is
+// the number of sessions to complete each day.
+type Settings struct {
+	User      string    `json:"user"`
+	Goal      int       `json:"goal"`
+	Controls  Controls  `json:"controls"`
+	Policy    Policy    `json:"policy"`
+	UpdatedAt time.Time `json:"updatedAt,omitempty"`
+}
+
+// Notice describes a notification about to be sent on a channel, such as a
+// chat platform or the webhooks. Key deduplicates holds of the same
+// notification.
+type Notice struct {
+	Channel   string
+	Key       string
+	Event     string
+	MessageID string
+	Payload   any
+}
+
+// Held is a notification held back during a focus session.
+type Held struct {
+	ID        string          `json:"id"`
+	SessionID string          `json:"sessionId"`
+	Channel   string          `json:"channel"`
+	Key       string          `json:"key,omitempty"`
+	Event     string          `json:"event,omitempty"`
+	MessageID string          `json:"messageId,omitempty"`
+	Payload   json.RawMessage `json:"payload"`
+	HeldAt    time.Time       `json:"heldAt"`
+}
+
+// Releaser delivers the notifications held on its channel once the session
+// ends. digest asks for them to be combined into one notification where the
+// channel supports it. It returns the IDs of the held notifications that went
+// out, also on failure, so a retry does not send them again.
+type Releaser interface {
+	Release(ctx context.Context, held []Held, digest bool) ([]string, error)
+}
+
+// Plan is the focus plan of a day.
+type Plan struct {
+	Date     time.Time `json:"date"`
+	Sessions []Batch   `json:"sessions"`
+	Metrics  Metrics   `json:"metrics"`
+	Controls Controls  `json:"controls"`
+}
+
+// Interval is a span of time.
+type Interval struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// Item is a message queued in a focus session.
+type Item struct {
+	MessageID string        `json:"messageId"`
+	Subject   string        `json:"subject"`
+	Sender    string        `json:"sender"`
+	Category  string        `json:"category,omitempty"`
+	Words     int           `json:"words"`
+	State     ItemState     `json:"state"`
+	Duration  time.Duration `json:"duration,omitempty"`
+	MarkedAt  *time.Time    `json:"markedAt,omitempty"`
+}
+
+// Progress counts the items of a session by state.
+type Progress struct {
+	Total     int `json:"total"`
+	Handled   int `json:"handled"`
+	Skipped   int `json:"skipped"`
+	Remaining int `json:"remaining"`
+}
+
+// FollowUp recommends revisiting a message after a session.
+type FollowUp struct {
+	MessageID string `json:"messageId"`
+	Subject   string `json:"subject"`
+	Reason    string `json:"reason"`
+}
+
+// Summary is the outcome of a finished session.
+type Summary struct {
+	ElapsedSeconds int64      `json:"elapsedSeconds"`
+	Progress       Progress   `json:"progress"`
+	FollowUps      []FollowUp `json:"followUps"`
+}
+
+// Session is a focus session worked through by the mailbox owner. Active time
+// is kept as closed Spans plus the span running since ResumedAt, so elapsed
+// time survives pauses and restarts. Version increases on every stored change
+// and guards updates against concurrent writers.
+type Session struct {
+	ID             string     `json:"id"`
+	BatchID        string     `json:"batchId,omitempty"`
+	Label          string     `json:"label"`
+	Category       string     `json:"category,omitempty"`
+	Estimated      int        `json:"estimated,omitempty"`
+	Status         Status     `json:"status"`
+	Version        int        `json:"version"`
+	Items          []Item     `json:"items"`
+	Progress       Progress   `json:"progress"`
+	ElapsedSeconds int64      `json:"elapsedSeconds"`
+	Spans          []Interval `json:"spans,omitempty"`
+	StartedAt      time.Time  `json:"startedAt"`
+	ResumedAt      *time.Time `json:"resumedAt,omitempty"`
+	EndedAt        *time.Time `json:"endedAt,omitempty"`
+	UpdatedAt      time.Time  `json:"updatedAt"`
+	Summary        *Summary   `json:"summary,omitempty"`
+}
+
+// Open reports whether the session is still running or paused.
+func (s Session) Open() bool {
+	return s.Status == StatusActive || s.Status == StatusPaused
+}
+
+// Active returns the active time of the session between from and to.
+func (s Session) Active(from, to time.Time) time.Duration {
+	spans := s.Spans
+	if s.ResumedAt != nil {
+		spans = append(spans[:len(spans):len(spans)], Interval{Start: *s.ResumedAt, End: to})
+	}
+	var total time.Duration
+	for _, span := range spans {
+		start, end := span.Start, span.End
+		if start.Before(from) {
+			start = from
+		}
+		if end.After(to) {
+			end = to
+		}
+		if end.After(start) {
+			total += end.Sub(start)
+		}
+	}
+	return total
+}
+
+// StartRequest starts a session from a planned batch or from explicit
+// messages. Label overrides the batch label.
+type StartRequest struct {
+	BatchID    string   `json:"batchId"`
+	MessageIDs []string `json:"messageIds"`
+	Label      string   `json:"label"`
+}
+
+// Repository defines the persistence contract for focus sessions, history,
+// held notifications and per-user settings. It owns the outbox table of
+// pending releases.
+type Repository interface {
+	outbox.Store
+	// Samples returns the samples handled at or after since.
+	Samples(ctx context.Context, since time.Time) ([]Sample, error)
+	// SaveSession stores session together with the handling samples it
+	// produced and appends effects to the outbox atomically. A session with
+	// Version zero is inserted, unless it is open while another open session
+	// is stored, which returns ErrSessionInProgress. Otherwise session is
+	// stored if the stored version equals session.Version and the version is
+	// incremented; a mismatch returns ErrConflict.
+	SaveSession(ctx context.Context, session Session, samples []Sample, effects ...outbox.Entry) error
+	GetSession(ctx context.Context, id string) (*Session, error)
+	ListSessions(ctx context.Context) ([]Session, error)
+	// SaveHold stores a held notification while its session is open and
+	// reports whether the notification is held. A notification with the same
+	// channel and non-empty key already held is not stored again but counts as
+	// held. It stores nothing and returns false when the session has ended, in
+	// the same transaction as the check, so no hold outlives its release.
+	SaveHold(ctx context.Context, held Held) (bool, error)
+	ListHolds(ctx context.Context) ([]Held, error)
+	DeleteHolds(ctx context.Context, ids ...string) error
+	SaveSettings(ctx context.Context, settings Settings) error
+	GetSettings(ctx context.Context, user string) (*Settings, error)
+}
+
+// BusySource reports the busy time of the mailbox owner, such as meetings.
+// It is optional.
+type BusySource interface {
+	Busy(ctx context.Context, from, to time.Time) ([]Interval, error)
+}
+
+// Visibility filters out messages that should not be planned, such as snoozed
+// ones. It is optional.
+type Visibility interface {
+	Visible(ctx context.Context, messages []email.EmailMessage) ([]email.EmailMessage, error)
+}
@@ -0,0 +1,775 @@
+package focus_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/example/iboz/internal/calendar"
+	calendarmemory "github.com/example/iboz/internal/calendar/adapter/memory"
+	"github.com/example/iboz/internal/dashboard"
+	"github.com/example/iboz/internal/email"
+	emailmemory "github.com/example/iboz/internal/email/adapter/memory"
+	"github.com/example/iboz/internal/focus"
+	"github.com/example/iboz/internal/focus/adapter/chat"
+	"github.com/example/iboz/internal/focus/adapter/memory"
+	focuswebhook "github.com/example/iboz/internal/focus/adapter/webhook"
+	"github.com/example/iboz/internal/notify"
+	"github.com/example/iboz/internal/outbox"
+	"github.com/example/iboz/internal/sla"
+	slachat "github.com/example/iboz/internal/sla/adapter/chat"
+	"github.com/example/iboz/internal/testutil"
+	"github.com/example/iboz/internal/webhooks"
+	webhookmemory "github.com/example/iboz/internal/webhooks/adapter/memory"
+)
+
+type busyFunc func(ctx context.Context, from, to time.Time) ([]focus.Interval, error)
+
+func (f busyFunc) Busy(ctx context.Context, from, to time.Time) ([]focus.Interval, error) {
+	return f(ctx, from, to)
+}
+
+type recordingNotifier struct {
+	sent []notify.Notification
+	// failOnce fails the next notification with this title once.
+	failOnce string
+}
+
+func (r *recordingNotifier) Notify(_ context.Context, n notify.Notification) error {
+	if r.failOnce != "" && n.Title == r.failOnce {
+		r.failOnce = ""
+		return errors.New("chat unavailable")
+	}
+	r.sent = append(r.sent, n)
+	return nil
+}
+
+// tuesday is a working day; the default calendar works 09:00-17:00 UTC.
+var tuesday = time.Date(2025, time.March, 18, 0, 0, 0, 0, time.UTC)
+
+func newPlanner(t *testing.T, clock *testutil.Clock, messages []email.EmailMessage, busy focus.BusySource) (*focus.Planner, *memory.Repository) {
+	t.Helper()
+	emails := emailmemory.NewRepository()
+	if err := emails.SaveMessages(context.Background(), messages, clock.Now()); err != nil {
+		t.Fatalf("save messages: %v", err)
+	}
+	repo := memory.NewRepository()
+	calendars := calendar.NewService(calendarmemory.NewRepository(), emails, calendar.DefaultHours(), clock)
+	return focus.NewPlanner(repo, emails, calendars, nil, busy, clock, focus.Config{}), repo
+}
+
+func TestPlanBatchesSimilarMessagesUrgentFirst(t *testing.T) {
+	clock := testutil.NewClock(tuesday.Add(10 * time.Hour))
+	received := clock.Now().Add(-2 * time.Hour)
+	planner, _ := newPlanner(t, clock, []email.EmailMessage{
+		{ID: "news-1", Subject: "Weekly digest", Sender: "a@news.example", Category: "newsletter", ReceivedAt: received.Add(-time.Hour)},
+		{ID: "news-2", Subject: "Your weekly digest", Sender: "b@letters.example", Category: "newsletter", ReceivedAt: received},
+		{ID: "act-1", Subject: "Contract renewal", Sender: "Legal <legal@customer.example>", Category: "action", ReceivedAt: received},
+		{ID: "act-2", Subject: "Invoice overdue", Sender: "billing@customer.example", Category: "action", ReceivedAt: received},
+		{ID: "act-3", Subject: "Lunch?", Sender: "friend@else.example", Category: "action", ReceivedAt: received.Add(time.Minute)},
+		{ID: "upd-1", Subject: "Contract renewal", Sender: "legal@customer.example", Category: "updates", Importance: "high", ReceivedAt: received},
+		{ID: "unsorted", Subject: "Hello", Sender: "x@y.example", ReceivedAt: received},
+		{ID: "sent", Subject: "Contract renewal", Sender: "me@example.com", Category: "action", Labels: []string{email.LabelSent}, ReceivedAt: received},
+	}, nil)
+
+	plan, err := planner.Plan(context.Background())
+	if err != nil {
+		t.Fatalf("plan: %v", err)
+	}
+	got := make([]string, 0, len(plan.Sessions))
+	for _, session := range plan.Sessions {
+		got = append(got, fmt.Sprintf("%s%v", session.Label, session.MessageIDs))
+	}
+	want := []string{
+		"Action: customer.example[act-1 act-2]",
+		"Updates: customer.example[upd-1]",
+		"Action: else.example[act-3]",
+		"Newsletter: digest[news-1 news-2]",
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("unexpected sessions:\n got %v\nwant %v", got, want)
+	}
+
+	first := plan.Sessions[0]
+	if first.Emails != 2 || !first.LLMSupport || first.Estimated != 15 {
+		t.Fatalf("unexpected first session: %+v", first)
+	}
+	if first.Description != "Handle 2 action messages from customer.example with drafted replies." {
+		t.Fatalf("unexpected description: %q", first.Description)
+	}
+	if newsletter := plan.Sessions[3]; newsletter.LLMSupport || newsletter.Estimated != 5 {
+		t.Fatalf("unexpected newsletter session: %+v", newsletter)
+	}
+	if !plan.Date.Equal(clock.Now()) || plan.Metrics.Goal != focus.DefaultGoal || plan.Controls != focus.DefaultControls() {
+		t.Fatalf("unexpected plan header: %+v", plan)
+	}
+
+	again, err := planner.Plan(context.Background())
+	if err != nil {
+		t.Fatalf("plan again: %v", err)
+	}
+	if again.Sessions[0].ID != first.ID {
+		t.Fatalf("expected stable session IDs, got %s and %s", first.ID, again.Sessions[0].ID)
+	}
+}
+
+func TestPlanEstimatesFromHandlingHistory(t *testing.T) {
+	clock := testutil.NewClock(tuesday.Add(11 * time.Hour))
+	planner, repo := newPlanner(t, clock, []email.EmailMessage{
+		{ID: "done-1", ThreadID: "thread-1", Category: "updates", Sender: "a@one.example", ReceivedAt: tuesday},
+		{ID: "reply-1", ThreadID: "thread-1", Sender: "me@example.com", Labels: []string{email.LabelSent}, ReceivedAt: tuesday.Add(time.Hour)},
+		{ID: "old-1", Category: "updates", Sender: "d@four.example", ReceivedAt: tuesday.Add(-60 * 24 * time.Hour)},
+		{ID: "open-1", Category: "updates", Sender: "b@two.example", ReceivedAt: tuesday},
+		{ID: "open-2", Category: "action", Sender: "c@three.example", ReceivedAt: tuesday},
+	}, nil)
+	// old-1 was handled in a session long before the sample history window.
+	old := focus.Session{ID: "session-old", Status: focus.StatusCompleted, Items: []focus.Item{{MessageID: "old-1", Category: "updates", State: focus.ItemHandled}}}
+	var samples []focus.Sample
+	for i, handledAt := range []time.Time{tuesday.Add(-48 * time.Hour), tuesday.Add(9 * time.Hour), tuesday.Add(10 * time.Hour)} {
+		samples = append(samples, focus.Sample{MessageID: fmt.Sprintf("done-%d", i+1), Category: "updates", Words: 400, Duration: 22 * time.Minute, HandledAt: handledAt})
+	}
+	if err := repo.SaveSession(context.Background(), old, samples); err != nil {
+		t.Fatalf("save session: %v", err)
+	}
+
+	plan, err := planner.Plan(context.Background())
+	if err != nil {
+		t.Fatalf("plan: %v", err)
+	}
+	if len(plan.Sessions) != 2 {
+		t.Fatalf("expected handled messages to be left out, got %+v", plan.Sessions)
+	}
+	// Three samples of 22 minutes for 400 words leave 20 minutes of handling.
+	if updates := plan.Sessions[1]; updates.Category != "updates" || updates.Estimated != 20 {
+		t.Fatalf("expected the history estimate, got %+v", updates)
+	}
+	if action := plan.Sessions[0]; action.Category != "action" || action.Estimated != 5 {
+		t.Fatalf("expected the default estimate, got %+v", action)
+	}
+	if plan.Metrics.ClearedToday != 2 {
+		t.Fatalf("expected two messages cleared today, got %d", plan.Metrics.ClearedToday)
+	}
+}
+
+// runSource lists fixed automation runs.
+type runSource []dashboard.Run
+
+func (r runSource) Runs(context.Context, time.Time) ([]dashboard.Run, error) {
+	return r, nil
+}
+
+func TestClearedCountsEveryInboxExit(t *testing.T) {
+	clock := testutil.NewClock(tuesday.Add(15 * time.Hour))
+	planner, repo := newPlanner(t, clock, []email.EmailMessage{
+		{ID: "answered", ThreadID: "thread-1", Category: "updates", Sender: "a@one.example", ReceivedAt: tuesday.Add(-24 * time.Hour)},
+		{ID: "early-reply", ThreadID: "thread-1", Sender: "me@example.com", Labels: []string{email.LabelSent}, ReceivedAt: tuesday.Add(-48 * time.Hour)},
+		{ID: "reply", ThreadID: "thread-1", Sender: "me@example.com", Labels: []string{email.LabelSent}, ReceivedAt: tuesday.Add(10 * time.Hour)},
+		{ID: "automated", Category: "updates", Sender: "b@two.example", ReceivedAt: tuesday},
+		{ID: "handled", Category: "action", Sender: "c@three.example", ReceivedAt: tuesday.Add(-24 * time.Hour)},
+		{ID: "open", Category: "action", Sender: "d@four.example", ReceivedAt: tuesday},
+	}, nil)
+	planner.CountRuns(runSource{
+		{Kind: "task", MessageID: "automated", CompletedAt: tuesday.Add(11 * time.Hour)},
+		{Kind: "reply", MessageID: "handled", CompletedAt: tuesday.Add(12 * time.Hour)},
+	})
+	// handled left the inbox in a session on Monday, before its automation ran.
+	samples := []focus.Sample{{MessageID: "handled", Category: "action", HandledAt: tuesday.Add(-10 * time.Hour)}}
+	if err := repo.SaveSession(context.Background(), focus.Session{ID: "session-mon", Status: focus.StatusCompleted}, samples); err != nil {
+		t.Fatalf("save session: %v", err)
+	}
+
+	history, err := planner.History(context.Background(), 2)
+	if err != nil {
+		t.Fatalf("history: %v", err)
+	}
+	if monday, today := history.Days[0], history.Days[1]; monday.Cleared != 1 || today.Cleared != 2 {
+		t.Fatalf("expected the reply and the run today and the session on Monday, got %+v", history.Days)
+	}
+}
+
+func TestPlanSchedulesAroundBusyTime(t *testing.T) {
+	clock := testutil.NewClock(tuesday.Add(15 * time.Hour))
+	messages := []email.EmailMessage{
+		{ID: "upd-1", Category: "updates", Sender: "b@two.example", ReceivedAt: tuesday.Add(time.Hour)},
+	}
+	for i := 0; i < 8; i++ {
+		messages = append(messages, email.EmailMessage{ID: fmt.Sprintf("act-%d", i), Category: "action", Sender: "a@one.example", ReceivedAt: tuesday.Add(2 * time.Hour)})
+	}
+	busyUntil := tuesday.Add(15*time.Hour + 30*time.Minute)
+	var asked focus.Interval
+	planner, _ := newPlanner(t, clock, messages, busyFunc(func(_ context.Context, from, to time.Time) ([]focus.Interval, error) {
+		asked = focus.Interval{Start: from, End: to}
+		return []focus.Interval{{Start: tuesday.Add(14 * time.Hour), End: busyUntil}}, nil
+	}))
+
+	plan, err := planner.Plan(context.Background())
+	if err != nil {
+		t.Fatalf("plan: %v", err)
+	}
+	if !asked.Start.Equal(clock.Now()) || !asked.End.Equal(tuesday.Add(17*time.Hour)) {
+		t.Fatalf("unexpected busy window: %+v", asked)
+	}
+	action, updates := plan.Sessions[0], plan.Sessions[1]
+	if action.Estimated != 40 || action.Start == nil || !action.Start.Equal(busyUntil) || !action.End.Equal(busyUntil.Add(40*time.Minute)) {
+		t.Fatalf("unexpected action session: %+v", action)
+	}
+	if want := action.End.Add(focus.DefaultBreak); updates.Start == nil || !updates.Start.Equal(want) {
+		t.Fatalf("expected updates after a break at %s, got %+v", want, updates)
+	}
+
+	clock.Set(tuesday.Add(16*time.Hour + 30*time.Minute))
+	plan, err = planner.Plan(context.Background())
+	if err != nil {
+		t.Fatalf("plan late: %v", err)
+	}
+	if action := plan.Sessions[0]; action.Start != nil {
+		t.Fatalf("expected the long session to stay unscheduled, got %+v", action)
+	}
+	if updates := plan.Sessions[1]; updates.Start == nil || !updates.Start.Equal(clock.Now()) {
+		t.Fatalf("expected the short session to take the remaining time, got %+v", updates)
+	}
+
+	clock.Set(tuesday.Add(18 * time.Hour))
+	plan, err = planner.Plan(context.Background())
+	if err != nil {
+		t.Fatalf("plan after hours: %v", err)
+	}
+	if next := tuesday.Add(33 * time.Hour); !plan.Date.Equal(next) || !plan.Sessions[0].Start.Equal(next) {
+		t.Fatalf("expected the plan to move to the next working day, got %s", plan.Date)
+	}
+}
+
+func TestSessionLifecycleTracksActiveTimeAndProgress(t *testing.T) {
+	clock := testutil.NewClock(tuesday.Add(10 * time.Hour))
+	emails := emailmemory.NewRepository()
+	messages := []email.EmailMessage{
+		{ID: "act-1", Subject: "Contract renewal", Snippet: "Please sign", Sender: "legal@customer.example", Category: "action", ThreadID: "t-1", ReceivedAt: tuesday},
+		{ID: "act-2", Subject: "Invoice", Sender: "billing@customer.example", Category: "action", ThreadID: "t-2", ReceivedAt: tuesday},
+		{ID: "act-3", Subject: "Renewal terms", Sender: "legal@customer.example", Category: "action", ThreadID: "t-3", ReceivedAt: tuesday},
+	}
+	if err := emails.SaveMessages(context.Background(), messages, clock.Now()); err != nil {
+		t.Fatalf("save messages: %v", err)
+	}
+	repo := memory.NewRepository()
+	calendars := calendar.NewService(calendarmemory.NewRepository(), emails, calendar.DefaultHours(), clock)
+	planner := focus.NewPlanner(repo, emails, calendars, nil, nil, clock, focus.Config{})
+	svc := focus.NewService(repo, emails, planner, clock)
+	ctx := context.Background()
+
+	plan, err := planner.Plan(ctx)
+	if err != nil || len(plan.Sessions) != 1 {
+		t.Fatalf("plan: %+v (%v)", plan, err)
+	}
+	session, err := svc.Start(ctx, focus.StartRequest{BatchID: plan.Sessions[0].ID})
+	if err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	if session.Label != "Action: customer.example" || session.Progress.Total != 3 || session.Items[0].Words != 4 {
+		t.Fatalf("unexpected session: %+v", session)
+	}
+	if _, err := svc.Start(ctx, focus.StartRequest{MessageIDs: []string{"act-1"}}); !errors.Is(err, focus.ErrSessionInProgress) {
+		t.Fatalf("expected ErrSessionInProgress, got %v", err)
+	}
+
+	clock.Advance(4 * time.Minute)
+	if _, err := svc.Pause(ctx, session.ID); err != nil {
+		t.Fatalf("pause: %v", err)
+	}
+	clock.Advance(30 * time.Minute)
+	if got, _ := svc.Get(ctx, session.ID); got.ElapsedSeconds != 240 {
+		t.Fatalf("expected the timer to stop while paused, got %ds", got.ElapsedSeconds)
+	}
+	if _, err := svc.Mark(ctx, session.ID, "act-1", focus.ItemHandled); !errors.Is(err, focus.ErrInvalidTransition) {
+		t.Fatalf("expected ErrInvalidTransition while paused, got %v", err)
+	}
+	if _, err := svc.Resume(ctx, session.ID); err != nil {
+		t.Fatalf("resume: %v", err)
+	}
+	clock.Advance(2 * time.Minute)
+	session, err = svc.Mark(ctx, session.ID, "act-1", focus.ItemHandled)
+	if err != nil {
+		t.Fatalf("mark handled: %v", err)
+	}
+	if session.Items[0].Duration != 6*time.Minute || session.ElapsedSeconds != 360 {
+		t.Fatalf("expected six active minutes, got %+v", session)
+	}
+	if _, err := svc.Mark(ctx, session.ID, "act-1", focus.ItemSkipped); !errors.Is(err, focus.ErrInvalidTransition) {
+		t.Fatalf("expected ErrInvalidTransition re-marking, got %v", err)
+	}
+	if _, err := svc.Mark(ctx, session.ID, "other", focus.ItemSkipped); !errors.Is(err, focus.ErrInvalidSession) {
+		t.Fatalf("expected ErrInvalidSession for a foreign message, got %v", err)
+	}
+	clock.Advance(time.Minute)
+	if _, err := svc.Mark(ctx, session.ID, "act-2", focus.ItemSkipped); err != nil {
+		t.Fatalf("mark skipped: %v", err)
+	}
+
+	reply := email.EmailMessage{ID: "sent-1", Subject: "Re: Contract renewal", Labels: []string{email.LabelSent}, ThreadID: "t-1", ReceivedAt: clock.Now()}
+	if err := emails.SaveMessages(ctx, append(messages, reply), clock.Now()); err != nil {
+		t.Fatalf("save reply: %v", err)
+	}
+	session, err = svc.Complete(ctx, session.ID)
+	if err != nil {
+		t.Fatalf("complete: %v", err)
+	}
+	if session.Status != focus.StatusCompleted || session.EndedAt == nil || session.Summary == nil {
+		t.Fatalf("unexpected completed session: %+v", session)
+	}
+	var reasons []string
+	for _, followUp := range session.Summary.FollowUps {
+		reasons = append(reasons, followUp.MessageID+": "+followUp.Reason)
+	}
+	want := []string{"act-2: Skipped during the session", "act-3: Not reached before the session ended"}
+	if fmt.Sprint(reasons) != fmt.Sprint(want) {
+		t.Fatalf("unexpected follow-ups:\n got %v\nwant %v", reasons, want)
+	}
+	if _, err := svc.Abandon(ctx, session.ID); !errors.Is(err, focus.ErrInvalidTransition) {
+		t.Fatalf("expected ErrInvalidTransition after completion, got %v", err)
+	}
+
+	samples, err := repo.Samples(ctx, tuesday)
+	if err != nil || len(samples) != 1 || samples[0].MessageID != "act-1" || samples[0].Duration != 6*time.Minute {
+		t.Fatalf("expected one handling sample, got %+v (%v)", samples, err)
+	}
+	if current, err := svc.Current(ctx); err != nil || current != nil {
+		t.Fatalf("expected no open session, got %+v (%v)", current, err)
+	}
+}
+
+func TestConcurrentSessionWritesApplyOnce(t *testing.T) {
+	clock := testutil.NewClock(tuesday.Add(10 * time.Hour))
+	emails := emailmemory.NewRepository()
+	messages := []email.EmailMessage{
+		{ID: "act-1", Subject: "Contract renewal", Sender: "legal@customer.example", Category: "action", ReceivedAt: tuesday},
+	}
+	if err := emails.SaveMessages(context.Background(), messages, clock.Now()); err != nil {
+		t.Fatalf("save messages: %v", err)
+	}
+	repo := memory.NewRepository()
+	calendars := calendar.NewService(calendarmemory.NewRepository(), emails, calendar.DefaultHours(), clock)
+	svc := focus.NewService(repo, emails, focus.NewPlanner(repo, emails, calendars, nil, nil, clock, focus.Config{}), clock)
+	ctx := context.Background()
+
+	const writers = 8
+	var wg sync.WaitGroup
+	started := make(chan focus.Session, writers)
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if session, err := svc.Start(ctx, focus.StartRequest{MessageIDs: []string{"act-1"}}); err == nil {
+				started <- session
+			} else if !errors.Is(err, focus.ErrSessionInProgress) {
+				t.Errorf("start: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	close(started)
+	if len(started) != 1 {
+		t.Fatalf("expected exactly one session to start, got %d", len(started))
+	}
+	session := <-started
+
+	clock.Advance(time.Minute)
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := svc.Mark(ctx, session.ID, "act-1", focus.ItemHandled); err != nil && !errors.Is(err, focus.ErrConflict) && !errors.Is(err, focus.ErrInvalidTransition) {
+				t.Errorf("mark: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	samples, err := repo.Samples(ctx, tuesday)
+	if err != nil || len(samples) != 1 {
+		t.Fatalf("expected one handling sample, got %+v (%v)", samples, err)
+	}
+
+	// A write based on a stale read is rejected instead of undoing the mark.
+	if err := repo.SaveSession(ctx, session, nil); !errors.Is(err, focus.ErrConflict) {
+		t.Fatalf("expected ErrConflict for a stale session, got %v", err)
+	}
+	if got, _ := svc.Get(ctx, session.ID); got.Items[0].State != focus.ItemHandled {
+		t.Fatalf("expected the mark to survive, got %+v", got.Items[0])
+	}
+}
+
+func TestNotificationsAreHeldDuringSessionsAndReleasedAsDigest(t *testing.T) {
+	ctx := context.Background()
+	clock := testutil.NewClock(tuesday.Add(10 * time.Hour))
+	emails := emailmemory.NewRepository()
+	if err := emails.SaveAuth(ctx, email.AuthRecord{State: email.AuthState{Username: "me@example.com"}}); err != nil {
+		t.Fatalf("save auth: %v", err)
+	}
+	if err := emails.SaveMessages(ctx, []email.EmailMessage{
+		{ID: "routine", Subject: "Status report", Sender: "team@example.com", Category: "updates"},
+		{ID: "urgent", Subject: "Outage", Sender: "ops@example.com", Importance: "high"},
+		{ID: "vip", Subject: "Board deck", Sender: "CEO <ceo@corp.example>"},
+	}, clock.Now()); err != nil {
+		t.Fatalf("save messages: %v", err)
+	}
+	repo := memory.NewRepository()
+	calendars := calendar.NewService(calendarmemory.NewRepository(), emails, calendar.DefaultHours(), clock)
+	svc := focus.NewService(repo, emails, focus.NewPlanner(repo, emails, calendars, nil, nil, clock, focus.Config{}), clock)
+
+	recorder := &recordingNotifier{}
+	notifier := chat.NewNotifier("slack", recorder, svc)
+	svc.OnRelease("slack", notifier)
+	webhookRepo := webhookmemory.NewRepository()
+	hooks := webhooks.NewService(webhookRepo, nil, clock)
+	gate := focuswebhook.NewGate(svc, hooks)
+	hooks.HoldWith(gate)
+	svc.OnRelease(focuswebhook.Channel, gate)
+	if _, err := hooks.CreateSubscription(ctx, webhooks.SubscriptionRequest{URL: "https://hooks.example.com", Events: []string{webhooks.WildcardEvent}}); err != nil {
+		t.Fatalf("create subscription: %v", err)
+	}
+	deliveries := func() int {
+		t.Helper()
+		list, err := hooks.Deliveries(ctx, "")
+		if err != nil {
+			t.Fatalf("deliveries: %v", err)
+		}
+		return len(list)
+	}
+
+	if err := notifier.Notify(ctx, notify.Notification{Title: "Before", MessageID: "routine"}); err != nil || len(recorder.sent) != 1 {
+		t.Fatalf("expected delivery without a session, got %d (%v)", len(recorder.sent), err)
+	}
+	if _, err := svc.UpdateSettings(ctx, focus.Settings{
+		Controls: focus.DefaultControls(),
+		Policy:   focus.Policy{Importance: []string{"HIGH"}, VIPs: []string{"@Corp.example"}, Events: []string{string(sla.EventBreached)}},
+	}); err != nil {
+		t.Fatalf("update settings: %v", err)
+	}
+	session, err := svc.Start(ctx, focus.StartRequest{MessageIDs: []string{"routine"}})
+	if err != nil {
+		t.Fatalf("start: %v", err)
+	}
+
+	for _, n := range []notify.Notification{
+		{Channel: "#ops", Title: "Routine", MessageID: "routine", Link: "https://app.example/messages/routine"},
+		{Channel: "#ops", Title: "Unrelated"},
+		{Channel: "#ops", Title: "Outage", MessageID: "urgent"},
+		{Channel: "#exec", Title: "Board deck", MessageID: "vip"},
+	} {
+		if err := notifier.Notify(ctx, n); err != nil {
+			t.Fatalf("notify %q: %v", n.Title, err)
+		}
+	}
+	if len(recorder.sent) != 3 || recorder.sent[1].Title != "Outage" || recorder.sent[2].Title != "Board deck" {
+		t.Fatalf("expected only urgent and VIP notifications to break through, got %+v", recorder.sent)
+	}
+	breach := sla.Event{Type: sla.EventBreached, Deadline: sla.Deadline{MessageID: "routine", Subject: "Status report"}}
+	if err := slachat.NewEscalator(notifier, notify.Linker{}).Escalate(ctx, breach, sla.Escalation{Action: "slack", Target: "#ops"}); err != nil {
+		t.Fatalf("escalate: %v", err)
+	}
+	if len(recorder.sent) != 4 || recorder.sent[3].Event != string(sla.EventBreached) {
+		t.Fatalf("expected the SLA breach to break through, got %+v", recorder.sent)
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := hooks.Publish(ctx, webhooks.EventMessageClassified, "classified:routine", map[string]string{"messageId": "routine"}); err != nil {
+			t.Fatalf("publish classified: %v", err)
+		}
+	}
+	if _, err := hooks.Publish(ctx, webhooks.EventSLABreached, "breached:routine", sla.Event{Type: sla.EventBreached, Deadline: sla.Deadline{MessageID: "routine"}}); err != nil {
+		t.Fatalf("publish breach: %v", err)
+	}
+	if got := deliveries(); got != 1 {
+		t.Fatalf("expected only the breach to be delivered, got %d deliveries", got)
+	}
+	if held, err := svc.Holds(ctx); err != nil || len(held) != 3 {
+		t.Fatalf("expected three held notifications, got %+v (%v)", held, err)
+	}
+
+	if _, err := svc.Complete(ctx, session.ID); err != nil {
+		t.Fatalf("complete: %v", err)
+	}
+	entries, err := repo.PendingEntries(ctx, clock.Now(), 10)
+	if err != nil || len(entries) != 1 || entries[0].Destination != focus.OutboxDestination {
+		t.Fatalf("expected one release entry, got %+v (%v)", entries, err)
+	}
+	if err := svc.Deliverer().Deliver(ctx, entries[0]); err != nil {
+		t.Fatalf("release: %v", err)
+	}
+	if len(recorder.sent) != 5 {
+		t.Fatalf("expected one digest, got %+v", recorder.sent)
+	}
+	digest := recorder.sent[4]
+	if digest.Channel != "#ops" || digest.Title != "2 notifications held during your focus session" || digest.Text != "• Routine — https://app.example/messages/routine\n• Unrelated" {
+		t.Fatalf("unexpected digest: %+v", digest)
+	}
+	if got := deliveries(); got != 2 {
+		t.Fatalf("expected the held event to be delivered once, got %d deliveries", got)
+	}
+	if held, err := svc.Holds(ctx); err != nil || len(held) != 0 {
+		t.Fatalf("expected no held notifications, got %+v (%v)", held, err)
+	}
+
+	// A hold racing with the end of the session is refused so it is sent now.
+	late := focus.Held{ID: "hold-late", SessionID: session.ID, Channel: "slack", Payload: []byte(`{"title":"Late"}`)}
+	if held, err := repo.SaveHold(ctx, late); err != nil || held {
+		t.Fatalf("expected a hold on an ended session to be refused, got held=%v (%v)", held, err)
+	}
+	if held, err := svc.Holds(ctx); err != nil || len(held) != 0 {
+		t.Fatalf("expected no held notifications, got %+v (%v)", held, err)
+	}
+}
+
+func TestFailedReleaseOnlyResendsWhatDidNotGoOut(t *testing.T) {
+	ctx := context.Background()
+	clock := testutil.NewClock(tuesday.Add(10 * time.Hour))
+	emails := emailmemory.NewRepository()
+	if err := emails.SaveAuth(ctx, email.AuthRecord{State: email.AuthState{Username: "me@example.com"}}); err != nil {
+		t.Fatalf("save auth: %v", err)
+	}
+	if err := emails.SaveMessages(ctx, []email.EmailMessage{{ID: "routine", Category: "updates"}}, clock.Now()); err != nil {
+		t.Fatalf("save messages: %v", err)
+	}
+	repo := memory.NewRepository()
+	calendars := calendar.NewService(calendarmemory.NewRepository(), emails, calendar.DefaultHours(), clock)
+	svc := focus.NewService(repo, emails, focus.NewPlanner(repo, emails, calendars, nil, nil, clock, focus.Config{}), clock)
+	recorder := &recordingNotifier{}
+	notifier := chat.NewNotifier("slack", recorder, svc)
+	svc.OnRelease("slack", notifier)
+	if _, err := svc.UpdateSettings(ctx, focus.Settings{Controls: focus.Controls{NotificationsMuted: true}}); err != nil {
+		t.Fatalf("update settings: %v", err)
+	}
+	session, err := svc.Start(ctx, focus.StartRequest{MessageIDs: []string{"routine"}})
+	if err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	for _, title := range []string{"First", "Second", "Third"} {
+		if err := notifier.Notify(ctx, notify.Notification{Channel: "#ops", Title: title}); err != nil {
+			t.Fatalf("notify %q: %v", title, err)
+		}
+	}
+	if _, err := svc.Complete(ctx, session.ID); err != nil {
+		t.Fatalf("complete: %v", err)
+	}
+	entries, err := repo.PendingEntries(ctx, clock.Now(), 10)
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("expected one release entry, got %+v (%v)", entries, err)
+	}
+
+	recorder.failOnce = "Second"
+	if err := svc.Deliverer().Deliver(ctx, entries[0]); err == nil {
+		t.Fatal("expected the release to fail")
+	}
+	if err := svc.Deliverer().Deliver(ctx, entries[0]); err != nil {
+		t.Fatalf("retry release: %v", err)
+	}
+	var titles []string
+	for _, n := range recorder.sent {
+		titles = append(titles, n.Title)
+	}
+	if strings.Join(titles, ",") != "First,Second,Third" {
+		t.Fatalf("expected each notification to be posted once, got %v", titles)
+	}
+}
+
+func TestNotificationsAreNotHeldWhenUnmuted(t *testing.T) {
+	ctx := context.Background()
+	clock := testutil.NewClock(tuesday.Add(10 * time.Hour))
+	emails := emailmemory.NewRepository()
+	if err := emails.SaveAuth(ctx, email.AuthRecord{State: email.AuthState{Username: "me@example.com"}}); err != nil {
+		t.Fatalf("save auth: %v", err)
+	}
+	if err := emails.SaveMessages(ctx, []email.EmailMessage{{ID: "routine", Category: "updates"}}, clock.Now()); err != nil {
+		t.Fatalf("save messages: %v", err)
+	}
+	repo := memory.NewRepository()
+	calendars := calendar.NewService(calendarmemory.NewRepository(), emails, calendar.DefaultHours(), clock)
+	planner := focus.NewPlanner(repo, emails, calendars, nil, nil, clock, focus.Config{})
+	svc := focus.NewService(repo, emails, planner, clock)
+	if _, err := svc.UpdateSettings(ctx, focus.Settings{Controls: focus.Controls{BatchingEnabled: true}}); err != nil {
+		t.Fatalf("update settings: %v", err)
+	}
+	if _, err := svc.Start(ctx, focus.StartRequest{MessageIDs: []string{"routine"}}); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+
+	held, err := svc.Hold(ctx, focus.Notice{Channel: "slack", MessageID: "routine", Payload: notify.Notification{Title: "Routine"}})
+	if err != nil || held {
+		t.Fatalf("expected muting to be off, got held=%v (%v)", held, err)
+	}
+	plan, err := planner.Plan(ctx)
+	if err != nil || plan.Controls.NotificationsMuted || !plan.Controls.BatchingEnabled {
+		t.Fatalf("expected the plan to report the stored controls, got %+v (%v)", plan, err)
+	}
+}
+
+func TestHistoryComputesStreakInUserTimezone(t *testing.T) {
+	ctx := context.Background()
+	// 22:00 on Tuesday 18 March in New York.
+	clock := testutil.NewClock(time.Date(2025, time.March, 19, 2, 0, 0, 0, time.UTC))
+	emails := emailmemory.NewRepository()
+	if err := emails.SaveAuth(ctx, email.AuthRecord{State: email.AuthState{Username: "me@example.com"}}); err != nil {
+		t.Fatalf("save auth: %v", err)
+	}
+	calendars := calendar.NewService(calendarmemory.NewRepository(), emails, calendar.Hours{Timezone: "America/New_York", Start: "09:00", End: "17:00"}, clock)
+	repo := memory.NewRepository()
+	planner := focus.NewPlanner(repo, emails, calendars, nil, nil, clock, focus.Config{})
+	svc := focus.NewService(repo, emails, planner, clock)
+	if _, err := svc.UpdateSettings(ctx, focus.Settings{Goal: 2, Controls: focus.DefaultControls()}); err != nil {
+		t.Fatalf("update settings: %v", err)
+	}
+
+	ended := func(id string, status focus.Status, end time.Time, samples ...focus.Sample) {
+		t.Helper()
+		start := end.Add(-25 * time.Minute)
+		session := focus.Session{ID: id, Status: status, StartedAt: start, EndedAt: &end, Spans: []focus.Interval{{Start: start, End: end}}}
+		if err := repo.SaveSession(ctx, session, samples); err != nil {
+			t.Fatalf("save session: %v", err)
+		}
+	}
+	utc := func(day, hour int) time.Time { return time.Date(2025, time.March, day, hour, 0, 0, 0, time.UTC) }
+	ended("thu", focus.StatusCompleted, utc(13, 15))
+	ended("fri-1", focus.StatusCompleted, utc(14, 14))
+	ended("fri-2", focus.StatusCompleted, utc(14, 16))
+	ended("mon-1", focus.StatusCompleted, utc(17, 14))
+	// 21:00 on Monday in New York, already Tuesday in UTC.
+	ended("mon-2", focus.StatusCompleted, utc(18, 1))
+	ended("mon-abandoned", focus.StatusAbandoned, utc(17, 18))
+	var samples []focus.Sample
+	for i, handledAt := range []time.Time{utc(18, 3), utc(18, 14), utc(18, 20), utc(19, 1)} {
+		samples = append(samples, focus.Sample{MessageID: fmt.Sprintf("m-%d", i), HandledAt: handledAt})
+	}
+	ended("tue", focus.StatusCompleted, utc(18, 15), samples...)
+
+	history, err := planner.History(ctx, 7)
+	if err != nil {
+		t.Fatalf("history: %v", err)
+	}
+	if history.Goal != 2 || history.Streak != 2 || history.Timezone != "America/New_York" || len(history.Days) != 7 {
+		t.Fatalf("unexpected history: %+v", history)
+	}
+	var got []string
+	for _, day := range history.Days {
+		got = append(got, fmt.Sprintf("%s:%d/%d/%dm/%v", day.Date[5:], day.Sessions, day.Cleared, day.FocusMinutes, day.GoalMet))
+	}
+	want := []string{
+		"03-12:0/0/0m/false", "03-13:1/0/25m/false", "03-14:2/0/50m/true", "03-15:0/0/0m/false",
+		"03-16:0/0/0m/false", "03-17:2/1/75m/true", "03-18:1/3/25m/false",
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("unexpected days:\n got %v\nwant %v", got, want)
+	}
+	if history.Days[3].WorkingDay || history.Days[4].WorkingDay || !history.Days[5].WorkingDay {
+		t.Fatalf("expected the weekend to be marked off: %+v", history.Days)
+	}
+
+	plan, err := planner.Plan(ctx)
+	if err != nil {
+		t.Fatalf("plan: %v", err)
+	}
+	if plan.Metrics != (focus.Metrics{ClearedToday: 3, Streak: 2, Goal: 2}) {
+		t.Fatalf("unexpected metrics: %+v", plan.Metrics)
+	}
+
+	ended("thu-2", focus.StatusCompleted, utc(13, 16))
+	if history, err = planner.History(ctx, 0); err != nil || history.Streak != 3 || len(history.Days) != focus.DefaultHistoryDays {
+		t.Fatalf("expected the streak to reach back to Thursday, got %+v (%v)", history, err)
+	}
+	if _, err := svc.UpdateSettings(ctx, focus.Settings{Goal: -1}); !errors.Is(err, focus.ErrInvalidSettings) {
+		t.Fatalf("expected ErrInvalidSettings, got %v", err)
+	}
+}
+
+// calendarStub is an in-memory calendar whose events are replaced by UID.
+type calendarStub struct {
+	events map[string]calendar.Event
+}
+
+func (c *calendarStub) Events(_ context.Context, from, to time.Time) ([]calendar.Event, error) {
+	var events []calendar.Event
+	for _, event := range c.events {
+		if event.Overlaps(from, to) {
+			events = append(events, event)
+		}
+	}
+	sort.Slice(events, func(i, j int) bool { return events[i].Start.Before(events[j].Start) })
+	return events, nil
+}
+
+func (c *calendarStub) PutEvent(_ context.Context, event calendar.Event) error {
+	c.events[event.UID] = event
+	return nil
+}
+
+func TestSessionsAreScheduledAroundMeetingsAndBlockedOnCalendar(t *testing.T) {
+	ctx := context.Background()
+	clock := testutil.NewClock(tuesday.Add(10 * time.Hour))
+	stub := &calendarStub{events: map[string]calendar.Event{
+		"standup": {UID: "standup", Start: tuesday.Add(10 * time.Hour), End: tuesday.Add(11 * time.Hour)},
+		"lunch":   {UID: "lunch", Start: tuesday.Add(11 * time.Hour), End: tuesday.Add(12 * time.Hour), Transparent: true},
+	}}
+	emails := emailmemory.NewRepository()
+	if err := emails.SaveMessages(ctx, []email.EmailMessage{
+		{ID: "act-1", Subject: "Contract renewal", Sender: "legal@customer.example", Category: "action", ReceivedAt: tuesday},
+		{ID: "act-2", Subject: "Contract terms", Sender: "legal@customer.example", Category: "action", ReceivedAt: tuesday},
+	}, clock.Now()); err != nil {
+		t.Fatalf("save messages: %v", err)
+	}
+	repo := memory.NewRepository()
+	calendars := calendar.NewService(calendarmemory.NewRepository(), emails, calendar.DefaultHours(), clock)
+	planner := focus.NewPlanner(repo, emails, calendars, nil, focus.CalendarBusy(stub), clock, focus.Config{})
+	svc := focus.NewService(repo, emails, planner, clock)
+	svc.BlockWith(stub)
+	relay := testutil.NewRelay(repo, map[string]outbox.Deliverer{
+		focus.OutboxDestination: svc.Deliverer(),
+		focus.BlockDestination:  svc.BlockDeliverer(),
+	}, clock)
+
+	plan, err := planner.Plan(ctx)
+	if err != nil || len(plan.Sessions) != 1 {
+		t.Fatalf("plan: %+v (%v)", plan, err)
+	}
+	batch := plan.Sessions[0]
+	if meetingEnd := tuesday.Add(11 * time.Hour); batch.Start == nil || !batch.Start.Equal(meetingEnd) {
+		t.Fatalf("expected the batch after the stand-up and over the free lunch, got %+v", batch)
+	}
+
+	clock.Set(*batch.Start)
+	session, err := svc.Start(ctx, focus.StartRequest{BatchID: batch.ID})
+	if err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	if session.Estimated != batch.Estimated {
+		t.Fatalf("expected the session to keep the batch estimate, got %+v", session)
+	}
+	pending, err := repo.PendingEntries(ctx, clock.Now(), 10)
+	if err != nil || len(pending) != 1 || pending[0].Destination != focus.BlockDestination {
+		t.Fatalf("expected one block entry, got %+v (%v)", pending, err)
+	}
+	testutil.Deliver(t, relay)
+	blockEvent := stub.events["iboz-"+session.ID]
+	if blockEvent.Summary != "Focus: "+batch.Label || !blockEvent.Start.Equal(clock.Now()) || !blockEvent.End.Equal(*batch.End) || blockEvent.Transparent {
+		t.Fatalf("unexpected block: %+v", blockEvent)
+	}
+	if busy, err := focus.CalendarBusy(stub).Busy(ctx, tuesday.Add(9*time.Hour), tuesday.Add(17*time.Hour)); err != nil || len(busy) != 2 {
+		t.Fatalf("expected the stand-up and the block to be busy, got %+v (%v)", busy, err)
+	}
+
+	clock.Advance(7 * time.Minute)
+	if _, err := svc.Complete(ctx, session.ID); err != nil {
+		t.Fatalf("complete: %v", err)
+	}
+	testutil.Deliver(t, relay)
+	if got := stub.events["iboz-"+session.ID]; !got.End.Equal(clock.Now()) {
+		t.Fatalf("expected the block to end with the session at %s, got %+v", clock.Now(), got)
+	}
+	if err := svc.BlockDeliverer().Deliver(ctx, pending[0]); err != nil {
+		t.Fatalf("redeliver start: %v", err)
+	}
+	if got := stub.events["iboz-"+session.ID]; !got.End.Equal(clock.Now()) {
+		t.Fatalf("expected a retried start to keep the actual end, got %+v", got)
+	}
+}
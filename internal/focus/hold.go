@@ -0,0 +1,189 @@
+package focus
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/mail"
+	"sort"
+	"strings"
+
+	"github.com/example/iboz/internal/email"
+	"github.com/example/iboz/internal/outbox"
+)
+
+// Holder decides whether a notification is held back by a focus session.
+type Holder interface {
+	// Hold stores the notice until the open session ends and reports true, or
+	// reports false when it must be sent now.
+	Hold(ctx context.Context, notice Notice) (bool, error)
+}
+
+// release is the outbox payload releasing the notifications of a session.
+type release struct {
+	SessionID string `json:"sessionId"`
+}
+
+// OnRelease registers the releaser of a notification channel. It must be
+// called before the outbox relay runs.
+func (s *Service) OnRelease(channel string, releaser Releaser) {
+	if releaser == nil {
+		panic(fmt.Sprintf("focus: releaser for %q is nil", channel))
+	}
+	if _, ok := s.releasers[channel]; ok {
+		panic(fmt.Sprintf("focus: releaser for %q registered twice", channel))
+	}
+	s.releasers[channel] = releaser
+}
+
+// Hold holds the notice while a session is open and notifications are muted,
+// unless the policy lets it break through.
+func (s *Service) Hold(ctx context.Context, notice Notice) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	settings, err := s.Settings(ctx)
+	if err != nil || !settings.Controls.NotificationsMuted {
+		return false, err
+	}
+	session, err := s.Current(ctx)
+	if err != nil || session == nil {
+		return false, err
+	}
+	urgent, err := s.breaksThrough(ctx, settings.Policy, notice)
+	if err != nil || urgent {
+		return false, err
+	}
+
+	payload, err := json.Marshal(notice.Payload)
+	if err != nil {
+		return false, fmt.Errorf("encode held notification: %w", err)
+	}
+	id, err := newHoldID()
+	if err != nil {
+		return false, err
+	}
+	held := Held{
+		ID:        id,
+		SessionID: session.ID,
+		Channel:   notice.Channel,
+		Key:       notice.Key,
+		Event:     notice.Event,
+		MessageID: notice.MessageID,
+		Payload:   payload,
+		HeldAt:    s.clock.Now().UTC(),
+	}
+	// The session may have ended since it was read; the repository then
+	// refuses the hold and the notification is sent now.
+	return s.repo.SaveHold(ctx, held)
+}
+
+// breaksThrough applies the policy to the notice and the message it is about.
+func (s *Service) breaksThrough(ctx context.Context, policy Policy, notice Notice) (bool, error) {
+	if notice.Event != "" && contains(policy.Events, notice.Event) {
+		return true, nil
+	}
+	if notice.MessageID == "" {
+		return false, nil
+	}
+	message, err := email.FindMessage(ctx, s.messages, notice.MessageID)
+	if errors.Is(err, email.ErrMessageNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if contains(policy.Importance, strings.ToLower(message.Importance)) || contains(policy.Categories, strings.ToLower(message.Category)) {
+		return true, nil
+	}
+	address := strings.ToLower(strings.TrimSpace(message.Sender))
+	if parsed, err := mail.ParseAddress(message.Sender); err == nil {
+		address = strings.ToLower(parsed.Address)
+	}
+	return contains(policy.VIPs, address) || contains(policy.VIPs, message.SenderDomain()), nil
+}
+
+// Holds returns the held notifications, oldest first.
+func (s *Service) Holds(ctx context.Context) ([]Held, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	list, err := s.repo.ListHolds(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(list, func(i, j int) bool { return list[i].HeldAt.Before(list[j].HeldAt) })
+	return list, nil
+}
+
+// Deliverer returns the outbox deliverer releasing the notifications held
+// during a session to their channels once it ends, as digests when batching
+// is enabled. The notifications a channel released are deleted even when it
+// failed part way, so a retry only resends the ones that did not go out.
+func (s *Service) Deliverer() outbox.Deliverer {
+	return outbox.DelivererFunc(func(ctx context.Context, entry outbox.Entry) error {
+		var payload release
+		if err := json.Unmarshal(entry.Payload, &payload); err != nil {
+			return fmt.Errorf("decode focus release: %w", err)
+		}
+		settings, err := s.Settings(ctx)
+		if err != nil {
+			return err
+		}
+		holds, err := s.Holds(ctx)
+		if err != nil {
+			return err
+		}
+		channels := make(map[string][]Held)
+		var order []string
+		for _, held := range holds {
+			if held.SessionID != payload.SessionID {
+				continue
+			}
+			if _, ok := channels[held.Channel]; !ok {
+				order = append(order, held.Channel)
+			}
+			channels[held.Channel] = append(channels[held.Channel], held)
+		}
+
+		var errs []error
+		for _, channel := range order {
+			releaser, ok := s.releasers[channel]
+			if !ok {
+				errs = append(errs, fmt.Errorf("focus: no releaser for channel %q", channel))
+				continue
+			}
+			released, err := releaser.Release(ctx, channels[channel], settings.Controls.BatchingEnabled)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("focus: release %s: %w", channel, err))
+			}
+			if len(released) == 0 {
+				continue
+			}
+			if err := s.repo.DeleteHolds(ctx, released...); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		return errors.Join(errs...)
+	})
+}
+
+func contains(values []string, value string) bool {
+	for _, candidate := range values {
+		if candidate == value {
+			return true
+		}
+	}
+	return false
+}
+
+func newHoldID() (string, error) {
+	buf := make([]byte, 6)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("generate held notification id: %w", err)
+	}
+	return "hold-" + hex.EncodeToString(buf), nil
+}
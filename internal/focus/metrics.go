@@ -0,0 +1,223 @@
+package focus
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/example/iboz/internal/calendar"
+	"github.com/example/iboz/internal/dashboard"
+)
+
+const (
+	// DefaultHistoryDays is the number of days returned by History by default.
+	DefaultHistoryDays = 30
+	// MaxHistoryDays bounds History and the streak.
+	MaxHistoryDays = 365
+)
+
+// Day is the focus activity of one calendar day of the mailbox owner. Cleared
+// counts the received messages that left the inbox that day.
+type Day struct {
+	Date         string `json:"date"`
+	Cleared      int    `json:"cleared"`
+	Sessions     int    `json:"sessions"`
+	FocusMinutes int    `json:"focusMinutes"`
+	WorkingDay   bool   `json:"workingDay"`
+	GoalMet      bool   `json:"goalMet"`
+}
+
+// History is the daily focus activity used for charting.
+type History struct {
+	Goal     int    `json:"goal"`
+	Streak   int    `json:"streak"`
+	Timezone string `json:"timezone"`
+	Days     []Day  `json:"days"`
+}
+
+// History returns the daily activity of the last days, today included. days
+// defaults to DefaultHistoryDays and is capped at MaxHistoryDays.
+func (p *Planner) History(ctx context.Context, days int) (*History, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if days <= 0 {
+		days = DefaultHistoryDays
+	}
+	if days > MaxHistoryDays {
+		days = MaxHistoryDays
+	}
+	cal, err := p.calendars.Calendar(ctx)
+	if err != nil {
+		return nil, err
+	}
+	settings, err := settingsOf(ctx, p.repo, p.messages)
+	if err != nil {
+		return nil, err
+	}
+	activity, err := p.activity(ctx, cal, settings.Goal, p.clock.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+	return &History{
+		Goal:     settings.Goal,
+		Streak:   streak(activity),
+		Timezone: cal.Location().String(),
+		Days:     reverse(activity[:days]),
+	}, nil
+}
+
+// metrics summarises today's activity.
+func (p *Planner) metrics(ctx context.Context, cal *calendar.Calendar, goal int, now time.Time) (Metrics, error) {
+	activity, err := p.activity(ctx, cal, goal, now)
+	if err != nil {
+		return Metrics{}, err
+	}
+	return Metrics{ClearedToday: activity[0].Cleared, Streak: streak(activity), Goal: goal}, nil
+}
+
+// CountRuns makes the messages handled by the automation runs of sources, such
+// as the runs behind the dashboard, count as cleared. It must be called before
+// the planner is used.
+func (p *Planner) CountRuns(sources ...dashboard.RunSource) {
+	p.runs = append(p.runs, sources...)
+}
+
+// activity returns MaxHistoryDays days of activity in the calendar's
+// timezone, newest first. Messages count as cleared on the day they left the
+// inbox and sessions on the day they were completed; abandoned sessions only
+// add focus time.
+func (p *Planner) activity(ctx context.Context, cal *calendar.Calendar, goal int, now time.Time) ([]Day, error) {
+	loc := cal.Location()
+	today := now.In(loc)
+	today = time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, loc)
+
+	days := make([]Day, MaxHistoryDays)
+	index := make(map[string]int, MaxHistoryDays)
+	for i := range days {
+		date := today.AddDate(0, 0, -i)
+		days[i] = Day{Date: date.Format(time.DateOnly), WorkingDay: cal.IsWorkingDay(date)}
+		index[days[i].Date] = i
+	}
+	day := func(t time.Time) *Day {
+		if i, ok := index[t.In(loc).Format(time.DateOnly)]; ok {
+			return &days[i]
+		}
+		return nil
+	}
+
+	exits, err := p.exits(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, at := range exits {
+		if d := day(at); d != nil {
+			d.Cleared++
+		}
+	}
+	sessions, err := p.repo.ListSessions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, session := range sessions {
+		if session.EndedAt == nil {
+			continue
+		}
+		d := day(*session.EndedAt)
+		if d == nil {
+			continue
+		}
+		d.FocusMinutes += int(session.Active(session.StartedAt, *session.EndedAt) / time.Minute)
+		if session.Status == StatusCompleted {
+			d.Sessions++
+		}
+	}
+	for i := range days {
+		days[i].GoalMet = days[i].Sessions >= goal
+	}
+	return days, nil
+}
+
+// exits returns when each message left the inbox. As on the dashboard, a
+// received message leaves it once the owner replied later in its thread or an
+// automation handled it, and it also leaves it once handled in a session;
+// the earliest of these counts.
+func (p *Planner) exits(ctx context.Context) (map[string]time.Time, error) {
+	exits := make(map[string]time.Time)
+	exit := func(messageID string, at time.Time) {
+		if first, ok := exits[messageID]; messageID != "" && (!ok || at.Before(first)) {
+			exits[messageID] = at
+		}
+	}
+
+	samples, err := p.repo.Samples(ctx, time.Time{})
+	if err != nil {
+		return nil, err
+	}
+	for _, sample := range samples {
+		exit(sample.MessageID, sample.HandledAt)
+	}
+	for _, source := range p.runs {
+		runs, err := source.Runs(ctx, time.Time{})
+		if err != nil {
+			return nil, err
+		}
+		for _, run := range runs {
+			exit(run.MessageID, run.CompletedAt)
+		}
+	}
+
+	messages, _, err := p.messages.GetMessages(ctx)
+	if err != nil {
+		return nil, err
+	}
+	user, err := owner(ctx, p.messages)
+	if err != nil {
+		return nil, err
+	}
+	sent := make(map[string][]time.Time)
+	for _, message := range messages {
+		if message.SentBy(user) {
+			sent[message.ThreadKey()] = append(sent[message.ThreadKey()], message.ReceivedAt)
+		}
+	}
+	for _, times := range sent {
+		sort.Slice(times, func(i, j int) bool { return times[i].Before(times[j]) })
+	}
+	for _, message := range messages {
+		if message.SentBy(user) {
+			continue
+		}
+		times := sent[message.ThreadKey()]
+		if i := sort.Search(len(times), func(i int) bool { return times[i].After(message.ReceivedAt) }); i < len(times) {
+			exit(message.ID, times[i])
+		}
+	}
+	return exits, nil
+}
+
+// streak counts the consecutive days meeting the goal, newest first. Today
+// does not break the streak while it is in progress, and days off never do:
+// they count when the goal was met and are skipped otherwise.
+func streak(days []Day) int {
+	count := 0
+	for i, day := range days {
+		switch {
+		case day.GoalMet:
+			count++
+		case i == 0 || !day.WorkingDay:
+			continue
+		default:
+			return count
+		}
+	}
+	return count
+}
+
+func reverse(days []Day) []Day {
+	reversed := make([]Day, len(days))
+	for i, day := range days {
+		reversed[len(days)-1-i] = day
+	}
+	return reversed
+}
@@ -0,0 +1,461 @@
+package focus
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/example/iboz/internal/calendar"
+	"github.com/example/iboz/internal/dashboard"
+	"github.com/example/iboz/internal/email"
+)
+
+const (
+	// historyWindow bounds the handling samples considered.
+	historyWindow = 30 * 24 * time.Hour
+	// wordsPerMinute is the reading speed used to estimate effort from length.
+	wordsPerMinute = 200
+	// similarity is the subject overlap above which two messages are batched together.
+	similarity = 0.4
+	// minHandling is the smallest handling time derived from history.
+	minHandling = 15 * time.Second
+	// roundTo is the granularity of session estimates.
+	roundTo = 5 * time.Minute
+)
+
+// defaultHandling is the handling time of a message, excluding reading, per
+// category until enough history is recorded.
+var defaultHandling = map[string]time.Duration{
+	"action":     5 * time.Minute,
+	"updates":    90 * time.Second,
+	"newsletter": 30 * time.Second,
+}
+
+const fallbackHandling = 2 * time.Minute
+
+// replyCategories are batched with drafted-reply support.
+var replyCategories = map[string]bool{"action": true}
+
+var stopwords = map[string]bool{
+	"the": true, "and": true, "for": true, "you": true, "your": true, "with": true,
+	"from": true, "this": true, "that": true, "are": true, "fwd": true, "fw": true,
+}
+
+// PlannerService computes the focus plan of the working day and the daily
+// progress towards the focus goal.
+type PlannerService interface {
+	Plan(ctx context.Context) (*Plan, error)
+	// History returns the last days of focus activity, oldest first.
+	History(ctx context.Context, days int) (*History, error)
+}
+
+var _ PlannerService = (*Planner)(nil)
+
+// Config tunes batching and scheduling.
+type Config struct {
+	MaxBatchSize int
+	MaxSession   time.Duration
+	Break        time.Duration
+}
+
+// Planner builds focus plans from the synced messages.
+type Planner struct {
+	repo       Repository
+	messages   email.Repository
+	calendars  calendar.Provider
+	visibility Visibility
+	busy       BusySource
+	runs       []dashboard.RunSource
+	clock      email.Clock
+	cfg        Config
+}
+
+// NewPlanner constructs a Planner. visibility and busy are optional.
+func NewPlanner(repo Repository, messages email.Repository, calendars calendar.Provider, visibility Visibility, busy BusySource, clock email.Clock, cfg Config) *Planner {
+	if repo == nil {
+		panic("focus: repository dependency is required")
+	}
+	if messages == nil {
+		panic("focus: email repository dependency is required")
+	}
+	if calendars == nil {
+		panic("focus: calendar dependency is required")
+	}
+	if clock == nil {
+		panic("focus: clock dependency is required")
+	}
+	if cfg.MaxBatchSize < 0 || cfg.MaxSession < 0 || cfg.Break < 0 {
+		panic("focus: config values cannot be negative")
+	}
+	if cfg.MaxBatchSize == 0 {
+		cfg.MaxBatchSize = DefaultMaxBatchSize
+	}
+	if cfg.MaxSession == 0 {
+		cfg.MaxSession = DefaultMaxSession
+	}
+	if cfg.Break == 0 {
+		cfg.Break = DefaultBreak
+	}
+	return &Planner{repo: repo, messages: messages, calendars: calendars, visibility: visibility, busy: busy, clock: clock, cfg: cfg}
+}
+
+// Plan batches the classified, unhandled messages and schedules the batches,
+// most urgent first, into the free working time left today or, after hours,
+// on the next working day.
+func (p *Planner) Plan(ctx context.Context) (*Plan, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	cal, err := p.calendars.Calendar(ctx)
+	if err != nil {
+		return nil, err
+	}
+	now := p.clock.Now().UTC()
+	samples, err := p.repo.Samples(ctx, now.Add(-historyWindow))
+	if err != nil {
+		return nil, err
+	}
+	pending, err := p.pending(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	settings, err := settingsOf(ctx, p.repo, p.messages)
+	if err != nil {
+		return nil, err
+	}
+
+	metrics, err := p.metrics(ctx, cal, settings.Goal, now)
+	if err != nil {
+		return nil, err
+	}
+
+	estimator := newEstimator(samples)
+	batches := p.group(pending, estimator)
+	start := cal.NextWorkingTime(now)
+	if err := p.schedule(ctx, cal, start, batches); err != nil {
+		return nil, err
+	}
+
+	planned := make([]Batch, 0, len(batches))
+	for _, b := range batches {
+		planned = append(planned, b.batch)
+	}
+	return &Plan{
+		Date:     start,
+		Sessions: planned,
+		Metrics:  metrics,
+		Controls: settings.Controls,
+	}, nil
+}
+
+// pending returns the visible, classified messages received by the mailbox
+// owner that are still to be handled: like the dashboard's inbox count, a
+// message is handled once the owner replied later in its thread, and it is
+// also handled once it was marked so in a focus session.
+func (p *Planner) pending(ctx context.Context) ([]email.EmailMessage, error) {
+	messages, _, err := p.messages.GetMessages(ctx)
+	if err != nil {
+		return nil, err
+	}
+	user, err := owner(ctx, p.messages)
+	if err != nil {
+		return nil, err
+	}
+	replies := email.LatestReplies(messages, user)
+	sessions, err := p.repo.ListSessions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	handled := make(map[string]bool)
+	for _, session := range sessions {
+		for _, item := range session.Items {
+			if item.State == ItemHandled {
+				handled[item.MessageID] = true
+			}
+		}
+	}
+
+	pending := make([]email.EmailMessage, 0, len(messages))
+	for _, message := range messages {
+		if message.Category == "" || message.SentBy(user) || handled[message.ID] || replies.Answered(message) {
+			continue
+		}
+		pending = append(pending, message)
+	}
+	if p.visibility != nil {
+		return p.visibility.Visible(ctx, pending)
+	}
+	return pending, nil
+}
+
+type group struct {
+	batch    Batch
+	effort   time.Duration
+	urgent   bool
+	oldest   time.Time
+	domain   string
+	threads  map[string]bool
+	tokens   map[string]int
+	messages int
+}
+
+// group batches messages of a category that share a thread, a sender domain or
+// most of their subject, splitting groups that exceed the session limits.
+func (p *Planner) group(messages []email.EmailMessage, estimator estimator) []*group {
+	sort.SliceStable(messages, func(i, j int) bool { return messages[i].ReceivedAt.Before(messages[j].ReceivedAt) })
+
+	var batches []*group
+	for _, message := range messages {
+		effort := estimator.estimate(message)
+		tokens := subjectTokens(message.Subject)
+		var target *group
+		for _, candidate := range batches {
+			if candidate.batch.Category != message.Category {
+				continue
+			}
+			if candidate.messages >= p.cfg.MaxBatchSize || candidate.effort+effort > p.cfg.MaxSession {
+				continue
+			}
+			if candidate.threads[message.ThreadKey()] || (candidate.domain != "" && candidate.domain == message.SenderDomain()) || overlap(candidate.tokens, tokens) >= similarity {
+				target = candidate
+				break
+			}
+		}
+		if target == nil {
+			target = &group{
+				batch:   Batch{Category: message.Category},
+				oldest:  message.ReceivedAt,
+				domain:  message.SenderDomain(),
+				threads: make(map[string]bool),
+				tokens:  make(map[string]int),
+			}
+			batches = append(batches, target)
+		}
+		target.add(message, tokens, effort)
+	}
+
+	for _, b := range batches {
+		b.describe()
+	}
+	sort.SliceStable(batches, func(i, j int) bool {
+		if batches[i].urgent != batches[j].urgent {
+			return batches[i].urgent
+		}
+		return batches[i].oldest.Before(batches[j].oldest)
+	})
+	return batches
+}
+
+func (b *group) add(message email.EmailMessage, tokens []string, effort time.Duration) {
+	b.messages++
+	b.effort += effort
+	b.threads[message.ThreadKey()] = true
+	if b.domain != message.SenderDomain() {
+		b.domain = ""
+	}
+	for _, token := range tokens {
+		b.tokens[token]++
+	}
+	if strings.EqualFold(message.Importance, "high") || replyCategories[message.Category] {
+		b.urgent = true
+	}
+	b.batch.MessageIDs = append(b.batch.MessageIDs, message.ID)
+}
+
+// describe fills in the presentation fields once the batch is complete.
+func (b *group) describe() {
+	s := &b.batch
+	s.Emails = b.messages
+	s.Estimated = int(roundUp(b.effort, roundTo) / time.Minute)
+	s.LLMSupport = b.urgent
+
+	sum := sha256.Sum256([]byte(strings.Join(s.MessageIDs, ",")))
+	s.ID = "focus-" + hex.EncodeToString(sum[:6])
+
+	theme := b.domain
+	if theme == "" {
+		theme = b.commonToken()
+	}
+	name := title(s.Category)
+	s.Label = name
+	noun := "messages"
+	if b.messages == 1 {
+		noun = "message"
+	}
+	s.Description = fmt.Sprintf("Handle %d %s %s", b.messages, strings.ToLower(name), noun)
+	if theme != "" {
+		s.Label += ": " + theme
+		if b.domain != "" {
+			s.Description += " from " + theme
+		} else {
+			s.Description += " about " + theme
+		}
+	}
+	if s.LLMSupport {
+		s.Description += " with drafted replies"
+	}
+	s.Description += "."
+}
+
+// commonToken returns the subject word shared by every message, if any.
+func (b *group) commonToken() string {
+	best := ""
+	for token, count := range b.tokens {
+		if count == b.messages && b.messages > 1 && (best == "" || len(token) > len(best) || (len(token) == len(best) && token < best)) {
+			best = token
+		}
+	}
+	return best
+}
+
+// schedule assigns start times to batches in order, leaving a break after each
+// session and skipping busy intervals. Batches that do not fit stay unscheduled.
+func (p *Planner) schedule(ctx context.Context, cal *calendar.Calendar, start time.Time, batches []*group) error {
+	_, closes, ok := cal.WorkingDay(start)
+	if !ok {
+		return nil
+	}
+	free := []Interval{{Start: start, End: closes}}
+	if p.busy != nil {
+		busy, err := p.busy.Busy(ctx, start, closes)
+		if err != nil {
+			return err
+		}
+		free = subtract(free, busy)
+	}
+
+	cursor := start
+	for _, b := range batches {
+		length := time.Duration(b.batch.Estimated) * time.Minute
+		for _, slot := range free {
+			from := slot.Start
+			if from.Before(cursor) {
+				from = cursor
+			}
+			if from.Add(length).After(slot.End) {
+				continue
+			}
+			end := from.Add(length)
+			b.batch.Start, b.batch.End = &from, &end
+			cursor = end.Add(p.cfg.Break)
+			break
+		}
+	}
+	return nil
+}
+
+// subtract removes busy intervals from free ones.
+func subtract(free, busy []Interval) []Interval {
+	for _, block := range busy {
+		var next []Interval
+		for _, slot := range free {
+			if !block.Start.Before(slot.End) || !block.End.After(slot.Start) {
+				next = append(next, slot)
+				continue
+			}
+			if block.Start.After(slot.Start) {
+				next = append(next, Interval{Start: slot.Start, End: block.Start})
+			}
+			if block.End.Before(slot.End) {
+				next = append(next, Interval{Start: block.End, End: slot.End})
+			}
+		}
+		free = next
+	}
+	return free
+}
+
+// estimator predicts the effort of a message from its length and the recorded
+// handling time of its category.
+type estimator struct {
+	handling map[string]time.Duration
+}
+
+func newEstimator(samples []Sample) estimator {
+	totals := make(map[string]time.Duration)
+	counts := make(map[string]int)
+	for _, sample := range samples {
+		handling := sample.Duration - reading(sample.Words)
+		if handling < minHandling {
+			handling = minHandling
+		}
+		totals[sample.Category] += handling
+		counts[sample.Category]++
+	}
+	handling := make(map[string]time.Duration)
+	for category, count := range counts {
+		if count >= MinSamples {
+			handling[category] = totals[category] / time.Duration(count)
+		}
+	}
+	return estimator{handling: handling}
+}
+
+func (e estimator) estimate(message email.EmailMessage) time.Duration {
+	handling, ok := e.handling[message.Category]
+	if !ok {
+		if handling, ok = defaultHandling[message.Category]; !ok {
+			handling = fallbackHandling
+		}
+	}
+	return handling + reading(Words(message))
+}
+
+// Words returns the length of a message in words.
+func Words(message email.EmailMessage) int {
+	return len(strings.Fields(message.Subject)) + len(strings.Fields(message.Snippet))
+}
+
+func reading(words int) time.Duration {
+	return time.Duration(words) * time.Minute / wordsPerMinute
+}
+
+func subjectTokens(subject string) []string {
+	words := strings.FieldsFunc(strings.ToLower(subject), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	seen := make(map[string]bool, len(words))
+	tokens := make([]string, 0, len(words))
+	for _, word := range words {
+		if len(word) < 3 || stopwords[word] || seen[word] {
+			continue
+		}
+		seen[word] = true
+		tokens = append(tokens, word)
+	}
+	return tokens
+}
+
+// overlap is the share of tokens already present in the batch.
+func overlap(batchTokens map[string]int, tokens []string) float64 {
+	if len(tokens) == 0 {
+		return 0
+	}
+	shared := 0
+	for _, token := range tokens {
+		if batchTokens[token] > 0 {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(tokens))
+}
+
+func roundUp(d, unit time.Duration) time.Duration {
+	if d <= 0 {
+		return unit
+	}
+	return ((d + unit - 1) / unit) * unit
+}
+
+func title(category string) string {
+	if category == "" {
+		return ""
+	}
+	return strings.ToUpper(category[:1]) + category[1:]
+}
@@ -0,0 +1,469 @@
+package focus
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/example/iboz/internal/calendar"
+	"github.com/example/iboz/internal/email"
+	"github.com/example/iboz/internal/outbox"
+)
+
+// DefaultLabel names sessions started from explicit messages.
+const DefaultLabel = "Focus session"
+
+// SessionService exposes the focus session lifecycle.
+type SessionService interface {
+	Start(ctx context.Context, req StartRequest) (Session, error)
+	Get(ctx context.Context, id string) (Session, error)
+	// Current returns the open session, or nil when none is running or paused.
+	Current(ctx context.Context) (*Session, error)
+	List(ctx context.Context) ([]Session, error)
+	Pause(ctx context.Context, id string) (Session, error)
+	Resume(ctx context.Context, id string) (Session, error)
+	Complete(ctx context.Context, id string) (Session, error)
+	Abandon(ctx context.Context, id string) (Session, error)
+	Mark(ctx context.Context, id, messageID string, state ItemState) (Session, error)
+	Settings(ctx context.Context) (Settings, error)
+	UpdateSettings(ctx context.Context, settings Settings) (Settings, error)
+	// Holds returns the notifications held until the open session ends.
+	Holds(ctx context.Context) ([]Held, error)
+}
+
+var (
+	_ SessionService = (*Service)(nil)
+	_ Holder         = (*Service)(nil)
+)
+
+// Service runs focus sessions, records how long each message took and holds
+// notifications back while a session is open.
+type Service struct {
+	repo      Repository
+	messages  email.Repository
+	planner   PlannerService
+	clock     email.Clock
+	releasers map[string]Releaser
+	blocks    calendar.Writer
+}
+
+// NewService constructs a focus session Service. planner resolves the batches
+// sessions are started from.
+func NewService(repo Repository, messages email.Repository, planner PlannerService, clock email.Clock) *Service {
+	if repo == nil {
+		panic("focus: repository dependency is required")
+	}
+	if messages == nil {
+		panic("focus: email repository dependency is required")
+	}
+	if planner == nil {
+		panic("focus: planner dependency is required")
+	}
+	if clock == nil {
+		panic("focus: clock dependency is required")
+	}
+	return &Service{repo: repo, messages: messages, planner: planner, clock: clock, releasers: make(map[string]Releaser)}
+}
+
+// Start opens an active session over the messages of a planned batch or of
+// the request. Only one session can be open at a time.
+func (s *Service) Start(ctx context.Context, req StartRequest) (Session, error) {
+	if err := ctx.Err(); err != nil {
+		return Session{}, err
+	}
+	current, err := s.Current(ctx)
+	if err != nil {
+		return Session{}, err
+	}
+	if current != nil {
+		return Session{}, fmt.Errorf("%w: %s", ErrSessionInProgress, current.ID)
+	}
+
+	session := Session{BatchID: strings.TrimSpace(req.BatchID), Label: strings.TrimSpace(req.Label)}
+	ids := req.MessageIDs
+	if session.BatchID != "" {
+		batch, err := s.batch(ctx, session.BatchID)
+		if err != nil {
+			return Session{}, err
+		}
+		ids = batch.MessageIDs
+		session.Category = batch.Category
+		session.Estimated = batch.Estimated
+		if session.Label == "" {
+			session.Label = batch.Label
+		}
+	}
+	if session.Label == "" {
+		session.Label = DefaultLabel
+	}
+
+	seen := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		id = strings.TrimSpace(id)
+		if id == "" || seen[id] {
+			continue
+		}
+		seen[id] = true
+		message, err := email.FindMessage(ctx, s.messages, id)
+		if err != nil {
+			return Session{}, err
+		}
+		session.Items = append(session.Items, Item{
+			MessageID: message.ID,
+			Subject:   message.Subject,
+			Sender:    message.Sender,
+			Category:  message.Category,
+			Words:     Words(message),
+			State:     ItemPending,
+		})
+	}
+	if len(session.Items) == 0 {
+		return Session{}, fmt.Errorf("%w: a batch or at least one message is required", ErrInvalidSession)
+	}
+
+	if session.ID, err = newID(); err != nil {
+		return Session{}, err
+	}
+	now := s.clock.Now().UTC()
+	session.Status = StatusActive
+	session.StartedAt = now
+	session.ResumedAt = &now
+	effects, err := s.blockEffects(session, now)
+	if err != nil {
+		return Session{}, err
+	}
+	return s.save(ctx, session, now, nil, effects...)
+}
+
+// batch finds a batch of the current plan.
+func (s *Service) batch(ctx context.Context, id string) (Batch, error) {
+	plan, err := s.planner.Plan(ctx)
+	if err != nil {
+		return Batch{}, err
+	}
+	for _, batch := range plan.Sessions {
+		if batch.ID == id {
+			return batch, nil
+		}
+	}
+	return Batch{}, fmt.Errorf("%w: batch %q is not in the current plan", ErrInvalidSession, id)
+}
+
+// Get returns a session by ID.
+func (s *Service) Get(ctx context.Context, id string) (Session, error) {
+	if err := ctx.Err(); err != nil {
+		return Session{}, err
+	}
+	session, err := s.repo.GetSession(ctx, id)
+	if err != nil {
+		return Session{}, err
+	}
+	if session == nil {
+		return Session{}, ErrSessionNotFound
+	}
+	return refresh(*session, s.clock.Now().UTC()), nil
+}
+
+// Current returns the open session, if any.
+func (s *Service) Current(ctx context.Context) (*Session, error) {
+	list, err := s.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, session := range list {
+		if session.Open() {
+			return &session, nil
+		}
+	}
+	return nil, nil
+}
+
+// List returns every session, most recently started first.
+func (s *Service) List(ctx context.Context) ([]Session, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	list, err := s.repo.ListSessions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	now := s.clock.Now().UTC()
+	for i := range list {
+		list[i] = refresh(list[i], now)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].StartedAt.After(list[j].StartedAt) })
+	return list, nil
+}
+
+// Pause stops the session timer.
+func (s *Service) Pause(ctx context.Context, id string) (Session, error) {
+	return s.transition(ctx, id, StatusPaused)
+}
+
+// Resume restarts the session timer.
+func (s *Service) Resume(ctx context.Context, id string) (Session, error) {
+	return s.transition(ctx, id, StatusActive)
+}
+
+// Complete ends the session and summarises it.
+func (s *Service) Complete(ctx context.Context, id string) (Session, error) {
+	return s.transition(ctx, id, StatusCompleted)
+}
+
+// Abandon ends the session early; it is summarised like a completed one.
+func (s *Service) Abandon(ctx context.Context, id string) (Session, error) {
+	return s.transition(ctx, id, StatusAbandoned)
+}
+
+func (s *Service) transition(ctx context.Context, id string, status Status) (Session, error) {
+	session, err := s.Get(ctx, id)
+	if err != nil {
+		return Session{}, err
+	}
+	if _, ok := transitions[session.Status][status]; !ok {
+		return Session{}, fmt.Errorf("%w: %s to %s", ErrInvalidTransition, session.Status, status)
+	}
+
+	now := s.clock.Now().UTC()
+	if session.ResumedAt != nil {
+		session.Spans = append(session.Spans, Interval{Start: *session.ResumedAt, End: now})
+		session.ResumedAt = nil
+	}
+	session.Status = status
+	var effects []outbox.Entry
+	switch status {
+	case StatusActive:
+		session.ResumedAt = &now
+	case StatusCompleted, StatusAbandoned:
+		session.EndedAt = &now
+		summary, err := s.summarise(ctx, refresh(session, now))
+		if err != nil {
+			return Session{}, err
+		}
+		session.Summary = &summary
+		entry, err := outbox.NewEntry(OutboxDestination, "focus:release:"+session.ID, release{SessionID: session.ID}, now)
+		if err != nil {
+			return Session{}, err
+		}
+		if effects, err = s.blockEffects(session, now); err != nil {
+			return Session{}, err
+		}
+		effects = append(effects, entry)
+	}
+	return s.save(ctx, session, now, nil, effects...)
+}
+
+// Mark records a queued message as handled or skipped. A handled message adds
+// a handling sample timed from the previous mark, counting active time only.
+func (s *Service) Mark(ctx context.Context, id, messageID string, state ItemState) (Session, error) {
+	if state != ItemHandled && state != ItemSkipped {
+		return Session{}, fmt.Errorf("%w: state must be %s or %s", ErrInvalidSession, ItemHandled, ItemSkipped)
+	}
+	session, err := s.Get(ctx, id)
+	if err != nil {
+		return Session{}, err
+	}
+	if session.Status != StatusActive {
+		return Session{}, fmt.Errorf("%w: session is %s", ErrInvalidTransition, session.Status)
+	}
+
+	index := -1
+	last := session.StartedAt
+	for i, item := range session.Items {
+		if item.MessageID == messageID {
+			index = i
+		}
+		if item.MarkedAt != nil && item.MarkedAt.After(last) {
+			last = *item.MarkedAt
+		}
+	}
+	if index < 0 {
+		return Session{}, fmt.Errorf("%w: message %q is not part of the session", ErrInvalidSession, messageID)
+	}
+	item := &session.Items[index]
+	if item.State != ItemPending {
+		return Session{}, fmt.Errorf("%w: message %q is already %s", ErrInvalidTransition, messageID, item.State)
+	}
+
+	now := s.clock.Now().UTC()
+	item.State = state
+	item.Duration = session.Active(last, now)
+	item.MarkedAt = &now
+	var samples []Sample
+	if state == ItemHandled {
+		samples = append(samples, Sample{MessageID: item.MessageID, Category: item.Category, Words: item.Words, Duration: item.Duration, HandledAt: now})
+	}
+	return s.save(ctx, session, now, samples)
+}
+
+// Settings returns the stored settings of the authenticated user, or the defaults.
+func (s *Service) Settings(ctx context.Context) (Settings, error) {
+	if err := ctx.Err(); err != nil {
+		return Settings{}, err
+	}
+	return settingsOf(ctx, s.repo, s.messages)
+}
+
+// UpdateSettings normalises and stores the settings of the authenticated user.
+// A zero goal selects DefaultGoal.
+func (s *Service) UpdateSettings(ctx context.Context, settings Settings) (Settings, error) {
+	if err := ctx.Err(); err != nil {
+		return Settings{}, err
+	}
+	if settings.Goal < 0 {
+		return Settings{}, fmt.Errorf("%w: goal cannot be negative", ErrInvalidSettings)
+	}
+	if settings.Goal == 0 {
+		settings.Goal = DefaultGoal
+	}
+	user, err := owner(ctx, s.messages)
+	if err != nil {
+		return Settings{}, err
+	}
+	if user == "" {
+		return Settings{}, email.ErrProviderNotAuthenticated
+	}
+	policy := settings.Policy
+	settings = Settings{
+		User:     user,
+		Goal:     settings.Goal,
+		Controls: settings.Controls,
+		Policy: Policy{
+			Importance: normalise(policy.Importance, strings.ToLower),
+			Categories: normalise(policy.Categories, strings.ToLower),
+			VIPs:       normalise(policy.VIPs, func(vip string) string { return strings.TrimPrefix(strings.ToLower(vip), "@") }),
+			Events:     normalise(policy.Events, nil),
+		},
+		UpdatedAt: s.clock.Now().UTC(),
+	}
+	if err := s.repo.SaveSettings(ctx, settings); err != nil {
+		return Settings{}, err
+	}
+	return settings, nil
+}
+
+// settingsOf returns the stored settings of the mailbox owner, or the defaults.
+func settingsOf(ctx context.Context, repo Repository, messages email.Repository) (Settings, error) {
+	user, err := owner(ctx, messages)
+	if err != nil {
+		return Settings{}, err
+	}
+	if user != "" {
+		stored, err := repo.GetSettings(ctx, user)
+		if err != nil {
+			return Settings{}, err
+		}
+		if stored != nil {
+			return *stored, nil
+		}
+	}
+	return Settings{User: user, Goal: DefaultGoal, Controls: DefaultControls(), Policy: DefaultPolicy()}, nil
+}
+
+// owner returns the username of the authenticated mailbox, or "".
+func owner(ctx context.Context, messages email.Repository) (string, error) {
+	auth, err := messages.GetAuth(ctx)
+	if err != nil || auth == nil {
+		return "", err
+	}
+	return auth.State.Username, nil
+}
+
+// normalise trims, transforms and deduplicates values, dropping empty ones.
+func normalise(values []string, transform func(string) string) []string {
+	seen := make(map[string]bool, len(values))
+	list := make([]string, 0, len(values))
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		if transform != nil {
+			value = transform(value)
+		}
+		if value == "" || seen[value] {
+			continue
+		}
+		seen[value] = true
+		list = append(list, value)
+	}
+	return list
+}
+
+// summarise recommends following up on skipped and unreached messages, and
+// on handled reply-worthy messages whose thread got no reply in the session.
+func (s *Service) summarise(ctx context.Context, session Session) (Summary, error) {
+	summary := Summary{ElapsedSeconds: session.ElapsedSeconds, Progress: session.Progress, FollowUps: []FollowUp{}}
+	messages, _, err := s.messages.GetMessages(ctx)
+	if err != nil {
+		return Summary{}, err
+	}
+	user, err := owner(ctx, s.messages)
+	if err != nil {
+		return Summary{}, err
+	}
+	threads := make(map[string]string, len(messages))
+	replied := make(map[string]bool)
+	for _, message := range messages {
+		threads[message.ID] = message.ThreadKey()
+		if message.SentBy(user) && !message.ReceivedAt.Before(session.StartedAt) {
+			replied[message.ThreadKey()] = true
+		}
+	}
+
+	for _, item := range session.Items {
+		reason := ""
+		switch {
+		case item.State == ItemSkipped:
+			reason = "Skipped during the session"
+		case item.State == ItemPending:
+			reason = "Not reached before the session ended"
+		case replyCategories[item.Category] && !replied[threads[item.MessageID]]:
+			reason = "Handled without sending a reply"
+		}
+		if reason != "" {
+			summary.FollowUps = append(summary.FollowUps, FollowUp{MessageID: item.MessageID, Subject: item.Subject, Reason: reason})
+		}
+	}
+	return summary, nil
+}
+
+// save stores session with the samples it produced. The repository rejects
+// the write when another request changed the session since it was read, or
+// opened another session first, so concurrent requests never both apply.
+func (s *Service) save(ctx context.Context, session Session, now time.Time, samples []Sample, effects ...outbox.Entry) (Session, error) {
+	session.UpdatedAt = now
+	session = refresh(session, now)
+	if err := s.repo.SaveSession(ctx, session, samples, effects...); err != nil {
+		return Session{}, err
+	}
+	session.Version++
+	return session, nil
+}
+
+// refresh recomputes the derived progress and elapsed time as of now.
+func refresh(session Session, now time.Time) Session {
+	progress := Progress{Total: len(session.Items)}
+	for _, item := range session.Items {
+		switch item.State {
+		case ItemHandled:
+			progress.Handled++
+		case ItemSkipped:
+			progress.Skipped++
+		default:
+			progress.Remaining++
+		}
+	}
+	session.Progress = progress
+	session.ElapsedSeconds = int64(session.Active(session.StartedAt, now) / time.Second)
+	return session
+}
+
+func newID() (string, error) {
+	buf := make([]byte, 6)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("generate focus session id: %w", err)
+	}
+	return "fs-" + hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,173 @@
+// Package slack posts notifications to Slack with Block Kit formatting, either
+// through chat.postMessage with a bot token or through an incoming webhook.
+package slack
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/example/iboz/internal/notify"
+)
+
+const (
+	// DefaultBaseURL is the Slack Web API root.
+	DefaultBaseURL = "https://slack.com/api"
+	defaultTimeout = 10 * time.Second
+)
+
+var _ notify.Notifier = (*Notifier)(nil)
+
+// Config configures a Notifier. Token selects chat.postMessage; otherwise every
+// notification goes to WebhookURL regardless of its channel.
+type Config struct {
+	Token          string
+	WebhookURL     string
+	BaseURL        string
+	DefaultChannel string
+	Client         *http.Client
+	// MaxWait bounds how long a rate-limited request waits before failing.
+	MaxWait time.Duration
+}
+
+// Notifier posts notifications to Slack.
+type Notifier struct {
+	cfg Config
+}
+
+// NewNotifier constructs a Slack Notifier. It panics without a token or webhook URL.
+func NewNotifier(cfg Config) *Notifier {
+	if cfg.Token == "" && cfg.WebhookURL == "" {
+		panic("slack: token or webhook url is required")
+	}
+	if cfg.BaseURL == "" {
+		cfg.BaseURL = DefaultBaseURL
+	}
+	cfg.BaseURL = strings.TrimRight(cfg.BaseURL, "/")
+	if cfg.Client == nil {
+		cfg.Client = &http.Client{Timeout: defaultTimeout}
+	}
+	if cfg.MaxWait == 0 {
+		cfg.MaxWait = notify.DefaultMaxWait
+	}
+	return &Notifier{cfg: cfg}
+}
+
+type postMessageResponse struct {
+	OK    bool   `json:"ok"`
+	Error string `json:"error"`
+}
+
+// Notify implements the notify.Notifier interface.
+func (n *Notifier) Notify(ctx context.Context, msg notify.Notification) error {
+	if err := msg.Validate(); err != nil {
+		return err
+	}
+	payload := map[string]interface{}{
+		"text":   fallbackText(msg),
+		"blocks": Blocks(msg),
+	}
+
+	if n.cfg.Token == "" {
+		if _, err := notify.PostJSON(ctx, n.cfg.Client, n.cfg.WebhookURL, nil, payload, n.cfg.MaxWait); err != nil {
+			return fmt.Errorf("slack: post webhook: %w", err)
+		}
+		return nil
+	}
+
+	channel := strings.TrimSpace(msg.Channel)
+	if channel == "" {
+		channel = n.cfg.DefaultChannel
+	}
+	if channel == "" {
+		return fmt.Errorf("%w: slack channel is required", notify.ErrInvalidNotification)
+	}
+	payload["channel"] = channel
+	payload["unfurl_links"] = false
+
+	header := http.Header{"Authorization": {"Bearer " + n.cfg.Token}}
+	body, err := notify.PostJSON(ctx, n.cfg.Client, n.cfg.BaseURL+"/chat.postMessage", header, payload, n.cfg.MaxWait)
+	if err != nil {
+		return fmt.Errorf("slack: chat.postMessage: %w", err)
+	}
+	var resp postMessageResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return fmt.Errorf("slack: decode chat.postMessage response: %w", err)
+	}
+	if !resp.OK {
+		if resp.Error == "ratelimited" {
+			return &notify.RateLimitError{RetryAfter: time.Second}
+		}
+		return fmt.Errorf("slack: chat.postMessage: %s", resp.Error)
+	}
+	return nil
+}
+
+// Blocks renders a notification as Block Kit blocks: a header, the text with
+// its fields, and a button linking back to the message.
+func Blocks(msg notify.Notification) []map[string]interface{} {
+	blocks := []map[string]interface{}{{
+		"type": "header",
+		"text": map[string]interface{}{"type": "plain_text", "text": truncate(msg.Title, 150), "emoji": true},
+	}}
+
+	section := map[string]interface{}{"type": "section"}
+	if msg.Text != "" {
+		section["text"] = map[string]interface{}{"type": "mrkdwn", "text": truncate(escape(msg.Text), 3000)}
+	}
+	if len(msg.Fields) > 0 {
+		fields := make([]map[string]interface{}, 0, len(msg.Fields))
+		// Slack rejects sections with more than ten fields.
+		for i, field := range msg.Fields {
+			if i == 10 {
+				break
+			}
+			fields = append(fields, map[string]interface{}{
+				"type": "mrkdwn",
+				"text": truncate("*"+escape(field.Title)+"*\n"+escape(field.Value), 2000),
+			})
+		}
+		section["fields"] = fields
+	}
+	if len(section) > 1 {
+		blocks = append(blocks, section)
+	}
+
+	if msg.Link != "" {
+		blocks = append(blocks, map[string]interface{}{
+			"type": "actions",
+			"elements": []map[string]interface{}{{
+				"type":      "button",
+				"text":      map[string]interface{}{"type": "plain_text", "text": "Open message"},
+				"url":       msg.Link,
+				"action_id": "open_message",
+			}},
+		})
+	}
+	return blocks
+}
+
+// fallbackText is shown in notifications and clients that cannot render blocks.
+func fallbackText(msg notify.Notification) string {
+	text := msg.Title
+	if msg.Link != "" {
+		text += " <" + msg.Link + "|Open message>"
+	}
+	return text
+}
+
+// escape applies the mrkdwn escaping Slack requires for user content.
+func escape(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(s)
+}
+
+func truncate(s string, limit int) string {
+	runes := []rune(s)
+	if len(runes) <= limit {
+		return s
+	}
+	return string(runes[:limit-1]) + "…"
+}
@@ -0,0 +1,106 @@
+// Package teams posts notifications to Microsoft Teams incoming webhooks as
+// Adaptive Cards.
+package teams
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/example/iboz/internal/notify"
+)
+
+const defaultTimeout = 10 * time.Second
+
+var _ notify.Notifier = (*Notifier)(nil)
+
+// Config configures a Notifier. Teams webhooks are bound to one channel, so a
+// notification whose Channel is itself an http(s) URL is posted there instead
+// of WebhookURL.
+type Config struct {
+	WebhookURL string
+	Client     *http.Client
+	// MaxWait bounds how long a rate-limited request waits before failing.
+	MaxWait time.Duration
+}
+
+// Notifier posts notifications to Teams.
+type Notifier struct {
+	cfg Config
+}
+
+// NewNotifier constructs a Teams Notifier.
+func NewNotifier(cfg Config) *Notifier {
+	if cfg.Client == nil {
+		cfg.Client = &http.Client{Timeout: defaultTimeout}
+	}
+	if cfg.MaxWait == 0 {
+		cfg.MaxWait = notify.DefaultMaxWait
+	}
+	return &Notifier{cfg: cfg}
+}
+
+// Notify implements the notify.Notifier interface.
+func (n *Notifier) Notify(ctx context.Context, msg notify.Notification) error {
+	if err := msg.Validate(); err != nil {
+		return err
+	}
+	target := n.cfg.WebhookURL
+	if channel := strings.TrimSpace(msg.Channel); strings.HasPrefix(channel, "https://") || strings.HasPrefix(channel, "http://") {
+		target = channel
+	}
+	if target == "" {
+		return fmt.Errorf("%w: teams webhook url is required", notify.ErrInvalidNotification)
+	}
+
+	payload := map[string]interface{}{
+		"type": "message",
+		"attachments": []map[string]interface{}{{
+			"contentType": "application/vnd.microsoft.card.adaptive",
+			"content":     Card(msg),
+		}},
+	}
+	if _, err := notify.PostJSON(ctx, n.cfg.Client, target, nil, payload, n.cfg.MaxWait); err != nil {
+		return fmt.Errorf("teams: post webhook: %w", err)
+	}
+	return nil
+}
+
+// Card renders a notification as an Adaptive Card with a fact set for its
+// fields and an action opening the message.
+func Card(msg notify.Notification) map[string]interface{} {
+	body := []map[string]interface{}{{
+		"type":   "TextBlock",
+		"text":   msg.Title,
+		"weight": "Bolder",
+		"size":   "Medium",
+		"wrap":   true,
+	}}
+	if msg.Text != "" {
+		body = append(body, map[string]interface{}{"type": "TextBlock", "text": msg.Text, "wrap": true})
+	}
+	if len(msg.Fields) > 0 {
+		facts := make([]map[string]string, 0, len(msg.Fields))
+		for _, field := range msg.Fields {
+			facts = append(facts, map[string]string{"title": field.Title, "value": field.Value})
+		}
+		body = append(body, map[string]interface{}{"type": "FactSet", "facts": facts})
+	}
+
+	card := map[string]interface{}{
+		"$schema": "http://adaptivecards.io/schemas/adaptive-card.json",
+		"type":    "AdaptiveCard",
+		"version": "1.4",
+		"body":    body,
+	}
+	if msg.Link != "" {
+		card["actions"] = []map[string]interface{}{{
+			"type":  "Action.OpenUrl",
+			"title": "Open message",
+			"url":   msg.Link,
+		}}
+	}
+	return card
+}
@@ -0,0 +1,130 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	// DefaultMaxWait is the longest Retry-After an adapter sleeps through before
+	// returning a RateLimitError.
+	DefaultMaxWait      = 30 * time.Second
+	maxRateLimitRetries = 3
+	maxResponseBytes    = 1 << 20
+)
+
+// PostJSON posts payload to target and returns the response body of a 2xx reply.
+// Throttled requests (429) are retried after their Retry-After delay while it
+// stays within maxWait.
+func PostJSON(ctx context.Context, client *http.Client, target string, header http.Header, payload any, maxWait time.Duration) ([]byte, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	for attempt := 1; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		for key, values := range header {
+			req.Header[key] = values
+		}
+		req.Header.Set("Content-Type", "application/json; charset=utf-8")
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		respBody, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseBytes))
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		switch {
+		case resp.StatusCode == http.StatusTooManyRequests:
+			wait := RetryAfter(resp.Header.Get("Retry-After"))
+			if wait > maxWait || attempt >= maxRateLimitRetries {
+				return nil, &RateLimitError{RetryAfter: wait}
+			}
+			if err := sleep(ctx, wait); err != nil {
+				return nil, err
+			}
+		case resp.StatusCode < 200 || resp.StatusCode >= 300:
+			return nil, fmt.Errorf("unexpected status %d: %s", resp.StatusCode, bytes.TrimSpace(respBody))
+		default:
+			return respBody, nil
+		}
+	}
+}
+
+// DoJSON sends payload (if non-nil) to target and decodes a 2xx JSON reply into
+// out (if non-nil). Task sinks and CRM
+// clients use it for their APIs.
+func DoJSON(ctx context.Context, client *http.Client, method, target string, header http.Header, payload, out any) error {
+	var body io.Reader
+	if payload != nil {
+		encoded, err := json.Marshal(payload)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(encoded)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, body)
+	if err != nil {
+		return err
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	req.Header.Set("Accept", "application/json")
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseBytes))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %d: %s", resp.StatusCode, bytes.TrimSpace(respBody))
+	}
+	if out == nil || len(bytes.TrimSpace(respBody)) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(respBody, out); err != nil {
+		return fmt.Errorf("decode response: %w", err)
+	}
+	return nil
+}
+
+// RetryAfter parses a Retry-After header given in seconds, defaulting to one second.
+func RetryAfter(value string) time.Duration {
+	seconds, err := strconv.Atoi(value)
+	if err != nil || seconds < 0 {
+		return time.Second
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
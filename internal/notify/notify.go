@@ -0,0 +1,71 @@
+// Package notify posts chat notifications about messages to team channels.
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// ErrInvalidNotification is returned when a notification lacks required content.
+var ErrInvalidNotification = errors.New("invalid notification")
+
+// Field is a labelled value rendered alongside the notification text.
+type Field struct {
+	Title string `json:"title"`
+	Value string `json:"value"`
+}
+
+// Notification is a chat message about an email. Channel names the destination
+// for adapters that post to several channels and is ignored by adapters bound
+// to a single incoming webhook. Event names the event reported, such as
+// "sla.breached", for notifications raised by one.
+type Notification struct {
+	Channel   string  `json:"channel,omitempty"`
+	Title     string  `json:"title"`
+	Text      string  `json:"text,omitempty"`
+	Fields    []Field `json:"fields,omitempty"`
+	MessageID string  `json:"messageId,omitempty"`
+	Event     string  `json:"event,omitempty"`
+	Link      string  `json:"link,omitempty"`
+}
+
+// Validate reports whether the notification can be rendered.
+func (n Notification) Validate() error {
+	if strings.TrimSpace(n.Title) == "" {
+		return fmt.Errorf("%w: title is required", ErrInvalidNotification)
+	}
+	return nil
+}
+
+// Notifier delivers notifications to a chat platform.
+type Notifier interface {
+	Notify(ctx context.Context, n Notification) error
+}
+
+// RateLimitError is returned when the platform keeps throttling requests beyond
+// the time an adapter is willing to wait.
+type RateLimitError struct {
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("rate limited, retry after %s", e.RetryAfter)
+}
+
+// Linker builds links from notifications back to messages in the web app.
+type Linker struct {
+	BaseURL string
+}
+
+// MessageURL returns the web app URL of a message, or "" without a base URL.
+func (l Linker) MessageURL(messageID string) string {
+	base := strings.TrimRight(l.BaseURL, "/")
+	if base == "" || messageID == "" {
+		return ""
+	}
+	return base + "/messages/" + url.PathEscape(messageID)
+}
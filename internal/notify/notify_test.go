@@ -0,0 +1,172 @@
+package notify_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/example/iboz/internal/notify"
+	"github.com/example/iboz/internal/notify/adapter/slack"
+	"github.com/example/iboz/internal/notify/adapter/teams"
+)
+
+type request struct {
+	path   string
+	header http.Header
+	body   map[string]interface{}
+}
+
+// stub records JSON requests and answers with the queued responses, then 200 with reply.
+type stub struct {
+	mu        sync.Mutex
+	requests  []request
+	throttled int
+	reply     string
+}
+
+func (s *stub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var body map[string]interface{}
+	json.NewDecoder(r.Body).Decode(&body)
+	s.mu.Lock()
+	s.requests = append(s.requests, request{path: r.URL.Path, header: r.Header.Clone(), body: body})
+	throttle := s.throttled > 0
+	if throttle {
+		s.throttled--
+	}
+	s.mu.Unlock()
+
+	if throttle {
+		w.Header().Set("Retry-After", "0")
+		w.WriteHeader(http.StatusTooManyRequests)
+		return
+	}
+	w.Write([]byte(s.reply))
+}
+
+func newStub(t *testing.T, reply string) (*stub, *httptest.Server) {
+	t.Helper()
+	s := &stub{reply: reply}
+	srv := httptest.NewServer(s)
+	t.Cleanup(srv.Close)
+	return s, srv
+}
+
+func notification() notify.Notification {
+	linker := notify.Linker{BaseURL: "https://iboz.example/"}
+	return notify.Notification{
+		Channel:   "#escalations",
+		Title:     "SLA breached: Contract renewal",
+		Text:      "Reply to <Ada> & team",
+		Fields:    []notify.Field{{Title: "From", Value: "ada@customer.example"}},
+		MessageID: "msg 1",
+		Link:      linker.MessageURL("msg 1"),
+	}
+}
+
+func TestSlackPostMessageRetriesRateLimit(t *testing.T) {
+	s, srv := newStub(t, `{"ok":true}`)
+	s.throttled = 1
+	notifier := slack.NewNotifier(slack.Config{Token: "xoxb-test", BaseURL: srv.URL + "/api/"})
+
+	if err := notifier.Notify(context.Background(), notification()); err != nil {
+		t.Fatalf("notify: %v", err)
+	}
+	if len(s.requests) != 2 {
+		t.Fatalf("expected a retry after the 429, got %d requests", len(s.requests))
+	}
+	req := s.requests[1]
+	if req.path != "/api/chat.postMessage" || req.header.Get("Authorization") != "Bearer xoxb-test" {
+		t.Fatalf("unexpected request: %s %v", req.path, req.header)
+	}
+	if req.body["channel"] != "#escalations" {
+		t.Fatalf("unexpected channel: %v", req.body["channel"])
+	}
+	blocks := req.body["blocks"].([]interface{})
+	if len(blocks) != 3 {
+		t.Fatalf("expected header, section and actions blocks, got %v", blocks)
+	}
+	section := blocks[1].(map[string]interface{})
+	if text := section["text"].(map[string]interface{})["text"]; text != "Reply to &lt;Ada&gt; &amp; team" {
+		t.Fatalf("expected escaped mrkdwn, got %q", text)
+	}
+	button := blocks[2].(map[string]interface{})["elements"].([]interface{})[0].(map[string]interface{})
+	if button["url"] != "https://iboz.example/messages/msg%201" {
+		t.Fatalf("unexpected link: %v", button["url"])
+	}
+}
+
+func TestSlackReportsAPIErrors(t *testing.T) {
+	_, srv := newStub(t, `{"ok":false,"error":"channel_not_found"}`)
+	notifier := slack.NewNotifier(slack.Config{Token: "xoxb-test", BaseURL: srv.URL})
+	if err := notifier.Notify(context.Background(), notification()); err == nil || err.Error() != "slack: chat.postMessage: channel_not_found" {
+		t.Fatalf("expected channel_not_found, got %v", err)
+	}
+
+	msg := notification()
+	msg.Channel = ""
+	if err := notifier.Notify(context.Background(), msg); !errors.Is(err, notify.ErrInvalidNotification) {
+		t.Fatalf("expected ErrInvalidNotification without a channel, got %v", err)
+	}
+}
+
+func TestSlackIncomingWebhook(t *testing.T) {
+	s, srv := newStub(t, "ok")
+	notifier := slack.NewNotifier(slack.Config{WebhookURL: srv.URL + "/services/T000/B000/XXX"})
+	if err := notifier.Notify(context.Background(), notification()); err != nil {
+		t.Fatalf("notify: %v", err)
+	}
+	req := s.requests[0]
+	if req.path != "/services/T000/B000/XXX" || req.header.Get("Authorization") != "" || req.body["channel"] != nil {
+		t.Fatalf("unexpected webhook request: %s %v %v", req.path, req.header, req.body)
+	}
+	if req.body["text"] != "SLA breached: Contract renewal <https://iboz.example/messages/msg%201|Open message>" {
+		t.Fatalf("unexpected fallback text: %v", req.body["text"])
+	}
+}
+
+func TestTeamsAdaptiveCard(t *testing.T) {
+	s, srv := newStub(t, "1")
+	notifier := teams.NewNotifier(teams.Config{WebhookURL: srv.URL + "/default"})
+
+	msg := notification()
+	msg.Channel = srv.URL + "/escalations"
+	if err := notifier.Notify(context.Background(), msg); err != nil {
+		t.Fatalf("notify: %v", err)
+	}
+	req := s.requests[0]
+	if req.path != "/escalations" {
+		t.Fatalf("expected the channel webhook to override the default, got %s", req.path)
+	}
+	attachment := req.body["attachments"].([]interface{})[0].(map[string]interface{})
+	if attachment["contentType"] != "application/vnd.microsoft.card.adaptive" {
+		t.Fatalf("unexpected attachment: %v", attachment)
+	}
+	card := attachment["content"].(map[string]interface{})
+	if card["type"] != "AdaptiveCard" || len(card["body"].([]interface{})) != 3 {
+		t.Fatalf("unexpected card: %v", card)
+	}
+	action := card["actions"].([]interface{})[0].(map[string]interface{})
+	if action["type"] != "Action.OpenUrl" || action["url"] != "https://iboz.example/messages/msg%201" {
+		t.Fatalf("unexpected action: %v", action)
+	}
+}
+
+func TestRateLimitBeyondMaxWaitFails(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "120")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer srv.Close()
+	notifier := teams.NewNotifier(teams.Config{WebhookURL: srv.URL, MaxWait: time.Second})
+
+	err := notifier.Notify(context.Background(), notification())
+	var limited *notify.RateLimitError
+	if !errors.As(err, &limited) || limited.RetryAfter != 2*time.Minute {
+		t.Fatalf("expected a RateLimitError with the server delay, got %v", err)
+	}
+}
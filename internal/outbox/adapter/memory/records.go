@@ -0,0 +1,116 @@
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/example/iboz/internal/outbox"
+)
+
+// Records is an in-memory collection of records keyed by ID whose saves append
+// outbox entries under the same lock. Repositories whose records only need
+// lookups by ID or by a predicate embed it and gain the outbox.Store methods.
+type Records[T any] struct {
+	mu      sync.RWMutex
+	records map[string]T
+	outbox  *Table
+	id      func(T) string
+	clone   func(T) T
+}
+
+// NewRecords builds an empty collection. id returns the key of a record and
+// clone copies the pointers it holds so callers never share state with it.
+func NewRecords[T any](id func(T) string, clone func(T) T) *Records[T] {
+	if id == nil || clone == nil {
+		panic("memory: id and clone functions are required")
+	}
+	return &Records[T]{records: make(map[string]T), outbox: NewTable(), id: id, clone: clone}
+}
+
+// Save inserts or replaces a record and appends effects to the outbox under the same lock.
+func (r *Records[T]) Save(ctx context.Context, record T, effects ...outbox.Entry) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	r.records[r.id(record)] = r.clone(record)
+	r.outbox.Append(effects...)
+	r.mu.Unlock()
+	return nil
+}
+
+// Get returns the record with the supplied identifier if present.
+func (r *Records[T]) Get(ctx context.Context, id string) (*T, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	record, ok := r.records[id]
+	if !ok {
+		return nil, nil
+	}
+	cloned := r.clone(record)
+	return &cloned, nil
+}
+
+// List returns every stored record.
+func (r *Records[T]) List(ctx context.Context) ([]T, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	list := make([]T, 0, len(r.records))
+	for _, record := range r.records {
+		list = append(list, r.clone(record))
+	}
+	return list, nil
+}
+
+// Find returns a record matching match if present.
+func (r *Records[T]) Find(ctx context.Context, match func(T) bool) (*T, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, record := range r.records {
+		if match(record) {
+			cloned := r.clone(record)
+			return &cloned, nil
+		}
+	}
+	return nil, nil
+}
+
+// PendingEntries implements the outbox.Store interface.
+func (r *Records[T]) PendingEntries(ctx context.Context, now time.Time, limit int) ([]outbox.Entry, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.outbox.Pending(now, limit), nil
+}
+
+// UpdateEntry implements the outbox.Store interface.
+func (r *Records[T]) UpdateEntry(ctx context.Context, entry outbox.Entry) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	r.outbox.Update(entry)
+	r.mu.Unlock()
+	return nil
+}
@@ -0,0 +1,141 @@
+// Package memory provides an in-memory outbox table, and a record collection
+// built on it, for embedding in the in-memory repositories that emit side effects.
+package memory
+
+import (
+	"sort"
+	"time"
+
+	"github.com/example/iboz/internal/outbox"
+)
+
+// KeyRetention is how long the key of a delivered entry keeps the same effect
+// from being appended again. Effects are deduplicated by their owners' state as
+// well, so the key only has to outlive retries and concurrent appends.
+const KeyRetention = 7 * 24 * time.Hour
+
+// Table stores outbox entries keyed by ID with a unique index on Key. Pending
+// entries are indexed in insertion order so the relay never scans delivered
+// ones, and delivered entries are pruned; their keys are kept for KeyRetention
+// so the same effect is not appended again. It is not safe for concurrent use:
+// the owning repository guards it with the same lock as its state so both
+// change together.
+type Table struct {
+	entries map[string]outbox.Entry
+	// keys maps the key of every held entry to the zero time, and the key of
+	// a delivered entry to its delivery time.
+	keys      map[string]time.Time
+	pending   []string
+	delivered []deliveredKey
+}
+
+// deliveredKey is a key in the order its entry was delivered.
+type deliveredKey struct {
+	key string
+	at  time.Time
+}
+
+// NewTable builds an empty outbox table.
+func NewTable() *Table {
+	return &Table{entries: make(map[string]outbox.Entry), keys: make(map[string]time.Time)}
+}
+
+// Append inserts entries, skipping any whose Key is already present.
+func (t *Table) Append(entries ...outbox.Entry) {
+	for _, entry := range entries {
+		if _, ok := t.keys[entry.Key]; ok {
+			continue
+		}
+		t.keys[entry.Key] = time.Time{}
+		t.entries[entry.ID] = cloneEntry(entry)
+		if entry.Status == outbox.StatusPending {
+			t.pending = append(t.pending, entry.ID)
+		}
+	}
+}
+
+// Pending returns up to limit pending entries due at now in insertion order.
+func (t *Table) Pending(now time.Time, limit int) []outbox.Entry {
+	var list []outbox.Entry
+	for _, id := range t.pending {
+		if limit > 0 && len(list) == limit {
+			break
+		}
+		if entry := t.entries[id]; !entry.NextAttemptAt.After(now) {
+			list = append(list, cloneEntry(entry))
+		}
+	}
+	return list
+}
+
+// Update replaces a stored entry; unknown entries are ignored. Entries leaving
+// the pending state drop out of the index, and delivered ones are pruned.
+func (t *Table) Update(entry outbox.Entry) {
+	if _, ok := t.entries[entry.ID]; !ok {
+		return
+	}
+	if entry.Status == outbox.StatusPending {
+		t.entries[entry.ID] = cloneEntry(entry)
+		return
+	}
+	for i, id := range t.pending {
+		if id == entry.ID {
+			t.pending = append(t.pending[:i], t.pending[i+1:]...)
+			break
+		}
+	}
+	if entry.Status == outbox.StatusDelivered {
+		delete(t.entries, entry.ID)
+		t.expireKeys(entry)
+		return
+	}
+	t.entries[entry.ID] = cloneEntry(entry)
+}
+
+// expireKeys records the delivery of entry and forgets the keys delivered more
+// than KeyRetention before it.
+func (t *Table) expireKeys(entry outbox.Entry) {
+	at := entry.NextAttemptAt
+	if entry.DeliveredAt != nil {
+		at = *entry.DeliveredAt
+	}
+	t.keys[entry.Key] = at
+	t.delivered = append(t.delivered, deliveredKey{key: entry.Key, at: at})
+
+	cutoff := at.Add(-KeyRetention)
+	expired := 0
+	for _, old := range t.delivered {
+		if !old.at.Before(cutoff) {
+			break
+		}
+		if stored, ok := t.keys[old.key]; ok && stored.Equal(old.at) {
+			delete(t.keys, old.key)
+		}
+		expired++
+	}
+	t.delivered = t.delivered[expired:]
+}
+
+// List returns the entries still held, pending or failed, oldest first.
+func (t *Table) List() []outbox.Entry {
+	list := make([]outbox.Entry, 0, len(t.entries))
+	for _, entry := range t.entries {
+		list = append(list, cloneEntry(entry))
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if !list[i].CreatedAt.Equal(list[j].CreatedAt) {
+			return list[i].CreatedAt.Before(list[j].CreatedAt)
+		}
+		return list[i].ID < list[j].ID
+	})
+	return list
+}
+
+func cloneEntry(entry outbox.Entry) outbox.Entry {
+	entry.Payload = append([]byte(nil), entry.Payload...)
+	if entry.DeliveredAt != nil {
+		v := *entry.DeliveredAt
+		entry.DeliveredAt = &v
+	}
+	return entry
+}
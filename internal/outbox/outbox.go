@@ -0,0 +1,116 @@
+// Package outbox records external side effects in the same write as the state
+// change that causes them and relays them to integrations afterwards, so a crash
+// between the two can neither lose an effect nor apply it without the state.
+package outbox
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Status enumerates the delivery state of an outbox entry.
+type Status string
+
+const (
+	StatusPending   Status = "pending"
+	StatusDelivered Status = "delivered"
+	StatusFailed    Status = "failed"
+)
+
+const (
+	// DefaultMaxAttempts is the number of deliveries tried before an entry fails.
+	DefaultMaxAttempts = 8
+	// DefaultBaseBackoff is the delay before the first retry; it doubles per attempt.
+	DefaultBaseBackoff = 30 * time.Second
+	// DefaultMaxBackoff caps the retry delay.
+	DefaultMaxBackoff = time.Hour
+	// DefaultBatchSize is the number of due entries relayed per store and run.
+	DefaultBatchSize = 50
+)
+
+// ErrInvalidEntry is returned when an entry cannot be built.
+var ErrInvalidEntry = errors.New("invalid outbox entry")
+
+// Entry is an external effect waiting to be delivered. Key identifies the effect
+// for deduplication: a store keeps at most one entry per key. ID is stable across
+// retries and is the idempotency key handed to integrations that support one.
+type Entry struct {
+	ID            string          `json:"id"`
+	Destination   string          `json:"destination"`
+	Key           string          `json:"key"`
+	Payload       json.RawMessage `json:"payload,omitempty"`
+	Status        Status          `json:"status"`
+	Attempts      int             `json:"attempts"`
+	NextAttemptAt time.Time       `json:"nextAttemptAt"`
+	LastError     string          `json:"lastError,omitempty"`
+	CreatedAt     time.Time       `json:"createdAt"`
+	DeliveredAt   *time.Time      `json:"deliveredAt,omitempty"`
+}
+
+// NewEntry builds a pending entry for destination with payload encoded as JSON.
+func NewEntry(destination, key string, payload any, now time.Time) (Entry, error) {
+	if strings.TrimSpace(destination) == "" || strings.TrimSpace(key) == "" {
+		return Entry{}, fmt.Errorf("%w: destination and key are required", ErrInvalidEntry)
+	}
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return Entry{}, fmt.Errorf("%w: %v", ErrInvalidEntry, err)
+	}
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return Entry{}, fmt.Errorf("generate outbox id: %w", err)
+	}
+	now = now.UTC()
+	return Entry{
+		ID:            "out-" + hex.EncodeToString(buf),
+		Destination:   destination,
+		Key:           key,
+		Payload:       raw,
+		Status:        StatusPending,
+		NextAttemptAt: now,
+		CreatedAt:     now,
+	}, nil
+}
+
+// Store is the outbox table of a repository. Entries are appended by the owning
+// repository's own write methods, inside the same transaction as the state they
+// describe; the relay only reads and updates them.
+type Store interface {
+	// PendingEntries returns up to limit pending entries due at now, oldest first.
+	PendingEntries(ctx context.Context, now time.Time, limit int) ([]Entry, error)
+	UpdateEntry(ctx context.Context, entry Entry) error
+}
+
+type entryIDKey struct{}
+
+// WithEntryID returns a context carrying the ID of the entry being delivered.
+func WithEntryID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, entryIDKey{}, id)
+}
+
+// EntryID returns the ID of the entry being delivered, if any, for use as an
+// idempotency key by adapters that only see the decoded effect.
+func EntryID(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(entryIDKey{}).(string)
+	return id, ok && id != ""
+}
+
+// Deliverer performs the effects of one destination. The relay's context carries
+// the entry ID; see EntryID.
+type Deliverer interface {
+	Deliver(ctx context.Context, entry Entry) error
+}
+
+// DelivererFunc adapts a function to the Deliverer interface.
+type DelivererFunc func(ctx context.Context, entry Entry) error
+
+// Deliver implements the Deliverer interface.
+func (f DelivererFunc) Deliver(ctx context.Context, entry Entry) error {
+	return f(ctx, entry)
+}
@@ -0,0 +1,170 @@
+package outbox_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/example/iboz/internal/outbox"
+	"github.com/example/iboz/internal/outbox/adapter/memory"
+	"github.com/example/iboz/internal/testutil"
+)
+
+// store guards a table the way an owning repository would.
+type store struct {
+	mu    sync.Mutex
+	table *memory.Table
+}
+
+func (s *store) append(entries ...outbox.Entry) {
+	s.mu.Lock()
+	s.table.Append(entries...)
+	s.mu.Unlock()
+}
+
+func (s *store) PendingEntries(_ context.Context, now time.Time, limit int) ([]outbox.Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.table.Pending(now, limit), nil
+}
+
+func (s *store) UpdateEntry(_ context.Context, entry outbox.Entry) error {
+	s.mu.Lock()
+	s.table.Update(entry)
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *store) entries() []outbox.Entry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.table.List()
+}
+
+func newEntry(t *testing.T, destination, key string, now time.Time) outbox.Entry {
+	t.Helper()
+	entry, err := outbox.NewEntry(destination, key, map[string]string{"key": key}, now)
+	if err != nil {
+		t.Fatalf("new entry: %v", err)
+	}
+	return entry
+}
+
+func TestRelayDeliversEachKeyOnce(t *testing.T) {
+	ctx := context.Background()
+	clock := testutil.NewClock(time.Date(2025, time.March, 18, 12, 0, 0, 0, time.UTC))
+	s := &store{table: memory.NewTable()}
+	var delivered []string
+	relay := outbox.NewRelay([]outbox.Store{s}, map[string]outbox.Deliverer{
+		"slack": outbox.DelivererFunc(func(ctx context.Context, entry outbox.Entry) error {
+			if id, ok := outbox.EntryID(ctx); !ok || id != entry.ID {
+				t.Errorf("expected the entry ID in the context, got %q", id)
+			}
+			delivered = append(delivered, entry.Key)
+			return nil
+		}),
+	}, clock, outbox.Config{})
+
+	s.append(newEntry(t, "slack", "rule-1:msg-1", clock.Now()), newEntry(t, "slack", "rule-1:msg-2", clock.Now()))
+	// A retried state change writes the same effect again.
+	s.append(newEntry(t, "slack", "rule-1:msg-1", clock.Now()))
+
+	relayed, err := relay.Deliver(ctx)
+	if err != nil || len(relayed) != 2 {
+		t.Fatalf("expected two deliveries, got %+v (%v)", relayed, err)
+	}
+	if _, err := relay.Deliver(ctx); err != nil {
+		t.Fatalf("second relay: %v", err)
+	}
+	if len(delivered) != 2 || delivered[0] != "rule-1:msg-1" || delivered[1] != "rule-1:msg-2" {
+		t.Fatalf("expected each effect once in order, got %v", delivered)
+	}
+	if entries := s.entries(); len(entries) != 0 {
+		t.Fatalf("expected delivered entries to be pruned, got %+v", entries)
+	}
+	// The pruned effect keeps its key, so a late retry is not delivered again.
+	s.append(newEntry(t, "slack", "rule-1:msg-2", clock.Now()))
+	if relayed, err := relay.Deliver(ctx); err != nil || len(relayed) != 0 {
+		t.Fatalf("expected no redelivery of a pruned key, got %+v (%v)", relayed, err)
+	}
+}
+
+func TestDeliveredKeysExpireAfterRetention(t *testing.T) {
+	clock := testutil.NewClock(time.Date(2025, time.March, 18, 12, 0, 0, 0, time.UTC))
+	s := &store{table: memory.NewTable()}
+	var delivered []string
+	relay := outbox.NewRelay([]outbox.Store{s}, map[string]outbox.Deliverer{
+		"slack": outbox.DelivererFunc(func(_ context.Context, entry outbox.Entry) error {
+			delivered = append(delivered, entry.Key)
+			return nil
+		}),
+	}, clock, outbox.Config{})
+
+	s.append(newEntry(t, "slack", "digest:2025-03-18", clock.Now()))
+	testutil.Deliver(t, relay)
+
+	clock.Advance(memory.KeyRetention - time.Hour)
+	s.append(newEntry(t, "slack", "digest:2025-03-18", clock.Now()), newEntry(t, "slack", "digest:2025-03-24", clock.Now()))
+	testutil.Deliver(t, relay)
+
+	clock.Advance(2 * time.Hour)
+	s.append(newEntry(t, "slack", "digest:2025-03-25", clock.Now()))
+	testutil.Deliver(t, relay)
+	// The first key was delivered more than KeyRetention ago and is forgotten.
+	s.append(newEntry(t, "slack", "digest:2025-03-18", clock.Now()), newEntry(t, "slack", "digest:2025-03-24", clock.Now()))
+	testutil.Deliver(t, relay)
+
+	want := []string{"digest:2025-03-18", "digest:2025-03-24", "digest:2025-03-25", "digest:2025-03-18"}
+	if len(delivered) != len(want) {
+		t.Fatalf("expected %v, got %v", want, delivered)
+	}
+	for i := range want {
+		if delivered[i] != want[i] {
+			t.Fatalf("expected %v, got %v", want, delivered)
+		}
+	}
+}
+
+func TestRelayBacksOffThenFails(t *testing.T) {
+	ctx := context.Background()
+	clock := testutil.NewClock(time.Date(2025, time.March, 18, 12, 0, 0, 0, time.UTC))
+	s := &store{table: memory.NewTable()}
+	attempts := 0
+	relay := outbox.NewRelay([]outbox.Store{s}, map[string]outbox.Deliverer{
+		"jira": outbox.DelivererFunc(func(context.Context, outbox.Entry) error {
+			attempts++
+			return errors.New("503 service unavailable")
+		}),
+	}, clock, outbox.Config{MaxAttempts: 3, BaseBackoff: time.Minute, MaxBackoff: 90 * time.Second})
+	s.append(newEntry(t, "jira", "task:msg-1", clock.Now()), newEntry(t, "pager", "page:msg-1", clock.Now()))
+
+	if _, err := relay.Deliver(ctx); err == nil {
+		t.Fatal("expected delivery errors")
+	}
+	for _, step := range []time.Duration{59 * time.Second, time.Second, 90 * time.Second} {
+		clock.Advance(step)
+		relay.Deliver(ctx)
+	}
+	if attempts != 3 {
+		t.Fatalf("expected three attempts after backoff, got %d", attempts)
+	}
+	for _, entry := range s.entries() {
+		if entry.Status != outbox.StatusFailed || entry.Attempts != 3 || entry.LastError == "" {
+			t.Fatalf("expected a failed entry after three attempts, got %+v", entry)
+		}
+	}
+	if got := relay.Backoff(2); got != 90*time.Second {
+		t.Fatalf("expected capped backoff, got %s", got)
+	}
+}
+
+func TestNewEntryValidates(t *testing.T) {
+	if _, err := outbox.NewEntry("", "key", nil, time.Now()); !errors.Is(err, outbox.ErrInvalidEntry) {
+		t.Fatalf("expected ErrInvalidEntry, got %v", err)
+	}
+	if _, err := outbox.NewEntry("slack", "key", func() {}, time.Now()); !errors.Is(err, outbox.ErrInvalidEntry) {
+		t.Fatalf("expected ErrInvalidEntry for unencodable payload, got %v", err)
+	}
+}
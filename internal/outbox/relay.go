@@ -0,0 +1,128 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/example/iboz/internal/email"
+)
+
+// Config tunes delivery retries.
+type Config struct {
+	MaxAttempts int
+	BaseBackoff time.Duration
+	MaxBackoff  time.Duration
+	BatchSize   int
+}
+
+// Relay delivers pending entries from a set of stores to their destinations.
+// An entry is marked delivered only after its deliverer succeeds, so a crash in
+// between redelivers it with the same ID; integrations deduplicate on that ID.
+type Relay struct {
+	stores     []Store
+	deliverers map[string]Deliverer
+	clock      email.Clock
+	cfg        Config
+}
+
+// NewRelay constructs a Relay. deliverers is keyed by destination.
+func NewRelay(stores []Store, deliverers map[string]Deliverer, clock email.Clock, cfg Config) *Relay {
+	if clock == nil {
+		panic("outbox: clock dependency is required")
+	}
+	for i, store := range stores {
+		if store == nil {
+			panic(fmt.Sprintf("outbox: store %d is nil", i))
+		}
+	}
+	registered := make(map[string]Deliverer, len(deliverers))
+	for destination, deliverer := range deliverers {
+		if deliverer == nil {
+			panic(fmt.Sprintf("outbox: deliverer for %q is nil", destination))
+		}
+		registered[destination] = deliverer
+	}
+	if cfg.MaxAttempts < 0 || cfg.BaseBackoff < 0 || cfg.MaxBackoff < 0 || cfg.BatchSize < 0 {
+		panic("outbox: config values cannot be negative")
+	}
+	if cfg.MaxAttempts == 0 {
+		cfg.MaxAttempts = DefaultMaxAttempts
+	}
+	if cfg.BaseBackoff == 0 {
+		cfg.BaseBackoff = DefaultBaseBackoff
+	}
+	if cfg.MaxBackoff == 0 {
+		cfg.MaxBackoff = DefaultMaxBackoff
+	}
+	if cfg.BatchSize == 0 {
+		cfg.BatchSize = DefaultBatchSize
+	}
+	return &Relay{stores: append([]Store(nil), stores...), deliverers: registered, clock: clock, cfg: cfg}
+}
+
+// Deliver attempts every due entry once and returns the entries it updated.
+// Failed deliveries are rescheduled with exponential backoff until
+// Config.MaxAttempts, after which the entry is marked failed.
+func (r *Relay) Deliver(ctx context.Context) ([]Entry, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	var relayed []Entry
+	var errs []error
+	for _, store := range r.stores {
+		entries, err := store.PendingEntries(ctx, r.clock.Now().UTC(), r.cfg.BatchSize)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		for _, entry := range entries {
+			deliverErr := r.deliver(ctx, entry)
+			if errors.Is(deliverErr, context.Canceled) && ctx.Err() != nil {
+				return relayed, errors.Join(append(errs, ctx.Err())...)
+			}
+
+			now := r.clock.Now().UTC()
+			entry.Attempts++
+			if deliverErr == nil {
+				entry.Status = StatusDelivered
+				entry.LastError = ""
+				entry.DeliveredAt = &now
+			} else {
+				entry.LastError = deliverErr.Error()
+				entry.NextAttemptAt = now.Add(r.Backoff(entry.Attempts))
+				if entry.Attempts >= r.cfg.MaxAttempts {
+					entry.Status = StatusFailed
+				}
+				errs = append(errs, fmt.Errorf("outbox: %s %s: %w", entry.Destination, entry.ID, deliverErr))
+			}
+			if err := store.UpdateEntry(ctx, entry); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			relayed = append(relayed, entry)
+		}
+	}
+	return relayed, errors.Join(errs...)
+}
+
+func (r *Relay) deliver(ctx context.Context, entry Entry) error {
+	deliverer, ok := r.deliverers[entry.Destination]
+	if !ok {
+		return fmt.Errorf("no deliverer registered for %q", entry.Destination)
+	}
+	return deliverer.Deliver(WithEntryID(ctx, entry.ID), entry)
+}
+
+// Backoff returns the delay before retrying an entry that failed attempt.
+func (r *Relay) Backoff(attempt int) time.Duration {
+	delay := r.cfg.BaseBackoff
+	for i := 1; i < attempt; i++ {
+		delay *= 2
+		if delay >= r.cfg.MaxBackoff {
+			return r.cfg.MaxBackoff
+		}
+	}
+	return delay
+}
@@ -0,0 +1,35 @@
+// Package periodic runs background jobs at a fixed interval.
+package periodic
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// Every returns a loop that calls fn once straight away and then once per
+// interval until its context is cancelled. Errors are logged under name, such
+// as "snooze: resurface", and do not stop the loop.
+func Every(name string, interval time.Duration, fn func(ctx context.Context) error) func(ctx context.Context) {
+	if fn == nil {
+		panic("periodic: job is required")
+	}
+	if interval <= 0 {
+		panic("periodic: interval must be positive")
+	}
+	return func(ctx context.Context) {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			if err := fn(ctx); err != nil && ctx.Err() == nil {
+				log.Printf("%s failed: %v", name, err)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}
+}
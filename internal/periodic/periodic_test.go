@@ -0,0 +1,38 @@
+package periodic_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/example/iboz/internal/periodic"
+)
+
+func TestEveryRunsUntilCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	calls := make(chan struct{}, 10)
+	run := periodic.Every("test: job", time.Millisecond, func(context.Context) error {
+		calls <- struct{}{}
+		return errors.New("keeps going")
+	})
+
+	done := make(chan struct{})
+	go func() {
+		run(ctx)
+		close(done)
+	}()
+	for i := 0; i < 3; i++ {
+		select {
+		case <-calls:
+		case <-time.After(time.Second):
+			t.Fatalf("expected call %d despite the previous error", i+1)
+		}
+	}
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("expected the loop to stop once cancelled")
+	}
+}
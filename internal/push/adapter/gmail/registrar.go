@@ -0,0 +1,143 @@
+// Package gmail manages Gmail watches publishing mailbox changes to a Cloud
+// Pub/Sub topic.
+package gmail
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/example/iboz/internal/email"
+	"github.com/example/iboz/internal/push"
+)
+
+const (
+	// DefaultBaseURL is the Gmail API root.
+	DefaultBaseURL = "https://gmail.googleapis.com/gmail/v1"
+	defaultTimeout = 10 * time.Second
+)
+
+// DefaultLabelIDs restricts notifications to the inbox.
+var DefaultLabelIDs = []string{"INBOX"}
+
+var _ push.Registrar = (*Registrar)(nil)
+
+// Config configures a Registrar. TopicName is the full Pub/Sub topic, e.g.
+// "projects/acme/topics/gmail", whose push subscription targets
+// /api/email/push/gmail?token=<verification token>.
+type Config struct {
+	BaseURL   string
+	TopicName string
+	LabelIDs  []string
+	Client    *http.Client
+}
+
+// Registrar calls users.watch with the OAuth access token the vault holds for
+// the account. Watches last seven days and are renewed by calling watch again.
+type Registrar struct {
+	cfg   Config
+	vault email.Vault
+}
+
+// NewRegistrar constructs a Gmail Registrar. It panics without a topic.
+func NewRegistrar(cfg Config, vault email.Vault) *Registrar {
+	if cfg.TopicName == "" {
+		panic("gmail: topic name is required")
+	}
+	if vault == nil {
+		panic("gmail: vault dependency is required")
+	}
+	if cfg.BaseURL == "" {
+		cfg.BaseURL = DefaultBaseURL
+	}
+	cfg.BaseURL = strings.TrimRight(cfg.BaseURL, "/")
+	if len(cfg.LabelIDs) == 0 {
+		cfg.LabelIDs = DefaultLabelIDs
+	}
+	if cfg.Client == nil {
+		cfg.Client = &http.Client{Timeout: defaultTimeout}
+	}
+	return &Registrar{cfg: cfg, vault: vault}
+}
+
+type watchResponse struct {
+	HistoryID  string `json:"historyId"`
+	Expiration string `json:"expiration"`
+}
+
+// Subscribe implements the push.Registrar interface.
+func (r *Registrar) Subscribe(ctx context.Context, sub push.Subscription) (push.Registration, error) {
+	var watch watchResponse
+	err := r.post(ctx, sub.Account, "/users/me/watch", map[string]interface{}{
+		"topicName":           r.cfg.TopicName,
+		"labelIds":            r.cfg.LabelIDs,
+		"labelFilterBehavior": "INCLUDE",
+	}, &watch)
+	if err != nil {
+		return push.Registration{}, fmt.Errorf("gmail: watch: %w", err)
+	}
+	millis, err := strconv.ParseInt(watch.Expiration, 10, 64)
+	if err != nil {
+		return push.Registration{}, fmt.Errorf("gmail: watch: invalid expiration %q", watch.Expiration)
+	}
+	return push.Registration{ExpiresAt: time.UnixMilli(millis).UTC(), Cursor: watch.HistoryID}, nil
+}
+
+// Renew implements the push.Registrar interface.
+func (r *Registrar) Renew(ctx context.Context, sub push.Subscription) (push.Registration, error) {
+	return r.Subscribe(ctx, sub)
+}
+
+// Unsubscribe implements the push.Registrar interface.
+func (r *Registrar) Unsubscribe(ctx context.Context, sub push.Subscription) error {
+	if err := r.post(ctx, sub.Account, "/users/me/stop", nil, nil); err != nil {
+		return fmt.Errorf("gmail: stop: %w", err)
+	}
+	return nil
+}
+
+func (r *Registrar) post(ctx context.Context, account, path string, payload, out any) error {
+	token, err := r.vault.Secret(ctx, account)
+	if err != nil {
+		return err
+	}
+	var body io.Reader = http.NoBody
+	if payload != nil {
+		encoded, err := json.Marshal(payload)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(encoded)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.cfg.BaseURL+path, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := r.cfg.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %d: %s", resp.StatusCode, bytes.TrimSpace(respBody))
+	}
+	if out == nil {
+		return nil
+	}
+	return json.Unmarshal(respBody, out)
+}
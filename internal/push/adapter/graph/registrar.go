@@ -0,0 +1,193 @@
+// Package graph manages Microsoft Graph change-notification subscriptions on
+// the connected mailbox.
+package graph
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/example/iboz/internal/email"
+	"github.com/example/iboz/internal/push"
+)
+
+const (
+	// DefaultBaseURL is the Graph API root.
+	DefaultBaseURL = "https://graph.microsoft.com/v1.0"
+	// DefaultResource watches the inbox of the signed-in user.
+	DefaultResource = "me/mailFolders('Inbox')/messages"
+	// DefaultChangeTypes are the changes notified.
+	DefaultChangeTypes = "created,updated"
+	// DefaultLifetime stays within the maximum Graph allows for mail subscriptions.
+	DefaultLifetime = 4200 * time.Minute
+	defaultTimeout  = 10 * time.Second
+)
+
+var _ push.Registrar = (*Registrar)(nil)
+
+// Config configures a Registrar. NotificationURL is the public address of
+// /api/email/push/graph; it also receives lifecycle notifications.
+type Config struct {
+	BaseURL         string
+	NotificationURL string
+	Resource        string
+	ChangeTypes     string
+	Lifetime        time.Duration
+	Client          *http.Client
+}
+
+// Registrar creates Graph subscriptions with the OAuth access token the vault
+// holds for the account.
+type Registrar struct {
+	cfg   Config
+	vault email.Vault
+	clock email.Clock
+}
+
+// NewRegistrar constructs a Graph Registrar. It panics without a notification URL.
+func NewRegistrar(cfg Config, vault email.Vault, clock email.Clock) *Registrar {
+	if cfg.NotificationURL == "" {
+		panic("graph: notification url is required")
+	}
+	if vault == nil {
+		panic("graph: vault dependency is required")
+	}
+	if clock == nil {
+		panic("graph: clock dependency is required")
+	}
+	if cfg.BaseURL == "" {
+		cfg.BaseURL = DefaultBaseURL
+	}
+	cfg.BaseURL = strings.TrimRight(cfg.BaseURL, "/")
+	if cfg.Resource == "" {
+		cfg.Resource = DefaultResource
+	}
+	if cfg.ChangeTypes == "" {
+		cfg.ChangeTypes = DefaultChangeTypes
+	}
+	if cfg.Lifetime == 0 {
+		cfg.Lifetime = DefaultLifetime
+	}
+	if cfg.Client == nil {
+		cfg.Client = &http.Client{Timeout: defaultTimeout}
+	}
+	return &Registrar{cfg: cfg, vault: vault, clock: clock}
+}
+
+type subscription struct {
+	ID                 string    `json:"id"`
+	ExpirationDateTime time.Time `json:"expirationDateTime"`
+}
+
+// Subscribe implements the push.Registrar interface.
+func (r *Registrar) Subscribe(ctx context.Context, sub push.Subscription) (push.Registration, error) {
+	body := map[string]interface{}{
+		"changeType":               r.cfg.ChangeTypes,
+		"notificationUrl":          r.cfg.NotificationURL,
+		"lifecycleNotificationUrl": r.cfg.NotificationURL,
+		"resource":                 r.cfg.Resource,
+		"expirationDateTime":       r.expiration(),
+		"clientState":              sub.ClientState,
+	}
+	var created subscription
+	if err := r.do(ctx, sub.Account, http.MethodPost, "/subscriptions", body, &created); err != nil {
+		return push.Registration{}, fmt.Errorf("graph: create subscription: %w", err)
+	}
+	return push.Registration{ExternalID: created.ID, ExpiresAt: created.ExpirationDateTime}, nil
+}
+
+// Renew implements the push.Registrar interface. A subscription Graph no longer
+// knows is created again.
+func (r *Registrar) Renew(ctx context.Context, sub push.Subscription) (push.Registration, error) {
+	if sub.ExternalID == "" {
+		return r.Subscribe(ctx, sub)
+	}
+	var renewed subscription
+	err := r.do(ctx, sub.Account, http.MethodPatch, "/subscriptions/"+url.PathEscape(sub.ExternalID), map[string]interface{}{
+		"expirationDateTime": r.expiration(),
+	}, &renewed)
+	var status *statusError
+	if errors.As(err, &status) && status.code == http.StatusNotFound {
+		return r.Subscribe(ctx, sub)
+	}
+	if err != nil {
+		return push.Registration{}, fmt.Errorf("graph: renew subscription: %w", err)
+	}
+	return push.Registration{ExternalID: sub.ExternalID, ExpiresAt: renewed.ExpirationDateTime}, nil
+}
+
+// Unsubscribe implements the push.Registrar interface.
+func (r *Registrar) Unsubscribe(ctx context.Context, sub push.Subscription) error {
+	if sub.ExternalID == "" {
+		return nil
+	}
+	err := r.do(ctx, sub.Account, http.MethodDelete, "/subscriptions/"+url.PathEscape(sub.ExternalID), nil, nil)
+	var status *statusError
+	if errors.As(err, &status) && status.code == http.StatusNotFound {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("graph: delete subscription: %w", err)
+	}
+	return nil
+}
+
+func (r *Registrar) expiration() string {
+	return r.clock.Now().UTC().Add(r.cfg.Lifetime).Format(time.RFC3339)
+}
+
+type statusError struct {
+	code int
+	body string
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("unexpected status %d: %s", e.code, e.body)
+}
+
+func (r *Registrar) do(ctx context.Context, account, method, path string, payload, out any) error {
+	token, err := r.vault.Secret(ctx, account)
+	if err != nil {
+		return err
+	}
+	var body io.Reader
+	if payload != nil {
+		encoded, err := json.Marshal(payload)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(encoded)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, r.cfg.BaseURL+path, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := r.cfg.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return &statusError{code: resp.StatusCode, body: string(bytes.TrimSpace(respBody))}
+	}
+	if out == nil {
+		return nil
+	}
+	return json.Unmarshal(respBody, out)
+}
@@ -0,0 +1,86 @@
+package memory
+
+import (
+	"context"
+	"sync"
+
+	"github.com/example/iboz/internal/push"
+)
+
+var _ push.Repository = (*Repository)(nil)
+
+// Repository provides an in-memory implementation of the push.Repository port.
+type Repository struct {
+	mu            sync.RWMutex
+	subscriptions map[string]push.Subscription
+}
+
+// NewRepository builds a new in-memory push subscription repository.
+func NewRepository() *Repository {
+	return &Repository{subscriptions: make(map[string]push.Subscription)}
+}
+
+// Save inserts or replaces a subscription.
+func (r *Repository) Save(ctx context.Context, subscription push.Subscription) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	r.subscriptions[subscription.ID] = clone(subscription)
+	r.mu.Unlock()
+	return nil
+}
+
+// Get returns the subscription with the supplied identifier if present.
+func (r *Repository) Get(ctx context.Context, id string) (*push.Subscription, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	subscription, ok := r.subscriptions[id]
+	if !ok {
+		return nil, nil
+	}
+	cloned := clone(subscription)
+	return &cloned, nil
+}
+
+// List returns every stored subscription.
+func (r *Repository) List(ctx context.Context) ([]push.Subscription, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	list := make([]push.Subscription, 0, len(r.subscriptions))
+	for _, subscription := range r.subscriptions {
+		list = append(list, clone(subscription))
+	}
+	return list, nil
+}
+
+// Delete removes a subscription if present.
+func (r *Repository) Delete(ctx context.Context, id string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	delete(r.subscriptions, id)
+	r.mu.Unlock()
+	return nil
+}
+
+func clone(subscription push.Subscription) push.Subscription {
+	if subscription.RenewedAt != nil {
+		renewed := *subscription.RenewedAt
+		subscription.RenewedAt = &renewed
+	}
+	return subscription
+}
@@ -0,0 +1,93 @@
+// Package push receives provider change notifications (Microsoft Graph and
+// Gmail Pub/Sub), turns them into queued incremental syncs and keeps the
+// provider subscriptions alive through scheduled renewals.
+package push
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+const (
+	// SyncTopic is the queue topic of syncs requested by notifications.
+	SyncTopic = "email.push.sync"
+	// RenewalType is the scheduled action type renewing a subscription.
+	RenewalType = "email.push.renew"
+	// DefaultRenewBefore is how long before expiry a subscription is renewed.
+	DefaultRenewBefore = 12 * time.Hour
+)
+
+// Graph lifecycle events that need action.
+const (
+	LifecycleReauthorizationRequired = "reauthorizationRequired"
+	LifecycleSubscriptionRemoved     = "subscriptionRemoved"
+	LifecycleMissed                  = "missed"
+)
+
+var (
+	// ErrSubscriptionNotFound is returned when a push subscription does not exist.
+	ErrSubscriptionNotFound = errors.New("push subscription not found")
+	// ErrUnsupportedProvider is returned when no registrar serves the configured provider.
+	ErrUnsupportedProvider = errors.New("push notifications are not supported for this provider")
+	// ErrUnauthorized is returned when a notification fails authentication.
+	ErrUnauthorized = errors.New("push notification not authorized")
+	// ErrInvalidNotification is returned when a notification cannot be decoded.
+	ErrInvalidNotification = errors.New("invalid push notification")
+)
+
+// Subscription is a provider watch delivering change notifications for one
+// account. ClientState is the shared secret Graph echoes in every notification;
+// Cursor is the latest Gmail history ID seen. Removed is set when the provider
+// reports that it dropped the watch, so the next renewal creates a new one.
+type Subscription struct {
+	ID          string     `json:"id"`
+	Provider    string     `json:"provider"`
+	Account     string     `json:"account"`
+	ExternalID  string     `json:"externalId,omitempty"`
+	ClientState string     `json:"-"`
+	Cursor      string     `json:"cursor,omitempty"`
+	ExpiresAt   time.Time  `json:"expiresAt"`
+	RenewalID   string     `json:"renewalId,omitempty"`
+	Removed     bool       `json:"removed,omitempty"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	RenewedAt   *time.Time `json:"renewedAt,omitempty"`
+}
+
+// Registration is the provider's view of a subscription after it is created or renewed.
+type Registration struct {
+	ExternalID string
+	ExpiresAt  time.Time
+	Cursor     string
+}
+
+// Registrar creates, extends and removes the watch of one provider.
+type Registrar interface {
+	Subscribe(ctx context.Context, subscription Subscription) (Registration, error)
+	Renew(ctx context.Context, subscription Subscription) (Registration, error)
+	Unsubscribe(ctx context.Context, subscription Subscription) error
+}
+
+// Repository defines the persistence contract for push subscriptions.
+type Repository interface {
+	Save(ctx context.Context, subscription Subscription) error
+	Get(ctx context.Context, id string) (*Subscription, error)
+	List(ctx context.Context) ([]Subscription, error)
+	Delete(ctx context.Context, id string) error
+}
+
+// SyncRequest is the payload of SyncTopic jobs. Cursor and Resource identify the
+// change for providers that sync incrementally. Mailboxes are currently synced
+// in full, so SyncHandler coalesces the jobs of an account instead.
+type SyncRequest struct {
+	Provider       string `json:"provider"`
+	Account        string `json:"account"`
+	SubscriptionID string `json:"subscriptionId"`
+	Cursor         string `json:"cursor,omitempty"`
+	Resource       string `json:"resource,omitempty"`
+}
+
+// RenewalPayload is the payload of RenewalType actions.
+type RenewalPayload struct {
+	SubscriptionID string `json:"subscriptionId"`
+}
@@ -0,0 +1,418 @@
+package push_test
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/example/iboz/internal/calendar"
+	calendarmemory "github.com/example/iboz/internal/calendar/adapter/memory"
+	"github.com/example/iboz/internal/email"
+	emailmemory "github.com/example/iboz/internal/email/adapter/memory"
+	"github.com/example/iboz/internal/push"
+	"github.com/example/iboz/internal/push/adapter/gmail"
+	"github.com/example/iboz/internal/push/adapter/graph"
+	"github.com/example/iboz/internal/push/adapter/memory"
+	"github.com/example/iboz/internal/queue"
+	"github.com/example/iboz/internal/schedule"
+	schedulememory "github.com/example/iboz/internal/schedule/adapter/memory"
+	"github.com/example/iboz/internal/schedule/adapter/renewal"
+	"github.com/example/iboz/internal/testutil"
+)
+
+const account = "ada@example.com"
+
+// recordingQueue captures enqueued jobs instead of running them.
+type recordingQueue struct {
+	mu   sync.Mutex
+	jobs []queue.Message
+}
+
+func (q *recordingQueue) Enqueue(_ context.Context, topic, key string, payload any) (queue.Message, error) {
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return queue.Message{}, err
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	msg := queue.Message{Topic: topic, Key: key, Payload: raw}
+	q.jobs = append(q.jobs, msg)
+	return msg, nil
+}
+
+func (q *recordingQueue) DeadLetters(context.Context) ([]queue.DeadLetter, error) { return nil, nil }
+func (q *recordingQueue) RetryDeadLetter(context.Context, string) (queue.Message, error) {
+	return queue.Message{}, nil
+}
+func (q *recordingQueue) DeleteDeadLetter(context.Context, string) error { return nil }
+
+func (q *recordingQueue) requests(t *testing.T) []push.SyncRequest {
+	t.Helper()
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	var reqs []push.SyncRequest
+	for _, job := range q.jobs {
+		if job.Topic != push.SyncTopic {
+			t.Fatalf("unexpected topic %q", job.Topic)
+		}
+		var req push.SyncRequest
+		if err := json.Unmarshal(job.Payload, &req); err != nil {
+			t.Fatalf("decode job: %v", err)
+		}
+		reqs = append(reqs, req)
+	}
+	return reqs
+}
+
+// provider stubs the Graph subscription and Gmail watch endpoints.
+type provider struct {
+	t     *testing.T
+	clock *testutil.Clock
+
+	mu          sync.Mutex
+	calls       []string
+	clientState string
+	graphGone   bool
+	historyID   string
+}
+
+func (p *provider) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Authorization") != "Bearer oauth-token" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.calls = append(p.calls, r.Method+" "+r.URL.Path)
+	expiry := p.clock.Now().Add(72 * time.Hour)
+	switch {
+	case r.Method == http.MethodPost && r.URL.Path == "/subscriptions":
+		var body map[string]string
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		p.clientState = body["clientState"]
+		if body["notificationUrl"] != "https://iboz.example.com/api/email/push/graph" {
+			p.t.Errorf("notificationUrl = %q", body["notificationUrl"])
+		}
+		p.graphGone = false
+		_ = json.NewEncoder(w).Encode(map[string]string{"id": "graph-sub-1", "expirationDateTime": expiry.Format(time.RFC3339)})
+	case r.Method == http.MethodPatch && strings.HasPrefix(r.URL.Path, "/subscriptions/"):
+		if p.graphGone {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]string{"id": "graph-sub-1", "expirationDateTime": expiry.Format(time.RFC3339)})
+	case r.Method == http.MethodDelete && strings.HasPrefix(r.URL.Path, "/subscriptions/"):
+		w.WriteHeader(http.StatusNoContent)
+	case r.URL.Path == "/users/me/watch":
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"historyId":  p.historyID,
+			"expiration": strconv.FormatInt(expiry.UnixMilli(), 10),
+		})
+	case r.URL.Path == "/users/me/stop":
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (p *provider) recorded() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]string(nil), p.calls...)
+}
+
+type fixture struct {
+	service   *push.Service
+	schedules *schedule.Service
+	queue     *recordingQueue
+	provider  *provider
+	clock     *testutil.Clock
+}
+
+func newFixture(t *testing.T, providerName string) fixture {
+	t.Helper()
+	ctx := context.Background()
+	clock := testutil.NewClock(testutil.Start)
+	stub := &provider{t: t, clock: clock, historyID: "100"}
+	srv := httptest.NewServer(stub)
+	t.Cleanup(srv.Close)
+
+	emails := emailmemory.NewRepository()
+	vault := emailmemory.NewVault()
+	if err := emails.SaveConfig(ctx, email.ProviderConfig{Provider: providerName}); err != nil {
+		t.Fatalf("save config: %v", err)
+	}
+	if err := emails.SaveAuth(ctx, email.AuthRecord{State: email.AuthState{Username: account}}); err != nil {
+		t.Fatalf("save auth: %v", err)
+	}
+	if err := vault.StoreSecret(ctx, account, "oauth-token"); err != nil {
+		t.Fatalf("store secret: %v", err)
+	}
+
+	calendars := calendar.NewService(calendarmemory.NewRepository(), emails, calendar.DefaultHours(), clock)
+	schedules := schedule.NewService(schedulememory.NewRepository(), nil, calendars, clock, schedule.Config{})
+	jobs := &recordingQueue{}
+	service := push.NewService(memory.NewRepository(), emails, map[string]push.Registrar{
+		email.ProviderOutlook: graph.NewRegistrar(graph.Config{
+			BaseURL:         srv.URL,
+			NotificationURL: "https://iboz.example.com/api/email/push/graph",
+		}, vault, clock),
+		email.ProviderGmail: gmail.NewRegistrar(gmail.Config{BaseURL: srv.URL, TopicName: "projects/iboz/topics/gmail"}, vault),
+	}, jobs, schedules, clock, push.Config{GmailToken: "pubsub-token"})
+	schedules.Handle(renewal.Type, renewal.NewHandler(service))
+	return fixture{service: service, schedules: schedules, queue: jobs, provider: stub, clock: clock}
+}
+
+func (f fixture) pendingRenewals(t *testing.T) []schedule.Action {
+	t.Helper()
+	actions, err := f.schedules.List(context.Background(), schedule.StatusScheduled)
+	if err != nil {
+		t.Fatalf("list actions: %v", err)
+	}
+	return actions
+}
+
+func graphBatch(subscriptionID, clientState, lifecycle string) []byte {
+	body, _ := json.Marshal(map[string]interface{}{
+		"value": []map[string]interface{}{{
+			"subscriptionId": subscriptionID,
+			"clientState":    clientState,
+			"changeType":     "created",
+			"resource":       "Users/ada/Messages/AAMk1",
+			"lifecycleEvent": lifecycle,
+			"resourceData":   map[string]string{"id": "AAMk1"},
+		}},
+	})
+	return body
+}
+
+func TestGraphSubscriptionQueuesSyncsAndRenewsOnSchedule(t *testing.T) {
+	f := newFixture(t, email.ProviderOutlook)
+	ctx := context.Background()
+
+	subscription, err := f.service.Subscribe(ctx)
+	if err != nil {
+		t.Fatalf("subscribe: %v", err)
+	}
+	if subscription.ExternalID != "graph-sub-1" || subscription.Account != account {
+		t.Fatalf("unexpected subscription %+v", subscription)
+	}
+	renewals := f.pendingRenewals(t)
+	if len(renewals) != 1 || renewals[0].ID != subscription.RenewalID {
+		t.Fatalf("expected one pending renewal, got %+v", renewals)
+	}
+	if want := subscription.ExpiresAt.Add(-push.DefaultRenewBefore); !renewals[0].RunAt.Equal(want) {
+		t.Fatalf("renewal at %s, want %s", renewals[0].RunAt, want)
+	}
+
+	if _, err := f.service.HandleGraph(ctx, graphBatch("graph-sub-1", "forged", "")); !errors.Is(err, push.ErrUnauthorized) {
+		t.Fatalf("expected unauthorized, got %v", err)
+	}
+	queued, err := f.service.HandleGraph(ctx, graphBatch("graph-sub-1", f.provider.clientState, ""))
+	if err != nil || queued != 1 {
+		t.Fatalf("handle graph: queued %d, %v", queued, err)
+	}
+	if queued, err := f.service.HandleGraph(ctx, graphBatch("someone-else", "x", "")); err != nil || queued != 0 {
+		t.Fatalf("unknown subscriptions should be ignored, got %d, %v", queued, err)
+	}
+	reqs := f.queue.requests(t)
+	if len(reqs) != 1 || reqs[0].Account != account || reqs[0].SubscriptionID != subscription.ID {
+		t.Fatalf("unexpected sync requests %+v", reqs)
+	}
+
+	f.clock.Set(renewals[0].RunAt)
+	if _, err := f.schedules.RunDue(ctx); err != nil {
+		t.Fatalf("run due: %v", err)
+	}
+	renewed, err := f.service.List(ctx)
+	if err != nil || len(renewed) != 1 {
+		t.Fatalf("list: %+v, %v", renewed, err)
+	}
+	if renewed[0].RenewedAt == nil || !renewed[0].ExpiresAt.After(subscription.ExpiresAt) {
+		t.Fatalf("subscription was not renewed: %+v", renewed[0])
+	}
+	next := f.pendingRenewals(t)
+	if len(next) != 1 || next[0].ID != renewed[0].RenewalID {
+		t.Fatalf("expected the next renewal to be scheduled, got %+v", next)
+	}
+}
+
+func TestGraphLifecycleEventsRecreateRemovedSubscriptions(t *testing.T) {
+	f := newFixture(t, email.ProviderOutlook)
+	ctx := context.Background()
+	subscription, err := f.service.Subscribe(ctx)
+	if err != nil {
+		t.Fatalf("subscribe: %v", err)
+	}
+
+	queued, err := f.service.HandleGraph(ctx, graphBatch("graph-sub-1", f.provider.clientState, push.LifecycleSubscriptionRemoved))
+	if err != nil || queued != 0 {
+		t.Fatalf("handle lifecycle: queued %d, %v", queued, err)
+	}
+	renewals := f.pendingRenewals(t)
+	if len(renewals) != 1 || !renewals[0].RunAt.Equal(f.clock.Now()) || renewals[0].ID == subscription.RenewalID {
+		t.Fatalf("expected an immediate renewal, got %+v", renewals)
+	}
+
+	if _, err := f.schedules.RunDue(ctx); err != nil {
+		t.Fatalf("run due: %v", err)
+	}
+	// A removed subscription is created again rather than renewed, and a
+	// later reauthorization renews the new one.
+	if _, err := f.service.HandleGraph(ctx, graphBatch("graph-sub-1", f.provider.clientState, push.LifecycleReauthorizationRequired)); err != nil {
+		t.Fatalf("handle reauthorization: %v", err)
+	}
+	if _, err := f.schedules.RunDue(ctx); err != nil {
+		t.Fatalf("run due: %v", err)
+	}
+	// A renewal that finds the subscription gone without a lifecycle event
+	// falls back to creating it.
+	f.provider.graphGone = true
+	if _, err := f.service.Renew(ctx, subscription.ID); err != nil {
+		t.Fatalf("renew: %v", err)
+	}
+	calls := f.provider.recorded()
+	want := []string{"POST /subscriptions", "POST /subscriptions", "PATCH /subscriptions/graph-sub-1", "PATCH /subscriptions/graph-sub-1", "POST /subscriptions"}
+	if strings.Join(calls, ",") != strings.Join(want, ",") {
+		t.Fatalf("calls = %v, want %v", calls, want)
+	}
+
+	queued, err = f.service.HandleGraph(ctx, graphBatch("graph-sub-1", f.provider.clientState, push.LifecycleMissed))
+	if err != nil || queued != 1 {
+		t.Fatalf("missed notifications should queue a sync, got %d, %v", queued, err)
+	}
+}
+
+func gmailEnvelope(address, historyID string) []byte {
+	data, _ := json.Marshal(map[string]interface{}{"emailAddress": address, "historyId": json.Number(historyID)})
+	body, _ := json.Marshal(map[string]interface{}{
+		"message":      map[string]string{"data": base64.StdEncoding.EncodeToString(data), "messageId": "1"},
+		"subscription": "projects/iboz/subscriptions/gmail-push",
+	})
+	return body
+}
+
+func TestGmailPushQueuesSyncFromLastHistory(t *testing.T) {
+	f := newFixture(t, email.ProviderGmail)
+	ctx := context.Background()
+	subscription, err := f.service.Subscribe(ctx)
+	if err != nil {
+		t.Fatalf("subscribe: %v", err)
+	}
+	if subscription.Cursor != "100" || subscription.ExpiresAt.IsZero() {
+		t.Fatalf("unexpected subscription %+v", subscription)
+	}
+
+	if _, err := f.service.HandleGmail(ctx, "wrong", gmailEnvelope(account, "120")); !errors.Is(err, push.ErrUnauthorized) {
+		t.Fatalf("expected unauthorized, got %v", err)
+	}
+	if _, err := f.service.HandleGmail(ctx, "pubsub-token", []byte(`{"message":{"data":"!!"}}`)); !errors.Is(err, push.ErrInvalidNotification) {
+		t.Fatalf("expected invalid notification, got %v", err)
+	}
+	if queued, err := f.service.HandleGmail(ctx, "pubsub-token", gmailEnvelope("other@example.com", "120")); err != nil || queued != 0 {
+		t.Fatalf("unknown accounts should be ignored, got %d, %v", queued, err)
+	}
+	for _, history := range []string{"120", "110"} {
+		if queued, err := f.service.HandleGmail(ctx, "pubsub-token", gmailEnvelope("ADA@example.com", history)); err != nil || queued != 1 {
+			t.Fatalf("handle gmail %s: queued %d, %v", history, queued, err)
+		}
+	}
+
+	reqs := f.queue.requests(t)
+	if len(reqs) != 2 || reqs[0].Cursor != "100" || reqs[1].Cursor != "120" {
+		t.Fatalf("unexpected sync requests %+v", reqs)
+	}
+	list, err := f.service.List(ctx)
+	if err != nil || len(list) != 1 || list[0].Cursor != "120" {
+		t.Fatalf("cursor should keep the newest history id, got %+v, %v", list, err)
+	}
+}
+
+func TestUnsubscribeStopsWatchAndCancelsRenewal(t *testing.T) {
+	f := newFixture(t, email.ProviderGmail)
+	ctx := context.Background()
+	subscription, err := f.service.Subscribe(ctx)
+	if err != nil {
+		t.Fatalf("subscribe: %v", err)
+	}
+	again, err := f.service.Subscribe(ctx)
+	if err != nil || again.ID != subscription.ID {
+		t.Fatalf("subscribing twice should renew the existing subscription, got %+v, %v", again, err)
+	}
+	if renewals := f.pendingRenewals(t); len(renewals) != 1 {
+		t.Fatalf("expected one pending renewal, got %+v", renewals)
+	}
+
+	if err := f.service.Unsubscribe(ctx, subscription.ID); err != nil {
+		t.Fatalf("unsubscribe: %v", err)
+	}
+	if renewals := f.pendingRenewals(t); len(renewals) != 0 {
+		t.Fatalf("renewal should be cancelled, got %+v", renewals)
+	}
+	if calls := f.provider.recorded(); calls[len(calls)-1] != "POST /users/me/stop" {
+		t.Fatalf("watch was not stopped: %v", calls)
+	}
+	if err := f.service.Unsubscribe(ctx, subscription.ID); !errors.Is(err, push.ErrSubscriptionNotFound) {
+		t.Fatalf("expected not found, got %v", err)
+	}
+}
+
+func TestSubscribeRequiresSupportedProvider(t *testing.T) {
+	f := newFixture(t, "imap")
+	if _, err := f.service.Subscribe(context.Background()); !errors.Is(err, push.ErrUnsupportedProvider) {
+		t.Fatalf("expected unsupported provider, got %v", err)
+	}
+}
+
+// mailbox counts full syncs and records when the last one started.
+type mailbox struct {
+	email.ProviderService
+	clock    email.Clock
+	lastSync time.Time
+	fetches  int
+}
+
+func (m *mailbox) State(context.Context) (email.ServiceState, error) {
+	return email.ServiceState{Auth: &email.AuthState{Username: account}, LastSync: m.lastSync}, nil
+}
+
+func (m *mailbox) FetchEmails(context.Context) ([]email.EmailMessage, error) {
+	m.fetches++
+	m.lastSync = m.clock.Now()
+	return nil, nil
+}
+
+func TestSyncHandlerCoalescesJobsOfAnAccount(t *testing.T) {
+	ctx := context.Background()
+	clock := testutil.NewClock(testutil.Start)
+	box := &mailbox{clock: clock}
+	handler := push.SyncHandler(box)
+	payload, _ := json.Marshal(push.SyncRequest{Provider: email.ProviderOutlook, Account: account})
+
+	burst := clock.Now()
+	clock.Advance(time.Second)
+	for i := 0; i < 3; i++ {
+		if err := handler.Handle(ctx, queue.Message{Topic: push.SyncTopic, Payload: payload, EnqueuedAt: burst}); err != nil {
+			t.Fatalf("handle: %v", err)
+		}
+	}
+	if box.fetches != 1 {
+		t.Fatalf("expected one sync for a burst of notifications, got %d", box.fetches)
+	}
+
+	clock.Advance(time.Second)
+	if err := handler.Handle(ctx, queue.Message{Topic: push.SyncTopic, Payload: payload, EnqueuedAt: clock.Now()}); err != nil {
+		t.Fatalf("handle: %v", err)
+	}
+	if box.fetches != 2 {
+		t.Fatalf("expected a change after the last sync to sync again, got %d", box.fetches)
+	}
+}
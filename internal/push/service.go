@@ -0,0 +1,471 @@
+package push
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/example/iboz/internal/email"
+	"github.com/example/iboz/internal/queue"
+	"github.com/example/iboz/internal/schedule"
+)
+
+// Config tunes push handling.
+type Config struct {
+	// GmailToken is the verification token Pub/Sub push endpoints carry in their
+	// query string; Gmail notifications are rejected when it is empty.
+	GmailToken string
+	// RenewBefore is how long before expiry subscriptions are renewed.
+	RenewBefore time.Duration
+}
+
+// PushService manages provider subscriptions and accepts their notifications.
+type PushService interface {
+	Subscribe(ctx context.Context) (*Subscription, error)
+	List(ctx context.Context) ([]Subscription, error)
+	Unsubscribe(ctx context.Context, id string) error
+	Renew(ctx context.Context, id string) (*Subscription, error)
+	HandleGraph(ctx context.Context, body []byte) (int, error)
+	HandleGmail(ctx context.Context, token string, body []byte) (int, error)
+}
+
+var _ PushService = (*Service)(nil)
+
+// Service registers push subscriptions for the connected account, schedules
+// their renewal and queues a sync for every authenticated notification.
+type Service struct {
+	repo       Repository
+	emails     email.Repository
+	registrars map[string]Registrar
+	queue      queue.QueueService
+	scheduler  schedule.ScheduleService
+	clock      email.Clock
+	cfg        Config
+}
+
+// NewService constructs a push Service. registrars is keyed by email provider.
+func NewService(repo Repository, emails email.Repository, registrars map[string]Registrar, queue queue.QueueService, scheduler schedule.ScheduleService, clock email.Clock, cfg Config) *Service {
+	if repo == nil {
+		panic("push: repository dependency is required")
+	}
+	if emails == nil {
+		panic("push: email repository dependency is required")
+	}
+	if queue == nil {
+		panic("push: queue dependency is required")
+	}
+	if scheduler == nil {
+		panic("push: scheduler dependency is required")
+	}
+	if clock == nil {
+		panic("push: clock dependency is required")
+	}
+	if cfg.RenewBefore < 0 {
+		panic("push: renew before cannot be negative")
+	}
+	if cfg.RenewBefore == 0 {
+		cfg.RenewBefore = DefaultRenewBefore
+	}
+	registered := make(map[string]Registrar, len(registrars))
+	for provider, registrar := range registrars {
+		if registrar == nil {
+			panic(fmt.Sprintf("push: registrar for %q is nil", provider))
+		}
+		registered[provider] = registrar
+	}
+	return &Service{repo: repo, emails: emails, registrars: registered, queue: queue, scheduler: scheduler, clock: clock, cfg: cfg}
+}
+
+// Subscribe watches the configured provider for changes to the authenticated
+// account. An existing subscription for the account is renewed instead.
+func (s *Service) Subscribe(ctx context.Context) (*Subscription, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	cfg, err := s.emails.GetConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if cfg == nil {
+		return nil, email.ErrProviderNotConfigured
+	}
+	auth, err := s.emails.GetAuth(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if auth == nil {
+		return nil, email.ErrProviderNotAuthenticated
+	}
+	registrar, ok := s.registrars[cfg.Provider]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedProvider, cfg.Provider)
+	}
+
+	existing, err := s.repo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, subscription := range existing {
+		if subscription.Provider == cfg.Provider && strings.EqualFold(subscription.Account, auth.State.Username) {
+			return s.Renew(ctx, subscription.ID)
+		}
+	}
+
+	id, err := randomHex(6)
+	if err != nil {
+		return nil, err
+	}
+	clientState, err := randomHex(24)
+	if err != nil {
+		return nil, err
+	}
+	subscription := Subscription{
+		ID:          "push-" + id,
+		Provider:    cfg.Provider,
+		Account:     auth.State.Username,
+		ClientState: clientState,
+		CreatedAt:   s.clock.Now().UTC(),
+	}
+	registration, err := registrar.Subscribe(ctx, subscription)
+	if err != nil {
+		return nil, err
+	}
+	subscription.ExternalID = registration.ExternalID
+	subscription.ExpiresAt = registration.ExpiresAt
+	subscription.Cursor = registration.Cursor
+	if err := s.scheduleRenewal(ctx, &subscription, s.renewalTime(subscription)); err != nil {
+		return nil, err
+	}
+	if err := s.repo.Save(ctx, subscription); err != nil {
+		return nil, err
+	}
+	return &subscription, nil
+}
+
+// List returns every subscription, soonest expiry first.
+func (s *Service) List(ctx context.Context) ([]Subscription, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	list, err := s.repo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ExpiresAt.Before(list[j].ExpiresAt) })
+	return list, nil
+}
+
+// Unsubscribe stops the provider watch, cancels its renewal and forgets it.
+func (s *Service) Unsubscribe(ctx context.Context, id string) error {
+	subscription, err := s.get(ctx, id)
+	if err != nil {
+		return err
+	}
+	if registrar, ok := s.registrars[subscription.Provider]; ok {
+		if err := registrar.Unsubscribe(ctx, *subscription); err != nil {
+			return err
+		}
+	}
+	if err := s.cancelRenewal(ctx, subscription.RenewalID); err != nil {
+		return err
+	}
+	return s.repo.Delete(ctx, id)
+}
+
+// Renew extends a subscription with its provider, or creates it again when the
+// provider removed it, and schedules the next renewal.
+func (s *Service) Renew(ctx context.Context, id string) (*Subscription, error) {
+	subscription, err := s.get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	registrar, ok := s.registrars[subscription.Provider]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedProvider, subscription.Provider)
+	}
+	var registration Registration
+	if subscription.Removed {
+		registration, err = registrar.Subscribe(ctx, *subscription)
+	} else {
+		registration, err = registrar.Renew(ctx, *subscription)
+	}
+	if err != nil {
+		return nil, err
+	}
+	subscription.Removed = false
+	now := s.clock.Now().UTC()
+	if registration.ExternalID != "" {
+		subscription.ExternalID = registration.ExternalID
+	}
+	if subscription.Cursor == "" {
+		subscription.Cursor = registration.Cursor
+	}
+	subscription.ExpiresAt = registration.ExpiresAt
+	subscription.RenewedAt = &now
+	if err := s.scheduleRenewal(ctx, subscription, s.renewalTime(*subscription)); err != nil {
+		return nil, err
+	}
+	if err := s.repo.Save(ctx, *subscription); err != nil {
+		return nil, err
+	}
+	return subscription, nil
+}
+
+// graphNotification is one entry of a Microsoft Graph change notification.
+type graphNotification struct {
+	SubscriptionID string `json:"subscriptionId"`
+	ClientState    string `json:"clientState"`
+	ChangeType     string `json:"changeType"`
+	Resource       string `json:"resource"`
+	LifecycleEvent string `json:"lifecycleEvent"`
+	ResourceData   struct {
+		ID string `json:"id"`
+	} `json:"resourceData"`
+}
+
+// HandleGraph authenticates a batch of Graph change or lifecycle notifications
+// by their client state and returns the number of syncs queued. Notifications
+// for unknown subscriptions are ignored; a wrong client state rejects the batch.
+func (s *Service) HandleGraph(ctx context.Context, body []byte) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	var batch struct {
+		Value []graphNotification `json:"value"`
+	}
+	if err := json.Unmarshal(body, &batch); err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrInvalidNotification, err)
+	}
+	subscriptions, err := s.repo.List(ctx)
+	if err != nil {
+		return 0, err
+	}
+	byExternal := make(map[string]Subscription, len(subscriptions))
+	for _, subscription := range subscriptions {
+		if subscription.Provider == email.ProviderOutlook && subscription.ExternalID != "" {
+			byExternal[subscription.ExternalID] = subscription
+		}
+	}
+	for _, notification := range batch.Value {
+		subscription, ok := byExternal[notification.SubscriptionID]
+		if ok && !hmac.Equal([]byte(notification.ClientState), []byte(subscription.ClientState)) {
+			return 0, ErrUnauthorized
+		}
+	}
+
+	queued := 0
+	for _, notification := range batch.Value {
+		subscription, ok := byExternal[notification.SubscriptionID]
+		if !ok {
+			continue
+		}
+		switch notification.LifecycleEvent {
+		case LifecycleReauthorizationRequired, LifecycleSubscriptionRemoved:
+			if notification.LifecycleEvent == LifecycleSubscriptionRemoved {
+				subscription.Removed = true
+			}
+			if err := s.scheduleRenewal(ctx, &subscription, s.clock.Now().UTC()); err != nil {
+				return queued, err
+			}
+			if err := s.repo.Save(ctx, subscription); err != nil {
+				return queued, err
+			}
+			byExternal[notification.SubscriptionID] = subscription
+			continue
+		case LifecycleMissed:
+			notification.ResourceData.ID = ""
+		case "":
+		default:
+			continue
+		}
+
+		key := ""
+		if notification.ResourceData.ID != "" {
+			key = strings.Join([]string{subscription.Provider, subscription.ID, notification.ChangeType, notification.ResourceData.ID}, ":")
+		}
+		if err := s.enqueue(ctx, key, SyncRequest{
+			Provider:       subscription.Provider,
+			Account:        subscription.Account,
+			SubscriptionID: subscription.ID,
+			Resource:       notification.Resource,
+		}); err != nil {
+			return queued, err
+		}
+		queued++
+	}
+	return queued, nil
+}
+
+// HandleGmail authenticates a Pub/Sub push by its verification token, decodes
+// the Gmail notification it wraps and queues a sync from the last seen history
+// ID. Notifications for unknown accounts are acknowledged and ignored.
+func (s *Service) HandleGmail(ctx context.Context, token string, body []byte) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	if s.cfg.GmailToken == "" || !hmac.Equal([]byte(token), []byte(s.cfg.GmailToken)) {
+		return 0, ErrUnauthorized
+	}
+	var envelope struct {
+		Message struct {
+			Data      string `json:"data"`
+			MessageID string `json:"messageId"`
+		} `json:"message"`
+		Subscription string `json:"subscription"`
+	}
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrInvalidNotification, err)
+	}
+	data, err := base64.StdEncoding.DecodeString(envelope.Message.Data)
+	if err != nil {
+		return 0, fmt.Errorf("%w: message data is not base64", ErrInvalidNotification)
+	}
+	var notification struct {
+		EmailAddress string      `json:"emailAddress"`
+		HistoryID    json.Number `json:"historyId"`
+	}
+	if err := json.Unmarshal(data, &notification); err != nil || notification.EmailAddress == "" {
+		return 0, fmt.Errorf("%w: message data is not a Gmail notification", ErrInvalidNotification)
+	}
+	historyID := notification.HistoryID.String()
+
+	subscriptions, err := s.repo.List(ctx)
+	if err != nil {
+		return 0, err
+	}
+	for _, subscription := range subscriptions {
+		if subscription.Provider != email.ProviderGmail || !strings.EqualFold(subscription.Account, notification.EmailAddress) {
+			continue
+		}
+		key := strings.Join([]string{subscription.Provider, subscription.Account, historyID}, ":")
+		if err := s.enqueue(ctx, key, SyncRequest{
+			Provider:       subscription.Provider,
+			Account:        subscription.Account,
+			SubscriptionID: subscription.ID,
+			Cursor:         subscription.Cursor,
+		}); err != nil {
+			return 0, err
+		}
+		if newerHistory(historyID, subscription.Cursor) {
+			subscription.Cursor = historyID
+			if err := s.repo.Save(ctx, subscription); err != nil {
+				return 1, err
+			}
+		}
+		return 1, nil
+	}
+	return 0, nil
+}
+
+func (s *Service) enqueue(ctx context.Context, key string, req SyncRequest) error {
+	_, err := s.queue.Enqueue(ctx, SyncTopic, key, req)
+	return err
+}
+
+func (s *Service) get(ctx context.Context, id string) (*Subscription, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	subscription, err := s.repo.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if subscription == nil {
+		return nil, ErrSubscriptionNotFound
+	}
+	return subscription, nil
+}
+
+// renewalTime is RenewBefore ahead of expiry, but never in the past.
+func (s *Service) renewalTime(subscription Subscription) time.Time {
+	now := s.clock.Now().UTC()
+	at := subscription.ExpiresAt.Add(-s.cfg.RenewBefore)
+	if at.Before(now) {
+		return now
+	}
+	return at
+}
+
+// scheduleRenewal replaces the pending renewal of subscription with one at runAt.
+func (s *Service) scheduleRenewal(ctx context.Context, subscription *Subscription, runAt time.Time) error {
+	if err := s.cancelRenewal(ctx, subscription.RenewalID); err != nil {
+		return err
+	}
+	payload, err := json.Marshal(RenewalPayload{SubscriptionID: subscription.ID})
+	if err != nil {
+		return err
+	}
+	action, err := s.scheduler.Schedule(ctx, schedule.Request{Type: RenewalType, Payload: payload, RunAt: runAt})
+	if err != nil {
+		return fmt.Errorf("schedule push renewal: %w", err)
+	}
+	subscription.RenewalID = action.ID
+	return nil
+}
+
+// cancelRenewal cancels a pending renewal; one that is running or gone is left alone.
+func (s *Service) cancelRenewal(ctx context.Context, id string) error {
+	if id == "" {
+		return nil
+	}
+	_, err := s.scheduler.Cancel(ctx, id)
+	if errors.Is(err, schedule.ErrNotPending) || errors.Is(err, schedule.ErrActionNotFound) {
+		return nil
+	}
+	return err
+}
+
+// SyncHandler returns a queue Handler that runs a sync for SyncRequest jobs
+// while their account is still the connected one. A sync fetches the whole
+// mailbox, so a job is skipped when a sync started after it was enqueued; a
+// burst of notifications costs one sync rather than one each.
+func SyncHandler(emails email.ProviderService) queue.Handler {
+	if emails == nil {
+		panic("push: email service dependency is required")
+	}
+	return queue.HandlerFunc(func(ctx context.Context, msg queue.Message) error {
+		var req SyncRequest
+		if err := json.Unmarshal(msg.Payload, &req); err != nil {
+			return fmt.Errorf("decode push sync: %w", err)
+		}
+		state, err := emails.State(ctx)
+		if err != nil {
+			return err
+		}
+		if state.Auth == nil || !strings.EqualFold(state.Auth.Username, req.Account) {
+			return nil
+		}
+		if !msg.EnqueuedAt.IsZero() && !state.LastSync.Before(msg.EnqueuedAt) {
+			return nil
+		}
+		_, err = emails.FetchEmails(ctx)
+		return err
+	})
+}
+
+// newerHistory reports whether Gmail history ID candidate is after current.
+func newerHistory(candidate, current string) bool {
+	next, err := strconv.ParseUint(candidate, 10, 64)
+	if err != nil {
+		return false
+	}
+	previous, err := strconv.ParseUint(current, 10, 64)
+	return err != nil || next > previous
+}
+
+func randomHex(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("generate push id: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,97 @@
+package inproc
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/example/iboz/internal/queue"
+)
+
+// DefaultBuffer is the number of messages a topic holds before Publish blocks.
+const DefaultBuffer = 1024
+
+var _ queue.Broker = (*Broker)(nil)
+
+// Broker is an embedded, in-process broker backed by one buffered channel per
+// topic. Every subscriber of a topic competes for its messages, whatever the
+// group, and messages published before the first subscriber are kept. Nothing
+// survives the process.
+type Broker struct {
+	mu     sync.Mutex
+	buffer int
+	topics map[string]chan queue.Delivery
+}
+
+// NewBroker constructs an in-process Broker. A non-positive buffer uses DefaultBuffer.
+func NewBroker(buffer int) *Broker {
+	if buffer <= 0 {
+		buffer = DefaultBuffer
+	}
+	return &Broker{buffer: buffer, topics: make(map[string]chan queue.Delivery)}
+}
+
+// Publish implements the queue.Broker interface. It blocks while the topic is
+// full until ctx is done.
+func (b *Broker) Publish(ctx context.Context, msg queue.Message) error {
+	select {
+	case b.topic(msg.Topic) <- delivery{broker: b, msg: msg}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Subscribe implements the queue.Broker interface.
+func (b *Broker) Subscribe(topic, _ string) (queue.Subscription, error) {
+	return subscription{deliveries: b.topic(topic)}, nil
+}
+
+func (b *Broker) topic(name string) chan queue.Delivery {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	ch, ok := b.topics[name]
+	if !ok {
+		ch = make(chan queue.Delivery, b.buffer)
+		b.topics[name] = ch
+	}
+	return ch
+}
+
+type delivery struct {
+	broker *Broker
+	msg    queue.Message
+}
+
+func (d delivery) Message() queue.Message {
+	return d.msg
+}
+
+// Ack is a no-op: a delivered message has already left its topic.
+func (d delivery) Ack() error {
+	return nil
+}
+
+// Nak puts the message back on its topic after delay as its next attempt.
+func (d delivery) Nak(delay time.Duration) error {
+	msg := d.msg
+	msg.Attempt++
+	time.AfterFunc(delay, func() {
+		d.broker.topic(msg.Topic) <- delivery{broker: d.broker, msg: msg}
+	})
+	return nil
+}
+
+type subscription struct {
+	deliveries chan queue.Delivery
+}
+
+func (s subscription) Deliveries() <-chan queue.Delivery {
+	return s.deliveries
+}
+
+// Close is a no-op: the topic channel is shared and stays open for other subscribers.
+func (s subscription) Close() error {
+	return nil
+}
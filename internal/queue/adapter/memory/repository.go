@@ -0,0 +1,123 @@
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/example/iboz/internal/queue"
+)
+
+var _ queue.Repository = (*Repository)(nil)
+
+type keyState struct {
+	done bool
+	at   time.Time
+}
+
+// Repository provides an in-memory implementation of the queue.Repository port.
+type Repository struct {
+	mu      sync.Mutex
+	keys    map[string]keyState
+	letters map[string]queue.DeadLetter
+}
+
+// NewRepository builds a new in-memory queue repository.
+func NewRepository() *Repository {
+	return &Repository{keys: make(map[string]keyState), letters: make(map[string]queue.DeadLetter)}
+}
+
+// Begin marks key in progress unless it is already running or completed after notBefore.
+func (r *Repository) Begin(ctx context.Context, key string, at, notBefore time.Time) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if state, ok := r.keys[key]; ok && (!state.done || !state.at.Before(notBefore)) {
+		return false, nil
+	}
+	r.keys[key] = keyState{at: at}
+	return true, nil
+}
+
+// Finish completes key or releases it for a retry.
+func (r *Repository) Finish(ctx context.Context, key string, succeeded bool, at time.Time) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !succeeded {
+		delete(r.keys, key)
+		return nil
+	}
+	r.keys[key] = keyState{done: true, at: at}
+	return nil
+}
+
+// SaveDeadLetter stores a dead letter keyed by its message ID.
+func (r *Repository) SaveDeadLetter(ctx context.Context, letter queue.DeadLetter) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	r.letters[letter.Message.ID] = clone(letter)
+	r.mu.Unlock()
+	return nil
+}
+
+// GetDeadLetter returns the dead letter of a message if present.
+func (r *Repository) GetDeadLetter(ctx context.Context, id string) (*queue.DeadLetter, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	letter, ok := r.letters[id]
+	if !ok {
+		return nil, nil
+	}
+	cloned := clone(letter)
+	return &cloned, nil
+}
+
+// ListDeadLetters returns every dead letter.
+func (r *Repository) ListDeadLetters(ctx context.Context) ([]queue.DeadLetter, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	list := make([]queue.DeadLetter, 0, len(r.letters))
+	for _, letter := range r.letters {
+		list = append(list, clone(letter))
+	}
+	return list, nil
+}
+
+// DeleteDeadLetter removes a dead letter if present.
+func (r *Repository) DeleteDeadLetter(ctx context.Context, id string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	delete(r.letters, id)
+	r.mu.Unlock()
+	return nil
+}
+
+func clone(letter queue.DeadLetter) queue.DeadLetter {
+	letter.Message.Payload = append([]byte(nil), letter.Message.Payload...)
+	return letter
+}
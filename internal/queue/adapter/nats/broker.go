@@ -0,0 +1,290 @@
+// Package nats implements the queue.Broker port over NATS JetStream. Jobs are
+// stored in a work-queue stream until a worker acknowledges them, so jobs
+// published while no worker is subscribed, or while a connection is being
+// re-established, are kept. Each topic has one durable consumer per group,
+// shared by the workers of that group.
+package nats
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+
+	"github.com/example/iboz/internal/queue"
+)
+
+const (
+	// DefaultSubjectPrefix namespaces job subjects.
+	DefaultSubjectPrefix = "iboz.jobs."
+	// DefaultStream is the JetStream stream holding jobs.
+	DefaultStream = "IBOZ_JOBS"
+	// DefaultAckWait is how long the server waits for a delivery to be settled
+	// before redelivering it. Deliveries held by a worker are kept alive.
+	DefaultAckWait = 30 * time.Second
+	// DefaultReconnectWait is the delay between reconnect attempts.
+	DefaultReconnectWait = time.Second
+	defaultTimeout       = 5 * time.Second
+	fetchWait            = time.Second
+)
+
+var _ queue.Broker = (*Broker)(nil)
+
+// Config configures a Broker.
+type Config struct {
+	// URL is a nats:// URL; credentials in its user info are used to connect.
+	URL           string
+	Name          string
+	SubjectPrefix string
+	Stream        string
+	AckWait       time.Duration
+	Timeout       time.Duration
+	ReconnectWait time.Duration
+}
+
+// Broker is a JetStream client. The connection is re-established until Close.
+type Broker struct {
+	cfg    Config
+	conn   *nats.Conn
+	js     jetstream.JetStream
+	stream string
+}
+
+// Dial connects to the server at cfg.URL and creates or updates the job stream.
+func Dial(cfg Config) (*Broker, error) {
+	if cfg.SubjectPrefix == "" {
+		cfg.SubjectPrefix = DefaultSubjectPrefix
+	}
+	if cfg.Stream == "" {
+		cfg.Stream = DefaultStream
+	}
+	if cfg.AckWait <= 0 {
+		cfg.AckWait = DefaultAckWait
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultTimeout
+	}
+	if cfg.ReconnectWait <= 0 {
+		cfg.ReconnectWait = DefaultReconnectWait
+	}
+	if !strings.HasPrefix(cfg.URL, "nats://") {
+		return nil, fmt.Errorf("nats: invalid url %q", cfg.URL)
+	}
+
+	conn, err := nats.Connect(cfg.URL,
+		nats.Name(cfg.Name),
+		nats.Timeout(cfg.Timeout),
+		nats.ReconnectWait(cfg.ReconnectWait),
+		nats.MaxReconnects(-1),
+		nats.RetryOnFailedConnect(false),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("nats: connect: %w", err)
+	}
+	js, err := jetstream.New(conn)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("nats: jetstream: %w", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Timeout)
+	defer cancel()
+	_, err = js.CreateOrUpdateStream(ctx, jetstream.StreamConfig{
+		Name:      cfg.Stream,
+		Subjects:  []string{cfg.SubjectPrefix + ">"},
+		Retention: jetstream.WorkQueuePolicy,
+		Storage:   jetstream.FileStorage,
+	})
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("nats: create stream %s: %w", cfg.Stream, err)
+	}
+	return &Broker{cfg: cfg, conn: conn, js: js, stream: cfg.Stream}, nil
+}
+
+// Publish implements the queue.Broker interface. It returns once the server
+// has stored the message.
+func (b *Broker) Publish(ctx context.Context, msg queue.Message) error {
+	if b.conn.IsClosed() {
+		return queue.ErrClosed
+	}
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("nats: encode message: %w", err)
+	}
+	if _, err := b.js.Publish(ctx, b.cfg.SubjectPrefix+msg.Topic, data); err != nil {
+		if errors.Is(err, nats.ErrConnectionClosed) {
+			return queue.ErrClosed
+		}
+		return fmt.Errorf("nats: publish %s: %w", msg.ID, err)
+	}
+	return nil
+}
+
+// Subscribe implements the queue.Broker interface. Subscribers of the same
+// topic and group bind to the same durable consumer and split its messages.
+// Only one group may consume a topic, as the stream is a work queue.
+func (b *Broker) Subscribe(topic, group string) (queue.Subscription, error) {
+	if b.conn.IsClosed() {
+		return nil, queue.ErrClosed
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), b.cfg.Timeout)
+	defer cancel()
+	consumer, err := b.js.CreateOrUpdateConsumer(ctx, b.stream, jetstream.ConsumerConfig{
+		Durable:       durableName(group, topic),
+		FilterSubject: b.cfg.SubjectPrefix + topic,
+		AckPolicy:     jetstream.AckExplicitPolicy,
+		AckWait:       b.cfg.AckWait,
+		// The queue service decides when a message is dead-lettered.
+		MaxDeliver: -1,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("nats: create consumer for %s: %w", topic, err)
+	}
+	sub := &subscription{
+		broker:     b,
+		consumer:   consumer,
+		deliveries: make(chan queue.Delivery),
+		closing:    make(chan struct{}),
+	}
+	go sub.fetch()
+	return sub, nil
+}
+
+// Close drops the connection. Unsettled deliveries are redelivered by the
+// server once their ack wait expires.
+func (b *Broker) Close() {
+	b.conn.Close()
+}
+
+// durableName turns a group and topic into a consumer name, which may not
+// contain dots or wildcards.
+func durableName(group, topic string) string {
+	return strings.NewReplacer(".", "_", "*", "_", ">", "_", " ", "_").Replace(group + "_" + topic)
+}
+
+type subscription struct {
+	broker     *Broker
+	consumer   jetstream.Consumer
+	deliveries chan queue.Delivery
+	closing    chan struct{}
+	closeOnce  sync.Once
+}
+
+func (s *subscription) Deliveries() <-chan queue.Delivery {
+	return s.deliveries
+}
+
+// Close stops fetching. A message fetched but not yet taken is handed back.
+func (s *subscription) Close() error {
+	s.closeOnce.Do(func() { close(s.closing) })
+	return nil
+}
+
+// fetch pulls one message at a time, so messages are only leased once a
+// worker is ready for them, until the subscription or connection is closed.
+func (s *subscription) fetch() {
+	defer close(s.deliveries)
+	for {
+		select {
+		case <-s.closing:
+			return
+		default:
+		}
+		msg, err := s.consumer.Next(jetstream.FetchMaxWait(fetchWait))
+		switch {
+		case err == nil:
+		case s.broker.conn.IsClosed():
+			return
+		case errors.Is(err, nats.ErrTimeout):
+			continue
+		default:
+			log.Printf("nats: fetch failed: %v", err)
+			select {
+			case <-s.closing:
+				return
+			case <-time.After(s.broker.cfg.ReconnectWait):
+			}
+			continue
+		}
+
+		d, err := newDelivery(msg, s.broker.cfg.AckWait)
+		if err != nil {
+			log.Printf("nats: dropping undecodable message: %v", err)
+			_ = msg.Term()
+			continue
+		}
+		select {
+		case s.deliveries <- d:
+		case <-s.closing:
+			_ = d.Nak(0)
+			return
+		}
+	}
+}
+
+// delivery is a fetched message. Until it is settled, it is reported as in
+// progress so the server does not redeliver it to another worker.
+type delivery struct {
+	msg     jetstream.Msg
+	message queue.Message
+	settled chan struct{}
+	once    sync.Once
+}
+
+func newDelivery(msg jetstream.Msg, ackWait time.Duration) (*delivery, error) {
+	var message queue.Message
+	if err := json.Unmarshal(msg.Data(), &message); err != nil {
+		return nil, err
+	}
+	meta, err := msg.Metadata()
+	if err != nil {
+		return nil, err
+	}
+	// Redeliveries of the same stored message count as further attempts.
+	message.Attempt += int(meta.NumDelivered) - 1
+
+	d := &delivery{msg: msg, message: message, settled: make(chan struct{})}
+	go d.keepAlive(ackWait / 2)
+	return d, nil
+}
+
+func (d *delivery) keepAlive(every time.Duration) {
+	ticker := time.NewTicker(every)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-d.settled:
+			return
+		case <-ticker.C:
+			_ = d.msg.InProgress()
+		}
+	}
+}
+
+func (d *delivery) Message() queue.Message {
+	return d.message
+}
+
+func (d *delivery) Ack() error {
+	d.once.Do(func() { close(d.settled) })
+	return settleErr(d.msg.Ack())
+}
+
+func (d *delivery) Nak(delay time.Duration) error {
+	d.once.Do(func() { close(d.settled) })
+	return settleErr(d.msg.NakWithDelay(delay))
+}
+
+func settleErr(err error) error {
+	if errors.Is(err, nats.ErrConnectionClosed) {
+		return queue.ErrClosed
+	}
+	return err
+}
@@ -0,0 +1,99 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+const (
+	// DefaultConcurrency bounds the number of messages handled at once.
+	DefaultConcurrency = 4
+	// DefaultMaxAttempts is the number of deliveries before a message is dead-lettered.
+	DefaultMaxAttempts = 5
+	// DefaultBaseBackoff is the delay before the first retry; it doubles per attempt.
+	DefaultBaseBackoff = time.Second
+	// DefaultMaxBackoff caps the retry delay.
+	DefaultMaxBackoff = 5 * time.Minute
+	// DefaultIdempotencyWindow is how long a completed key suppresses duplicates.
+	DefaultIdempotencyWindow = 24 * time.Hour
+)
+
+var (
+	// ErrDeadLetterNotFound is returned when a dead letter does not exist.
+	ErrDeadLetterNotFound = errors.New("dead letter not found")
+	// ErrInvalidMessage is returned when a message cannot be enqueued.
+	ErrInvalidMessage = errors.New("invalid queue message")
+	// ErrClosed is returned when using a closed broker.
+	ErrClosed = errors.New("queue broker closed")
+)
+
+// Message is a job travelling through the broker. Messages sharing a Key are
+// handled at most once successfully within the idempotency window.
+type Message struct {
+	ID         string          `json:"id"`
+	Topic      string          `json:"topic"`
+	Key        string          `json:"key,omitempty"`
+	Payload    json.RawMessage `json:"payload,omitempty"`
+	Attempt    int             `json:"attempt"`
+	EnqueuedAt time.Time       `json:"enqueuedAt"`
+}
+
+// DeadLetter is a message that exhausted its retries.
+type DeadLetter struct {
+	Message  Message   `json:"message"`
+	Error    string    `json:"error"`
+	FailedAt time.Time `json:"failedAt"`
+}
+
+// Handler processes messages of one topic.
+type Handler interface {
+	Handle(ctx context.Context, msg Message) error
+}
+
+// HandlerFunc adapts a function to the Handler interface.
+type HandlerFunc func(ctx context.Context, msg Message) error
+
+// Handle implements the Handler interface.
+func (f HandlerFunc) Handle(ctx context.Context, msg Message) error {
+	return f(ctx, msg)
+}
+
+// Delivery is a message handed to a worker pool. It is settled by exactly one
+// call to Ack or Nak.
+type Delivery interface {
+	Message() Message
+	// Ack removes the message from the broker once it is handled or dead-lettered.
+	Ack() error
+	// Nak hands the message back to be delivered again after delay, as its next
+	// attempt.
+	Nak(delay time.Duration) error
+}
+
+// Subscription delivers the messages of one topic until closed.
+type Subscription interface {
+	Deliveries() <-chan Delivery
+	Close() error
+}
+
+// Broker transports messages between producers and worker pools. Subscribers
+// sharing a group split the messages of a topic between them. The broker owns
+// redelivery: a message is kept until it is acknowledged.
+type Broker interface {
+	Publish(ctx context.Context, msg Message) error
+	Subscribe(topic, group string) (Subscription, error)
+}
+
+// Repository stores idempotency keys and dead letters.
+type Repository interface {
+	// Begin marks key in progress and reports false when the key is already in
+	// progress or completed after notBefore.
+	Begin(ctx context.Context, key string, at, notBefore time.Time) (bool, error)
+	// Finish completes key, or releases it for a retry when succeeded is false.
+	Finish(ctx context.Context, key string, succeeded bool, at time.Time) error
+	SaveDeadLetter(ctx context.Context, letter DeadLetter) error
+	GetDeadLetter(ctx context.Context, id string) (*DeadLetter, error)
+	ListDeadLetters(ctx context.Context) ([]DeadLetter, error)
+	DeleteDeadLetter(ctx context.Context, id string) error
+}
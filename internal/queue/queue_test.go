@@ -0,0 +1,533 @@
+package queue_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	natsserver "github.com/nats-io/nats-server/v2/server"
+
+	"github.com/example/iboz/internal/email"
+	"github.com/example/iboz/internal/queue"
+	"github.com/example/iboz/internal/queue/adapter/inproc"
+	"github.com/example/iboz/internal/queue/adapter/memory"
+	"github.com/example/iboz/internal/queue/adapter/nats"
+	"github.com/example/iboz/internal/testutil"
+)
+
+func newClock() *testutil.Clock {
+	return testutil.NewClock(time.Date(2025, time.March, 18, 12, 0, 0, 0, time.UTC))
+}
+
+// runService starts the worker pool and stops it when the test ends.
+func runService(t *testing.T, svc *queue.Service) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		svc.Run(ctx)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+}
+
+func eventually(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+type recorder struct {
+	mu       sync.Mutex
+	attempts []int
+	fail     int
+}
+
+func (r *recorder) Handle(_ context.Context, msg queue.Message) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.attempts = append(r.attempts, msg.Attempt)
+	if len(r.attempts) <= r.fail {
+		return errors.New("provider unavailable")
+	}
+	return nil
+}
+
+func (r *recorder) calls() []int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]int(nil), r.attempts...)
+}
+
+func TestBackoffDoublesUpToMax(t *testing.T) {
+	svc := queue.NewService(inproc.NewBroker(0), memory.NewRepository(), newClock(), queue.Config{
+		BaseBackoff: time.Second,
+		MaxBackoff:  10 * time.Second,
+	})
+	for attempt, want := range map[int]time.Duration{1: time.Second, 2: 2 * time.Second, 4: 8 * time.Second, 5: 10 * time.Second, 30: 10 * time.Second} {
+		if got := svc.Backoff(attempt); got != want {
+			t.Fatalf("backoff(%d) = %s, want %s", attempt, got, want)
+		}
+	}
+}
+
+func TestFailingJobIsRetriedThenDeadLettered(t *testing.T) {
+	svc := queue.NewService(inproc.NewBroker(0), memory.NewRepository(), newClock(), queue.Config{
+		MaxAttempts: 3,
+		BaseBackoff: time.Millisecond,
+	})
+	handler := &recorder{fail: 100}
+	svc.Handle("classify", handler)
+	runService(t, svc)
+
+	msg, err := svc.Enqueue(context.Background(), "classify", "", map[string]string{"messageId": "msg-1"})
+	if err != nil {
+		t.Fatalf("enqueue: %v", err)
+	}
+
+	var letters []queue.DeadLetter
+	eventually(t, "dead letter", func() bool {
+		letters, _ = svc.DeadLetters(context.Background())
+		return len(letters) == 1
+	})
+	if got := handler.calls(); len(got) != 3 || got[0] != 1 || got[2] != 3 {
+		t.Fatalf("expected attempts 1..3, got %v", got)
+	}
+	if letters[0].Message.ID != msg.ID || letters[0].Error != "provider unavailable" {
+		t.Fatalf("unexpected dead letter: %+v", letters[0])
+	}
+
+	retried, err := svc.RetryDeadLetter(context.Background(), msg.ID)
+	if err != nil {
+		t.Fatalf("retry dead letter: %v", err)
+	}
+	if retried.Attempt != 1 {
+		t.Fatalf("expected a fresh attempt budget, got %d", retried.Attempt)
+	}
+	eventually(t, "second dead letter", func() bool {
+		letters, _ = svc.DeadLetters(context.Background())
+		return len(handler.calls()) == 6 && len(letters) == 1
+	})
+	if err := svc.DeleteDeadLetter(context.Background(), msg.ID); err != nil {
+		t.Fatalf("delete dead letter: %v", err)
+	}
+	if err := svc.DeleteDeadLetter(context.Background(), msg.ID); !errors.Is(err, queue.ErrDeadLetterNotFound) {
+		t.Fatalf("expected ErrDeadLetterNotFound, got %v", err)
+	}
+}
+
+func TestTransientFailureSucceedsOnRetry(t *testing.T) {
+	svc := queue.NewService(inproc.NewBroker(0), memory.NewRepository(), newClock(), queue.Config{BaseBackoff: time.Millisecond})
+	handler := &recorder{fail: 2}
+	svc.Handle("classify", handler)
+	runService(t, svc)
+
+	if _, err := svc.Enqueue(context.Background(), "classify", "classify:msg-1", nil); err != nil {
+		t.Fatalf("enqueue: %v", err)
+	}
+	eventually(t, "successful retry", func() bool { return len(handler.calls()) == 3 })
+	letters, _ := svc.DeadLetters(context.Background())
+	if len(letters) != 0 {
+		t.Fatalf("expected no dead letters, got %+v", letters)
+	}
+}
+
+func TestIdempotencyKeySuppressesDuplicates(t *testing.T) {
+	svc := queue.NewService(inproc.NewBroker(0), memory.NewRepository(), newClock(), queue.Config{})
+	handler := &recorder{}
+	svc.Handle("classify", handler)
+
+	for i := 0; i < 3; i++ {
+		if _, err := svc.Enqueue(context.Background(), "classify", "classify:msg-1", nil); err != nil {
+			t.Fatalf("enqueue: %v", err)
+		}
+	}
+	if _, err := svc.Enqueue(context.Background(), "classify", "classify:msg-2", nil); err != nil {
+		t.Fatalf("enqueue: %v", err)
+	}
+	runService(t, svc)
+
+	eventually(t, "both keys handled", func() bool { return len(handler.calls()) >= 2 })
+	time.Sleep(20 * time.Millisecond)
+	if got := handler.calls(); len(got) != 2 {
+		t.Fatalf("expected one run per key, got %d", len(got))
+	}
+}
+
+func TestRunBoundsConcurrency(t *testing.T) {
+	svc := queue.NewService(inproc.NewBroker(0), memory.NewRepository(), newClock(), queue.Config{Concurrency: 2})
+
+	var mu sync.Mutex
+	running, peak, total := 0, 0, 0
+	release := make(chan struct{})
+	svc.Handle("llm", queue.HandlerFunc(func(ctx context.Context, _ queue.Message) error {
+		mu.Lock()
+		running++
+		if running > peak {
+			peak = running
+		}
+		mu.Unlock()
+		<-release
+		mu.Lock()
+		running--
+		total++
+		mu.Unlock()
+		return nil
+	}))
+	for i := 0; i < 6; i++ {
+		if _, err := svc.Enqueue(context.Background(), "llm", "", nil); err != nil {
+			t.Fatalf("enqueue: %v", err)
+		}
+	}
+	runService(t, svc)
+
+	eventually(t, "pool saturation", func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return running == 2
+	})
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	eventually(t, "all jobs", func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return total == 6
+	})
+	if peak != 2 {
+		t.Fatalf("expected at most two jobs in flight, saw %d", peak)
+	}
+}
+
+func TestHandleInOrderRunsOneMessageAtATime(t *testing.T) {
+	svc := queue.NewService(inproc.NewBroker(0), memory.NewRepository(), newClock(), queue.Config{
+		Concurrency: 4,
+		BaseBackoff: time.Millisecond,
+	})
+
+	var mu sync.Mutex
+	var order []string
+	running, peak := 0, 0
+	failed := false
+	svc.HandleInOrder("sla.sync", queue.HandlerFunc(func(_ context.Context, msg queue.Message) error {
+		mu.Lock()
+		running++
+		if running > peak {
+			peak = running
+		}
+		mu.Unlock()
+		time.Sleep(2 * time.Millisecond)
+		mu.Lock()
+		defer mu.Unlock()
+		running--
+		if string(msg.Payload) == `"first"` && !failed {
+			failed = true
+			return errors.New("store unavailable")
+		}
+		order = append(order, string(msg.Payload))
+		return nil
+	}))
+	for _, payload := range []string{"first", "second", "third"} {
+		if _, err := svc.Enqueue(context.Background(), "sla.sync", "", payload); err != nil {
+			t.Fatalf("enqueue: %v", err)
+		}
+	}
+	runService(t, svc)
+
+	eventually(t, "all syncs", func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(order) == 3
+	})
+	if got := strings.Join(order, ","); got != `"first","second","third"` || peak != 1 {
+		t.Fatalf("expected syncs in order one at a time, got %s with %d in flight", got, peak)
+	}
+}
+
+type sendRecorder struct {
+	mu   sync.Mutex
+	sent []email.OutgoingMessage
+}
+
+func (s *sendRecorder) Send(_ context.Context, msg email.OutgoingMessage) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sent = append(s.sent, msg)
+	return nil
+}
+
+func (s *sendRecorder) messages() []email.OutgoingMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]email.OutgoingMessage(nil), s.sent...)
+}
+
+type composer struct{}
+
+func (composer) Compose(_ context.Context, messageID string, req email.ReplyRequest) (email.OutgoingMessage, error) {
+	if messageID != "msg-1" {
+		return email.OutgoingMessage{}, email.ErrMessageNotFound
+	}
+	return email.OutgoingMessage{To: []string{"ada@example.com"}, TextBody: req.TextBody, MessageID: "<reply-1@example.com>"}, nil
+}
+
+func TestReplyPublisherQueuesTheSend(t *testing.T) {
+	svc := queue.NewService(inproc.NewBroker(0), memory.NewRepository(), newClock(), queue.Config{})
+	sender := &sendRecorder{}
+	svc.Handle("reply.send", queue.SendHandler(sender))
+	publisher := queue.NewReplyPublisher(composer{}, svc, "reply.send")
+
+	if _, err := publisher.Reply(context.Background(), "msg-404", email.ReplyRequest{TextBody: "Thanks"}); !errors.Is(err, email.ErrMessageNotFound) {
+		t.Fatalf("expected ErrMessageNotFound before queueing, got %v", err)
+	}
+	reply, err := publisher.Reply(context.Background(), "msg-1", email.ReplyRequest{TextBody: "Thanks"})
+	if err != nil {
+		t.Fatalf("reply: %v", err)
+	}
+	if reply.MessageID != "<reply-1@example.com>" || len(sender.messages()) != 0 {
+		t.Fatalf("expected the composed reply back before sending, got %+v", reply)
+	}
+	runService(t, svc)
+
+	eventually(t, "reply sent", func() bool { return len(sender.messages()) == 1 })
+	if got := sender.messages()[0]; got.MessageID != reply.MessageID || got.TextBody != "Thanks" {
+		t.Fatalf("unexpected sent message: %+v", got)
+	}
+}
+
+func TestEnqueueValidatesTopic(t *testing.T) {
+	svc := queue.NewService(inproc.NewBroker(0), memory.NewRepository(), newClock(), queue.Config{})
+	if _, err := svc.Enqueue(context.Background(), " ", "", nil); !errors.Is(err, queue.ErrInvalidMessage) {
+		t.Fatalf("expected ErrInvalidMessage, got %v", err)
+	}
+	if _, err := svc.Enqueue(context.Background(), "classify", "", func() {}); !errors.Is(err, queue.ErrInvalidMessage) {
+		t.Fatalf("expected ErrInvalidMessage for unencodable payload, got %v", err)
+	}
+}
+
+type syncRecorder struct {
+	mu       sync.Mutex
+	synced   []string
+	syncedAt time.Time
+}
+
+func (s *syncRecorder) MessagesSynced(_ context.Context, messages []email.EmailMessage, syncedAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, msg := range messages {
+		s.synced = append(s.synced, msg.ID)
+	}
+	s.syncedAt = syncedAt
+	return nil
+}
+
+func (s *syncRecorder) ids() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.synced...)
+}
+
+func TestSyncPublisherDefersListener(t *testing.T) {
+	svc := queue.NewService(inproc.NewBroker(0), memory.NewRepository(), newClock(), queue.Config{})
+	listener := &syncRecorder{}
+	svc.Handle("sla.sync", queue.SyncHandler(listener))
+	publisher := queue.NewSyncPublisher(svc, "sla.sync")
+
+	syncedAt := time.Date(2025, time.March, 18, 12, 0, 0, 0, time.UTC)
+	messages := []email.EmailMessage{{ID: "msg-1"}, {ID: "msg-2"}}
+	for i := 0; i < 2; i++ {
+		if err := publisher.MessagesSynced(context.Background(), messages, syncedAt); err != nil {
+			t.Fatalf("publish sync: %v", err)
+		}
+	}
+	if len(listener.ids()) != 0 {
+		t.Fatal("expected the listener to run asynchronously")
+	}
+	runService(t, svc)
+
+	eventually(t, "sync delivery", func() bool { return len(listener.ids()) == 2 })
+	time.Sleep(20 * time.Millisecond)
+	if got := listener.ids(); strings.Join(got, ",") != "msg-1,msg-2" {
+		t.Fatalf("expected the same sync to be replayed once, got %v", got)
+	}
+	if !listener.syncedAt.Equal(syncedAt) {
+		t.Fatalf("unexpected sync time: %s", listener.syncedAt)
+	}
+}
+
+// startNATS runs an embedded JetStream server for a test, skipping it when the
+// server cannot start.
+func startNATS(t *testing.T, opts *natsserver.Options) *natsserver.Server {
+	t.Helper()
+	opts.Host = "127.0.0.1"
+	if opts.Port == 0 {
+		opts.Port = -1
+	}
+	if opts.StoreDir == "" {
+		opts.StoreDir = t.TempDir()
+	}
+	opts.JetStream = true
+	opts.NoLog, opts.NoSigs = true, true
+	srv, err := natsserver.NewServer(opts)
+	if err != nil {
+		t.Skipf("nats-server unavailable: %v", err)
+	}
+	go srv.Start()
+	if !srv.ReadyForConnections(5 * time.Second) {
+		srv.Shutdown()
+		t.Skip("nats-server did not start")
+	}
+	t.Cleanup(srv.Shutdown)
+	return srv
+}
+
+func dial(t *testing.T, url string) *nats.Broker {
+	t.Helper()
+	broker, err := nats.Dial(nats.Config{URL: url, Name: "iboz-test", ReconnectWait: 10 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("dial %s: %v", url, err)
+	}
+	t.Cleanup(broker.Close)
+	return broker
+}
+
+func TestServiceOverNATSKeepsJobsUntilDeadLettered(t *testing.T) {
+	broker := dial(t, startNATS(t, &natsserver.Options{}).ClientURL())
+	svc := queue.NewService(broker, memory.NewRepository(), newClock(), queue.Config{
+		MaxAttempts: 3,
+		BaseBackoff: time.Millisecond,
+	})
+	handler := &recorder{fail: 100}
+	svc.Handle("actions", handler)
+
+	// Published before any worker subscribes, the job waits in the stream.
+	msg, err := svc.Enqueue(context.Background(), "actions", "", nil)
+	if err != nil {
+		t.Fatalf("enqueue: %v", err)
+	}
+	runService(t, svc)
+
+	var letters []queue.DeadLetter
+	eventually(t, "dead letter", func() bool {
+		letters, _ = svc.DeadLetters(context.Background())
+		return len(letters) == 1
+	})
+	if got := handler.calls(); len(got) != 3 || got[0] != 1 || got[2] != 3 {
+		t.Fatalf("expected redeliveries as attempts 1..3, got %v", got)
+	}
+	if letters[0].Message.ID != msg.ID {
+		t.Fatalf("unexpected dead letter: %+v", letters[0])
+	}
+
+	if _, err := svc.RetryDeadLetter(context.Background(), msg.ID); err != nil {
+		t.Fatalf("retry dead letter: %v", err)
+	}
+	eventually(t, "retried dead letter", func() bool { return len(handler.calls()) == 6 })
+	if got := handler.calls(); got[3] != 1 {
+		t.Fatalf("expected a fresh attempt budget, got %v", got)
+	}
+}
+
+func TestNATSBrokerSplitsWorkBetweenWorkers(t *testing.T) {
+	url := startNATS(t, &natsserver.Options{}).ClientURL()
+	publisher := dial(t, url)
+	var subs []queue.Subscription
+	for i := 0; i < 2; i++ {
+		sub, err := dial(t, url).Subscribe("classify", queue.Group)
+		if err != nil {
+			t.Fatalf("subscribe: %v", err)
+		}
+		t.Cleanup(func() { sub.Close() })
+		subs = append(subs, sub)
+	}
+
+	for i := 0; i < 10; i++ {
+		msg := queue.Message{ID: fmt.Sprintf("job-%d", i), Topic: "classify", Attempt: 1}
+		if err := publisher.Publish(context.Background(), msg); err != nil {
+			t.Fatalf("publish: %v", err)
+		}
+	}
+
+	seen := make(map[string]bool)
+	timeout := time.After(5 * time.Second)
+	for len(seen) < 10 {
+		var delivery queue.Delivery
+		select {
+		case delivery = <-subs[0].Deliveries():
+		case delivery = <-subs[1].Deliveries():
+		case <-timeout:
+			t.Fatalf("received %d of 10 messages", len(seen))
+		}
+		if id := delivery.Message().ID; seen[id] {
+			t.Fatalf("message %s delivered twice", id)
+		}
+		seen[delivery.Message().ID] = true
+		if err := delivery.Ack(); err != nil {
+			t.Fatalf("ack: %v", err)
+		}
+	}
+
+	publisher.Close()
+	if err := publisher.Publish(context.Background(), queue.Message{Topic: "classify"}); !errors.Is(err, queue.ErrClosed) {
+		t.Fatalf("expected ErrClosed, got %v", err)
+	}
+}
+
+func TestNATSBrokerKeepsJobsAcrossServerRestart(t *testing.T) {
+	dir := t.TempDir()
+	srv := startNATS(t, &natsserver.Options{StoreDir: dir})
+	port := srv.Addr().(*net.TCPAddr).Port
+	broker := dial(t, srv.ClientURL())
+
+	if err := broker.Publish(context.Background(), queue.Message{ID: "job-1", Topic: "sync", Attempt: 1}); err != nil {
+		t.Fatalf("publish: %v", err)
+	}
+	srv.Shutdown()
+	srv.WaitForShutdown()
+	startNATS(t, &natsserver.Options{Port: port, StoreDir: dir})
+
+	var sub queue.Subscription
+	eventually(t, "subscribe after reconnect", func() bool {
+		var err error
+		sub, err = broker.Subscribe("sync", queue.Group)
+		return err == nil
+	})
+	defer sub.Close()
+	select {
+	case delivery := <-sub.Deliveries():
+		if delivery.Message().ID != "job-1" {
+			t.Fatalf("unexpected message: %+v", delivery.Message())
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the job to survive the restart")
+	}
+}
+
+func TestNATSDialRejectsBadCredentials(t *testing.T) {
+	srv := startNATS(t, &natsserver.Options{Username: "iboz", Password: "secret"})
+	addr := srv.Addr().String()
+
+	if _, err := nats.Dial(nats.Config{URL: "nats://iboz:wrong@" + addr}); err == nil || !strings.Contains(strings.ToLower(err.Error()), "authorization violation") {
+		t.Fatalf("expected authorization error, got %v", err)
+	}
+	broker, err := nats.Dial(nats.Config{URL: "nats://iboz:secret@" + addr})
+	if err != nil {
+		t.Fatalf("dial with credentials: %v", err)
+	}
+	broker.Close()
+	if _, err := nats.Dial(nats.Config{URL: "http://" + addr}); err == nil {
+		t.Fatal("expected non-nats URL to be rejected")
+	}
+}
@@ -0,0 +1,58 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/example/iboz/internal/email"
+)
+
+// ReplyPublisher is an email.ReplyService that composes replies inline, so
+// invalid requests fail immediately, and leaves delivery to the queue.
+type ReplyPublisher struct {
+	composer email.ReplyComposer
+	queue    QueueService
+	topic    string
+}
+
+var _ email.ReplyService = (*ReplyPublisher)(nil)
+
+// NewReplyPublisher constructs a ReplyPublisher enqueueing to topic.
+func NewReplyPublisher(composer email.ReplyComposer, queue QueueService, topic string) *ReplyPublisher {
+	if composer == nil {
+		panic("queue: reply composer dependency is required")
+	}
+	if queue == nil {
+		panic("queue: queue service dependency is required")
+	}
+	return &ReplyPublisher{composer: composer, queue: queue, topic: topic}
+}
+
+// Reply implements email.ReplyService. The job is keyed by the Message-ID of the
+// reply so a redelivered job does not send it twice.
+func (p *ReplyPublisher) Reply(ctx context.Context, messageID string, req email.ReplyRequest) (email.OutgoingMessage, error) {
+	msg, err := p.composer.Compose(ctx, messageID, req)
+	if err != nil {
+		return email.OutgoingMessage{}, err
+	}
+	if _, err := p.queue.Enqueue(ctx, p.topic, p.topic+":"+msg.MessageID, msg); err != nil {
+		return email.OutgoingMessage{}, err
+	}
+	return msg, nil
+}
+
+// SendHandler returns a Handler that sends the email.OutgoingMessage jobs
+// published by a ReplyPublisher through sender.
+func SendHandler(sender email.MailSender) Handler {
+	if sender == nil {
+		panic("queue: mail sender dependency is required")
+	}
+	return HandlerFunc(func(ctx context.Context, msg Message) error {
+		var outgoing email.OutgoingMessage
+		if err := json.Unmarshal(msg.Payload, &outgoing); err != nil {
+			return fmt.Errorf("decode outgoing message: %w", err)
+		}
+		return sender.Send(ctx, outgoing)
+	})
+}
@@ -0,0 +1,337 @@
+package queue
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/example/iboz/internal/email"
+)
+
+// Group is the subscription group shared by every worker pool.
+const Group = "iboz-workers"
+
+// Config tunes the worker pool.
+type Config struct {
+	Concurrency       int
+	MaxAttempts       int
+	BaseBackoff       time.Duration
+	MaxBackoff        time.Duration
+	IdempotencyWindow time.Duration
+}
+
+// QueueService exposes enqueueing and dead-letter inspection.
+type QueueService interface {
+	Enqueue(ctx context.Context, topic, key string, payload any) (Message, error)
+	DeadLetters(ctx context.Context) ([]DeadLetter, error)
+	RetryDeadLetter(ctx context.Context, id string) (Message, error)
+	DeleteDeadLetter(ctx context.Context, id string) error
+}
+
+var _ QueueService = (*Service)(nil)
+
+// Service publishes jobs and runs a bounded worker pool over the registered topics.
+type Service struct {
+	broker   Broker
+	repo     Repository
+	clock    email.Clock
+	cfg      Config
+	handlers map[string]Handler
+	ordered  map[string]bool
+}
+
+// NewService constructs a queue Service. Register handlers with Handle before calling Run.
+func NewService(broker Broker, repo Repository, clock email.Clock, cfg Config) *Service {
+	if broker == nil {
+		panic("queue: broker dependency is required")
+	}
+	if repo == nil {
+		panic("queue: repository dependency is required")
+	}
+	if clock == nil {
+		panic("queue: clock dependency is required")
+	}
+	if cfg.Concurrency < 0 || cfg.MaxAttempts < 0 || cfg.BaseBackoff < 0 || cfg.MaxBackoff < 0 || cfg.IdempotencyWindow < 0 {
+		panic("queue: config values cannot be negative")
+	}
+	if cfg.Concurrency == 0 {
+		cfg.Concurrency = DefaultConcurrency
+	}
+	if cfg.MaxAttempts == 0 {
+		cfg.MaxAttempts = DefaultMaxAttempts
+	}
+	if cfg.BaseBackoff == 0 {
+		cfg.BaseBackoff = DefaultBaseBackoff
+	}
+	if cfg.MaxBackoff == 0 {
+		cfg.MaxBackoff = DefaultMaxBackoff
+	}
+	if cfg.IdempotencyWindow == 0 {
+		cfg.IdempotencyWindow = DefaultIdempotencyWindow
+	}
+	return &Service{broker: broker, repo: repo, clock: clock, cfg: cfg, handlers: make(map[string]Handler), ordered: make(map[string]bool)}
+}
+
+// Handle registers the handler of topic. It must be called before Run.
+func (s *Service) Handle(topic string, handler Handler) {
+	if handler == nil {
+		panic(fmt.Sprintf("queue: handler for %q is nil", topic))
+	}
+	if _, ok := s.handlers[topic]; ok {
+		panic(fmt.Sprintf("queue: handler for %q registered twice", topic))
+	}
+	s.handlers[topic] = handler
+}
+
+// HandleInOrder registers the handler of a topic whose messages must be handled
+// one at a time in delivery order, such as sync events feeding read-modify-write
+// state. A failing message is retried in place and holds back the messages
+// behind it until it succeeds or is dead-lettered. Order is kept within one
+// worker pool. It must be called before Run.
+func (s *Service) HandleInOrder(topic string, handler Handler) {
+	s.Handle(topic, handler)
+	s.ordered[topic] = true
+}
+
+// Enqueue publishes payload, encoded as JSON, to topic. key may be empty to
+// skip duplicate suppression.
+func (s *Service) Enqueue(ctx context.Context, topic, key string, payload any) (Message, error) {
+	if err := ctx.Err(); err != nil {
+		return Message{}, err
+	}
+	if strings.TrimSpace(topic) == "" {
+		return Message{}, fmt.Errorf("%w: topic is required", ErrInvalidMessage)
+	}
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return Message{}, fmt.Errorf("%w: %v", ErrInvalidMessage, err)
+	}
+	id, err := newID()
+	if err != nil {
+		return Message{}, err
+	}
+	msg := Message{ID: id, Topic: topic, Key: key, Payload: raw, Attempt: 1, EnqueuedAt: s.clock.Now().UTC()}
+	if err := s.broker.Publish(ctx, msg); err != nil {
+		return Message{}, err
+	}
+	return msg, nil
+}
+
+// DeadLetters returns messages that exhausted their retries, most recent first.
+func (s *Service) DeadLetters(ctx context.Context) ([]DeadLetter, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	list, err := s.repo.ListDeadLetters(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].FailedAt.After(list[j].FailedAt) })
+	return list, nil
+}
+
+// RetryDeadLetter republishes a dead letter with a fresh attempt budget.
+func (s *Service) RetryDeadLetter(ctx context.Context, id string) (Message, error) {
+	letter, err := s.deadLetter(ctx, id)
+	if err != nil {
+		return Message{}, err
+	}
+	msg := letter.Message
+	msg.Attempt = 1
+	if err := s.broker.Publish(ctx, msg); err != nil {
+		return Message{}, err
+	}
+	if err := s.repo.DeleteDeadLetter(ctx, id); err != nil {
+		return Message{}, err
+	}
+	return msg, nil
+}
+
+// DeleteDeadLetter discards a dead letter.
+func (s *Service) DeleteDeadLetter(ctx context.Context, id string) error {
+	if _, err := s.deadLetter(ctx, id); err != nil {
+		return err
+	}
+	return s.repo.DeleteDeadLetter(ctx, id)
+}
+
+func (s *Service) deadLetter(ctx context.Context, id string) (*DeadLetter, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	letter, err := s.repo.GetDeadLetter(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if letter == nil {
+		return nil, ErrDeadLetterNotFound
+	}
+	return letter, nil
+}
+
+// Run subscribes to every registered topic and handles messages with at most
+// Config.Concurrency in flight until ctx is cancelled. Topics registered with
+// HandleInOrder have at most one message in flight. In-flight messages finish
+// before Run returns.
+func (s *Service) Run(ctx context.Context) {
+	slots := make(chan struct{}, s.cfg.Concurrency)
+	var inflight sync.WaitGroup
+	var consumers sync.WaitGroup
+
+	for topic, handler := range s.handlers {
+		sub, err := s.broker.Subscribe(topic, Group)
+		if err != nil {
+			log.Printf("queue: subscribe to %s failed: %v", topic, err)
+			continue
+		}
+		ordered := s.ordered[topic]
+		consumers.Add(1)
+		go func(topic string, handler Handler, sub Subscription) {
+			defer consumers.Done()
+			defer sub.Close()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case delivery, ok := <-sub.Deliveries():
+					if !ok {
+						if ctx.Err() == nil {
+							log.Printf("queue: subscription to %s closed", topic)
+						}
+						return
+					}
+					select {
+					case slots <- struct{}{}:
+					case <-ctx.Done():
+						s.settle(delivery, delivery.Nak(0))
+						return
+					}
+					if ordered {
+						s.processInOrder(ctx, handler, delivery)
+						<-slots
+						continue
+					}
+					inflight.Add(1)
+					go func() {
+						defer inflight.Done()
+						defer func() { <-slots }()
+						s.process(ctx, handler, delivery)
+					}()
+				}
+			}
+		}(topic, handler, sub)
+	}
+
+	consumers.Wait()
+	inflight.Wait()
+}
+
+func (s *Service) process(ctx context.Context, handler Handler, delivery Delivery) {
+	msg := delivery.Message()
+	if err := s.handle(ctx, handler, msg); err != nil {
+		s.retry(ctx, delivery, msg, err)
+		return
+	}
+	s.settle(delivery, delivery.Ack())
+}
+
+// processInOrder handles a delivery, retrying it in place after each backoff so
+// the messages behind it wait for the outcome.
+func (s *Service) processInOrder(ctx context.Context, handler Handler, delivery Delivery) {
+	msg := delivery.Message()
+	for {
+		err := s.handle(ctx, handler, msg)
+		if err == nil {
+			s.settle(delivery, delivery.Ack())
+			return
+		}
+		if msg.Attempt >= s.cfg.MaxAttempts || ctx.Err() != nil {
+			s.retry(ctx, delivery, msg, err)
+			return
+		}
+		timer := time.NewTimer(s.Backoff(msg.Attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			s.retry(ctx, delivery, msg, err)
+			return
+		case <-timer.C:
+		}
+		msg.Attempt++
+	}
+}
+
+// handle runs handler unless msg.Key already completed within the idempotency window.
+func (s *Service) handle(ctx context.Context, handler Handler, msg Message) error {
+	now := s.clock.Now().UTC()
+	if msg.Key != "" {
+		fresh, err := s.repo.Begin(ctx, msg.Key, now, now.Add(-s.cfg.IdempotencyWindow))
+		if err != nil {
+			log.Printf("queue: idempotency check for %s failed: %v", msg.ID, err)
+			return err
+		}
+		if !fresh {
+			return nil
+		}
+	}
+
+	err := handler.Handle(ctx, msg)
+	if msg.Key != "" {
+		if finishErr := s.repo.Finish(ctx, msg.Key, err == nil, s.clock.Now().UTC()); finishErr != nil {
+			log.Printf("queue: record completion of %s failed: %v", msg.ID, finishErr)
+		}
+	}
+	return err
+}
+
+// retry hands msg back to the broker for redelivery after an exponential
+// backoff, or dead-letters it once the attempt budget is spent. A message that
+// cannot be dead-lettered is kept by the broker.
+func (s *Service) retry(ctx context.Context, delivery Delivery, msg Message, cause error) {
+	if msg.Attempt < s.cfg.MaxAttempts {
+		s.settle(delivery, delivery.Nak(s.Backoff(msg.Attempt)))
+		return
+	}
+	letter := DeadLetter{Message: msg, Error: cause.Error(), FailedAt: s.clock.Now().UTC()}
+	if err := s.repo.SaveDeadLetter(context.WithoutCancel(ctx), letter); err != nil {
+		log.Printf("queue: dead-letter %s failed: %v", msg.ID, err)
+		s.settle(delivery, delivery.Nak(s.cfg.MaxBackoff))
+		return
+	}
+	s.settle(delivery, delivery.Ack())
+}
+
+// settle logs a failed Ack or Nak; the broker then delivers the message again.
+func (s *Service) settle(delivery Delivery, err error) {
+	if err != nil && !errors.Is(err, ErrClosed) {
+		log.Printf("queue: settle %s failed: %v", delivery.Message().ID, err)
+	}
+}
+
+// Backoff returns the delay before retrying a message that failed attempt.
+func (s *Service) Backoff(attempt int) time.Duration {
+	delay := s.cfg.BaseBackoff
+	for i := 1; i < attempt; i++ {
+		delay *= 2
+		if delay >= s.cfg.MaxBackoff {
+			return s.cfg.MaxBackoff
+		}
+	}
+	return delay
+}
+
+func newID() (string, error) {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("generate message id: %w", err)
+	}
+	return "job-" + hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,55 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/example/iboz/internal/email"
+)
+
+// SyncEvent is the payload of jobs published by a SyncPublisher.
+type SyncEvent struct {
+	Messages []email.EmailMessage `json:"messages"`
+	SyncedAt time.Time            `json:"syncedAt"`
+}
+
+// SyncPublisher is an email.SyncListener that defers processing of a sync to
+// the queue instead of running it inline with the fetch.
+type SyncPublisher struct {
+	queue QueueService
+	topic string
+}
+
+var _ email.SyncListener = (*SyncPublisher)(nil)
+
+// NewSyncPublisher constructs a SyncPublisher enqueueing to topic.
+func NewSyncPublisher(queue QueueService, topic string) *SyncPublisher {
+	if queue == nil {
+		panic("queue: queue service dependency is required")
+	}
+	return &SyncPublisher{queue: queue, topic: topic}
+}
+
+// MessagesSynced implements email.SyncListener. Each sync is keyed by its time
+// so a republished event is handled once.
+func (p *SyncPublisher) MessagesSynced(ctx context.Context, messages []email.EmailMessage, syncedAt time.Time) error {
+	key := p.topic + ":" + syncedAt.UTC().Format(time.RFC3339Nano)
+	_, err := p.queue.Enqueue(ctx, p.topic, key, SyncEvent{Messages: messages, SyncedAt: syncedAt})
+	return err
+}
+
+// SyncHandler returns a Handler that replays SyncEvent jobs into listener.
+func SyncHandler(listener email.SyncListener) Handler {
+	if listener == nil {
+		panic("queue: sync listener dependency is required")
+	}
+	return HandlerFunc(func(ctx context.Context, msg Message) error {
+		var event SyncEvent
+		if err := json.Unmarshal(msg.Payload, &event); err != nil {
+			return fmt.Errorf("decode sync event: %w", err)
+		}
+		return listener.MessagesSynced(ctx, event.Messages, event.SyncedAt)
+	})
+}
@@ -0,0 +1,374 @@
+package recommendations
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"net/mail"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/example/iboz/internal/email"
+)
+
+var _ RecommendationService = (*Engine)(nil)
+
+// Engine mines the synced messages for recommendations on every request.
+type Engine struct {
+	messages email.Repository
+	clock    email.Clock
+	cfg      Config
+}
+
+// NewEngine constructs a recommendation Engine over the synced messages.
+func NewEngine(messages email.Repository, clock email.Clock, cfg Config) *Engine {
+	if messages == nil {
+		panic("recommendations: email repository dependency is required")
+	}
+	if clock == nil {
+		panic("recommendations: clock dependency is required")
+	}
+	if cfg.Window < 0 || cfg.MinOccurrences < 0 || cfg.MinShare < 0 || cfg.MinShare > 1 {
+		panic("recommendations: invalid config")
+	}
+	if cfg.Window == 0 {
+		cfg.Window = DefaultWindow
+	}
+	if cfg.MinOccurrences == 0 {
+		cfg.MinOccurrences = DefaultMinOccurrences
+	}
+	if cfg.MinShare == 0 {
+		cfg.MinShare = DefaultMinShare
+	}
+	return &Engine{messages: messages, clock: clock, cfg: cfg}
+}
+
+// history records when the owner last replied in each thread.
+type history struct {
+	lastReply map[string]time.Time
+}
+
+func (h history) replied(message email.EmailMessage) bool {
+	return h.lastReply[message.ThreadKey()].After(message.ReceivedAt)
+}
+
+// Recommend implements the RecommendationService interface. Recommendations
+// are ranked by confidence, then by the number of messages behind them.
+func (e *Engine) Recommend(ctx context.Context, limit int) ([]Recommendation, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	messages, _, err := e.messages.GetMessages(ctx)
+	if err != nil {
+		return nil, err
+	}
+	auth, err := e.messages.GetAuth(ctx)
+	if err != nil {
+		return nil, err
+	}
+	user := ""
+	if auth != nil {
+		user = auth.State.Username
+	}
+
+	now := e.clock.Now().UTC()
+	since := now.Add(-e.cfg.Window)
+	h := history{lastReply: make(map[string]time.Time)}
+	bySender := make(map[string][]email.EmailMessage)
+	bySubject := make(map[string][]email.EmailMessage)
+	// Only providers that label inbox messages tell archived ones apart.
+	inboxLabels := false
+	for _, message := range messages {
+		if message.SentBy(user) {
+			if thread := message.ThreadKey(); message.ReceivedAt.After(h.lastReply[thread]) {
+				h.lastReply[thread] = message.ReceivedAt
+			}
+			continue
+		}
+		inboxLabels = inboxLabels || message.HasLabel(LabelInbox)
+		if message.ReceivedAt.Before(since) || message.ReceivedAt.After(now) {
+			continue
+		}
+		if sender := address(message.Sender); sender != "" {
+			bySender[sender] = append(bySender[sender], message)
+		}
+		if subject := subjectKey(message.Subject); subject != "" {
+			bySubject[subject] = append(bySubject[subject], message)
+		}
+	}
+
+	// A subject that only one recommended sender uses is covered by the
+	// sender's recommendation.
+	var list []Recommendation
+	covered := make(map[string]bool)
+	for sender, received := range bySender {
+		rec, ok := e.digest(sender, received)
+		if !ok && inboxLabels {
+			rec, ok = e.archive(sender, received, h)
+		}
+		if ok {
+			list = append(list, rec)
+			covered[sender] = true
+		}
+	}
+	for subject, received := range bySubject {
+		if covered[common(received, func(m email.EmailMessage) string { return address(m.Sender) })] {
+			continue
+		}
+		if rec, ok := e.recurring(subject, received, h); ok {
+			list = append(list, rec)
+		}
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Confidence != list[j].Confidence {
+			return list[i].Confidence > list[j].Confidence
+		}
+		if list[i].Evidence.Matching != list[j].Evidence.Matching {
+			return list[i].Evidence.Matching > list[j].Evidence.Matching
+		}
+		return list[i].ID < list[j].ID
+	})
+	if limit > 0 && len(list) > limit {
+		list = list[:limit]
+	}
+	if list == nil {
+		list = []Recommendation{}
+	}
+	return list, nil
+}
+
+// digest suggests a weekly digest for a sender whose newsletters stay unread.
+func (e *Engine) digest(sender string, received []email.EmailMessage) (Recommendation, bool) {
+	var newsletters, unread []email.EmailMessage
+	for _, message := range received {
+		if message.Category != "newsletter" {
+			continue
+		}
+		newsletters = append(newsletters, message)
+		if message.HasLabel(LabelUnread) {
+			unread = append(unread, message)
+		}
+	}
+	confidence, ok := e.confidence(len(unread), len(newsletters))
+	if !ok {
+		return Recommendation{}, false
+	}
+	id := recommendationID(KindDigest, sender)
+	return Recommendation{
+		ID:          id,
+		Kind:        KindDigest,
+		Title:       "Send " + sender + " to a weekly digest",
+		Description: fmt.Sprintf("%d of %d newsletters from %s were never opened.", len(unread), len(newsletters), sender),
+		Confidence:  confidence,
+		Evidence:    evidence(newsletters, unread),
+		Automation: Automation{
+			ID:          id,
+			Name:        "Weekly digest for " + sender,
+			Description: "Keep newsletters from " + sender + " out of the inbox and summarise them in the weekly digest.",
+			Trigger:     "sender: " + sender,
+			Conditions:  []string{"category = 'newsletter'"},
+			Actions:     []string{"Skip the inbox", "Add to the weekly digest"},
+			Parameters:  map[string]any{"sender": sender, "schedule": "weekly"},
+		},
+	}, true
+}
+
+// archive suggests archiving a sender whose messages are archived without a
+// reply.
+func (e *Engine) archive(sender string, received []email.EmailMessage, h history) (Recommendation, bool) {
+	var archived []email.EmailMessage
+	for _, message := range received {
+		if !message.HasLabel(LabelInbox) && !h.replied(message) {
+			archived = append(archived, message)
+		}
+	}
+	confidence, ok := e.confidence(len(archived), len(received))
+	if !ok {
+		return Recommendation{}, false
+	}
+	id := recommendationID(KindArchive, sender)
+	conditions := []string{}
+	if category := common(archived, func(m email.EmailMessage) string { return m.Category }); category != "" {
+		conditions = append(conditions, "category = '"+category+"'")
+	}
+	actions := []string{"Skip the inbox"}
+	if domain := archived[0].SenderDomain(); domain != "" {
+		actions = append(actions, "Label as "+domain)
+	}
+	return Recommendation{
+		ID:          id,
+		Kind:        KindArchive,
+		Title:       "Auto-archive mail from " + sender,
+		Description: fmt.Sprintf("%d of %d messages from %s were archived without a reply.", len(archived), len(received), sender),
+		Confidence:  confidence,
+		Evidence:    evidence(received, archived),
+		Automation: Automation{
+			ID:          id,
+			Name:        "Auto-archive " + sender,
+			Description: "Archive messages from " + sender + " as they arrive.",
+			Trigger:     "sender: " + sender,
+			Conditions:  conditions,
+			Actions:     actions,
+			Parameters:  map[string]any{"sender": sender},
+		},
+	}, true
+}
+
+// recurring suggests handling a subject that arrives on several days. Subjects
+// that are usually answered get a template reply held for approval; others are
+// labelled and queued for the next focus session.
+func (e *Engine) recurring(subject string, received []email.EmailMessage, h history) (Recommendation, bool) {
+	days := make(map[string]bool)
+	var replied []email.EmailMessage
+	for _, message := range received {
+		days[message.ReceivedAt.UTC().Format(time.DateOnly)] = true
+		if h.replied(message) {
+			replied = append(replied, message)
+		}
+	}
+	if len(days) < e.cfg.MinOccurrences {
+		return Recommendation{}, false
+	}
+	answered := 2*len(replied) >= len(received)
+	consistent := len(replied)
+	if !answered {
+		consistent = len(received) - len(replied)
+	}
+	confidence, ok := e.confidence(consistent, len(received))
+	if !ok {
+		return Recommendation{}, false
+	}
+
+	id := recommendationID(KindRecurring, subject)
+	rec := Recommendation{
+		ID:         id,
+		Kind:       KindRecurring,
+		Confidence: confidence,
+		Evidence:   evidence(received, received),
+		Automation: Automation{
+			ID:         id,
+			Trigger:    "subject: " + subject,
+			Conditions: []string{},
+			Parameters: map[string]any{"subject": subject},
+		},
+	}
+	if sender := common(received, func(m email.EmailMessage) string { return address(m.Sender) }); sender != "" {
+		rec.Automation.Conditions = append(rec.Automation.Conditions, "sender = '"+sender+"'")
+		rec.Automation.Parameters["sender"] = sender
+	}
+	if answered {
+		rec.Title = "Reply to \"" + subject + "\" from a template"
+		rec.Description = fmt.Sprintf("\"%s\" arrived on %d days and %d of %d messages were answered.", subject, len(days), len(replied), len(received))
+		rec.Automation.Name = "Template reply to \"" + subject + "\""
+		rec.Automation.Description = "Draft the usual reply to \"" + subject + "\" and send it once approved."
+		rec.Automation.Actions = []string{"Draft reply from template", "Label as Recurring"}
+		rec.Automation.RequiresApproval = true
+	} else {
+		rec.Title = "Batch \"" + subject + "\" into focus sessions"
+		rec.Description = fmt.Sprintf("\"%s\" arrived on %d days and %d of %d messages went unanswered.", subject, len(days), consistent, len(received))
+		rec.Automation.Name = "Batch \"" + subject + "\""
+		rec.Automation.Description = "Label \"" + subject + "\" and hold it for the next focus session."
+		rec.Automation.Actions = []string{"Label as Recurring", "Snooze until the next focus session"}
+	}
+	return rec, true
+}
+
+// confidence rates a pattern followed by matching of total messages: the share
+// that follows it, discounted while there are few of them.
+func (e *Engine) confidence(matching, total int) (float64, bool) {
+	if matching < e.cfg.MinOccurrences || total == 0 {
+		return 0, false
+	}
+	share := float64(matching) / float64(total)
+	if share < e.cfg.MinShare {
+		return 0, false
+	}
+	support := math.Min(1, 0.5+0.1*float64(matching))
+	return math.Min(0.99, math.Round(share*support*100)/100), true
+}
+
+// evidence lists the most recent matching messages first.
+func evidence(received, matching []email.EmailMessage) Evidence {
+	sorted := append([]email.EmailMessage(nil), matching...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].ReceivedAt.After(sorted[j].ReceivedAt) })
+	ev := Evidence{Messages: len(received), Matching: len(matching), MessageIDs: []string{}}
+	for i, message := range sorted {
+		if i == 0 {
+			ev.LastSeen = message.ReceivedAt
+		}
+		if i < MaxEvidence {
+			ev.MessageIDs = append(ev.MessageIDs, message.ID)
+		}
+	}
+	return ev
+}
+
+// common returns the value key yields for every message, or "".
+func common(messages []email.EmailMessage, key func(email.EmailMessage) string) string {
+	value := ""
+	for i, message := range messages {
+		current := key(message)
+		if i > 0 && current != value {
+			return ""
+		}
+		value = current
+	}
+	return value
+}
+
+func recommendationID(kind Kind, key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return "rec-" + string(kind) + "-" + hex.EncodeToString(sum[:4])
+}
+
+// address returns the lower-cased address of a sender.
+func address(sender string) string {
+	if parsed, err := mail.ParseAddress(sender); err == nil {
+		sender = parsed.Address
+	}
+	return strings.ToLower(strings.TrimSpace(sender))
+}
+
+// subjectKey normalises a subject so recurring messages share it: reply and
+// forward prefixes are dropped and numbers become "#". Subjects with fewer
+// than four letters return "".
+func subjectKey(subject string) string {
+	key := strings.ToLower(strings.TrimSpace(subject))
+	for {
+		trimmed := key
+		for _, prefix := range []string{"re:", "fw:", "fwd:", "aw:"} {
+			trimmed = strings.TrimSpace(strings.TrimPrefix(trimmed, prefix))
+		}
+		if trimmed == key {
+			break
+		}
+		key = trimmed
+	}
+
+	var b strings.Builder
+	letters := 0
+	inNumber := false
+	for _, r := range key {
+		switch {
+		case unicode.IsDigit(r):
+			if !inNumber {
+				b.WriteRune('#')
+			}
+			inNumber = true
+			continue
+		case unicode.IsLetter(r):
+			letters++
+		}
+		inNumber = false
+		b.WriteRune(r)
+	}
+	if letters < 4 {
+		return ""
+	}
+	return strings.Join(strings.Fields(strings.Trim(b.String(), " -:|")), " ")
+}
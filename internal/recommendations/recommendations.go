@@ -0,0 +1,89 @@
+// Package recommendations mines the message history for repeated patterns and
+// suggests the automations that would handle them.
+package recommendations
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	// DefaultWindow is the message history mined for patterns.
+	DefaultWindow = 30 * 24 * time.Hour
+	// DefaultMinOccurrences is the number of messages a pattern needs before it
+	// is suggested.
+	DefaultMinOccurrences = 3
+	// DefaultMinShare is the share of a sender's or subject's messages that
+	// must follow the pattern.
+	DefaultMinShare = 0.8
+	// MaxEvidence caps the message IDs listed as evidence of a pattern.
+	MaxEvidence = 5
+)
+
+// Labels read from the synced messages. Providers that mirror Gmail labels
+// drop LabelInbox from archived messages and keep LabelUnread on unopened ones.
+// Archive recommendations are only mined when some message carries LabelInbox,
+// since without it every message would look archived.
+const (
+	LabelInbox  = "INBOX"
+	LabelUnread = "UNREAD"
+)
+
+// Kind enumerates the patterns recommendations are mined from.
+type Kind string
+
+const (
+	// KindArchive is a sender whose messages are archived without a reply.
+	KindArchive Kind = "archive"
+	// KindDigest is a sender of newsletters that are never opened.
+	KindDigest Kind = "digest"
+	// KindRecurring is a subject that keeps coming back.
+	KindRecurring Kind = "recurring"
+)
+
+// Automation is the automation a recommendation would create. It has the shape
+// of the templates listed by GET /api/automations; ID and Parameters are the
+// templateId and parameters of POST /api/automations/test-run.
+type Automation struct {
+	ID               string         `json:"id"`
+	Name             string         `json:"name"`
+	Description      string         `json:"description"`
+	Trigger          string         `json:"trigger"`
+	Conditions       []string       `json:"conditions"`
+	Actions          []string       `json:"actions"`
+	RequiresApproval bool           `json:"requiresApproval"`
+	Parameters       map[string]any `json:"parameters"`
+}
+
+// Evidence is the history a recommendation was mined from.
+type Evidence struct {
+	Messages   int       `json:"messages"`
+	Matching   int       `json:"matching"`
+	MessageIDs []string  `json:"messageIds"`
+	LastSeen   time.Time `json:"lastSeen"`
+}
+
+// Recommendation is a ranked automation suggestion.
+type Recommendation struct {
+	ID          string     `json:"id"`
+	Kind        Kind       `json:"kind"`
+	Title       string     `json:"title"`
+	Description string     `json:"description"`
+	Confidence  float64    `json:"confidence"`
+	Evidence    Evidence   `json:"evidence"`
+	Automation  Automation `json:"automation"`
+}
+
+// Config tunes mining. Zero values select the defaults.
+type Config struct {
+	Window         time.Duration
+	MinOccurrences int
+	MinShare       float64
+}
+
+// RecommendationService exposes the ranked recommendations.
+type RecommendationService interface {
+	// Recommend returns up to limit recommendations, best first; a limit of
+	// zero returns all of them.
+	Recommend(ctx context.Context, limit int) ([]Recommendation, error)
+}
@@ -0,0 +1,122 @@
+package recommendations_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/example/iboz/internal/email"
+	emailmemory "github.com/example/iboz/internal/email/adapter/memory"
+	"github.com/example/iboz/internal/recommendations"
+	"github.com/example/iboz/internal/testutil"
+)
+
+var now = time.Date(2025, time.March, 18, 12, 0, 0, 0, time.UTC)
+
+func newEngine(t *testing.T, messages []email.EmailMessage) *recommendations.Engine {
+	t.Helper()
+	ctx := context.Background()
+	repo := emailmemory.NewRepository()
+	if err := repo.SaveAuth(ctx, email.AuthRecord{State: email.AuthState{Username: "me@example.com"}}); err != nil {
+		t.Fatalf("save auth: %v", err)
+	}
+	if err := repo.SaveMessages(ctx, messages, now); err != nil {
+		t.Fatalf("save messages: %v", err)
+	}
+	return recommendations.NewEngine(repo, testutil.NewClock(now), recommendations.Config{})
+}
+
+func TestRecommendationsAreMinedFromHistory(t *testing.T) {
+	day := 24 * time.Hour
+	var messages []email.EmailMessage
+	for i := 0; i < 4; i++ {
+		messages = append(messages, email.EmailMessage{
+			ID: fmt.Sprintf("news-%d", i), Subject: fmt.Sprintf("Issue %d", 40+i), Sender: "Letters <news@letters.example>",
+			Category: "newsletter", Labels: []string{"INBOX", "UNREAD"}, ReceivedAt: now.Add(-time.Duration(i+1) * day),
+		})
+	}
+	for i := 0; i < 5; i++ {
+		messages = append(messages, email.EmailMessage{
+			ID: fmt.Sprintf("alert-%d", i), Subject: fmt.Sprintf("CPU alert on host-%d", i), Sender: "alerts@monitoring.example",
+			Category: "updates", ReceivedAt: now.Add(-time.Duration(i) * time.Hour),
+		})
+	}
+	for i := 0; i < 3; i++ {
+		thread := fmt.Sprintf("status-%d", i)
+		received := now.Add(-time.Duration(7*i+1) * day)
+		messages = append(messages,
+			email.EmailMessage{ID: thread, Subject: fmt.Sprintf("Re: Weekly status report %d", 11+i), Sender: "boss@example.com", ThreadID: thread, Labels: []string{"INBOX"}, ReceivedAt: received},
+			email.EmailMessage{ID: thread + "-reply", Subject: "Re: Weekly status report", Sender: "me@example.com", ThreadID: thread, Labels: []string{email.LabelSent}, ReceivedAt: received.Add(time.Hour)},
+		)
+	}
+	messages = append(messages,
+		email.EmailMessage{ID: "friend-1", Subject: "Lunch?", Sender: "friend@example.org", Labels: []string{"INBOX"}, ReceivedAt: now.Add(-day)},
+		email.EmailMessage{ID: "friend-2", Subject: "Photos", Sender: "friend@example.org", ReceivedAt: now.Add(-2 * day)},
+		email.EmailMessage{ID: "friend-3", Subject: "Trip", Sender: "friend@example.org", ReceivedAt: now.Add(-3 * day)},
+		email.EmailMessage{ID: "old-alert", Subject: "CPU alert", Sender: "alerts@monitoring.example", Labels: []string{"INBOX"}, ReceivedAt: now.Add(-40 * day)},
+	)
+	engine := newEngine(t, messages)
+
+	list, err := engine.Recommend(context.Background(), 0)
+	if err != nil {
+		t.Fatalf("recommend: %v", err)
+	}
+	if len(list) != 3 {
+		t.Fatalf("expected three recommendations, got %+v", list)
+	}
+	archive, digest, recurring := list[0], list[1], list[2]
+
+	if archive.Kind != recommendations.KindArchive || archive.Confidence != 0.99 || archive.Evidence.Matching != 5 || archive.Evidence.MessageIDs[0] != "alert-0" {
+		t.Fatalf("unexpected archive recommendation: %+v", archive)
+	}
+	automation := archive.Automation
+	if automation.ID != archive.ID || automation.Trigger != "sender: alerts@monitoring.example" || fmt.Sprint(automation.Conditions) != "[category = 'updates']" ||
+		fmt.Sprint(automation.Actions) != "[Skip the inbox Label as monitoring.example]" || automation.RequiresApproval || automation.Parameters["sender"] != "alerts@monitoring.example" {
+		t.Fatalf("unexpected archive automation: %+v", automation)
+	}
+
+	if digest.Kind != recommendations.KindDigest || digest.Confidence != 0.9 || digest.Description != "4 of 4 newsletters from news@letters.example were never opened." {
+		t.Fatalf("unexpected digest recommendation: %+v", digest)
+	}
+
+	if recurring.Kind != recommendations.KindRecurring || recurring.Confidence != 0.8 || recurring.Automation.Trigger != "subject: weekly status report #" ||
+		!recurring.Automation.RequiresApproval || fmt.Sprint(recurring.Automation.Conditions) != "[sender = 'boss@example.com']" {
+		t.Fatalf("unexpected recurring recommendation: %+v", recurring)
+	}
+
+	again, err := engine.Recommend(context.Background(), 2)
+	if err != nil || len(again) != 2 || again[0].ID != archive.ID || again[1].ID != digest.ID {
+		t.Fatalf("expected the two best recommendations with stable IDs, got %+v (%v)", again, err)
+	}
+}
+
+func TestRecommendationsNeedEnoughConsistentMessages(t *testing.T) {
+	engine := newEngine(t, []email.EmailMessage{
+		{ID: "a-1", Subject: "Ping", Sender: "a@example.org", ReceivedAt: now.Add(-time.Hour)},
+		{ID: "a-2", Subject: "Pong", Sender: "a@example.org", ReceivedAt: now.Add(-2 * time.Hour)},
+		{ID: "b-1", Subject: "Invoice 1", Sender: "b@example.org", ReceivedAt: now.Add(-time.Hour)},
+		{ID: "b-2", Subject: "Invoice 2", Sender: "b@example.org", ReceivedAt: now.Add(-2 * time.Hour)},
+		{ID: "b-3", Subject: "Invoice 3", Sender: "b@example.org", Labels: []string{"INBOX"}, ReceivedAt: now.Add(-3 * time.Hour)},
+	})
+	list, err := engine.Recommend(context.Background(), 0)
+	if err != nil || list == nil || len(list) != 0 {
+		t.Fatalf("expected no recommendations, got %+v (%v)", list, err)
+	}
+
+	// Without inbox labels from the provider no message counts as archived.
+	unlabelled := newEngine(t, []email.EmailMessage{
+		{ID: "c-1", Subject: "Receipt", Sender: "c@example.org", ReceivedAt: now.Add(-time.Hour)},
+		{ID: "c-2", Subject: "Your order", Sender: "c@example.org", ReceivedAt: now.Add(-2 * time.Hour)},
+		{ID: "c-3", Subject: "Shipping update", Sender: "c@example.org", ReceivedAt: now.Add(-3 * time.Hour)},
+	})
+	if list, err := unlabelled.Recommend(context.Background(), 0); err != nil || len(list) != 0 {
+		t.Fatalf("expected no archive recommendation without inbox labels, got %+v (%v)", list, err)
+	}
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := engine.Recommend(cancelled, 0); err == nil {
+		t.Fatal("expected an error for a cancelled context")
+	}
+}
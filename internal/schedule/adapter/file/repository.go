@@ -0,0 +1,165 @@
+package file
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/example/iboz/internal/schedule"
+	"github.com/example/iboz/internal/schedule/adapter/memory"
+)
+
+var _ schedule.Repository = (*Repository)(nil)
+
+// Repository persists scheduled actions as a JSON document so they survive
+// restarts. Every mutation rewrites the file through a temporary file and an
+// atomic rename. It is safe for one process at a time.
+type Repository struct {
+	mu    sync.Mutex
+	path  string
+	cache *memory.Repository
+}
+
+// Open loads the actions stored at path, creating the parent directory if needed.
+func Open(path string) (*Repository, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, fmt.Errorf("file: create data directory: %w", err)
+	}
+	repo := &Repository{path: path, cache: memory.NewRepository()}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return repo, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("file: read %s: %w", path, err)
+	}
+	var actions []schedule.Action
+	if err := json.Unmarshal(data, &actions); err != nil {
+		return nil, fmt.Errorf("file: decode %s: %w", path, err)
+	}
+	for _, action := range actions {
+		if err := repo.cache.Create(context.Background(), action); err != nil {
+			return nil, err
+		}
+	}
+	return repo, nil
+}
+
+// Create inserts a new action.
+func (r *Repository) Create(ctx context.Context, action schedule.Action) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.mutate(ctx, func(next *memory.Repository) (bool, error) {
+		return true, next.Create(ctx, action)
+	})
+}
+
+// Get returns the action with the supplied identifier if present.
+func (r *Repository) Get(ctx context.Context, id string) (*schedule.Action, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.cache.Get(ctx, id)
+}
+
+// List returns every stored action.
+func (r *Repository) List(ctx context.Context) ([]schedule.Action, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.cache.List(ctx)
+}
+
+// Update replaces an action when its version matches the stored one.
+func (r *Repository) Update(ctx context.Context, action schedule.Action) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.mutate(ctx, func(next *memory.Repository) (bool, error) {
+		return true, next.Update(ctx, action)
+	})
+}
+
+// Claim leases due actions to owner and persists the leases before returning
+// them, so a restart never hands out an action that was already claimed.
+func (r *Repository) Claim(ctx context.Context, owner string, now, leaseUntil time.Time, limit int) ([]schedule.Action, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var claimed []schedule.Action
+	err := r.mutate(ctx, func(next *memory.Repository) (bool, error) {
+		var err error
+		claimed, err = next.Claim(ctx, owner, now, leaseUntil, limit)
+		return len(claimed) > 0, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return claimed, nil
+}
+
+// mutate applies change to a copy of the cache and, when change reports that
+// it changed something, writes the copy to disk and only then swaps it in, so
+// a failed write leaves memory and disk in step. Callers hold r.mu.
+func (r *Repository) mutate(ctx context.Context, change func(next *memory.Repository) (bool, error)) error {
+	actions, err := r.cache.List(ctx)
+	if err != nil {
+		return err
+	}
+	next := memory.NewRepository()
+	for _, action := range actions {
+		if err := next.Create(ctx, action); err != nil {
+			return err
+		}
+	}
+	changed, err := change(next)
+	if err != nil || !changed {
+		return err
+	}
+	if err := r.persist(ctx, next); err != nil {
+		return err
+	}
+	r.cache = next
+	return nil
+}
+
+func (r *Repository) persist(ctx context.Context, cache *memory.Repository) error {
+	actions, err := cache.List(ctx)
+	if err != nil {
+		return err
+	}
+	sort.Slice(actions, func(i, j int) bool { return actions[i].ID < actions[j].ID })
+	data, err := json.Marshal(actions)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(r.path), filepath.Base(r.path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("file: create temp file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("file: write %s: %w", tmp.Name(), err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("file: sync %s: %w", tmp.Name(), err)
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), r.path); err != nil {
+		return fmt.Errorf("file: replace %s: %w", r.path, err)
+	}
+	return nil
+}
@@ -0,0 +1,155 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/example/iboz/internal/schedule"
+)
+
+var _ schedule.Repository = (*Repository)(nil)
+
+// Repository provides an in-memory implementation of the schedule.Repository port.
+type Repository struct {
+	mu      sync.Mutex
+	actions map[string]schedule.Action
+}
+
+// NewRepository builds a new in-memory schedule repository.
+func NewRepository() *Repository {
+	return &Repository{actions: make(map[string]schedule.Action)}
+}
+
+// Create inserts a new action.
+func (r *Repository) Create(ctx context.Context, action schedule.Action) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.actions[action.ID]; ok {
+		return fmt.Errorf("scheduled action %s already exists", action.ID)
+	}
+	r.actions[action.ID] = clone(action)
+	return nil
+}
+
+// Get returns the action with the supplied identifier if present.
+func (r *Repository) Get(ctx context.Context, id string) (*schedule.Action, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	action, ok := r.actions[id]
+	if !ok {
+		return nil, nil
+	}
+	cloned := clone(action)
+	return &cloned, nil
+}
+
+// List returns every stored action.
+func (r *Repository) List(ctx context.Context) ([]schedule.Action, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	list := make([]schedule.Action, 0, len(r.actions))
+	for _, action := range r.actions {
+		list = append(list, clone(action))
+	}
+	return list, nil
+}
+
+// Update replaces an action when its version matches the stored one.
+func (r *Repository) Update(ctx context.Context, action schedule.Action) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.actions[action.ID]
+	if !ok || stored.Version != action.Version {
+		return schedule.ErrConflict
+	}
+	action.Version++
+	r.actions[action.ID] = clone(action)
+	return nil
+}
+
+// Claim leases due actions to owner.
+func (r *Repository) Claim(ctx context.Context, owner string, now, leaseUntil time.Time, limit int) ([]schedule.Action, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	claimed := lease(r.actions, owner, now, leaseUntil, limit)
+	for _, action := range claimed {
+		r.actions[action.ID] = clone(action)
+	}
+	return claimed, nil
+}
+
+// lease selects up to limit due actions, oldest RunAt first, and returns them
+// leased to owner without modifying actions.
+func lease(actions map[string]schedule.Action, owner string, now, leaseUntil time.Time, limit int) []schedule.Action {
+	var due []schedule.Action
+	for _, action := range actions {
+		if action.Due(now) {
+			due = append(due, action)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool {
+		if due[i].RunAt.Equal(due[j].RunAt) {
+			return due[i].ID < due[j].ID
+		}
+		return due[i].RunAt.Before(due[j].RunAt)
+	})
+	if limit > 0 && len(due) > limit {
+		due = due[:limit]
+	}
+
+	claimed := make([]schedule.Action, 0, len(due))
+	for _, action := range due {
+		until := leaseUntil
+		action.Status = schedule.StatusClaimed
+		action.LeaseOwner = owner
+		action.LeaseUntil = &until
+		action.Attempts++
+		action.UpdatedAt = now
+		action.Version++
+		claimed = append(claimed, clone(action))
+	}
+	return claimed
+}
+
+func clone(action schedule.Action) schedule.Action {
+	action.Payload = append([]byte(nil), action.Payload...)
+	action.LeaseUntil = cloneTime(action.LeaseUntil)
+	action.CompletedAt = cloneTime(action.CompletedAt)
+	return action
+}
+
+func cloneTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	v := *t
+	return &v
+}
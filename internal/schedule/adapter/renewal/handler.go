@@ -0,0 +1,61 @@
+package renewal
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"github.com/example/iboz/internal/push"
+	"github.com/example/iboz/internal/schedule"
+)
+
+// Type identifies scheduled push subscription renewals.
+const Type = push.RenewalType
+
+var _ schedule.Handler = (*Handler)(nil)
+
+// Handler renews a push subscription when the action runs. Renew schedules
+// the following renewal, so each subscription keeps exactly one pending action.
+type Handler struct {
+	push push.PushService
+}
+
+// NewHandler constructs a renewal Handler.
+func NewHandler(service push.PushService) *Handler {
+	if service == nil {
+		panic("renewal: push service dependency is required")
+	}
+	return &Handler{push: service}
+}
+
+// Validate implements the schedule.Handler interface.
+func (h *Handler) Validate(raw json.RawMessage) error {
+	_, err := decode(raw)
+	return err
+}
+
+// Run implements the schedule.Handler interface. Renewals of removed
+// subscriptions succeed without doing anything.
+func (h *Handler) Run(ctx context.Context, action schedule.Action) error {
+	payload, err := decode(action.Payload)
+	if err != nil {
+		return err
+	}
+	_, err = h.push.Renew(ctx, payload.SubscriptionID)
+	if errors.Is(err, push.ErrSubscriptionNotFound) {
+		return nil
+	}
+	return err
+}
+
+func decode(raw json.RawMessage) (push.RenewalPayload, error) {
+	var payload push.RenewalPayload
+	if err := json.Unmarshal(raw, &payload); err != nil {
+		return push.RenewalPayload{}, errors.New("renewal payload must be a JSON object")
+	}
+	if strings.TrimSpace(payload.SubscriptionID) == "" {
+		return push.RenewalPayload{}, errors.New("subscriptionId is required")
+	}
+	return payload, nil
+}
@@ -0,0 +1,72 @@
+package reply
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"github.com/example/iboz/internal/email"
+	"github.com/example/iboz/internal/schedule"
+)
+
+// Type identifies scheduled replies.
+const Type = "email.reply"
+
+var _ schedule.Handler = (*Handler)(nil)
+
+// Payload is the JSON payload of a scheduled reply, such as "send at 9am" or a
+// follow-up asking for an update in two days.
+type Payload struct {
+	MessageID string `json:"messageId"`
+	Subject   string `json:"subject,omitempty"`
+	TextBody  string `json:"textBody,omitempty"`
+	HTMLBody  string `json:"htmlBody,omitempty"`
+}
+
+// Handler sends a threaded reply through the reply service when the action runs.
+type Handler struct {
+	replies email.ReplyService
+}
+
+// NewHandler constructs a scheduled reply Handler.
+func NewHandler(replies email.ReplyService) *Handler {
+	if replies == nil {
+		panic("reply: reply service dependency is required")
+	}
+	return &Handler{replies: replies}
+}
+
+// Validate implements the schedule.Handler interface.
+func (h *Handler) Validate(raw json.RawMessage) error {
+	_, err := decode(raw)
+	return err
+}
+
+// Run implements the schedule.Handler interface.
+func (h *Handler) Run(ctx context.Context, action schedule.Action) error {
+	payload, err := decode(action.Payload)
+	if err != nil {
+		return err
+	}
+	_, err = h.replies.Reply(ctx, payload.MessageID, email.ReplyRequest{
+		Subject:  payload.Subject,
+		TextBody: payload.TextBody,
+		HTMLBody: payload.HTMLBody,
+	})
+	return err
+}
+
+func decode(raw json.RawMessage) (Payload, error) {
+	var payload Payload
+	if err := json.Unmarshal(raw, &payload); err != nil {
+		return Payload{}, errors.New("reply payload must be a JSON object")
+	}
+	if strings.TrimSpace(payload.MessageID) == "" {
+		return Payload{}, errors.New("messageId is required")
+	}
+	if strings.TrimSpace(payload.TextBody) == "" && strings.TrimSpace(payload.HTMLBody) == "" {
+		return Payload{}, email.ErrEmptyReply
+	}
+	return payload, nil
+}
@@ -0,0 +1,110 @@
+package schedule
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+// Status enumerates the lifecycle of a scheduled action.
+type Status string
+
+const (
+	StatusScheduled        Status = "scheduled"
+	StatusAwaitingApproval Status = "awaiting_approval"
+	StatusClaimed          Status = "claimed"
+	StatusDone             Status = "done"
+	StatusFailed           Status = "failed"
+	StatusCancelled        Status = "cancelled"
+)
+
+const (
+	// DefaultLease bounds how long a worker may hold an action before it is abandoned.
+	DefaultLease = 5 * time.Minute
+	// DefaultBatchSize is the number of due actions claimed per run.
+	DefaultBatchSize = 20
+)
+
+var (
+	// ErrActionNotFound is returned when an action does not exist.
+	ErrActionNotFound = errors.New("scheduled action not found")
+	// ErrInvalidAction is returned when a schedule request fails validation.
+	ErrInvalidAction = errors.New("invalid scheduled action")
+	// ErrNotPending is returned when cancelling, rescheduling or approving an action that already ran or was claimed.
+	ErrNotPending = errors.New("scheduled action is no longer pending")
+	// ErrConflict is returned by repositories when an update races with another writer.
+	ErrConflict = errors.New("scheduled action was modified concurrently")
+	// ErrUnknownStatus is returned when filtering by an unsupported status.
+	ErrUnknownStatus = errors.New("unknown scheduled action status")
+)
+
+// Action is a unit of work deferred until RunAt. Version increases on every
+// stored change and guards updates against concurrent writers.
+type Action struct {
+	ID          string          `json:"id"`
+	Type        string          `json:"type"`
+	Payload     json.RawMessage `json:"payload,omitempty"`
+	RunAt       time.Time       `json:"runAt"`
+	Status      Status          `json:"status"`
+	Version     int             `json:"version"`
+	LeaseOwner  string          `json:"leaseOwner,omitempty"`
+	LeaseUntil  *time.Time      `json:"leaseUntil,omitempty"`
+	Attempts    int             `json:"attempts"`
+	LastError   string          `json:"lastError,omitempty"`
+	CreatedAt   time.Time       `json:"createdAt"`
+	UpdatedAt   time.Time       `json:"updatedAt"`
+	CompletedAt *time.Time      `json:"completedAt,omitempty"`
+}
+
+// Due reports whether the action is waiting to be claimed at now.
+func (a Action) Due(now time.Time) bool {
+	return a.Status == StatusScheduled && !a.RunAt.After(now)
+}
+
+// Pending reports whether the action has yet to run, approved or not.
+func (a Action) Pending() bool {
+	return a.Status == StatusScheduled || a.Status == StatusAwaitingApproval
+}
+
+// Request describes an action to schedule. Exactly one of RunAt or Delay must be
+// set; BusinessHours defers the resulting time to the user's next working time.
+// An action that RequiresApproval does not run until it is approved.
+type Request struct {
+	Type             string
+	Payload          json.RawMessage
+	RunAt            time.Time
+	Delay            time.Duration
+	BusinessHours    bool
+	RequiresApproval bool
+}
+
+// Repository defines the persistence contract for scheduled actions.
+type Repository interface {
+	Create(ctx context.Context, action Action) error
+	Get(ctx context.Context, id string) (*Action, error)
+	List(ctx context.Context) ([]Action, error)
+	// Update stores action if the stored version equals action.Version and
+	// increments the version; otherwise it returns ErrConflict.
+	Update(ctx context.Context, action Action) error
+	// Claim atomically leases up to limit due actions to owner, oldest RunAt first.
+	Claim(ctx context.Context, owner string, now, leaseUntil time.Time, limit int) ([]Action, error)
+}
+
+// Handler executes one action type.
+type Handler interface {
+	// Validate rejects malformed payloads when the action is scheduled.
+	Validate(payload json.RawMessage) error
+	Run(ctx context.Context, action Action) error
+}
+
+// ParseStatus validates a status filter; the empty string matches every action.
+func ParseStatus(value string) (Status, error) {
+	switch status := Status(strings.ToLower(strings.TrimSpace(value))); status {
+	case "", StatusScheduled, StatusAwaitingApproval, StatusClaimed, StatusDone, StatusFailed, StatusCancelled:
+		return status, nil
+	default:
+		return "", ErrUnknownStatus
+	}
+}
@@ -0,0 +1,339 @@
+package schedule_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/example/iboz/internal/calendar"
+	"github.com/example/iboz/internal/email"
+	"github.com/example/iboz/internal/schedule"
+	"github.com/example/iboz/internal/schedule/adapter/file"
+	"github.com/example/iboz/internal/schedule/adapter/memory"
+	"github.com/example/iboz/internal/testutil"
+)
+
+type countingHandler struct {
+	mu   sync.Mutex
+	runs map[string]int
+	err  error
+}
+
+func (h *countingHandler) Validate(payload json.RawMessage) error {
+	if !json.Valid(payload) {
+		return errors.New("payload must be JSON")
+	}
+	return nil
+}
+
+func (h *countingHandler) Run(_ context.Context, action schedule.Action) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.runs == nil {
+		h.runs = make(map[string]int)
+	}
+	h.runs[action.ID]++
+	return h.err
+}
+
+func newService(t *testing.T, repo schedule.Repository, handler schedule.Handler, clock email.Clock) *schedule.Service {
+	t.Helper()
+	cal, err := calendar.New(calendar.DefaultHours(), clock)
+	if err != nil {
+		t.Fatalf("calendar: %v", err)
+	}
+	return schedule.NewService(repo, map[string]schedule.Handler{"test": handler}, cal, clock, schedule.Config{Lease: time.Minute})
+}
+
+func TestRunDueRunsActionsOnceWhenDue(t *testing.T) {
+	ctx := context.Background()
+	clock := testutil.NewClock(time.Date(2025, time.March, 18, 12, 0, 0, 0, time.UTC))
+	handler := &countingHandler{}
+	svc := newService(t, memory.NewRepository(), handler, clock)
+
+	if _, err := svc.Schedule(ctx, schedule.Request{Type: "unknown", Payload: json.RawMessage(`{}`), Delay: time.Hour}); !errors.Is(err, schedule.ErrInvalidAction) {
+		t.Fatalf("expected invalid action for unknown type, got %v", err)
+	}
+	if _, err := svc.Schedule(ctx, schedule.Request{Type: "test", Payload: json.RawMessage(`{}`), RunAt: clock.Now().Add(-time.Hour)}); !errors.Is(err, schedule.ErrInvalidAction) {
+		t.Fatalf("expected invalid action for past run time, got %v", err)
+	}
+
+	action, err := svc.Schedule(ctx, schedule.Request{Type: "test", Payload: json.RawMessage(`{"n":1}`), Delay: time.Hour})
+	if err != nil {
+		t.Fatalf("schedule: %v", err)
+	}
+	if ran, err := svc.RunDue(ctx); err != nil || len(ran) != 0 {
+		t.Fatalf("nothing should run yet: %+v (%v)", ran, err)
+	}
+
+	clock.Set(clock.Now().Add(time.Hour))
+	ran, err := svc.RunDue(ctx)
+	if err != nil || len(ran) != 1 || ran[0].Status != schedule.StatusDone || ran[0].Attempts != 1 {
+		t.Fatalf("expected the action to run, got %+v (%v)", ran, err)
+	}
+	if ran, _ := svc.RunDue(ctx); len(ran) != 0 {
+		t.Fatalf("action should run only once, got %+v", ran)
+	}
+	if handler.runs[action.ID] != 1 {
+		t.Fatalf("expected one run, got %d", handler.runs[action.ID])
+	}
+	if _, err := svc.Cancel(ctx, action.ID); !errors.Is(err, schedule.ErrNotPending) {
+		t.Fatalf("expected completed action to be immutable, got %v", err)
+	}
+}
+
+func TestConcurrentWorkersClaimEachActionOnce(t *testing.T) {
+	ctx := context.Background()
+	clock := testutil.NewClock(time.Date(2025, time.March, 18, 12, 0, 0, 0, time.UTC))
+	repo := memory.NewRepository()
+	handler := &countingHandler{}
+	first := newService(t, repo, handler, clock)
+	second := newService(t, repo, handler, clock)
+
+	for i := 0; i < 10; i++ {
+		if _, err := first.Schedule(ctx, schedule.Request{Type: "test", Payload: json.RawMessage(`{}`), Delay: time.Minute}); err != nil {
+			t.Fatalf("schedule: %v", err)
+		}
+	}
+	clock.Set(clock.Now().Add(time.Minute))
+
+	var wg sync.WaitGroup
+	for _, svc := range []*schedule.Service{first, second, first, second} {
+		wg.Add(1)
+		go func(svc *schedule.Service) {
+			defer wg.Done()
+			if _, err := svc.RunDue(ctx); err != nil {
+				t.Errorf("run due: %v", err)
+			}
+		}(svc)
+	}
+	wg.Wait()
+
+	if len(handler.runs) != 10 {
+		t.Fatalf("expected ten distinct actions to run, got %d", len(handler.runs))
+	}
+	for id, runs := range handler.runs {
+		if runs != 1 {
+			t.Fatalf("action %s ran %d times", id, runs)
+		}
+	}
+}
+
+func TestExpiredLeaseIsFailedNotRerun(t *testing.T) {
+	ctx := context.Background()
+	clock := testutil.NewClock(time.Date(2025, time.March, 18, 12, 0, 0, 0, time.UTC))
+	repo := memory.NewRepository()
+	handler := &countingHandler{}
+	svc := newService(t, repo, handler, clock)
+
+	action, err := svc.Schedule(ctx, schedule.Request{Type: "test", Payload: json.RawMessage(`{}`), Delay: time.Minute})
+	if err != nil {
+		t.Fatalf("schedule: %v", err)
+	}
+	// Simulate a worker that claimed the action and crashed.
+	clock.Set(clock.Now().Add(time.Minute))
+	if _, err := repo.Claim(ctx, "crashed-worker", clock.Now(), clock.Now().Add(time.Minute), 10); err != nil {
+		t.Fatalf("claim: %v", err)
+	}
+
+	clock.Set(clock.Now().Add(2 * time.Minute))
+	if ran, err := svc.RunDue(ctx); err != nil || len(ran) != 0 {
+		t.Fatalf("expected no runs, got %+v (%v)", ran, err)
+	}
+	stored, err := svc.Get(ctx, action.ID)
+	if err != nil || stored.Status != schedule.StatusFailed || stored.LastError == "" {
+		t.Fatalf("expected abandoned action to fail, got %+v (%v)", stored, err)
+	}
+	if len(handler.runs) != 0 {
+		t.Fatalf("abandoned action must not run again")
+	}
+}
+
+// slowHandler takes 40 seconds of clock time per action and records whether
+// each action still held its lease when it ran.
+type slowHandler struct {
+	countingHandler
+	clock   *testutil.Clock
+	expired []string
+}
+
+func (h *slowHandler) Run(ctx context.Context, action schedule.Action) error {
+	h.clock.Advance(40 * time.Second)
+	if action.LeaseUntil == nil || !h.clock.Now().Before(*action.LeaseUntil) {
+		h.expired = append(h.expired, action.ID)
+	}
+	return h.countingHandler.Run(ctx, action)
+}
+
+func TestEachActionIsLeasedWhenItRuns(t *testing.T) {
+	ctx := context.Background()
+	clock := testutil.NewClock(time.Date(2025, time.March, 18, 12, 0, 0, 0, time.UTC))
+	handler := &slowHandler{clock: clock}
+	svc := newService(t, memory.NewRepository(), handler, clock)
+
+	for i := 0; i < 3; i++ {
+		if _, err := svc.Schedule(ctx, schedule.Request{Type: "test", Payload: json.RawMessage(`{}`), Delay: time.Minute}); err != nil {
+			t.Fatalf("schedule: %v", err)
+		}
+	}
+	clock.Set(clock.Now().Add(time.Minute))
+
+	ran, err := svc.RunDue(ctx)
+	if err != nil || len(ran) != 3 {
+		t.Fatalf("expected three runs, got %+v (%v)", ran, err)
+	}
+	if len(handler.expired) != 0 {
+		t.Fatalf("expected every action to run within its lease, expired for %v", handler.expired)
+	}
+}
+
+func TestRescheduleAndCancel(t *testing.T) {
+	ctx := context.Background()
+	clock := testutil.NewClock(time.Date(2025, time.March, 18, 12, 0, 0, 0, time.UTC))
+	svc := newService(t, memory.NewRepository(), &countingHandler{}, clock)
+
+	action, err := svc.Schedule(ctx, schedule.Request{Type: "test", Payload: json.RawMessage(`{}`), Delay: time.Hour})
+	if err != nil {
+		t.Fatalf("schedule: %v", err)
+	}
+	// 20:00 Tuesday defers to Wednesday 09:00 in business hours.
+	rescheduled, err := svc.Reschedule(ctx, action.ID, schedule.Request{Delay: 8 * time.Hour, BusinessHours: true})
+	if err != nil {
+		t.Fatalf("reschedule: %v", err)
+	}
+	if want := time.Date(2025, time.March, 19, 9, 0, 0, 0, time.UTC); !rescheduled.RunAt.Equal(want) {
+		t.Fatalf("expected %s, got %s", want, rescheduled.RunAt)
+	}
+	if _, err := svc.Cancel(ctx, action.ID); err != nil {
+		t.Fatalf("cancel: %v", err)
+	}
+	clock.Set(rescheduled.RunAt)
+	if ran, _ := svc.RunDue(ctx); len(ran) != 0 {
+		t.Fatalf("cancelled action should not run, got %+v", ran)
+	}
+	if _, err := svc.Reschedule(ctx, "missing", schedule.Request{Delay: time.Hour}); !errors.Is(err, schedule.ErrActionNotFound) {
+		t.Fatalf("expected not found, got %v", err)
+	}
+}
+
+func TestActionsAwaitingApprovalRunOnceApproved(t *testing.T) {
+	ctx := context.Background()
+	clock := testutil.NewClock(time.Date(2025, time.March, 18, 12, 0, 0, 0, time.UTC))
+	handler := &countingHandler{}
+	svc := newService(t, memory.NewRepository(), handler, clock)
+
+	action, err := svc.Schedule(ctx, schedule.Request{Type: "test", Payload: json.RawMessage(`{}`), Delay: time.Hour, RequiresApproval: true})
+	if err != nil || action.Status != schedule.StatusAwaitingApproval {
+		t.Fatalf("expected an action awaiting approval, got %+v (%v)", action, err)
+	}
+	clock.Advance(2 * time.Hour)
+	if ran, _ := svc.RunDue(ctx); len(ran) != 0 {
+		t.Fatalf("unapproved action should not run, got %+v", ran)
+	}
+
+	approved, err := svc.Approve(ctx, action.ID)
+	if err != nil || approved.Status != schedule.StatusScheduled {
+		t.Fatalf("expected the action to be scheduled, got %+v (%v)", approved, err)
+	}
+	if _, err := svc.Approve(ctx, action.ID); !errors.Is(err, schedule.ErrNotPending) {
+		t.Fatalf("expected a second approval to be rejected, got %v", err)
+	}
+	if ran, err := svc.RunDue(ctx); err != nil || len(ran) != 1 || handler.runs[action.ID] != 1 {
+		t.Fatalf("expected the approved action to run, got %+v (%v)", ran, err)
+	}
+}
+
+func TestFileRepositorySurvivesRestart(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "actions.json")
+	clock := testutil.NewClock(time.Date(2025, time.March, 18, 12, 0, 0, 0, time.UTC))
+
+	repo, err := file.Open(path)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	svc := newService(t, repo, &countingHandler{}, clock)
+	pending, err := svc.Schedule(ctx, schedule.Request{Type: "test", Payload: json.RawMessage(`{"keep":true}`), Delay: time.Hour})
+	if err != nil {
+		t.Fatalf("schedule: %v", err)
+	}
+	claimed, err := svc.Schedule(ctx, schedule.Request{Type: "test", Payload: json.RawMessage(`{}`), Delay: time.Minute})
+	if err != nil {
+		t.Fatalf("schedule: %v", err)
+	}
+	if _, err := repo.Claim(ctx, "crashed-worker", clock.Now().Add(time.Minute), clock.Now().Add(2*time.Minute), 10); err != nil {
+		t.Fatalf("claim: %v", err)
+	}
+
+	reopened, err := file.Open(path)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	handler := &countingHandler{}
+	svc = newService(t, reopened, handler, clock)
+	clock.Set(clock.Now().Add(time.Hour))
+	ran, err := svc.RunDue(ctx)
+	if err != nil || len(ran) != 1 || ran[0].ID != pending.ID || string(ran[0].Payload) != `{"keep":true}` {
+		t.Fatalf("expected the pending action to run after restart, got %+v (%v)", ran, err)
+	}
+	if handler.runs[claimed.ID] != 0 {
+		t.Fatalf("action claimed before the restart must not run again")
+	}
+}
+
+func TestFileRepositoryKeepsMemoryInStepWithDiskOnWriteFailure(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "actions.json")
+	clock := testutil.NewClock(time.Date(2025, time.March, 18, 12, 0, 0, 0, time.UTC))
+
+	repo, err := file.Open(path)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	svc := newService(t, repo, &countingHandler{}, clock)
+	action, err := svc.Schedule(ctx, schedule.Request{Type: "test", Payload: json.RawMessage(`{}`), Delay: time.Minute})
+	if err != nil {
+		t.Fatalf("schedule: %v", err)
+	}
+
+	// A directory in place of the file makes every write fail.
+	if err := os.Remove(path); err != nil {
+		t.Fatalf("remove: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Join(path, "blocked"), 0o700); err != nil {
+		t.Fatalf("block: %v", err)
+	}
+	if _, err := svc.Schedule(ctx, schedule.Request{Type: "test", Payload: json.RawMessage(`{}`), Delay: time.Minute}); err == nil {
+		t.Fatal("expected the failed write to be reported")
+	}
+	if _, err := repo.Claim(ctx, "worker", clock.Now().Add(time.Minute), clock.Now().Add(2*time.Minute), 10); err == nil {
+		t.Fatal("expected the failed claim write to be reported")
+	}
+	list, err := svc.List(ctx, "")
+	if err != nil || len(list) != 1 || list[0].ID != action.ID || list[0].Status != schedule.StatusScheduled {
+		t.Fatalf("expected only the persisted, unclaimed action in memory, got %+v (%v)", list, err)
+	}
+
+	if err := os.RemoveAll(path); err != nil {
+		t.Fatalf("unblock: %v", err)
+	}
+	claimed, err := repo.Claim(ctx, "worker", clock.Now().Add(time.Minute), clock.Now().Add(2*time.Minute), 10)
+	if err != nil || len(claimed) != 1 {
+		t.Fatalf("expected the action to be claimable once writes succeed, got %+v (%v)", claimed, err)
+	}
+}
+
+func TestHandledTypesAreNotUserFacing(t *testing.T) {
+	clock := testutil.NewClock(time.Date(2025, time.March, 18, 12, 0, 0, 0, time.UTC))
+	svc := newService(t, memory.NewRepository(), &countingHandler{}, clock)
+	svc.Handle("internal.renew", &countingHandler{})
+
+	if !svc.UserFacing("test") || svc.UserFacing("internal.renew") || svc.UserFacing("missing") {
+		t.Fatal("expected only the types passed to NewService to be user-facing")
+	}
+}
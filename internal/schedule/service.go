@@ -0,0 +1,346 @@
+package schedule
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/example/iboz/internal/calendar"
+	"github.com/example/iboz/internal/email"
+)
+
+// Config tunes claiming behaviour.
+type Config struct {
+	Lease     time.Duration
+	BatchSize int
+}
+
+// ScheduleService exposes the scheduled action queue.
+type ScheduleService interface {
+	Schedule(ctx context.Context, req Request) (*Action, error)
+	Get(ctx context.Context, id string) (*Action, error)
+	List(ctx context.Context, status Status) ([]Action, error)
+	Cancel(ctx context.Context, id string) (*Action, error)
+	Reschedule(ctx context.Context, id string, req Request) (*Action, error)
+	Approve(ctx context.Context, id string) (*Action, error)
+	RunDue(ctx context.Context) ([]Action, error)
+	UserFacing(actionType string) bool
+}
+
+var _ ScheduleService = (*Service)(nil)
+
+// Service stores deferred actions and runs them at most once when due.
+type Service struct {
+	repo      Repository
+	handlers  map[string]Handler
+	internal  map[string]bool
+	calendars calendar.Provider
+	clock     email.Clock
+	owner     string
+	cfg       Config
+}
+
+// NewService constructs a schedule Service. handlers is keyed by action type.
+func NewService(repo Repository, handlers map[string]Handler, calendars calendar.Provider, clock email.Clock, cfg Config) *Service {
+	if repo == nil {
+		panic("schedule: repository dependency is required")
+	}
+	if calendars == nil {
+		panic("schedule: calendar dependency is required")
+	}
+	if clock == nil {
+		panic("schedule: clock dependency is required")
+	}
+	if cfg.Lease < 0 || cfg.BatchSize < 0 {
+		panic("schedule: lease and batch size cannot be negative")
+	}
+	if cfg.Lease == 0 {
+		cfg.Lease = DefaultLease
+	}
+	if cfg.BatchSize == 0 {
+		cfg.BatchSize = DefaultBatchSize
+	}
+	registered := make(map[string]Handler, len(handlers))
+	for actionType, handler := range handlers {
+		if handler == nil {
+			panic(fmt.Sprintf("schedule: handler for %q is nil", actionType))
+		}
+		registered[actionType] = handler
+	}
+	owner, err := randomHex(8)
+	if err != nil {
+		panic(fmt.Sprintf("schedule: generate lease owner: %v", err))
+	}
+	return &Service{repo: repo, handlers: registered, internal: make(map[string]bool), calendars: calendars, clock: clock, owner: "worker-" + owner, cfg: cfg}
+}
+
+// Handle registers the handler of an action type for services that depend on
+// the scheduler themselves. Such types are internal and not user-facing. It
+// must be called before the worker runs.
+func (s *Service) Handle(actionType string, handler Handler) {
+	if handler == nil {
+		panic(fmt.Sprintf("schedule: handler for %q is nil", actionType))
+	}
+	if _, ok := s.handlers[actionType]; ok {
+		panic(fmt.Sprintf("schedule: handler for %q registered twice", actionType))
+	}
+	s.handlers[actionType] = handler
+	s.internal[actionType] = true
+}
+
+// UserFacing reports whether users may manage actions of actionType. Types
+// registered through Handle belong to other services.
+func (s *Service) UserFacing(actionType string) bool {
+	_, ok := s.handlers[actionType]
+	return ok && !s.internal[actionType]
+}
+
+// Schedule validates the request and stores a new pending action, held until
+// approved when the request requires approval.
+func (s *Service) Schedule(ctx context.Context, req Request) (*Action, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	req.Type = strings.TrimSpace(req.Type)
+	handler, ok := s.handlers[req.Type]
+	if !ok {
+		return nil, fmt.Errorf("%w: unsupported type %q", ErrInvalidAction, req.Type)
+	}
+	if err := handler.Validate(req.Payload); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidAction, err)
+	}
+	now := s.clock.Now().UTC()
+	runAt, err := s.resolveRunAt(ctx, req, now)
+	if err != nil {
+		return nil, err
+	}
+	id, err := randomHex(6)
+	if err != nil {
+		return nil, fmt.Errorf("generate action id: %w", err)
+	}
+
+	action := Action{
+		ID:        "act-" + id,
+		Type:      req.Type,
+		Payload:   append([]byte(nil), req.Payload...),
+		RunAt:     runAt,
+		Status:    StatusScheduled,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if req.RequiresApproval {
+		action.Status = StatusAwaitingApproval
+	}
+	if err := s.repo.Create(ctx, action); err != nil {
+		return nil, err
+	}
+	return &action, nil
+}
+
+// Get returns an action by ID.
+func (s *Service) Get(ctx context.Context, id string) (*Action, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	action, err := s.repo.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if action == nil {
+		return nil, ErrActionNotFound
+	}
+	return action, nil
+}
+
+// List returns actions ordered by run time, optionally filtered by status.
+func (s *Service) List(ctx context.Context, status Status) ([]Action, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	all, err := s.repo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	list := make([]Action, 0, len(all))
+	for _, action := range all {
+		if status == "" || action.Status == status {
+			list = append(list, action)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].RunAt.Before(list[j].RunAt) })
+	return list, nil
+}
+
+// Cancel stops a pending action from running.
+func (s *Service) Cancel(ctx context.Context, id string) (*Action, error) {
+	return s.modifyPending(ctx, id, func(action *Action, now time.Time) error {
+		action.Status = StatusCancelled
+		action.CompletedAt = &now
+		return nil
+	})
+}
+
+// Approve releases an action awaiting approval to run at its scheduled time.
+func (s *Service) Approve(ctx context.Context, id string) (*Action, error) {
+	return s.modifyPending(ctx, id, func(action *Action, _ time.Time) error {
+		if action.Status != StatusAwaitingApproval {
+			return ErrNotPending
+		}
+		action.Status = StatusScheduled
+		return nil
+	})
+}
+
+// Reschedule moves a pending action to the time described by req. Type and
+// Payload of req are ignored.
+func (s *Service) Reschedule(ctx context.Context, id string, req Request) (*Action, error) {
+	return s.modifyPending(ctx, id, func(action *Action, now time.Time) error {
+		runAt, err := s.resolveRunAt(ctx, req, now)
+		if err != nil {
+			return err
+		}
+		action.RunAt = runAt
+		return nil
+	})
+}
+
+func (s *Service) modifyPending(ctx context.Context, id string, modify func(*Action, time.Time) error) (*Action, error) {
+	action, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !action.Pending() {
+		return nil, ErrNotPending
+	}
+	now := s.clock.Now().UTC()
+	if err := modify(action, now); err != nil {
+		return nil, err
+	}
+	action.UpdatedAt = now
+	if err := s.repo.Update(ctx, *action); err != nil {
+		if errors.Is(err, ErrConflict) {
+			return nil, ErrNotPending
+		}
+		return nil, err
+	}
+	action.Version++
+	return action, nil
+}
+
+// RunDue fails actions whose lease expired without completing, then claims and
+// runs up to Config.BatchSize due actions one at a time. Each action is leased
+// right before it runs and its run is bounded by that lease. A claimed action is
+// never handed out again, so a worker that crashes mid-run leaves it failed
+// rather than running it twice.
+func (s *Service) RunDue(ctx context.Context) ([]Action, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := s.abandonExpired(ctx, s.clock.Now().UTC()); err != nil {
+		return nil, err
+	}
+
+	var ran []Action
+	var errs []error
+	for i := 0; i < s.cfg.BatchSize && ctx.Err() == nil; i++ {
+		now := s.clock.Now().UTC()
+		claimed, err := s.repo.Claim(ctx, s.owner, now, now.Add(s.cfg.Lease), 1)
+		if err != nil {
+			errs = append(errs, err)
+			break
+		}
+		if len(claimed) == 0 {
+			break
+		}
+		action := claimed[0]
+
+		runCtx, cancel := context.WithTimeout(ctx, s.cfg.Lease)
+		runErr := s.run(runCtx, action)
+		cancel()
+
+		completedAt := s.clock.Now().UTC()
+		action.Status = StatusDone
+		action.LastError = ""
+		if runErr != nil {
+			action.Status = StatusFailed
+			action.LastError = runErr.Error()
+			errs = append(errs, fmt.Errorf("schedule: %s %s: %w", action.Type, action.ID, runErr))
+		}
+		action.LeaseUntil = nil
+		action.UpdatedAt = completedAt
+		action.CompletedAt = &completedAt
+		if err := s.repo.Update(ctx, action); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		action.Version++
+		ran = append(ran, action)
+	}
+	return ran, errors.Join(errs...)
+}
+
+func (s *Service) run(ctx context.Context, action Action) error {
+	handler, ok := s.handlers[action.Type]
+	if !ok {
+		return fmt.Errorf("no handler registered for %q", action.Type)
+	}
+	return handler.Run(ctx, action)
+}
+
+func (s *Service) abandonExpired(ctx context.Context, now time.Time) error {
+	all, err := s.repo.List(ctx)
+	if err != nil {
+		return err
+	}
+	for _, action := range all {
+		if action.Status != StatusClaimed || action.LeaseUntil == nil || now.Before(*action.LeaseUntil) {
+			continue
+		}
+		action.Status = StatusFailed
+		action.LastError = fmt.Sprintf("lease held by %s expired before completion", action.LeaseOwner)
+		action.LeaseUntil = nil
+		action.UpdatedAt = now
+		action.CompletedAt = &now
+		if err := s.repo.Update(ctx, action); err != nil && !errors.Is(err, ErrConflict) {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Service) resolveRunAt(ctx context.Context, req Request, now time.Time) (time.Time, error) {
+	if req.RunAt.IsZero() == (req.Delay == 0) {
+		return time.Time{}, fmt.Errorf("%w: exactly one of runAt or delay is required", ErrInvalidAction)
+	}
+	if req.Delay < 0 {
+		return time.Time{}, fmt.Errorf("%w: delay cannot be negative", ErrInvalidAction)
+	}
+	runAt := req.RunAt.UTC()
+	if req.Delay != 0 {
+		runAt = now.Add(req.Delay)
+	}
+	if req.BusinessHours {
+		cal, err := s.calendars.Calendar(ctx)
+		if err != nil {
+			return time.Time{}, err
+		}
+		runAt = cal.NextWorkingTime(runAt)
+	}
+	if runAt.Before(now) {
+		return time.Time{}, fmt.Errorf("%w: run time must not be in the past", ErrInvalidAction)
+	}
+	return runAt, nil
+}
+
+func randomHex(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
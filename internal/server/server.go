@@ -3,33 +3,125 @@ package server
 import (
 	"context"
 	"embed"
+	"errors"
+	"fmt"
 	"io/fs"
 	"log"
 	"net/http"
 	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 
 	"github.com/example/iboz/internal/api"
+	"github.com/example/iboz/internal/bulk"
+	bulkmemory "github.com/example/iboz/internal/bulk/adapter/memory"
+	"github.com/example/iboz/internal/calendar"
+	"github.com/example/iboz/internal/calendar/adapter/caldav"
+	calendarmemory "github.com/example/iboz/internal/calendar/adapter/memory"
+	"github.com/example/iboz/internal/crm"
+	crmhubspot "github.com/example/iboz/internal/crm/adapter/hubspot"
+	crmmemory "github.com/example/iboz/internal/crm/adapter/memory"
+	crmsalesforce "github.com/example/iboz/internal/crm/adapter/salesforce"
+	"github.com/example/iboz/internal/dashboard"
+	dashboardruns "github.com/example/iboz/internal/dashboard/adapter/runs"
+	"github.com/example/iboz/internal/delegation"
+	delegationmail "github.com/example/iboz/internal/delegation/adapter/mail"
+	delegationmemory "github.com/example/iboz/internal/delegation/adapter/memory"
+	delegationwebhook "github.com/example/iboz/internal/delegation/adapter/webhook"
+	"github.com/example/iboz/internal/digest"
+	digestllm "github.com/example/iboz/internal/digest/adapter/llm"
+	digestmemory "github.com/example/iboz/internal/digest/adapter/memory"
 	"github.com/example/iboz/internal/email"
 	"github.com/example/iboz/internal/email/adapter/memory"
+	"github.com/example/iboz/internal/email/adapter/smtp"
 	"github.com/example/iboz/internal/email/adapter/synthetic"
+	"github.com/example/iboz/internal/focus"
+	focuschat "github.com/example/iboz/internal/focus/adapter/chat"
+	focusmemory "github.com/example/iboz/internal/focus/adapter/memory"
+	focuswebhook "github.com/example/iboz/internal/focus/adapter/webhook"
+	"github.com/example/iboz/internal/notify"
+	"github.com/example/iboz/internal/notify/adapter/slack"
+	"github.com/example/iboz/internal/notify/adapter/teams"
+	"github.com/example/iboz/internal/outbox"
+	"github.com/example/iboz/internal/periodic"
+	"github.com/example/iboz/internal/push"
+	pushgmail "github.com/example/iboz/internal/push/adapter/gmail"
+	pushgraph "github.com/example/iboz/internal/push/adapter/graph"
+	pushmemory "github.com/example/iboz/internal/push/adapter/memory"
+	"github.com/example/iboz/internal/queue"
+	"github.com/example/iboz/internal/queue/adapter/inproc"
+	queuememory "github.com/example/iboz/internal/queue/adapter/memory"
+	queuenats "github.com/example/iboz/internal/queue/adapter/nats"
+	"github.com/example/iboz/internal/recommendations"
+	"github.com/example/iboz/internal/schedule"
+	schedulefile "github.com/example/iboz/internal/schedule/adapter/file"
+	schedulememory "github.com/example/iboz/internal/schedule/adapter/memory"
+	schedulerenewal "github.com/example/iboz/internal/schedule/adapter/renewal"
+	schedulereply "github.com/example/iboz/internal/schedule/adapter/reply"
+	"github.com/example/iboz/internal/sla"
+	slachat "github.com/example/iboz/internal/sla/adapter/chat"
+	slamail "github.com/example/iboz/internal/sla/adapter/mail"
+	slamemory "github.com/example/iboz/internal/sla/adapter/memory"
+	slawebhook "github.com/example/iboz/internal/sla/adapter/webhook"
+	"github.com/example/iboz/internal/snooze"
+	snoozememory "github.com/example/iboz/internal/snooze/adapter/memory"
+	"github.com/example/iboz/internal/spam"
+	"github.com/example/iboz/internal/tasks"
+	tasksasana "github.com/example/iboz/internal/tasks/adapter/asana"
+	tasksjira "github.com/example/iboz/internal/tasks/adapter/jira"
+	tasksmemory "github.com/example/iboz/internal/tasks/adapter/memory"
+	taskswebhook "github.com/example/iboz/internal/tasks/adapter/webhook"
+	"github.com/example/iboz/internal/templates"
+	templatememory "github.com/example/iboz/internal/templates/adapter/memory"
+	"github.com/example/iboz/internal/waiting"
+	waitingmemory "github.com/example/iboz/internal/waiting/adapter/memory"
+	"github.com/example/iboz/internal/webhooks"
+	webhookmemory "github.com/example/iboz/internal/webhooks/adapter/memory"
 )
 
 //go:embed all:static
 var embeddedStatic embed.FS
 
 const (
-	defaultAddress = ":8080"
-	readTimeout    = 15 * time.Second
-	writeTimeout   = 15 * time.Second
+	defaultAddress   = ":8080"
+	readTimeout      = 15 * time.Second
+	writeTimeout     = 15 * time.Second
+	snoozeInterval   = 30 * time.Second
+	overdueInterval  = time.Minute
+	followUpInterval = 15 * time.Minute
+	slaInterval      = time.Minute
+	scheduleInterval = 15 * time.Second
+	outboxInterval   = 15 * time.Second
+	taskSyncInterval = 10 * time.Minute
+	digestInterval   = time.Minute
+	runEventInterval = time.Minute
+	// runEventWindow is how far back finished automation runs are published.
+	runEventWindow = 24 * time.Hour
+
+	waitingSyncTopic  = "email.synced.waiting"
+	slaSyncTopic      = "email.synced.sla"
+	webhooksSyncTopic = "email.synced.webhooks"
+	replySendTopic    = "email.reply.send"
 )
 
+// worker is a background loop that runs until its context is cancelled.
+type worker func(ctx context.Context)
+
 type Server struct {
 	httpServer *http.Server
+	workers    []worker
+
+	mu      sync.Mutex
+	ctx     context.Context
+	cancel  context.CancelFunc
+	stopped bool
+	wg      sync.WaitGroup
 }
 
 func New() *Server {
@@ -39,10 +131,137 @@ func New() *Server {
 	e.Use(middleware.Logger())
 	e.Use(middleware.Recover())
 
+	clock := email.NewSystemClock()
 	emailRepo := memory.NewRepository()
-	emailService := email.NewService(emailRepo, email.NewSHA256Hasher(), synthetic.NewGenerator(), email.NewSystemClock())
+	vault := memory.NewVault()
+	emailService := email.NewService(emailRepo, email.NewSHA256Hasher(), vault, synthetic.NewGenerator(), clock)
+	emailService.ClassifyWith(bulk.NewDetector())
+	emailService.ClassifyWith(spam.NewScorer(emailRepo, spam.Config{
+		Threshold: intFromEnv("IBOZ_SPAM_THRESHOLD"),
+	}))
+	hours, err := calendarHoursFromEnv()
+	if err != nil {
+		log.Fatalf("failed to load calendar: %v", err)
+	}
+	calendarService := calendar.NewService(calendarmemory.NewRepository(), emailRepo, hours, clock)
+	broker, err := queueBrokerFromEnv()
+	if err != nil {
+		log.Fatalf("failed to connect to queue broker: %v", err)
+	}
+	queueService := queue.NewService(broker, queuememory.NewRepository(), clock, queue.Config{
+		Concurrency: intFromEnv("IBOZ_QUEUE_CONCURRENCY"),
+	})
+	sender, err := mailSenderFromEnv(vault)
+	if err != nil {
+		log.Fatalf("failed to configure SMTP: %v", err)
+	}
+	linker := notify.Linker{BaseURL: os.Getenv("IBOZ_PUBLIC_URL")}
+	mailer := email.NewMailer(emailRepo, sender, clock)
+	if sender != nil {
+		queueService.Handle(replySendTopic, queue.SendHandler(sender))
+	}
+	templateService := templates.NewService(templatememory.NewRepository(), emailRepo, clock)
+	snoozeService := snooze.NewService(snoozememory.NewRepository(), emailRepo, emailRepo, calendarService, clock)
+	emailService.ClassifyWith(snoozeService)
+	focusRepo := focusmemory.NewRepository()
+	calendarSource, calendarWriter := calendarSourceFromEnv(hours, clock)
+	var focusBusy focus.BusySource
+	if calendarSource != nil {
+		focusBusy = focus.CalendarBusy(calendarSource)
+	}
+	focusPlanner := focus.NewPlanner(focusRepo, emailRepo, calendarService, snoozeService, focusBusy, clock, focus.Config{
+		MaxBatchSize: intFromEnv("IBOZ_FOCUS_BATCH_SIZE"),
+	})
+	focusService := focus.NewService(focusRepo, emailRepo, focusPlanner, clock)
+	if calendarWriter != nil && os.Getenv("IBOZ_FOCUS_CALENDAR_BLOCKS") == "true" {
+		focusService.BlockWith(calendarWriter)
+	}
+	webhookRepo := webhookmemory.NewRepository()
+	webhookService := webhooks.NewService(webhookRepo, nil, clock)
+	webhookGate := focuswebhook.NewGate(focusService, webhookService)
+	webhookService.HoldWith(webhookGate)
+	focusService.OnRelease(focuswebhook.Channel, webhookGate)
+	queueService.HandleInOrder(webhooksSyncTopic, queue.SyncHandler(webhookService))
+	emailService.OnSync(queue.NewSyncPublisher(queueService, webhooksSyncTopic))
+	waitingTracker := waiting.NewTracker(waitingmemory.NewRepository(), emailRepo, webhookService, calendarService, clock, waiting.Config{
+		FollowUpBusinessDays: intFromEnv("IBOZ_FOLLOW_UP_BUSINESS_DAYS"),
+	})
+	queueService.HandleInOrder(waitingSyncTopic, queue.SyncHandler(waitingTracker))
+	emailService.OnSync(queue.NewSyncPublisher(queueService, waitingSyncTopic))
+	slaRepo := slamemory.NewRepository()
+	escalators, escalationTargets := slaEscalators(sender, emailRepo, linker, focusService, clock)
+	slaEngine := sla.NewEngine(slaRepo, emailRepo, calendarService, escalators, webhookService, clock)
+	for action, targets := range escalationTargets {
+		slaEngine.RestrictTargets(action, targets...)
+	}
+	queueService.HandleInOrder(slaSyncTopic, queue.SyncHandler(slaEngine))
+	emailService.OnSync(queue.NewSyncPublisher(queueService, slaSyncTopic))
+	scheduleRepo, err := scheduleRepositoryFromEnv()
+	if err != nil {
+		log.Fatalf("failed to open scheduled actions: %v", err)
+	}
+	scheduleService := schedule.NewService(scheduleRepo, map[string]schedule.Handler{
+		schedulereply.Type: schedulereply.NewHandler(mailer),
+	}, calendarService, clock, schedule.Config{})
+	pushService := push.NewService(pushmemory.NewRepository(), emailRepo, pushRegistrarsFromEnv(vault, clock), queueService, scheduleService, clock, push.Config{
+		GmailToken: os.Getenv("IBOZ_GMAIL_PUSH_TOKEN"),
+	})
+	scheduleService.Handle(schedulerenewal.Type, schedulerenewal.NewHandler(pushService))
+	queueService.Handle(push.SyncTopic, push.SyncHandler(emailService))
+	tasksRepo := tasksmemory.NewRepository()
+	taskService := tasks.NewService(tasksRepo, emailRepo, emailRepo, taskSinksFromEnv(), linker, clock)
+	crmRepo := crmmemory.NewRepository()
+	crmService := crm.NewService(crmRepo, emailRepo, emailRepo, crmClientsFromEnv(), clock)
+	digestRepo := digestmemory.NewRepository()
+	digestService := digest.NewService(digestRepo, emailRepo, sender, webhookService, digestSummarizerFromEnv(), clock, digest.Config{
+		Timezone: hours.Timezone,
+	})
+	bulkRepo := bulkmemory.NewRepository()
+	unsubscribeService := bulk.NewService(bulkRepo, emailRepo, sender, nil, clock)
+	relay := outbox.NewRelay([]outbox.Store{slaRepo, tasksRepo, webhookRepo, crmRepo, focusRepo, digestRepo, bulkRepo}, map[string]outbox.Deliverer{
+		sla.OutboxDestination:      slaEngine.Deliverer(),
+		tasks.OutboxDestination:    taskService.Deliverer(),
+		webhooks.OutboxDestination: webhookService.Deliverer(),
+		crm.OutboxDestination:      crmService.Deliverer(),
+		focus.OutboxDestination:    focusService.Deliverer(),
+		focus.BlockDestination:     focusService.BlockDeliverer(),
+		digest.OutboxDestination:   digestService.Deliverer(),
+		bulk.OutboxDestination:     unsubscribeService.Deliverer(),
+	}, clock, outbox.Config{})
+	runSources := []dashboard.RunSource{
+		dashboardruns.Replies(scheduleRepo),
+		dashboardruns.Tasks(tasksRepo),
+		dashboardruns.CRM(crmRepo),
+	}
+	focusPlanner.CountRuns(runSources...)
+	dashboardService := dashboard.NewService(emailRepo, snoozeService, runSources, clock, dashboard.Config{
+		InboxZeroTarget: intFromEnv("IBOZ_INBOX_ZERO_TARGET"),
+	})
+	emailService.OnSync(dashboardService)
+	delegationService := delegation.NewService(delegationmemory.NewRepository(), emailRepo, delegationNudgers(sender, emailRepo, clock), clock)
 
-	api.Register(e.Group("/api"), emailService)
+	api.Register(e.Group("/api"), api.Dependencies{
+		Email:           emailService,
+		Replies:         queue.NewReplyPublisher(mailer, queueService, replySendTopic),
+		Templates:       templateService,
+		Snoozes:         snoozeService,
+		Delegations:     delegationService,
+		Waiting:         waitingTracker,
+		SLA:             slaEngine,
+		Calendar:        calendarService,
+		Schedules:       scheduleService,
+		Queue:           queueService,
+		Tasks:           taskService,
+		Webhooks:        webhookService,
+		Push:            pushService,
+		CRM:             crmService,
+		Focus:           focusPlanner,
+		FocusSessions:   focusService,
+		Dashboard:       dashboardService,
+		Recommendations: recommendations.NewEngine(emailRepo, clock, recommendations.Config{}),
+		Digests:         digestService,
+		Unsubscribes:    unsubscribeService,
+	})
 
 	subFS, err := fs.Sub(embeddedStatic, "static")
 	if err != nil {
@@ -63,17 +282,398 @@ func New() *Server {
 		WriteTimeout: writeTimeout,
 	}
 
-	return &Server{httpServer: srv}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Server{
+		httpServer: srv,
+		workers: []worker{
+			periodic.Every("snooze: resurface", snoozeInterval, func(ctx context.Context) error {
+				_, err := snoozeService.Resurface(ctx)
+				return err
+			}),
+			periodic.Every("delegation: overdue check", overdueInterval, func(ctx context.Context) error {
+				_, err := delegationService.CheckOverdue(ctx)
+				return err
+			}),
+			periodic.Every("waiting: reminder check", followUpInterval, func(ctx context.Context) error {
+				_, err := waitingTracker.CheckReminders(ctx)
+				return err
+			}),
+			periodic.Every("sla: deadline evaluation", slaInterval, func(ctx context.Context) error {
+				_, err := slaEngine.Evaluate(ctx)
+				return err
+			}),
+			periodic.Every("schedule: running due actions", scheduleInterval, func(ctx context.Context) error {
+				_, err := scheduleService.RunDue(ctx)
+				return err
+			}),
+			queueService.Run,
+			periodic.Every("outbox: relaying entries", outboxInterval, func(ctx context.Context) error {
+				_, err := relay.Deliver(ctx)
+				return err
+			}),
+			periodic.Every("tasks: status sync", taskSyncInterval, func(ctx context.Context) error {
+				_, err := taskService.SyncStatuses(ctx)
+				return err
+			}),
+			periodic.Every("digest: sending due digests", digestInterval, func(ctx context.Context) error {
+				_, err := digestService.RunDue(ctx)
+				return err
+			}),
+			periodic.Every("webhooks: automation events", runEventInterval, func(ctx context.Context) error {
+				return publishAutomationEvents(ctx, webhookService, runSources, scheduleService, clock.Now().Add(-runEventWindow))
+			}),
+		},
+		ctx:    ctx,
+		cancel: cancel,
+	}
+}
+
+// publishAutomationEvents publishes the automation runs finished since since and
+// the scheduled actions awaiting approval. Webhooks are keyed per occurrence, so
+// each is published once however often this runs.
+func publishAutomationEvents(ctx context.Context, hooks *webhooks.Service, sources []dashboard.RunSource, schedules schedule.ScheduleService, since time.Time) error {
+	var errs []error
+	for _, source := range sources {
+		runs, err := source.Runs(ctx, since)
+		if err == nil {
+			err = hooks.RunsCompleted(ctx, runs)
+		}
+		errs = append(errs, err)
+	}
+	awaiting, err := schedules.List(ctx, schedule.StatusAwaitingApproval)
+	if err == nil {
+		err = hooks.ApprovalsRequested(ctx, awaiting)
+	}
+	return errors.Join(append(errs, err)...)
+}
+
+// calendarHoursFromEnv builds the default working hours from IBOZ_TIMEZONE,
+// IBOZ_WORKING_HOURS ("09:00-17:00") and the holidays of the IBOZ_HOLIDAYS_ICS file.
+func calendarHoursFromEnv() (calendar.Hours, error) {
+	hours := calendar.DefaultHours()
+	hours.Timezone = os.Getenv("IBOZ_TIMEZONE")
+	if window := os.Getenv("IBOZ_WORKING_HOURS"); window != "" {
+		start, end, ok := strings.Cut(window, "-")
+		if !ok {
+			return hours, fmt.Errorf("IBOZ_WORKING_HOURS must look like 09:00-17:00, got %q", window)
+		}
+		hours.Start, hours.End = strings.TrimSpace(start), strings.TrimSpace(end)
+	}
+	if path := os.Getenv("IBOZ_HOLIDAYS_ICS"); path != "" {
+		f, err := os.Open(path)
+		if err != nil {
+			return hours, err
+		}
+		defer f.Close()
+		holidays, err := calendar.ParseICS(f)
+		if err != nil {
+			return hours, fmt.Errorf("%s: %w", path, err)
+		}
+		for _, holiday := range holidays {
+			hours.Holidays = append(hours.Holidays, holiday.Date)
+		}
+	}
+	if _, err := calendar.New(hours, email.NewSystemClock()); err != nil {
+		return hours, err
+	}
+	return hours, nil
+}
+
+// calendarSourceFromEnv reads meetings from the CalDAV collection at
+// IBOZ_CALDAV_URL, which can also be written to, or from the read-only
+// iCalendar feed at IBOZ_CALENDAR_ICS_URL. Both are nil when neither is set.
+func calendarSourceFromEnv(hours calendar.Hours, clock email.Clock) (calendar.Source, calendar.Writer) {
+	loc := time.UTC
+	if hours.Timezone != "" {
+		if parsed, err := time.LoadLocation(hours.Timezone); err == nil {
+			loc = parsed
+		}
+	}
+	if url := os.Getenv("IBOZ_CALDAV_URL"); url != "" {
+		client := caldav.NewClient(caldav.Config{
+			URL:      url,
+			Username: os.Getenv("IBOZ_CALDAV_USERNAME"),
+			Password: os.Getenv("IBOZ_CALDAV_PASSWORD"),
+			Location: loc,
+		}, clock)
+		return client, client
+	}
+	if url := os.Getenv("IBOZ_CALENDAR_ICS_URL"); url != "" {
+		return caldav.NewFeed(url, loc, nil), nil
+	}
+	return nil, nil
+}
+
+// scheduleRepositoryFromEnv persists scheduled actions under IBOZ_DATA_DIR when
+// set so they survive restarts, and keeps them in memory otherwise.
+func scheduleRepositoryFromEnv() (schedule.Repository, error) {
+	dir := os.Getenv("IBOZ_DATA_DIR")
+	if dir == "" {
+		return schedulememory.NewRepository(), nil
+	}
+	return schedulefile.Open(filepath.Join(dir, "scheduled-actions.json"))
+}
+
+// queueBrokerFromEnv connects to the NATS server at IBOZ_NATS_URL when set and
+// falls back to the embedded in-process broker otherwise.
+func queueBrokerFromEnv() (queue.Broker, error) {
+	url := os.Getenv("IBOZ_NATS_URL")
+	if url == "" {
+		return inproc.NewBroker(0), nil
+	}
+	return queuenats.Dial(queuenats.Config{URL: url, Name: "iboz"})
+}
+
+// mailSenderFromEnv builds an SMTP sender from IBOZ_SMTP_* variables, or nil when no host is set.
+func mailSenderFromEnv(vault email.Vault) (email.MailSender, error) {
+	host := os.Getenv("IBOZ_SMTP_HOST")
+	if host == "" {
+		return nil, nil
+	}
+	security := smtp.Security(strings.ToLower(os.Getenv("IBOZ_SMTP_SECURITY")))
+	if !security.Valid() {
+		return nil, fmt.Errorf("IBOZ_SMTP_SECURITY must be none, starttls or tls, got %q", os.Getenv("IBOZ_SMTP_SECURITY"))
+	}
+	username := os.Getenv("IBOZ_SMTP_USERNAME")
+	if username != "" {
+		if err := storeSMTPPassword(vault, username); err != nil {
+			return nil, err
+		}
+	}
+	return smtp.NewSender(smtp.Config{
+		Host:      host,
+		Port:      intFromEnv("IBOZ_SMTP_PORT"),
+		Security:  security,
+		Username:  username,
+		Mechanism: os.Getenv("IBOZ_SMTP_AUTH"),
+	}, vault), nil
+}
+
+// storeSMTPPassword puts IBOZ_SMTP_PASSWORD in the vault entry of the relay
+// account, or checks that the vault already holds one.
+func storeSMTPPassword(vault email.Vault, username string) error {
+	ctx := context.Background()
+	if password := os.Getenv("IBOZ_SMTP_PASSWORD"); password != "" {
+		return vault.StoreSecret(ctx, smtp.VaultKey(username), password)
+	}
+	if _, err := vault.Secret(ctx, smtp.VaultKey(username)); err != nil {
+		return fmt.Errorf("IBOZ_SMTP_PASSWORD is required when IBOZ_SMTP_USERNAME is set: %w", err)
+	}
+	return nil
+}
+
+// digestSummarizerFromEnv summarises digest groups with the IBOZ_LLM_MODEL
+// model of the chat completions API at IBOZ_LLM_URL (OpenAI by default), and
+// returns nil when no model is set.
+func digestSummarizerFromEnv() digest.Summarizer {
+	model := os.Getenv("IBOZ_LLM_MODEL")
+	if model == "" {
+		return nil
+	}
+	return digestllm.NewSummarizer(digestllm.Config{
+		BaseURL: os.Getenv("IBOZ_LLM_URL"),
+		APIKey:  os.Getenv("IBOZ_LLM_API_KEY"),
+		Model:   model,
+	})
+}
+
+// delegationNudgers emails assignees when SMTP is configured and posts to
+// IBOZ_DELEGATION_WEBHOOK_URL when set.
+func delegationNudgers(sender email.MailSender, repo email.Repository, clock email.Clock) delegation.Nudgers {
+	nudgers := delegation.Nudgers{}
+	if sender != nil {
+		nudgers["email"] = delegationmail.NewNudger(sender, repo, clock)
+	}
+	if url := os.Getenv("IBOZ_DELEGATION_WEBHOOK_URL"); url != "" {
+		nudgers["webhook"] = delegationwebhook.NewNudger(url, nil)
+	}
+	return nudgers
+}
+
+// slaEscalators enables each escalation action whose integration is
+// configured and returns the targets each action is restricted to. Webhook
+// escalations post only to the IBOZ_SLA_WEBHOOK_TARGETS URLs and Teams ones
+// only to IBOZ_TEAMS_WEBHOOK_URL or the IBOZ_SLA_TEAMS_TARGETS URLs; Slack and
+// email targets are restricted when IBOZ_SLA_SLACK_CHANNELS or
+// IBOZ_SLA_EMAIL_TARGETS is set. Chat escalations are held back during focus
+// sessions.
+func slaEscalators(sender email.MailSender, repo email.Repository, linker notify.Linker, focusService *focus.Service, clock email.Clock) (map[string]sla.Escalator, map[string][]string) {
+	escalators := make(map[string]sla.Escalator)
+	targets := make(map[string][]string)
+	if urls := envList("IBOZ_SLA_WEBHOOK_TARGETS"); len(urls) > 0 {
+		escalators[sla.ActionWebhook] = slawebhook.NewEscalator(nil)
+		targets[sla.ActionWebhook] = urls
+	}
+	if url := os.Getenv("IBOZ_TEAMS_WEBHOOK_URL"); url != "" {
+		teamsNotifier := focuschat.NewNotifier(sla.ActionTeams, teams.NewNotifier(teams.Config{WebhookURL: url}), focusService)
+		focusService.OnRelease(sla.ActionTeams, teamsNotifier)
+		escalators[sla.ActionTeams] = slachat.NewEscalator(teamsNotifier, linker)
+		targets[sla.ActionTeams] = append([]string{url}, envList("IBOZ_SLA_TEAMS_TARGETS")...)
+	}
+	if sender != nil {
+		escalators[sla.ActionEmail] = slamail.NewEscalator(sender, repo, clock)
+		if addresses := envList("IBOZ_SLA_EMAIL_TARGETS"); len(addresses) > 0 {
+			targets[sla.ActionEmail] = addresses
+		}
+	}
+	if notifier := slackNotifierFromEnv(); notifier != nil {
+		slackNotifier := focuschat.NewNotifier(sla.ActionSlack, notifier, focusService)
+		focusService.OnRelease(sla.ActionSlack, slackNotifier)
+		escalators[sla.ActionSlack] = slachat.NewEscalator(slackNotifier, linker)
+		if channels := envList("IBOZ_SLA_SLACK_CHANNELS"); len(channels) > 0 {
+			targets[sla.ActionSlack] = channels
+		}
+	}
+	return escalators, targets
+}
+
+// envList splits the comma-separated value of key, dropping empty items.
+func envList(key string) []string {
+	var list []string
+	for _, item := range strings.Split(os.Getenv(key), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// slackNotifierFromEnv posts with the IBOZ_SLACK_TOKEN bot token, or to the
+// IBOZ_SLACK_WEBHOOK_URL incoming webhook, and returns nil when neither is set.
+func slackNotifierFromEnv() notify.Notifier {
+	token, webhook := os.Getenv("IBOZ_SLACK_TOKEN"), os.Getenv("IBOZ_SLACK_WEBHOOK_URL")
+	if token == "" && webhook == "" {
+		return nil
+	}
+	return slack.NewNotifier(slack.Config{
+		Token:          token,
+		WebhookURL:     webhook,
+		BaseURL:        os.Getenv("IBOZ_SLACK_API_URL"),
+		DefaultChannel: os.Getenv("IBOZ_SLACK_CHANNEL"),
+	})
+}
+
+// taskSinksFromEnv enables the Jira, Asana and generic webhook task sinks whose
+// IBOZ_JIRA_*, IBOZ_ASANA_* and IBOZ_TASK_WEBHOOK_* variables are set.
+func taskSinksFromEnv() map[string]tasks.Sink {
+	sinks := make(map[string]tasks.Sink)
+	if site := os.Getenv("IBOZ_JIRA_URL"); site != "" {
+		sinks[tasks.ProviderJira] = tasksjira.NewSink(tasksjira.Config{
+			BaseURL:       site,
+			Email:         os.Getenv("IBOZ_JIRA_EMAIL"),
+			APIToken:      os.Getenv("IBOZ_JIRA_API_TOKEN"),
+			ProjectKey:    os.Getenv("IBOZ_JIRA_PROJECT"),
+			IssueType:     os.Getenv("IBOZ_JIRA_ISSUE_TYPE"),
+			WebhookSecret: os.Getenv("IBOZ_JIRA_WEBHOOK_SECRET"),
+		})
+	}
+	if token := os.Getenv("IBOZ_ASANA_TOKEN"); token != "" {
+		cfg := tasksasana.Config{
+			BaseURL:       os.Getenv("IBOZ_ASANA_API_URL"),
+			Token:         token,
+			Workspace:     os.Getenv("IBOZ_ASANA_WORKSPACE"),
+			Project:       os.Getenv("IBOZ_ASANA_PROJECT"),
+			WebhookSecret: os.Getenv("IBOZ_ASANA_WEBHOOK_SECRET"),
+		}
+		// A handshake secret is only trusted when it can be kept across restarts.
+		if dir := os.Getenv("IBOZ_DATA_DIR"); dir != "" {
+			cfg.SecretPath = filepath.Join(dir, "asana-webhook-secret")
+			cfg.AcceptHandshake = os.Getenv("IBOZ_ASANA_WEBHOOK_REGISTRATION") == "true"
+		}
+		sinks[tasks.ProviderAsana] = tasksasana.NewSink(cfg)
+	}
+	if url := os.Getenv("IBOZ_TASK_WEBHOOK_URL"); url != "" {
+		sinks[tasks.ProviderWebhook] = taskswebhook.NewSink(taskswebhook.Config{
+			URL:    url,
+			Secret: os.Getenv("IBOZ_TASK_WEBHOOK_SECRET"),
+		})
+	}
+	return sinks
+}
+
+// crmClientsFromEnv enables Salesforce when IBOZ_SALESFORCE_URL is set and
+// HubSpot when IBOZ_HUBSPOT_TOKEN is.
+func crmClientsFromEnv() map[string]crm.Client {
+	clients := make(map[string]crm.Client)
+	if instance := os.Getenv("IBOZ_SALESFORCE_URL"); instance != "" {
+		clients[crm.ProviderSalesforce] = crmsalesforce.NewClient(crmsalesforce.Config{
+			BaseURL:     instance,
+			AccessToken: os.Getenv("IBOZ_SALESFORCE_TOKEN"),
+			APIVersion:  os.Getenv("IBOZ_SALESFORCE_API_VERSION"),
+		})
+	}
+	if token := os.Getenv("IBOZ_HUBSPOT_TOKEN"); token != "" {
+		clients[crm.ProviderHubSpot] = crmhubspot.NewClient(crmhubspot.Config{
+			BaseURL:  os.Getenv("IBOZ_HUBSPOT_API_URL"),
+			Token:    token,
+			PortalID: os.Getenv("IBOZ_HUBSPOT_PORTAL_ID"),
+			Pipeline: os.Getenv("IBOZ_HUBSPOT_PIPELINE"),
+		})
+	}
+	return clients
+}
+
+// pushRegistrarsFromEnv enables Graph subscriptions when a public URL is known
+// (IBOZ_PUSH_URL, falling back to IBOZ_PUBLIC_URL) and Gmail watches when
+// IBOZ_GMAIL_PUBSUB_TOPIC is set.
+func pushRegistrarsFromEnv(vault email.Vault, clock email.Clock) map[string]push.Registrar {
+	registrars := make(map[string]push.Registrar)
+	base := os.Getenv("IBOZ_PUSH_URL")
+	if base == "" {
+		base = os.Getenv("IBOZ_PUBLIC_URL")
+	}
+	if base != "" {
+		registrars[email.ProviderOutlook] = pushgraph.NewRegistrar(pushgraph.Config{
+			BaseURL:         os.Getenv("IBOZ_GRAPH_API_URL"),
+			NotificationURL: strings.TrimRight(base, "/") + "/api/email/push/graph",
+		}, vault, clock)
+	}
+	if topic := os.Getenv("IBOZ_GMAIL_PUBSUB_TOPIC"); topic != "" {
+		registrars[email.ProviderGmail] = pushgmail.NewRegistrar(pushgmail.Config{
+			BaseURL:   os.Getenv("IBOZ_GMAIL_API_URL"),
+			TopicName: topic,
+		}, vault)
+	}
+	return registrars
+}
+
+// intFromEnv parses an integer variable, returning zero when unset or invalid.
+func intFromEnv(key string) int {
+	value, _ := strconv.Atoi(os.Getenv(key))
+	return value
 }
 
 func (s *Server) Start() error {
+	s.startWorkers()
 	return s.httpServer.ListenAndServe()
 }
 
 func (s *Server) Stop(ctx context.Context) error {
+	s.mu.Lock()
+	s.stopped = true
+	s.mu.Unlock()
+
+	s.cancel()
+	s.wg.Wait()
 	return s.httpServer.Shutdown(ctx)
 }
 
+func (s *Server) startWorkers() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.stopped {
+		return
+	}
+	for _, w := range s.workers {
+		s.wg.Add(1)
+		go func(run worker) {
+			defer s.wg.Done()
+			run(s.ctx)
+		}(w)
+	}
+}
+
 func spaHandler(filesystem http.FileSystem) echo.HandlerFunc {
 	fileServer := http.FileServer(filesystem)
 
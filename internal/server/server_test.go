@@ -1,13 +1,18 @@
 package server
 
 import (
+	"context"
 	"io/fs"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"testing/fstest"
 
 	"github.com/labstack/echo/v4"
+
+	"github.com/example/iboz/internal/email/adapter/memory"
+	"github.com/example/iboz/internal/email/adapter/smtp"
 )
 
 func TestNewConfiguresHTTPServer(t *testing.T) {
@@ -44,6 +49,39 @@ func TestNewHonorsEnvironmentAddress(t *testing.T) {
 	}
 }
 
+func TestMailSenderFromEnvRejectsUnknownSecurity(t *testing.T) {
+	t.Setenv("IBOZ_SMTP_HOST", "smtp.example.com")
+	t.Setenv("IBOZ_SMTP_SECURITY", "ssl")
+
+	if _, err := mailSenderFromEnv(nil); err == nil || !strings.Contains(err.Error(), "IBOZ_SMTP_SECURITY") {
+		t.Fatalf("expected the security mode to be rejected, got %v", err)
+	}
+
+	t.Setenv("IBOZ_SMTP_SECURITY", "TLS")
+	if sender, err := mailSenderFromEnv(nil); err != nil || sender == nil {
+		t.Fatalf("expected a sender, got %v (%v)", sender, err)
+	}
+}
+
+func TestMailSenderFromEnvNeedsRelayPassword(t *testing.T) {
+	t.Setenv("IBOZ_SMTP_HOST", "smtp.example.com")
+	t.Setenv("IBOZ_SMTP_USERNAME", "relay@example.com")
+	t.Setenv("IBOZ_SMTP_PASSWORD", "")
+	vault := memory.NewVault()
+
+	if _, err := mailSenderFromEnv(vault); err == nil || !strings.Contains(err.Error(), "IBOZ_SMTP_PASSWORD") {
+		t.Fatalf("expected a missing relay password to be reported, got %v", err)
+	}
+
+	t.Setenv("IBOZ_SMTP_PASSWORD", "relay-secret")
+	if _, err := mailSenderFromEnv(vault); err != nil {
+		t.Fatalf("mail sender: %v", err)
+	}
+	if secret, err := vault.Secret(context.Background(), smtp.VaultKey("relay@example.com")); err != nil || secret != "relay-secret" {
+		t.Fatalf("expected the relay password in its own vault entry, got %q (%v)", secret, err)
+	}
+}
+
 func setupSPAHandler(t *testing.T) echo.HandlerFunc {
 	t.Helper()
 	fsys := fstest.MapFS{
@@ -123,3 +161,24 @@ func TestSpaHandlerFallsBackForDirectory(t *testing.T) {
 		t.Fatalf("expected index fallback for directory, got %q", body)
 	}
 }
+
+func TestStopCancelsWorkers(t *testing.T) {
+	t.Setenv("IBOZ_LISTEN_ADDR", "127.0.0.1:0")
+
+	srv := New()
+	done := make(chan struct{})
+	srv.workers = []worker{func(ctx context.Context) {
+		<-ctx.Done()
+		close(done)
+	}}
+	srv.startWorkers()
+
+	if err := srv.Stop(context.Background()); err != nil {
+		t.Fatalf("stop returned error: %v", err)
+	}
+	select {
+	case <-done:
+	default:
+		t.Fatalf("worker was not cancelled before Stop returned")
+	}
+}
@@ -0,0 +1,49 @@
+package chat
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/example/iboz/internal/notify"
+	"github.com/example/iboz/internal/sla"
+)
+
+var _ sla.Escalator = (*Escalator)(nil)
+
+// Escalator posts breach events to the chat channel named by the escalation target.
+type Escalator struct {
+	notifier notify.Notifier
+	linker   notify.Linker
+}
+
+// NewEscalator constructs a chat-backed Escalator.
+func NewEscalator(notifier notify.Notifier, linker notify.Linker) *Escalator {
+	if notifier == nil {
+		panic("chat: notifier dependency is required")
+	}
+	return &Escalator{notifier: notifier, linker: linker}
+}
+
+// Escalate implements the sla.Escalator interface.
+func (e *Escalator) Escalate(ctx context.Context, event sla.Event, escalation sla.Escalation) error {
+	d := event.Deadline
+	policy := event.Policy.Name
+	if policy == "" {
+		policy = d.PolicyID
+	}
+	return e.notifier.Notify(ctx, notify.Notification{
+		Channel: escalation.Target,
+		Title:   fmt.Sprintf("SLA breached: %s", d.Subject),
+		Text:    fmt.Sprintf("No reply was sent before the %s deadline.", policy),
+		Fields: []notify.Field{
+			{Title: "From", Value: d.Sender},
+			{Title: "Received", Value: d.ReceivedAt.UTC().Format(time.RFC1123)},
+			{Title: "Due", Value: d.DueAt.UTC().Format(time.RFC1123)},
+			{Title: "Policy", Value: policy},
+		},
+		MessageID: d.MessageID,
+		Event:     string(event.Type),
+		Link:      e.linker.MessageURL(d.MessageID),
+	})
+}
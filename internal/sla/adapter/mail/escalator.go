@@ -0,0 +1,69 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/example/iboz/internal/email"
+	"github.com/example/iboz/internal/sla"
+)
+
+var _ sla.Escalator = (*Escalator)(nil)
+
+// Escalator emails the escalation target from the authenticated mailbox when a deadline is breached.
+type Escalator struct {
+	sender email.MailSender
+	repo   email.Repository
+	clock  email.Clock
+}
+
+// NewEscalator constructs an email-backed Escalator.
+func NewEscalator(sender email.MailSender, repo email.Repository, clock email.Clock) *Escalator {
+	if sender == nil {
+		panic("mail: sender dependency is required")
+	}
+	if repo == nil {
+		panic("mail: repository dependency is required")
+	}
+	if clock == nil {
+		panic("mail: clock dependency is required")
+	}
+	return &Escalator{sender: sender, repo: repo, clock: clock}
+}
+
+// Escalate implements the sla.Escalator interface.
+func (e *Escalator) Escalate(ctx context.Context, event sla.Event, escalation sla.Escalation) error {
+	auth, err := e.repo.GetAuth(ctx)
+	if err != nil {
+		return err
+	}
+	if auth == nil {
+		return email.ErrProviderNotAuthenticated
+	}
+
+	now := e.clock.Now().UTC()
+	messageID, err := email.NewMessageID(auth.State.Username, now)
+	if err != nil {
+		return err
+	}
+
+	return e.sender.Send(ctx, email.OutgoingMessage{
+		From:      auth.State.Username,
+		To:        []string{escalation.Target},
+		Subject:   fmt.Sprintf("SLA breached: %q", event.Deadline.Subject),
+		TextBody:  body(event),
+		Date:      now,
+		MessageID: messageID,
+	})
+}
+
+func body(event sla.Event) string {
+	d := event.Deadline
+	name := event.Policy.Name
+	if name == "" {
+		name = d.PolicyID
+	}
+	return fmt.Sprintf("Hi,\n\nThe message %q from %s received %s was due for a response by %s under the %q policy and has not been answered.\n",
+		d.Subject, d.Sender, d.ReceivedAt.Format(time.RFC1123), d.DueAt.Format(time.RFC1123), name)
+}
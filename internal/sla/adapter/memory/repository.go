@@ -0,0 +1,190 @@
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/example/iboz/internal/outbox"
+	outboxmemory "github.com/example/iboz/internal/outbox/adapter/memory"
+	"github.com/example/iboz/internal/sla"
+)
+
+var _ sla.Repository = (*Repository)(nil)
+
+// Repository provides an in-memory implementation of the sla.Repository port.
+type Repository struct {
+	mu        sync.RWMutex
+	policies  map[string]sla.Policy
+	deadlines map[string]sla.Deadline
+	outbox    *outboxmemory.Table
+}
+
+// NewRepository builds a new in-memory SLA repository.
+func NewRepository() *Repository {
+	return &Repository{
+		policies:  make(map[string]sla.Policy),
+		deadlines: make(map[string]sla.Deadline),
+		outbox:    outboxmemory.NewTable(),
+	}
+}
+
+// SavePolicy inserts or replaces a policy.
+func (r *Repository) SavePolicy(ctx context.Context, policy sla.Policy) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	r.policies[policy.ID] = clonePolicy(policy)
+	r.mu.Unlock()
+	return nil
+}
+
+// GetPolicy returns the policy with the supplied identifier if present.
+func (r *Repository) GetPolicy(ctx context.Context, id string) (*sla.Policy, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	policy, ok := r.policies[id]
+	if !ok {
+		return nil, nil
+	}
+	cloned := clonePolicy(policy)
+	return &cloned, nil
+}
+
+// ListPolicies returns every stored policy.
+func (r *Repository) ListPolicies(ctx context.Context) ([]sla.Policy, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	list := make([]sla.Policy, 0, len(r.policies))
+	for _, policy := range r.policies {
+		list = append(list, clonePolicy(policy))
+	}
+	return list, nil
+}
+
+// DeletePolicy removes a policy if present.
+func (r *Repository) DeletePolicy(ctx context.Context, id string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	delete(r.policies, id)
+	r.mu.Unlock()
+	return nil
+}
+
+// SaveDeadline inserts the deadline of a message or replaces one whose version
+// matches the stored one, appending effects to the outbox under the same lock.
+func (r *Repository) SaveDeadline(ctx context.Context, deadline sla.Deadline, effects ...outbox.Entry) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.deadlines[deadline.MessageID]
+	if ok != (deadline.Version > 0) || stored.Version != deadline.Version {
+		return sla.ErrConflict
+	}
+	deadline.Version++
+	r.deadlines[deadline.MessageID] = cloneDeadline(deadline)
+	r.outbox.Append(effects...)
+	return nil
+}
+
+// GetDeadline returns the deadline of a message if present.
+func (r *Repository) GetDeadline(ctx context.Context, messageID string) (*sla.Deadline, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	deadline, ok := r.deadlines[messageID]
+	if !ok {
+		return nil, nil
+	}
+	cloned := cloneDeadline(deadline)
+	return &cloned, nil
+}
+
+// ListDeadlines returns every stored deadline.
+func (r *Repository) ListDeadlines(ctx context.Context) ([]sla.Deadline, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	list := make([]sla.Deadline, 0, len(r.deadlines))
+	for _, deadline := range r.deadlines {
+		list = append(list, cloneDeadline(deadline))
+	}
+	return list, nil
+}
+
+// PendingEntries implements the outbox.Store interface.
+func (r *Repository) PendingEntries(ctx context.Context, now time.Time, limit int) ([]outbox.Entry, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.outbox.Pending(now, limit), nil
+}
+
+// UpdateEntry implements the outbox.Store interface.
+func (r *Repository) UpdateEntry(ctx context.Context, entry outbox.Entry) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	r.outbox.Update(entry)
+	r.mu.Unlock()
+	return nil
+}
+
+func clonePolicy(policy sla.Policy) sla.Policy {
+	policy.Escalations = append([]sla.Escalation(nil), policy.Escalations...)
+	if policy.Hours != nil {
+		hours := *policy.Hours
+		hours.Days = append([]time.Weekday(nil), policy.Hours.Days...)
+		hours.Holidays = append([]string(nil), policy.Hours.Holidays...)
+		policy.Hours = &hours
+	}
+	return policy
+}
+
+func cloneDeadline(deadline sla.Deadline) sla.Deadline {
+	deadline.WarnedAt = cloneTime(deadline.WarnedAt)
+	deadline.BreachedAt = cloneTime(deadline.BreachedAt)
+	deadline.MetAt = cloneTime(deadline.MetAt)
+	deadline.EscalatedAt = cloneTime(deadline.EscalatedAt)
+	return deadline
+}
+
+func cloneTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	v := *t
+	return &v
+}
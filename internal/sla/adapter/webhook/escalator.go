@@ -0,0 +1,62 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/example/iboz/internal/outbox"
+	"github.com/example/iboz/internal/sla"
+)
+
+const defaultTimeout = 10 * time.Second
+
+var _ sla.Escalator = (*Escalator)(nil)
+
+// Escalator posts breach events as JSON to the escalation target URL. When relayed
+// from the outbox, the entry ID is sent as the Idempotency-Key header so
+// receivers can drop redeliveries.
+type Escalator struct {
+	client *http.Client
+}
+
+// NewEscalator constructs a webhook-backed Escalator. A nil client uses a client with a 10s timeout.
+func NewEscalator(client *http.Client) *Escalator {
+	if client == nil {
+		client = &http.Client{Timeout: defaultTimeout}
+	}
+	return &Escalator{client: client}
+}
+
+// Escalate implements the sla.Escalator interface.
+func (e *Escalator) Escalate(ctx context.Context, event sla.Event, escalation sla.Escalation) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, escalation.Target, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if id, ok := outbox.EntryID(ctx); ok {
+		req.Header.Set("Idempotency-Key", id)
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("webhook: post escalation: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook: unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,473 @@
+package sla
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/example/iboz/internal/calendar"
+	"github.com/example/iboz/internal/email"
+	"github.com/example/iboz/internal/outbox"
+)
+
+// SLAService exposes policy management and deadline tracking.
+type SLAService interface {
+	email.SyncListener
+	CreatePolicy(ctx context.Context, policy Policy) (*Policy, error)
+	UpdatePolicy(ctx context.Context, id string, policy Policy) (*Policy, error)
+	GetPolicy(ctx context.Context, id string) (*Policy, error)
+	ListPolicies(ctx context.Context) ([]Policy, error)
+	DeletePolicy(ctx context.Context, id string) error
+	Deadlines(ctx context.Context, status DeadlineStatus) ([]Deadline, error)
+	Evaluate(ctx context.Context) ([]Event, error)
+}
+
+var _ SLAService = (*Engine)(nil)
+
+// Engine computes response deadlines on ingest and raises warning and breach events.
+type Engine struct {
+	repo       Repository
+	auth       email.Repository
+	calendars  calendar.Provider
+	escalators map[string]Escalator
+	targets    map[string]map[string]bool
+	listener   EventListener
+	clock      email.Clock
+}
+
+// NewEngine constructs an Engine. Deadlines of policies without their own
+// hours count the working time of calendars. escalators is keyed by escalation
+// action and listener may be nil.
+func NewEngine(repo Repository, auth email.Repository, calendars calendar.Provider, escalators map[string]Escalator, listener EventListener, clock email.Clock) *Engine {
+	if repo == nil {
+		panic("sla: repository dependency is required")
+	}
+	if auth == nil {
+		panic("sla: email repository dependency is required")
+	}
+	if calendars == nil {
+		panic("sla: calendar dependency is required")
+	}
+	if clock == nil {
+		panic("sla: clock dependency is required")
+	}
+	registered := make(map[string]Escalator, len(escalators))
+	for action, escalator := range escalators {
+		if escalator == nil {
+			panic(fmt.Sprintf("sla: escalator for %q is nil", action))
+		}
+		registered[action] = escalator
+	}
+	return &Engine{repo: repo, auth: auth, calendars: calendars, escalators: registered, targets: make(map[string]map[string]bool), listener: listener, clock: clock}
+}
+
+// RestrictTargets limits the escalations of action to targets. Policies naming
+// any other target are rejected, and stored ones are not escalated. It must be
+// called before the engine is used.
+func (e *Engine) RestrictTargets(action string, targets ...string) {
+	allowed := make(map[string]bool, len(targets))
+	for _, target := range targets {
+		if target = strings.TrimSpace(target); target != "" {
+			allowed[target] = true
+		}
+	}
+	e.targets[action] = allowed
+}
+
+// permitted reports whether escalation names a target its action allows.
+func (e *Engine) permitted(escalation Escalation) bool {
+	allowed, restricted := e.targets[escalation.Action]
+	return !restricted || allowed[strings.TrimSpace(escalation.Target)]
+}
+
+// CreatePolicy validates and stores a new policy.
+func (e *Engine) CreatePolicy(ctx context.Context, policy Policy) (*Policy, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := e.validate(&policy); err != nil {
+		return nil, err
+	}
+	id, err := newID()
+	if err != nil {
+		return nil, err
+	}
+	policy.ID = id
+	policy.CreatedAt = e.clock.Now().UTC()
+	if err := e.repo.SavePolicy(ctx, policy); err != nil {
+		return nil, err
+	}
+	return &policy, nil
+}
+
+// UpdatePolicy replaces an existing policy. Deadlines already computed keep their due dates.
+func (e *Engine) UpdatePolicy(ctx context.Context, id string, policy Policy) (*Policy, error) {
+	existing, err := e.GetPolicy(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := e.validate(&policy); err != nil {
+		return nil, err
+	}
+	policy.ID = existing.ID
+	policy.CreatedAt = existing.CreatedAt
+	if err := e.repo.SavePolicy(ctx, policy); err != nil {
+		return nil, err
+	}
+	return &policy, nil
+}
+
+// GetPolicy returns a policy by ID.
+func (e *Engine) GetPolicy(ctx context.Context, id string) (*Policy, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	policy, err := e.repo.GetPolicy(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if policy == nil {
+		return nil, ErrPolicyNotFound
+	}
+	return policy, nil
+}
+
+// ListPolicies returns policies in evaluation order: the oldest matching policy wins.
+func (e *Engine) ListPolicies(ctx context.Context) ([]Policy, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	policies, err := e.repo.ListPolicies(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(policies, func(i, j int) bool {
+		if policies[i].CreatedAt.Equal(policies[j].CreatedAt) {
+			return policies[i].ID < policies[j].ID
+		}
+		return policies[i].CreatedAt.Before(policies[j].CreatedAt)
+	})
+	return policies, nil
+}
+
+// DeletePolicy removes a policy. Deadlines it produced remain tracked.
+func (e *Engine) DeletePolicy(ctx context.Context, id string) error {
+	if _, err := e.GetPolicy(ctx, id); err != nil {
+		return err
+	}
+	return e.repo.DeletePolicy(ctx, id)
+}
+
+// Deadlines returns deadlines ordered by due date, optionally filtered by status.
+func (e *Engine) Deadlines(ctx context.Context, status DeadlineStatus) ([]Deadline, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	all, err := e.repo.ListDeadlines(ctx)
+	if err != nil {
+		return nil, err
+	}
+	list := make([]Deadline, 0, len(all))
+	for _, deadline := range all {
+		if status == "" || deadline.Status == status {
+			list = append(list, deadline)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].DueAt.Before(list[j].DueAt) })
+	return list, nil
+}
+
+// MessagesSynced implements email.SyncListener. Inbound messages matching a
+// policy get a deadline; replies from the mailbox owner meet the open deadlines
+// of earlier messages in the same thread.
+func (e *Engine) MessagesSynced(ctx context.Context, messages []email.EmailMessage, _ time.Time) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	auth, err := e.auth.GetAuth(ctx)
+	if err != nil {
+		return err
+	}
+	var user string
+	if auth != nil {
+		user = auth.State.Username
+	}
+	policies, err := e.ListPolicies(ctx)
+	if err != nil {
+		return err
+	}
+
+	replies := make(map[string]time.Time)
+	for _, message := range messages {
+		if message.SentBy(user) {
+			key := message.ThreadKey()
+			if message.ReceivedAt.After(replies[key]) {
+				replies[key] = message.ReceivedAt.UTC()
+			}
+			continue
+		}
+		if err := e.track(ctx, message, policies); err != nil {
+			return err
+		}
+	}
+	if len(replies) == 0 {
+		return nil
+	}
+
+	deadlines, err := e.repo.ListDeadlines(ctx)
+	if err != nil {
+		return err
+	}
+	for _, deadline := range deadlines {
+		repliedAt, ok := replies[deadline.ThreadID]
+		if !ok || repliedAt.Before(deadline.ReceivedAt) {
+			continue
+		}
+		if err := e.meet(ctx, deadline, repliedAt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// meet marks deadline met by a reply at repliedAt, re-reading it when another
+// writer changed it first.
+func (e *Engine) meet(ctx context.Context, deadline Deadline, repliedAt time.Time) error {
+	for deadline.Open() {
+		deadline.Status = StatusMet
+		deadline.MetAt = &repliedAt
+		err := e.repo.SaveDeadline(ctx, deadline)
+		if !errors.Is(err, ErrConflict) {
+			return err
+		}
+		current, err := e.repo.GetDeadline(ctx, deadline.MessageID)
+		if err != nil || current == nil {
+			return err
+		}
+		deadline = *current
+	}
+	return nil
+}
+
+func (e *Engine) track(ctx context.Context, message email.EmailMessage, policies []Policy) error {
+	existing, err := e.repo.GetDeadline(ctx, message.ID)
+	if err != nil || existing != nil {
+		return err
+	}
+	for _, policy := range policies {
+		if !policy.Match.Matches(message) {
+			continue
+		}
+		warnAt, dueAt, err := e.schedule(ctx, policy, message.ReceivedAt.UTC())
+		if err != nil {
+			return err
+		}
+		err = e.repo.SaveDeadline(ctx, Deadline{
+			MessageID:  message.ID,
+			ThreadID:   message.ThreadKey(),
+			PolicyID:   policy.ID,
+			Subject:    message.Subject,
+			Sender:     message.Sender,
+			ReceivedAt: message.ReceivedAt.UTC(),
+			WarnAt:     warnAt,
+			DueAt:      dueAt,
+			Status:     StatusPending,
+		})
+		if errors.Is(err, ErrConflict) {
+			// Tracked by a concurrent sync.
+			return nil
+		}
+		return err
+	}
+	return nil
+}
+
+// Evaluate raises warning events for deadlines entering their warning window and
+// breach events for deadlines that have passed. The policy's escalations are
+// written to the outbox together with the breached deadline and delivered by the
+// relay through Deliverer.
+func (e *Engine) Evaluate(ctx context.Context) ([]Event, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	deadlines, err := e.repo.ListDeadlines(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	now := e.clock.Now().UTC()
+	var events []Event
+	var errs []error
+	for _, deadline := range deadlines {
+		pendingEscalation := deadline.Status == StatusBreached && deadline.EscalatedAt == nil
+		if !deadline.Open() && !pendingEscalation {
+			continue
+		}
+		policy, err := e.repo.GetPolicy(ctx, deadline.PolicyID)
+		if err != nil {
+			return events, err
+		}
+		if policy == nil {
+			policy = &Policy{ID: deadline.PolicyID}
+		}
+
+		var event *Event
+		switch {
+		case deadline.Open() && !now.Before(deadline.DueAt):
+			deadline.Status = StatusBreached
+			deadline.BreachedAt = &now
+			event = &Event{Type: EventBreached, At: now, Deadline: deadline, Policy: *policy}
+		case deadline.Status == StatusPending && !now.Before(deadline.WarnAt):
+			deadline.Status = StatusWarning
+			deadline.WarnedAt = &now
+			event = &Event{Type: EventWarning, At: now, Deadline: deadline, Policy: *policy}
+		}
+
+		var effects []outbox.Entry
+		if deadline.Status == StatusBreached && deadline.EscalatedAt == nil {
+			if effects, err = e.escalations(deadline, *policy, now); err != nil {
+				errs = append(errs, err)
+			} else {
+				deadline.EscalatedAt = &now
+			}
+		}
+		if event == nil && deadline.EscalatedAt == nil {
+			continue
+		}
+		if err := e.repo.SaveDeadline(ctx, deadline, effects...); errors.Is(err, ErrConflict) {
+			// Met or evaluated by another writer since it was listed; the
+			// next evaluation sees the stored deadline.
+			continue
+		} else if err != nil {
+			return events, err
+		}
+		if event == nil {
+			continue
+		}
+		deadline.Version++
+		event.Deadline = deadline
+		if e.listener != nil {
+			if err := e.listener.SLAEvent(ctx, *event); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		events = append(events, *event)
+	}
+	return events, errors.Join(errs...)
+}
+
+// escalationEffect is the outbox payload of one escalation.
+type escalationEffect struct {
+	Event      Event      `json:"event"`
+	Escalation Escalation `json:"escalation"`
+}
+
+// escalations builds one outbox entry per escalation of policy, keyed by the
+// message and escalation so a repeated evaluation cannot enqueue it twice.
+func (e *Engine) escalations(deadline Deadline, policy Policy, now time.Time) ([]outbox.Entry, error) {
+	event := Event{Type: EventBreached, At: now, Deadline: deadline, Policy: policy}
+	if deadline.BreachedAt != nil {
+		event.At = *deadline.BreachedAt
+	}
+	entries := make([]outbox.Entry, 0, len(policy.Escalations))
+	for i, escalation := range policy.Escalations {
+		key := fmt.Sprintf("sla:%s:%d:%s:%s", deadline.MessageID, i, escalation.Action, escalation.Target)
+		entry, err := outbox.NewEntry(OutboxDestination, key, escalationEffect{Event: event, Escalation: escalation}, now)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// Deliverer returns the outbox deliverer running escalations with the engine's escalators.
+func (e *Engine) Deliverer() outbox.Deliverer {
+	return outbox.DelivererFunc(func(ctx context.Context, entry outbox.Entry) error {
+		var effect escalationEffect
+		if err := json.Unmarshal(entry.Payload, &effect); err != nil {
+			return fmt.Errorf("decode escalation: %w", err)
+		}
+		escalator, ok := e.escalators[effect.Escalation.Action]
+		if !ok {
+			return fmt.Errorf("no escalator for action %q", effect.Escalation.Action)
+		}
+		if !e.permitted(effect.Escalation) {
+			return fmt.Errorf("%s escalation target %q is not allowed", effect.Escalation.Action, effect.Escalation.Target)
+		}
+		if err := escalator.Escalate(ctx, effect.Event, effect.Escalation); err != nil {
+			return fmt.Errorf("%s escalation to %s: %w", effect.Escalation.Action, effect.Escalation.Target, err)
+		}
+		return nil
+	})
+}
+
+func (e *Engine) validate(policy *Policy) error {
+	policy.Name = strings.TrimSpace(policy.Name)
+	policy.Match.SenderDomain = strings.ToLower(strings.TrimSpace(policy.Match.SenderDomain))
+	if policy.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidPolicy)
+	}
+	if policy.ResponseWithin <= 0 {
+		return fmt.Errorf("%w: responseWithin must be positive", ErrInvalidPolicy)
+	}
+	if policy.WarnBefore < 0 || policy.WarnBefore >= policy.ResponseWithin {
+		return fmt.Errorf("%w: warnBefore must be between zero and responseWithin", ErrInvalidPolicy)
+	}
+	if policy.Hours != nil && policy.AroundTheClock {
+		return fmt.Errorf("%w: businessHours and aroundTheClock are exclusive", ErrInvalidPolicy)
+	}
+	if policy.Hours != nil {
+		if _, err := calendar.New(*policy.Hours, e.clock); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidPolicy, err)
+		}
+	}
+	for _, escalation := range policy.Escalations {
+		if _, ok := e.escalators[escalation.Action]; !ok {
+			return fmt.Errorf("%w: unsupported escalation action %q", ErrInvalidPolicy, escalation.Action)
+		}
+		if strings.TrimSpace(escalation.Target) == "" {
+			return fmt.Errorf("%w: escalation target is required", ErrInvalidPolicy)
+		}
+		if !e.permitted(escalation) {
+			return fmt.Errorf("%w: %s escalation target %q is not allowed", ErrInvalidPolicy, escalation.Action, escalation.Target)
+		}
+	}
+	return nil
+}
+
+// schedule returns the warning and due times of p for a message received at receivedAt.
+func (e *Engine) schedule(ctx context.Context, p Policy, receivedAt time.Time) (time.Time, time.Time, error) {
+	within := time.Duration(p.ResponseWithin)
+	warnAfter := within - time.Duration(p.WarnBefore)
+	if p.AroundTheClock {
+		return receivedAt.Add(warnAfter), receivedAt.Add(within), nil
+	}
+	var (
+		cal *calendar.Calendar
+		err error
+	)
+	if p.Hours != nil {
+		cal, err = calendar.New(*p.Hours, e.clock)
+	} else {
+		cal, err = e.calendars.Calendar(ctx)
+	}
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	return cal.Add(receivedAt, warnAfter), cal.Add(receivedAt, within), nil
+}
+
+func newID() (string, error) {
+	buf := make([]byte, 6)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("generate sla policy id: %w", err)
+	}
+	return "sla-" + hex.EncodeToString(buf), nil
+}
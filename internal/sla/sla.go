@@ -0,0 +1,192 @@
+package sla
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/example/iboz/internal/calendar"
+	"github.com/example/iboz/internal/email"
+	"github.com/example/iboz/internal/outbox"
+)
+
+// DeadlineStatus enumerates the lifecycle of a response deadline.
+type DeadlineStatus string
+
+const (
+	StatusPending  DeadlineStatus = "pending"
+	StatusWarning  DeadlineStatus = "warning"
+	StatusBreached DeadlineStatus = "breached"
+	StatusMet      DeadlineStatus = "met"
+)
+
+// EventType enumerates the events raised by the engine.
+type EventType string
+
+const (
+	EventWarning  EventType = "sla.warning"
+	EventBreached EventType = "sla.breached"
+)
+
+// Escalation action types handled by the bundled escalators.
+const (
+	ActionWebhook = "webhook"
+	ActionEmail   = "email"
+	ActionSlack   = "slack"
+	ActionTeams   = "teams"
+)
+
+// OutboxDestination is the outbox destination of escalations, delivered by the
+// Engine's Deliverer.
+const OutboxDestination = "sla.escalation"
+
+var (
+	// ErrPolicyNotFound is returned when a policy does not exist.
+	ErrPolicyNotFound = errors.New("sla policy not found")
+	// ErrInvalidPolicy is returned when a policy fails validation.
+	ErrInvalidPolicy = errors.New("invalid sla policy")
+	// ErrUnknownStatus is returned when filtering by an unsupported status.
+	ErrUnknownStatus = errors.New("unknown sla deadline status")
+	// ErrConflict is returned by repositories when a deadline update races with another writer.
+	ErrConflict = errors.New("sla deadline was modified concurrently")
+)
+
+// Duration is a time.Duration encoded as a Go duration string in JSON.
+type Duration time.Duration
+
+// MarshalJSON implements json.Marshaler.
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	if value == "" {
+		*d = 0
+		return nil
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// Match selects the messages a policy applies to. Empty fields match everything;
+// set fields must all match.
+type Match struct {
+	SenderDomain string `json:"senderDomain,omitempty"`
+	Label        string `json:"label,omitempty"`
+	Category     string `json:"category,omitempty"`
+}
+
+// Escalation is an action executed when a deadline is breached.
+type Escalation struct {
+	Action string `json:"action"`
+	Target string `json:"target"`
+}
+
+// Matches reports whether message falls under the selector.
+func (m Match) Matches(message email.EmailMessage) bool {
+	if m.SenderDomain != "" && !strings.EqualFold(m.SenderDomain, message.SenderDomain()) {
+		return false
+	}
+	if m.Label != "" && !message.HasLabel(m.Label) {
+		return false
+	}
+	if m.Category != "" && !strings.EqualFold(m.Category, message.Category) {
+		return false
+	}
+	return true
+}
+
+// Policy defines the response deadline for matching messages. Only working
+// time counts: Hours overrides the user's working-hours calendar, and
+// AroundTheClock counts every hour instead.
+type Policy struct {
+	ID             string          `json:"id"`
+	Name           string          `json:"name"`
+	Match          Match           `json:"match"`
+	ResponseWithin Duration        `json:"responseWithin"`
+	WarnBefore     Duration        `json:"warnBefore,omitempty"`
+	Hours          *calendar.Hours `json:"businessHours,omitempty"`
+	AroundTheClock bool            `json:"aroundTheClock,omitempty"`
+	Escalations    []Escalation    `json:"escalations,omitempty"`
+	CreatedAt      time.Time       `json:"createdAt"`
+}
+
+// Deadline is the response deadline computed for a message on ingest. Version
+// increases on every stored change and guards updates against concurrent writers.
+type Deadline struct {
+	MessageID   string         `json:"messageId"`
+	ThreadID    string         `json:"threadId"`
+	PolicyID    string         `json:"policyId"`
+	Subject     string         `json:"subject"`
+	Sender      string         `json:"sender"`
+	ReceivedAt  time.Time      `json:"receivedAt"`
+	WarnAt      time.Time      `json:"warnAt"`
+	DueAt       time.Time      `json:"dueAt"`
+	Status      DeadlineStatus `json:"status"`
+	Version     int            `json:"version"`
+	WarnedAt    *time.Time     `json:"warnedAt,omitempty"`
+	BreachedAt  *time.Time     `json:"breachedAt,omitempty"`
+	MetAt       *time.Time     `json:"metAt,omitempty"`
+	EscalatedAt *time.Time     `json:"escalatedAt,omitempty"`
+}
+
+// Event is raised when a deadline enters the warning window or is breached.
+type Event struct {
+	Type     EventType `json:"type"`
+	At       time.Time `json:"at"`
+	Deadline Deadline  `json:"deadline"`
+	Policy   Policy    `json:"policy"`
+}
+
+// Open reports whether the deadline can still warn or breach.
+func (d Deadline) Open() bool {
+	return d.Status == StatusPending || d.Status == StatusWarning
+}
+
+// Repository defines the persistence contract for policies and deadlines. It owns
+// the outbox table holding pending escalations.
+type Repository interface {
+	outbox.Store
+	SavePolicy(ctx context.Context, policy Policy) error
+	GetPolicy(ctx context.Context, id string) (*Policy, error)
+	ListPolicies(ctx context.Context) ([]Policy, error)
+	DeletePolicy(ctx context.Context, id string) error
+	// SaveDeadline stores deadline and appends effects to the outbox atomically.
+	// A deadline with Version zero is inserted; otherwise it is stored if the
+	// stored version equals deadline.Version and the version is incremented. A
+	// mismatch, or inserting a deadline that exists, returns ErrConflict.
+	SaveDeadline(ctx context.Context, deadline Deadline, effects ...outbox.Entry) error
+	GetDeadline(ctx context.Context, messageID string) (*Deadline, error)
+	ListDeadlines(ctx context.Context) ([]Deadline, error)
+}
+
+// Escalator executes one kind of escalation action.
+type Escalator interface {
+	Escalate(ctx context.Context, event Event, escalation Escalation) error
+}
+
+// EventListener observes warning and breach events. It is optional.
+type EventListener interface {
+	SLAEvent(ctx context.Context, event Event) error
+}
+
+// ParseStatus validates a status filter; the empty string matches every deadline.
+func ParseStatus(value string) (DeadlineStatus, error) {
+	switch status := DeadlineStatus(value); status {
+	case "", StatusPending, StatusWarning, StatusBreached, StatusMet:
+		return status, nil
+	default:
+		return "", ErrUnknownStatus
+	}
+}
@@ -0,0 +1,366 @@
+package sla_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/example/iboz/internal/calendar"
+	"github.com/example/iboz/internal/email"
+	emailmemory "github.com/example/iboz/internal/email/adapter/memory"
+	"github.com/example/iboz/internal/notify"
+	"github.com/example/iboz/internal/outbox"
+	"github.com/example/iboz/internal/sla"
+	"github.com/example/iboz/internal/sla/adapter/chat"
+	"github.com/example/iboz/internal/sla/adapter/memory"
+	"github.com/example/iboz/internal/testutil"
+)
+
+type recordingEscalator struct {
+	escalated []sla.Escalation
+	err       error
+}
+
+func (r *recordingEscalator) Escalate(_ context.Context, _ sla.Event, escalation sla.Escalation) error {
+	if r.err != nil {
+		return r.err
+	}
+	r.escalated = append(r.escalated, escalation)
+	return nil
+}
+
+type recordingListener struct {
+	events []sla.EventType
+}
+
+func (r *recordingListener) SLAEvent(_ context.Context, event sla.Event) error {
+	r.events = append(r.events, event.Type)
+	return nil
+}
+
+func newEngine(t *testing.T, escalator sla.Escalator, listener sla.EventListener, clock email.Clock) *sla.Engine {
+	t.Helper()
+	auth := emailmemory.NewRepository()
+	if err := auth.SaveAuth(context.Background(), email.AuthRecord{State: email.AuthState{Username: "support@example.com"}}); err != nil {
+		t.Fatalf("save auth: %v", err)
+	}
+	return sla.NewEngine(memory.NewRepository(), auth, newCalendar(t, calendar.DefaultHours(), clock), map[string]sla.Escalator{sla.ActionWebhook: escalator}, listener, clock)
+}
+
+func newCalendar(t *testing.T, hours calendar.Hours, clock email.Clock) *calendar.Calendar {
+	t.Helper()
+	cal, err := calendar.New(hours, clock)
+	if err != nil {
+		t.Fatalf("calendar: %v", err)
+	}
+	return cal
+}
+
+func TestBusinessHoursDeadlineSkipsNightsWeekendsAndHolidays(t *testing.T) {
+	ctx := context.Background()
+	// Friday 16:00 in New York.
+	receivedAt := time.Date(2025, time.March, 21, 20, 0, 0, 0, time.UTC)
+	clock := testutil.NewClock(receivedAt)
+	engine := newEngine(t, &recordingEscalator{}, nil, clock)
+
+	_, err := engine.CreatePolicy(ctx, sla.Policy{
+		Name:           "Enterprise",
+		Match:          sla.Match{SenderDomain: "Customer.example"},
+		ResponseWithin: sla.Duration(4 * time.Hour),
+		WarnBefore:     sla.Duration(time.Hour),
+		Hours: &calendar.Hours{
+			Timezone: "America/New_York",
+			Start:    "09:00",
+			End:      "17:00",
+			Holidays: []string{"2025-03-24"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("create policy: %v", err)
+	}
+
+	batch := []email.EmailMessage{
+		{ID: "in-1", Sender: "Ada <ada@customer.example>", Subject: "Outage", ReceivedAt: receivedAt},
+		{ID: "in-2", Sender: "bob@other.example", Subject: "Hello", ReceivedAt: receivedAt},
+	}
+	if err := engine.MessagesSynced(ctx, batch, receivedAt); err != nil {
+		t.Fatalf("messages synced: %v", err)
+	}
+
+	deadlines, err := engine.Deadlines(ctx, "")
+	if err != nil {
+		t.Fatalf("deadlines: %v", err)
+	}
+	if len(deadlines) != 1 || deadlines[0].MessageID != "in-1" {
+		t.Fatalf("expected one deadline for the matching sender, got %+v", deadlines)
+	}
+	// One hour on Friday, Monday is a holiday, three hours on Tuesday from 09:00.
+	wantDue := time.Date(2025, time.March, 25, 16, 0, 0, 0, time.UTC)
+	wantWarn := time.Date(2025, time.March, 25, 15, 0, 0, 0, time.UTC)
+	if !deadlines[0].DueAt.Equal(wantDue) || !deadlines[0].WarnAt.Equal(wantWarn) {
+		t.Fatalf("unexpected schedule: warn %s due %s", deadlines[0].WarnAt, deadlines[0].DueAt)
+	}
+}
+
+func TestPoliciesWithoutHoursFollowUserCalendar(t *testing.T) {
+	ctx := context.Background()
+	// Friday 16:00 UTC, an hour before the default working hours end.
+	receivedAt := time.Date(2025, time.March, 21, 16, 0, 0, 0, time.UTC)
+	clock := testutil.NewClock(receivedAt)
+	engine := newEngine(t, &recordingEscalator{}, nil, clock)
+
+	for _, policy := range []sla.Policy{
+		{Name: "Standard", Match: sla.Match{Label: "standard"}, ResponseWithin: sla.Duration(4 * time.Hour)},
+		{Name: "Critical", Match: sla.Match{Label: "critical"}, ResponseWithin: sla.Duration(4 * time.Hour), AroundTheClock: true},
+	} {
+		if _, err := engine.CreatePolicy(ctx, policy); err != nil {
+			t.Fatalf("create policy: %v", err)
+		}
+	}
+	if _, err := engine.CreatePolicy(ctx, sla.Policy{Name: "Both", ResponseWithin: sla.Duration(time.Hour), AroundTheClock: true, Hours: &calendar.Hours{Start: "09:00", End: "17:00"}}); !errors.Is(err, sla.ErrInvalidPolicy) {
+		t.Fatalf("expected business hours and around the clock to be exclusive, got %v", err)
+	}
+
+	batch := []email.EmailMessage{
+		{ID: "in-1", Sender: "ada@customer.example", Labels: []string{"standard"}, ReceivedAt: receivedAt},
+		{ID: "in-2", Sender: "ada@customer.example", Labels: []string{"critical"}, ReceivedAt: receivedAt},
+	}
+	if err := engine.MessagesSynced(ctx, batch, receivedAt); err != nil {
+		t.Fatalf("messages synced: %v", err)
+	}
+	deadlines, err := engine.Deadlines(ctx, "")
+	if err != nil || len(deadlines) != 2 {
+		t.Fatalf("expected two deadlines, got %+v (%v)", deadlines, err)
+	}
+	due := map[string]time.Time{}
+	for _, deadline := range deadlines {
+		due[deadline.MessageID] = deadline.DueAt
+	}
+	// One hour on Friday and three on Monday from 09:00.
+	if want := time.Date(2025, time.March, 24, 12, 0, 0, 0, time.UTC); !due["in-1"].Equal(want) {
+		t.Fatalf("expected the calendar deadline %s, got %s", want, due["in-1"])
+	}
+	if want := receivedAt.Add(4 * time.Hour); !due["in-2"].Equal(want) {
+		t.Fatalf("expected the around-the-clock deadline %s, got %s", want, due["in-2"])
+	}
+}
+
+func TestEvaluateWarnsBreachesAndEscalatesThroughOutbox(t *testing.T) {
+	ctx := context.Background()
+	receivedAt := time.Date(2025, time.March, 18, 9, 0, 0, 0, time.UTC)
+	clock := testutil.NewClock(receivedAt)
+	escalator := &recordingEscalator{}
+	listener := &recordingListener{}
+	auth := emailmemory.NewRepository()
+	if err := auth.SaveAuth(ctx, email.AuthRecord{State: email.AuthState{Username: "support@example.com"}}); err != nil {
+		t.Fatalf("save auth: %v", err)
+	}
+	repo := memory.NewRepository()
+	engine := sla.NewEngine(repo, auth, newCalendar(t, calendar.DefaultHours(), clock), map[string]sla.Escalator{sla.ActionWebhook: escalator}, listener, clock)
+	relay := outbox.NewRelay([]outbox.Store{repo}, map[string]outbox.Deliverer{sla.OutboxDestination: engine.Deliverer()}, clock, outbox.Config{BaseBackoff: time.Minute})
+
+	_, err := engine.CreatePolicy(ctx, sla.Policy{
+		Name:           "Urgent",
+		Match:          sla.Match{Label: "urgent"},
+		ResponseWithin: sla.Duration(2 * time.Hour),
+		WarnBefore:     sla.Duration(30 * time.Minute),
+		Escalations:    []sla.Escalation{{Action: sla.ActionWebhook, Target: "https://hooks.example/sla"}},
+	})
+	if err != nil {
+		t.Fatalf("create policy: %v", err)
+	}
+	batch := []email.EmailMessage{{ID: "in-1", Sender: "ada@customer.example", Labels: []string{"URGENT"}, ReceivedAt: receivedAt}}
+	if err := engine.MessagesSynced(ctx, batch, receivedAt); err != nil {
+		t.Fatalf("messages synced: %v", err)
+	}
+
+	clock.Set(receivedAt.Add(90 * time.Minute))
+	events, err := engine.Evaluate(ctx)
+	if err != nil || len(events) != 1 || events[0].Type != sla.EventWarning {
+		t.Fatalf("expected a warning event, got %+v (%v)", events, err)
+	}
+
+	clock.Set(receivedAt.Add(2 * time.Hour))
+	events, err = engine.Evaluate(ctx)
+	if err != nil || len(events) != 1 || events[0].Type != sla.EventBreached {
+		t.Fatalf("expected a breach event, got %+v (%v)", events, err)
+	}
+	breached, err := engine.Deadlines(ctx, sla.StatusBreached)
+	if err != nil || len(breached) != 1 || breached[0].EscalatedAt == nil {
+		t.Fatalf("expected the breach to record its escalation, got %+v (%v)", breached, err)
+	}
+	if len(escalator.escalated) != 0 {
+		t.Fatal("expected escalations to wait for the relay")
+	}
+
+	escalator.err = errors.New("endpoint down")
+	relayed, err := relay.Deliver(ctx)
+	if err == nil || len(relayed) != 1 || relayed[0].Status != outbox.StatusPending || relayed[0].Attempts != 1 {
+		t.Fatalf("expected a failed attempt left pending, got %+v (%v)", relayed, err)
+	}
+	if relayed, err := relay.Deliver(ctx); err != nil || len(relayed) != 0 {
+		t.Fatalf("expected the retry to wait for its backoff, got %+v (%v)", relayed, err)
+	}
+
+	escalator.err = nil
+	clock.Advance(time.Minute)
+	if relayed, err := relay.Deliver(ctx); err != nil || len(relayed) != 1 || relayed[0].Status != outbox.StatusDelivered {
+		t.Fatalf("expected the escalation to be delivered, got %+v (%v)", relayed, err)
+	}
+	if events, err := engine.Evaluate(ctx); err != nil || len(events) != 0 {
+		t.Fatalf("expected no new events, got %+v (%v)", events, err)
+	}
+	if _, err := relay.Deliver(ctx); err != nil || len(escalator.escalated) != 1 {
+		t.Fatalf("expected escalation to run once, got %+v (%v)", escalator.escalated, err)
+	}
+	if len(listener.events) != 2 {
+		t.Fatalf("expected warning and breach events, got %v", listener.events)
+	}
+}
+
+func TestReplyMeetsDeadline(t *testing.T) {
+	ctx := context.Background()
+	receivedAt := time.Date(2025, time.March, 18, 9, 0, 0, 0, time.UTC)
+	clock := testutil.NewClock(receivedAt)
+	engine := newEngine(t, &recordingEscalator{}, nil, clock)
+
+	if _, err := engine.CreatePolicy(ctx, sla.Policy{Name: "All", ResponseWithin: sla.Duration(time.Hour)}); err != nil {
+		t.Fatalf("create policy: %v", err)
+	}
+	batch := []email.EmailMessage{{ID: "in-1", Sender: "ada@customer.example", ThreadID: "t-1", ReceivedAt: receivedAt}}
+	if err := engine.MessagesSynced(ctx, batch, receivedAt); err != nil {
+		t.Fatalf("messages synced: %v", err)
+	}
+	batch = append(batch, email.EmailMessage{ID: "out-1", Sender: "support@example.com", ThreadID: "t-1", ReceivedAt: receivedAt.Add(20 * time.Minute)})
+	if err := engine.MessagesSynced(ctx, batch, receivedAt); err != nil {
+		t.Fatalf("messages synced: %v", err)
+	}
+
+	met, err := engine.Deadlines(ctx, sla.StatusMet)
+	if err != nil || len(met) != 1 || met[0].MetAt == nil {
+		t.Fatalf("expected deadline to be met, got %+v (%v)", met, err)
+	}
+	clock.Set(receivedAt.Add(2 * time.Hour))
+	if events, err := engine.Evaluate(ctx); err != nil || len(events) != 0 {
+		t.Fatalf("expected no events for a met deadline, got %+v (%v)", events, err)
+	}
+}
+
+// racingRepository runs beforePolicy once, between Evaluate listing the
+// deadlines and saving them.
+type racingRepository struct {
+	*memory.Repository
+	beforePolicy func()
+}
+
+func (r *racingRepository) GetPolicy(ctx context.Context, id string) (*sla.Policy, error) {
+	if hook := r.beforePolicy; hook != nil {
+		r.beforePolicy = nil
+		hook()
+	}
+	return r.Repository.GetPolicy(ctx, id)
+}
+
+func TestEvaluateSkipsDeadlineMetConcurrently(t *testing.T) {
+	ctx := context.Background()
+	receivedAt := time.Date(2025, time.March, 18, 9, 0, 0, 0, time.UTC)
+	clock := testutil.NewClock(receivedAt)
+	listener := &recordingListener{}
+	auth := emailmemory.NewRepository()
+	if err := auth.SaveAuth(ctx, email.AuthRecord{State: email.AuthState{Username: "support@example.com"}}); err != nil {
+		t.Fatalf("save auth: %v", err)
+	}
+	repo := &racingRepository{Repository: memory.NewRepository()}
+	engine := sla.NewEngine(repo, auth, newCalendar(t, calendar.DefaultHours(), clock), map[string]sla.Escalator{sla.ActionWebhook: &recordingEscalator{}}, listener, clock)
+
+	_, err := engine.CreatePolicy(ctx, sla.Policy{
+		Name:           "All",
+		ResponseWithin: sla.Duration(time.Hour),
+		Escalations:    []sla.Escalation{{Action: sla.ActionWebhook, Target: "https://hooks.example/sla"}},
+	})
+	if err != nil {
+		t.Fatalf("create policy: %v", err)
+	}
+	batch := []email.EmailMessage{{ID: "in-1", Sender: "ada@customer.example", ThreadID: "t-1", ReceivedAt: receivedAt}}
+	if err := engine.MessagesSynced(ctx, batch, receivedAt); err != nil {
+		t.Fatalf("messages synced: %v", err)
+	}
+
+	clock.Set(receivedAt.Add(2 * time.Hour))
+	repo.beforePolicy = func() {
+		reply := append(batch, email.EmailMessage{ID: "out-1", Sender: "support@example.com", ThreadID: "t-1", ReceivedAt: receivedAt.Add(50 * time.Minute)})
+		if err := engine.MessagesSynced(ctx, reply, clock.Now()); err != nil {
+			t.Fatalf("messages synced: %v", err)
+		}
+	}
+	events, err := engine.Evaluate(ctx)
+	if err != nil || len(events) != 0 || len(listener.events) != 0 {
+		t.Fatalf("expected no breach for a deadline met meanwhile, got %+v (%v)", events, err)
+	}
+	if met, err := engine.Deadlines(ctx, sla.StatusMet); err != nil || len(met) != 1 {
+		t.Fatalf("expected the deadline to stay met, got %+v (%v)", met, err)
+	}
+	if pending, err := repo.PendingEntries(ctx, clock.Now(), 10); err != nil || len(pending) != 0 {
+		t.Fatalf("expected no escalation in the outbox, got %+v (%v)", pending, err)
+	}
+}
+
+func TestCreatePolicyValidation(t *testing.T) {
+	engine := newEngine(t, &recordingEscalator{}, nil, testutil.NewClock(time.Time{}))
+	cases := []sla.Policy{
+		{ResponseWithin: sla.Duration(time.Hour)},
+		{Name: "No duration"},
+		{Name: "Warn too late", ResponseWithin: sla.Duration(time.Hour), WarnBefore: sla.Duration(time.Hour)},
+		{Name: "Bad hours", ResponseWithin: sla.Duration(time.Hour), Hours: &calendar.Hours{Start: "17:00", End: "09:00"}},
+		{Name: "Bad action", ResponseWithin: sla.Duration(time.Hour), Escalations: []sla.Escalation{{Action: sla.ActionEmail, Target: "lead@example.com"}}},
+	}
+	for _, policy := range cases {
+		if _, err := engine.CreatePolicy(context.Background(), policy); !errors.Is(err, sla.ErrInvalidPolicy) {
+			t.Fatalf("expected invalid policy for %+v, got %v", policy, err)
+		}
+	}
+}
+
+func TestRestrictTargetsRejectsUnlistedTargets(t *testing.T) {
+	engine := newEngine(t, &recordingEscalator{}, nil, testutil.NewClock(time.Time{}))
+	engine.RestrictTargets(sla.ActionWebhook, "https://hooks.example/sla")
+
+	allowed := sla.Policy{Name: "Allowed", ResponseWithin: sla.Duration(time.Hour), Escalations: []sla.Escalation{{Action: sla.ActionWebhook, Target: "https://hooks.example/sla"}}}
+	if _, err := engine.CreatePolicy(context.Background(), allowed); err != nil {
+		t.Fatalf("create policy with an allowed target: %v", err)
+	}
+	internal := sla.Policy{Name: "Internal", ResponseWithin: sla.Duration(time.Hour), Escalations: []sla.Escalation{{Action: sla.ActionWebhook, Target: "http://169.254.169.254/latest"}}}
+	if _, err := engine.CreatePolicy(context.Background(), internal); !errors.Is(err, sla.ErrInvalidPolicy) {
+		t.Fatalf("expected an unlisted target to be rejected, got %v", err)
+	}
+}
+
+type recordingNotifier struct {
+	sent []notify.Notification
+}
+
+func (r *recordingNotifier) Notify(_ context.Context, n notify.Notification) error {
+	r.sent = append(r.sent, n)
+	return nil
+}
+
+func TestChatEscalatorPostsToTargetChannel(t *testing.T) {
+	notifier := &recordingNotifier{}
+	escalator := chat.NewEscalator(notifier, notify.Linker{BaseURL: "https://iboz.example"})
+	event := sla.Event{
+		Type:     sla.EventBreached,
+		Deadline: sla.Deadline{MessageID: "in-1", Subject: "Outage", Sender: "ada@customer.example", PolicyID: "sla-1"},
+		Policy:   sla.Policy{ID: "sla-1", Name: "Enterprise"},
+	}
+	if err := escalator.Escalate(context.Background(), event, sla.Escalation{Action: sla.ActionSlack, Target: "#escalations"}); err != nil {
+		t.Fatalf("escalate: %v", err)
+	}
+	if len(notifier.sent) != 1 {
+		t.Fatalf("expected one notification, got %d", len(notifier.sent))
+	}
+	got := notifier.sent[0]
+	if got.Channel != "#escalations" || got.Title != "SLA breached: Outage" || got.Link != "https://iboz.example/messages/in-1" {
+		t.Fatalf("unexpected notification: %+v", got)
+	}
+}
@@ -0,0 +1,86 @@
+package memory
+
+import (
+	"context"
+	"sync"
+
+	"github.com/example/iboz/internal/snooze"
+)
+
+var _ snooze.Repository = (*Repository)(nil)
+
+// Repository provides an in-memory implementation of the snooze.Repository port.
+type Repository struct {
+	mu      sync.RWMutex
+	snoozes map[string]snooze.Snooze
+}
+
+// NewRepository builds a new in-memory snooze repository.
+func NewRepository() *Repository {
+	return &Repository{snoozes: make(map[string]snooze.Snooze)}
+}
+
+// Save inserts or replaces the snooze for its message.
+func (r *Repository) Save(ctx context.Context, s snooze.Snooze) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	r.snoozes[s.MessageID] = clone(s)
+	r.mu.Unlock()
+	return nil
+}
+
+// Get returns the snooze for messageID if present.
+func (r *Repository) Get(ctx context.Context, messageID string) (*snooze.Snooze, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	s, ok := r.snoozes[messageID]
+	if !ok {
+		return nil, nil
+	}
+	cloned := clone(s)
+	return &cloned, nil
+}
+
+// List returns every stored snooze.
+func (r *Repository) List(ctx context.Context) ([]snooze.Snooze, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	list := make([]snooze.Snooze, 0, len(r.snoozes))
+	for _, s := range r.snoozes {
+		list = append(list, clone(s))
+	}
+	return list, nil
+}
+
+// Delete removes the snooze for messageID.
+func (r *Repository) Delete(ctx context.Context, messageID string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	delete(r.snoozes, messageID)
+	r.mu.Unlock()
+	return nil
+}
+
+func clone(s snooze.Snooze) snooze.Snooze {
+	if s.ResurfacedAt != nil {
+		at := *s.ResurfacedAt
+		s.ResurfacedAt = &at
+	}
+	return s
+}
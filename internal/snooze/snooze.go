@@ -0,0 +1,338 @@
+package snooze
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/example/iboz/internal/calendar"
+	"github.com/example/iboz/internal/email"
+)
+
+// PresetNextBusinessMorning snoozes until the next opening of the user's working hours.
+const PresetNextBusinessMorning = "next-business-morning"
+
+// inboxLabel is restored when a relabelled message resurfaces.
+const inboxLabel = "INBOX"
+
+var (
+	// ErrSnoozeNotFound is returned when a message has no snooze.
+	ErrSnoozeNotFound = errors.New("snooze not found")
+	// ErrInvalidSnooze is returned when a snooze request cannot be resolved to a future time.
+	ErrInvalidSnooze = errors.New("invalid snooze request")
+)
+
+// Snooze hides a message from queues until Until.
+type Snooze struct {
+	MessageID    string     `json:"messageId"`
+	Until        time.Time  `json:"until"`
+	Label        string     `json:"label,omitempty"`
+	CreatedAt    time.Time  `json:"createdAt"`
+	ResurfacedAt *time.Time `json:"resurfacedAt,omitempty"`
+}
+
+// Active reports whether the snooze still hides its message at now.
+func (s Snooze) Active(now time.Time) bool {
+	return s.ResurfacedAt == nil && now.Before(s.Until)
+}
+
+// Request describes when a message should resurface. Exactly one of Until,
+// Duration or Preset must be set; Timezone overrides the calendar's timezone for presets.
+type Request struct {
+	MessageID string
+	Until     time.Time
+	Duration  time.Duration
+	Preset    string
+	Timezone  string
+	Label     string
+}
+
+// Repository defines the persistence contract for snoozes.
+type Repository interface {
+	Save(ctx context.Context, snooze Snooze) error
+	Get(ctx context.Context, messageID string) (*Snooze, error)
+	List(ctx context.Context) ([]Snooze, error)
+	Delete(ctx context.Context, messageID string) error
+}
+
+// Labeler applies label changes at the provider. It is optional.
+type Labeler interface {
+	Relabel(ctx context.Context, messageID string, add, remove []string) error
+}
+
+// SnoozeService exposes snooze management.
+type SnoozeService interface {
+	Snooze(ctx context.Context, req Request) (Snooze, error)
+	Cancel(ctx context.Context, messageID string) error
+	List(ctx context.Context) ([]Snooze, error)
+	Visible(ctx context.Context, messages []email.EmailMessage) ([]email.EmailMessage, error)
+	Resurface(ctx context.Context) ([]Snooze, error)
+}
+
+var (
+	_ SnoozeService    = (*Service)(nil)
+	_ email.Classifier = (*Service)(nil)
+)
+
+// Service manages snoozed messages and resurfaces them when due.
+type Service struct {
+	repo      Repository
+	messages  email.Repository
+	labeler   Labeler
+	calendars calendar.Provider
+	clock     email.Clock
+}
+
+// NewService constructs a snooze Service. labeler may be nil to skip provider relabelling.
+func NewService(repo Repository, messages email.Repository, labeler Labeler, calendars calendar.Provider, clock email.Clock) *Service {
+	if repo == nil {
+		panic("snooze: repository dependency is required")
+	}
+	if messages == nil {
+		panic("snooze: message repository dependency is required")
+	}
+	if calendars == nil {
+		panic("snooze: calendar dependency is required")
+	}
+	if clock == nil {
+		panic("snooze: clock dependency is required")
+	}
+	return &Service{repo: repo, messages: messages, labeler: labeler, calendars: calendars, clock: clock}
+}
+
+// Snooze hides the message until the requested time, replacing any previous snooze.
+func (s *Service) Snooze(ctx context.Context, req Request) (Snooze, error) {
+	if err := ctx.Err(); err != nil {
+		return Snooze{}, err
+	}
+	if _, err := email.FindMessage(ctx, s.messages, req.MessageID); err != nil {
+		return Snooze{}, err
+	}
+
+	now := s.clock.Now().UTC()
+	until, err := s.resolveUntil(ctx, req, now)
+	if err != nil {
+		return Snooze{}, err
+	}
+
+	snooze := Snooze{
+		MessageID: req.MessageID,
+		Until:     until,
+		Label:     strings.TrimSpace(req.Label),
+		CreatedAt: now,
+	}
+
+	if snooze.Label != "" && s.labeler != nil {
+		if err := s.labeler.Relabel(ctx, snooze.MessageID, []string{snooze.Label}, []string{inboxLabel}); err != nil {
+			return Snooze{}, fmt.Errorf("relabel snoozed message: %w", err)
+		}
+	}
+	if err := s.repo.Save(ctx, snooze); err != nil {
+		return Snooze{}, err
+	}
+	return snooze, nil
+}
+
+// Cancel resurfaces a snoozed message immediately and forgets the snooze.
+func (s *Service) Cancel(ctx context.Context, messageID string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	snooze, err := s.repo.Get(ctx, messageID)
+	if err != nil {
+		return err
+	}
+	if snooze == nil {
+		return ErrSnoozeNotFound
+	}
+	if snooze.ResurfacedAt == nil {
+		if err := s.restoreLabels(ctx, *snooze); err != nil {
+			return err
+		}
+	}
+	return s.repo.Delete(ctx, messageID)
+}
+
+// List returns every snooze ordered by resurface time.
+func (s *Service) List(ctx context.Context) ([]Snooze, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	list, err := s.repo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Until.Before(list[j].Until) })
+	return list, nil
+}
+
+// Visible filters out messages that are currently snoozed.
+func (s *Service) Visible(ctx context.Context, messages []email.EmailMessage) ([]email.EmailMessage, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	list, err := s.repo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.clock.Now().UTC()
+	hidden := make(map[string]struct{}, len(list))
+	for _, snooze := range list {
+		if snooze.Active(now) {
+			hidden[snooze.MessageID] = struct{}{}
+		}
+	}
+	if len(hidden) == 0 {
+		return messages, nil
+	}
+
+	visible := make([]email.EmailMessage, 0, len(messages))
+	for _, message := range messages {
+		if _, ok := hidden[message.ID]; !ok {
+			visible = append(visible, message)
+		}
+	}
+	return visible, nil
+}
+
+// Resurface marks every due snooze as resurfaced and returns them. A snooze
+// that fails is left due for the next run without holding up the others, and
+// the failures are returned joined.
+func (s *Service) Resurface(ctx context.Context) ([]Snooze, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	list, err := s.repo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.clock.Now().UTC()
+	var due []Snooze
+	var errs []error
+	for _, snooze := range list {
+		if snooze.ResurfacedAt != nil || now.Before(snooze.Until) {
+			continue
+		}
+		if err := s.restoreLabels(ctx, snooze); err != nil {
+			errs = append(errs, fmt.Errorf("resurface %s: %w", snooze.MessageID, err))
+			continue
+		}
+		resurfacedAt := now
+		snooze.ResurfacedAt = &resurfacedAt
+		if err := s.repo.Save(ctx, snooze); err != nil {
+			errs = append(errs, fmt.Errorf("resurface %s: %w", snooze.MessageID, err))
+			continue
+		}
+		due = append(due, snooze)
+	}
+	return due, errors.Join(errs...)
+}
+
+// Classify implements email.Classifier. A sync returns messages with the labels
+// the provider holds, so messages under an active labelled snooze get the
+// snooze label back and leave INBOX again until they resurface.
+func (s *Service) Classify(ctx context.Context, messages []email.EmailMessage) ([]email.EmailMessage, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	list, err := s.repo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.clock.Now().UTC()
+	labels := make(map[string]string, len(list))
+	for _, snooze := range list {
+		if snooze.Label != "" && snooze.Active(now) {
+			labels[snooze.MessageID] = snooze.Label
+		}
+	}
+	if len(labels) == 0 {
+		return messages, nil
+	}
+
+	for i, message := range messages {
+		label, ok := labels[message.ID]
+		if !ok {
+			continue
+		}
+		relabelled := make([]string, 0, len(message.Labels)+1)
+		for _, existing := range message.Labels {
+			if existing != inboxLabel && existing != label {
+				relabelled = append(relabelled, existing)
+			}
+		}
+		messages[i].Labels = append(relabelled, label)
+	}
+	return messages, nil
+}
+
+func (s *Service) restoreLabels(ctx context.Context, snooze Snooze) error {
+	if snooze.Label == "" || s.labeler == nil {
+		return nil
+	}
+	err := s.labeler.Relabel(ctx, snooze.MessageID, []string{inboxLabel}, []string{snooze.Label})
+	if err != nil && !errors.Is(err, email.ErrMessageNotFound) {
+		return fmt.Errorf("relabel resurfaced message: %w", err)
+	}
+	return nil
+}
+
+func (s *Service) resolveUntil(ctx context.Context, req Request, now time.Time) (time.Time, error) {
+	set := 0
+	if !req.Until.IsZero() {
+		set++
+	}
+	if req.Duration != 0 {
+		set++
+	}
+	if req.Preset != "" {
+		set++
+	}
+	if set != 1 {
+		return time.Time{}, fmt.Errorf("%w: exactly one of until, duration or preset is required", ErrInvalidSnooze)
+	}
+
+	var until time.Time
+	switch {
+	case !req.Until.IsZero():
+		until = req.Until.UTC()
+	case req.Duration != 0:
+		until = now.Add(req.Duration)
+	default:
+		if req.Preset != PresetNextBusinessMorning {
+			return time.Time{}, fmt.Errorf("%w: unsupported preset %q", ErrInvalidSnooze, req.Preset)
+		}
+		cal, err := s.calendar(ctx, req.Timezone)
+		if err != nil {
+			return time.Time{}, err
+		}
+		until = cal.NextOpening(now)
+	}
+
+	if !until.After(now) {
+		return time.Time{}, fmt.Errorf("%w: snooze time must be in the future", ErrInvalidSnooze)
+	}
+	return until, nil
+}
+
+// calendar returns the user's calendar, evaluated in timezone when one is given.
+func (s *Service) calendar(ctx context.Context, timezone string) (*calendar.Calendar, error) {
+	cal, err := s.calendars.Calendar(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(timezone) == "" {
+		return cal, nil
+	}
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		return nil, fmt.Errorf("%w: unknown timezone %q", ErrInvalidSnooze, timezone)
+	}
+	return cal.In(loc), nil
+}
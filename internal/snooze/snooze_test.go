@@ -0,0 +1,273 @@
+package snooze_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/example/iboz/internal/calendar"
+	"github.com/example/iboz/internal/email"
+	emailmemory "github.com/example/iboz/internal/email/adapter/memory"
+	"github.com/example/iboz/internal/snooze"
+	"github.com/example/iboz/internal/snooze/adapter/memory"
+	"github.com/example/iboz/internal/testutil"
+)
+
+func seedMessages(t *testing.T, repo email.Repository, now time.Time) {
+	t.Helper()
+	messages := []email.EmailMessage{
+		{ID: "msg-1", Subject: "Newsletter", Labels: []string{"INBOX"}},
+		{ID: "msg-2", Subject: "Invoice", Labels: []string{"INBOX"}},
+	}
+	if err := repo.SaveMessages(context.Background(), messages, now); err != nil {
+		t.Fatalf("seed messages: %v", err)
+	}
+}
+
+func newCalendar(t *testing.T, hours calendar.Hours, clock email.Clock) *calendar.Calendar {
+	t.Helper()
+	cal, err := calendar.New(hours, clock)
+	if err != nil {
+		t.Fatalf("calendar: %v", err)
+	}
+	return cal
+}
+
+func TestNextBusinessMorningPresetFollowsCalendar(t *testing.T) {
+	ctx := context.Background()
+	// Friday 18:00 in Berlin; Monday is a holiday.
+	clock := testutil.NewClock(time.Date(2025, time.March, 21, 17, 0, 0, 0, time.UTC))
+	messages := emailmemory.NewRepository()
+	seedMessages(t, messages, clock.Now())
+	cal := newCalendar(t, calendar.Hours{Timezone: "Europe/Berlin", Start: "08:00", End: "17:00", Holidays: []string{"2025-03-24"}}, clock)
+	svc := snooze.NewService(memory.NewRepository(), messages, nil, cal, clock)
+
+	created, err := svc.Snooze(ctx, snooze.Request{MessageID: "msg-1", Preset: snooze.PresetNextBusinessMorning})
+	if err != nil {
+		t.Fatalf("snooze: %v", err)
+	}
+	want := time.Date(2025, time.March, 25, 7, 0, 0, 0, time.UTC)
+	if !created.Until.Equal(want) {
+		t.Fatalf("expected %s, got %s", want, created.Until)
+	}
+
+	created, err = svc.Snooze(ctx, snooze.Request{MessageID: "msg-2", Preset: snooze.PresetNextBusinessMorning, Timezone: "America/New_York"})
+	if err != nil {
+		t.Fatalf("snooze: %v", err)
+	}
+	// Friday 13:00 in New York resurfaces at Tuesday 08:00 local.
+	want = time.Date(2025, time.March, 25, 12, 0, 0, 0, time.UTC)
+	if !created.Until.Equal(want) {
+		t.Fatalf("expected %s with timezone override, got %s", want, created.Until)
+	}
+}
+
+// failingLabeler fails to move the messages in failures back to INBOX.
+type failingLabeler struct {
+	snooze.Labeler
+	failures map[string]error
+}
+
+func (f failingLabeler) Relabel(ctx context.Context, messageID string, add, remove []string) error {
+	if err := f.failures[messageID]; err != nil && len(add) == 1 && add[0] == "INBOX" {
+		return err
+	}
+	return f.Labeler.Relabel(ctx, messageID, add, remove)
+}
+
+func TestResurfaceContinuesPastFailures(t *testing.T) {
+	ctx := context.Background()
+	clock := testutil.NewClock(time.Date(2025, time.March, 18, 12, 0, 0, 0, time.UTC))
+	messages := emailmemory.NewRepository()
+	seedMessages(t, messages, clock.Now())
+	errProvider := errors.New("provider unavailable")
+	labeler := failingLabeler{Labeler: messages, failures: map[string]error{
+		"msg-1": errProvider,
+		"msg-2": email.ErrMessageNotFound,
+	}}
+	svc := snooze.NewService(memory.NewRepository(), messages, labeler, newCalendar(t, calendar.DefaultHours(), clock), clock)
+	for _, id := range []string{"msg-1", "msg-2"} {
+		if _, err := svc.Snooze(ctx, snooze.Request{MessageID: id, Duration: time.Hour, Label: "Snoozed"}); err != nil {
+			t.Fatalf("snooze %s: %v", id, err)
+		}
+	}
+
+	clock.Advance(2 * time.Hour)
+	due, err := svc.Resurface(ctx)
+	if !errors.Is(err, errProvider) {
+		t.Fatalf("expected the relabel failure, got %v", err)
+	}
+	if len(due) != 1 || due[0].MessageID != "msg-2" {
+		t.Fatalf("expected the deleted message to resurface past the failure, got %+v", due)
+	}
+
+	delete(labeler.failures, "msg-1")
+	due, err = svc.Resurface(ctx)
+	if err != nil || len(due) != 1 || due[0].MessageID != "msg-1" {
+		t.Fatalf("expected the failed snooze to resurface on the next run, got %+v (%v)", due, err)
+	}
+}
+
+func TestSnoozeHidesAndResurfaces(t *testing.T) {
+	ctx := context.Background()
+	clock := testutil.NewClock(time.Date(2025, time.March, 18, 12, 0, 0, 0, time.UTC))
+	messages := emailmemory.NewRepository()
+	seedMessages(t, messages, clock.Now())
+	svc := snooze.NewService(memory.NewRepository(), messages, messages, newCalendar(t, calendar.DefaultHours(), clock), clock)
+
+	if _, err := svc.Snooze(ctx, snooze.Request{MessageID: "missing", Duration: time.Hour}); !errors.Is(err, email.ErrMessageNotFound) {
+		t.Fatalf("expected message not found, got %v", err)
+	}
+	if _, err := svc.Snooze(ctx, snooze.Request{MessageID: "msg-1"}); !errors.Is(err, snooze.ErrInvalidSnooze) {
+		t.Fatalf("expected invalid snooze without a time, got %v", err)
+	}
+	if _, err := svc.Snooze(ctx, snooze.Request{MessageID: "msg-1", Until: clock.Now().Add(-time.Minute)}); !errors.Is(err, snooze.ErrInvalidSnooze) {
+		t.Fatalf("expected invalid snooze for past time, got %v", err)
+	}
+
+	created, err := svc.Snooze(ctx, snooze.Request{MessageID: "msg-1", Duration: 2 * time.Hour, Label: "Snoozed"})
+	if err != nil {
+		t.Fatalf("snooze: %v", err)
+	}
+	if !created.Until.Equal(clock.Now().Add(2 * time.Hour)) {
+		t.Fatalf("unexpected until: %s", created.Until)
+	}
+
+	cached, _, _ := messages.GetMessages(ctx)
+	if labels := cached[0].Labels; len(labels) != 1 || labels[0] != "Snoozed" {
+		t.Fatalf("expected message relabelled as snoozed, got %v", labels)
+	}
+
+	visible, err := svc.Visible(ctx, cached)
+	if err != nil {
+		t.Fatalf("visible: %v", err)
+	}
+	if len(visible) != 1 || visible[0].ID != "msg-2" {
+		t.Fatalf("expected only msg-2 visible, got %+v", visible)
+	}
+
+	due, err := svc.Resurface(ctx)
+	if err != nil || len(due) != 0 {
+		t.Fatalf("nothing should resurface yet: %v %v", due, err)
+	}
+
+	clock.Advance(2 * time.Hour)
+	due, err = svc.Resurface(ctx)
+	if err != nil {
+		t.Fatalf("resurface: %v", err)
+	}
+	if len(due) != 1 || due[0].ResurfacedAt == nil {
+		t.Fatalf("expected one resurfaced snooze, got %+v", due)
+	}
+
+	cached, _, _ = messages.GetMessages(ctx)
+	if labels := cached[0].Labels; len(labels) != 1 || labels[0] != "INBOX" {
+		t.Fatalf("expected inbox label restored, got %v", labels)
+	}
+	visible, _ = svc.Visible(ctx, cached)
+	if len(visible) != 2 {
+		t.Fatalf("expected both messages visible after resurfacing, got %d", len(visible))
+	}
+
+	due, _ = svc.Resurface(ctx)
+	if len(due) != 0 {
+		t.Fatalf("snooze should resurface only once")
+	}
+}
+
+func TestCancelSnooze(t *testing.T) {
+	ctx := context.Background()
+	clock := testutil.NewClock(time.Date(2025, time.March, 18, 12, 0, 0, 0, time.UTC))
+	messages := emailmemory.NewRepository()
+	seedMessages(t, messages, clock.Now())
+	svc := snooze.NewService(memory.NewRepository(), messages, nil, newCalendar(t, calendar.DefaultHours(), clock), clock)
+
+	if err := svc.Cancel(ctx, "msg-2"); !errors.Is(err, snooze.ErrSnoozeNotFound) {
+		t.Fatalf("expected not found, got %v", err)
+	}
+	if _, err := svc.Snooze(ctx, snooze.Request{MessageID: "msg-2", Preset: snooze.PresetNextBusinessMorning, Timezone: "Mars/Olympus"}); !errors.Is(err, snooze.ErrInvalidSnooze) {
+		t.Fatalf("expected invalid timezone error, got %v", err)
+	}
+	if _, err := svc.Snooze(ctx, snooze.Request{MessageID: "msg-2", Preset: snooze.PresetNextBusinessMorning}); err != nil {
+		t.Fatalf("snooze: %v", err)
+	}
+	if err := svc.Cancel(ctx, "msg-2"); err != nil {
+		t.Fatalf("cancel: %v", err)
+	}
+	list, _ := svc.List(ctx)
+	if len(list) != 0 {
+		t.Fatalf("expected no snoozes after cancel, got %v", list)
+	}
+}
+
+// provider returns the same messages, labelled as the provider holds them, on
+// every sync.
+type provider struct {
+	messages []email.EmailMessage
+}
+
+func (p provider) Generate(context.Context, email.ProviderConfig, email.AuthState, time.Time) ([]email.EmailMessage, error) {
+	messages := make([]email.EmailMessage, len(p.messages))
+	for i, message := range p.messages {
+		messages[i] = message
+		messages[i].Labels = append([]string(nil), message.Labels...)
+	}
+	return messages, nil
+}
+
+func TestSnoozeLabelsSurviveResync(t *testing.T) {
+	ctx := context.Background()
+	clock := testutil.NewClock(time.Date(2025, time.March, 18, 12, 0, 0, 0, time.UTC))
+	messages := emailmemory.NewRepository()
+	generator := provider{messages: []email.EmailMessage{
+		{ID: "msg-1", Subject: "Newsletter", Labels: []string{"INBOX"}},
+		{ID: "msg-2", Subject: "Invoice", Labels: []string{"INBOX"}},
+	}}
+	mail := email.NewService(messages, email.NewSHA256Hasher(), emailmemory.NewVault(), generator, clock)
+	svc := snooze.NewService(memory.NewRepository(), messages, messages, newCalendar(t, calendar.DefaultHours(), clock), clock)
+	mail.ClassifyWith(svc)
+	if err := mail.ConfigureProvider(ctx, email.ProviderConfig{
+		Provider:    email.ProviderGmail,
+		DisplayName: "Me",
+		Connection:  email.ConnectionSettings{Protocol: email.ProtocolAPI},
+	}); err != nil {
+		t.Fatalf("configure provider: %v", err)
+	}
+	if _, err := mail.Authenticate(ctx, email.AuthRequest{Method: email.AuthMethodOAuth, Username: "me@example.com", Secret: "abcdefghi"}); err != nil {
+		t.Fatalf("authenticate: %v", err)
+	}
+	if _, err := mail.FetchEmails(ctx); err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+
+	if _, err := svc.Snooze(ctx, snooze.Request{MessageID: "msg-1", Duration: time.Hour, Label: "Snoozed"}); err != nil {
+		t.Fatalf("snooze: %v", err)
+	}
+	labels := func() []string {
+		t.Helper()
+		message, err := email.FindMessage(ctx, messages, "msg-1")
+		if err != nil {
+			t.Fatalf("find message: %v", err)
+		}
+		return message.Labels
+	}
+
+	if _, err := mail.FetchEmails(ctx); err != nil {
+		t.Fatalf("resync: %v", err)
+	}
+	if got := labels(); len(got) != 1 || got[0] != "Snoozed" {
+		t.Fatalf("expected the snooze label to survive a resync, got %v", got)
+	}
+
+	clock.Advance(2 * time.Hour)
+	if _, err := svc.Resurface(ctx); err != nil {
+		t.Fatalf("resurface: %v", err)
+	}
+	if _, err := mail.FetchEmails(ctx); err != nil {
+		t.Fatalf("resync: %v", err)
+	}
+	if got := labels(); len(got) != 1 || got[0] != "INBOX" {
+		t.Fatalf("expected the resurfaced message back in INBOX, got %v", got)
+	}
+}
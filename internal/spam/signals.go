@@ -0,0 +1,241 @@
+package spam
+
+import (
+	"fmt"
+	"net"
+	"net/mail"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+
+	"golang.org/x/net/publicsuffix"
+
+	"github.com/example/iboz/internal/email"
+)
+
+// shorteners hide the destination of a link.
+var shorteners = map[string]bool{
+	"bit.ly": true, "buff.ly": true, "cutt.ly": true, "goo.gl": true, "is.gd": true,
+	"ow.ly": true, "rebrand.ly": true, "t.co": true, "tinyurl.com": true,
+}
+
+// providers are well-known mail providers. They are registered by their own
+// operators, so one is never a lookalike of another however close the names.
+var providers = map[string]bool{
+	"aol.com": true, "fastmail.com": true, "gmail.com": true, "gmx.com": true, "gmx.de": true,
+	"gmx.net": true, "googlemail.com": true, "hey.com": true, "hotmail.com": true, "icloud.com": true,
+	"live.com": true, "mac.com": true, "mail.com": true, "mail.ru": true, "me.com": true,
+	"msn.com": true, "outlook.com": true, "proton.me": true, "protonmail.com": true, "web.de": true,
+	"yahoo.com": true, "yandex.com": true, "yandex.ru": true, "ymail.com": true, "zoho.com": true,
+}
+
+var (
+	linkPattern    = regexp.MustCompile(`(?i)https?://[^\s<>"'()]+`)
+	addressPattern = regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)
+)
+
+// authenticationReasons reads the SPF, DKIM and DMARC results of the topmost
+// Authentication-Results header, the one added by the receiving server.
+func authenticationReasons(headers email.Header) []email.SpamReason {
+	verdicts := headers.Authentication()
+
+	var reasons []email.SpamReason
+	if verdicts["dmarc"] == "fail" {
+		reasons = append(reasons, email.SpamReason{Signal: "dmarc", Points: dmarcFailPoints, Detail: "DMARC check failed"})
+	}
+	switch verdicts["spf"] {
+	case "fail":
+		reasons = append(reasons, email.SpamReason{Signal: "spf", Points: spfFailPoints, Detail: "SPF check failed"})
+	case "softfail":
+		reasons = append(reasons, email.SpamReason{Signal: "spf", Points: spfSoftFailPoints, Detail: "SPF check soft-failed"})
+	}
+	if verdicts["dkim"] == "fail" {
+		reasons = append(reasons, email.SpamReason{Signal: "dkim", Points: dkimFailPoints, Detail: "DKIM signature failed"})
+	}
+	return reasons
+}
+
+// senderReasons flags display names borrowed from contacts, addresses posing
+// as display names, lookalikes of known domains and replies redirected to an
+// unknown domain.
+func (c *contacts) senderReasons(message email.EmailMessage) []email.SpamReason {
+	parsed, err := mail.ParseAddress(message.Sender)
+	if err != nil {
+		return nil
+	}
+	address := strings.ToLower(parsed.Address)
+	domain := message.SenderDomain()
+
+	var reasons []email.SpamReason
+	if name := normalizeName(parsed.Name); name != "" {
+		if addresses := c.names[name]; len(addresses) > 0 && !addresses[address] {
+			reasons = append(reasons, email.SpamReason{
+				Signal: "display-name",
+				Points: spoofedNamePoints,
+				Detail: fmt.Sprintf("display name %q belongs to a contact but was sent from %s", parsed.Name, address),
+			})
+		} else if embedded := addressPattern.FindString(parsed.Name); embedded != "" && !strings.EqualFold(embedded, address) {
+			reasons = append(reasons, email.SpamReason{
+				Signal: "display-name",
+				Points: embeddedAddrPoints,
+				Detail: fmt.Sprintf("display name shows %s but was sent from %s", embedded, address),
+			})
+		}
+	}
+	reasons = append(reasons, c.domainReasons("sender", domain)...)
+
+	if replyTo, err := mail.ParseAddress(message.Headers.Get("Reply-To")); err == nil {
+		replyDomain := domainOf(replyTo.Address)
+		if replyDomain != "" && replyDomain != domain && !c.domains[replyDomain] {
+			reasons = append(reasons, email.SpamReason{
+				Signal: "reply-to",
+				Points: replyToPoints,
+				Detail: fmt.Sprintf("replies go to %s rather than %s", replyDomain, domain),
+			})
+		}
+	}
+	return reasons
+}
+
+// linkReasons flags the links of text that hide or disguise their destination.
+// Each kind of problem is counted once.
+func (c *contacts) linkReasons(text string) []email.SpamReason {
+	var reasons []email.SpamReason
+	seen := make(map[string]bool)
+	flag := func(reason email.SpamReason) {
+		if !seen[reason.Signal] {
+			seen[reason.Signal] = true
+			reasons = append(reasons, reason)
+		}
+	}
+	for _, link := range linkPattern.FindAllString(text, -1) {
+		link = strings.TrimRight(link, ".,;:!?")
+		parsed, err := url.Parse(link)
+		if err != nil || parsed.Hostname() == "" {
+			continue
+		}
+		host := strings.ToLower(parsed.Hostname())
+		switch {
+		case net.ParseIP(host) != nil:
+			flag(email.SpamReason{Signal: "link-ip", Points: ipLinkPoints, Detail: fmt.Sprintf("link points to the address %s", host)})
+		case parsed.User != nil:
+			flag(email.SpamReason{Signal: "link-userinfo", Points: userinfoLinkPoints, Detail: fmt.Sprintf("link disguises its host %s", host)})
+		case shorteners[strings.TrimPrefix(host, "www.")]:
+			flag(email.SpamReason{Signal: "link-shortener", Points: shortLinkPoints, Detail: fmt.Sprintf("link is shortened with %s", host)})
+		}
+		for _, reason := range c.domainReasons("link", host) {
+			flag(reason)
+		}
+	}
+	return reasons
+}
+
+// domainReasons flags internationalised domains and domains imitating a known
+// one, by spelling or by embedding it as a subdomain.
+func (c *contacts) domainReasons(kind, domain string) []email.SpamReason {
+	domain = strings.TrimPrefix(strings.TrimSuffix(domain, "."), "www.")
+	if domain == "" {
+		return nil
+	}
+	known := make([]string, 0, len(c.domains))
+	for candidate := range c.domains {
+		if domain == candidate || strings.HasSuffix(domain, "."+candidate) {
+			return nil
+		}
+		known = append(known, candidate)
+	}
+	sort.Strings(known)
+
+	var reasons []email.SpamReason
+	if strings.HasPrefix(domain, "xn--") || strings.Contains(domain, ".xn--") {
+		reasons = append(reasons, email.SpamReason{
+			Signal: kind + "-punycode",
+			Points: punycodePoints,
+			Detail: fmt.Sprintf("%s domain %s uses international characters", kind, domain),
+		})
+	}
+	registered := registrable(domain)
+	for _, candidate := range known {
+		if embeds(domain, registered, candidate) || (!providers[registered] && lookalike(registered, registrable(candidate))) {
+			reasons = append(reasons, email.SpamReason{
+				Signal: kind + "-lookalike",
+				Points: lookalikePoints,
+				Detail: fmt.Sprintf("%s domain %s imitates %s", kind, domain, candidate),
+			})
+			break
+		}
+	}
+	return reasons
+}
+
+// registrable returns the domain its owner registered, such as example.co.uk
+// for mail.example.co.uk, or domain itself when it has no public suffix.
+func registrable(domain string) string {
+	if registered, err := publicsuffix.EffectiveTLDPlusOne(domain); err == nil {
+		return registered
+	}
+	return domain
+}
+
+// embeds reports whether known appears among the subdomains of registered, as
+// in acme.com.evil.net. A domain that merely extends known with a public
+// suffix, such as acme.com.au, is a registration of its own.
+func embeds(domain, registered, known string) bool {
+	return strings.HasPrefix(domain, known+".") && len(known) < len(domain)-len(registered)
+}
+
+// homoglyphs maps characters and pairs commonly swapped for lookalikes to the
+// character they imitate.
+var homoglyphs = strings.NewReplacer("rn", "m", "vv", "w", "0", "o", "1", "l", "i", "l", "3", "e", "5", "s")
+
+// lookalike reports whether domain differs from known only by homoglyphs or by
+// a single edit, such as a swapped, missing or extra character.
+func lookalike(domain, known string) bool {
+	if domain == known || len(known) < 6 {
+		return false
+	}
+	if homoglyphs.Replace(domain) == homoglyphs.Replace(known) {
+		return true
+	}
+	return editDistance(domain, known) <= 1
+}
+
+// editDistance returns the optimal string alignment distance of a and b,
+// counting insertions, deletions, substitutions and adjacent transpositions.
+// Strings whose lengths differ by more than one are reported as 2 apart, which
+// is all lookalike needs.
+func editDistance(a, b string) int {
+	if diff := len(a) - len(b); diff > 1 || diff < -1 {
+		return 2
+	}
+	rows := make([][]int, len(a)+1)
+	for i := range rows {
+		rows[i] = make([]int, len(b)+1)
+		rows[i][0] = i
+	}
+	for j := range rows[0] {
+		rows[0][j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			rows[i][j] = min(rows[i-1][j]+1, rows[i][j-1]+1, rows[i-1][j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				rows[i][j] = min(rows[i][j], rows[i-2][j-2]+1)
+			}
+		}
+	}
+	return rows[len(a)][len(b)]
+}
+
+func domainOf(address string) string {
+	at := strings.LastIndex(address, "@")
+	if at < 0 {
+		return ""
+	}
+	return strings.ToLower(address[at+1:])
+}
@@ -0,0 +1,196 @@
+// Package spam scores messages for spam and phishing locally, from their
+// authentication results, their sender compared with the mailbox's contacts,
+// and the links they carry.
+package spam
+
+import (
+	"context"
+	"fmt"
+	"net/mail"
+	"strings"
+
+	"github.com/example/iboz/internal/email"
+)
+
+const (
+	// Category is set on messages scoring at least the threshold.
+	Category = "spam"
+	// DefaultThreshold is the score from which a message is filed as spam.
+	DefaultThreshold = 50
+	// MaxScore caps the score of a message.
+	MaxScore = 100
+)
+
+// Points added by each signal.
+const (
+	dmarcFailPoints    = 35
+	spfFailPoints      = 20
+	spfSoftFailPoints  = 10
+	dkimFailPoints     = 15
+	spoofedNamePoints  = 40
+	embeddedAddrPoints = 30
+	lookalikePoints    = 40
+	punycodePoints     = 20
+	replyToPoints      = 15
+	ipLinkPoints       = 25
+	userinfoLinkPoints = 25
+	shortLinkPoints    = 10
+)
+
+var _ email.Classifier = (*Scorer)(nil)
+
+// Config tunes the Scorer. Zero values select the defaults.
+type Config struct {
+	// Threshold is the score from which a message is filed as spam.
+	Threshold int
+}
+
+// Scorer implements email.Classifier, storing a score and its reasons on every
+// received message and filing the messages scoring at least the threshold as
+// Category. Contacts are the mailbox, the addresses it has written to and the
+// senders of stored messages whose score carries no distrust signal, so a
+// message that slipped under the threshold does not vouch for its sender.
+type Scorer struct {
+	messages email.Repository
+	cfg      Config
+}
+
+// distrust lists the signals that keep a sender out of the contacts.
+var distrust = map[string]bool{
+	"dmarc":            true,
+	"spf":              true,
+	"dkim":             true,
+	"display-name":     true,
+	"sender-lookalike": true,
+	"sender-punycode":  true,
+	"link-lookalike":   true,
+}
+
+// NewScorer constructs a Scorer reading contacts from messages.
+func NewScorer(messages email.Repository, cfg Config) *Scorer {
+	if messages == nil {
+		panic("spam: email repository dependency is required")
+	}
+	if cfg.Threshold < 0 || cfg.Threshold > MaxScore {
+		panic(fmt.Sprintf("spam: threshold must be between 0 and %d", MaxScore))
+	}
+	if cfg.Threshold == 0 {
+		cfg.Threshold = DefaultThreshold
+	}
+	return &Scorer{messages: messages, cfg: cfg}
+}
+
+// Classify implements email.Classifier. The contacts are read once per sync,
+// from the messages stored by the previous one. Messages sent from the mailbox
+// are not scored.
+func (s *Scorer) Classify(ctx context.Context, messages []email.EmailMessage) ([]email.EmailMessage, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	known, err := s.contacts(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i, message := range messages {
+		if !message.SentBy(known.owner) {
+			messages[i] = s.score(known, message)
+		}
+	}
+	return messages, nil
+}
+
+// score stores the score of message and files it as spam at the threshold.
+func (s *Scorer) score(known *contacts, message email.EmailMessage) email.EmailMessage {
+	reasons := []email.SpamReason{}
+	reasons = append(reasons, authenticationReasons(message.Headers)...)
+	reasons = append(reasons, known.senderReasons(message)...)
+	reasons = append(reasons, known.linkReasons(message.Subject+" "+message.Snippet)...)
+
+	score := 0
+	for _, reason := range reasons {
+		score += reason.Points
+	}
+	if score > MaxScore {
+		score = MaxScore
+	}
+	message.Spam = &email.SpamScore{Score: score, Reasons: reasons}
+	if score >= s.cfg.Threshold {
+		message.Category = Category
+	}
+	return message
+}
+
+// contacts indexes the people the mailbox knows.
+func (s *Scorer) contacts(ctx context.Context) (*contacts, error) {
+	known := &contacts{names: make(map[string]map[string]bool), domains: make(map[string]bool)}
+	auth, err := s.messages.GetAuth(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if auth != nil {
+		known.owner = auth.State.Username
+		known.add(known.owner, "")
+	}
+	messages, _, err := s.messages.GetMessages(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, message := range messages {
+		switch {
+		case message.SentBy(known.owner):
+			for _, recipient := range message.Recipients {
+				known.add(recipient, "")
+			}
+		case trusted(message.Spam):
+			known.add(message.Sender, "")
+		}
+	}
+	return known, nil
+}
+
+// trusted reports whether a message was scored without distrust signals.
+// Unscored messages are not trusted.
+func trusted(score *email.SpamScore) bool {
+	if score == nil {
+		return false
+	}
+	for _, reason := range score.Reasons {
+		if distrust[reason.Signal] {
+			return false
+		}
+	}
+	return true
+}
+
+// contacts indexes known addresses by display name and their domains.
+type contacts struct {
+	owner string
+	// names maps lower-cased display names to the addresses using them.
+	names   map[string]map[string]bool
+	domains map[string]bool
+}
+
+// add records a contact; a name is taken from address when it has one.
+func (c *contacts) add(address, name string) {
+	if parsed, err := mail.ParseAddress(address); err == nil {
+		address, name = parsed.Address, parsed.Name
+	}
+	address = strings.ToLower(strings.TrimSpace(address))
+	at := strings.LastIndex(address, "@")
+	if at < 0 {
+		return
+	}
+	c.domains[address[at+1:]] = true
+	name = normalizeName(name)
+	if name == "" {
+		return
+	}
+	if c.names[name] == nil {
+		c.names[name] = make(map[string]bool)
+	}
+	c.names[name][address] = true
+}
+
+func normalizeName(name string) string {
+	return strings.Join(strings.Fields(strings.ToLower(strings.Trim(name, `"' `))), " ")
+}
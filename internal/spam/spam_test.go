@@ -0,0 +1,253 @@
+package spam_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/example/iboz/internal/email"
+	"github.com/example/iboz/internal/email/adapter/memory"
+	"github.com/example/iboz/internal/spam"
+)
+
+var now = time.Date(2025, time.March, 18, 9, 0, 0, 0, time.UTC)
+
+type fixedClock struct{}
+
+func (fixedClock) Now() time.Time {
+	return now
+}
+
+// newScorer returns a Scorer for the mailbox of me@acme-corp.com, which has
+// written to the CFO and received mail from the bank.
+func newScorer(t *testing.T) *spam.Scorer {
+	t.Helper()
+	ctx := context.Background()
+	repo := memory.NewRepository()
+	if err := repo.SaveAuth(ctx, email.AuthRecord{State: email.AuthState{Username: "me@acme-corp.com"}}); err != nil {
+		t.Fatalf("save auth: %v", err)
+	}
+	err := repo.SaveMessages(ctx, []email.EmailMessage{
+		{ID: "sent-1", Sender: "me@acme-corp.com", Labels: []string{email.LabelSent}, Recipients: []string{"dana@acme-corp.com"}},
+		{ID: "in-1", Sender: "Dana Whitfield <dana@acme-corp.com>", Subject: "Budget", Spam: &email.SpamScore{Reasons: []email.SpamReason{}}},
+		{ID: "in-2", Sender: "Northwind Bank <alerts@northwindbank.com>", Subject: "Statement ready", Spam: &email.SpamScore{Reasons: []email.SpamReason{}}},
+	}, now)
+	if err != nil {
+		t.Fatalf("save messages: %v", err)
+	}
+	return spam.NewScorer(repo, spam.Config{})
+}
+
+func classify(t *testing.T, scorer *spam.Scorer, message email.EmailMessage) email.EmailMessage {
+	t.Helper()
+	scored, err := scorer.Classify(context.Background(), []email.EmailMessage{message})
+	if err != nil {
+		t.Fatalf("classify: %v", err)
+	}
+	return scored[0]
+}
+
+func signals(score *email.SpamScore) map[string]int {
+	found := make(map[string]int)
+	for _, reason := range score.Reasons {
+		found[reason.Signal] = reason.Points
+	}
+	return found
+}
+
+func TestScorerFlagsPhishing(t *testing.T) {
+	scorer := newScorer(t)
+	cases := []struct {
+		name    string
+		message email.EmailMessage
+		signals []string
+		spam    bool
+	}{
+		{
+			name: "failed authentication",
+			message: email.EmailMessage{
+				ID:     "msg-1",
+				Sender: "alerts@northwindbank.com",
+				Headers: email.Header{"Authentication-Results": {
+					"mx.acme-corp.com; spf=softfail smtp.mailfrom=northwindbank.com; dkim=fail header.d=northwindbank.com; dmarc=fail (p=reject) header.from=northwindbank.com",
+					"relay.example; spf=pass; dkim=pass; dmarc=pass",
+				}},
+			},
+			signals: []string{"dmarc", "spf", "dkim"},
+			spam:    true,
+		},
+		{
+			name:    "spoofed display name",
+			message: email.EmailMessage{ID: "msg-2", Sender: "Dana Whitfield <dana.whitfield@freemail.example>", Subject: "Urgent wire"},
+			signals: []string{"display-name"},
+		},
+		{
+			name:    "lookalike domain",
+			message: email.EmailMessage{ID: "msg-3", Sender: "Dana Whitfield <dana@acme-c0rp.com>", Subject: "Invoice"},
+			signals: []string{"display-name", "sender-lookalike"},
+			spam:    true,
+		},
+		{
+			name: "suspicious links",
+			message: email.EmailMessage{
+				ID:      "msg-4",
+				Sender:  "support@helpdesk.example",
+				Subject: "Verify your account",
+				Snippet: "Sign in at http://198.51.100.7/login or https://northwindbank.com.secure-login.example/verify.",
+				Headers: email.Header{"Reply-To": {"recovery@elsewhere.example"}},
+			},
+			signals: []string{"link-ip", "link-lookalike", "reply-to"},
+			spam:    true,
+		},
+		{
+			name:    "address as display name",
+			message: email.EmailMessage{ID: "msg-5", Sender: `"alerts@northwindbank.com" <billing@payments.example>`},
+			signals: []string{"display-name"},
+		},
+		{
+			name: "known contact",
+			message: email.EmailMessage{
+				ID:      "msg-6",
+				Sender:  "Dana Whitfield <dana@acme-corp.com>",
+				Subject: "Q2 plan",
+				Snippet: "Draft at https://docs.acme-corp.com/q2.",
+				Headers: email.Header{"Authentication-Results": {"mx.acme-corp.com; spf=pass; dkim=pass; dmarc=pass"}},
+			},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.message.Category = "updates"
+			scored := classify(t, scorer, tc.message)
+			if scored.Spam == nil {
+				t.Fatal("expected a score")
+			}
+			found := signals(scored.Spam)
+			if len(found) != len(tc.signals) {
+				t.Fatalf("expected signals %v, got %+v", tc.signals, scored.Spam.Reasons)
+			}
+			for _, signal := range tc.signals {
+				if _, ok := found[signal]; !ok {
+					t.Fatalf("expected signal %q, got %+v", signal, scored.Spam.Reasons)
+				}
+			}
+			if got := scored.Category == spam.Category; got != tc.spam {
+				t.Fatalf("expected spam %v, got category %q with score %d", tc.spam, scored.Category, scored.Spam.Score)
+			}
+		})
+	}
+}
+
+func TestScorerLeavesUnrelatedRegistrationsAlone(t *testing.T) {
+	ctx := context.Background()
+	repo := memory.NewRepository()
+	if err := repo.SaveAuth(ctx, email.AuthRecord{State: email.AuthState{Username: "me@acme-corp.com"}}); err != nil {
+		t.Fatalf("save auth: %v", err)
+	}
+	err := repo.SaveMessages(ctx, []email.EmailMessage{
+		{ID: "sent-1", Sender: "me@acme-corp.com", Labels: []string{email.LabelSent}, Recipients: []string{"sam@gmail.com"}},
+		{ID: "in-1", Sender: "Orders <orders@globex.com>", Subject: "Order shipped", Spam: &email.SpamScore{Reasons: []email.SpamReason{}}},
+	}, now)
+	if err != nil {
+		t.Fatalf("save messages: %v", err)
+	}
+	scorer := spam.NewScorer(repo, spam.Config{})
+
+	for _, message := range []email.EmailMessage{
+		{ID: "msg-1", Sender: "Lee <lee@mail.com>", Subject: "Lunch"},
+		{ID: "msg-2", Sender: "Kim <kim@gmx.com>", Subject: "Photos"},
+		{ID: "msg-3", Sender: "Sales <sales@globex.com.au>", Subject: "Quote", Snippet: "Details at https://shop.globex.com.au/quote."},
+	} {
+		scored := classify(t, scorer, message)
+		if scored.Spam == nil || len(scored.Spam.Reasons) != 0 {
+			t.Fatalf("expected %s to be clean, got %+v", message.Sender, scored.Spam)
+		}
+	}
+
+	for _, message := range []email.EmailMessage{
+		{ID: "msg-4", Sender: "Sam <sam@gmall.com>", Subject: "New number"},
+		{ID: "msg-5", Sender: "Orders <orders@globex.com.shipping.example>", Subject: "Order held"},
+	} {
+		scored := classify(t, scorer, message)
+		if _, ok := signals(scored.Spam)["sender-lookalike"]; !ok {
+			t.Fatalf("expected %s to be flagged as a lookalike, got %+v", message.Sender, scored.Spam)
+		}
+	}
+}
+
+func TestScorerSkipsSentMessagesAndCapsScore(t *testing.T) {
+	scorer := newScorer(t)
+
+	sent := classify(t, scorer, email.EmailMessage{ID: "sent-2", Sender: "me@acme-corp.com", Snippet: "http://198.51.100.7/"})
+	if sent.Spam != nil {
+		t.Fatalf("expected sent messages not to be scored, got %+v", sent.Spam)
+	}
+
+	scored := classify(t, scorer, email.EmailMessage{
+		ID:      "msg-1",
+		Sender:  "Dana Whitfield <dana@acme-c0rp.com>",
+		Snippet: "http://198.51.100.7/ https://user@bit.ly/x",
+		Headers: email.Header{"Authentication-Results": {"mx.acme-corp.com; spf=fail; dkim=fail; dmarc=fail"}},
+	})
+	if scored.Spam.Score != spam.MaxScore || scored.Category != spam.Category {
+		t.Fatalf("expected a capped spam score, got %d (%q)", scored.Spam.Score, scored.Category)
+	}
+}
+
+// mailbox generates the messages of a mailbox that receives one more message
+// with every sync.
+type mailbox struct {
+	arrivals []email.EmailMessage
+	synced   int
+}
+
+func (m *mailbox) Generate(context.Context, email.ProviderConfig, email.AuthState, time.Time) ([]email.EmailMessage, error) {
+	if m.synced < len(m.arrivals) {
+		m.synced++
+	}
+	return append([]email.EmailMessage(nil), m.arrivals[:m.synced]...), nil
+}
+
+func TestScorerDoesNotTrustSendersItFlagged(t *testing.T) {
+	ctx := context.Background()
+	repo := memory.NewRepository()
+	generator := &mailbox{arrivals: []email.EmailMessage{
+		{ID: "in-1", Sender: "Northwind Bank <alerts@northwindbank.com>", Subject: "Statement ready"},
+		{ID: "in-2", Sender: "Northwind Bank <alerts@northwindbamk.com>", Subject: "Confirm your details"},
+		{ID: "in-3", Sender: "Northwind Bank <alerts@northwindbamk.com>", Subject: "Final notice"},
+	}}
+	svc := email.NewService(repo, email.NewSHA256Hasher(), memory.NewVault(), generator, fixedClock{})
+	svc.ClassifyWith(spam.NewScorer(repo, spam.Config{}))
+	if err := svc.ConfigureProvider(ctx, email.ProviderConfig{
+		Provider:    email.ProviderGmail,
+		DisplayName: "Me",
+		Connection:  email.ConnectionSettings{Protocol: email.ProtocolAPI},
+	}); err != nil {
+		t.Fatalf("configure provider: %v", err)
+	}
+	if _, err := svc.Authenticate(ctx, email.AuthRequest{Method: email.AuthMethodOAuth, Username: "me@acme-corp.com", Secret: "abcdefghi"}); err != nil {
+		t.Fatalf("authenticate: %v", err)
+	}
+
+	for sync := 1; sync <= 4; sync++ {
+		if _, err := svc.FetchEmails(ctx); err != nil {
+			t.Fatalf("sync %d: %v", sync, err)
+		}
+	}
+	for _, id := range []string{"in-2", "in-3"} {
+		message, err := svc.Message(ctx, id)
+		if err != nil {
+			t.Fatalf("message %s: %v", id, err)
+		}
+		if _, ok := signals(message.Spam)["sender-lookalike"]; !ok {
+			t.Fatalf("expected %s to stay flagged as a lookalike, got %+v", id, message.Spam)
+		}
+	}
+	bank, err := svc.Message(ctx, "in-1")
+	if err != nil {
+		t.Fatalf("message in-1: %v", err)
+	}
+	if bank.Spam == nil || bank.Spam.Score != 0 {
+		t.Fatalf("expected the bank to stay clean, got %+v", bank.Spam)
+	}
+}
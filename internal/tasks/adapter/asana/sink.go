@@ -0,0 +1,232 @@
+// Package asana creates Asana tasks from messages and reads their completion
+// back through the REST API or webhooks.
+package asana
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/example/iboz/internal/notify"
+	"github.com/example/iboz/internal/tasks"
+)
+
+const (
+	// DefaultBaseURL is the Asana API root.
+	DefaultBaseURL = "https://app.asana.com/api/1.0"
+	// SignatureHeader carries the hex HMAC-SHA256 of webhook deliveries.
+	SignatureHeader = "X-Hook-Signature"
+	defaultTimeout  = 10 * time.Second
+)
+
+var (
+	_ tasks.Sink        = (*Sink)(nil)
+	_ tasks.WebhookSink = (*Sink)(nil)
+)
+
+// Config configures a Sink. Tasks are added to Project when set, otherwise to
+// Workspace. Assignees are user GIDs or emails.
+//
+// WebhookSecret pins the secret of an existing webhook. Without it, webhooks
+// are rejected unless AcceptHandshake is set, in which case the secret of the
+// first handshake is kept and written to SecretPath, when set, so it survives
+// restarts; a secret already stored there is used instead of a new handshake.
+type Config struct {
+	BaseURL         string
+	Token           string
+	Workspace       string
+	Project         string
+	WebhookSecret   string
+	AcceptHandshake bool
+	SecretPath      string
+	Client          *http.Client
+}
+
+// Sink creates Asana tasks.
+type Sink struct {
+	cfg Config
+
+	mu     sync.Mutex
+	loaded bool
+	secret string
+}
+
+// NewSink constructs an Asana Sink. It panics without a token or a workspace or project.
+func NewSink(cfg Config) *Sink {
+	if cfg.Token == "" {
+		panic("asana: token is required")
+	}
+	if cfg.Workspace == "" && cfg.Project == "" {
+		panic("asana: workspace or project is required")
+	}
+	if cfg.BaseURL == "" {
+		cfg.BaseURL = DefaultBaseURL
+	}
+	cfg.BaseURL = strings.TrimRight(cfg.BaseURL, "/")
+	if cfg.Client == nil {
+		cfg.Client = &http.Client{Timeout: defaultTimeout}
+	}
+	return &Sink{cfg: cfg, secret: cfg.WebhookSecret, loaded: cfg.WebhookSecret != ""}
+}
+
+// handshake accepts a webhook handshake presenting the known secret, or keeps
+// and persists the secret of the first one when the sink accepts handshakes.
+func (s *Sink) handshake(secret string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.load(); err != nil {
+		return err
+	}
+	if s.secret != "" {
+		if s.secret != secret {
+			return tasks.ErrInvalidSignature
+		}
+		return nil
+	}
+	if !s.cfg.AcceptHandshake {
+		return tasks.ErrInvalidSignature
+	}
+	if s.cfg.SecretPath != "" {
+		if err := os.WriteFile(s.cfg.SecretPath, []byte(secret), 0o600); err != nil {
+			return fmt.Errorf("asana: store webhook secret: %w", err)
+		}
+	}
+	s.secret = secret
+	return nil
+}
+
+func (s *Sink) webhookSecret() (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.load(); err != nil {
+		return "", err
+	}
+	return s.secret, nil
+}
+
+// load reads the secret stored at SecretPath once. Callers hold s.mu.
+func (s *Sink) load() error {
+	if s.loaded || s.cfg.SecretPath == "" {
+		return nil
+	}
+	data, err := os.ReadFile(s.cfg.SecretPath)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("asana: read webhook secret: %w", err)
+	}
+	s.secret = strings.TrimSpace(string(data))
+	s.loaded = true
+	return nil
+}
+
+type task struct {
+	GID          string `json:"gid"`
+	PermalinkURL string `json:"permalink_url"`
+	Completed    bool   `json:"completed"`
+}
+
+type envelope struct {
+	Data task `json:"data"`
+}
+
+// Create implements the tasks.Sink interface.
+func (s *Sink) Create(ctx context.Context, t tasks.Task) (tasks.External, error) {
+	notes := t.Description
+	if t.Link != "" {
+		notes = strings.TrimSpace(notes + "\n\nOpen the message: " + t.Link)
+	}
+	data := map[string]interface{}{
+		"name":  t.Title,
+		"notes": notes,
+	}
+	if s.cfg.Project != "" {
+		data["projects"] = []string{s.cfg.Project}
+	} else {
+		data["workspace"] = s.cfg.Workspace
+	}
+	if t.Assignee != "" {
+		data["assignee"] = t.Assignee
+	}
+	if t.DueDate != "" {
+		data["due_on"] = t.DueDate
+	}
+
+	var created envelope
+	target := s.cfg.BaseURL + "/tasks?opt_fields=gid,permalink_url,completed"
+	if err := notify.DoJSON(ctx, s.cfg.Client, http.MethodPost, target, s.header(), map[string]interface{}{"data": data}, &created); err != nil {
+		return tasks.External{}, fmt.Errorf("asana: create task: %w", err)
+	}
+	if created.Data.GID == "" {
+		return tasks.External{}, fmt.Errorf("asana: create task: response has no gid")
+	}
+	return external(created.Data), nil
+}
+
+// Fetch implements the tasks.Sink interface.
+func (s *Sink) Fetch(ctx context.Context, gid string) (tasks.External, error) {
+	var current envelope
+	target := s.cfg.BaseURL + "/tasks/" + url.PathEscape(gid) + "?opt_fields=gid,permalink_url,completed"
+	if err := notify.DoJSON(ctx, s.cfg.Client, http.MethodGet, target, s.header(), nil, &current); err != nil {
+		return tasks.External{}, fmt.Errorf("asana: get task: %w", err)
+	}
+	return external(current.Data), nil
+}
+
+// ParseWebhook implements the tasks.WebhookSink interface. A handshake request
+// records its secret and reports nothing; the caller echoes tasks.HandshakeHeader.
+// Events only carry task GIDs, so each changed task is fetched.
+func (s *Sink) ParseWebhook(ctx context.Context, header http.Header, body []byte) ([]tasks.External, error) {
+	if handshake := header.Get(tasks.HandshakeHeader); handshake != "" {
+		return nil, s.handshake(handshake)
+	}
+
+	secret, err := s.webhookSecret()
+	if err != nil {
+		return nil, err
+	}
+	if secret == "" || !tasks.VerifySignature(secret, body, header.Get(SignatureHeader)) {
+		return nil, tasks.ErrInvalidSignature
+	}
+
+	var delivery struct {
+		Events []struct {
+			Resource struct {
+				GID          string `json:"gid"`
+				ResourceType string `json:"resource_type"`
+			} `json:"resource"`
+		} `json:"events"`
+	}
+	if err := json.Unmarshal(body, &delivery); err != nil {
+		return nil, fmt.Errorf("%w: %v", tasks.ErrInvalidTask, err)
+	}
+
+	seen := make(map[string]bool)
+	var changes []tasks.External
+	for _, event := range delivery.Events {
+		gid := event.Resource.GID
+		if event.Resource.ResourceType != "task" || gid == "" || seen[gid] {
+			continue
+		}
+		seen[gid] = true
+		current, err := s.Fetch(ctx, gid)
+		if err != nil {
+			return changes, err
+		}
+		changes = append(changes, current)
+	}
+	return changes, nil
+}
+
+func (s *Sink) header() http.Header {
+	return http.Header{"Authorization": {"Bearer " + s.cfg.Token}}
+}
+
+func external(t task) tasks.External {
+	return tasks.External{ID: t.GID, URL: t.PermalinkURL, Done: t.Completed}
+}
@@ -0,0 +1,190 @@
+// Package jira creates Jira Cloud issues from messages and reads their status
+// back through the REST API v3 or issue webhooks.
+package jira
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/example/iboz/internal/notify"
+	"github.com/example/iboz/internal/tasks"
+)
+
+const (
+	// DefaultIssueType is the issue type created when none is configured.
+	DefaultIssueType = "Task"
+	// SignatureHeader carries the HMAC of webhook deliveries as "sha256=<hex>".
+	SignatureHeader = "X-Hub-Signature"
+	defaultTimeout  = 10 * time.Second
+	doneCategory    = "done"
+)
+
+var (
+	_ tasks.Sink        = (*Sink)(nil)
+	_ tasks.WebhookSink = (*Sink)(nil)
+)
+
+// Config configures a Sink. Assignees are Jira account IDs. Webhooks are
+// rejected unless WebhookSecret is set.
+type Config struct {
+	BaseURL       string
+	Email         string
+	APIToken      string
+	ProjectKey    string
+	IssueType     string
+	WebhookSecret string
+	Client        *http.Client
+}
+
+// Sink creates Jira issues.
+type Sink struct {
+	cfg Config
+}
+
+// NewSink constructs a Jira Sink. It panics without a site URL, credentials or project key.
+func NewSink(cfg Config) *Sink {
+	if cfg.BaseURL == "" {
+		panic("jira: base url is required")
+	}
+	if cfg.Email == "" || cfg.APIToken == "" {
+		panic("jira: email and api token are required")
+	}
+	if cfg.ProjectKey == "" {
+		panic("jira: project key is required")
+	}
+	cfg.BaseURL = strings.TrimRight(cfg.BaseURL, "/")
+	if cfg.IssueType == "" {
+		cfg.IssueType = DefaultIssueType
+	}
+	if cfg.Client == nil {
+		cfg.Client = &http.Client{Timeout: defaultTimeout}
+	}
+	return &Sink{cfg: cfg}
+}
+
+type issue struct {
+	Key    string `json:"key"`
+	Fields struct {
+		Status struct {
+			StatusCategory struct {
+				Key string `json:"key"`
+			} `json:"statusCategory"`
+		} `json:"status"`
+	} `json:"fields"`
+}
+
+// Create implements the tasks.Sink interface.
+func (s *Sink) Create(ctx context.Context, task tasks.Task) (tasks.External, error) {
+	fields := map[string]interface{}{
+		"project":     map[string]string{"key": s.cfg.ProjectKey},
+		"issuetype":   map[string]string{"name": s.cfg.IssueType},
+		"summary":     truncate(task.Title, 255),
+		"description": Document(task.Description, task.Link),
+	}
+	if task.Assignee != "" {
+		fields["assignee"] = map[string]string{"id": task.Assignee}
+	}
+	if task.DueDate != "" {
+		fields["duedate"] = task.DueDate
+	}
+
+	var created issue
+	if err := notify.DoJSON(ctx, s.cfg.Client, http.MethodPost, s.cfg.BaseURL+"/rest/api/3/issue", s.header(), map[string]interface{}{"fields": fields}, &created); err != nil {
+		return tasks.External{}, fmt.Errorf("jira: create issue: %w", err)
+	}
+	if created.Key == "" {
+		return tasks.External{}, fmt.Errorf("jira: create issue: response has no key")
+	}
+	return tasks.External{ID: created.Key, URL: s.browseURL(created.Key)}, nil
+}
+
+// Fetch implements the tasks.Sink interface.
+func (s *Sink) Fetch(ctx context.Context, key string) (tasks.External, error) {
+	var current issue
+	target := s.cfg.BaseURL + "/rest/api/3/issue/" + url.PathEscape(key) + "?fields=status"
+	if err := notify.DoJSON(ctx, s.cfg.Client, http.MethodGet, target, s.header(), nil, &current); err != nil {
+		return tasks.External{}, fmt.Errorf("jira: get issue: %w", err)
+	}
+	return s.external(current), nil
+}
+
+// ParseWebhook implements the tasks.WebhookSink interface for issue events.
+func (s *Sink) ParseWebhook(_ context.Context, header http.Header, body []byte) ([]tasks.External, error) {
+	if !tasks.VerifySignature(s.cfg.WebhookSecret, body, header.Get(SignatureHeader)) {
+		return nil, tasks.ErrInvalidSignature
+	}
+	var event struct {
+		Issue *issue `json:"issue"`
+	}
+	if err := json.Unmarshal(body, &event); err != nil {
+		return nil, fmt.Errorf("%w: %v", tasks.ErrInvalidTask, err)
+	}
+	if event.Issue == nil || event.Issue.Key == "" {
+		return nil, nil
+	}
+	return []tasks.External{s.external(*event.Issue)}, nil
+}
+
+func (s *Sink) external(current issue) tasks.External {
+	return tasks.External{
+		ID:   current.Key,
+		URL:  s.browseURL(current.Key),
+		Done: current.Fields.Status.StatusCategory.Key == doneCategory,
+	}
+}
+
+func (s *Sink) browseURL(key string) string {
+	return s.cfg.BaseURL + "/browse/" + url.PathEscape(key)
+}
+
+func (s *Sink) header() http.Header {
+	req := http.Request{Header: http.Header{}}
+	req.SetBasicAuth(s.cfg.Email, s.cfg.APIToken)
+	return req.Header
+}
+
+// Document renders text as an Atlassian Document Format body: one paragraph
+// per blank-line separated block, followed by a link back to the message.
+func Document(text, link string) map[string]interface{} {
+	content := []map[string]interface{}{}
+	for _, block := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n\n") {
+		block = strings.TrimSpace(block)
+		if block == "" {
+			continue
+		}
+		var inline []map[string]interface{}
+		for i, line := range strings.Split(block, "\n") {
+			if i > 0 {
+				inline = append(inline, map[string]interface{}{"type": "hardBreak"})
+			}
+			if line != "" {
+				inline = append(inline, map[string]interface{}{"type": "text", "text": line})
+			}
+		}
+		content = append(content, map[string]interface{}{"type": "paragraph", "content": inline})
+	}
+	if link != "" {
+		content = append(content, map[string]interface{}{
+			"type": "paragraph",
+			"content": []map[string]interface{}{{
+				"type":  "text",
+				"text":  "Open the message",
+				"marks": []map[string]interface{}{{"type": "link", "attrs": map[string]string{"href": link}}},
+			}},
+		})
+	}
+	return map[string]interface{}{"type": "doc", "version": 1, "content": content}
+}
+
+func truncate(value string, limit int) string {
+	runes := []rune(value)
+	if len(runes) <= limit {
+		return value
+	}
+	return string(runes[:limit-1]) + "…"
+}
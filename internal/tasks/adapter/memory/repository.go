@@ -0,0 +1,35 @@
+package memory
+
+import (
+	"context"
+
+	outboxmemory "github.com/example/iboz/internal/outbox/adapter/memory"
+	"github.com/example/iboz/internal/tasks"
+)
+
+var _ tasks.Repository = (*Repository)(nil)
+
+// Repository provides an in-memory implementation of the tasks.Repository port.
+type Repository struct {
+	*outboxmemory.Records[tasks.Task]
+}
+
+// NewRepository builds a new in-memory task repository.
+func NewRepository() *Repository {
+	return &Repository{Records: outboxmemory.NewRecords(func(task tasks.Task) string { return task.ID }, cloneTask)}
+}
+
+// FindByExternal returns the task created in provider under externalID if present.
+func (r *Repository) FindByExternal(ctx context.Context, provider, externalID string) (*tasks.Task, error) {
+	return r.Find(ctx, func(task tasks.Task) bool {
+		return task.Provider == provider && task.ExternalID == externalID
+	})
+}
+
+func cloneTask(task tasks.Task) tasks.Task {
+	if task.CompletedAt != nil {
+		completed := *task.CompletedAt
+		task.CompletedAt = &completed
+	}
+	return task
+}
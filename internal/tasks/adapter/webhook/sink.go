@@ -0,0 +1,101 @@
+// Package webhook hands tasks to any tracker through a JSON webhook.
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/example/iboz/internal/notify"
+	"github.com/example/iboz/internal/outbox"
+	"github.com/example/iboz/internal/tasks"
+)
+
+const (
+	// SignatureHeader carries the hex HMAC-SHA256 of request bodies in both directions.
+	SignatureHeader = "X-Iboz-Signature"
+	defaultTimeout  = 10 * time.Second
+)
+
+var (
+	_ tasks.Sink        = (*Sink)(nil)
+	_ tasks.WebhookSink = (*Sink)(nil)
+)
+
+// Config configures a Sink. Secret signs outgoing tasks and authenticates
+// status callbacks, which are rejected without it.
+type Config struct {
+	URL    string
+	Secret string
+	Client *http.Client
+}
+
+// Sink posts tasks as JSON to URL and expects {"id", "url"} in reply. Status is
+// reported back through callbacks of the form {"id", "url", "done"}.
+type Sink struct {
+	cfg Config
+}
+
+// NewSink constructs a webhook Sink. It panics without a URL.
+func NewSink(cfg Config) *Sink {
+	if cfg.URL == "" {
+		panic("webhook: url is required")
+	}
+	if cfg.Client == nil {
+		cfg.Client = &http.Client{Timeout: defaultTimeout}
+	}
+	return &Sink{cfg: cfg}
+}
+
+type reference struct {
+	ID   string `json:"id"`
+	URL  string `json:"url"`
+	Done bool   `json:"done"`
+}
+
+// Create implements the tasks.Sink interface. The outbox entry ID is sent as
+// the Idempotency-Key header so receivers can drop redeliveries.
+func (s *Sink) Create(ctx context.Context, task tasks.Task) (tasks.External, error) {
+	body, err := json.Marshal(task)
+	if err != nil {
+		return tasks.External{}, err
+	}
+	header := http.Header{}
+	if id, ok := outbox.EntryID(ctx); ok {
+		header.Set("Idempotency-Key", id)
+	}
+	if s.cfg.Secret != "" {
+		header.Set(SignatureHeader, "sha256="+tasks.Sign(s.cfg.Secret, body))
+	}
+
+	var created reference
+	if err := notify.DoJSON(ctx, s.cfg.Client, http.MethodPost, s.cfg.URL, header, json.RawMessage(body), &created); err != nil {
+		return tasks.External{}, fmt.Errorf("webhook: create task: %w", err)
+	}
+	if created.ID == "" {
+		return tasks.External{}, fmt.Errorf("webhook: create task: response has no id")
+	}
+	return tasks.External(created), nil
+}
+
+// Fetch implements the tasks.Sink interface; webhook trackers cannot be polled.
+func (s *Sink) Fetch(context.Context, string) (tasks.External, error) {
+	return tasks.External{}, tasks.ErrStatusUnavailable
+}
+
+// ParseWebhook implements the tasks.WebhookSink interface.
+func (s *Sink) ParseWebhook(_ context.Context, header http.Header, body []byte) ([]tasks.External, error) {
+	if !tasks.VerifySignature(s.cfg.Secret, body, header.Get(SignatureHeader)) {
+		return nil, tasks.ErrInvalidSignature
+	}
+	var change reference
+	if err := json.Unmarshal(body, &change); err != nil {
+		return nil, fmt.Errorf("%w: %v", tasks.ErrInvalidTask, err)
+	}
+	if change.ID == "" {
+		return nil, fmt.Errorf("%w: id is required", tasks.ErrInvalidTask)
+	}
+	return []tasks.External{tasks.External(change)}, nil
+}
@@ -0,0 +1,299 @@
+package tasks
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/example/iboz/internal/email"
+	"github.com/example/iboz/internal/notify"
+	"github.com/example/iboz/internal/outbox"
+)
+
+// TaskService exposes task creation from messages and completion sync.
+type TaskService interface {
+	Create(ctx context.Context, req Request) (*Task, error)
+	Get(ctx context.Context, id string) (*Task, error)
+	List(ctx context.Context, messageID string) ([]Task, error)
+	SyncStatuses(ctx context.Context) ([]Task, error)
+	HandleWebhook(ctx context.Context, provider string, header http.Header, body []byte) ([]Task, error)
+}
+
+var _ TaskService = (*Service)(nil)
+
+// Service creates tasks through the outbox and keeps their status in sync.
+type Service struct {
+	repo     Repository
+	messages email.Repository
+	linker   MessageLinker
+	sinks    map[string]Sink
+	links    notify.Linker
+	clock    email.Clock
+}
+
+// NewService constructs a task Service. sinks is keyed by provider and links
+// builds the message links included in task descriptions.
+func NewService(repo Repository, messages email.Repository, linker MessageLinker, sinks map[string]Sink, links notify.Linker, clock email.Clock) *Service {
+	if repo == nil {
+		panic("tasks: repository dependency is required")
+	}
+	if messages == nil {
+		panic("tasks: email repository dependency is required")
+	}
+	if linker == nil {
+		panic("tasks: message linker dependency is required")
+	}
+	if clock == nil {
+		panic("tasks: clock dependency is required")
+	}
+	registered := make(map[string]Sink, len(sinks))
+	for provider, sink := range sinks {
+		if sink == nil {
+			panic(fmt.Sprintf("tasks: sink for %q is nil", provider))
+		}
+		registered[provider] = sink
+	}
+	return &Service{repo: repo, messages: messages, linker: linker, sinks: registered, links: links, clock: clock}
+}
+
+// creation is the outbox payload of a task creation.
+type creation struct {
+	TaskID string `json:"taskId"`
+}
+
+// Create validates the request and stores a pending task together with the
+// outbox entry that creates it in the tracker.
+func (s *Service) Create(ctx context.Context, req Request) (*Task, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	req.Provider = strings.ToLower(strings.TrimSpace(req.Provider))
+	if _, ok := s.sinks[req.Provider]; !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownProvider, req.Provider)
+	}
+	if req.DueDate = strings.TrimSpace(req.DueDate); req.DueDate != "" {
+		if _, err := time.Parse(DateLayout, req.DueDate); err != nil {
+			return nil, fmt.Errorf("%w: dueDate must use YYYY-MM-DD", ErrInvalidTask)
+		}
+	}
+	message, err := email.FindMessage(ctx, s.messages, req.MessageID)
+	if err != nil {
+		return nil, err
+	}
+
+	link := s.links.MessageURL(message.ID)
+	title := strings.TrimSpace(req.Title)
+	if title == "" {
+		title = message.Subject
+	}
+	if title == "" {
+		return nil, fmt.Errorf("%w: title is required", ErrInvalidTask)
+	}
+	description := strings.TrimSpace(req.Description)
+	if description == "" {
+		description = message.Summary()
+	}
+
+	id, err := newID()
+	if err != nil {
+		return nil, err
+	}
+	now := s.clock.Now().UTC()
+	task := Task{
+		ID:          id,
+		MessageID:   message.ID,
+		Provider:    req.Provider,
+		Title:       title,
+		Description: description,
+		Link:        link,
+		Assignee:    strings.TrimSpace(req.Assignee),
+		DueDate:     req.DueDate,
+		Status:      StatusPending,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	entry, err := outbox.NewEntry(OutboxDestination, "tasks:create:"+task.ID, creation{TaskID: task.ID}, now)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.Save(ctx, task, entry); err != nil {
+		return nil, err
+	}
+	return &task, nil
+}
+
+// Get returns a task by ID.
+func (s *Service) Get(ctx context.Context, id string) (*Task, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	task, err := s.repo.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if task == nil {
+		return nil, ErrTaskNotFound
+	}
+	return task, nil
+}
+
+// List returns tasks, newest first, optionally only those of one message.
+func (s *Service) List(ctx context.Context, messageID string) ([]Task, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	all, err := s.repo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	list := make([]Task, 0, len(all))
+	for _, task := range all {
+		if messageID == "" || task.MessageID == messageID {
+			list = append(list, task)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].CreatedAt.After(list[j].CreatedAt) })
+	return list, nil
+}
+
+// Deliverer returns the outbox deliverer creating pending tasks in their tracker.
+func (s *Service) Deliverer() outbox.Deliverer {
+	return outbox.DelivererFunc(func(ctx context.Context, entry outbox.Entry) error {
+		var payload creation
+		if err := json.Unmarshal(entry.Payload, &payload); err != nil {
+			return fmt.Errorf("decode task creation: %w", err)
+		}
+		task, err := s.Get(ctx, payload.TaskID)
+		if err != nil {
+			return err
+		}
+		if task.Status != StatusPending {
+			return nil
+		}
+		sink, ok := s.sinks[task.Provider]
+		if !ok {
+			return fmt.Errorf("%w: %q", ErrUnknownProvider, task.Provider)
+		}
+		external, err := sink.Create(ctx, *task)
+		if err != nil {
+			return err
+		}
+		task.ExternalID = external.ID
+		task.URL = external.URL
+		task.Status = StatusOpen
+		return s.apply(ctx, task, external.Done)
+	})
+}
+
+// SyncStatuses polls the tracker of every open task and records completions.
+// Sinks that cannot be polled are skipped; they report through webhooks.
+func (s *Service) SyncStatuses(ctx context.Context) ([]Task, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	all, err := s.repo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var completed []Task
+	var errs []error
+	for _, task := range all {
+		if task.Status != StatusOpen {
+			continue
+		}
+		sink, ok := s.sinks[task.Provider]
+		if !ok {
+			continue
+		}
+		external, err := sink.Fetch(ctx, task.ExternalID)
+		if errors.Is(err, ErrStatusUnavailable) {
+			continue
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("tasks: fetch %s %s: %w", task.Provider, task.ExternalID, err))
+			continue
+		}
+		if !external.Done {
+			continue
+		}
+		if err := s.apply(ctx, &task, true); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		completed = append(completed, task)
+	}
+	return completed, errors.Join(errs...)
+}
+
+// HandleWebhook applies a tracker's change notification and returns the tasks it updated.
+func (s *Service) HandleWebhook(ctx context.Context, provider string, header http.Header, body []byte) ([]Task, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	sink, ok := s.sinks[provider]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownProvider, provider)
+	}
+	hooks, ok := sink.(WebhookSink)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s does not accept webhooks", ErrUnknownProvider, provider)
+	}
+	changes, err := hooks.ParseWebhook(ctx, header, body)
+	if err != nil {
+		return nil, err
+	}
+
+	var updated []Task
+	for _, change := range changes {
+		task, err := s.repo.FindByExternal(ctx, provider, change.ID)
+		if err != nil {
+			return updated, err
+		}
+		if task == nil || task.Status == StatusPending || (task.Status == StatusDone) == change.Done {
+			continue
+		}
+		if change.URL != "" {
+			task.URL = change.URL
+		}
+		if err := s.apply(ctx, task, change.Done); err != nil {
+			return updated, err
+		}
+		updated = append(updated, *task)
+	}
+	return updated, nil
+}
+
+// apply stores task with its completion state and mirrors it onto the message.
+func (s *Service) apply(ctx context.Context, task *Task, done bool) error {
+	now := s.clock.Now().UTC()
+	task.Status = StatusOpen
+	task.CompletedAt = nil
+	if done {
+		task.Status = StatusDone
+		task.CompletedAt = &now
+	}
+	task.UpdatedAt = now
+	if err := s.repo.Save(ctx, *task); err != nil {
+		return err
+	}
+	link := email.TaskLink{Provider: task.Provider, ExternalID: task.ExternalID, URL: task.URL, Status: string(task.Status)}
+	if err := s.linker.LinkTask(ctx, task.MessageID, link); err != nil && !errors.Is(err, email.ErrMessageNotFound) {
+		return err
+	}
+	return nil
+}
+
+func newID() (string, error) {
+	buf := make([]byte, 6)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("generate task id: %w", err)
+	}
+	return "task-" + hex.EncodeToString(buf), nil
+}
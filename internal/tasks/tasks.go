@@ -0,0 +1,117 @@
+package tasks
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/example/iboz/internal/email"
+	"github.com/example/iboz/internal/outbox"
+)
+
+// Status enumerates the lifecycle of a task created from a message.
+type Status string
+
+const (
+	// StatusPending tasks wait for the outbox relay to create them externally.
+	StatusPending Status = "pending"
+	StatusOpen    Status = "open"
+	StatusDone    Status = "done"
+)
+
+// Provider names of the bundled sinks.
+const (
+	ProviderJira    = "jira"
+	ProviderAsana   = "asana"
+	ProviderWebhook = "webhook"
+)
+
+// OutboxDestination is the outbox destination of task creations, delivered by
+// the Service's Deliverer.
+const OutboxDestination = "tasks.create"
+
+// HandshakeHeader carries the secret of a webhook handshake (Asana), which the
+// receiver echoes back in its response.
+const HandshakeHeader = "X-Hook-Secret"
+
+// DateLayout is the format of due dates.
+const DateLayout = "2006-01-02"
+
+var (
+	// ErrTaskNotFound is returned when a task does not exist.
+	ErrTaskNotFound = errors.New("task not found")
+	// ErrInvalidTask is returned when a task request fails validation.
+	ErrInvalidTask = errors.New("invalid task")
+	// ErrUnknownProvider is returned when no sink is configured for a provider.
+	ErrUnknownProvider = errors.New("unknown task provider")
+	// ErrStatusUnavailable is returned by sinks that cannot be polled for status.
+	ErrStatusUnavailable = errors.New("task status cannot be polled")
+	// ErrInvalidSignature is returned when a webhook delivery cannot be authenticated.
+	ErrInvalidSignature = errors.New("invalid webhook signature")
+)
+
+// Task is a unit of work created in an external tracker from a message.
+type Task struct {
+	ID          string     `json:"id"`
+	MessageID   string     `json:"messageId"`
+	Provider    string     `json:"provider"`
+	Title       string     `json:"title"`
+	Description string     `json:"description,omitempty"`
+	Link        string     `json:"link,omitempty"`
+	Assignee    string     `json:"assignee,omitempty"`
+	DueDate     string     `json:"dueDate,omitempty"`
+	Status      Status     `json:"status"`
+	ExternalID  string     `json:"externalId,omitempty"`
+	URL         string     `json:"url,omitempty"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	UpdatedAt   time.Time  `json:"updatedAt"`
+	CompletedAt *time.Time `json:"completedAt,omitempty"`
+}
+
+// Request describes a task to create. Title and Description default to the
+// message subject and a summary of the message.
+type Request struct {
+	MessageID   string `json:"messageId"`
+	Provider    string `json:"provider"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Assignee    string `json:"assignee"`
+	DueDate     string `json:"dueDate"`
+}
+
+// External is the state of a task as reported by its tracker.
+type External struct {
+	ID   string
+	URL  string
+	Done bool
+}
+
+// Repository defines the persistence contract for tasks. It owns the outbox
+// table holding pending creations.
+type Repository interface {
+	outbox.Store
+	// Save stores task and appends effects to the outbox atomically.
+	Save(ctx context.Context, task Task, effects ...outbox.Entry) error
+	Get(ctx context.Context, id string) (*Task, error)
+	List(ctx context.Context) ([]Task, error)
+	FindByExternal(ctx context.Context, provider, externalID string) (*Task, error)
+}
+
+// Sink creates tasks in an external tracker and reports their state.
+type Sink interface {
+	Create(ctx context.Context, task Task) (External, error)
+	// Fetch returns the current state of a task, or ErrStatusUnavailable.
+	Fetch(ctx context.Context, externalID string) (External, error)
+}
+
+// WebhookSink is implemented by sinks that accept change notifications.
+type WebhookSink interface {
+	// ParseWebhook verifies a delivery and returns the tasks it reports on.
+	ParseWebhook(ctx context.Context, header http.Header, body []byte) ([]External, error)
+}
+
+// MessageLinker records task links on messages.
+type MessageLinker interface {
+	LinkTask(ctx context.Context, messageID string, link email.TaskLink) error
+}
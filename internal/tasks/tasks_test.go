@@ -0,0 +1,300 @@
+package tasks_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/example/iboz/internal/email"
+	emailmemory "github.com/example/iboz/internal/email/adapter/memory"
+	"github.com/example/iboz/internal/notify"
+	"github.com/example/iboz/internal/outbox"
+	"github.com/example/iboz/internal/tasks"
+	"github.com/example/iboz/internal/tasks/adapter/asana"
+	"github.com/example/iboz/internal/tasks/adapter/jira"
+	"github.com/example/iboz/internal/tasks/adapter/memory"
+	"github.com/example/iboz/internal/tasks/adapter/webhook"
+	"github.com/example/iboz/internal/testutil"
+)
+
+type fixture struct {
+	service  *tasks.Service
+	relay    *outbox.Relay
+	messages *emailmemory.Repository
+	clock    *testutil.Clock
+}
+
+func newFixture(t *testing.T, sinks map[string]tasks.Sink) fixture {
+	t.Helper()
+	clock := testutil.NewClock(testutil.Start)
+	messages := testutil.Mailbox(t, clock, email.EmailMessage{
+		ID:         "msg-1",
+		Subject:    "Renewal contract",
+		Sender:     "buyer@customer.example",
+		ReceivedAt: clock.Now().Add(-time.Hour),
+		Snippet:    "Please send the signed renewal by Friday.",
+	})
+	repo := memory.NewRepository()
+	service := tasks.NewService(repo, messages, messages, sinks, notify.Linker{BaseURL: "https://iboz.example"}, clock)
+	relay := testutil.NewRelay(repo, map[string]outbox.Deliverer{
+		tasks.OutboxDestination: service.Deliverer(),
+	}, clock)
+	return fixture{service: service, relay: relay, messages: messages, clock: clock}
+}
+
+func TestJiraIssueCreatedAndCompletionPolled(t *testing.T) {
+	var mu sync.Mutex
+	var created map[string]any
+	category := "new"
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, ok := r.BasicAuth(); !ok || user != "ops@example.com" || pass != "token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/rest/api/3/issue":
+			json.NewDecoder(r.Body).Decode(&created)
+			w.Write([]byte(`{"id":"10001","key":"OPS-7"}`))
+		case r.Method == http.MethodGet && r.URL.Path == "/rest/api/3/issue/OPS-7":
+			w.Write([]byte(`{"key":"OPS-7","fields":{"status":{"statusCategory":{"key":"` + category + `"}}}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	f := newFixture(t, map[string]tasks.Sink{
+		tasks.ProviderJira: jira.NewSink(jira.Config{BaseURL: srv.URL, Email: "ops@example.com", APIToken: "token", ProjectKey: "OPS"}),
+	})
+	ctx := context.Background()
+	task, err := f.service.Create(ctx, tasks.Request{MessageID: "msg-1", Provider: "Jira", Assignee: "5b10ac8d", DueDate: "2025-03-21"})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if task.Status != tasks.StatusPending || task.Title != "Renewal contract" || task.Link != "https://iboz.example/messages/msg-1" {
+		t.Fatalf("unexpected task: %+v", task)
+	}
+
+	testutil.Deliver(t, f.relay)
+	testutil.Deliver(t, f.relay)
+	fields, _ := created["fields"].(map[string]any)
+	if fields["summary"] != "Renewal contract" || fields["duedate"] != "2025-03-21" {
+		t.Fatalf("unexpected issue fields: %v", fields)
+	}
+	if assignee, _ := fields["assignee"].(map[string]any); assignee["id"] != "5b10ac8d" {
+		t.Fatalf("unexpected assignee: %v", fields["assignee"])
+	}
+	description, _ := json.Marshal(fields["description"])
+	if !strings.Contains(string(description), `"href":"https://iboz.example/messages/msg-1"`) || !strings.Contains(string(description), "signed renewal") {
+		t.Fatalf("description missing summary or link: %s", description)
+	}
+
+	stored, err := f.service.Get(ctx, task.ID)
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if stored.Status != tasks.StatusOpen || stored.ExternalID != "OPS-7" || stored.URL != srv.URL+"/browse/OPS-7" {
+		t.Fatalf("unexpected stored task: %+v", stored)
+	}
+	links := testutil.Message(t, f.messages, "msg-1").Tasks
+	if len(links) != 1 || links[0].ExternalID != "OPS-7" || links[0].URL != stored.URL || links[0].Status != "open" {
+		t.Fatalf("unexpected message links: %+v", links)
+	}
+
+	if completed, err := f.service.SyncStatuses(ctx); err != nil || len(completed) != 0 {
+		t.Fatalf("expected no completion yet, got %v %v", completed, err)
+	}
+	mu.Lock()
+	category = "done"
+	mu.Unlock()
+	f.clock.Advance(time.Hour)
+	completed, err := f.service.SyncStatuses(ctx)
+	if err != nil || len(completed) != 1 || completed[0].CompletedAt == nil {
+		t.Fatalf("expected completion, got %v %v", completed, err)
+	}
+	if links := testutil.Message(t, f.messages, "msg-1").Tasks; len(links) != 1 || links[0].Status != "done" {
+		t.Fatalf("expected done link, got %+v", links)
+	}
+}
+
+func TestJiraWebhookRequiresSignature(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"key":"OPS-1"}`))
+	}))
+	defer srv.Close()
+
+	f := newFixture(t, map[string]tasks.Sink{
+		tasks.ProviderJira: jira.NewSink(jira.Config{BaseURL: srv.URL, Email: "a", APIToken: "b", ProjectKey: "OPS", WebhookSecret: "s3cret"}),
+	})
+	ctx := context.Background()
+	if _, err := f.service.Create(ctx, tasks.Request{MessageID: "msg-1", Provider: tasks.ProviderJira}); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	testutil.Deliver(t, f.relay)
+
+	body := []byte(`{"webhookEvent":"jira:issue_updated","issue":{"key":"OPS-1","fields":{"status":{"statusCategory":{"key":"done"}}}}}`)
+	if _, err := f.service.HandleWebhook(ctx, tasks.ProviderJira, http.Header{}, body); !errors.Is(err, tasks.ErrInvalidSignature) {
+		t.Fatalf("expected ErrInvalidSignature, got %v", err)
+	}
+	header := http.Header{jira.SignatureHeader: {"sha256=" + tasks.Sign("s3cret", body)}}
+	updated, err := f.service.HandleWebhook(ctx, tasks.ProviderJira, header, body)
+	if err != nil || len(updated) != 1 || updated[0].Status != tasks.StatusDone {
+		t.Fatalf("expected completed task, got %v %v", updated, err)
+	}
+}
+
+func TestAsanaHandshakeAndWebhookCompletion(t *testing.T) {
+	var mu sync.Mutex
+	var created map[string]map[string]any
+	completed := false
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer pat" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/tasks":
+			json.NewDecoder(r.Body).Decode(&created)
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"data":{"gid":"1201","permalink_url":"https://app.asana.com/0/1/1201","completed":false}}`))
+		case r.Method == http.MethodGet && r.URL.Path == "/tasks/1201":
+			json.NewEncoder(w).Encode(map[string]any{"data": map[string]any{"gid": "1201", "permalink_url": "https://app.asana.com/0/1/1201", "completed": completed}})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+	secretPath := filepath.Join(t.TempDir(), "asana-webhook-secret")
+
+	f := newFixture(t, map[string]tasks.Sink{
+		tasks.ProviderAsana: asana.NewSink(asana.Config{BaseURL: srv.URL, Token: "pat", Project: "42", AcceptHandshake: true, SecretPath: secretPath}),
+	})
+	ctx := context.Background()
+	if _, err := f.service.Create(ctx, tasks.Request{MessageID: "msg-1", Provider: tasks.ProviderAsana, Title: "Send renewal", Assignee: "me"}); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	testutil.Deliver(t, f.relay)
+	data := created["data"]
+	if data["name"] != "Send renewal" || data["assignee"] != "me" || !strings.Contains(data["notes"].(string), "https://iboz.example/messages/msg-1") {
+		t.Fatalf("unexpected asana task: %v", data)
+	}
+	if links := testutil.Message(t, f.messages, "msg-1").Tasks; len(links) != 1 || links[0].URL != "https://app.asana.com/0/1/1201" {
+		t.Fatalf("unexpected links: %+v", links)
+	}
+
+	if _, err := f.service.HandleWebhook(ctx, tasks.ProviderAsana, http.Header{tasks.HandshakeHeader: {"hook-secret"}}, nil); err != nil {
+		t.Fatalf("handshake: %v", err)
+	}
+	if _, err := f.service.HandleWebhook(ctx, tasks.ProviderAsana, http.Header{tasks.HandshakeHeader: {"other"}}, nil); !errors.Is(err, tasks.ErrInvalidSignature) {
+		t.Fatalf("expected a second handshake to be rejected, got %v", err)
+	}
+
+	mu.Lock()
+	completed = true
+	mu.Unlock()
+	body := []byte(`{"events":[{"action":"changed","resource":{"gid":"1201","resource_type":"task"}},{"action":"changed","resource":{"gid":"1201","resource_type":"task"}}]}`)
+	if _, err := f.service.HandleWebhook(ctx, tasks.ProviderAsana, http.Header{asana.SignatureHeader: {tasks.Sign("wrong", body)}}, body); !errors.Is(err, tasks.ErrInvalidSignature) {
+		t.Fatalf("expected ErrInvalidSignature, got %v", err)
+	}
+	updated, err := f.service.HandleWebhook(ctx, tasks.ProviderAsana, http.Header{asana.SignatureHeader: {tasks.Sign("hook-secret", body)}}, body)
+	if err != nil || len(updated) != 1 || updated[0].Status != tasks.StatusDone {
+		t.Fatalf("expected completed task, got %v %v", updated, err)
+	}
+	if links := testutil.Message(t, f.messages, "msg-1").Tasks; links[0].Status != "done" {
+		t.Fatalf("expected done link, got %+v", links)
+	}
+
+	// After a restart the persisted secret still applies and no new handshake is trusted.
+	restarted := asana.NewSink(asana.Config{BaseURL: srv.URL, Token: "pat", Project: "42", AcceptHandshake: true, SecretPath: secretPath})
+	if _, err := restarted.ParseWebhook(ctx, http.Header{tasks.HandshakeHeader: {"attacker"}}, nil); !errors.Is(err, tasks.ErrInvalidSignature) {
+		t.Fatalf("expected a foreign handshake after restart to be rejected, got %v", err)
+	}
+	if changes, err := restarted.ParseWebhook(ctx, http.Header{asana.SignatureHeader: {tasks.Sign("hook-secret", body)}}, body); err != nil || len(changes) != 1 {
+		t.Fatalf("expected the persisted secret to verify deliveries, got %v %v", changes, err)
+	}
+}
+
+func TestAsanaRejectsHandshakesUnlessAccepted(t *testing.T) {
+	sink := asana.NewSink(asana.Config{Token: "pat", Project: "42"})
+	if _, err := sink.ParseWebhook(context.Background(), http.Header{tasks.HandshakeHeader: {"hook-secret"}}, nil); !errors.Is(err, tasks.ErrInvalidSignature) {
+		t.Fatalf("expected an unconfigured handshake to be rejected, got %v", err)
+	}
+	body := []byte(`{"events":[]}`)
+	if _, err := sink.ParseWebhook(context.Background(), http.Header{asana.SignatureHeader: {tasks.Sign("", body)}}, body); !errors.Is(err, tasks.ErrInvalidSignature) {
+		t.Fatalf("expected deliveries without a secret to be rejected, got %v", err)
+	}
+}
+
+func TestWebhookSinkSignsTasksAndAcceptsCallbacks(t *testing.T) {
+	var signature, idempotency string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		signature = r.Header.Get(webhook.SignatureHeader)
+		idempotency = r.Header.Get("Idempotency-Key")
+		w.Write([]byte(`{"id":"T-9","url":"https://tracker.example/T-9"}`))
+	}))
+	defer srv.Close()
+
+	f := newFixture(t, map[string]tasks.Sink{
+		tasks.ProviderWebhook: webhook.NewSink(webhook.Config{URL: srv.URL, Secret: "shh"}),
+	})
+	ctx := context.Background()
+	task, err := f.service.Create(ctx, tasks.Request{MessageID: "msg-1", Provider: tasks.ProviderWebhook})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	testutil.Deliver(t, f.relay)
+	if !strings.HasPrefix(signature, "sha256=") || !strings.HasPrefix(idempotency, "out-") {
+		t.Fatalf("expected signed, idempotent delivery, got %q %q", signature, idempotency)
+	}
+	if completed, err := f.service.SyncStatuses(ctx); err != nil || len(completed) != 0 {
+		t.Fatalf("webhook tasks must not be polled, got %v %v", completed, err)
+	}
+
+	body := []byte(`{"id":"T-9","done":true}`)
+	updated, err := f.service.HandleWebhook(ctx, tasks.ProviderWebhook, http.Header{webhook.SignatureHeader: {"sha256=" + tasks.Sign("shh", body)}}, body)
+	if err != nil || len(updated) != 1 || updated[0].ID != task.ID || updated[0].URL != "https://tracker.example/T-9" {
+		t.Fatalf("expected callback to complete task, got %v %v", updated, err)
+	}
+
+	reopen := []byte(`{"id":"T-9","done":false}`)
+	updated, err = f.service.HandleWebhook(ctx, tasks.ProviderWebhook, http.Header{webhook.SignatureHeader: {tasks.Sign("shh", reopen)}}, reopen)
+	if err != nil || len(updated) != 1 || updated[0].Status != tasks.StatusOpen || updated[0].CompletedAt != nil {
+		t.Fatalf("expected callback to reopen task, got %v %v", updated, err)
+	}
+}
+
+func TestCreateValidation(t *testing.T) {
+	f := newFixture(t, map[string]tasks.Sink{
+		tasks.ProviderWebhook: webhook.NewSink(webhook.Config{URL: "http://127.0.0.1:1"}),
+	})
+	ctx := context.Background()
+
+	cases := []struct {
+		name string
+		req  tasks.Request
+		want error
+	}{
+		{"unknown provider", tasks.Request{MessageID: "msg-1", Provider: "trello"}, tasks.ErrUnknownProvider},
+		{"bad due date", tasks.Request{MessageID: "msg-1", Provider: tasks.ProviderWebhook, DueDate: "Friday"}, tasks.ErrInvalidTask},
+		{"missing message", tasks.Request{MessageID: "msg-404", Provider: tasks.ProviderWebhook}, email.ErrMessageNotFound},
+	}
+	for _, tc := range cases {
+		if _, err := f.service.Create(ctx, tc.req); !errors.Is(err, tc.want) {
+			t.Fatalf("%s: expected %v, got %v", tc.name, tc.want, err)
+		}
+	}
+	if _, err := f.service.Get(ctx, "task-missing"); !errors.Is(err, tasks.ErrTaskNotFound) {
+		t.Fatalf("expected ErrTaskNotFound, got %v", err)
+	}
+}
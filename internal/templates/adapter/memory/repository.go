@@ -0,0 +1,77 @@
+package memory
+
+import (
+	"context"
+	"sync"
+
+	"github.com/example/iboz/internal/templates"
+)
+
+var _ templates.Repository = (*Repository)(nil)
+
+// Repository provides an in-memory implementation of the templates.Repository port.
+type Repository struct {
+	mu        sync.RWMutex
+	templates map[string]templates.Template
+}
+
+// NewRepository builds a new in-memory template repository.
+func NewRepository() *Repository {
+	return &Repository{templates: make(map[string]templates.Template)}
+}
+
+// Save inserts or replaces a template.
+func (r *Repository) Save(ctx context.Context, tpl templates.Template) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	r.templates[tpl.ID] = tpl
+	r.mu.Unlock()
+	return nil
+}
+
+// Get returns the template with the supplied identifier if present.
+func (r *Repository) Get(ctx context.Context, id string) (*templates.Template, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	tpl, ok := r.templates[id]
+	if !ok {
+		return nil, nil
+	}
+	return &tpl, nil
+}
+
+// List returns every stored template.
+func (r *Repository) List(ctx context.Context) ([]templates.Template, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	list := make([]templates.Template, 0, len(r.templates))
+	for _, tpl := range r.templates {
+		list = append(list, tpl)
+	}
+	return list, nil
+}
+
+// Delete removes the template with the supplied identifier.
+func (r *Repository) Delete(ctx context.Context, id string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	delete(r.templates, id)
+	r.mu.Unlock()
+	return nil
+}
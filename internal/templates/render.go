@@ -0,0 +1,302 @@
+package templates
+
+import (
+	"bytes"
+	"fmt"
+	htmltemplate "html/template"
+	"net/mail"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	texttemplate "text/template"
+	"text/template/parse"
+	"time"
+
+	"github.com/example/iboz/internal/email"
+)
+
+// Variables is the data exposed to templates, referenced as {{.SenderName}} and so on.
+type Variables struct {
+	SenderName  string
+	SenderEmail string
+	Subject     string
+	Snippet     string
+	CaseNumber  string
+	ReceivedAt  time.Time
+}
+
+var knownVariables = func() map[string]struct{} {
+	known := make(map[string]struct{})
+	t := reflect.TypeOf(Variables{})
+	for i := 0; i < t.NumField(); i++ {
+		known[t.Field(i).Name] = struct{}{}
+	}
+	return known
+}()
+
+type parsed struct {
+	subject *texttemplate.Template
+	text    *texttemplate.Template
+	html    *htmltemplate.Template
+}
+
+// Render executes every variant of tpl against message. Templates referencing
+// unknown variables are refused with an error wrapping ErrUnknownVariables.
+func Render(tpl Template, message email.EmailMessage) (Rendered, error) {
+	p, err := compile(tpl)
+	if err != nil {
+		return Rendered{}, err
+	}
+	if err := validate(p).Err(); err != nil {
+		return Rendered{}, err
+	}
+	vars := VariablesFor(message, tpl.CaseNumberPattern)
+
+	var result Rendered
+	if p.subject != nil {
+		if result.Subject, err = executeText(p.subject, vars); err != nil {
+			return Rendered{}, err
+		}
+	}
+	if p.text != nil {
+		if result.TextBody, err = executeText(p.text, vars); err != nil {
+			return Rendered{}, err
+		}
+	}
+	if p.html != nil {
+		var buf bytes.Buffer
+		if err := p.html.Execute(&buf, vars); err != nil {
+			return Rendered{}, fmt.Errorf("render html body: %w", err)
+		}
+		result.HTMLBody = buf.String()
+	}
+	return result, nil
+}
+
+// Validate lists the variables referenced by tpl and flags unknown ones.
+func Validate(tpl Template) (Validation, error) {
+	p, err := compile(tpl)
+	if err != nil {
+		return Validation{}, err
+	}
+	return validate(p), nil
+}
+
+func validate(p parsed) Validation {
+	referenced := make(map[string]struct{})
+	if p.subject != nil {
+		collectTemplates(p.subject.Name(), textTrees(p.subject), referenced)
+	}
+	if p.text != nil {
+		collectTemplates(p.text.Name(), textTrees(p.text), referenced)
+	}
+	if p.html != nil {
+		collectTemplates(p.html.Name(), htmlTrees(p.html), referenced)
+	}
+
+	result := Validation{Valid: true, Variables: []string{}}
+	for name := range referenced {
+		result.Variables = append(result.Variables, name)
+		if _, ok := knownVariables[name]; !ok {
+			result.Unknown = append(result.Unknown, name)
+			result.Valid = false
+		}
+	}
+	sort.Strings(result.Variables)
+	sort.Strings(result.Unknown)
+	return result
+}
+
+// VariablesFor derives template variables from a message. An empty pattern uses DefaultCaseNumberPattern.
+func VariablesFor(message email.EmailMessage, caseNumberPattern string) Variables {
+	vars := Variables{
+		SenderEmail: message.Sender,
+		Subject:     message.Subject,
+		Snippet:     message.Snippet,
+		ReceivedAt:  message.ReceivedAt,
+	}
+	if addr, err := mail.ParseAddress(message.Sender); err == nil {
+		vars.SenderEmail = addr.Address
+		vars.SenderName = addr.Name
+	}
+	if vars.SenderName == "" {
+		local, _, _ := strings.Cut(vars.SenderEmail, "@")
+		vars.SenderName = local
+	}
+
+	pattern := caseNumberPattern
+	if pattern == "" {
+		pattern = DefaultCaseNumberPattern
+	}
+	if re, err := regexp.Compile(pattern); err == nil {
+		for _, source := range []string{message.Subject, message.Snippet} {
+			if match := re.FindStringSubmatch(source); match != nil {
+				vars.CaseNumber = match[len(match)-1]
+				break
+			}
+		}
+	}
+	return vars
+}
+
+func compile(tpl Template) (parsed, error) {
+	var p parsed
+	var err error
+	if tpl.Subject != "" {
+		if p.subject, err = texttemplate.New("subject").Option("missingkey=error").Parse(tpl.Subject); err != nil {
+			return parsed{}, fmt.Errorf("invalid subject template: %w", err)
+		}
+	}
+	if tpl.TextBody != "" {
+		if p.text, err = texttemplate.New("text").Option("missingkey=error").Parse(tpl.TextBody); err != nil {
+			return parsed{}, fmt.Errorf("invalid text template: %w", err)
+		}
+	}
+	if tpl.HTMLBody != "" {
+		if p.html, err = htmltemplate.New("html").Option("missingkey=error").Parse(tpl.HTMLBody); err != nil {
+			return parsed{}, fmt.Errorf("invalid html template: %w", err)
+		}
+	}
+	return p, nil
+}
+
+func executeText(t *texttemplate.Template, vars Variables) (string, error) {
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, vars); err != nil {
+		return "", fmt.Errorf("render %s: %w", t.Name(), err)
+	}
+	return buf.String(), nil
+}
+
+func textTrees(t *texttemplate.Template) map[string]*parse.Tree {
+	trees := make(map[string]*parse.Tree)
+	for _, tmpl := range t.Templates() {
+		if tmpl.Tree != nil {
+			trees[tmpl.Name()] = tmpl.Tree
+		}
+	}
+	return trees
+}
+
+func htmlTrees(t *htmltemplate.Template) map[string]*parse.Tree {
+	trees := make(map[string]*parse.Tree)
+	for _, tmpl := range t.Templates() {
+		if tmpl.Tree != nil {
+			trees[tmpl.Name()] = tmpl.Tree
+		}
+	}
+	return trees
+}
+
+// collectTemplates records the fields referenced by the template named root and
+// by the templates it invokes. Templates defined but never invoked are
+// collected as if invoked with the template data.
+func collectTemplates(root string, trees map[string]*parse.Tree, out map[string]struct{}) {
+	c := collector{trees: trees, visited: make(map[visit]bool), out: out}
+	c.template(root, true)
+	names := make([]string, 0, len(trees))
+	for name := range trees {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if !c.visited[visit{name, true}] && !c.visited[visit{name, false}] {
+			c.template(name, true)
+		}
+	}
+}
+
+// visit is a template walked with dot being the template data or not.
+type visit struct {
+	name string
+	root bool
+}
+
+type collector struct {
+	trees   map[string]*parse.Tree
+	visited map[visit]bool
+	out     map[string]struct{}
+}
+
+// template walks a named template once per kind of dot. In its body both dot
+// and $ are the value the template was invoked with.
+func (c collector) template(name string, dotIsRoot bool) {
+	tree, ok := c.trees[name]
+	if !ok || c.visited[visit{name, dotIsRoot}] {
+		return
+	}
+	c.visited[visit{name, dotIsRoot}] = true
+	c.fields(tree.Root, dotIsRoot, dotIsRoot)
+}
+
+// fields records the top-level field of every $.Field reference while $ is the
+// template data, and of every .Field reference while dot is. The bodies of with
+// and range rebind dot, so only their pipelines and else branches reference it.
+func (c collector) fields(node parse.Node, dotIsRoot, dollarIsRoot bool) {
+	switch n := node.(type) {
+	case nil:
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			c.fields(child, dotIsRoot, dollarIsRoot)
+		}
+	case *parse.ActionNode:
+		c.fields(n.Pipe, dotIsRoot, dollarIsRoot)
+	case *parse.PipeNode:
+		if n == nil {
+			return
+		}
+		for _, cmd := range n.Cmds {
+			c.fields(cmd, dotIsRoot, dollarIsRoot)
+		}
+	case *parse.CommandNode:
+		for _, arg := range n.Args {
+			c.fields(arg, dotIsRoot, dollarIsRoot)
+		}
+	case *parse.FieldNode:
+		if dotIsRoot {
+			c.out[n.Ident[0]] = struct{}{}
+		}
+	case *parse.VariableNode:
+		if dollarIsRoot && len(n.Ident) > 1 && n.Ident[0] == "$" {
+			c.out[n.Ident[1]] = struct{}{}
+		}
+	case *parse.ChainNode:
+		c.fields(n.Node, dotIsRoot, dollarIsRoot)
+	case *parse.IfNode:
+		c.branch(&n.BranchNode, dotIsRoot, dollarIsRoot, dotIsRoot)
+	case *parse.RangeNode:
+		c.branch(&n.BranchNode, dotIsRoot, dollarIsRoot, false)
+	case *parse.WithNode:
+		c.branch(&n.BranchNode, dotIsRoot, dollarIsRoot, false)
+	case *parse.TemplateNode:
+		c.fields(n.Pipe, dotIsRoot, dollarIsRoot)
+		c.template(n.Name, passesRoot(n.Pipe, dotIsRoot, dollarIsRoot))
+	}
+}
+
+// branch collects the fields of a branch whose body runs with dot being the
+// template data when bodyDotIsRoot is set.
+func (c collector) branch(n *parse.BranchNode, dotIsRoot, dollarIsRoot, bodyDotIsRoot bool) {
+	c.fields(n.Pipe, dotIsRoot, dollarIsRoot)
+	c.fields(n.List, bodyDotIsRoot, dollarIsRoot)
+	c.fields(n.ElseList, dotIsRoot, dollarIsRoot)
+}
+
+// passesRoot reports whether a template invoked with pipe receives the
+// template data, as with {{template "x" .}} or {{template "x" $}}.
+func passesRoot(pipe *parse.PipeNode, dotIsRoot, dollarIsRoot bool) bool {
+	if pipe == nil || len(pipe.Decl) > 0 || len(pipe.Cmds) != 1 || len(pipe.Cmds[0].Args) != 1 {
+		return false
+	}
+	switch arg := pipe.Cmds[0].Args[0].(type) {
+	case *parse.DotNode:
+		return dotIsRoot
+	case *parse.VariableNode:
+		return dollarIsRoot && len(arg.Ident) == 1 && arg.Ident[0] == "$"
+	}
+	return false
+}
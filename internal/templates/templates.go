@@ -0,0 +1,251 @@
+package templates
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/example/iboz/internal/email"
+)
+
+// DefaultCaseNumberPattern extracts identifiers such as "Case #4821" from subjects and snippets.
+const DefaultCaseNumberPattern = `(?i)case\s*(?:#|no\.?|number)?\s*([A-Z0-9-]*\d[A-Z0-9-]*)`
+
+var (
+	// ErrTemplateNotFound is returned when a template does not exist.
+	ErrTemplateNotFound = errors.New("template not found")
+	// ErrUnknownVariables is returned when a template references variables that cannot be rendered.
+	ErrUnknownVariables = errors.New("template references unknown variables")
+)
+
+// Template is a reusable reply with plain text and HTML variants.
+type Template struct {
+	ID                string    `json:"id"`
+	Name              string    `json:"name"`
+	Subject           string    `json:"subject,omitempty"`
+	TextBody          string    `json:"textBody"`
+	HTMLBody          string    `json:"htmlBody,omitempty"`
+	CaseNumberPattern string    `json:"caseNumberPattern,omitempty"`
+	CreatedAt         time.Time `json:"createdAt"`
+	UpdatedAt         time.Time `json:"updatedAt"`
+}
+
+// Rendered holds the output of rendering a template against a message.
+type Rendered struct {
+	Subject  string `json:"subject,omitempty"`
+	TextBody string `json:"textBody"`
+	HTMLBody string `json:"htmlBody,omitempty"`
+}
+
+// Validation reports which variables a template references.
+type Validation struct {
+	Valid     bool     `json:"valid"`
+	Variables []string `json:"variables"`
+	Unknown   []string `json:"unknown,omitempty"`
+}
+
+// Err returns an error wrapping ErrUnknownVariables that names the unknown
+// variables, or nil when the template is valid.
+func (v Validation) Err() error {
+	if v.Valid {
+		return nil
+	}
+	return fmt.Errorf("%w: %s", ErrUnknownVariables, strings.Join(v.Unknown, ", "))
+}
+
+// Repository defines the persistence contract for templates.
+type Repository interface {
+	Save(ctx context.Context, tpl Template) error
+	Get(ctx context.Context, id string) (*Template, error)
+	List(ctx context.Context) ([]Template, error)
+	Delete(ctx context.Context, id string) error
+}
+
+// TemplateService exposes template management and rendering.
+type TemplateService interface {
+	Create(ctx context.Context, tpl Template) (Template, error)
+	Update(ctx context.Context, id string, tpl Template) (Template, error)
+	Get(ctx context.Context, id string) (Template, error)
+	List(ctx context.Context) ([]Template, error)
+	Delete(ctx context.Context, id string) error
+	Preview(ctx context.Context, id, messageID string) (Rendered, error)
+	Validate(ctx context.Context, id string) (Validation, error)
+	Attachable(ctx context.Context, id string) error
+}
+
+var _ TemplateService = (*Service)(nil)
+
+// Service manages reply templates and renders them over cached messages.
+type Service struct {
+	repo     Repository
+	messages email.Repository
+	clock    email.Clock
+}
+
+// NewService constructs a template Service.
+func NewService(repo Repository, messages email.Repository, clock email.Clock) *Service {
+	if repo == nil {
+		panic("templates: repository dependency is required")
+	}
+	if messages == nil {
+		panic("templates: message repository dependency is required")
+	}
+	if clock == nil {
+		panic("templates: clock dependency is required")
+	}
+	return &Service{repo: repo, messages: messages, clock: clock}
+}
+
+// Create validates and stores a new template.
+func (s *Service) Create(ctx context.Context, tpl Template) (Template, error) {
+	if err := ctx.Err(); err != nil {
+		return Template{}, err
+	}
+
+	cleaned, err := normalize(tpl)
+	if err != nil {
+		return Template{}, err
+	}
+
+	id, err := newID()
+	if err != nil {
+		return Template{}, err
+	}
+	now := s.clock.Now().UTC()
+	cleaned.ID = id
+	cleaned.CreatedAt = now
+	cleaned.UpdatedAt = now
+
+	if err := s.repo.Save(ctx, cleaned); err != nil {
+		return Template{}, err
+	}
+	return cleaned, nil
+}
+
+// Update replaces the content of an existing template.
+func (s *Service) Update(ctx context.Context, id string, tpl Template) (Template, error) {
+	existing, err := s.Get(ctx, id)
+	if err != nil {
+		return Template{}, err
+	}
+
+	cleaned, err := normalize(tpl)
+	if err != nil {
+		return Template{}, err
+	}
+	cleaned.ID = existing.ID
+	cleaned.CreatedAt = existing.CreatedAt
+	cleaned.UpdatedAt = s.clock.Now().UTC()
+
+	if err := s.repo.Save(ctx, cleaned); err != nil {
+		return Template{}, err
+	}
+	return cleaned, nil
+}
+
+// Get returns the template with the supplied identifier.
+func (s *Service) Get(ctx context.Context, id string) (Template, error) {
+	if err := ctx.Err(); err != nil {
+		return Template{}, err
+	}
+	tpl, err := s.repo.Get(ctx, id)
+	if err != nil {
+		return Template{}, err
+	}
+	if tpl == nil {
+		return Template{}, ErrTemplateNotFound
+	}
+	return *tpl, nil
+}
+
+// List returns every stored template ordered by name.
+func (s *Service) List(ctx context.Context) ([]Template, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	list, err := s.repo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list, nil
+}
+
+// Delete removes a template.
+func (s *Service) Delete(ctx context.Context, id string) error {
+	if _, err := s.Get(ctx, id); err != nil {
+		return err
+	}
+	return s.repo.Delete(ctx, id)
+}
+
+// Preview renders the template against the cached message identified by messageID.
+func (s *Service) Preview(ctx context.Context, id, messageID string) (Rendered, error) {
+	tpl, err := s.Get(ctx, id)
+	if err != nil {
+		return Rendered{}, err
+	}
+	message, err := email.FindMessage(ctx, s.messages, messageID)
+	if err != nil {
+		return Rendered{}, err
+	}
+	return Render(tpl, message)
+}
+
+// Validate reports whether every variable referenced by the template is known.
+func (s *Service) Validate(ctx context.Context, id string) (Validation, error) {
+	tpl, err := s.Get(ctx, id)
+	if err != nil {
+		return Validation{}, err
+	}
+	return Validate(tpl)
+}
+
+// Attachable returns an error wrapping ErrUnknownVariables when the template
+// cannot yet be attached to an automation.
+func (s *Service) Attachable(ctx context.Context, id string) error {
+	result, err := s.Validate(ctx, id)
+	if err != nil {
+		return err
+	}
+	return result.Err()
+}
+
+func normalize(tpl Template) (Template, error) {
+	result := Template{
+		Name:              strings.TrimSpace(tpl.Name),
+		Subject:           strings.TrimSpace(tpl.Subject),
+		TextBody:          tpl.TextBody,
+		HTMLBody:          tpl.HTMLBody,
+		CaseNumberPattern: strings.TrimSpace(tpl.CaseNumberPattern),
+	}
+	if result.Name == "" {
+		return Template{}, errors.New("template name is required")
+	}
+	if strings.TrimSpace(result.TextBody) == "" && strings.TrimSpace(result.HTMLBody) == "" {
+		return Template{}, errors.New("template requires a text or html body")
+	}
+	if result.CaseNumberPattern != "" {
+		if _, err := regexp.Compile(result.CaseNumberPattern); err != nil {
+			return Template{}, fmt.Errorf("invalid case number pattern: %w", err)
+		}
+	}
+	if _, err := compile(result); err != nil {
+		return Template{}, err
+	}
+	return result, nil
+}
+
+func newID() (string, error) {
+	buf := make([]byte, 6)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("generate template id: %w", err)
+	}
+	return "tpl-" + hex.EncodeToString(buf), nil
+}
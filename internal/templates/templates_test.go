@@ -0,0 +1,191 @@
+package templates_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/example/iboz/internal/email"
+	emailmemory "github.com/example/iboz/internal/email/adapter/memory"
+	"github.com/example/iboz/internal/templates"
+	"github.com/example/iboz/internal/templates/adapter/memory"
+)
+
+type fixedClock struct {
+	now time.Time
+}
+
+func (f fixedClock) Now() time.Time {
+	return f.now
+}
+
+func TestVariablesForExtractsCaseNumber(t *testing.T) {
+	message := email.EmailMessage{
+		Subject: "Re: Case #48213 printer offline",
+		Sender:  "Dana Fox <dana@customer.com>",
+	}
+
+	vars := templates.VariablesFor(message, "")
+	if vars.CaseNumber != "48213" {
+		t.Fatalf("unexpected case number: %q", vars.CaseNumber)
+	}
+	if vars.SenderName != "Dana Fox" || vars.SenderEmail != "dana@customer.com" {
+		t.Fatalf("unexpected sender variables: %+v", vars)
+	}
+
+	vars = templates.VariablesFor(email.EmailMessage{Subject: "Ticket SR-7 update", Sender: "ops@example.com"}, `SR-(\d+)`)
+	if vars.CaseNumber != "7" {
+		t.Fatalf("custom pattern not applied: %q", vars.CaseNumber)
+	}
+	if vars.SenderName != "ops" {
+		t.Fatalf("expected local part fallback, got %q", vars.SenderName)
+	}
+}
+
+func TestRenderEscapesHTMLVariant(t *testing.T) {
+	tpl := templates.Template{
+		Subject:  "Case {{.CaseNumber}} received",
+		TextBody: "Hello {{.SenderName}}",
+		HTMLBody: "<p>Hello {{.SenderName}}</p>",
+	}
+	message := email.EmailMessage{Subject: "case 12", Sender: `"<b>Eve</b>" <eve@example.com>`}
+
+	rendered, err := templates.Render(tpl, message)
+	if err != nil {
+		t.Fatalf("render: %v", err)
+	}
+	if rendered.Subject != "Case 12 received" {
+		t.Fatalf("unexpected subject: %q", rendered.Subject)
+	}
+	if rendered.TextBody != "Hello <b>Eve</b>" {
+		t.Fatalf("text variant should not escape: %q", rendered.TextBody)
+	}
+	if rendered.HTMLBody != "<p>Hello &lt;b&gt;Eve&lt;/b&gt;</p>" {
+		t.Fatalf("html variant should escape: %q", rendered.HTMLBody)
+	}
+}
+
+func TestValidateFlagsUnknownVariables(t *testing.T) {
+	tpl := templates.Template{
+		TextBody: "{{if .CaseNumber}}Case {{$.CaseNumber}}{{end}} for {{.Customer.Name}}",
+		HTMLBody: "{{with .Subject}}{{.}}{{end}} {{.Priority}}",
+	}
+
+	result, err := templates.Validate(tpl)
+	if err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+	if result.Valid {
+		t.Fatalf("expected validation failure")
+	}
+	if !reflect.DeepEqual(result.Unknown, []string{"Customer", "Priority"}) {
+		t.Fatalf("unexpected unknown variables: %v", result.Unknown)
+	}
+	if !reflect.DeepEqual(result.Variables, []string{"CaseNumber", "Customer", "Priority", "Subject"}) {
+		t.Fatalf("unexpected variables: %v", result.Variables)
+	}
+}
+
+func TestValidateFollowsDotIntoWithAndRange(t *testing.T) {
+	tpl := templates.Template{
+		Subject:  "{{with .ReceivedAt}}{{.Year}}{{else}}{{.Subject}}{{end}}",
+		TextBody: "{{range .Items}}{{.SKU}} for {{$.SenderName}}{{end}}",
+	}
+
+	result, err := templates.Validate(tpl)
+	if err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+	if !reflect.DeepEqual(result.Unknown, []string{"Items"}) {
+		t.Fatalf("expected only the ranged field to be unknown, got %v", result.Unknown)
+	}
+	if !reflect.DeepEqual(result.Variables, []string{"Items", "ReceivedAt", "SenderName", "Subject"}) {
+		t.Fatalf("unexpected variables: %v", result.Variables)
+	}
+
+	tpl.TextBody = "{{with $when := .ReceivedAt}}{{.Month}} {{$when.Day}}{{end}}"
+	if result, err := templates.Validate(tpl); err != nil || !result.Valid {
+		t.Fatalf("expected fields of the with value to be valid, got %+v (%v)", result, err)
+	}
+}
+
+func TestValidateFollowsInvokedTemplates(t *testing.T) {
+	tpl := templates.Template{
+		TextBody: `{{define "greeting"}}Hi {{.Requester}}{{end}}{{template "greeting" .}}`,
+		HTMLBody: `{{block "footer" $}}<p>{{.Team}}</p>{{end}}{{define "date"}}{{.Year}} {{$.Year}}{{end}}{{template "date" .ReceivedAt}}`,
+	}
+
+	result, err := templates.Validate(tpl)
+	if err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+	if !reflect.DeepEqual(result.Unknown, []string{"Requester", "Team"}) {
+		t.Fatalf("expected the fields of invoked templates to be checked, got %v", result.Unknown)
+	}
+	if !reflect.DeepEqual(result.Variables, []string{"ReceivedAt", "Requester", "Team"}) {
+		t.Fatalf("unexpected variables: %v", result.Variables)
+	}
+	if _, err := templates.Render(templates.Template{TextBody: tpl.TextBody}, email.EmailMessage{}); !errors.Is(err, templates.ErrUnknownVariables) {
+		t.Fatalf("expected unknown variables error, got %v", err)
+	}
+
+	tpl = templates.Template{TextBody: `{{define "unused"}}{{.Priority}}{{end}}Hi {{.SenderName}}`}
+	if result, err := templates.Validate(tpl); err != nil || !reflect.DeepEqual(result.Unknown, []string{"Priority"}) {
+		t.Fatalf("expected defined templates to be checked, got %+v (%v)", result, err)
+	}
+}
+
+func TestRenderRefusesUnknownVariables(t *testing.T) {
+	tpl := templates.Template{TextBody: "Hi {{if false}}{{.Requester}}{{end}}{{.SenderName}}"}
+	if _, err := templates.Render(tpl, email.EmailMessage{Sender: "ada@example.com"}); !errors.Is(err, templates.ErrUnknownVariables) {
+		t.Fatalf("expected unknown variables error, got %v", err)
+	}
+}
+
+func TestServiceCRUDAndAttachable(t *testing.T) {
+	ctx := context.Background()
+	clock := fixedClock{now: time.Date(2025, time.March, 18, 12, 0, 0, 0, time.UTC)}
+	svc := templates.NewService(memory.NewRepository(), emailmemory.NewRepository(), clock)
+
+	if _, err := svc.Create(ctx, templates.Template{Name: "Empty"}); err == nil {
+		t.Fatalf("expected error for template without body")
+	}
+	if _, err := svc.Create(ctx, templates.Template{Name: "Broken", TextBody: "{{.SenderName"}); err == nil {
+		t.Fatalf("expected parse error")
+	}
+	if _, err := svc.Create(ctx, templates.Template{Name: "Bad regex", TextBody: "hi", CaseNumberPattern: "("}); err == nil {
+		t.Fatalf("expected regex error")
+	}
+
+	created, err := svc.Create(ctx, templates.Template{Name: " Ack ", TextBody: "Hi {{.Requester}}"})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if created.Name != "Ack" || !created.CreatedAt.Equal(clock.now) {
+		t.Fatalf("unexpected created template: %+v", created)
+	}
+
+	if err := svc.Attachable(ctx, created.ID); !errors.Is(err, templates.ErrUnknownVariables) {
+		t.Fatalf("expected unknown variables error, got %v", err)
+	}
+
+	if _, err := svc.Update(ctx, created.ID, templates.Template{Name: "Ack", TextBody: "Hi {{.SenderName}}"}); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if err := svc.Attachable(ctx, created.ID); err != nil {
+		t.Fatalf("expected template to be attachable: %v", err)
+	}
+
+	if _, err := svc.Preview(ctx, created.ID, "missing"); !errors.Is(err, email.ErrMessageNotFound) {
+		t.Fatalf("expected message not found, got %v", err)
+	}
+
+	if err := svc.Delete(ctx, created.ID); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if _, err := svc.Get(ctx, created.ID); !errors.Is(err, templates.ErrTemplateNotFound) {
+		t.Fatalf("expected not found, got %v", err)
+	}
+}
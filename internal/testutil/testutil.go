@@ -0,0 +1,113 @@
+// Package testutil holds the fixtures shared by the service tests: a settable
+// clock, a seeded mailbox, a recording sender and helpers to run an outbox
+// relay.
+package testutil
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/example/iboz/internal/email"
+	emailmemory "github.com/example/iboz/internal/email/adapter/memory"
+	"github.com/example/iboz/internal/outbox"
+)
+
+// Owner is the address of the mailbox owner seeded by Mailbox.
+const Owner = "me@example.com"
+
+// Start is the time the service tests begin at, a Tuesday morning.
+var Start = time.Date(2025, time.March, 18, 9, 0, 0, 0, time.UTC)
+
+var (
+	_ email.Clock      = (*Clock)(nil)
+	_ email.MailSender = (*RecordingSender)(nil)
+)
+
+// Clock is an email.Clock that only moves when a test moves it. It is safe for
+// concurrent use.
+type Clock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+// NewClock returns a clock reading now.
+func NewClock(now time.Time) *Clock {
+	return &Clock{now: now}
+}
+
+// Now implements the email.Clock interface.
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Set moves the clock to now.
+func (c *Clock) Set(now time.Time) {
+	c.mu.Lock()
+	c.now = now
+	c.mu.Unlock()
+}
+
+// Advance moves the clock forward by d.
+func (c *Clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	c.now = c.now.Add(d)
+	c.mu.Unlock()
+}
+
+// NewRelay builds a relay over store with the default outbox configuration.
+func NewRelay(store outbox.Store, deliverers map[string]outbox.Deliverer, clock email.Clock) *outbox.Relay {
+	return outbox.NewRelay([]outbox.Store{store}, deliverers, clock, outbox.Config{})
+}
+
+// Deliver runs relay once, failing t on a delivery error, and returns the
+// entries it relayed.
+func Deliver(t testing.TB, relay *outbox.Relay) []outbox.Entry {
+	t.Helper()
+	relayed, err := relay.Deliver(context.Background())
+	if err != nil {
+		t.Fatalf("deliver: %v", err)
+	}
+	return relayed
+}
+
+// Mailbox returns an in-memory mailbox owned by Owner holding messages, saved
+// at the time of clock.
+func Mailbox(t testing.TB, clock email.Clock, messages ...email.EmailMessage) *emailmemory.Repository {
+	t.Helper()
+	ctx := context.Background()
+	store := emailmemory.NewRepository()
+	if err := store.SaveAuth(ctx, email.AuthRecord{State: email.AuthState{Username: Owner}}); err != nil {
+		t.Fatalf("save auth: %v", err)
+	}
+	if len(messages) > 0 {
+		if err := store.SaveMessages(ctx, messages, clock.Now()); err != nil {
+			t.Fatalf("save messages: %v", err)
+		}
+	}
+	return store
+}
+
+// Message returns the stored message id, failing t when it is missing.
+func Message(t testing.TB, store email.Repository, id string) email.EmailMessage {
+	t.Helper()
+	message, err := email.FindMessage(context.Background(), store, id)
+	if err != nil {
+		t.Fatalf("find message %s: %v", id, err)
+	}
+	return message
+}
+
+// RecordingSender is an email sender that keeps what it sends.
+type RecordingSender struct {
+	Sent []email.OutgoingMessage
+}
+
+// Send implements the email.MailSender interface.
+func (r *RecordingSender) Send(_ context.Context, msg email.OutgoingMessage) error {
+	r.Sent = append(r.Sent, msg)
+	return nil
+}
@@ -0,0 +1,82 @@
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/example/iboz/internal/waiting"
+)
+
+var _ waiting.Repository = (*Repository)(nil)
+
+// Repository provides an in-memory implementation of the waiting.Repository port.
+type Repository struct {
+	mu      sync.RWMutex
+	threads map[string]waiting.Thread
+}
+
+// NewRepository builds a new in-memory thread repository.
+func NewRepository() *Repository {
+	return &Repository{threads: make(map[string]waiting.Thread)}
+}
+
+// Save inserts or replaces a thread.
+func (r *Repository) Save(ctx context.Context, thread waiting.Thread) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	r.threads[thread.ThreadID] = clone(thread)
+	r.mu.Unlock()
+	return nil
+}
+
+// Get returns the thread with the supplied identifier if present.
+func (r *Repository) Get(ctx context.Context, threadID string) (*waiting.Thread, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	thread, ok := r.threads[threadID]
+	if !ok {
+		return nil, nil
+	}
+	cloned := clone(thread)
+	return &cloned, nil
+}
+
+// List returns every tracked thread.
+func (r *Repository) List(ctx context.Context) ([]waiting.Thread, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	list := make([]waiting.Thread, 0, len(r.threads))
+	for _, thread := range r.threads {
+		list = append(list, clone(thread))
+	}
+	return list, nil
+}
+
+func clone(thread waiting.Thread) waiting.Thread {
+	thread.Recipients = append([]string(nil), thread.Recipients...)
+	thread.RepliedAt = cloneTime(thread.RepliedAt)
+	thread.RemindedAt = cloneTime(thread.RemindedAt)
+	return thread
+}
+
+func cloneTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	v := *t
+	return &v
+}
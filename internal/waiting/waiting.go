@@ -0,0 +1,242 @@
+package waiting
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/example/iboz/internal/calendar"
+	"github.com/example/iboz/internal/email"
+)
+
+// Status enumerates the state of a tracked conversation.
+type Status string
+
+const (
+	StatusWaiting Status = "waiting"
+	StatusReplied Status = "replied"
+)
+
+// DefaultFollowUpBusinessDays is used when Config.FollowUpBusinessDays is zero.
+const DefaultFollowUpBusinessDays = 3
+
+// ErrUnknownStatus is returned when filtering by an unsupported status.
+var ErrUnknownStatus = errors.New("unknown waiting status")
+
+// Thread is a conversation in which the user sent the most recent message.
+type Thread struct {
+	ThreadID      string     `json:"threadId"`
+	Subject       string     `json:"subject"`
+	Recipients    []string   `json:"recipients"`
+	LastMessageID string     `json:"lastMessageId"`
+	WaitingSince  time.Time  `json:"waitingSince"`
+	FollowUpAt    time.Time  `json:"followUpAt"`
+	Status        Status     `json:"status"`
+	RepliedAt     *time.Time `json:"repliedAt,omitempty"`
+	RemindedAt    *time.Time `json:"remindedAt,omitempty"`
+}
+
+// Config tunes reminder behaviour.
+type Config struct {
+	FollowUpBusinessDays int
+}
+
+// Repository defines the persistence contract for tracked threads.
+type Repository interface {
+	Save(ctx context.Context, thread Thread) error
+	Get(ctx context.Context, threadID string) (*Thread, error)
+	List(ctx context.Context) ([]Thread, error)
+}
+
+// Reminder is notified when a waiting thread is due for follow-up. It is optional.
+type Reminder interface {
+	Remind(ctx context.Context, thread Thread) error
+}
+
+// TrackerService exposes the waiting-for-reply queue.
+type TrackerService interface {
+	email.SyncListener
+	List(ctx context.Context, status Status) ([]Thread, error)
+	WaitingCount(ctx context.Context) (int, error)
+	CheckReminders(ctx context.Context) ([]Thread, error)
+}
+
+var _ TrackerService = (*Tracker)(nil)
+
+// Tracker derives waiting threads from synced inbox and sent messages.
+type Tracker struct {
+	repo      Repository
+	auth      email.Repository
+	reminder  Reminder
+	calendars calendar.Provider
+	clock     email.Clock
+	cfg       Config
+}
+
+// NewTracker constructs a Tracker. reminder may be nil to only flag due threads.
+// Follow-up dates count working days of the user's calendar.
+func NewTracker(repo Repository, auth email.Repository, reminder Reminder, calendars calendar.Provider, clock email.Clock, cfg Config) *Tracker {
+	if repo == nil {
+		panic("waiting: repository dependency is required")
+	}
+	if auth == nil {
+		panic("waiting: email repository dependency is required")
+	}
+	if calendars == nil {
+		panic("waiting: calendar dependency is required")
+	}
+	if clock == nil {
+		panic("waiting: clock dependency is required")
+	}
+	if cfg.FollowUpBusinessDays < 0 {
+		panic("waiting: follow-up business days cannot be negative")
+	}
+	if cfg.FollowUpBusinessDays == 0 {
+		cfg.FollowUpBusinessDays = DefaultFollowUpBusinessDays
+	}
+	return &Tracker{repo: repo, auth: auth, reminder: reminder, calendars: calendars, clock: clock, cfg: cfg}
+}
+
+// MessagesSynced implements email.SyncListener by re-evaluating every thread in the batch.
+func (t *Tracker) MessagesSynced(ctx context.Context, messages []email.EmailMessage, _ time.Time) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	auth, err := t.auth.GetAuth(ctx)
+	if err != nil {
+		return err
+	}
+	var user string
+	if auth != nil {
+		user = auth.State.Username
+	}
+	cal, err := t.calendars.Calendar(ctx)
+	if err != nil {
+		return err
+	}
+
+	threads := make(map[string][]email.EmailMessage)
+	for _, message := range messages {
+		key := message.ThreadKey()
+		threads[key] = append(threads[key], message)
+	}
+
+	for key, thread := range threads {
+		sort.Slice(thread, func(i, j int) bool { return thread[i].ReceivedAt.Before(thread[j].ReceivedAt) })
+		if err := t.evaluate(ctx, key, thread, user, cal); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t *Tracker) evaluate(ctx context.Context, key string, thread []email.EmailMessage, user string, cal *calendar.Calendar) error {
+	existing, err := t.repo.Get(ctx, key)
+	if err != nil {
+		return err
+	}
+
+	last := thread[len(thread)-1]
+	if last.SentBy(user) {
+		if existing != nil && existing.Status == StatusWaiting && existing.LastMessageID == last.ID {
+			return nil
+		}
+		return t.repo.Save(ctx, Thread{
+			ThreadID:      key,
+			Subject:       last.Subject,
+			Recipients:    append([]string(nil), last.Recipients...),
+			LastMessageID: last.ID,
+			WaitingSince:  last.ReceivedAt.UTC(),
+			FollowUpAt:    cal.AddWorkingDays(last.ReceivedAt, t.cfg.FollowUpBusinessDays),
+			Status:        StatusWaiting,
+		})
+	}
+
+	if existing == nil || existing.Status != StatusWaiting {
+		return nil
+	}
+	repliedAt := last.ReceivedAt.UTC()
+	existing.Status = StatusReplied
+	existing.RepliedAt = &repliedAt
+	return t.repo.Save(ctx, *existing)
+}
+
+// List returns tracked threads ordered by how long they have been waiting.
+func (t *Tracker) List(ctx context.Context, status Status) ([]Thread, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	all, err := t.repo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	list := make([]Thread, 0, len(all))
+	for _, thread := range all {
+		if status == "" || thread.Status == status {
+			list = append(list, thread)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].WaitingSince.Before(list[j].WaitingSince) })
+	return list, nil
+}
+
+// WaitingCount returns the number of threads awaiting a reply.
+func (t *Tracker) WaitingCount(ctx context.Context) (int, error) {
+	list, err := t.List(ctx, StatusWaiting)
+	if err != nil {
+		return 0, err
+	}
+	return len(list), nil
+}
+
+// CheckReminders flags waiting threads whose follow-up date has passed and
+// notifies the reminder once per thread. A thread that fails is retried on the
+// next check and does not hold back the others; the failures are joined.
+func (t *Tracker) CheckReminders(ctx context.Context) ([]Thread, error) {
+	list, err := t.List(ctx, StatusWaiting)
+	if err != nil {
+		return nil, err
+	}
+
+	now := t.clock.Now().UTC()
+	var (
+		due  []Thread
+		errs []error
+	)
+	for _, thread := range list {
+		if thread.RemindedAt != nil || now.Before(thread.FollowUpAt) {
+			continue
+		}
+		if t.reminder != nil {
+			if err := t.reminder.Remind(ctx, thread); err != nil {
+				errs = append(errs, fmt.Errorf("remind thread %s: %w", thread.ThreadID, err))
+				continue
+			}
+		}
+		remindedAt := now
+		thread.RemindedAt = &remindedAt
+		if err := t.repo.Save(ctx, thread); err != nil {
+			errs = append(errs, fmt.Errorf("remind thread %s: %w", thread.ThreadID, err))
+			continue
+		}
+		due = append(due, thread)
+	}
+	return due, errors.Join(errs...)
+}
+
+// ParseStatus validates a status filter; the empty string matches every thread.
+func ParseStatus(value string) (Status, error) {
+	switch Status(strings.ToLower(strings.TrimSpace(value))) {
+	case "":
+		return "", nil
+	case StatusWaiting:
+		return StatusWaiting, nil
+	case StatusReplied:
+		return StatusReplied, nil
+	default:
+		return "", ErrUnknownStatus
+	}
+}
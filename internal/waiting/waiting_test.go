@@ -0,0 +1,154 @@
+package waiting_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/example/iboz/internal/calendar"
+	"github.com/example/iboz/internal/email"
+	emailmemory "github.com/example/iboz/internal/email/adapter/memory"
+	"github.com/example/iboz/internal/testutil"
+	"github.com/example/iboz/internal/waiting"
+	"github.com/example/iboz/internal/waiting/adapter/memory"
+)
+
+type recordingReminder struct {
+	reminded []waiting.Thread
+}
+
+func (r *recordingReminder) Remind(_ context.Context, thread waiting.Thread) error {
+	r.reminded = append(r.reminded, thread)
+	return nil
+}
+
+func newTracker(t *testing.T, reminder waiting.Reminder, clock email.Clock) *waiting.Tracker {
+	t.Helper()
+	auth := emailmemory.NewRepository()
+	if err := auth.SaveAuth(context.Background(), email.AuthRecord{State: email.AuthState{Username: "me@example.com"}}); err != nil {
+		t.Fatalf("save auth: %v", err)
+	}
+	cal, err := calendar.New(calendar.DefaultHours(), clock)
+	if err != nil {
+		t.Fatalf("calendar: %v", err)
+	}
+	return waiting.NewTracker(memory.NewRepository(), auth, reminder, cal, clock, waiting.Config{FollowUpBusinessDays: 2})
+}
+
+func TestTrackerMarksWaitingUntilReply(t *testing.T) {
+	ctx := context.Background()
+	sentAt := time.Date(2025, time.March, 20, 9, 0, 0, 0, time.UTC) // Thursday
+	clock := testutil.NewClock(sentAt.Add(time.Hour))
+	reminder := &recordingReminder{}
+	tracker := newTracker(t, reminder, clock)
+
+	batch := []email.EmailMessage{
+		{ID: "in-1", Sender: "vendor@example.com", ThreadID: "t-quote", Subject: "Quote", ReceivedAt: sentAt.Add(-time.Hour)},
+		{ID: "out-1", Sender: "Me@example.com", ThreadID: "t-quote", Subject: "Re: Quote", ReceivedAt: sentAt, Recipients: []string{"vendor@example.com"}},
+		{ID: "out-2", Sender: "alias@example.com", Labels: []string{email.LabelSent}, MessageID: "<intro@example.com>", Subject: "Intro", ReceivedAt: sentAt},
+		{ID: "in-2", Sender: "news@example.com", Subject: "Newsletter", ReceivedAt: sentAt},
+	}
+	if err := tracker.MessagesSynced(ctx, batch, clock.Now()); err != nil {
+		t.Fatalf("messages synced: %v", err)
+	}
+
+	waitingThreads, err := tracker.List(ctx, waiting.StatusWaiting)
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if len(waitingThreads) != 2 {
+		t.Fatalf("expected two waiting threads, got %+v", waitingThreads)
+	}
+	var quote waiting.Thread
+	for _, thread := range waitingThreads {
+		if thread.ThreadID == "t-quote" {
+			quote = thread
+		}
+	}
+	wantFollowUp := time.Date(2025, time.March, 24, 9, 0, 0, 0, time.UTC) // Monday
+	if !quote.FollowUpAt.Equal(wantFollowUp) {
+		t.Fatalf("expected follow-up %s, got %s", wantFollowUp, quote.FollowUpAt)
+	}
+
+	clock.Set(wantFollowUp.Add(time.Minute))
+	due, err := tracker.CheckReminders(ctx)
+	if err != nil {
+		t.Fatalf("check reminders: %v", err)
+	}
+	if len(due) != 2 || len(reminder.reminded) != 2 {
+		t.Fatalf("expected two reminders, got %d", len(reminder.reminded))
+	}
+	if due, _ := tracker.CheckReminders(ctx); len(due) != 0 {
+		t.Fatalf("reminders should be raised once")
+	}
+
+	reply := []email.EmailMessage{
+		{ID: "in-3", Sender: "vendor@example.com", ThreadID: "t-quote", Subject: "Re: Quote", ReceivedAt: clock.Now()},
+	}
+	if err := tracker.MessagesSynced(ctx, reply, clock.Now()); err != nil {
+		t.Fatalf("messages synced: %v", err)
+	}
+
+	count, err := tracker.WaitingCount(ctx)
+	if err != nil || count != 1 {
+		t.Fatalf("expected one waiting thread after reply, got %d (%v)", count, err)
+	}
+	replied, _ := tracker.List(ctx, waiting.StatusReplied)
+	if len(replied) != 1 || replied[0].RepliedAt == nil {
+		t.Fatalf("expected replied thread, got %+v", replied)
+	}
+}
+
+// failingReminder fails for the threads in fail and records the others.
+type failingReminder struct {
+	recordingReminder
+	fail map[string]bool
+}
+
+func (f *failingReminder) Remind(ctx context.Context, thread waiting.Thread) error {
+	if f.fail[thread.ThreadID] {
+		return errors.New("endpoint unavailable")
+	}
+	return f.recordingReminder.Remind(ctx, thread)
+}
+
+func TestCheckRemindersContinuesPastFailures(t *testing.T) {
+	ctx := context.Background()
+	sentAt := time.Date(2025, time.March, 20, 9, 0, 0, 0, time.UTC)
+	clock := testutil.NewClock(sentAt)
+	reminder := &failingReminder{fail: map[string]bool{"t-a": true}}
+	tracker := newTracker(t, reminder, clock)
+
+	batch := []email.EmailMessage{
+		{ID: "out-a", Sender: "me@example.com", ThreadID: "t-a", Subject: "A", ReceivedAt: sentAt},
+		{ID: "out-b", Sender: "me@example.com", ThreadID: "t-b", Subject: "B", ReceivedAt: sentAt},
+	}
+	if err := tracker.MessagesSynced(ctx, batch, clock.Now()); err != nil {
+		t.Fatalf("messages synced: %v", err)
+	}
+
+	clock.Advance(7 * 24 * time.Hour)
+	due, err := tracker.CheckReminders(ctx)
+	if err == nil {
+		t.Fatal("expected the failing reminder to be reported")
+	}
+	if len(due) != 1 || due[0].ThreadID != "t-b" || len(reminder.reminded) != 1 {
+		t.Fatalf("expected the other thread to be reminded, got %+v", due)
+	}
+
+	reminder.fail = nil
+	due, err = tracker.CheckReminders(ctx)
+	if err != nil || len(due) != 1 || due[0].ThreadID != "t-a" {
+		t.Fatalf("expected the failed reminder to be retried, got %+v (%v)", due, err)
+	}
+}
+
+func TestParseStatus(t *testing.T) {
+	if status, err := waiting.ParseStatus(" Waiting "); err != nil || status != waiting.StatusWaiting {
+		t.Fatalf("unexpected parse result: %q (%v)", status, err)
+	}
+	if _, err := waiting.ParseStatus("snoozed"); err == nil {
+		t.Fatalf("expected error for unknown status")
+	}
+}
@@ -0,0 +1,238 @@
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/example/iboz/internal/outbox"
+	outboxmemory "github.com/example/iboz/internal/outbox/adapter/memory"
+	"github.com/example/iboz/internal/webhooks"
+)
+
+var _ webhooks.Repository = (*Repository)(nil)
+
+// Repository provides an in-memory implementation of the webhooks.Repository port.
+type Repository struct {
+	mu            sync.RWMutex
+	subscriptions map[string]webhooks.Subscription
+	deliveries    map[string]webhooks.Delivery
+	byOutbox      map[string]string
+	keys          map[string]struct{}
+	outbox        *outboxmemory.Table
+}
+
+// NewRepository builds a new in-memory webhook repository.
+func NewRepository() *Repository {
+	return &Repository{
+		subscriptions: make(map[string]webhooks.Subscription),
+		deliveries:    make(map[string]webhooks.Delivery),
+		byOutbox:      make(map[string]string),
+		keys:          make(map[string]struct{}),
+		outbox:        outboxmemory.NewTable(),
+	}
+}
+
+// SaveSubscription inserts or replaces a subscription.
+func (r *Repository) SaveSubscription(ctx context.Context, subscription webhooks.Subscription) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	r.subscriptions[subscription.ID] = cloneSubscription(subscription)
+	r.mu.Unlock()
+	return nil
+}
+
+// GetSubscription returns the subscription with the supplied identifier if present.
+func (r *Repository) GetSubscription(ctx context.Context, id string) (*webhooks.Subscription, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	subscription, ok := r.subscriptions[id]
+	if !ok {
+		return nil, nil
+	}
+	cloned := cloneSubscription(subscription)
+	return &cloned, nil
+}
+
+// ListSubscriptions returns every stored subscription.
+func (r *Repository) ListSubscriptions(ctx context.Context) ([]webhooks.Subscription, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	list := make([]webhooks.Subscription, 0, len(r.subscriptions))
+	for _, subscription := range r.subscriptions {
+		list = append(list, cloneSubscription(subscription))
+	}
+	return list, nil
+}
+
+// DeleteSubscription removes a subscription if present. Its delivery log is kept.
+func (r *Repository) DeleteSubscription(ctx context.Context, id string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	delete(r.subscriptions, id)
+	r.mu.Unlock()
+	return nil
+}
+
+// SaveDeliveries stores deliveries and appends effects to the outbox under the same lock.
+func (r *Repository) SaveDeliveries(ctx context.Context, key string, deliveries []webhooks.Delivery, effects ...outbox.Entry) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if key != "" {
+		if _, seen := r.keys[key]; seen {
+			return false, nil
+		}
+		r.keys[key] = struct{}{}
+	}
+	for _, delivery := range deliveries {
+		r.deliveries[delivery.ID] = cloneDelivery(delivery)
+		if delivery.OutboxID != "" {
+			r.byOutbox[delivery.OutboxID] = delivery.ID
+		}
+	}
+	r.outbox.Append(effects...)
+	return true, nil
+}
+
+// GetDelivery returns the delivery with the supplied identifier if present.
+func (r *Repository) GetDelivery(ctx context.Context, id string) (*webhooks.Delivery, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	delivery, ok := r.deliveries[id]
+	if !ok {
+		return nil, nil
+	}
+	cloned := cloneDelivery(delivery)
+	return &cloned, nil
+}
+
+// ListDeliveries returns the deliveries of a subscription, or all when subscriptionID is empty.
+func (r *Repository) ListDeliveries(ctx context.Context, subscriptionID string) ([]webhooks.Delivery, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	list := make([]webhooks.Delivery, 0)
+	for _, delivery := range r.deliveries {
+		if subscriptionID == "" || delivery.SubscriptionID == subscriptionID {
+			list = append(list, cloneDelivery(delivery))
+		}
+	}
+	return list, nil
+}
+
+// AppendAttempt adds an attempt to a delivery's log.
+func (r *Repository) AppendAttempt(ctx context.Context, deliveryID string, attempt webhooks.Attempt) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delivery, ok := r.deliveries[deliveryID]
+	if !ok {
+		return webhooks.ErrDeliveryNotFound
+	}
+	delivery.Attempts = append(delivery.Attempts, attempt)
+	r.deliveries[deliveryID] = delivery
+	return nil
+}
+
+// PendingEntries implements the outbox.Store interface.
+func (r *Repository) PendingEntries(ctx context.Context, now time.Time, limit int) ([]outbox.Entry, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.outbox.Pending(now, limit), nil
+}
+
+// UpdateEntry implements the outbox.Store interface and mirrors the entry's
+// status onto its delivery. A relayed entry whose last attempt logged an error
+// was dropped by the deliverer and counts as failed.
+func (r *Repository) UpdateEntry(ctx context.Context, entry outbox.Entry) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.outbox.Update(entry)
+	id, ok := r.byOutbox[entry.ID]
+	if !ok {
+		return nil
+	}
+	delivery := r.deliveries[id]
+	delivery.NextAttemptAt = nil
+	switch entry.Status {
+	case outbox.StatusDelivered:
+		if last := len(delivery.Attempts) - 1; last >= 0 && delivery.Attempts[last].Error != "" {
+			delivery.Status = webhooks.DeliveryFailed
+			break
+		}
+		delivery.Status = webhooks.DeliverySucceeded
+		delivery.DeliveredAt = cloneTime(entry.DeliveredAt)
+	case outbox.StatusFailed:
+		delivery.Status = webhooks.DeliveryFailed
+	default:
+		delivery.Status = webhooks.DeliveryPending
+		next := entry.NextAttemptAt
+		delivery.NextAttemptAt = &next
+	}
+	r.deliveries[id] = delivery
+	return nil
+}
+
+func cloneSubscription(subscription webhooks.Subscription) webhooks.Subscription {
+	subscription.Events = append([]string(nil), subscription.Events...)
+	return subscription
+}
+
+func cloneDelivery(delivery webhooks.Delivery) webhooks.Delivery {
+	delivery.Event.Data = append([]byte(nil), delivery.Event.Data...)
+	delivery.Attempts = append([]webhooks.Attempt{}, delivery.Attempts...)
+	delivery.NextAttemptAt = cloneTime(delivery.NextAttemptAt)
+	delivery.DeliveredAt = cloneTime(delivery.DeliveredAt)
+	return delivery
+}
+
+func cloneTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	v := *t
+	return &v
+}
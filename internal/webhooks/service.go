@@ -0,0 +1,558 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/example/iboz/internal/dashboard"
+	"github.com/example/iboz/internal/email"
+	"github.com/example/iboz/internal/outbox"
+	"github.com/example/iboz/internal/schedule"
+	"github.com/example/iboz/internal/sla"
+	"github.com/example/iboz/internal/waiting"
+)
+
+const (
+	defaultTimeout   = 10 * time.Second
+	maxResponseBytes = 64 << 10
+)
+
+// WebhookService exposes subscription management, publishing and the delivery log.
+type WebhookService interface {
+	CreateSubscription(ctx context.Context, req SubscriptionRequest) (*Subscription, error)
+	UpdateSubscription(ctx context.Context, id string, req SubscriptionRequest) (*Subscription, error)
+	GetSubscription(ctx context.Context, id string) (*Subscription, error)
+	ListSubscriptions(ctx context.Context) ([]Subscription, error)
+	DeleteSubscription(ctx context.Context, id string) error
+	Publish(ctx context.Context, eventType, key string, data any) (*Event, error)
+	Deliveries(ctx context.Context, subscriptionID string) ([]Delivery, error)
+	GetDelivery(ctx context.Context, id string) (*Delivery, error)
+	Replay(ctx context.Context, deliveryID string) (*Delivery, error)
+}
+
+var (
+	_ WebhookService     = (*Service)(nil)
+	_ sla.EventListener  = (*Service)(nil)
+	_ email.SyncListener = (*Service)(nil)
+)
+
+// Service manages subscriptions and queues signed deliveries through the outbox.
+type Service struct {
+	repo   Repository
+	client *http.Client
+	clock  email.Clock
+	gate   Gate
+}
+
+// NewService constructs a webhook Service. A nil client uses a client with a 10s timeout.
+func NewService(repo Repository, client *http.Client, clock email.Clock) *Service {
+	if repo == nil {
+		panic("webhooks: repository dependency is required")
+	}
+	if clock == nil {
+		panic("webhooks: clock dependency is required")
+	}
+	if client == nil {
+		client = &http.Client{Timeout: defaultTimeout}
+	}
+	return &Service{repo: repo, client: client, clock: clock}
+}
+
+// HoldWith routes published events through gate before they are delivered. It
+// must be called before events are published.
+func (s *Service) HoldWith(gate Gate) {
+	if gate == nil {
+		panic("webhooks: gate is nil")
+	}
+	s.gate = gate
+}
+
+// CreateSubscription validates and stores a subscription. The response carries
+// the secret, generated when none is supplied.
+func (s *Service) CreateSubscription(ctx context.Context, req SubscriptionRequest) (*Subscription, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	id, err := newID("whk-")
+	if err != nil {
+		return nil, err
+	}
+	if req.Secret == "" {
+		if req.Secret, err = newSecret(); err != nil {
+			return nil, err
+		}
+	}
+	now := s.clock.Now().UTC()
+	subscription := Subscription{ID: id, Active: true, CreatedAt: now}
+	if err := apply(&subscription, req, now); err != nil {
+		return nil, err
+	}
+	if err := s.repo.SaveSubscription(ctx, subscription); err != nil {
+		return nil, err
+	}
+	return &subscription, nil
+}
+
+// UpdateSubscription replaces the settings of a subscription. The secret is
+// only returned when the request rotates it.
+func (s *Service) UpdateSubscription(ctx context.Context, id string, req SubscriptionRequest) (*Subscription, error) {
+	subscription, err := s.subscription(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := apply(subscription, req, s.clock.Now().UTC()); err != nil {
+		return nil, err
+	}
+	if err := s.repo.SaveSubscription(ctx, *subscription); err != nil {
+		return nil, err
+	}
+	if req.Secret == "" {
+		subscription.Secret = ""
+	}
+	return subscription, nil
+}
+
+// GetSubscription returns a subscription without its secret.
+func (s *Service) GetSubscription(ctx context.Context, id string) (*Subscription, error) {
+	subscription, err := s.subscription(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	subscription.Secret = ""
+	return subscription, nil
+}
+
+// ListSubscriptions returns every subscription, oldest first, without secrets.
+func (s *Service) ListSubscriptions(ctx context.Context) ([]Subscription, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	list, err := s.repo.ListSubscriptions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i := range list {
+		list[i].Secret = ""
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].CreatedAt.Before(list[j].CreatedAt) })
+	return list, nil
+}
+
+// DeleteSubscription removes a subscription. Its pending deliveries are dropped
+// when they come up for delivery.
+func (s *Service) DeleteSubscription(ctx context.Context, id string) error {
+	if _, err := s.subscription(ctx, id); err != nil {
+		return err
+	}
+	return s.repo.DeleteSubscription(ctx, id)
+}
+
+// Publish queues a delivery of the event to every matching subscription, or
+// hands it to the gate to be released later. A non-empty key makes repeated
+// publications of the same occurrence no-ops, in which case the returned
+// event is nil.
+func (s *Service) Publish(ctx context.Context, eventType, key string, data any) (*Event, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if !knownEvent(eventType) {
+		return nil, fmt.Errorf("%w: unknown event type %q", ErrInvalidEvent, eventType)
+	}
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidEvent, err)
+	}
+	id, err := newID("evt-")
+	if err != nil {
+		return nil, err
+	}
+	event := Event{ID: id, Type: eventType, OccurredAt: s.clock.Now().UTC(), Data: raw, Key: key}
+	if s.gate != nil {
+		held, err := s.gate.Hold(ctx, event)
+		if err != nil {
+			return nil, err
+		}
+		if held {
+			return &event, nil
+		}
+	}
+	saved, err := s.fanOut(ctx, event)
+	if err != nil || !saved {
+		return nil, err
+	}
+	return &event, nil
+}
+
+// Release queues the deliveries of events held by the gate, keeping their IDs
+// and occurrence times. Events whose key was delivered meanwhile are skipped.
+func (s *Service) Release(ctx context.Context, events []Event) error {
+	for _, event := range events {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if _, err := s.fanOut(ctx, event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fanOut stores a delivery of event for every matching subscription and
+// reports false when its key was seen before.
+func (s *Service) fanOut(ctx context.Context, event Event) (bool, error) {
+	subscriptions, err := s.repo.ListSubscriptions(ctx)
+	if err != nil {
+		return false, err
+	}
+	var deliveries []Delivery
+	var effects []outbox.Entry
+	for _, subscription := range subscriptions {
+		if !subscription.Matches(event.Type) {
+			continue
+		}
+		delivery, entry, err := s.newDelivery(subscription, event)
+		if err != nil {
+			return false, err
+		}
+		deliveries = append(deliveries, delivery)
+		effects = append(effects, entry)
+	}
+	return s.repo.SaveDeliveries(ctx, event.Key, deliveries, effects...)
+}
+
+// Deliveries returns the delivery log, newest first, optionally for one subscription.
+func (s *Service) Deliveries(ctx context.Context, subscriptionID string) ([]Delivery, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if subscriptionID != "" {
+		if _, err := s.subscription(ctx, subscriptionID); err != nil {
+			return nil, err
+		}
+	}
+	list, err := s.repo.ListDeliveries(ctx, subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].CreatedAt.After(list[j].CreatedAt) })
+	return list, nil
+}
+
+// GetDelivery returns a delivery with its attempts.
+func (s *Service) GetDelivery(ctx context.Context, id string) (*Delivery, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	delivery, err := s.repo.GetDelivery(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if delivery == nil {
+		return nil, ErrDeliveryNotFound
+	}
+	return delivery, nil
+}
+
+// Replay queues a new delivery of a past delivery's event to the current URL
+// and secret of its subscription.
+func (s *Service) Replay(ctx context.Context, deliveryID string) (*Delivery, error) {
+	original, err := s.GetDelivery(ctx, deliveryID)
+	if err != nil {
+		return nil, err
+	}
+	subscription, err := s.subscription(ctx, original.SubscriptionID)
+	if err != nil {
+		return nil, err
+	}
+	delivery, entry, err := s.newDelivery(*subscription, original.Event)
+	if err != nil {
+		return nil, err
+	}
+	delivery.ReplayOf = original.ID
+	if _, err := s.repo.SaveDeliveries(ctx, "", []Delivery{delivery}, entry); err != nil {
+		return nil, err
+	}
+	return &delivery, nil
+}
+
+// delivery is the outbox payload of a webhook delivery.
+type delivery struct {
+	DeliveryID string `json:"deliveryId"`
+}
+
+// Deliverer returns the outbox deliverer that signs and posts deliveries and
+// logs each attempt. Deliveries of deleted subscriptions are dropped.
+func (s *Service) Deliverer() outbox.Deliverer {
+	return outbox.DelivererFunc(func(ctx context.Context, entry outbox.Entry) error {
+		var payload delivery
+		if err := json.Unmarshal(entry.Payload, &payload); err != nil {
+			return fmt.Errorf("decode webhook delivery: %w", err)
+		}
+		d, err := s.GetDelivery(ctx, payload.DeliveryID)
+		if err != nil {
+			return err
+		}
+		subscription, err := s.repo.GetSubscription(ctx, d.SubscriptionID)
+		if err != nil {
+			return err
+		}
+		if subscription == nil {
+			return s.repo.AppendAttempt(ctx, d.ID, Attempt{At: s.clock.Now().UTC(), Error: ErrSubscriptionNotFound.Error()})
+		}
+
+		attempt, sendErr := s.send(ctx, *d, subscription.Secret)
+		if err := s.repo.AppendAttempt(ctx, d.ID, attempt); err != nil {
+			return errors.Join(sendErr, err)
+		}
+		return sendErr
+	})
+}
+
+func (s *Service) send(ctx context.Context, d Delivery, secret string) (Attempt, error) {
+	started := s.clock.Now().UTC()
+	attempt := Attempt{At: started}
+	body, err := json.Marshal(d.Event)
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.URL, bytes.NewReader(body))
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt, err
+	}
+	timestamp := started.Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, d.Event.Type)
+	req.Header.Set(DeliveryHeader, d.ID)
+	req.Header.Set(TimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(SignatureHeader, "sha256="+Sign(secret, timestamp, body))
+
+	resp, err := s.client.Do(req)
+	attempt.DurationMS = s.clock.Now().Sub(started).Milliseconds()
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt, fmt.Errorf("webhooks: post delivery: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, maxResponseBytes))
+
+	attempt.StatusCode = resp.StatusCode
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		err := fmt.Errorf("webhooks: unexpected status %d", resp.StatusCode)
+		attempt.Error = err.Error()
+		return attempt, err
+	}
+	return attempt, nil
+}
+
+// SLAEvent implements the sla.EventListener interface, publishing sla.warning
+// and sla.breached.
+func (s *Service) SLAEvent(ctx context.Context, event sla.Event) error {
+	key := fmt.Sprintf("%s:%s:%d", event.Type, event.Deadline.MessageID, event.Deadline.DueAt.Unix())
+	_, err := s.Publish(ctx, string(event.Type), key, event)
+	return err
+}
+
+// Remind implements the waiting.Reminder interface, publishing
+// waiting.follow_up_due once per sent message left without a reply.
+func (s *Service) Remind(ctx context.Context, thread waiting.Thread) error {
+	key := EventFollowUpDue + ":" + thread.ThreadID + ":" + thread.LastMessageID
+	_, err := s.Publish(ctx, EventFollowUpDue, key, thread)
+	return err
+}
+
+// RunsCompleted publishes automation.run.completed once per finished reply,
+// task or CRM run, as listed by the dashboard run sources.
+func (s *Service) RunsCompleted(ctx context.Context, runs []dashboard.Run) error {
+	var errs []error
+	for _, run := range runs {
+		key := fmt.Sprintf("%s:%s:%s:%d", EventAutomationRunComplete, run.Kind, run.MessageID, run.CompletedAt.UnixNano())
+		if _, err := s.Publish(ctx, EventAutomationRunComplete, key, run); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// ApprovalsRequested publishes approval.requested once per scheduled action
+// held for approval. Other actions are skipped.
+func (s *Service) ApprovalsRequested(ctx context.Context, actions []schedule.Action) error {
+	var errs []error
+	for _, action := range actions {
+		if action.Status != schedule.StatusAwaitingApproval {
+			continue
+		}
+		key := EventApprovalRequested + ":" + action.ID
+		if _, err := s.Publish(ctx, EventApprovalRequested, key, action); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// classification is the data of message.classified events.
+type classification struct {
+	MessageID  string    `json:"messageId"`
+	Subject    string    `json:"subject"`
+	Sender     string    `json:"sender"`
+	Category   string    `json:"category"`
+	Importance string    `json:"importance"`
+	ReceivedAt time.Time `json:"receivedAt"`
+}
+
+// MessagesSynced implements the email.SyncListener interface, publishing
+// message.classified once per message and category.
+func (s *Service) MessagesSynced(ctx context.Context, messages []email.EmailMessage, _ time.Time) error {
+	var errs []error
+	for _, message := range messages {
+		if message.Category == "" {
+			continue
+		}
+		data := classification{
+			MessageID:  message.ID,
+			Subject:    message.Subject,
+			Sender:     message.Sender,
+			Category:   message.Category,
+			Importance: message.Importance,
+			ReceivedAt: message.ReceivedAt,
+		}
+		key := EventMessageClassified + ":" + message.ID + ":" + message.Category
+		if _, err := s.Publish(ctx, EventMessageClassified, key, data); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (s *Service) subscription(ctx context.Context, id string) (*Subscription, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	subscription, err := s.repo.GetSubscription(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if subscription == nil {
+		return nil, ErrSubscriptionNotFound
+	}
+	return subscription, nil
+}
+
+func (s *Service) newDelivery(subscription Subscription, event Event) (Delivery, outbox.Entry, error) {
+	id, err := newID("whd-")
+	if err != nil {
+		return Delivery{}, outbox.Entry{}, err
+	}
+	now := s.clock.Now().UTC()
+	entry, err := outbox.NewEntry(OutboxDestination, "webhooks:"+id, delivery{DeliveryID: id}, now)
+	if err != nil {
+		return Delivery{}, outbox.Entry{}, err
+	}
+	return Delivery{
+		ID:             id,
+		SubscriptionID: subscription.ID,
+		Event:          event,
+		URL:            subscription.URL,
+		Status:         DeliveryPending,
+		NextAttemptAt:  &now,
+		CreatedAt:      now,
+		OutboxID:       entry.ID,
+	}, entry, nil
+}
+
+// apply validates req and copies it onto subscription.
+func apply(subscription *Subscription, req SubscriptionRequest, now time.Time) error {
+	target, err := url.Parse(strings.TrimSpace(req.URL))
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return fmt.Errorf("%w: url must be an absolute http(s) URL", ErrInvalidSubscription)
+	}
+	if len(req.Events) == 0 {
+		return fmt.Errorf("%w: at least one event type is required", ErrInvalidSubscription)
+	}
+	events := make([]string, 0, len(req.Events))
+	seen := make(map[string]bool)
+	for _, event := range req.Events {
+		event = strings.TrimSpace(event)
+		if event != WildcardEvent && !knownEvent(event) {
+			return fmt.Errorf("%w: unknown event type %q", ErrInvalidSubscription, event)
+		}
+		if !seen[event] {
+			seen[event] = true
+			events = append(events, event)
+		}
+	}
+
+	subscription.URL = target.String()
+	subscription.Events = events
+	subscription.Description = strings.TrimSpace(req.Description)
+	if req.Secret != "" {
+		subscription.Secret = req.Secret
+	}
+	if req.Active != nil {
+		subscription.Active = *req.Active
+	}
+	subscription.UpdatedAt = now
+	return nil
+}
+
+func knownEvent(eventType string) bool {
+	for _, known := range EventTypes {
+		if known == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// Sign returns the hex HMAC-SHA256 of "<timestamp>.<body>" under secret.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10) + "."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks the signature headers of a delivery, rejecting timestamps more
+// than tolerance away from now. Receivers written in Go can use it directly.
+func Verify(secret string, header http.Header, body []byte, now time.Time, tolerance time.Duration) bool {
+	timestamp, err := strconv.ParseInt(header.Get(TimestampHeader), 10, 64)
+	if err != nil {
+		return false
+	}
+	if skew := now.Sub(time.Unix(timestamp, 0)); skew > tolerance || skew < -tolerance {
+		return false
+	}
+	signature := strings.TrimPrefix(header.Get(SignatureHeader), "sha256=")
+	return hmac.Equal([]byte(signature), []byte(Sign(secret, timestamp, body)))
+}
+
+func newID(prefix string) (string, error) {
+	buf := make([]byte, 6)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("generate webhook id: %w", err)
+	}
+	return prefix + hex.EncodeToString(buf), nil
+}
+
+func newSecret() (string, error) {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("generate webhook secret: %w", err)
+	}
+	return "whsec_" + hex.EncodeToString(buf), nil
+}
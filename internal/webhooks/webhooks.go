@@ -0,0 +1,163 @@
+// Package webhooks delivers platform events to subscribed endpoints with
+// HMAC-SHA256 signatures, keeping a log of every delivery attempt.
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/example/iboz/internal/outbox"
+)
+
+// Event types that subscriptions can select. WildcardEvent selects all of them.
+const (
+	EventMessageClassified     = "message.classified"
+	EventAutomationRunComplete = "automation.run.completed"
+	EventApprovalRequested     = "approval.requested"
+	EventSLAWarning            = "sla.warning"
+	EventSLABreached           = "sla.breached"
+	EventDigestReady           = "digest.ready"
+	EventFollowUpDue           = "waiting.follow_up_due"
+	WildcardEvent              = "*"
+)
+
+// EventTypes lists the events published by the platform.
+var EventTypes = []string{
+	EventMessageClassified,
+	EventAutomationRunComplete,
+	EventApprovalRequested,
+	EventSLAWarning,
+	EventSLABreached,
+	EventDigestReady,
+	EventFollowUpDue,
+}
+
+// OutboxDestination is the outbox destination of webhook deliveries, delivered
+// by the Service's Deliverer.
+const OutboxDestination = "webhooks.delivery"
+
+// Headers sent with every delivery. SignatureHeader holds "sha256=<hex>", the
+// HMAC-SHA256 under the subscription secret of "<timestamp>.<body>".
+const (
+	EventHeader     = "X-Iboz-Event"
+	DeliveryHeader  = "X-Iboz-Delivery"
+	TimestampHeader = "X-Iboz-Timestamp"
+	SignatureHeader = "X-Iboz-Signature"
+)
+
+var (
+	// ErrSubscriptionNotFound is returned when a subscription does not exist.
+	ErrSubscriptionNotFound = errors.New("webhook subscription not found")
+	// ErrDeliveryNotFound is returned when a delivery does not exist.
+	ErrDeliveryNotFound = errors.New("webhook delivery not found")
+	// ErrInvalidSubscription is returned when a subscription fails validation.
+	ErrInvalidSubscription = errors.New("invalid webhook subscription")
+	// ErrInvalidEvent is returned when an event cannot be published.
+	ErrInvalidEvent = errors.New("invalid webhook event")
+)
+
+// Subscription routes selected events to an endpoint. Secret is only returned
+// when it is set or generated.
+type Subscription struct {
+	ID          string    `json:"id"`
+	URL         string    `json:"url"`
+	Events      []string  `json:"events"`
+	Secret      string    `json:"secret,omitempty"`
+	Description string    `json:"description,omitempty"`
+	Active      bool      `json:"active"`
+	CreatedAt   time.Time `json:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+// Matches reports whether the subscription receives events of eventType.
+func (s Subscription) Matches(eventType string) bool {
+	if !s.Active {
+		return false
+	}
+	for _, selected := range s.Events {
+		if selected == eventType || selected == WildcardEvent {
+			return true
+		}
+	}
+	return false
+}
+
+// SubscriptionRequest creates or replaces a subscription. An empty Secret
+// generates one on creation and keeps the current one on update; Active
+// defaults to true.
+type SubscriptionRequest struct {
+	URL         string   `json:"url"`
+	Events      []string `json:"events"`
+	Secret      string   `json:"secret"`
+	Description string   `json:"description"`
+	Active      *bool    `json:"active"`
+}
+
+// Event is a platform occurrence delivered as the JSON request body. Key
+// deduplicates publications of the same occurrence.
+type Event struct {
+	ID         string          `json:"id"`
+	Type       string          `json:"type"`
+	OccurredAt time.Time       `json:"occurredAt"`
+	Data       json.RawMessage `json:"data"`
+	Key        string          `json:"-"`
+}
+
+// DeliveryStatus enumerates the outcome of a delivery.
+type DeliveryStatus string
+
+const (
+	DeliveryPending   DeliveryStatus = "pending"
+	DeliverySucceeded DeliveryStatus = "succeeded"
+	DeliveryFailed    DeliveryStatus = "failed"
+)
+
+// Attempt logs one request of a delivery.
+type Attempt struct {
+	At         time.Time `json:"at"`
+	StatusCode int       `json:"statusCode,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	DurationMS int64     `json:"durationMs"`
+}
+
+// Delivery is an event sent to one subscription, retried with backoff until it
+// succeeds or exhausts its attempts. ReplayOf names the delivery it replays.
+type Delivery struct {
+	ID             string         `json:"id"`
+	SubscriptionID string         `json:"subscriptionId"`
+	Event          Event          `json:"event"`
+	URL            string         `json:"url"`
+	Status         DeliveryStatus `json:"status"`
+	Attempts       []Attempt      `json:"attempts"`
+	NextAttemptAt  *time.Time     `json:"nextAttemptAt,omitempty"`
+	ReplayOf       string         `json:"replayOf,omitempty"`
+	CreatedAt      time.Time      `json:"createdAt"`
+	DeliveredAt    *time.Time     `json:"deliveredAt,omitempty"`
+	OutboxID       string         `json:"-"`
+}
+
+// Gate holds events back from delivery, such as during a focus session. Held
+// events are handed back through Service.Release.
+type Gate interface {
+	Hold(ctx context.Context, event Event) (bool, error)
+}
+
+// Repository defines the persistence contract for subscriptions and deliveries.
+// It owns the outbox table of pending deliveries and keeps each delivery's
+// status in step with its outbox entry.
+type Repository interface {
+	outbox.Store
+	SaveSubscription(ctx context.Context, subscription Subscription) error
+	GetSubscription(ctx context.Context, id string) (*Subscription, error)
+	ListSubscriptions(ctx context.Context) ([]Subscription, error)
+	DeleteSubscription(ctx context.Context, id string) error
+	// SaveDeliveries stores deliveries and appends effects to the outbox
+	// atomically. It stores nothing and returns false when key was seen before.
+	SaveDeliveries(ctx context.Context, key string, deliveries []Delivery, effects ...outbox.Entry) (bool, error)
+	GetDelivery(ctx context.Context, id string) (*Delivery, error)
+	// ListDeliveries returns the deliveries of a subscription, or all when subscriptionID is empty.
+	ListDeliveries(ctx context.Context, subscriptionID string) ([]Delivery, error)
+	AppendAttempt(ctx context.Context, deliveryID string, attempt Attempt) error
+}
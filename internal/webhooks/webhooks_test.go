@@ -0,0 +1,311 @@
+package webhooks_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/example/iboz/internal/dashboard"
+	"github.com/example/iboz/internal/email"
+	"github.com/example/iboz/internal/outbox"
+	"github.com/example/iboz/internal/schedule"
+	"github.com/example/iboz/internal/sla"
+	"github.com/example/iboz/internal/testutil"
+	"github.com/example/iboz/internal/waiting"
+	"github.com/example/iboz/internal/webhooks"
+	"github.com/example/iboz/internal/webhooks/adapter/memory"
+)
+
+// receiver records verified deliveries and answers with the queued statuses.
+type receiver struct {
+	t      *testing.T
+	secret string
+	clock  *testutil.Clock
+
+	mu       sync.Mutex
+	statuses []int
+	events   []string
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := io.ReadAll(req.Body)
+	if !webhooks.Verify(r.secret, req.Header, body, r.clock.Now(), 5*time.Minute) {
+		r.t.Errorf("delivery %s failed signature verification", req.Header.Get(webhooks.DeliveryHeader))
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, req.Header.Get(webhooks.EventHeader))
+	status := http.StatusNoContent
+	if len(r.statuses) > 0 {
+		status, r.statuses = r.statuses[0], r.statuses[1:]
+	}
+	w.WriteHeader(status)
+}
+
+func (r *receiver) received() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.events...)
+}
+
+type fixture struct {
+	service *webhooks.Service
+	relay   *outbox.Relay
+	clock   *testutil.Clock
+	hook    *receiver
+	url     string
+}
+
+func newFixture(t *testing.T, cfg outbox.Config, statuses ...int) fixture {
+	t.Helper()
+	clock := testutil.NewClock(testutil.Start)
+	hook := &receiver{t: t, secret: "whsec_test", clock: clock, statuses: statuses}
+	srv := httptest.NewServer(hook)
+	t.Cleanup(srv.Close)
+
+	repo := memory.NewRepository()
+	service := webhooks.NewService(repo, nil, clock)
+	relay := outbox.NewRelay([]outbox.Store{repo}, map[string]outbox.Deliverer{
+		webhooks.OutboxDestination: service.Deliverer(),
+	}, clock, cfg)
+	return fixture{service: service, relay: relay, clock: clock, hook: hook, url: srv.URL}
+}
+
+func (f fixture) subscribe(t *testing.T, events ...string) *webhooks.Subscription {
+	t.Helper()
+	subscription, err := f.service.CreateSubscription(context.Background(), webhooks.SubscriptionRequest{URL: f.url, Events: events, Secret: f.hook.secret})
+	if err != nil {
+		t.Fatalf("create subscription: %v", err)
+	}
+	return subscription
+}
+
+func TestPublishRetriesWithBackoffAndLogsAttempts(t *testing.T) {
+	ctx := context.Background()
+	f := newFixture(t, outbox.Config{}, http.StatusInternalServerError)
+	subscription := f.subscribe(t, webhooks.EventDigestReady)
+
+	if _, err := f.service.Publish(ctx, webhooks.EventFollowUpDue, "", map[string]string{"digestId": "dig-1"}); err != nil {
+		t.Fatalf("publish unmatched: %v", err)
+	}
+	event, err := f.service.Publish(ctx, webhooks.EventDigestReady, "", map[string]string{"digestId": "dig-1"})
+	if err != nil {
+		t.Fatalf("publish: %v", err)
+	}
+
+	if _, err := f.relay.Deliver(ctx); err == nil {
+		t.Fatal("expected the first attempt to fail")
+	}
+	deliveries, err := f.service.Deliveries(ctx, subscription.ID)
+	if err != nil || len(deliveries) != 1 {
+		t.Fatalf("expected one delivery, got %v %v", deliveries, err)
+	}
+	delivery := deliveries[0]
+	if delivery.Event.ID != event.ID || delivery.Status != webhooks.DeliveryPending || len(delivery.Attempts) != 1 || delivery.Attempts[0].StatusCode != http.StatusInternalServerError {
+		t.Fatalf("unexpected delivery after failure: %+v", delivery)
+	}
+	if delivery.NextAttemptAt == nil || !delivery.NextAttemptAt.Equal(f.clock.Now().Add(outbox.DefaultBaseBackoff)) {
+		t.Fatalf("expected retry after the base backoff, got %v", delivery.NextAttemptAt)
+	}
+
+	testutil.Deliver(t, f.relay)
+	f.clock.Advance(outbox.DefaultBaseBackoff)
+	testutil.Deliver(t, f.relay)
+	got, err := f.service.GetDelivery(ctx, delivery.ID)
+	if err != nil {
+		t.Fatalf("get delivery: %v", err)
+	}
+	if got.Status != webhooks.DeliverySucceeded || len(got.Attempts) != 2 || got.DeliveredAt == nil || got.NextAttemptAt != nil {
+		t.Fatalf("unexpected delivery after retry: %+v", got)
+	}
+	if received := f.hook.received(); len(received) != 2 || received[1] != webhooks.EventDigestReady {
+		t.Fatalf("unexpected received events: %v", received)
+	}
+}
+
+func TestDeliveryFailsAfterMaxAttempts(t *testing.T) {
+	ctx := context.Background()
+	f := newFixture(t, outbox.Config{MaxAttempts: 2, BaseBackoff: time.Second}, http.StatusBadGateway, http.StatusBadGateway)
+	f.subscribe(t, webhooks.WildcardEvent)
+	if _, err := f.service.Publish(ctx, webhooks.EventFollowUpDue, "", nil); err != nil {
+		t.Fatalf("publish: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		f.relay.Deliver(ctx)
+		f.clock.Advance(time.Minute)
+	}
+	deliveries, _ := f.service.Deliveries(ctx, "")
+	if len(deliveries) != 1 || deliveries[0].Status != webhooks.DeliveryFailed || len(deliveries[0].Attempts) != 2 {
+		t.Fatalf("expected a failed delivery after two attempts, got %+v", deliveries)
+	}
+}
+
+func TestListenersPublishOncePerOccurrence(t *testing.T) {
+	ctx := context.Background()
+	f := newFixture(t, outbox.Config{})
+	f.subscribe(t, webhooks.EventMessageClassified, webhooks.EventSLABreached, webhooks.EventFollowUpDue, webhooks.EventAutomationRunComplete, webhooks.EventApprovalRequested)
+
+	messages := []email.EmailMessage{
+		{ID: "msg-1", Subject: "Invoice", Category: "finance"},
+		{ID: "msg-2", Subject: "Hello"},
+	}
+	for i := 0; i < 2; i++ {
+		if err := f.service.MessagesSynced(ctx, messages, f.clock.Now()); err != nil {
+			t.Fatalf("messages synced: %v", err)
+		}
+	}
+	messages[0].Category = "action"
+	if err := f.service.MessagesSynced(ctx, messages, f.clock.Now()); err != nil {
+		t.Fatalf("messages synced: %v", err)
+	}
+	breach := sla.Event{Type: sla.EventBreached, At: f.clock.Now(), Deadline: sla.Deadline{MessageID: "msg-1", DueAt: f.clock.Now()}}
+	for i := 0; i < 2; i++ {
+		if err := f.service.SLAEvent(ctx, breach); err != nil {
+			t.Fatalf("sla event: %v", err)
+		}
+	}
+	if err := f.service.SLAEvent(ctx, sla.Event{Type: sla.EventWarning, Deadline: breach.Deadline}); err != nil {
+		t.Fatalf("sla warning: %v", err)
+	}
+	thread := waiting.Thread{ThreadID: "thread-1", LastMessageID: "sent-1", Status: waiting.StatusWaiting}
+	for i := 0; i < 2; i++ {
+		if err := f.service.Remind(ctx, thread); err != nil {
+			t.Fatalf("remind: %v", err)
+		}
+	}
+
+	runs := []dashboard.Run{
+		{Kind: "reply", MessageID: "msg-1", CompletedAt: f.clock.Now()},
+		{Kind: "task", MessageID: "msg-1", CompletedAt: f.clock.Now()},
+	}
+	actions := []schedule.Action{
+		{ID: "act-1", Type: "reply", Status: schedule.StatusAwaitingApproval},
+		{ID: "act-2", Type: "reply", Status: schedule.StatusScheduled},
+	}
+	for i := 0; i < 2; i++ {
+		if err := f.service.RunsCompleted(ctx, runs); err != nil {
+			t.Fatalf("runs completed: %v", err)
+		}
+		if err := f.service.ApprovalsRequested(ctx, actions); err != nil {
+			t.Fatalf("approvals requested: %v", err)
+		}
+	}
+
+	testutil.Deliver(t, f.relay)
+	received := f.hook.received()
+	counts := map[string]int{}
+	for _, event := range received {
+		counts[event]++
+	}
+	if len(received) != 7 || counts[webhooks.EventMessageClassified] != 2 || counts[webhooks.EventSLABreached] != 1 || counts[webhooks.EventFollowUpDue] != 1 ||
+		counts[webhooks.EventAutomationRunComplete] != 2 || counts[webhooks.EventApprovalRequested] != 1 {
+		t.Fatalf("unexpected deliveries: %v", received)
+	}
+}
+
+func TestReplayAndDeletedSubscriptions(t *testing.T) {
+	ctx := context.Background()
+	f := newFixture(t, outbox.Config{})
+	subscription := f.subscribe(t, webhooks.EventFollowUpDue)
+	if _, err := f.service.Publish(ctx, webhooks.EventFollowUpDue, "", nil); err != nil {
+		t.Fatalf("publish: %v", err)
+	}
+	f.relay.Deliver(ctx)
+	deliveries, _ := f.service.Deliveries(ctx, subscription.ID)
+
+	replay, err := f.service.Replay(ctx, deliveries[0].ID)
+	if err != nil {
+		t.Fatalf("replay: %v", err)
+	}
+	if replay.ReplayOf != deliveries[0].ID || replay.Event.ID != deliveries[0].Event.ID || replay.ID == deliveries[0].ID {
+		t.Fatalf("unexpected replay: %+v", replay)
+	}
+	f.relay.Deliver(ctx)
+	if received := f.hook.received(); len(received) != 2 {
+		t.Fatalf("expected the replay to be delivered, got %v", received)
+	}
+
+	f.clock.Advance(time.Minute)
+	if _, err := f.service.Publish(ctx, webhooks.EventFollowUpDue, "", nil); err != nil {
+		t.Fatalf("publish: %v", err)
+	}
+	if err := f.service.DeleteSubscription(ctx, subscription.ID); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	testutil.Deliver(t, f.relay)
+	if received := f.hook.received(); len(received) != 2 {
+		t.Fatalf("expected no delivery to a deleted subscription, got %v", received)
+	}
+	deliveries, _ = f.service.Deliveries(ctx, "")
+	if dropped := deliveries[0]; dropped.Status != webhooks.DeliveryFailed || dropped.Attempts[0].Error == "" {
+		t.Fatalf("expected the dropped delivery to be logged as failed, got %+v", dropped)
+	}
+	if _, err := f.service.Replay(ctx, deliveries[0].ID); !errors.Is(err, webhooks.ErrSubscriptionNotFound) {
+		t.Fatalf("expected ErrSubscriptionNotFound, got %v", err)
+	}
+	if _, err := f.service.Replay(ctx, "whd-missing"); !errors.Is(err, webhooks.ErrDeliveryNotFound) {
+		t.Fatalf("expected ErrDeliveryNotFound, got %v", err)
+	}
+}
+
+func TestSubscriptionValidationAndSecrets(t *testing.T) {
+	ctx := context.Background()
+	f := newFixture(t, outbox.Config{})
+
+	for _, req := range []webhooks.SubscriptionRequest{
+		{URL: "ftp://example.com", Events: []string{webhooks.EventSLABreached}},
+		{URL: "https://example.com/hook"},
+		{URL: "https://example.com/hook", Events: []string{"message.deleted"}},
+	} {
+		if _, err := f.service.CreateSubscription(ctx, req); !errors.Is(err, webhooks.ErrInvalidSubscription) {
+			t.Fatalf("%+v: expected ErrInvalidSubscription, got %v", req, err)
+		}
+	}
+
+	created, err := f.service.CreateSubscription(ctx, webhooks.SubscriptionRequest{URL: "https://example.com/hook", Events: []string{"sla.breached", "sla.breached"}})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if len(created.Secret) < 32 || len(created.Events) != 1 || !created.Active {
+		t.Fatalf("unexpected subscription: %+v", created)
+	}
+	got, err := f.service.GetSubscription(ctx, created.ID)
+	if err != nil || got.Secret != "" {
+		t.Fatalf("expected the secret to be redacted, got %+v %v", got, err)
+	}
+
+	inactive := false
+	updated, err := f.service.UpdateSubscription(ctx, created.ID, webhooks.SubscriptionRequest{URL: "https://example.com/v2", Events: []string{"*"}, Active: &inactive})
+	if err != nil || updated.Secret != "" || updated.Active || updated.URL != "https://example.com/v2" {
+		t.Fatalf("unexpected update: %+v %v", updated, err)
+	}
+	if updated.Matches(webhooks.EventSLABreached) {
+		t.Fatal("inactive subscriptions must not match")
+	}
+	if _, err := f.service.Publish(ctx, "message.deleted", "", nil); !errors.Is(err, webhooks.ErrInvalidEvent) {
+		t.Fatalf("expected ErrInvalidEvent, got %v", err)
+	}
+}
+
+func TestVerifyRejectsStaleAndTamperedDeliveries(t *testing.T) {
+	now := time.Date(2025, time.March, 18, 9, 0, 0, 0, time.UTC)
+	body := []byte(`{"id":"evt-1"}`)
+	header := http.Header{}
+	header.Set(webhooks.TimestampHeader, "1742288400")
+	header.Set(webhooks.SignatureHeader, "sha256="+webhooks.Sign("secret", now.Unix(), body))
+
+	if !webhooks.Verify("secret", header, body, now, time.Minute) {
+		t.Fatal("expected a valid signature")
+	}
+	if webhooks.Verify("secret", header, []byte(`{"id":"evt-2"}`), now, time.Minute) {
+		t.Fatal("expected a tampered body to fail")
+	}
+	if webhooks.Verify("secret", header, body, now.Add(time.Hour), time.Minute) {
+		t.Fatal("expected a stale timestamp to fail")
+	}
+}